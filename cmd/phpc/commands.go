@@ -0,0 +1,241 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/neokofg/php-compiler/internal/compiler"
+	"github.com/neokofg/php-compiler/internal/diag"
+	"github.com/neokofg/php-compiler/internal/disasm"
+	"github.com/neokofg/php-compiler/internal/phpbc"
+	"github.com/neokofg/php-compiler/internal/vm"
+)
+
+var commands = map[string]func(args []string) error{
+	"run":    runCommand,
+	"build":  buildCommand,
+	"exec":   execCommand,
+	"disasm": disasmCommand,
+}
+
+type commandArgs struct {
+	file        string
+	emit        string
+	outFile     string
+	debug       bool
+	errorFormat string
+	optLevel    int
+}
+
+func parseCommandArgs(args []string) (*commandArgs, error) {
+	parsed := &commandArgs{errorFormat: errorFormatHuman, optLevel: defaultOptLevel}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--debug":
+			parsed.debug = true
+		case strings.HasPrefix(arg, "--emit="):
+			parsed.emit = strings.TrimPrefix(arg, "--emit=")
+		case strings.HasPrefix(arg, "--error-format="):
+			parsed.errorFormat = strings.TrimPrefix(arg, "--error-format=")
+			if parsed.errorFormat != errorFormatHuman && parsed.errorFormat != errorFormatJSON {
+				return nil, fmt.Errorf("unknown --error-format value %q, expected human or json", parsed.errorFormat)
+			}
+		case strings.HasPrefix(arg, "-O"):
+			level, err := parseOptLevel(arg)
+			if err != nil {
+				return nil, err
+			}
+			parsed.optLevel = level
+		case arg == "-o" || arg == "--out":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("%s requires a file name", arg)
+			}
+			i++
+			parsed.outFile = args[i]
+		case strings.HasPrefix(arg, "-"):
+			return nil, fmt.Errorf("unknown option %s\n%s", arg, usage)
+		default:
+			if parsed.file != "" {
+				return nil, fmt.Errorf("unexpected argument %s\n%s", arg, usage)
+			}
+			parsed.file = arg
+		}
+	}
+
+	if parsed.file == "" {
+		return nil, fmt.Errorf(usage)
+	}
+
+	return parsed, nil
+}
+
+func compileFile(parsed *commandArgs) (*compiler.Compiler, error) {
+	source, err := readSource(parsed.file)
+	if err != nil {
+		return nil, err
+	}
+
+	phpCompiler, diagnostics := parseAndCompile(lexicalAnalysis(parsed.file, source), parsed.optLevel)
+	if err := reportDiagnostics(diagnostics, diag.Sources{parsed.file: source}, parsed.errorFormat); err != nil {
+		return nil, err
+	}
+
+	return phpCompiler, nil
+}
+
+func toProgram(phpCompiler *compiler.Compiler) *phpbc.Program {
+	return &phpbc.Program{
+		Constants: phpCompiler.GetConstants(),
+		Functions: phpCompiler.GetFunctions(),
+		Variables: phpCompiler.GetVariables(),
+		Code:      phpCompiler.GetBytecode(),
+		Handlers:  phpCompiler.GetHandlers(),
+		File:      phpCompiler.GetFile(),
+		Lines:     phpCompiler.GetLines(),
+	}
+}
+
+// runCommand compiles the script and executes it on the Go VM,
+// without generating C code or invoking a C toolchain.
+func runCommand(args []string) error {
+	parsed, err := parseCommandArgs(args)
+	if err != nil {
+		return err
+	}
+
+	phpCompiler, err := compileFile(parsed)
+	if err != nil {
+		return err
+	}
+
+	return executeProgram(toProgram(phpCompiler), parsed.debug)
+}
+
+func buildCommand(args []string) error {
+	parsed, err := parseCommandArgs(args)
+	if err != nil {
+		return err
+	}
+
+	phpCompiler, err := compileFile(parsed)
+	if err != nil {
+		return err
+	}
+
+	baseName := strings.TrimSuffix(parsed.file, ".php")
+
+	switch parsed.emit {
+	case "bytecode":
+		outFile := parsed.outFile
+		if outFile == "" {
+			outFile = baseName + ".phpbc"
+		}
+		return writeBytecodeFile(toProgram(phpCompiler), outFile)
+	case "native", "":
+		outFile := parsed.outFile
+		if outFile == "" {
+			outFile = baseName
+		}
+
+		tmpFile := "vm_exec_temp.c"
+		if err := generateVMCode(phpCompiler, tmpFile); err != nil {
+			return err
+		}
+		defer os.Remove(tmpFile)
+
+		return compileAndRunVM(tmpFile, outFile)
+	default:
+		return fmt.Errorf("unknown --emit value %q, expected bytecode or native", parsed.emit)
+	}
+}
+
+// execCommand runs a precompiled .phpbc file on the Go VM.
+func execCommand(args []string) error {
+	parsed, err := parseCommandArgs(args)
+	if err != nil {
+		return err
+	}
+
+	program, err := readBytecodeFile(parsed.file)
+	if err != nil {
+		return err
+	}
+
+	return executeProgram(program, parsed.debug)
+}
+
+// disasmCommand prints the bytecode of a .php script or a .phpbc file.
+func disasmCommand(args []string) error {
+	parsed, err := parseCommandArgs(args)
+	if err != nil {
+		return err
+	}
+
+	var program *phpbc.Program
+	if strings.HasSuffix(parsed.file, ".phpbc") {
+		program, err = readBytecodeFile(parsed.file)
+	} else {
+		var phpCompiler *compiler.Compiler
+		phpCompiler, err = compileFile(parsed)
+		if err == nil {
+			program = toProgram(phpCompiler)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return disasm.Disassemble(os.Stdout, program)
+}
+
+func readBytecodeFile(path string) (*phpbc.Program, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("File reading error %s: %v", path, err)
+	}
+	defer f.Close()
+
+	program, err := phpbc.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return program, nil
+}
+
+func writeBytecodeFile(program *phpbc.Program, outFile string) error {
+	f, err := os.Create(outFile)
+	if err != nil {
+		return fmt.Errorf("Cannot create bytecode file %s: %v", outFile, err)
+	}
+
+	if err := phpbc.Write(f, program); err != nil {
+		f.Close()
+		return fmt.Errorf("Error writing bytecode file %s: %v", outFile, err)
+	}
+
+	return f.Close()
+}
+
+func executeProgram(program *phpbc.Program, debug bool) error {
+	machine := vm.New(os.Stdout)
+	machine.SetDebugMode(debug)
+	machine.SetExceptionTable(program.Handlers)
+	machine.SetDebugInfo(program.File, program.Lines, program.Functions, program.Variables)
+
+	if err := machine.Execute(program.Code, program.Constants); err != nil {
+		var uncaught *vm.UncaughtError
+		if errors.As(err, &uncaught) {
+			fmt.Fprintf(os.Stdout, "PHP Fatal error:  %v\n", uncaught)
+			return &exitStatusError{code: uncaughtExitStatus}
+		}
+		return fmt.Errorf("VM runtime error: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/neokofg/php-compiler/internal/diag"
+)
+
+const (
+	errorFormatHuman = "human"
+	errorFormatJSON  = "json"
+)
+
+// errAborted is returned once the diagnostics explaining the failure
+// have been printed, so main only has to set the exit code.
+var errAborted = errors.New("aborting due to previous errors")
+
+// reportDiagnostics prints diagnostics to stderr and returns errAborted
+// if any of them is an error.
+func reportDiagnostics(diagnostics diag.List, sources diag.Sources, format string) error {
+	diagnostics.Sort()
+
+	if format == errorFormatJSON {
+		if err := diagnostics.WriteJSON(os.Stderr); err != nil {
+			return err
+		}
+	} else {
+		diagnostics.Render(os.Stderr, sources)
+	}
+
+	errorCount := diagnostics.ErrorCount()
+	if errorCount == 0 {
+		return nil
+	}
+
+	if format != errorFormatJSON {
+		plural := "s"
+		if errorCount == 1 {
+			plural = ""
+		}
+		fmt.Fprintf(os.Stderr, "error: aborting due to %d previous error%s\n", errorCount, plural)
+	}
+
+	return errAborted
+}
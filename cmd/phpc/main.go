@@ -16,33 +16,48 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"github.com/neokofg/php-compiler/internal/compiler"
+	"github.com/neokofg/php-compiler/internal/diag"
 	"github.com/neokofg/php-compiler/internal/lexer"
+	"github.com/neokofg/php-compiler/internal/optimizer"
 	"github.com/neokofg/php-compiler/internal/parser"
 	"github.com/neokofg/php-compiler/internal/token"
+	"math"
 	"os"
 	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
 )
 
+const usage = `Usage: phpc file.php [--out name] [-O0|-O1]
+       phpc run file.php [--debug]
+       phpc build --emit=bytecode|native file.php [-o name]
+       phpc exec file.phpbc [--debug]
+       phpc disasm file.php|file.phpbc
+
+Compiling commands accept --error-format=human|json, and -O0 or -O1 to
+turn constant folding off or on. -O1 is the default.`
+
 func main() {
-	source, outFile, err := processArgs()
-	if err != nil {
-		fmt.Println(err)
-		return
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				exit(err)
+			}
+			return
+		}
 	}
 
-	tokens, err := lexicalAnalysis(source)
+	source, outFile, optLevel, err := processArgs()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exit(err)
 	}
 
-	phpCompiler, err := parseAndCompile(tokens)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
+	phpCompiler, diagnostics := parseAndCompile(lexicalAnalysis(os.Args[1], source), optLevel)
+	if err := reportDiagnostics(diagnostics, diag.Sources{os.Args[1]: source}, errorFormatHuman); err != nil {
 		os.Exit(1)
 	}
 
@@ -50,69 +65,119 @@ func main() {
 	if err := generateVMCode(phpCompiler, tmpFile); err != nil {
 		panic(err)
 	}
-	defer os.Remove(tmpFile)
 
-	if err := compileAndRunVM(tmpFile, outFile); err != nil {
-		fmt.Println(err)
+	err = compileAndRunVM(tmpFile, outFile)
+	os.Remove(tmpFile)
+	if err != nil {
+		exit(err)
+	}
+}
+
+// exit prints err, unless it has been reported already, and ends the
+// process with the exit status err carries, or 1.
+func exit(err error) {
+	var status *exitStatusError
+	switch {
+	case errors.As(err, &status):
+		os.Exit(status.code)
+	case err != errAborted:
+		fmt.Fprintf(os.Stderr, "%v\n", err)
 	}
+	os.Exit(1)
 }
 
-func processArgs() (string, string, error) {
+func processArgs() (string, string, int, error) {
 	if len(os.Args) < 2 {
-		return "", "", fmt.Errorf("Usage: phpc file.php [--out name]")
+		return "", "", 0, fmt.Errorf(usage)
 	}
 
 	outFile := ""
-	if len(os.Args) > 3 && os.Args[2] == "--out" {
-		outFile = os.Args[3]
+	optLevel := defaultOptLevel
+	for i := 2; i < len(os.Args); i++ {
+		switch arg := os.Args[i]; {
+		case arg == "--out" && i+1 < len(os.Args):
+			i++
+			outFile = os.Args[i]
+		case strings.HasPrefix(arg, "-O"):
+			level, err := parseOptLevel(arg)
+			if err != nil {
+				return "", "", 0, err
+			}
+			optLevel = level
+		}
 	}
 
-	data, err := os.ReadFile(os.Args[1])
+	source, err := readSource(os.Args[1])
 	if err != nil {
-		return "", "", fmt.Errorf("File reading error %s: %v", os.Args[1], err)
+		return "", "", 0, err
 	}
 
-	source := string(data)
-	if strings.HasPrefix(source, "<?php") {
-		source = strings.TrimPrefix(source, "<?php")
-		source = strings.TrimLeft(source, " \n\r\t")
+	return source, outFile, optLevel, nil
+}
+
+// defaultOptLevel is the optimization level used without -O0 or -O1.
+const defaultOptLevel = 1
+
+func parseOptLevel(arg string) (int, error) {
+	switch arg {
+	case "-O0":
+		return 0, nil
+	case "-O1":
+		return 1, nil
+	default:
+		return 0, fmt.Errorf("unknown optimization level %s, expected -O0 or -O1", arg)
 	}
+}
 
-	return source, outFile, nil
+func readSource(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("File reading error %s: %v", path, err)
+	}
+
+	return string(data), nil
 }
 
-func lexicalAnalysis(source string) ([]token.Token, error) {
-	lexerInstance := lexer.NewLexer(source)
+// lexicalAnalysis keeps T_ILLEGAL tokens in the stream: the parser reports
+// them as lexer errors and recovers, like it does for its own errors.
+func lexicalAnalysis(filename, source string) []token.Token {
+	lexerInstance := lexer.NewFileLexer(filename, source)
 	var tokens []token.Token
 
 	for {
 		tok := lexerInstance.NextToken()
-		if tok.Type == token.T_ILLEGAL {
-			return nil, fmt.Errorf("Lexer analyze error: %s", tok.Value)
-		}
 		if tok.Type == token.T_EOF {
 			break
 		}
 		tokens = append(tokens, tok)
 	}
 
-	return tokens, nil
+	return tokens
 }
 
-func parseAndCompile(tokens []token.Token) (*compiler.Compiler, error) {
+// parseAndCompile returns every diagnostic found along the way. The
+// compiler is only usable when the diagnostics contain no errors. At
+// optLevel 1 and above, constant expressions are folded before compiling.
+func parseAndCompile(tokens []token.Token, optLevel int) (*compiler.Compiler, diag.List) {
+	var diagnostics diag.List
+
 	parserInstance := parser.NewParser(tokens)
 	stmts, err := parserInstance.Parse()
 	if err != nil {
-		return nil, fmt.Errorf("Syntax analyze error: %v", err)
+		diagnostics.AddError(err, diag.ErrSyntax)
+		return nil, diagnostics
 	}
 
-	phpCompiler := compiler.New()
-	err = phpCompiler.CompileProgram(stmts)
-	if err != nil {
-		return nil, fmt.Errorf("Compilation error: %v", err)
+	if optLevel >= 1 {
+		stmts = optimizer.Optimize(stmts)
 	}
 
-	return phpCompiler, nil
+	phpCompiler := compiler.New()
+	// CompileProgram's error is made of the same diagnostics.
+	_ = phpCompiler.CompileProgram(stmts)
+	diagnostics = append(diagnostics, phpCompiler.Diagnostics()...)
+
+	return phpCompiler, diagnostics
 }
 
 func generateVMCode(phpCompiler *compiler.Compiler, tmpFile string) error {
@@ -129,6 +194,7 @@ func generateVMCode(phpCompiler *compiler.Compiler, tmpFile string) error {
 		"#include <stdint.h>\n",
 		"#include <stddef.h>\n",
 		"#include <stdio.h>\n",
+		"#include <math.h>\n",
 		"#include \"vm.h\"\n\n",
 	}
 
@@ -175,16 +241,34 @@ func generateVMCode(phpCompiler *compiler.Compiler, tmpFile string) error {
 	for _, c := range constants {
 		if c.Type == "string" {
 			if _, err := f.WriteString(fmt.Sprintf("    {.type = TYPE_STRING, .value.str_val = %s},\n",
-				strconv.Quote(c.Value))); err != nil {
+				cStringLiteral(c.Value))); err != nil {
 				return fmt.Errorf("Error writing string constant in %s: %v", tmpFile, err)
 			}
+		} else if c.Type == "bool" {
+			b, errBool := strconv.ParseBool(c.Value)
+			if errBool != nil {
+				return fmt.Errorf("Critical error: Cannot convert const '%s' to bool: %v", c.Value, errBool)
+			}
+
+			if _, err := f.WriteString(fmt.Sprintf("    {.type = TYPE_BOOLEAN, .value.bool_val = %t},\n", b)); err != nil {
+				return fmt.Errorf("Error writing bool constant in %s: %v", tmpFile, err)
+			}
+		} else if c.Type == "float" {
+			num, errFloat := strconv.ParseFloat(c.Value, 64)
+			if errFloat != nil {
+				return fmt.Errorf("Critical error: Cannot convert const '%s' to float: %v", c.Value, errFloat)
+			}
+
+			if _, err := f.WriteString(fmt.Sprintf("    {.type = TYPE_FLOAT, .value.float_val = %s},\n", cFloatLiteral(num))); err != nil {
+				return fmt.Errorf("Error writing float constant in %s: %v", tmpFile, err)
+			}
 		} else {
-			num, errAtoi := strconv.Atoi(c.Value)
-			if errAtoi != nil {
-				return fmt.Errorf("Critical error: Cannot convert const '%s' to integer: %v", c.Value, errAtoi)
+			num, errParse := strconv.ParseInt(c.Value, 10, 64)
+			if errParse != nil {
+				return fmt.Errorf("Critical error: Cannot convert const '%s' to integer: %v", c.Value, errParse)
 			}
 
-			if _, err := f.WriteString(fmt.Sprintf("    {.type = TYPE_INT, .value.int_val = %d},\n", num)); err != nil {
+			if _, err := f.WriteString(fmt.Sprintf("    {.type = TYPE_INT, .value.int_val = %s},\n", cIntLiteral(num))); err != nil {
 				return fmt.Errorf("Error writing int constant in %s: %v", tmpFile, err)
 			}
 		}
@@ -198,6 +282,48 @@ func generateVMCode(phpCompiler *compiler.Compiler, tmpFile string) error {
 		return fmt.Errorf("Error writing constants_len in %s: %v", tmpFile, err)
 	}
 
+	var handlers []string
+	for _, h := range phpCompiler.GetHandlers() {
+		handlers = append(handlers, fmt.Sprintf("{%d, %d, %d, %d, %d}", h.Start, h.End, h.Target, h.Function, h.StackDepth))
+	}
+	if err := writeCTable(f, "ExceptionHandler", "exception_handlers", handlers); err != nil {
+		return fmt.Errorf("Error writing exception table in %s: %v", tmpFile, err)
+	}
+
+	var lines []string
+	for _, l := range phpCompiler.GetLines() {
+		lines = append(lines, fmt.Sprintf("{%d, %d}", l.Addr, l.Line))
+	}
+	if err := writeCTable(f, "LineEntry", "lines", lines); err != nil {
+		return fmt.Errorf("Error writing line table in %s: %v", tmpFile, err)
+	}
+
+	var functionNames []string
+	for _, fn := range phpCompiler.GetFunctions() {
+		functionNames = append(functionNames, fmt.Sprintf("{%d, %s}", fn.Address, cStringLiteral(fn.Name)))
+	}
+	if err := writeCTable(f, "FunctionName", "function_names", functionNames); err != nil {
+		return fmt.Errorf("Error writing function names in %s: %v", tmpFile, err)
+	}
+
+	var variableNames []string
+	for name, idx := range phpCompiler.GetVariables() {
+		variableNames = append(variableNames, fmt.Sprintf("{-1, %d, %s}", idx, cStringLiteral(name)))
+	}
+	sort.Strings(variableNames)
+	for _, fn := range phpCompiler.GetFunctions() {
+		for slot, name := range fn.Locals {
+			variableNames = append(variableNames, fmt.Sprintf("{%d, %d, %s}", fn.Address, slot, cStringLiteral(name)))
+		}
+	}
+	if err := writeCTable(f, "VariableName", "variable_names", variableNames); err != nil {
+		return fmt.Errorf("Error writing variable names in %s: %v", tmpFile, err)
+	}
+
+	if _, err := f.WriteString(fmt.Sprintf("const char* source_file = %s;\n\n", cStringLiteral(phpCompiler.GetFile()))); err != nil {
+		return fmt.Errorf("Error writing source file in %s: %v", tmpFile, err)
+	}
+
 	mainFunctionCode := []string{
 		"int main(int argc, char** argv) {\n",
 		"    VM* vm = vm_new();\n",
@@ -208,8 +334,12 @@ func generateVMCode(phpCompiler *compiler.Compiler, tmpFile string) error {
 		"    if (argc > 1 && strcmp(argv[1], \"--debug\") == 0) {\n",
 		"        vm_set_debug_mode(vm, true);\n",
 		"    }\n\n",
+		"    vm_set_exception_table(vm, exception_handlers, exception_handlers_len);\n",
+		"    vm_set_debug_info(vm, source_file, lines, lines_len, function_names, function_names_len,\n",
+		"                      variable_names, variable_names_len);\n\n",
 		"    status_t status = vm_execute(vm, bytecode, bytecode_len, constants, constants_len);\n",
 		"    vm_free(vm);\n\n",
+		fmt.Sprintf("    if (status == STATUS_THROWN) {\n        return %d;\n    }\n", uncaughtExitStatus),
 		"    return (status == STATUS_SUCCESS) ? 0 : 1;\n",
 		"}\n",
 	}
@@ -223,6 +353,95 @@ func generateVMCode(phpCompiler *compiler.Compiler, tmpFile string) error {
 	return nil
 }
 
+// writeCTable writes a const array of rows and its length as name_len. C
+// has no empty arrays, so an empty table gets a placeholder row.
+func writeCTable(f *os.File, typ, name string, rows []string) error {
+	if _, err := fmt.Fprintf(f, "const %s %s[] = {\n", typ, name); err != nil {
+		return err
+	}
+	count := len(rows)
+	if count == 0 {
+		rows = []string{"{0}"}
+	}
+	for _, row := range rows {
+		if _, err := fmt.Fprintf(f, "    %s,\n", row); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(f, "};\n\nsize_t %s_len = %d;\n\n", name, count)
+	return err
+}
+
+// cIntLiteral writes num as a 64-bit C literal. The minimum value has no
+// literal of its own, since -9223372036854775808 is the negation of a
+// number that does not fit.
+func cIntLiteral(num int64) string {
+	if num == math.MinInt64 {
+		return "INT64_MIN"
+	}
+	return fmt.Sprintf("INT64_C(%d)", num)
+}
+
+// cFloatLiteral writes num so that the C compiler reads back the same double.
+func cFloatLiteral(num float64) string {
+	switch {
+	case math.IsInf(num, 1):
+		return "INFINITY"
+	case math.IsInf(num, -1):
+		return "-INFINITY"
+	case math.IsNaN(num):
+		return "NAN"
+	}
+
+	literal := strconv.FormatFloat(num, 'g', -1, 64)
+	if !strings.ContainsAny(literal, ".e") {
+		literal += ".0"
+	}
+	return literal
+}
+
+// cStringLiteral writes s as a C string literal byte by byte. Bytes
+// outside printable ASCII become three-digit octal escapes, which, unlike
+// \x escapes, cannot swallow the characters that follow them.
+func cStringLiteral(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case c == '\n':
+			sb.WriteString("\\n")
+		case c == '\t':
+			sb.WriteString("\\t")
+		case c == '?':
+			// Avoids accidental trigraphs such as ??=.
+			sb.WriteString("\\?")
+		case c >= 0x20 && c < 0x7F:
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, "\\%03o", c)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// uncaughtExitStatus is the exit status of a compiled script that ends
+// with an uncaught exception, which it has reported itself.
+const uncaughtExitStatus = 255
+
+// exitStatusError is returned when the script failed with a non-zero exit
+// status after reporting why, so main only has to pass the status on.
+type exitStatusError struct {
+	code int
+}
+
+func (e *exitStatusError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
 func compileAndRunVM(tmpFile string, outFile string) error {
 	target := "vm_exec"
 	if outFile != "" {
@@ -244,10 +463,21 @@ func compileAndRunVM(tmpFile string, outFile string) error {
 		vmDir+"/src/handlers/logic.c",
 		vmDir+"/src/handlers/string.c",
 		vmDir+"/src/handlers/function.c",
+		vmDir+"/src/handlers/array.c",
+		vmDir+"/src/handlers/foreach.c",
+		vmDir+"/src/handlers/native.c",
+		vmDir+"/src/handlers/object.c",
+		vmDir+"/src/handlers/exception.c",
+		vmDir+"/src/handlers/closure.c",
 		vmDir+"/src/components/value.c",
 		vmDir+"/src/components/memory.c",
 		vmDir+"/src/components/stack.c",
-		vmDir+"/src/components/error.c")
+		vmDir+"/src/components/error.c",
+		vmDir+"/src/components/array.c",
+		vmDir+"/src/components/native.c",
+		vmDir+"/src/components/object.c",
+		vmDir+"/src/components/exception.c",
+		"-lm")
 
 	cmd.Stderr = os.Stderr
 	cmd.Stdout = os.Stdout
@@ -262,11 +492,18 @@ func compileAndRunVM(tmpFile string, outFile string) error {
 		execCmd.Stdout = os.Stdout
 		execCmd.Stderr = os.Stderr
 
-		if runErr := execCmd.Run(); runErr != nil {
+		runErr := execCmd.Run()
+		os.Remove(target)
+
+		var exitErr *exec.ExitError
+		if errors.As(runErr, &exitErr) && exitErr.ExitCode() > 0 {
+			// The VM has printed the uncaught exception or the fatal
+			// error like PHP does.
+			return &exitStatusError{code: exitErr.ExitCode()}
+		}
+		if runErr != nil {
 			return fmt.Errorf("VM runtime error, please report this error to repository owners: %v", runErr)
 		}
-
-		os.Remove(target)
 	}
 
 	return nil
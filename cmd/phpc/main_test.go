@@ -0,0 +1,225 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/neokofg/php-compiler/internal/vm"
+)
+
+// TestMain lets the tests run the test binary as phpc, with the arguments
+// after its name, when PHPC_TEST_MAIN is set.
+func TestMain(m *testing.M) {
+	if os.Getenv("PHPC_TEST_MAIN") != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// TestOptimizerPreservesOutput runs every example at -O0 and -O1 on the
+// Go VM and expects the same output from both.
+func TestOptimizerPreservesOutput(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "..", "example-php-code", "*.php"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no examples found")
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			source, err := readSource(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			unoptimized := runAtLevel(t, file, source, 0)
+			optimized := runAtLevel(t, file, source, 1)
+			if unoptimized != optimized {
+				t.Errorf("output differs between -O0 and -O1\n-O0:\n%s\n-O1:\n%s", unoptimized, optimized)
+			}
+		})
+	}
+}
+
+func runAtLevel(t *testing.T, file, source string, optLevel int) string {
+	t.Helper()
+
+	phpCompiler, diagnostics := parseAndCompile(lexicalAnalysis(file, source), optLevel)
+	if diagnostics.HasErrors() {
+		t.Fatalf("-O%d: %v", optLevel, diagnostics.Err())
+	}
+
+	program := toProgram(phpCompiler)
+
+	var out bytes.Buffer
+	machine := vm.New(&out)
+	machine.SetExceptionTable(program.Handlers)
+	machine.SetDebugInfo(program.File, program.Lines, program.Functions, program.Variables)
+	if err := machine.Execute(program.Code, program.Constants); err != nil {
+		out.WriteString("error: " + err.Error())
+	}
+
+	return out.String()
+}
+
+func TestNativeByRefArguments(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name: "array element",
+			source: `<?php
+$m = ["x" => [3, 1, 2], "list" => []];
+sort($m["x"]);
+echo array_push($m["list"], "a", "b") . " " . implode(",", $m["x"]) . " " . implode(",", $m["list"]);`,
+			want: "2 1,2,3 a,b",
+		},
+		{
+			name: "keys evaluated once",
+			source: `<?php
+$i = 0;
+$m = [[1], [2]];
+array_push($m[$i++], 5);
+echo $i . " " . implode(",", $m[0]) . " " . implode(",", $m[1]);`,
+			want: "1 1,5 2",
+		},
+		{
+			name: "property",
+			source: `<?php
+class Bag {
+    public $items = [3, 1, 2];
+    public $groups = ["a" => [9, 7]];
+    public function sortAll() {
+        sort($this->items);
+        return sort($this->groups["a"]);
+    }
+}
+$bag = new Bag();
+echo ($bag->sortAll() ? "sorted " : "") . implode(",", $bag->items) . " " . implode(",", $bag->groups["a"]) . " " . array_pop($bag->items);`,
+			want: "sorted 1,2,3 7,9 3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runAtLevel(t, "test.php", tt.source, defaultOptLevel); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForeachByRefElement(t *testing.T) {
+	source := `<?php
+$m = ["x" => [1, 2, 3], "a" => ["b" => [10, 20]]];
+foreach ($m["x"] as &$v) { $v = $v * 2; }
+unset($v);
+foreach ($m["a"]["b"] as $k => &$w) { $w = $w + $k; }
+unset($w);
+$key = "x";
+foreach ($m[$key] as &$v) { $v++; }
+unset($v);
+class Bag {
+    public $groups = ["a" => [1, 2]];
+    public function scale() {
+        foreach ($this->groups["a"] as &$g) { $g = $g * 10; }
+    }
+}
+$bag = new Bag();
+$bag->scale();
+echo implode(",", $m["x"]) . " " . implode(",", $m["a"]["b"]) . " " . implode(",", $bag->groups["a"]);`
+	want := "3,5,7 10,21 10,20"
+
+	for _, optLevel := range []int{0, 1} {
+		if got := runAtLevel(t, "test.php", source, optLevel); got != want {
+			t.Errorf("-O%d: got %q, want %q", optLevel, got, want)
+		}
+	}
+}
+
+func TestUncaughtExceptionExitStatus(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "throw.php")
+	if err := os.WriteFile(script, []byte("<?php\nthrow new Exception(\"boom\");\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The compiled script is built from the VM sources in ./vm.
+	vmDir, err := filepath.Abs(filepath.Join("..", "..", "vm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(vmDir, filepath.Join(dir, "vm")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		needsGCC bool
+	}{
+		{name: "compiled", args: []string{script}, needsGCC: true},
+		{name: "run", args: []string{"run", script}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := exec.LookPath("gcc"); tt.needsGCC && err != nil {
+				t.Skip("gcc not found")
+			}
+
+			output, code := runPhpc(t, dir, tt.args...)
+			if code != uncaughtExitStatus {
+				t.Errorf("exit status %d, want %d", code, uncaughtExitStatus)
+			}
+			if !strings.Contains(output, "Uncaught Exception: boom") {
+				t.Errorf("output does not report the exception:\n%s", output)
+			}
+		})
+	}
+}
+
+func TestBadOptLevelExitStatus(t *testing.T) {
+	script, err := filepath.Abs(filepath.Join("..", "..", "example-php-code", "echo.php"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{{script, "-O2"}, {"run", script, "-O2"}} {
+		output, code := runPhpc(t, t.TempDir(), args...)
+		if code != 1 {
+			t.Errorf("phpc %s: exit status %d, want 1", strings.Join(args, " "), code)
+		}
+		if !strings.Contains(output, "unknown optimization level -O2") {
+			t.Errorf("phpc %s: output does not report the bad flag:\n%s", strings.Join(args, " "), output)
+		}
+	}
+}
+
+// runPhpc runs phpc with args in dir and returns its combined output and
+// exit status.
+func runPhpc(t *testing.T, dir string, args ...string) (string, int) {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "PHPC_TEST_MAIN=1")
+	output, err := cmd.CombinedOutput()
+
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatal(err)
+	}
+	return string(output), cmd.ProcessState.ExitCode()
+}
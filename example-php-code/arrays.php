@@ -0,0 +1,35 @@
+<?php
+$list = [10, 20, 30];
+$list[] = 40;
+echo "Second: $list[1], last: " . $list[3] . "\n";
+
+$user = array('name' => 'Ann', 'langs' => ['PHP', 'Go']);
+$user['langs'][] = 'C';
+$user['visits'] = 1;
+$user['visits'] += 2;
+echo "{$user['name']} knows {$user['langs'][2]} and has {$user['visits']} visits\n";
+
+// Arrays are values: the copy is not affected by writes to the original.
+$copy = $user;
+$user['langs'][0] = 'Rust';
+echo $copy['langs'][0] . " " . $user['langs'][0] . "\n";
+
+$grid = [];
+$grid[1][2] = 'x';
+echo "Grid: " . $grid[1][2] . "\n";
+
+$merged = ['a' => 1, 'b' => 2] + ['b' => 3, 'c' => 4];
+echo $merged['a'] . $merged['b'] . $merged['c'] . "\n";
+
+foreach ($user['langs'] as $i => $lang) {
+    echo "$i: $lang\n";
+}
+
+$prices = ['tea' => 3, 'cake' => 5];
+foreach ($prices as &$price) {
+    $price = $price * 2;
+}
+unset($price); // $price stays bound to the last element otherwise
+foreach ($prices as $item => $price) {
+    echo "$item costs $price\n";
+}
@@ -0,0 +1,71 @@
+<?php
+
+function show($list) {
+    $parts = [];
+    foreach ($list as $key => $value) {
+        $parts[] = $key . "=" . (is_array($value) ? show($value) : $value);
+    }
+    return "[" . implode(", ", $parts) . "]";
+}
+
+$a = $b = $c = 5;
+echo $a . " " . $b . " " . $c . "\n";
+
+$total = ($price = 40) + ($tax = 8);
+echo $price . " + " . $tax . " = " . $total . "\n";
+
+$lines = ["first", "second", "third"];
+$i = 0;
+while (($line = $lines[$i] ?? null) !== null) {
+    echo $i++ . ": " . $line . "\n";
+}
+
+$n = 10;
+echo ($n += 5) . " " . ($n -= 3) . " " . ($n *= 2) . " " . ($n /= 4) . " " . ($n %= 4) . "\n";
+
+$greeting = "Hello";
+echo ($greeting .= ", world") . "!\n";
+
+$stock = [];
+$first = $stock[] = "apple";
+$stock['pear'] = $stock['plum'] = 3;
+$stock['pear'] += 2;
+echo $first . " " . show($stock) . "\n";
+
+$counts = [];
+$counts['a'] ??= 0;
+echo $counts['a']++ . " " . ++$counts['a'] . " " . $counts['a']-- . " " . $counts['a'] . "\n";
+
+$grid = [[1, 2], [3, 4]];
+$grid[1][0] = $grid[0][1] = 9;
+echo show($grid) . "\n";
+
+class Counter {
+    public $value = 0;
+    public $log = [];
+    public $label;
+}
+
+$counter = new Counter();
+$result = $counter->value = 41;
+echo $result . " " . ++$counter->value . " " . $counter->value++ . " " . $counter->value . "\n";
+echo ($counter->value *= 2) . "\n";
+echo ($counter->label ??= "default") . " " . ($counter->label ??= "ignored") . "\n";
+$counter->log[] = $counter->log['last'] = "started";
+echo show($counter->log) . "\n";
+
+$original = [1, 2];
+$copy = $original;
+$copy[] = $original[] = 3;
+$copy[] = 4;
+echo show($original) . " " . show($copy) . "\n";
+
+$double = fn($x) => $y = $x * 2;
+echo $double(21) . "\n";
+
+$permissions = 0b0111;
+$permissions &= ~0b0010;
+$permissions |= 0b1000;
+$permissions ^= 0b0001;
+$permissions <<= 2;
+echo "permissions: " . $permissions . " " . ($permissions >>= 3) . "\n";
@@ -0,0 +1,24 @@
+<?php
+$words = explode(" ", "the quick brown fox");
+echo count($words) . " words, longest has " . max(word_lengths($words)) . " letters\n";
+
+function word_lengths($list) {
+    $lengths = [];
+    foreach ($list as $word) {
+        $lengths[] = strlen($word);
+    }
+    return $lengths;
+}
+
+echo ucfirst(implode(", ", $words)) . "\n";
+echo str_replace("quick", "slow", "the quick fox") . "\n";
+echo strtoupper(substr("compiler", 0, 3)) . str_repeat("!", 3) . "\n";
+
+// sort and array_push change the array passed to them.
+$numbers = [5, 3, 8, 1];
+sort($numbers);
+array_push($numbers, 13, 21);
+echo implode(" ", $numbers) . ", sum " . array_sum($numbers) . "\n";
+
+echo "17 / 5 = " . intdiv(17, 5) . ", sqrt(2) = " . round(sqrt(2), 3) . "\n";
+echo implode(",", range("a", "e")) . "\n";
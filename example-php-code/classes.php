@@ -0,0 +1,56 @@
+<?php
+
+class Counter {
+    public $count = 0;
+    public $step;
+    private $history = [];
+
+    public function __construct($step) {
+        $this->step = $step;
+    }
+
+    public function tick() {
+        $this->count += $this->step;
+        $this->history[] = $this->count;
+        return $this;
+    }
+
+    public function history() {
+        return implode(", ", $this->history);
+    }
+
+    private function reset() {
+        $this->count = 0;
+    }
+}
+
+class Point {
+    public $x = 0;
+    public $y = 0;
+
+    function describe() {
+        return "($this->x, $this->y)";
+    }
+}
+
+$c = new Counter(5);
+$c->tick();
+$c->tick()->tick();
+echo "count: $c->count\n";
+echo "history: " . $c->history() . "\n";
+
+$c->count++;
+echo "after ++: " . $c->count . "\n";
+
+$p = new Point;
+$p->x = 3;
+$p->y = 4;
+echo "point: " . $p->describe() . "\n";
+
+$q = $p;
+$q->x = 10;
+echo "shared: " . $p->x . "\n";
+
+$p->tags = ["a"];
+$p->tags[] = "b";
+echo "tags: " . count($p->tags) . "\n";
@@ -0,0 +1,82 @@
+<?php
+
+function apply($callback, $items) {
+    $result = [];
+    foreach ($items as $key => $item) {
+        $result[$key] = $callback($item);
+    }
+    return $result;
+}
+
+$rate = 2;
+$scale = fn($x) => $x * $rate;
+echo implode(", ", apply($scale, [1, 2, 3])) . "\n";
+
+$prefix = "item";
+$label = function ($x) use ($prefix) {
+    return $prefix . "#" . $x;
+};
+$prefix = "changed";
+echo implode(", ", apply($label, [1, 2])) . "\n";
+
+function makeCounter() {
+    $count = 0;
+    return function () use (&$count) {
+        $count++;
+        return $count;
+    };
+}
+
+$first = makeCounter();
+$second = makeCounter();
+$first();
+$first();
+echo "first: " . $first() . ", second: " . $second() . "\n";
+
+$total = 0;
+$add = function ($n) use (&$total) {
+    $total += $n;
+};
+foreach ([5, 10, 20] as $n) {
+    $add($n);
+}
+echo "total: " . $total . "\n";
+
+// Natives call closures and functions passed by name back.
+$words = ["pear", "fig", "banana"];
+usort($words, fn($a, $b) => strlen($a) - strlen($b));
+echo implode(", ", array_map('strtoupper', $words)) . "\n";
+echo implode(", ", array_map(fn($w) => $w . ":" . strlen($w), $words)) . "\n";
+
+$adder = fn($a) => fn($b) => $a + $b;
+$addFive = $adder(5);
+echo "5 + 7 = " . $addFive(7) . "\n";
+
+class Cart {
+    private $items = [];
+    private $discount;
+
+    public function __construct($discount) {
+        $this->discount = $discount;
+    }
+
+    public function add($name, $price) {
+        $this->items[$name] = $price;
+    }
+
+    public function pricer() {
+        return fn($name) => $this->items[$name] * (100 - $this->discount) / 100;
+    }
+}
+
+$cart = new Cart(10);
+$cart->add("book", 20);
+$cart->add("pen", 5);
+$price = $cart->pricer();
+echo "book: " . $price("book") . ", pen: " . $price("pen") . "\n";
+
+try {
+    $label();
+} catch (ArgumentCountError $e) {
+    echo $e->getMessage() . "\n";
+}
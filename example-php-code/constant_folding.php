@@ -0,0 +1,25 @@
+<?php
+
+// With -O1, the default, these expressions are computed at compile time.
+// Compare `phpc disasm` of this file with and without -O0.
+$secondsPerDay = 60 * 60 * 24;
+$greeting = "Hello, " . "world" . "!";
+echo $greeting . " A day has " . $secondsPerDay . " seconds.\n";
+
+echo "A week has " . $secondsPerDay * 7 . " seconds, " . 2 ** 10 . " bytes make a KiB.\n";
+echo "Halves: " . 7 / 2 . " " . 8 / 2 . "\n";
+
+if (false) {
+    echo "This branch is not compiled.\n";
+} else {
+    echo "Only this branch is compiled.\n";
+}
+
+echo (1 > 2 ? "bigger" : "smaller") . "\n";
+
+// Division by zero is left for run time, where it can be caught.
+try {
+    echo 1 % 0;
+} catch (DivisionByZeroError $e) {
+    echo "Caught: " . $e->getMessage() . "\n";
+}
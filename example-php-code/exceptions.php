@@ -0,0 +1,62 @@
+<?php
+
+class InsufficientFunds extends RuntimeException {
+    public function __construct($needed) {
+        parent::__construct("need " . $needed . " more", 402);
+    }
+}
+
+class Account {
+    private $balance = 0;
+
+    public function deposit($amount) {
+        if ($amount <= 0) {
+            throw new InvalidArgumentException("deposit must be positive");
+        }
+        $this->balance += $amount;
+    }
+
+    public function withdraw($amount) {
+        if ($amount > $this->balance) {
+            throw new InsufficientFunds($amount - $this->balance);
+        }
+        $this->balance -= $amount;
+        return $this->balance;
+    }
+}
+
+$account = new Account();
+$account->deposit(100);
+
+foreach ([30, 200, 0] as $amount) {
+    try {
+        if ($amount == 0) {
+            $account->deposit($amount);
+        } else {
+            echo "left: " . $account->withdraw($amount) . "\n";
+        }
+    } catch (InsufficientFunds $e) {
+        echo "declined (" . $e->getCode() . "): " . $e->getMessage() . "\n";
+    } catch (LogicException | TypeError $e) {
+        echo "invalid: " . $e->getMessage() . "\n";
+    } finally {
+        echo "checked " . $amount . "\n";
+    }
+}
+
+function ratio($a, $b) {
+    try {
+        return $a / $b;
+    } finally {
+        echo "ratio(" . $a . ", " . $b . ") done\n";
+    }
+}
+
+try {
+    echo ratio(10, 4) . "\n";
+    echo ratio(1, 0) . "\n";
+} catch (DivisionByZeroError $e) {
+    echo "caught " . $e->getMessage() . " on line " . $e->getLine() . "\n";
+}
+
+$account->withdraw(1000);
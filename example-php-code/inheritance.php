@@ -0,0 +1,92 @@
+<?php
+
+interface Shape {
+    public function area();
+}
+
+interface Named extends Shape {
+    public function name();
+}
+
+trait Describes {
+    public $unit = "cm";
+
+    public function describe() {
+        return $this->name() . ": " . $this->area() . " " . $this->unit . "2";
+    }
+}
+
+trait Greets {
+    public function describe() {
+        return "hello";
+    }
+
+    public function greet() {
+        return "Hi, I am " . $this->name();
+    }
+}
+
+abstract class Figure implements Named {
+    use Describes, Greets {
+        Describes::describe insteadof Greets;
+        Greets::describe as protected hello;
+    }
+
+    protected $id;
+
+    public function __construct($id) {
+        $this->id = $id;
+    }
+
+    public function name() {
+        return static::kind() . " #" . $this->id;
+    }
+
+    public static function kind() {
+        return "figure";
+    }
+
+    public static function make($id, $size) {
+        return new static($id, $size);
+    }
+}
+
+class Square extends Figure {
+    private $side;
+
+    public function __construct($id, $side) {
+        parent::__construct($id);
+        $this->side = $side;
+    }
+
+    public function area() {
+        return $this->side * $this->side;
+    }
+
+    public static function kind() {
+        return "square";
+    }
+}
+
+final class Rectangle extends Square {
+    public function name() {
+        return "wide " . parent::name();
+    }
+
+    public static function kind() {
+        return "rectangle";
+    }
+}
+
+$shapes = [new Square(1, 3), Rectangle::make(2, 4)];
+foreach ($shapes as $shape) {
+    echo $shape->describe() . "\n";
+    echo $shape->greet() . "\n";
+}
+
+foreach ($shapes as $shape) {
+    echo ($shape instanceof Rectangle ? "rectangle" : "not a rectangle") . ", "
+        . ($shape instanceof Shape ? "a shape" : "not a shape") . "\n";
+}
+
+echo Figure::kind() . " " . Square::kind() . "\n";
@@ -0,0 +1,35 @@
+<?php
+
+function check($label, $result) {
+    echo $label . ": " . ($result ? "true" : "false") . "\n";
+}
+
+function loud($value) {
+    echo "evaluated " . $value . "\n";
+    return $value;
+}
+
+$divisor = 0;
+check("guarded division", $divisor != 0 && 10 / $divisor > 1);
+
+check("and skips", loud(0) && loud(1));
+check("or skips", loud(1) || loud(0));
+check("or falls through", loud(0) || loud("yes"));
+
+check("keyword and", true and loud(1));
+check("keyword or", false or loud(0));
+check("xor", true xor false);
+check("xor both", true xor true);
+
+// and, xor and or bind more loosely than && and ||, and than each other.
+check("precedence", true or false and false);
+check("mixed", false and true xor true);
+
+$items = ['a' => 1];
+check("isset guard", isset($items['b']) && $items['b'] > 0);
+
+$i = 0;
+while ($i < 100 && $i * $i < 50) {
+    $i++;
+}
+echo $i . "\n";
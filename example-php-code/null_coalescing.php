@@ -0,0 +1,57 @@
+<?php
+
+function label($value) {
+    if ($value === null) {
+        return "null";
+    }
+    return is_bool($value) ? ($value ? "true" : "false") : "" . $value;
+}
+
+$config = ['name' => 'app', 'debug' => null, 'db' => ['host' => 'localhost']];
+
+echo $config['name'] ?? "unnamed";
+echo "\n";
+echo $config['debug'] ?? "debug off";
+echo "\n";
+echo $config['db']['port'] ?? 5432;
+echo "\n";
+echo $missing ?? $config['db']['host'] ?? "nowhere";
+echo "\n";
+
+$count = 0;
+echo ($count ?: "none") . "\n";
+echo $count > 0 ? "some\n" : "empty\n";
+
+echo label(isset($config['name'])) . " " . label(isset($config['debug'])) . " " . label(isset($config['name'], $config['db']['host'])) . "\n";
+echo label(empty($config['debug'])) . " " . label(empty($config['name'])) . " " . label(empty($nothing)) . "\n";
+
+$config['timeout'] ??= 30;
+$config['timeout'] ??= 60;
+$config['db']['user'] ??= "root";
+echo $config['timeout'] . " " . $config['db']['user'] . "\n";
+
+class Settings {
+    public $theme = null;
+    private $secret = "hidden";
+
+    function hasSecret() {
+        return isset($this->secret);
+    }
+}
+
+$settings = new Settings();
+$settings->theme ??= "dark";
+echo $settings->theme . "\n";
+echo label(isset($settings->secret)) . " " . label($settings->hasSecret()) . "\n";
+
+unset($settings->theme);
+echo $settings->theme ?? "no theme";
+echo "\n";
+
+unset($config['debug'], $config['db']['host']);
+$config[] = "appended";
+echo implode(",", array_keys($config)) . "\n";
+echo implode(",", array_keys($config['db'])) . "\n";
+
+unset($count);
+echo label(isset($count)) . "\n";
@@ -0,0 +1,41 @@
+<?php
+
+$flags = 0;
+$read = 1 << 0;
+$write = 1 << 1;
+$exec = 1 << 2;
+
+$flags = $flags | $read | $exec;
+echo "flags: " . $flags . "\n";
+echo "can write: " . (($flags & $write) != 0 ? "yes" : "no") . "\n";
+echo "toggled: " . ($flags ^ $write) . "\n";
+echo "mask: " . (~$flags & 7) . "\n";
+echo "halved: " . (64 >> 1) . "\n";
+echo "remainder: " . 17 % 5 . "\n";
+
+// + and - bind more tightly than ., and * more tightly than +.
+echo "sum: " . 1 + 2 * 3 . "\n";
+echo "shift: " . (1 << 2 + 1) . "\n";
+
+// Comparisons bind more tightly than && and ||, which short-circuit.
+$n = 7;
+echo ($n > 5 && $n % 2 == 1 ? "odd and big" : "other") . "\n";
+echo (!($n < 0) || $n / 0 ? "guarded" : "unreachable") . "\n";
+
+echo (null ?? 0 ?: "fallback") . "\n";
+
+// ** binds more tightly than the unary minus and is right associative.
+echo "powers: " . 2 ** 10 . " " . 2 ** 3 ** 2 . " " . -2 ** 2 . " " . (-2) ** 2 . "\n";
+echo "fractions: " . 2 ** -2 . " " . 9 ** 0.5 . "\n";
+echo "overflow: " . 2 ** 63 . "\n";
+
+$balance = 100;
+$balance -= 250;
+echo "balance: " . $balance . " " . -$balance . " " . +"42" . "\n";
+
+$area = 3;
+$area **= 2;
+echo "area: " . $area . ", per side: " . intdiv($area, 2) . " r " . $area % 2 . "\n";
+
+// <=> compares like < and >, and gives -1, 0 or 1.
+echo "spaceship: " . (1 <=> 2) . " " . ("b" <=> "a") . " " . ([1, 2] <=> [1, 2]) . "\n";
@@ -0,0 +1,20 @@
+<?php
+function fibonacci($n) {
+    if ($n < 2) {
+        return $n;
+    }
+    return fibonacci($n - 1) + fibonacci($n - 2);
+}
+
+function factorial($n) {
+    if ($n <= 1) {
+        return 1;
+    }
+    return $n * factorial($n - 1);
+}
+
+$i = 0;
+while ($i <= 10) {
+    echo "fibonacci(" . $i . ") = " . fibonacci($i) . ", factorial(" . $i . ") = " . factorial($i) . "\n";
+    $i++;
+}
@@ -0,0 +1,22 @@
+<?php
+echo 'Single quotes keep $vars and \n as written, only \' and \\ are escapes' . "\n";
+echo "Double quotes: tab[\t] hex[\x41] octal[\102] unicode[\u{263A}]\n";
+
+$text = <<<EOT
+    Heredoc lines lose the indentation
+    of the closing marker, "quotes" need no escaping.
+    EOT;
+echo $text . "\n";
+
+echo <<<'EOT'
+Nowdoc is raw: \x41 \n
+EOT;
+echo "\n";
+
+$name = "PHP";
+$version = 8;
+echo "Hello $name {$version}.x, braces: ${name}\n";
+echo <<<EOT
+    Heredocs interpolate too: $name
+    EOT;
+echo "\n";
@@ -3,57 +3,199 @@ package ast
 
 import "github.com/neokofg/php-compiler/internal/token"
 
-type Expr interface{}
+type Expr interface {
+	Node
+}
 
 type NumberLiteral struct {
-	Value int
+	Span
+	Value int64
+}
+
+type FloatLiteral struct {
+	Span
+	Value float64
 }
 
 type StringLiteral struct {
+	Span
 	Value string
 }
 
+// InterpolatedString is a double-quoted string or heredoc with embedded
+// variables. Parts holds its literal text as StringLiterals and the
+// embedded expressions, in source order.
+type InterpolatedString struct {
+	Span
+	Parts []Expr
+}
+
+// ArrayLiteral is [a, 'k' => b] or array(a, 'k' => b).
+type ArrayLiteral struct {
+	Span
+	Items []ArrayItem
+}
+
+// ArrayItem is an element of an array literal. Key is nil when the element
+// gets the next integer key.
+type ArrayItem struct {
+	Span
+	Key   Expr
+	Value Expr
+}
+
+// IndexExpr is $base[index]. Index is nil for $base[], which is only
+// allowed as an assignment target.
+type IndexExpr struct {
+	Span
+	Base  Expr
+	Index Expr
+}
+
 type VarExpr struct {
+	Span
 	Name string
 }
 
 type BinaryExpr struct {
+	Span
 	Left  Expr
 	Op    token.TokenType
 	Right Expr
 }
 
 type UnaryExpr struct {
+	Span
 	Op   token.TokenType
 	Expr Expr
 }
 
 type BooleanLiteral struct {
+	Span
 	Value bool
 }
 
-type PostfixExpr struct {
+type NullLiteral struct {
+	Span
+}
+
+// TernaryExpr is cond ? then : else, or cond ?: else when Then is nil.
+type TernaryExpr struct {
+	Span
+	Cond Expr
+	Then Expr
+	Else Expr
+}
+
+// IssetExpr is isset(vars), true when every var is set and not null.
+type IssetExpr struct {
+	Span
+	Vars []Expr
+}
+
+// EmptyExpr is empty(expr), true when expr is unset or falsy.
+type EmptyExpr struct {
+	Span
 	Expr Expr
-	Op   token.TokenType
 }
 
-type PrefixExpr struct {
-	Op   token.TokenType
+// PostfixExpr is $x++ or $x--. Expr is a variable, an array element or a
+// property, like the target of an AssignExpr.
+type PostfixExpr struct {
+	Span
 	Expr Expr
+	Op   token.TokenType
 }
 
-type CompoundAssignStmt struct {
-	Name string
+// PrefixExpr is ++$x or --$x.
+type PrefixExpr struct {
+	Span
 	Op   token.TokenType
 	Expr Expr
 }
 
+// AssignExpr is target = expr, or a compound assignment such as
+// target += expr when Op is not T_EQ. Target is a VarExpr, a PropertyFetch
+// or an IndexExpr of one of them, where a nil index appends, as in
+// $name[] = expr.
 type AssignExpr struct {
-	Name string
-	Expr Expr
+	Span
+	Target Expr
+	Op     token.TokenType
+	Expr   Expr
 }
 
 type FunctionCall struct {
+	Span
 	Name string
 	Args []Expr
 }
+
+// NewExpr is new Class(args). Args is empty when the parentheses are left
+// out. Class may be self, parent or static.
+type NewExpr struct {
+	Span
+	Class string
+	Args  []Expr
+}
+
+// InstanceofExpr is expr instanceof Class. Class may be self, parent or
+// static.
+type InstanceofExpr struct {
+	Span
+	Expr  Expr
+	Class string
+}
+
+// PropertyFetch is $object->name.
+type PropertyFetch struct {
+	Span
+	Object Expr
+	Name   string
+}
+
+// MethodCall is $object->name(args).
+type MethodCall struct {
+	Span
+	Object Expr
+	Name   string
+	Args   []Expr
+}
+
+// StaticCall is Class::name(args). Class may be self, parent or static.
+type StaticCall struct {
+	Span
+	Class string
+	Name  string
+	Args  []Expr
+}
+
+// ClosureExpr is function (params) use (vars) { body }.
+type ClosureExpr struct {
+	Span
+	Params []string
+	Uses   []ClosureUse
+	Body   []Stmt
+}
+
+// ClosureUse is a variable a closure captures: by value when the closure
+// is created, or by reference for use (&$name).
+type ClosureUse struct {
+	Name  string
+	ByRef bool
+}
+
+// ArrowFunction is fn(params) => expr. It captures by value every
+// variable of the enclosing scope that expr uses.
+type ArrowFunction struct {
+	Span
+	Params []string
+	Expr   Expr
+}
+
+// CallExpr calls the value of Callee, such as $f(args) or $obj->f[0](args).
+type CallExpr struct {
+	Span
+	Callee Expr
+	Args   []Expr
+}
@@ -0,0 +1,29 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package ast
+
+import "github.com/neokofg/php-compiler/internal/token"
+
+// Node is implemented by every expression and statement.
+// Pos is the first character of the node, End is right after the last one.
+type Node interface {
+	Pos() token.Position
+	End() token.Position
+}
+
+// Span is embedded into every node to record where it came from in the source.
+type Span struct {
+	StartPos token.Position
+	EndPos   token.Position
+}
+
+func NewSpan(start, end token.Position) Span {
+	return Span{StartPos: start, EndPos: end}
+}
+
+func (s Span) Pos() token.Position {
+	return s.StartPos
+}
+
+func (s Span) End() token.Position {
+	return s.EndPos
+}
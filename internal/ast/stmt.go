@@ -1,65 +1,203 @@
 // Licensed under GNU GPL v3. See LICENSE file for details.
 package ast
 
-type Stmt interface{}
+import "github.com/neokofg/php-compiler/internal/token"
 
-type AssignStmt struct {
-	Name string
-	Expr Expr
+type Stmt interface {
+	Node
 }
 
 type EchoStmt struct {
+	Span
 	Expr Expr
 }
 
 type IfStmt struct {
+	Span
 	Cond Expr
 	Then []Stmt
 	Else []Stmt
 }
 
 type WhileStmt struct {
+	Span
 	Cond Expr
 	Body []Stmt
 }
 
 type ForStmt struct {
+	Span
 	Init Expr
 	Cond Expr
 	Incr Expr
 	Body []Stmt
 }
 
-type BreakStmt struct{}
+// ForeachStmt is foreach (Expr as $Key => $Value). Key is empty when the
+// loop has no key variable; ByRef is set for &$Value.
+type ForeachStmt struct {
+	Span
+	Expr  Expr
+	Key   string
+	Value string
+	ByRef bool
+	Body  []Stmt
+}
+
+type BreakStmt struct {
+	Span
+}
 
-type ContinueStmt struct{}
+type ContinueStmt struct {
+	Span
+}
 
 type DoWhileStmt struct {
+	Span
 	Body []Stmt
 	Cond Expr
 }
 
 type SwitchStmt struct {
+	Span
 	Expr  Expr
 	Cases []CaseStmt
 }
 
 type CaseStmt struct {
+	Span
 	Expr  Expr
 	Stmts []Stmt
 }
 
 type FunctionDecl struct {
-	Name      string
-	Params    []string
-	Body      []Stmt
-	StartAddr int
+	Span
+	Name        string
+	Params      []string
+	Body        []Stmt
+	StartAddr   int
+	Conditional bool // declared in a block or a function body, defined when the declaration runs
 }
 
 type ReturnStmt struct {
+	Span
 	Expr Expr
 }
 
+type GlobalStmt struct {
+	Span
+	Names []string
+}
+
+type StaticStmt struct {
+	Span
+	Vars []StaticVar
+}
+
+type StaticVar struct {
+	Span
+	Name string
+	Init Expr
+}
+
 type FunctionCallStmt struct {
+	Span
 	Call *FunctionCall
 }
+
+// ExprStmt is an expression evaluated for its side effects, such as a
+// method call or new Foo().
+type ExprStmt struct {
+	Span
+	Expr Expr
+}
+
+// ClassDecl is a class, an interface or a trait with its members in source
+// order. Kind is T_CLASS, T_INTERFACE or T_TRAIT. Parent is the class named
+// after extends, empty when there is none. Interfaces lists the interfaces a
+// class implements, or the ones an interface extends.
+type ClassDecl struct {
+	Span
+	Kind       token.TokenType
+	Name       string
+	Abstract   bool
+	Final      bool
+	Parent     string
+	Interfaces []string
+	Traits     []TraitUse
+	Properties []PropertyDecl
+	Methods    []MethodDecl
+}
+
+// TraitUse is a use statement in a class body: use A, B { rules }.
+type TraitUse struct {
+	Span
+	Traits []string
+	Rules  []TraitRule
+}
+
+// TraitRule is one conflict resolution rule of a TraitUse. With InsteadOf
+// set it is Trait::Method insteadof InsteadOf; otherwise it is
+// [Trait::]Method as [Visibility] [Alias], where Visibility is 0 when the
+// rule keeps the visibility of the method and Alias is empty when it only
+// changes the visibility.
+type TraitRule struct {
+	Span
+	Trait      string
+	Method     string
+	InsteadOf  []string
+	Alias      string
+	Visibility token.TokenType
+}
+
+// PropertyDecl is a property of a class. Visibility is T_PUBLIC,
+// T_PROTECTED or T_PRIVATE; Default is nil when the property starts as null.
+type PropertyDecl struct {
+	Span
+	Name       string
+	Visibility token.TokenType
+	Default    Expr
+}
+
+// MethodDecl is a method: a function that receives the object as $this,
+// unless it is static. HasBody is false for abstract and interface methods,
+// which end with a semicolon.
+type MethodDecl struct {
+	Span
+	Visibility token.TokenType
+	Static     bool
+	Abstract   bool
+	Final      bool
+	HasBody    bool
+	Function   *FunctionDecl
+}
+
+// UnsetStmt is unset(vars). Each var is a variable, an array element or
+// a property.
+type UnsetStmt struct {
+	Span
+	Vars []Expr
+}
+
+type ThrowStmt struct {
+	Span
+	Expr Expr
+}
+
+// TryStmt is try { } with its catch clauses and an optional finally block.
+// Finally is nil when there is no finally block.
+type TryStmt struct {
+	Span
+	Body    []Stmt
+	Catches []CatchClause
+	Finally []Stmt
+}
+
+// CatchClause catches the exceptions that are instances of one of Types,
+// binding them to Var unless it is empty.
+type CatchClause struct {
+	Span
+	Types []string
+	Var   string
+	Body  []Stmt
+}
@@ -0,0 +1,128 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package ast
+
+// Blocks returns the statement lists nested in stmt: the bodies of its
+// branches, loops, functions and methods, and those of the closures in its
+// expressions. Only the blocks run by stmt itself are returned, the ones
+// nested deeper are found by calling Blocks on their statements.
+func Blocks(stmt Stmt) [][]Stmt {
+	var blocks [][]Stmt
+	closures := func(exprs ...Expr) {
+		for _, expr := range exprs {
+			blocks = closureBodies(blocks, expr)
+		}
+	}
+
+	switch s := stmt.(type) {
+	case *EchoStmt:
+		closures(s.Expr)
+	case *IfStmt:
+		closures(s.Cond)
+		blocks = append(blocks, s.Then, s.Else)
+	case *WhileStmt:
+		closures(s.Cond)
+		blocks = append(blocks, s.Body)
+	case *ForStmt:
+		closures(s.Init, s.Cond, s.Incr)
+		blocks = append(blocks, s.Body)
+	case *ForeachStmt:
+		closures(s.Expr)
+		blocks = append(blocks, s.Body)
+	case *DoWhileStmt:
+		blocks = append(blocks, s.Body)
+		closures(s.Cond)
+	case *SwitchStmt:
+		closures(s.Expr)
+		for _, c := range s.Cases {
+			closures(c.Expr)
+			blocks = append(blocks, c.Stmts)
+		}
+	case *FunctionDecl:
+		blocks = append(blocks, s.Body)
+	case *ReturnStmt:
+		closures(s.Expr)
+	case *StaticStmt:
+		for _, v := range s.Vars {
+			closures(v.Init)
+		}
+	case *FunctionCallStmt:
+		closures(s.Call)
+	case *ExprStmt:
+		closures(s.Expr)
+	case *ClassDecl:
+		for _, p := range s.Properties {
+			closures(p.Default)
+		}
+		for _, method := range s.Methods {
+			blocks = append(blocks, method.Function.Body)
+		}
+	case *UnsetStmt:
+		closures(s.Vars...)
+	case *ThrowStmt:
+		closures(s.Expr)
+	case *TryStmt:
+		blocks = append(blocks, s.Body, s.Finally)
+		for _, c := range s.Catches {
+			blocks = append(blocks, c.Body)
+		}
+	}
+	return blocks
+}
+
+// closureBodies appends to blocks the bodies of the closures in expr. It
+// does not look inside those bodies.
+func closureBodies(blocks [][]Stmt, expr Expr) [][]Stmt {
+	walk := func(exprs ...Expr) {
+		for _, e := range exprs {
+			blocks = closureBodies(blocks, e)
+		}
+	}
+
+	switch e := expr.(type) {
+	case *ClosureExpr:
+		blocks = append(blocks, e.Body)
+	case *ArrowFunction:
+		walk(e.Expr)
+	case *InterpolatedString:
+		walk(e.Parts...)
+	case *ArrayLiteral:
+		for _, item := range e.Items {
+			walk(item.Key, item.Value)
+		}
+	case *IndexExpr:
+		walk(e.Base, e.Index)
+	case *BinaryExpr:
+		walk(e.Left, e.Right)
+	case *UnaryExpr:
+		walk(e.Expr)
+	case *PostfixExpr:
+		walk(e.Expr)
+	case *PrefixExpr:
+		walk(e.Expr)
+	case *TernaryExpr:
+		walk(e.Cond, e.Then, e.Else)
+	case *IssetExpr:
+		walk(e.Vars...)
+	case *EmptyExpr:
+		walk(e.Expr)
+	case *AssignExpr:
+		walk(e.Target, e.Expr)
+	case *FunctionCall:
+		walk(e.Args...)
+	case *NewExpr:
+		walk(e.Args...)
+	case *InstanceofExpr:
+		walk(e.Expr)
+	case *PropertyFetch:
+		walk(e.Object)
+	case *MethodCall:
+		walk(e.Object)
+		walk(e.Args...)
+	case *StaticCall:
+		walk(e.Args...)
+	case *CallExpr:
+		walk(e.Callee)
+		walk(e.Args...)
+	}
+	return blocks
+}
@@ -1,14 +1,20 @@
 // Licensed under GNU GPL v3. See LICENSE file for details.
 package bytecode
 
+import "math"
+
 type BytecodeBuilder struct {
-	code     []byte
-	onUpdate func([]byte)
+	code       []byte
+	handlers   []Handler
+	lines      []Line
+	outOfRange int // position of the first 16-bit operand that did not fit, or -1
+	onUpdate   func([]byte)
 }
 
 func NewBytecodeBuilder() *BytecodeBuilder {
 	return &BytecodeBuilder{
-		code: make([]byte, 0, 64),
+		code:       make([]byte, 0, 64),
+		outOfRange: -1,
 	}
 }
 
@@ -17,27 +23,52 @@ func (b *BytecodeBuilder) Append(value byte) {
 	b.notifyUpdate()
 }
 
-func (b *BytecodeBuilder) AppendUint16(value uint16) {
-	lowByte := byte(value & 0xFF)
-	highByte := byte(value >> 8)
-	b.code = append(b.code, lowByte, highByte)
-	b.notifyUpdate()
+// AppendUint16 appends an unsigned operand: an address or a forward jump.
+func (b *BytecodeBuilder) AppendUint16(value int) {
+	b.code = append(b.code, 0, 0)
+	b.PatchUint16(len(b.code)-2, value)
+}
+
+// AppendInt16 appends a signed operand: the offset of a JUMP.
+func (b *BytecodeBuilder) AppendInt16(value int) {
+	b.code = append(b.code, 0, 0)
+	b.PatchInt16(len(b.code)-2, value)
+}
+
+// PatchUint16 overwrites the unsigned operand at position. A value that
+// does not fit is recorded, see OutOfRange, instead of being wrapped.
+func (b *BytecodeBuilder) PatchUint16(position int, value int) {
+	b.patch16(position, value, value >= 0 && value <= math.MaxUint16)
 }
 
-func (b *BytecodeBuilder) AppendInt16(value int16) {
-	b.AppendUint16(uint16(value))
+// PatchInt16 overwrites the signed operand at position.
+func (b *BytecodeBuilder) PatchInt16(position int, value int) {
+	b.patch16(position, value, value >= math.MinInt16 && value <= math.MaxInt16)
 }
 
-func (b *BytecodeBuilder) PatchUint16(position int, value uint16) {
+func (b *BytecodeBuilder) patch16(position int, value int, fits bool) {
 	if position+1 >= len(b.code) {
 		panic("patch position out of bounds")
 	}
 
+	if !fits {
+		if b.outOfRange < 0 {
+			b.outOfRange = position
+		}
+		value = 0
+	}
+
 	b.code[position] = byte(value & 0xFF)
-	b.code[position+1] = byte(value >> 8)
+	b.code[position+1] = byte(value >> 8 & 0xFF)
 	b.notifyUpdate()
 }
 
+// OutOfRange reports the position of the first address or jump offset
+// that was too far to encode. The bytecode is unusable when there is one.
+func (b *BytecodeBuilder) OutOfRange() (int, bool) {
+	return b.outOfRange, b.outOfRange >= 0
+}
+
 func (b *BytecodeBuilder) CurrentPosition() int {
 	return len(b.code)
 }
@@ -0,0 +1,160 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package bytecode
+
+type OperandKind int
+
+const (
+	OperandConst       OperandKind = iota // 1-byte constant pool index
+	OperandVar                            // 1-byte variable index
+	OperandJump                           // int16 offset relative to the next instruction
+	OperandJumpForward                    // uint16 offset relative to the next instruction
+	OperandArgCount                       // 1-byte argument count
+	OperandAddr                           // uint16 absolute bytecode address
+	OperandParams                         // 1-byte count followed by that many local slots
+	OperandLocal                          // 1-byte local slot of the current call frame
+	OperandDims                           // 1-byte number of array dimensions
+	OperandAppendMask                     // 1-byte mask of the dimensions written with []
+	OperandByRef                          // 1-byte flag, 1 for a foreach by reference
+	OperandModifiers                      // 1-byte member modifiers, see MemberPublic
+	OperandClassFlags                     // 1-byte class flags, see ClassAbstract
+	OperandCaptures                       // 1-byte number of values a closure captures
+	OperandDepth                          // 1-byte number of stack values
+)
+
+type OpInfo struct {
+	Name     string
+	Operands []OperandKind
+}
+
+var opInfos = map[byte]OpInfo{
+	OP_LOAD_CONST: {"LOAD_CONST", []OperandKind{OperandConst}},
+	OP_PRINT:      {"PRINT", nil},
+	OP_HALT:       {"HALT", nil},
+	OP_POP:        {"POP", nil},
+	OP_DUP:        {"DUP", nil},
+	OP_TUCK:       {"TUCK", []OperandKind{OperandDepth}},
+
+	OP_ADD: {"ADD", nil},
+	OP_SUB: {"SUB", nil},
+	OP_MUL: {"MUL", nil},
+	OP_DIV: {"DIV", nil},
+
+	OP_CONCAT: {"CONCAT", nil},
+
+	OP_STORE_VAR:   {"STORE_VAR", []OperandKind{OperandVar}},
+	OP_LOAD_VAR:    {"LOAD_VAR", []OperandKind{OperandVar}},
+	OP_STORE_LOCAL: {"STORE_LOCAL", []OperandKind{OperandLocal}},
+	OP_LOAD_LOCAL:  {"LOAD_LOCAL", []OperandKind{OperandLocal}},
+	OP_STATIC_INIT: {"STATIC_INIT", []OperandKind{OperandVar, OperandJumpForward}},
+	OP_LOAD_NULL:   {"LOAD_NULL", nil},
+	OP_REF_VAR:     {"REF_VAR", []OperandKind{OperandVar}},
+	OP_REF_LOCAL:   {"REF_LOCAL", []OperandKind{OperandLocal}},
+	OP_UNSET_VAR:   {"UNSET_VAR", []OperandKind{OperandVar}},
+	OP_UNSET_LOCAL: {"UNSET_LOCAL", []OperandKind{OperandLocal}},
+	OP_BIND_VAR:    {"BIND_VAR", []OperandKind{OperandVar}},
+	OP_BIND_LOCAL:  {"BIND_LOCAL", []OperandKind{OperandLocal}},
+
+	OP_LOAD_VAR_QUIET:   {"LOAD_VAR_QUIET", []OperandKind{OperandVar}},
+	OP_LOAD_LOCAL_QUIET: {"LOAD_LOCAL_QUIET", []OperandKind{OperandLocal}},
+
+	OP_JUMP:          {"JUMP", []OperandKind{OperandJump}},
+	OP_JUMP_IF_FALSE: {"JUMP_IF_FALSE", []OperandKind{OperandJumpForward}},
+	OP_COALESCE:      {"COALESCE", []OperandKind{OperandJumpForward}},
+
+	OP_GT:  {"GT", nil},
+	OP_LT:  {"LT", nil},
+	OP_EQ:  {"EQ", nil},
+	OP_NOT: {"NOT", nil},
+
+	OP_AND: {"AND", nil},
+	OP_OR:  {"OR", nil},
+
+	OP_INC:      {"INC", nil},
+	OP_DEC:      {"DEC", nil},
+	OP_POST_INC: {"POST_INC", nil},
+	OP_POST_DEC: {"POST_DEC", nil},
+
+	OP_MOD: {"MOD", nil},
+	OP_POW: {"POW", nil},
+
+	OP_BIT_AND: {"BIT_AND", nil},
+	OP_BIT_OR:  {"BIT_OR", nil},
+	OP_BIT_XOR: {"BIT_XOR", nil},
+	OP_BIT_NOT: {"BIT_NOT", nil},
+	OP_LSHIFT:  {"LSHIFT", nil},
+	OP_RSHIFT:  {"RSHIFT", nil},
+
+	OP_GTE:         {"GTE", nil},
+	OP_LTE:         {"LTE", nil},
+	OP_IDENTITY_EQ: {"IDENTITY_EQ", nil},
+	OP_IDENTITY_NE: {"IDENTITY_NE", nil},
+	OP_SPACESHIP:   {"SPACESHIP", nil},
+
+	OP_ASSIGN_ADD:     {"ASSIGN_ADD", nil},
+	OP_ASSIGN_SUB:     {"ASSIGN_SUB", nil},
+	OP_ASSIGN_MUL:     {"ASSIGN_MUL", nil},
+	OP_ASSIGN_DIV:     {"ASSIGN_DIV", nil},
+	OP_ASSIGN_MOD:     {"ASSIGN_MOD", nil},
+	OP_ASSIGN_CONCAT:  {"ASSIGN_CONCAT", nil},
+	OP_ASSIGN_POW:     {"ASSIGN_POW", nil},
+	OP_ASSIGN_BIT_AND: {"ASSIGN_BIT_AND", nil},
+	OP_ASSIGN_BIT_OR:  {"ASSIGN_BIT_OR", nil},
+	OP_ASSIGN_BIT_XOR: {"ASSIGN_BIT_XOR", nil},
+	OP_ASSIGN_LSHIFT:  {"ASSIGN_LSHIFT", nil},
+	OP_ASSIGN_RSHIFT:  {"ASSIGN_RSHIFT", nil},
+
+	OP_BREAK:    {"BREAK", []OperandKind{OperandJump}},
+	OP_CONTINUE: {"CONTINUE", []OperandKind{OperandJump}},
+
+	OP_FUNC_DECL:   {"FUNC_DECL", []OperandKind{OperandParams}},
+	OP_FUNC_CALL:   {"FUNC_CALL", []OperandKind{OperandArgCount, OperandAddr}},
+	OP_RETURN:      {"RETURN", nil},
+	OP_ENTER_FUNC:  {"ENTER_FUNC", nil},
+	OP_EXIT_FUNC:   {"EXIT_FUNC", nil},
+	OP_CALL_NATIVE: {"CALL_NATIVE", []OperandKind{OperandConst, OperandArgCount}},
+
+	OP_CLOSURE:       {"CLOSURE", []OperandKind{OperandAddr, OperandCaptures}},
+	OP_CALL_INDIRECT: {"CALL_INDIRECT", []OperandKind{OperandArgCount}},
+	OP_FUNC_BIND:     {"FUNC_BIND", []OperandKind{OperandConst, OperandAddr}},
+	OP_CALL_NAMED:    {"CALL_NAMED", []OperandKind{OperandConst, OperandArgCount}},
+
+	OP_ARRAY_NEW:    {"ARRAY_NEW", nil},
+	OP_ARRAY_PUSH:   {"ARRAY_PUSH", nil},
+	OP_ARRAY_INSERT: {"ARRAY_INSERT", nil},
+	OP_ARRAY_GET:    {"ARRAY_GET", nil},
+	OP_ARRAY_SET:    {"ARRAY_SET", []OperandKind{OperandDims, OperandAppendMask}},
+	OP_ARRAY_FETCH:  {"ARRAY_FETCH", []OperandKind{OperandDims}},
+
+	OP_ARRAY_GET_QUIET:   {"ARRAY_GET_QUIET", nil},
+	OP_ARRAY_FETCH_QUIET: {"ARRAY_FETCH_QUIET", []OperandKind{OperandDims}},
+	OP_ARRAY_UNSET:       {"ARRAY_UNSET", []OperandKind{OperandDims}},
+
+	OP_FOREACH_INIT: {"FOREACH_INIT", []OperandKind{OperandByRef}},
+	OP_FOREACH_NEXT: {"FOREACH_NEXT", []OperandKind{OperandByRef, OperandJumpForward}},
+	OP_FOREACH_END:  {"FOREACH_END", []OperandKind{OperandByRef}},
+
+	OP_CLASS_DECL:   {"CLASS_DECL", []OperandKind{OperandConst, OperandClassFlags}},
+	OP_CLASS_PROP:   {"CLASS_PROP", []OperandKind{OperandConst, OperandModifiers}},
+	OP_CLASS_METHOD: {"CLASS_METHOD", []OperandKind{OperandConst, OperandModifiers, OperandAddr}},
+	OP_NEW:          {"NEW", []OperandKind{OperandConst, OperandArgCount}},
+	OP_PROP_GET:     {"PROP_GET", []OperandKind{OperandConst}},
+	OP_PROP_SET:     {"PROP_SET", []OperandKind{OperandConst}},
+	OP_METHOD_CALL:  {"METHOD_CALL", []OperandKind{OperandConst, OperandArgCount}},
+
+	OP_CLASS_EXTENDS:    {"CLASS_EXTENDS", []OperandKind{OperandConst}},
+	OP_CLASS_IMPLEMENTS: {"CLASS_IMPLEMENTS", []OperandKind{OperandConst}},
+	OP_STATIC_CALL:      {"STATIC_CALL", []OperandKind{OperandConst, OperandConst, OperandArgCount}},
+
+	OP_PROP_GET_QUIET: {"PROP_GET_QUIET", []OperandKind{OperandConst}},
+	OP_PROP_UNSET:     {"PROP_UNSET", []OperandKind{OperandConst}},
+	OP_INSTANCEOF:     {"INSTANCEOF", []OperandKind{OperandConst}},
+
+	OP_THROW: {"THROW", nil},
+	OP_CATCH: {"CATCH", []OperandKind{OperandConst, OperandJumpForward}},
+}
+
+// Lookup returns the name and operand layout of an opcode.
+func Lookup(opcode byte) (OpInfo, bool) {
+	info, ok := opInfos[opcode]
+	return info, ok
+}
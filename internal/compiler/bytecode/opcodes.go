@@ -6,6 +6,8 @@ const (
 	OP_PRINT      = 0x02
 	OP_HALT       = 0xFF
 	OP_POP        = 0x0C
+	OP_DUP        = 0x0E
+	OP_TUCK       = 0x1A
 
 	OP_ADD = 0x03
 	OP_SUB = 0x04
@@ -14,11 +16,25 @@ const (
 
 	OP_CONCAT = 0x0F
 
-	OP_STORE_VAR = 0x10
-	OP_LOAD_VAR  = 0x11
+	OP_STORE_VAR   = 0x10
+	OP_LOAD_VAR    = 0x11
+	OP_STORE_LOCAL = 0x12
+	OP_LOAD_LOCAL  = 0x13
+	OP_STATIC_INIT = 0x14
+	OP_LOAD_NULL   = 0x15
+	OP_REF_VAR     = 0x16
+	OP_REF_LOCAL   = 0x17
+	OP_UNSET_VAR   = 0x18
+	OP_UNSET_LOCAL = 0x19
+	OP_BIND_VAR    = 0x1B
+	OP_BIND_LOCAL  = 0x1C
+
+	OP_LOAD_VAR_QUIET   = 0x1D
+	OP_LOAD_LOCAL_QUIET = 0x1E
 
 	OP_JUMP          = 0x21
 	OP_JUMP_IF_FALSE = 0x20
+	OP_COALESCE      = 0x22
 
 	OP_GT  = 0x07
 	OP_LT  = 0x08
@@ -34,6 +50,7 @@ const (
 	OP_POST_DEC = 0x33
 
 	OP_MOD = 0x34
+	OP_POW = 0x35
 
 	OP_BIT_AND = 0x40
 	OP_BIT_OR  = 0x41
@@ -46,20 +63,92 @@ const (
 	OP_LTE         = 0x51
 	OP_IDENTITY_EQ = 0x52
 	OP_IDENTITY_NE = 0x53
+	OP_SPACESHIP   = 0x54
 
-	OP_ASSIGN_ADD    = 0x60
-	OP_ASSIGN_SUB    = 0x61
-	OP_ASSIGN_MUL    = 0x62
-	OP_ASSIGN_DIV    = 0x63
-	OP_ASSIGN_MOD    = 0x64
-	OP_ASSIGN_CONCAT = 0x65
+	OP_ASSIGN_ADD     = 0x60
+	OP_ASSIGN_SUB     = 0x61
+	OP_ASSIGN_MUL     = 0x62
+	OP_ASSIGN_DIV     = 0x63
+	OP_ASSIGN_MOD     = 0x64
+	OP_ASSIGN_CONCAT  = 0x65
+	OP_ASSIGN_POW     = 0x66
+	OP_ASSIGN_BIT_AND = 0x67
+	OP_ASSIGN_BIT_OR  = 0x68
+	OP_ASSIGN_BIT_XOR = 0x69
+	OP_ASSIGN_LSHIFT  = 0x6A
+	OP_ASSIGN_RSHIFT  = 0x6B
 
 	OP_BREAK    = 0x70
 	OP_CONTINUE = 0x71
 
-	OP_FUNC_DECL  = 0x80
-	OP_FUNC_CALL  = 0x81
-	OP_RETURN     = 0x82
-	OP_ENTER_FUNC = 0x83
-	OP_EXIT_FUNC  = 0x84
+	OP_FUNC_DECL   = 0x80
+	OP_FUNC_CALL   = 0x81
+	OP_RETURN      = 0x82
+	OP_ENTER_FUNC  = 0x83
+	OP_EXIT_FUNC   = 0x84
+	OP_CALL_NATIVE = 0x85
+
+	OP_CLOSURE       = 0x86
+	OP_CALL_INDIRECT = 0x87
+	OP_FUNC_BIND     = 0x88
+	OP_CALL_NAMED    = 0x89
+
+	OP_ARRAY_NEW    = 0x90
+	OP_ARRAY_PUSH   = 0x91
+	OP_ARRAY_INSERT = 0x92
+	OP_ARRAY_GET    = 0x93
+	OP_ARRAY_SET    = 0x94
+	OP_ARRAY_FETCH  = 0x95
+
+	OP_ARRAY_GET_QUIET   = 0x9A
+	OP_ARRAY_FETCH_QUIET = 0x9B
+	OP_ARRAY_UNSET       = 0x9C
+
+	OP_FOREACH_INIT = 0x96
+	OP_FOREACH_NEXT = 0x97
+	OP_FOREACH_END  = 0x99
+
+	OP_CLASS_DECL   = 0xA0
+	OP_CLASS_PROP   = 0xA1
+	OP_CLASS_METHOD = 0xA2
+	OP_NEW          = 0xA3
+	OP_PROP_GET     = 0xA4
+	OP_PROP_SET     = 0xA5
+	OP_METHOD_CALL  = 0xA6
+
+	OP_CLASS_EXTENDS    = 0xA7
+	OP_CLASS_IMPLEMENTS = 0xA8
+	OP_STATIC_CALL      = 0xA9
+
+	OP_PROP_GET_QUIET = 0xAA
+	OP_PROP_UNSET     = 0xAB
+	OP_INSTANCEOF     = 0xAC
+
+	OP_THROW = 0xB0
+	OP_CATCH = 0xB1
+)
+
+// MaxArrayDims is the number of dimensions an array instruction can
+// address: the append mask of ARRAY_SET has one bit per dimension.
+const MaxArrayDims = 8
+
+// Member modifiers, the operand of CLASS_PROP and CLASS_METHOD. The low
+// two bits hold the visibility.
+const (
+	MemberPublic    = 0x00
+	MemberProtected = 0x01
+	MemberPrivate   = 0x02
+
+	MemberVisibilityMask = 0x03
+
+	MemberStatic   = 0x04
+	MemberAbstract = 0x08 // declared without a body; the address is 0
+)
+
+// Class flags, the operand of CLASS_DECL.
+const (
+	ClassAbstract  = 0x01
+	ClassFinal     = 0x02
+	ClassInterface = 0x04
+	ClassTrait     = 0x08
 )
@@ -0,0 +1,70 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package bytecode
+
+import "sort"
+
+// MainFunction is the Function of handlers that belong to the top-level
+// script rather than to a function body.
+const MainFunction = -1
+
+// Handler is one entry of the exception table. An exception thrown by an
+// instruction in [Start, End) of a frame running Function is caught here:
+// the operand stack is cut back to StackDepth values above the frame base,
+// the exception is pushed and execution resumes at Target. Entries are
+// ordered innermost first, so the first match wins.
+type Handler struct {
+	Start      int
+	End        int
+	Target     int
+	Function   int // FUNC_DECL address of the enclosing function, or MainFunction
+	StackDepth int // values a loop keeps on the stack at the try, e.g. foreach state
+}
+
+// Line maps the instructions from Addr up to the next entry to a source line.
+type Line struct {
+	Addr int
+	Line int
+}
+
+// AddHandler appends an entry to the exception table.
+func (b *BytecodeBuilder) AddHandler(handler Handler) {
+	b.handlers = append(b.handlers, handler)
+}
+
+// MarkLine records that the code emitted from now on comes from line.
+func (b *BytecodeBuilder) MarkLine(line int) {
+	if line <= 0 {
+		return
+	}
+
+	addr := len(b.code)
+	if n := len(b.lines); n > 0 {
+		last := &b.lines[n-1]
+		if last.Line == line {
+			return
+		}
+		if last.Addr == addr {
+			last.Line = line
+			return
+		}
+	}
+	b.lines = append(b.lines, Line{Addr: addr, Line: line})
+}
+
+func (b *BytecodeBuilder) Handlers() []Handler {
+	return b.handlers
+}
+
+func (b *BytecodeBuilder) Lines() []Line {
+	return b.lines
+}
+
+// LineAt returns the source line of the instruction at addr, or 0 when
+// the table has no entry for it.
+func LineAt(lines []Line, addr int) int {
+	i := sort.Search(len(lines), func(i int) bool { return lines[i].Addr > addr })
+	if i == 0 {
+		return 0
+	}
+	return lines[i-1].Line
+}
@@ -0,0 +1,38 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package class
+
+import (
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/native"
+	"github.com/neokofg/php-compiler/internal/token"
+)
+
+// builtinDecl describes a built-in class as if the script declared it, so
+// that user classes are checked against it like against their own classes.
+// The methods have empty bodies; their code lives in the runtime.
+func builtinDecl(c *native.Class) *ast.ClassDecl {
+	decl := &ast.ClassDecl{
+		Kind:       token.T_CLASS,
+		Name:       c.Name,
+		Parent:     c.Parent,
+		Interfaces: c.Interfaces,
+		Final:      c.Final,
+	}
+	if c.Interface {
+		decl.Kind = token.T_INTERFACE
+	}
+
+	for _, m := range c.Methods {
+		decl.Methods = append(decl.Methods, ast.MethodDecl{
+			Visibility: token.T_PUBLIC,
+			Final:      m.Final,
+			HasBody:    !c.Interface,
+			Function: &ast.FunctionDecl{
+				Name:   m.Name,
+				Params: m.Params,
+				Body:   []ast.Stmt{},
+			},
+		})
+	}
+	return decl
+}
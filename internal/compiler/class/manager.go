@@ -0,0 +1,75 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package class
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/native"
+	"github.com/neokofg/php-compiler/internal/token"
+)
+
+// Class is what the compiler knows about a class, interface or trait
+// before its code runs.
+type Class struct {
+	Name    string
+	Decl    *ast.ClassDecl
+	Defined bool // the declaration has been compiled
+	Builtin bool // provided by the runtime, see native.Classes
+}
+
+// Manager tracks the classes of the program, starting with the built-in
+// ones. Class names are case-insensitive, as in PHP.
+type Manager struct {
+	classes map[string]*Class
+}
+
+func NewManager() *Manager {
+	m := &Manager{
+		classes: make(map[string]*Class),
+	}
+	for _, c := range native.Classes() {
+		m.classes[strings.ToLower(c.Name)] = &Class{Name: c.Name, Decl: builtinDecl(c), Defined: true, Builtin: true}
+	}
+	return m
+}
+
+// Declare registers a class ahead of its code, so that new can precede
+// the declaration and classes can extend classes declared further down.
+// When a name is declared more than once, the first declaration is kept.
+func (m *Manager) Declare(decl *ast.ClassDecl) {
+	key := strings.ToLower(decl.Name)
+	if _, exists := m.classes[key]; !exists {
+		m.classes[key] = &Class{Name: decl.Name, Decl: decl}
+	}
+}
+
+// Define records that the declaration of name is being compiled. A second
+// declaration of the same name is an error.
+func (m *Manager) Define(decl *ast.ClassDecl) error {
+	key := strings.ToLower(decl.Name)
+	if class, exists := m.classes[key]; exists && class.Defined {
+		return fmt.Errorf("cannot declare %s %s, because the name is already in use", KindName(decl), decl.Name)
+	}
+
+	m.classes[key] = &Class{Name: decl.Name, Decl: decl, Defined: true}
+	return nil
+}
+
+func (m *Manager) GetClass(name string) (*Class, bool) {
+	class, exists := m.classes[strings.ToLower(name)]
+	return class, exists
+}
+
+// KindName returns "class", "interface" or "trait" for use in messages.
+func KindName(decl *ast.ClassDecl) string {
+	switch decl.Kind {
+	case token.T_INTERFACE:
+		return "interface"
+	case token.T_TRAIT:
+		return "trait"
+	default:
+		return "class"
+	}
+}
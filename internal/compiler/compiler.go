@@ -20,14 +20,19 @@ import (
 	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
 	"github.com/neokofg/php-compiler/internal/compiler/constant"
 	"github.com/neokofg/php-compiler/internal/compiler/expr"
+	"github.com/neokofg/php-compiler/internal/compiler/function"
 	"github.com/neokofg/php-compiler/internal/compiler/interfaces"
 	"github.com/neokofg/php-compiler/internal/compiler/stmt"
+	"github.com/neokofg/php-compiler/internal/compiler/variable"
+	"github.com/neokofg/php-compiler/internal/diag"
+	"github.com/neokofg/php-compiler/internal/token"
 )
 
 type Compiler struct {
 	context      *interfaces.Context
 	stmtCompiler interfaces.StmtCompiler
 	exprCompiler interfaces.ExprCompiler
+	file         string
 }
 
 func New() *Compiler {
@@ -43,16 +48,53 @@ func New() *Compiler {
 	}
 }
 
+// CompileProgram compiles every statement even after an error. The returned
+// error is a diag.List; warnings are available from Diagnostics either way.
 func (c *Compiler) CompileProgram(stmts []ast.Stmt) error {
-	for _, statement := range stmts {
-		if err := c.stmtCompiler.CompileStmt(statement); err != nil {
-			return err
-		}
+	if len(stmts) > 0 {
+		c.file = stmts[0].Pos().Filename
+	}
+
+	declareFunctions(c.context.FunctionManager, stmts, false)
+	declareClasses(c.context.ClassManager, stmts)
+	c.bindFunctions(stmts)
+
+	if err := c.stmtCompiler.CompileBlock(hoistClasses(stmts)); err != nil {
+		return err
 	}
 
 	c.context.BytecodeBuilder.Append(bytecode.OP_HALT)
 
-	return nil
+	c.relocateCalls()
+	c.checkLimits()
+
+	return c.context.Diagnostics.Err()
+}
+
+// checkLimits reports the script-wide tables that outgrew the one-byte
+// operands referring to them, and the first jump or call whose target is
+// too far for its 16-bit operand.
+func (c *Compiler) checkLimits() {
+	if pos, ok := c.context.BytecodeBuilder.OutOfRange(); ok {
+		c.context.ReportError(&interfaces.CompilationError{
+			Code: diag.ErrLimit,
+			Msg:  "the script is too large: a jump or call target here is out of the range of its 16-bit operand",
+			Pos:  token.Position{Filename: c.file, Line: bytecode.LineAt(c.context.BytecodeBuilder.Lines(), pos), Column: 1},
+		})
+	}
+
+	if n := len(c.context.ConstantPool.GetAll()); n > constant.MaxConstants {
+		c.context.ReportError(interfaces.Errorf(diag.ErrLimit, nil,
+			"the script uses %d distinct constants, at most %d are supported", n, constant.MaxConstants))
+	}
+	if n := len(c.context.VariableManager.GetAllVariables()); n > variable.MaxSlots {
+		c.context.ReportError(interfaces.Errorf(diag.ErrLimit, nil,
+			"the script uses %d global and static variables, at most %d are supported", n, variable.MaxSlots))
+	}
+}
+
+func (c *Compiler) Diagnostics() diag.List {
+	return c.context.Diagnostics
 }
 
 func (c *Compiler) GetBytecode() []byte {
@@ -62,3 +104,26 @@ func (c *Compiler) GetBytecode() []byte {
 func (c *Compiler) GetConstants() []constant.Constant {
 	return c.context.ConstantPool.GetAll()
 }
+
+func (c *Compiler) GetFunctions() []function.Function {
+	return c.context.FunctionManager.GetAllFunctions()
+}
+
+func (c *Compiler) GetVariables() map[string]int {
+	return c.context.VariableManager.GetAllVariables()
+}
+
+// GetHandlers returns the exception table, innermost handlers first.
+func (c *Compiler) GetHandlers() []bytecode.Handler {
+	return c.context.BytecodeBuilder.Handlers()
+}
+
+// GetLines returns the source lines of the bytecode, ordered by address.
+func (c *Compiler) GetLines() []bytecode.Line {
+	return c.context.BytecodeBuilder.Lines()
+}
+
+// GetFile returns the name of the compiled source file.
+func (c *Compiler) GetFile() string {
+	return c.file
+}
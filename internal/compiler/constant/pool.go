@@ -1,6 +1,12 @@
 // Licensed under GNU GPL v3. See LICENSE file for details.
 package constant
 
+// MaxConstants is the size of the constant pool: instructions refer to
+// constants by a one-byte index.
+const MaxConstants = 256
+
+// Constant is a literal of the program. Type is "int", "float", "string"
+// or "bool", and Value its text: a bool is "true" or "false".
 type Constant struct {
 	Type  string
 	Value string
@@ -2,29 +2,21 @@
 package compiler
 
 import (
-	"fmt"
+	"github.com/neokofg/php-compiler/internal/compiler/interfaces"
+	"github.com/neokofg/php-compiler/internal/token"
 )
 
-type CompilationError struct {
-	Msg string
-	Pos int
-}
-
-func (e *CompilationError) Error() string {
-	if e.Pos >= 0 {
-		return fmt.Sprintf("compilation error at position %d: %s", e.Pos, e.Msg)
-	}
-	return fmt.Sprintf("compilation error: %s", e.Msg)
-}
+// CompilationError lives in the interfaces package so that the statement
+// and expression compilers can return it without importing this package.
+type CompilationError = interfaces.CompilationError
 
 func NewError(msg string) error {
 	return &CompilationError{
 		Msg: msg,
-		Pos: -1,
 	}
 }
 
-func NewErrorAtPos(msg string, pos int) error {
+func NewErrorAtPos(msg string, pos token.Position) error {
 	return &CompilationError{
 		Msg: msg,
 		Pos: pos,
@@ -0,0 +1,65 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package expr
+
+import (
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/compiler/interfaces"
+	"github.com/neokofg/php-compiler/internal/diag"
+)
+
+type ArrayCompiler struct {
+	context      interfaces.CompilationContext
+	exprCompiler interfaces.ExprCompiler
+}
+
+func NewArrayCompiler(context interfaces.CompilationContext, exprCompiler interfaces.ExprCompiler) *ArrayCompiler {
+	return &ArrayCompiler{
+		context:      context,
+		exprCompiler: exprCompiler,
+	}
+}
+
+// Compile builds the array on the stack: ARRAY_NEW, then one ARRAY_PUSH or
+// ARRAY_INSERT per item.
+func (c *ArrayCompiler) Compile(expr *ast.ArrayLiteral) error {
+	builder := c.context.GetBytecodeBuilder()
+	builder.Append(bytecode.OP_ARRAY_NEW)
+
+	for _, item := range expr.Items {
+		if item.Key != nil {
+			if err := c.exprCompiler.CompileExpr(item.Key); err != nil {
+				return err
+			}
+		}
+
+		if err := c.exprCompiler.CompileExpr(item.Value); err != nil {
+			return err
+		}
+
+		if item.Key != nil {
+			builder.Append(bytecode.OP_ARRAY_INSERT)
+		} else {
+			builder.Append(bytecode.OP_ARRAY_PUSH)
+		}
+	}
+
+	return nil
+}
+
+func (c *ArrayCompiler) CompileIndex(expr *ast.IndexExpr) error {
+	if expr.Index == nil {
+		return interfaces.Errorf(diag.ErrInvalidOperand, expr, "cannot use [] for reading")
+	}
+
+	if err := c.exprCompiler.CompileExpr(expr.Base); err != nil {
+		return err
+	}
+
+	if err := c.exprCompiler.CompileExpr(expr.Index); err != nil {
+		return err
+	}
+
+	c.context.GetBytecodeBuilder().Append(bytecode.OP_ARRAY_GET)
+	return nil
+}
@@ -0,0 +1,491 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package expr
+
+import (
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/compiler/constant"
+	"github.com/neokofg/php-compiler/internal/compiler/interfaces"
+	"github.com/neokofg/php-compiler/internal/diag"
+	"github.com/neokofg/php-compiler/internal/token"
+)
+
+// AssignCompiler compiles the expressions that write a variable, an array
+// element or a property: assignments, compound assignments, ++ and --.
+// The written value is computed on top of the stack. When the result of
+// the expression is used, a copy of it is tucked under the values the
+// write consumes, where it stays once they are gone:
+//
+//	$x = $a[0] += 5   =>   LOAD a, 0, ARRAY_FETCH, 5, ASSIGN_ADD, TUCK 2, ARRAY_SET, STORE a, DUP, STORE x
+//
+// An array element is written by loading the array, letting ARRAY_SET
+// hand back the updated one and storing that where the array came from.
+type AssignCompiler struct {
+	context      interfaces.CompilationContext
+	exprCompiler interfaces.ExprCompiler
+}
+
+func NewAssignCompiler(context interfaces.CompilationContext, exprCompiler interfaces.ExprCompiler) *AssignCompiler {
+	return &AssignCompiler{
+		context:      context,
+		exprCompiler: exprCompiler,
+	}
+}
+
+// update describes the value a write stores.
+type update struct {
+	op      token.TokenType       // T_EQ, a compound assignment operator, T_INC or T_DEC
+	expr    ast.Expr              // the right operand, nil for T_INC and T_DEC
+	postfix bool                  // the result is the old value, as in $x++
+	call    func(depth int) error // computes the value in place of op, see CompileByRef
+}
+
+// quiet reports whether the old value is read without a warning when it
+// is undefined: ??= tests it, and a by-reference argument creates it.
+func (u update) quiet() bool {
+	return u.op == token.T_COALESCE_EQ || u.call != nil
+}
+
+// Compile compiles an assignment. When keep is false, its result is not
+// left on the stack.
+func (c *AssignCompiler) Compile(expr *ast.AssignExpr, keep bool) error {
+	return c.compileWrite(expr, expr.Target, update{op: expr.Op, expr: expr.Expr}, keep)
+}
+
+func (c *AssignCompiler) CompilePrefix(expr *ast.PrefixExpr, keep bool) error {
+	return c.compileWrite(expr, expr.Expr, update{op: expr.Op}, keep)
+}
+
+func (c *AssignCompiler) CompilePostfix(expr *ast.PostfixExpr, keep bool) error {
+	return c.compileWrite(expr, expr.Expr, update{op: expr.Op, postfix: true}, keep)
+}
+
+// CompileByRef passes target, a variable, an array element or a property,
+// by reference to the code emitted by call, and writes the new value back
+// like a compound assignment does. call finds the old value on top of the
+// stack, above the depth values the write consumes, and leaves the result
+// of the whole expression under those values and the new value on top.
+func (c *AssignCompiler) CompileByRef(target ast.Expr, call func(depth int) error) error {
+	return c.compileWrite(target, target, update{call: call}, true)
+}
+
+func (c *AssignCompiler) compileWrite(node ast.Node, target ast.Expr, u update, keep bool) error {
+	switch t := target.(type) {
+	case *ast.VarExpr:
+		return c.compileVar(node, t.Name, u, keep)
+
+	case *ast.PropertyFetch:
+		return c.compileProperty(node, t, nil, u, keep)
+
+	case *ast.IndexExpr:
+		// Peel the [index] suffixes off the target: what remains is the
+		// variable or the property that holds the array.
+		var indexes []ast.Expr
+		var holder ast.Expr = t
+		for {
+			index, ok := holder.(*ast.IndexExpr)
+			if !ok {
+				break
+			}
+			indexes = append([]ast.Expr{index.Index}, indexes...)
+			holder = index.Base
+		}
+
+		switch h := holder.(type) {
+		case *ast.VarExpr:
+			return c.compileElement(node, h.Name, indexes, u, keep)
+		case *ast.PropertyFetch:
+			return c.compileProperty(node, h, indexes, u, keep)
+		}
+	}
+
+	switch {
+	case u.call != nil:
+		return interfaces.Errorf(diag.ErrInvalidOperand, node, "cannot pass the result of an expression by reference")
+	case u.expr == nil:
+		return interfaces.Errorf(diag.ErrInvalidOperand, node, "cannot increment or decrement the result of an expression")
+	}
+	return interfaces.Errorf(diag.ErrInvalidOperand, node, "cannot assign to the result of an expression")
+}
+
+func (c *AssignCompiler) compileVar(node ast.Node, name string, u update, keep bool) error {
+	if name == "this" {
+		return interfaces.Errorf(diag.ErrInvalidOperand, node, "cannot re-assign $this")
+	}
+
+	if u.op == token.T_COALESCE_EQ {
+		return c.compileVarCoalesce(name, u.expr, keep)
+	}
+
+	switch {
+	case u.quiet():
+		c.context.EmitLoadVarQuiet(name)
+	case u.op != token.T_EQ:
+		c.context.EmitLoadVar(name)
+	}
+	if err := c.compileUpdate(u, keep, 0); err != nil {
+		return err
+	}
+	c.context.EmitStoreVar(name)
+
+	return nil
+}
+
+// compileVarCoalesce compiles $name ??= expr, which evaluates and stores
+// expr only when the variable is null:
+//
+//	LOAD name COALESCE keep, expr STORE name JUMP end, keep: POP, end:
+//
+// When the result is used, the value left by COALESCE is the result, and
+// there is nothing to jump over.
+func (c *AssignCompiler) compileVarCoalesce(name string, expr ast.Expr, keep bool) error {
+	builder := c.context.GetBytecodeBuilder()
+
+	c.context.EmitLoadVarQuiet(name)
+	keepJump := emitJump(builder, bytecode.OP_COALESCE)
+
+	if err := c.exprCompiler.CompileExpr(expr); err != nil {
+		return err
+	}
+	c.emitKeep(keep, 0)
+	c.context.EmitStoreVar(name)
+
+	if keep {
+		patchJump(builder, keepJump)
+		return nil
+	}
+
+	endJump := emitJump(builder, bytecode.OP_JUMP)
+	patchJump(builder, keepJump)
+	builder.Append(bytecode.OP_POP)
+	patchJump(builder, endJump)
+
+	return nil
+}
+
+// compileElement writes an element of the array in the variable name.
+func (c *AssignCompiler) compileElement(node ast.Node, name string, indexes []ast.Expr, u update, keep bool) error {
+	if u.op == token.T_EQ {
+		err := c.compileElementAssign(node, indexes, u.expr, keep, func() (int, error) {
+			c.context.EmitLoadVarQuiet(name)
+			return 1, nil
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		// Like =, ??= and passing by reference create the array of an
+		// undefined variable quietly.
+		if u.quiet() {
+			c.context.EmitLoadVarQuiet(name)
+		} else {
+			c.context.EmitLoadVar(name)
+		}
+		if err := c.compileElementWrite(node, indexes, u, keep, 0); err != nil {
+			return err
+		}
+	}
+
+	c.context.EmitStoreVar(name)
+
+	return nil
+}
+
+// compileElementAssign compiles a plain assignment to an array element.
+// The keys and the value are evaluated before the array is loaded, as in
+// PHP, so that the outer write of $a[] = $a['k'] = 1 sees the inner one.
+// load pushes the array, after the object holding it if there is one, and
+// returns how many values it pushed; they are moved under the keys and
+// the value, and ARRAY_SET leaves the updated array in their place:
+//
+//	$a[$k] = $v   =>   k, v, LOAD a, TUCK 2 POP, ARRAY_SET
+func (c *AssignCompiler) compileElementAssign(node ast.Node, indexes []ast.Expr, expr ast.Expr, keep bool, load func() (int, error)) error {
+	appendMask, keys, err := c.compileKeys(node, indexes)
+	if err != nil {
+		return err
+	}
+
+	if err := c.exprCompiler.CompileExpr(expr); err != nil {
+		return err
+	}
+	c.emitKeep(keep, keys)
+
+	holders, err := load()
+	if err != nil {
+		return err
+	}
+	builder := c.context.GetBytecodeBuilder()
+	for i := 0; i < holders; i++ {
+		// TUCK then POP moves the value on top under the others.
+		builder.Append(bytecode.OP_TUCK)
+		builder.Append(byte(keys + holders))
+		builder.Append(bytecode.OP_POP)
+	}
+
+	builder.Append(bytecode.OP_ARRAY_SET)
+	builder.Append(byte(len(indexes)))
+	builder.Append(appendMask)
+
+	return nil
+}
+
+// compileElementWrite writes an element of the array on top of the stack
+// and leaves the updated array in its place. depth is the number of
+// values under the array that the write consumes after that. Compound
+// assignments and ++/-- read the old element with ARRAY_FETCH.
+func (c *AssignCompiler) compileElementWrite(node ast.Node, indexes []ast.Expr, u update, keep bool, depth int) error {
+	appendMask, keys, err := c.compileKeys(node, indexes)
+	if err != nil {
+		return err
+	}
+	depth += keys + 1 // the keys and the array
+
+	if appendMask != 0 {
+		return interfaces.Errorf(diag.ErrInvalidOperand, node, "cannot use [] for reading")
+	}
+
+	if u.op == token.T_COALESCE_EQ {
+		return c.compileElementCoalesce(len(indexes), u.expr, keep, depth)
+	}
+
+	builder := c.context.GetBytecodeBuilder()
+	if u.quiet() {
+		builder.Append(bytecode.OP_ARRAY_FETCH_QUIET)
+	} else {
+		builder.Append(bytecode.OP_ARRAY_FETCH)
+	}
+	builder.Append(byte(len(indexes)))
+
+	if err := c.compileUpdate(u, keep, depth); err != nil {
+		return err
+	}
+
+	builder.Append(bytecode.OP_ARRAY_SET)
+	builder.Append(byte(len(indexes)))
+	builder.Append(0)
+
+	return nil
+}
+
+// compileKeys pushes the keys of the dimensions that are not appends and
+// returns the ARRAY_SET mask of the appends and the number of keys.
+func (c *AssignCompiler) compileKeys(node ast.Node, indexes []ast.Expr) (byte, int, error) {
+	if len(indexes) > bytecode.MaxArrayDims {
+		return 0, 0, interfaces.Errorf(diag.ErrCompile, node, "cannot assign to more than %d array dimensions", bytecode.MaxArrayDims)
+	}
+
+	var appendMask byte
+	keys := 0
+	for i, index := range indexes {
+		if index == nil {
+			appendMask |= 1 << i
+			continue
+		}
+		if err := c.exprCompiler.CompileExpr(index); err != nil {
+			return 0, 0, err
+		}
+		keys++
+	}
+	return appendMask, keys, nil
+}
+
+// compileElementCoalesce writes expr to the element when it is null.
+// Otherwise the keys and the element are popped, and the array is stored
+// back unchanged:
+//
+//	ARRAY_FETCH_QUIET COALESCE keep, expr ARRAY_SET JUMP end, keep: POP..., end:
+func (c *AssignCompiler) compileElementCoalesce(dims int, expr ast.Expr, keep bool, depth int) error {
+	builder := c.context.GetBytecodeBuilder()
+
+	builder.Append(bytecode.OP_ARRAY_FETCH_QUIET)
+	builder.Append(byte(dims))
+	keepJump := emitJump(builder, bytecode.OP_COALESCE)
+
+	if err := c.exprCompiler.CompileExpr(expr); err != nil {
+		return err
+	}
+	c.emitKeep(keep, depth)
+	builder.Append(bytecode.OP_ARRAY_SET)
+	builder.Append(byte(dims))
+	builder.Append(0)
+	endJump := emitJump(builder, bytecode.OP_JUMP)
+
+	patchJump(builder, keepJump)
+	c.emitKeep(keep, depth)
+	for i := 0; i <= dims; i++ {
+		builder.Append(bytecode.OP_POP)
+	}
+	patchJump(builder, endJump)
+
+	return nil
+}
+
+// compileProperty pushes the object and the new value for PROP_SET.
+// Objects are handles, so nothing is stored back into a variable. When
+// the old value is needed, for compound assignments and array elements,
+// the object is duplicated and the property read with PROP_GET first:
+//
+//	$obj->items[] = $x   =>   x, obj DUP PROP_GET items, TUCK 2 POP TUCK 2 POP, ARRAY_SET, PROP_SET items
+//
+// ??= reads the property with PROP_GET_QUIET, so that a missing one is
+// not reported.
+func (c *AssignCompiler) compileProperty(node ast.Node, fetch *ast.PropertyFetch, indexes []ast.Expr, u update, keep bool) error {
+	builder := c.context.GetBytecodeBuilder()
+
+	nameIdx := c.context.GetConstantPool().Add(constant.Constant{
+		Type:  "string",
+		Value: fetch.Name,
+	})
+
+	fetchProperty := func() error {
+		if err := c.exprCompiler.CompileExpr(fetch.Object); err != nil {
+			return err
+		}
+		builder.Append(bytecode.OP_DUP)
+		if u.quiet() {
+			builder.Append(bytecode.OP_PROP_GET_QUIET)
+		} else {
+			builder.Append(bytecode.OP_PROP_GET)
+		}
+		builder.Append(byte(nameIdx))
+		return nil
+	}
+
+	var err error
+	switch {
+	case len(indexes) > 0 && u.op == token.T_EQ:
+		err = c.compileElementAssign(node, indexes, u.expr, keep, func() (int, error) {
+			return 2, fetchProperty()
+		})
+	case len(indexes) > 0:
+		if err = fetchProperty(); err == nil {
+			err = c.compileElementWrite(node, indexes, u, keep, 1)
+		}
+	default:
+		if err = c.exprCompiler.CompileExpr(fetch.Object); err != nil {
+			return err
+		}
+		if u.op == token.T_COALESCE_EQ {
+			return c.compilePropertyCoalesce(nameIdx, u.expr, keep)
+		}
+		switch {
+		case u.quiet():
+			builder.Append(bytecode.OP_DUP)
+			builder.Append(bytecode.OP_PROP_GET_QUIET)
+			builder.Append(byte(nameIdx))
+		case u.op != token.T_EQ:
+			builder.Append(bytecode.OP_DUP)
+			builder.Append(bytecode.OP_PROP_GET)
+			builder.Append(byte(nameIdx))
+		}
+		err = c.compileUpdate(u, keep, 1)
+	}
+	if err != nil {
+		return err
+	}
+
+	builder.Append(bytecode.OP_PROP_SET)
+	builder.Append(byte(nameIdx))
+
+	return nil
+}
+
+// compilePropertyCoalesce compiles $obj->name ??= expr:
+//
+//	obj DUP PROP_GET_QUIET name COALESCE keep, expr PROP_SET name JUMP end, keep: POP POP, end:
+func (c *AssignCompiler) compilePropertyCoalesce(nameIdx int, expr ast.Expr, keep bool) error {
+	builder := c.context.GetBytecodeBuilder()
+
+	builder.Append(bytecode.OP_DUP)
+	builder.Append(bytecode.OP_PROP_GET_QUIET)
+	builder.Append(byte(nameIdx))
+	keepJump := emitJump(builder, bytecode.OP_COALESCE)
+
+	if err := c.exprCompiler.CompileExpr(expr); err != nil {
+		return err
+	}
+	c.emitKeep(keep, 1)
+	builder.Append(bytecode.OP_PROP_SET)
+	builder.Append(byte(nameIdx))
+	endJump := emitJump(builder, bytecode.OP_JUMP)
+
+	patchJump(builder, keepJump)
+	c.emitKeep(keep, 1)
+	builder.Append(bytecode.OP_POP)
+	builder.Append(bytecode.OP_POP)
+	patchJump(builder, endJump)
+
+	return nil
+}
+
+// compileUpdate computes the value to write from the old one on top of
+// the stack, or pushes expr for a plain assignment. When keep is set, the
+// result is tucked under the depth values below.
+func (c *AssignCompiler) compileUpdate(u update, keep bool, depth int) error {
+	if u.call != nil {
+		return u.call(depth)
+	}
+
+	if u.postfix {
+		c.emitKeep(keep, depth)
+	}
+
+	if u.expr != nil {
+		if err := c.exprCompiler.CompileExpr(u.expr); err != nil {
+			return err
+		}
+	}
+
+	builder := c.context.GetBytecodeBuilder()
+	switch u.op {
+	case token.T_PLUS_EQ:
+		builder.Append(bytecode.OP_ASSIGN_ADD)
+	case token.T_MINUS_EQ:
+		builder.Append(bytecode.OP_ASSIGN_SUB)
+	case token.T_MUL_EQ:
+		builder.Append(bytecode.OP_ASSIGN_MUL)
+	case token.T_DIV_EQ:
+		builder.Append(bytecode.OP_ASSIGN_DIV)
+	case token.T_MOD_EQ:
+		builder.Append(bytecode.OP_ASSIGN_MOD)
+	case token.T_DOT_EQ:
+		builder.Append(bytecode.OP_ASSIGN_CONCAT)
+	case token.T_POW_EQ:
+		builder.Append(bytecode.OP_ASSIGN_POW)
+	case token.T_BIT_AND_EQ:
+		builder.Append(bytecode.OP_ASSIGN_BIT_AND)
+	case token.T_BIT_OR_EQ:
+		builder.Append(bytecode.OP_ASSIGN_BIT_OR)
+	case token.T_BIT_XOR_EQ:
+		builder.Append(bytecode.OP_ASSIGN_BIT_XOR)
+	case token.T_LSHIFT_EQ:
+		builder.Append(bytecode.OP_ASSIGN_LSHIFT)
+	case token.T_RSHIFT_EQ:
+		builder.Append(bytecode.OP_ASSIGN_RSHIFT)
+	case token.T_INC:
+		builder.Append(bytecode.OP_INC)
+	case token.T_DEC:
+		builder.Append(bytecode.OP_DEC)
+	}
+
+	if !u.postfix {
+		c.emitKeep(keep, depth)
+	}
+	return nil
+}
+
+// emitKeep copies the value on top of the stack under the depth values
+// below it, when keep is set.
+func (c *AssignCompiler) emitKeep(keep bool, depth int) {
+	if !keep {
+		return
+	}
+
+	builder := c.context.GetBytecodeBuilder()
+	if depth == 0 {
+		builder.Append(bytecode.OP_DUP)
+		return
+	}
+	builder.Append(bytecode.OP_TUCK)
+	builder.Append(byte(depth))
+}
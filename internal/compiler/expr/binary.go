@@ -2,10 +2,10 @@
 package expr
 
 import (
-	"fmt"
 	"github.com/neokofg/php-compiler/internal/ast"
 	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
 	"github.com/neokofg/php-compiler/internal/compiler/interfaces"
+	"github.com/neokofg/php-compiler/internal/diag"
 	"github.com/neokofg/php-compiler/internal/token"
 )
 
@@ -22,6 +22,10 @@ func NewBinaryCompiler(context interfaces.CompilationContext, exprCompiler inter
 }
 
 func (c *BinaryCompiler) Compile(expr *ast.BinaryExpr) error {
+	if expr.Op == token.T_LOGICAL_XOR {
+		return c.compileXor(expr)
+	}
+
 	if err := c.exprCompiler.CompileExpr(expr.Left); err != nil {
 		return err
 	}
@@ -41,6 +45,8 @@ func (c *BinaryCompiler) Compile(expr *ast.BinaryExpr) error {
 		c.context.GetBytecodeBuilder().Append(bytecode.OP_DIV)
 	case token.T_MOD:
 		c.context.GetBytecodeBuilder().Append(bytecode.OP_MOD)
+	case token.T_POW:
+		c.context.GetBytecodeBuilder().Append(bytecode.OP_POW)
 	case token.T_GT:
 		c.context.GetBytecodeBuilder().Append(bytecode.OP_GT)
 	case token.T_LT:
@@ -58,10 +64,8 @@ func (c *BinaryCompiler) Compile(expr *ast.BinaryExpr) error {
 		c.context.GetBytecodeBuilder().Append(bytecode.OP_NOT)
 	case token.T_NOTEQEQ:
 		c.context.GetBytecodeBuilder().Append(bytecode.OP_IDENTITY_NE)
-	case token.T_AND:
-		c.context.GetBytecodeBuilder().Append(bytecode.OP_AND)
-	case token.T_OR:
-		c.context.GetBytecodeBuilder().Append(bytecode.OP_OR)
+	case token.T_SPACESHIP:
+		c.context.GetBytecodeBuilder().Append(bytecode.OP_SPACESHIP)
 	case token.T_BIT_AND:
 		c.context.GetBytecodeBuilder().Append(bytecode.OP_BIT_AND)
 	case token.T_BIT_OR:
@@ -75,8 +79,28 @@ func (c *BinaryCompiler) Compile(expr *ast.BinaryExpr) error {
 	case token.T_DOT:
 		c.context.GetBytecodeBuilder().Append(bytecode.OP_CONCAT)
 	default:
-		return fmt.Errorf("unsupported binary operator: %v", expr.Op)
+		return interfaces.Errorf(diag.ErrCompile, expr, "unsupported binary operator: %v", expr.Op)
+	}
+
+	return nil
+}
+
+// compileXor compiles left xor right to left NOT right NOT IDENTITY_NE:
+// the operands are converted to bools, which differ when exactly one of
+// them is true.
+func (c *BinaryCompiler) compileXor(expr *ast.BinaryExpr) error {
+	builder := c.context.GetBytecodeBuilder()
+
+	if err := c.exprCompiler.CompileExpr(expr.Left); err != nil {
+		return err
+	}
+	builder.Append(bytecode.OP_NOT)
+
+	if err := c.exprCompiler.CompileExpr(expr.Right); err != nil {
+		return err
 	}
+	builder.Append(bytecode.OP_NOT)
 
+	builder.Append(bytecode.OP_IDENTITY_NE)
 	return nil
 }
@@ -2,6 +2,8 @@
 package expr
 
 import (
+	"strconv"
+
 	"github.com/neokofg/php-compiler/internal/ast"
 	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
 	"github.com/neokofg/php-compiler/internal/compiler/constant"
@@ -19,14 +21,9 @@ func NewBooleanCompiler(context interfaces.CompilationContext) *BooleanCompiler
 }
 
 func (c *BooleanCompiler) Compile(expr *ast.BooleanLiteral) error {
-	value := "0"
-	if expr.Value {
-		value = "1"
-	}
-
 	idx := c.context.GetConstantPool().Add(constant.Constant{
-		Type:  "int",
-		Value: value,
+		Type:  "bool",
+		Value: strconv.FormatBool(expr.Value),
 	})
 
 	c.context.GetBytecodeBuilder().Append(bytecode.OP_LOAD_CONST)
@@ -2,10 +2,11 @@
 package expr
 
 import (
-	"fmt"
 	"github.com/neokofg/php-compiler/internal/ast"
 	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
 	"github.com/neokofg/php-compiler/internal/compiler/interfaces"
+	"github.com/neokofg/php-compiler/internal/diag"
+	"github.com/neokofg/php-compiler/internal/token"
 )
 
 type exprCompiler struct {
@@ -14,11 +15,16 @@ type exprCompiler struct {
 	stringCompiler       *StringCompiler
 	booleanCompiler      *BooleanCompiler
 	varCompiler          *VarCompiler
-	postfixCompiler      *PostfixCompiler
-	prefixCompiler       *PrefixCompiler
 	binaryCompiler       *BinaryCompiler
 	unaryCompiler        *UnaryCompiler
 	functionCallCompiler *FunctionCallCompiler
+	interpolatedCompiler *InterpolatedStringCompiler
+	arrayCompiler        *ArrayCompiler
+	objectCompiler       *ObjectCompiler
+	issetCompiler        *IssetCompiler
+	assignCompiler       *AssignCompiler
+	conditionalCompiler  *ConditionalCompiler
+	closureCompiler      interfaces.ClosureCompiler
 }
 
 func NewCompiler(context interfaces.CompilationContext) interfaces.ExprCompiler {
@@ -30,54 +36,107 @@ func NewCompiler(context interfaces.CompilationContext) interfaces.ExprCompiler
 	compiler.stringCompiler = NewStringCompiler(context)
 	compiler.booleanCompiler = NewBooleanCompiler(context)
 	compiler.varCompiler = NewVarCompiler(context)
-	compiler.postfixCompiler = NewPostfixCompiler(context)
-	compiler.prefixCompiler = NewPrefixCompiler(context)
 
 	compiler.unaryCompiler = NewUnaryCompiler(context, compiler)
 	compiler.binaryCompiler = NewBinaryCompiler(context, compiler)
-	compiler.functionCallCompiler = NewFunctionCallCompiler(context, compiler)
+	compiler.interpolatedCompiler = NewInterpolatedStringCompiler(context, compiler)
+	compiler.arrayCompiler = NewArrayCompiler(context, compiler)
+	compiler.objectCompiler = NewObjectCompiler(context, compiler)
+	compiler.issetCompiler = NewIssetCompiler(context, compiler)
+	compiler.assignCompiler = NewAssignCompiler(context, compiler)
+	compiler.functionCallCompiler = NewFunctionCallCompiler(context, compiler, compiler.assignCompiler)
+	compiler.conditionalCompiler = NewConditionalCompiler(context, compiler, compiler.issetCompiler)
 
 	return compiler
 }
 
+func (c *exprCompiler) SetClosureCompiler(closures interfaces.ClosureCompiler) {
+	c.closureCompiler = closures
+}
+
 func (c *exprCompiler) CompileExpr(expr ast.Expr) error {
 	switch e := expr.(type) {
 	case *ast.NumberLiteral:
 		return c.numberCompiler.Compile(e)
+	case *ast.FloatLiteral:
+		return c.numberCompiler.CompileFloat(e)
 	case *ast.StringLiteral:
 		return c.stringCompiler.Compile(e)
+	case *ast.InterpolatedString:
+		return c.interpolatedCompiler.Compile(e)
+	case *ast.ArrayLiteral:
+		return c.arrayCompiler.Compile(e)
+	case *ast.IndexExpr:
+		return c.arrayCompiler.CompileIndex(e)
 	case *ast.BooleanLiteral:
 		return c.booleanCompiler.Compile(e)
+	case *ast.NullLiteral:
+		c.context.GetBytecodeBuilder().Append(bytecode.OP_LOAD_NULL)
+		return nil
 	case *ast.VarExpr:
 		return c.varCompiler.Compile(e)
 	case *ast.UnaryExpr:
 		return c.unaryCompiler.Compile(e)
 	case *ast.BinaryExpr:
+		switch e.Op {
+		case token.T_COALESCE:
+			return c.conditionalCompiler.CompileCoalesce(e)
+		case token.T_AND, token.T_LOGICAL_AND:
+			return c.conditionalCompiler.CompileAnd(e)
+		case token.T_OR, token.T_LOGICAL_OR:
+			return c.conditionalCompiler.CompileOr(e)
+		}
 		return c.binaryCompiler.Compile(e)
+	case *ast.TernaryExpr:
+		return c.conditionalCompiler.CompileTernary(e)
+	case *ast.IssetExpr:
+		return c.issetCompiler.CompileIsset(e)
+	case *ast.EmptyExpr:
+		return c.issetCompiler.CompileEmpty(e)
 	case *ast.PostfixExpr:
-		return c.postfixCompiler.Compile(e)
+		return c.assignCompiler.CompilePostfix(e, true)
 	case *ast.PrefixExpr:
-		return c.prefixCompiler.Compile(e)
+		return c.assignCompiler.CompilePrefix(e, true)
 	case *ast.FunctionCall:
 		return c.functionCallCompiler.Compile(e)
 	case *ast.AssignExpr:
-		return c.compileAssignExpr(e)
+		return c.assignCompiler.Compile(e, true)
+	case *ast.NewExpr:
+		return c.objectCompiler.CompileNew(e)
+	case *ast.InstanceofExpr:
+		return c.objectCompiler.CompileInstanceof(e)
+	case *ast.PropertyFetch:
+		return c.objectCompiler.CompileProperty(e)
+	case *ast.MethodCall:
+		return c.objectCompiler.CompileMethodCall(e)
+	case *ast.StaticCall:
+		return c.objectCompiler.CompileStaticCall(e)
+	case *ast.ClosureExpr:
+		return c.closureCompiler.CompileClosure(e)
+	case *ast.ArrowFunction:
+		return c.closureCompiler.CompileArrowFunction(e)
+	case *ast.CallExpr:
+		return c.functionCallCompiler.CompileIndirect(e)
 	default:
-		return fmt.Errorf("unsupported expression type: %T", expr)
+		return interfaces.Errorf(diag.ErrCompile, expr, "unsupported expression type: %T", expr)
 	}
 }
 
-func (c *exprCompiler) compileAssignExpr(expr *ast.AssignExpr) error {
-	if err := c.CompileExpr(expr.Expr); err != nil {
-		return err
+// CompileForEffect compiles expr for its side effects only. Writes then
+// skip the copy of their result that would be popped right away.
+func (c *exprCompiler) CompileForEffect(expr ast.Expr) error {
+	switch e := expr.(type) {
+	case *ast.AssignExpr:
+		return c.assignCompiler.Compile(e, false)
+	case *ast.PostfixExpr:
+		return c.assignCompiler.CompilePostfix(e, false)
+	case *ast.PrefixExpr:
+		return c.assignCompiler.CompilePrefix(e, false)
 	}
 
-	varIdx := c.context.GetVariableManager().GetIndex(expr.Name)
-	c.context.GetBytecodeBuilder().Append(bytecode.OP_STORE_VAR)
-	c.context.GetBytecodeBuilder().Append(byte(varIdx))
-
-	c.context.GetBytecodeBuilder().Append(bytecode.OP_LOAD_VAR)
-	c.context.GetBytecodeBuilder().Append(byte(varIdx))
-
+	if err := c.CompileExpr(expr); err != nil {
+		return err
+	}
+	c.context.GetBytecodeBuilder().Append(bytecode.OP_POP)
 	return nil
 }
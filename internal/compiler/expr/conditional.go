@@ -0,0 +1,150 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package expr
+
+import (
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/compiler/interfaces"
+)
+
+// ConditionalCompiler compiles the operators that evaluate only some of
+// their operands: the ternary operator, ?? and the logical && and ||.
+type ConditionalCompiler struct {
+	context       interfaces.CompilationContext
+	exprCompiler  interfaces.ExprCompiler
+	issetCompiler *IssetCompiler
+}
+
+func NewConditionalCompiler(context interfaces.CompilationContext, exprCompiler interfaces.ExprCompiler, issetCompiler *IssetCompiler) *ConditionalCompiler {
+	return &ConditionalCompiler{
+		context:       context,
+		exprCompiler:  exprCompiler,
+		issetCompiler: issetCompiler,
+	}
+}
+
+// CompileTernary compiles cond ? then : else to
+//
+//	cond JUMP_IF_FALSE else, then JUMP end, else: else, end:
+//
+// The short form cond ?: else keeps a copy of cond as its result.
+func (c *ConditionalCompiler) CompileTernary(expr *ast.TernaryExpr) error {
+	builder := c.context.GetBytecodeBuilder()
+
+	if err := c.exprCompiler.CompileExpr(expr.Cond); err != nil {
+		return err
+	}
+	if expr.Then == nil {
+		builder.Append(bytecode.OP_DUP)
+	}
+
+	elseJump := emitJump(builder, bytecode.OP_JUMP_IF_FALSE)
+
+	if expr.Then != nil {
+		if err := c.exprCompiler.CompileExpr(expr.Then); err != nil {
+			return err
+		}
+	}
+	endJump := emitJump(builder, bytecode.OP_JUMP)
+
+	patchJump(builder, elseJump)
+	if expr.Then == nil {
+		builder.Append(bytecode.OP_POP)
+	}
+	if err := c.exprCompiler.CompileExpr(expr.Else); err != nil {
+		return err
+	}
+
+	patchJump(builder, endJump)
+	return nil
+}
+
+// CompileCoalesce compiles left ?? right. The left operand is read like
+// in isset, and COALESCE skips the right one when it is not null.
+func (c *ConditionalCompiler) CompileCoalesce(expr *ast.BinaryExpr) error {
+	builder := c.context.GetBytecodeBuilder()
+
+	if err := c.issetCompiler.CompileQuiet(expr.Left); err != nil {
+		return err
+	}
+	endJump := emitJump(builder, bytecode.OP_COALESCE)
+
+	if err := c.exprCompiler.CompileExpr(expr.Right); err != nil {
+		return err
+	}
+
+	patchJump(builder, endJump)
+	return nil
+}
+
+// CompileAnd compiles left && right to
+//
+//	left DUP JUMP_IF_FALSE end, POP right, end: NOT NOT
+//
+// so that right runs only when left is true. The two NOTs turn the operand
+// left on the stack into a bool.
+func (c *ConditionalCompiler) CompileAnd(expr *ast.BinaryExpr) error {
+	builder := c.context.GetBytecodeBuilder()
+
+	if err := c.exprCompiler.CompileExpr(expr.Left); err != nil {
+		return err
+	}
+	builder.Append(bytecode.OP_DUP)
+	endJump := emitJump(builder, bytecode.OP_JUMP_IF_FALSE)
+
+	builder.Append(bytecode.OP_POP)
+	if err := c.exprCompiler.CompileExpr(expr.Right); err != nil {
+		return err
+	}
+
+	patchJump(builder, endJump)
+	builder.Append(bytecode.OP_NOT)
+	builder.Append(bytecode.OP_NOT)
+	return nil
+}
+
+// CompileOr compiles left || right to
+//
+//	left NOT DUP JUMP_IF_FALSE end, POP right NOT, end: NOT
+//
+// so that right runs only when left is false.
+func (c *ConditionalCompiler) CompileOr(expr *ast.BinaryExpr) error {
+	builder := c.context.GetBytecodeBuilder()
+
+	if err := c.exprCompiler.CompileExpr(expr.Left); err != nil {
+		return err
+	}
+	builder.Append(bytecode.OP_NOT)
+	builder.Append(bytecode.OP_DUP)
+	endJump := emitJump(builder, bytecode.OP_JUMP_IF_FALSE)
+
+	builder.Append(bytecode.OP_POP)
+	if err := c.exprCompiler.CompileExpr(expr.Right); err != nil {
+		return err
+	}
+	builder.Append(bytecode.OP_NOT)
+
+	patchJump(builder, endJump)
+	builder.Append(bytecode.OP_NOT)
+	return nil
+}
+
+// emitJump emits a forward jump whose target is patched later and returns
+// its position.
+func emitJump(builder *bytecode.BytecodeBuilder, op byte) int {
+	pos := builder.CurrentPosition()
+	builder.Append(op)
+	builder.AppendUint16(0xFFFF) // Placeholder
+	return pos
+}
+
+// patchJump makes the jump at pos land at the current position. JUMP has
+// a signed offset, the conditional jumps an unsigned one.
+func patchJump(builder *bytecode.BytecodeBuilder, pos int) {
+	offset := builder.CurrentPosition() - (pos + 3)
+	if builder.Get()[pos] == bytecode.OP_JUMP {
+		builder.PatchInt16(pos+1, offset)
+	} else {
+		builder.PatchUint16(pos+1, offset)
+	}
+}
@@ -3,31 +3,49 @@ package expr
 
 import (
 	"fmt"
+
 	"github.com/neokofg/php-compiler/internal/ast"
 	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/compiler/constant"
 	"github.com/neokofg/php-compiler/internal/compiler/interfaces"
+	"github.com/neokofg/php-compiler/internal/diag"
+	"github.com/neokofg/php-compiler/internal/native"
 )
 
 type FunctionCallCompiler struct {
-	context      interfaces.CompilationContext
-	exprCompiler interfaces.ExprCompiler
+	context        interfaces.CompilationContext
+	exprCompiler   interfaces.ExprCompiler
+	assignCompiler *AssignCompiler
 }
 
-func NewFunctionCallCompiler(context interfaces.CompilationContext, exprCompiler interfaces.ExprCompiler) *FunctionCallCompiler {
+func NewFunctionCallCompiler(context interfaces.CompilationContext, exprCompiler interfaces.ExprCompiler, assignCompiler *AssignCompiler) *FunctionCallCompiler {
 	return &FunctionCallCompiler{
-		context:      context,
-		exprCompiler: exprCompiler,
+		context:        context,
+		exprCompiler:   exprCompiler,
+		assignCompiler: assignCompiler,
 	}
 }
 
+// Compile pushes the arguments last to first, so that the callee's FUNC_DECL
+// pops them in parameter order, then emits FUNC_CALL with the argument
+// count. The VM keeps the return address and locals in a call frame, which
+// makes recursive and nested calls safe. The target address is relocated
+// after the whole program is compiled, since the callee may come later.
+// Names that are not user functions are looked up among the natives.
 func (c *FunctionCallCompiler) Compile(expr *ast.FunctionCall) error {
 	function, exists := c.context.GetFunctionManager().GetFunction(expr.Name)
 	if !exists {
-		return fmt.Errorf("undefined function: %s", expr.Name)
+		if counts, ok := c.context.GetFunctionManager().GetConditional(expr.Name); ok {
+			return c.compileNamed(expr, counts)
+		}
+		if fn, ok := native.Lookup(expr.Name); ok {
+			return c.compileNative(expr, fn)
+		}
+		return interfaces.Errorf(diag.ErrUndefinedFunction, expr, "undefined function: %s", expr.Name)
 	}
 
 	if len(expr.Args) != function.ParamCount {
-		return fmt.Errorf("function %s requires %d arguments, %d given",
+		return interfaces.Errorf(diag.ErrArgumentCount, expr, "function %s requires %d arguments, %d given",
 			expr.Name, function.ParamCount, len(expr.Args))
 	}
 
@@ -40,7 +58,157 @@ func (c *FunctionCallCompiler) Compile(expr *ast.FunctionCall) error {
 	c.context.GetBytecodeBuilder().Append(bytecode.OP_FUNC_CALL)
 	c.context.GetBytecodeBuilder().Append(byte(len(expr.Args)))
 
-	c.context.GetBytecodeBuilder().AppendUint16(uint16(function.Address))
+	c.context.GetFunctionManager().AddRelocation(function.Name, c.context.GetBytecodeBuilder().CurrentPosition())
+	c.context.GetBytecodeBuilder().AppendUint16(0xFFFF) // Placeholder
+
+	return nil
+}
+
+// compileNamed calls a function that is declared in a block or a function
+// body. Which declaration runs, if any, is only known at run time, so
+// CALL_NAMED looks the name up among the functions bound so far. The
+// arguments are pushed like for FUNC_CALL and their count must match one
+// of the declarations.
+func (c *FunctionCallCompiler) compileNamed(expr *ast.FunctionCall, counts []int) error {
+	matches := false
+	for _, count := range counts {
+		matches = matches || count == len(expr.Args)
+	}
+	if !matches {
+		return interfaces.Errorf(diag.ErrArgumentCount, expr, "function %s requires %d arguments, %d given",
+			expr.Name, counts[0], len(expr.Args))
+	}
+
+	for i := len(expr.Args) - 1; i >= 0; i-- {
+		if err := c.exprCompiler.CompileExpr(expr.Args[i]); err != nil {
+			return err
+		}
+	}
+
+	idx := c.context.GetConstantPool().Add(constant.Constant{
+		Type:  "string",
+		Value: expr.Name,
+	})
 
+	c.context.GetBytecodeBuilder().Append(bytecode.OP_CALL_NAMED)
+	c.context.GetBytecodeBuilder().Append(byte(idx))
+	c.context.GetBytecodeBuilder().Append(byte(len(expr.Args)))
 	return nil
 }
+
+// CompileIndirect calls the value of the callee expression, a closure. The
+// callee goes below the arguments, which are pushed last to first like for
+// FUNC_CALL, and CALL_INDIRECT checks the argument count at run time.
+func (c *FunctionCallCompiler) CompileIndirect(expr *ast.CallExpr) error {
+	if len(expr.Args) > 255 {
+		return interfaces.Errorf(diag.ErrArgumentCount, expr, "too many arguments")
+	}
+
+	if err := c.exprCompiler.CompileExpr(expr.Callee); err != nil {
+		return err
+	}
+
+	for i := len(expr.Args) - 1; i >= 0; i-- {
+		if err := c.exprCompiler.CompileExpr(expr.Args[i]); err != nil {
+			return err
+		}
+	}
+
+	c.context.GetBytecodeBuilder().Append(bytecode.OP_CALL_INDIRECT)
+	c.context.GetBytecodeBuilder().Append(byte(len(expr.Args)))
+	return nil
+}
+
+// compileNative pushes the arguments in source order and emits CALL_NATIVE
+// with the function name and the argument count. The VM pushes the result
+// followed by the final value of each by-reference argument, which are
+// stored back into their variables here, last one first.
+//
+// A by-reference first argument may also be an array element or a
+// property. It is written back by the AssignCompiler, which keeps the
+// array and the keys, or the object, under the arguments meanwhile:
+//
+//	sort($m['x'])   =>   LOAD m, 'x' ARRAY_FETCH_QUIET, CALL_NATIVE sort, TUCK 1 POP TUCK 3 POP, ARRAY_SET, STORE m
+func (c *FunctionCallCompiler) compileNative(expr *ast.FunctionCall, fn *native.Function) error {
+	if !fn.Accepts(len(expr.Args)) {
+		return interfaces.Errorf(diag.ErrArgumentCount, expr, "function %s requires %s, %d given",
+			expr.Name, arityText(fn), len(expr.Args))
+	}
+	if len(expr.Args) > 255 {
+		return interfaces.Errorf(diag.ErrArgumentCount, expr, "too many arguments to %s", expr.Name)
+	}
+
+	if len(expr.Args) > 0 && fn.IsByRef(0) {
+		switch arg := expr.Args[0].(type) {
+		case *ast.IndexExpr, *ast.PropertyFetch:
+			return c.assignCompiler.CompileByRef(arg, func(depth int) error {
+				return c.compileNativeCall(expr, fn, 1, depth)
+			})
+		case *ast.VarExpr:
+		default:
+			return interfaces.Errorf(diag.ErrInvalidOperand, arg,
+				"argument #1 of %s must be a variable, an array element or a property, it is passed by reference", expr.Name)
+		}
+	}
+	return c.compileNativeCall(expr, fn, 0, 0)
+}
+
+// compileNativeCall pushes the arguments from first on and emits the call.
+// The argument before first is already on the stack, above depth values
+// that its write back consumes; the result is moved under those values,
+// and the final value of the argument is left on top.
+func (c *FunctionCallCompiler) compileNativeCall(expr *ast.FunctionCall, fn *native.Function, first, depth int) error {
+	var byRef []string
+	for i := first; i < len(expr.Args); i++ {
+		arg := expr.Args[i]
+		if fn.IsByRef(i) {
+			v, ok := arg.(*ast.VarExpr)
+			if !ok {
+				return interfaces.Errorf(diag.ErrInvalidOperand, arg,
+					"argument #%d of %s must be a variable, it is passed by reference", i+1, expr.Name)
+			}
+			byRef = append(byRef, v.Name)
+		}
+
+		if err := c.exprCompiler.CompileExpr(arg); err != nil {
+			return err
+		}
+	}
+
+	idx := c.context.GetConstantPool().Add(constant.Constant{
+		Type:  "string",
+		Value: fn.Name,
+	})
+
+	builder := c.context.GetBytecodeBuilder()
+	builder.Append(bytecode.OP_CALL_NATIVE)
+	builder.Append(byte(idx))
+	builder.Append(byte(len(expr.Args)))
+
+	for i := len(byRef) - 1; i >= 0; i-- {
+		c.context.EmitStoreVar(byRef[i])
+	}
+
+	if depth > 0 {
+		// Swap the result and the final value, then move the result
+		// under the depth values.
+		builder.Append(bytecode.OP_TUCK)
+		builder.Append(1)
+		builder.Append(bytecode.OP_POP)
+		builder.Append(bytecode.OP_TUCK)
+		builder.Append(byte(depth + 1))
+		builder.Append(bytecode.OP_POP)
+	}
+	return nil
+}
+
+func arityText(fn *native.Function) string {
+	switch {
+	case fn.MaxArgs == native.Variadic:
+		return fmt.Sprintf("at least %d arguments", fn.MinArgs)
+	case fn.MinArgs == fn.MaxArgs:
+		return fmt.Sprintf("%d arguments", fn.MinArgs)
+	default:
+		return fmt.Sprintf("%d to %d arguments", fn.MinArgs, fn.MaxArgs)
+	}
+}
@@ -0,0 +1,42 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package expr
+
+import (
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/compiler/interfaces"
+)
+
+type InterpolatedStringCompiler struct {
+	context      interfaces.CompilationContext
+	exprCompiler interfaces.ExprCompiler
+}
+
+func NewInterpolatedStringCompiler(context interfaces.CompilationContext, exprCompiler interfaces.ExprCompiler) *InterpolatedStringCompiler {
+	return &InterpolatedStringCompiler{
+		context:      context,
+		exprCompiler: exprCompiler,
+	}
+}
+
+// Compile lowers the string to a chain of CONCATs. A string that starts
+// with a variable is concatenated onto "", so the result is always a string.
+func (c *InterpolatedStringCompiler) Compile(expr *ast.InterpolatedString) error {
+	parts := expr.Parts
+	if _, ok := parts[0].(*ast.StringLiteral); !ok {
+		parts = append([]ast.Expr{&ast.StringLiteral{Span: expr.Span}}, parts...)
+	}
+
+	if err := c.exprCompiler.CompileExpr(parts[0]); err != nil {
+		return err
+	}
+
+	for _, part := range parts[1:] {
+		if err := c.exprCompiler.CompileExpr(part); err != nil {
+			return err
+		}
+		c.context.GetBytecodeBuilder().Append(bytecode.OP_CONCAT)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package expr
+
+import (
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/compiler/constant"
+	"github.com/neokofg/php-compiler/internal/compiler/interfaces"
+	"github.com/neokofg/php-compiler/internal/diag"
+)
+
+// IssetCompiler compiles isset and empty, which read their operands
+// without the warnings PHP gives for undefined array keys and properties.
+type IssetCompiler struct {
+	context      interfaces.CompilationContext
+	exprCompiler interfaces.ExprCompiler
+}
+
+func NewIssetCompiler(context interfaces.CompilationContext, exprCompiler interfaces.ExprCompiler) *IssetCompiler {
+	return &IssetCompiler{
+		context:      context,
+		exprCompiler: exprCompiler,
+	}
+}
+
+// CompileIsset checks the vars left to right and stops at the first one
+// that is null:
+//
+//	a LOAD_NULL IDENTITY_NE DUP JUMP_IF_FALSE end POP, b LOAD_NULL IDENTITY_NE, end:
+func (c *IssetCompiler) CompileIsset(expr *ast.IssetExpr) error {
+	builder := c.context.GetBytecodeBuilder()
+
+	var jumps []int
+	for i, v := range expr.Vars {
+		switch v.(type) {
+		case *ast.VarExpr, *ast.IndexExpr, *ast.PropertyFetch:
+		default:
+			return interfaces.Errorf(diag.ErrInvalidOperand, v,
+				"cannot use isset() on the result of an expression (you can use \"null !== expression\" instead)")
+		}
+
+		if err := c.CompileQuiet(v); err != nil {
+			return err
+		}
+		builder.Append(bytecode.OP_LOAD_NULL)
+		builder.Append(bytecode.OP_IDENTITY_NE)
+
+		if i < len(expr.Vars)-1 {
+			builder.Append(bytecode.OP_DUP)
+			jumps = append(jumps, emitJump(builder, bytecode.OP_JUMP_IF_FALSE))
+			builder.Append(bytecode.OP_POP)
+		}
+	}
+
+	for _, jump := range jumps {
+		patchJump(builder, jump)
+	}
+	return nil
+}
+
+// CompileEmpty compiles empty(expr) as !expr, reading expr quietly.
+func (c *IssetCompiler) CompileEmpty(expr *ast.EmptyExpr) error {
+	if err := c.CompileQuiet(expr.Expr); err != nil {
+		return err
+	}
+
+	c.context.GetBytecodeBuilder().Append(bytecode.OP_NOT)
+	return nil
+}
+
+// CompileQuiet compiles expr so that undefined variables and missing array
+// elements and properties along the way read as null without a warning.
+func (c *IssetCompiler) CompileQuiet(expr ast.Expr) error {
+	builder := c.context.GetBytecodeBuilder()
+
+	switch e := expr.(type) {
+	case *ast.VarExpr:
+		if e.Name == "this" {
+			return c.exprCompiler.CompileExpr(expr)
+		}
+		c.context.EmitLoadVarQuiet(e.Name)
+		return nil
+
+	case *ast.IndexExpr:
+		if e.Index == nil {
+			return interfaces.Errorf(diag.ErrInvalidOperand, e, "cannot use [] for reading")
+		}
+		if err := c.CompileQuiet(e.Base); err != nil {
+			return err
+		}
+		if err := c.exprCompiler.CompileExpr(e.Index); err != nil {
+			return err
+		}
+		builder.Append(bytecode.OP_ARRAY_GET_QUIET)
+		return nil
+
+	case *ast.PropertyFetch:
+		if err := c.CompileQuiet(e.Object); err != nil {
+			return err
+		}
+		nameIdx := c.context.GetConstantPool().Add(constant.Constant{
+			Type:  "string",
+			Value: e.Name,
+		})
+		builder.Append(bytecode.OP_PROP_GET_QUIET)
+		builder.Append(byte(nameIdx))
+		return nil
+
+	default:
+		return c.exprCompiler.CompileExpr(expr)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
 	"github.com/neokofg/php-compiler/internal/compiler/constant"
 	"github.com/neokofg/php-compiler/internal/compiler/interfaces"
+	"strconv"
 )
 
 type NumberCompiler struct {
@@ -29,3 +30,16 @@ func (c *NumberCompiler) Compile(expr *ast.NumberLiteral) error {
 	c.context.GetBytecodeBuilder().Append(byte(idx))
 	return nil
 }
+
+// CompileFloat stores the shortest representation that parses back to
+// exactly the same float.
+func (c *NumberCompiler) CompileFloat(expr *ast.FloatLiteral) error {
+	idx := c.context.GetConstantPool().Add(constant.Constant{
+		Type:  "float",
+		Value: strconv.FormatFloat(expr.Value, 'g', -1, 64),
+	})
+
+	c.context.GetBytecodeBuilder().Append(bytecode.OP_LOAD_CONST)
+	c.context.GetBytecodeBuilder().Append(byte(idx))
+	return nil
+}
@@ -0,0 +1,166 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package expr
+
+import (
+	"strings"
+
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/compiler/constant"
+	"github.com/neokofg/php-compiler/internal/compiler/interfaces"
+	"github.com/neokofg/php-compiler/internal/diag"
+)
+
+type ObjectCompiler struct {
+	context      interfaces.CompilationContext
+	exprCompiler interfaces.ExprCompiler
+}
+
+func NewObjectCompiler(context interfaces.CompilationContext, exprCompiler interfaces.ExprCompiler) *ObjectCompiler {
+	return &ObjectCompiler{
+		context:      context,
+		exprCompiler: exprCompiler,
+	}
+}
+
+// CompileNew pushes the constructor arguments last to first and emits NEW.
+// The VM creates the object, runs __construct when the class has one and
+// leaves the object on the stack.
+func (c *ObjectCompiler) CompileNew(expr *ast.NewExpr) error {
+	class, err := c.resolveClass(expr, expr.Class)
+	if err != nil {
+		return err
+	}
+
+	if err := c.compileArgs(expr, expr.Args); err != nil {
+		return err
+	}
+
+	c.context.GetBytecodeBuilder().Append(bytecode.OP_NEW)
+	c.context.GetBytecodeBuilder().Append(byte(c.addName(class)))
+	c.context.GetBytecodeBuilder().Append(byte(len(expr.Args)))
+	return nil
+}
+
+// CompileStaticCall pushes the arguments last to first and emits
+// STATIC_CALL with the class and method names. self, parent and static
+// are resolved by the VM from the calling frame.
+func (c *ObjectCompiler) CompileStaticCall(expr *ast.StaticCall) error {
+	class, err := c.resolveClass(expr, expr.Class)
+	if err != nil {
+		return err
+	}
+
+	if err := c.compileArgs(expr, expr.Args); err != nil {
+		return err
+	}
+
+	c.context.GetBytecodeBuilder().Append(bytecode.OP_STATIC_CALL)
+	c.context.GetBytecodeBuilder().Append(byte(c.addName(class)))
+	c.context.GetBytecodeBuilder().Append(byte(c.addName(expr.Name)))
+	c.context.GetBytecodeBuilder().Append(byte(len(expr.Args)))
+	return nil
+}
+
+// resolveClass returns the name to emit for a class reference. self,
+// parent and static are kept, in lower case, since they are relative to
+// the class of the running method; they are only valid inside a class.
+// Other names must be declared somewhere in the program.
+func (c *ObjectCompiler) resolveClass(node ast.Node, name string) (string, error) {
+	current := c.context.GetVariableManager().CurrentClass()
+
+	switch keyword := strings.ToLower(name); keyword {
+	case "self", "parent", "static":
+		if current == "" {
+			return "", interfaces.Errorf(diag.ErrUndefinedClass, node, "cannot use \"%s\" when no class scope is active", keyword)
+		}
+		if keyword == "parent" {
+			class, _ := c.context.GetClassManager().GetClass(current)
+			if class == nil || class.Decl.Parent == "" {
+				return "", interfaces.Errorf(diag.ErrUndefinedClass, node, "cannot use \"parent\" when current class scope has no parent")
+			}
+		}
+		return keyword, nil
+	}
+
+	class, exists := c.context.GetClassManager().GetClass(name)
+	if !exists {
+		return "", interfaces.Errorf(diag.ErrUndefinedClass, node, "undefined class: %s", name)
+	}
+	return class.Name, nil
+}
+
+// CompileInstanceof pushes the value and emits INSTANCEOF with the class
+// name. Unlike new, instanceof accepts a class that is not declared: no
+// value is an instance of it.
+func (c *ObjectCompiler) CompileInstanceof(expr *ast.InstanceofExpr) error {
+	class := expr.Class
+	switch strings.ToLower(class) {
+	case "self", "parent", "static":
+		var err error
+		if class, err = c.resolveClass(expr, class); err != nil {
+			return err
+		}
+	default:
+		if decl, exists := c.context.GetClassManager().GetClass(class); exists {
+			class = decl.Name
+		}
+	}
+
+	if err := c.exprCompiler.CompileExpr(expr.Expr); err != nil {
+		return err
+	}
+
+	c.context.GetBytecodeBuilder().Append(bytecode.OP_INSTANCEOF)
+	c.context.GetBytecodeBuilder().Append(byte(c.addName(class)))
+	return nil
+}
+
+func (c *ObjectCompiler) CompileProperty(expr *ast.PropertyFetch) error {
+	if err := c.exprCompiler.CompileExpr(expr.Object); err != nil {
+		return err
+	}
+
+	c.context.GetBytecodeBuilder().Append(bytecode.OP_PROP_GET)
+	c.context.GetBytecodeBuilder().Append(byte(c.addName(expr.Name)))
+	return nil
+}
+
+// CompileMethodCall pushes the object, then the arguments last to first.
+// Which method runs depends on the class of the object, so METHOD_CALL
+// looks it up by name at run time and calls it like FUNC_CALL, with the
+// object bound to $this.
+func (c *ObjectCompiler) CompileMethodCall(expr *ast.MethodCall) error {
+	if err := c.exprCompiler.CompileExpr(expr.Object); err != nil {
+		return err
+	}
+
+	if err := c.compileArgs(expr, expr.Args); err != nil {
+		return err
+	}
+
+	c.context.GetBytecodeBuilder().Append(bytecode.OP_METHOD_CALL)
+	c.context.GetBytecodeBuilder().Append(byte(c.addName(expr.Name)))
+	c.context.GetBytecodeBuilder().Append(byte(len(expr.Args)))
+	return nil
+}
+
+func (c *ObjectCompiler) compileArgs(call ast.Node, args []ast.Expr) error {
+	if len(args) > 255 {
+		return interfaces.Errorf(diag.ErrArgumentCount, call, "too many arguments")
+	}
+
+	for i := len(args) - 1; i >= 0; i-- {
+		if err := c.exprCompiler.CompileExpr(args[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *ObjectCompiler) addName(name string) int {
+	return c.context.GetConstantPool().Add(constant.Constant{
+		Type:  "string",
+		Value: name,
+	})
+}
@@ -2,10 +2,13 @@
 package expr
 
 import (
-	"fmt"
+	"strconv"
+
 	"github.com/neokofg/php-compiler/internal/ast"
 	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/compiler/constant"
 	"github.com/neokofg/php-compiler/internal/compiler/interfaces"
+	"github.com/neokofg/php-compiler/internal/diag"
 	"github.com/neokofg/php-compiler/internal/token"
 )
 
@@ -21,6 +24,8 @@ func NewUnaryCompiler(context interfaces.CompilationContext, exprCompiler interf
 	}
 }
 
+// Compile compiles -x and +x like PHP does, to x * -1 and x * 1, so that
+// they convert strings and overflow to float like the other arithmetic.
 func (c *UnaryCompiler) Compile(expr *ast.UnaryExpr) error {
 	if err := c.exprCompiler.CompileExpr(expr.Expr); err != nil {
 		return err
@@ -29,9 +34,27 @@ func (c *UnaryCompiler) Compile(expr *ast.UnaryExpr) error {
 	switch expr.Op {
 	case token.T_NOT:
 		c.context.GetBytecodeBuilder().Append(bytecode.OP_NOT)
+	case token.T_BIT_NOT:
+		c.context.GetBytecodeBuilder().Append(bytecode.OP_BIT_NOT)
+	case token.T_MINUS:
+		c.emitMul(-1)
+	case token.T_PLUS:
+		c.emitMul(1)
 	default:
-		return fmt.Errorf("unsupported unary operator: %v", expr.Op)
+		return interfaces.Errorf(diag.ErrCompile, expr, "unsupported unary operator: %v", expr.Op)
 	}
 
 	return nil
 }
+
+func (c *UnaryCompiler) emitMul(factor int64) {
+	idx := c.context.GetConstantPool().Add(constant.Constant{
+		Type:  "int",
+		Value: strconv.FormatInt(factor, 10),
+	})
+
+	builder := c.context.GetBytecodeBuilder()
+	builder.Append(bytecode.OP_LOAD_CONST)
+	builder.Append(byte(idx))
+	builder.Append(bytecode.OP_MUL)
+}
@@ -3,8 +3,8 @@ package expr
 
 import (
 	"github.com/neokofg/php-compiler/internal/ast"
-	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
 	"github.com/neokofg/php-compiler/internal/compiler/interfaces"
+	"github.com/neokofg/php-compiler/internal/diag"
 )
 
 type VarCompiler struct {
@@ -18,9 +18,10 @@ func NewVarCompiler(context interfaces.CompilationContext) *VarCompiler {
 }
 
 func (c *VarCompiler) Compile(expr *ast.VarExpr) error {
-	varIdx := c.context.GetVariableManager().GetIndex(expr.Name)
+	if expr.Name == "this" && !c.context.GetVariableManager().InMethod() {
+		return interfaces.Errorf(diag.ErrInvalidOperand, expr, "cannot use $this outside of an object context")
+	}
 
-	c.context.GetBytecodeBuilder().Append(bytecode.OP_LOAD_VAR)
-	c.context.GetBytecodeBuilder().Append(byte(varIdx))
+	c.context.EmitLoadVar(expr.Name)
 	return nil
 }
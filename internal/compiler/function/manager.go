@@ -3,29 +3,98 @@ package function
 
 import (
 	"fmt"
+	"sort"
 )
 
 type Function struct {
 	Name       string
 	ParamCount int
 	Address    int
+	Locals     []string // local variable names in slot order, parameters first
+}
+
+// ClosureName is the name of every closure and arrow function, as PHP
+// shows them in stack traces.
+const ClosureName = "{closure}"
+
+// Unresolved is the address of a function that is declared but whose code
+// has not been emitted yet.
+const Unresolved = -1
+
+// Relocation is a call site whose uint16 target address is patched once the
+// address of the called function is known.
+type Relocation struct {
+	Name     string
+	Position int
 }
 
 type Manager struct {
-	functions map[string]Function
+	functions   map[string]Function
+	conditional map[string][]int // parameter counts of the conditional declarations of each name
+	bound       []Function       // code of the conditional declarations
+	closures    []Function
+	relocations []Relocation
 }
 
 func NewManager() *Manager {
 	return &Manager{
-		functions: make(map[string]Function),
+		functions:   make(map[string]Function),
+		conditional: make(map[string][]int),
 	}
 }
 
-func (m *Manager) AddFunction(name string, paramCount int, address int) error {
+// Declare registers a function signature ahead of its code, so that calls
+// can precede the declaration like in PHP. The first signature wins;
+// redeclarations are reported by AddFunction.
+func (m *Manager) Declare(name string, paramCount int) {
+	if _, exists := m.functions[name]; exists {
+		return
+	}
+
+	m.functions[name] = Function{
+		Name:       name,
+		ParamCount: paramCount,
+		Address:    Unresolved,
+	}
+}
+
+// DeclareConditional registers a function declared in a block or in a
+// function body. It only exists once its declaration has run, so calls to
+// it are resolved by name at run time, and it may be declared several
+// times, once in each branch of an if.
+func (m *Manager) DeclareConditional(name string, paramCount int) {
+	m.conditional[name] = append(m.conditional[name], paramCount)
+}
+
+// GetConditional returns the parameter counts of the conditional
+// declarations of name.
+func (m *Manager) GetConditional(name string) ([]int, bool) {
+	counts, exists := m.conditional[name]
+	return counts, exists
+}
+
+// AddConditional records the code of a conditional declaration. A name
+// that also has a declaration at the top level of the script is reported
+// as redeclared, whichever comes first.
+func (m *Manager) AddConditional(name string, paramCount int, address int, locals []string) error {
 	if _, exists := m.functions[name]; exists {
 		return fmt.Errorf("function '%s' already defined", name)
 	}
 
+	m.bound = append(m.bound, Function{
+		Name:       name,
+		ParamCount: paramCount,
+		Address:    address,
+		Locals:     locals,
+	})
+	return nil
+}
+
+func (m *Manager) AddFunction(name string, paramCount int, address int) error {
+	if function, exists := m.functions[name]; exists && function.Address != Unresolved {
+		return fmt.Errorf("function '%s' already defined", name)
+	}
+
 	m.functions[name] = Function{
 		Name:       name,
 		ParamCount: paramCount,
@@ -35,7 +104,55 @@ func (m *Manager) AddFunction(name string, paramCount int, address int) error {
 	return nil
 }
 
+func (m *Manager) SetLocals(name string, locals []string) {
+	if function, exists := m.functions[name]; exists {
+		function.Locals = locals
+		m.functions[name] = function
+	}
+}
+
+// AddClosure records the code of a closure. Closures cannot be called by
+// name, so they are kept apart from the named functions.
+func (m *Manager) AddClosure(paramCount int, address int, locals []string) {
+	m.closures = append(m.closures, Function{
+		Name:       ClosureName,
+		ParamCount: paramCount,
+		Address:    address,
+		Locals:     locals,
+	})
+}
+
 func (m *Manager) GetFunction(name string) (Function, bool) {
 	function, exists := m.functions[name]
 	return function, exists
 }
+
+// AddRelocation records that the uint16 at position must hold the address
+// of the named function.
+func (m *Manager) AddRelocation(name string, position int) {
+	m.relocations = append(m.relocations, Relocation{Name: name, Position: position})
+}
+
+func (m *Manager) GetRelocations() []Relocation {
+	return m.relocations
+}
+
+// GetAllFunctions returns the functions and closures with emitted code
+// ordered by address.
+func (m *Manager) GetAllFunctions() []Function {
+	functions := make([]Function, 0, len(m.functions)+len(m.bound)+len(m.closures))
+	functions = append(functions, m.closures...)
+	functions = append(functions, m.bound...)
+	for _, function := range m.functions {
+		if function.Address == Unresolved {
+			continue
+		}
+		functions = append(functions, function)
+	}
+
+	sort.Slice(functions, func(i, j int) bool {
+		return functions[i].Address < functions[j].Address
+	})
+
+	return functions
+}
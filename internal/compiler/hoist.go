@@ -0,0 +1,139 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package compiler
+
+import (
+	"strings"
+
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/compiler/class"
+	"github.com/neokofg/php-compiler/internal/compiler/constant"
+	"github.com/neokofg/php-compiler/internal/compiler/function"
+)
+
+// declareFunctions registers the signature of every function declared in
+// stmts before any code is emitted, so a call can be compiled wherever it
+// appears in the file. Like in PHP, only the functions at the top level of
+// the script exist from the start. Those nested in blocks, loops, other
+// functions and closures are conditional: they are defined when their
+// declaration runs, and may be declared once in each branch of an if.
+func declareFunctions(functions *function.Manager, stmts []ast.Stmt, conditional bool) {
+	for _, stmt := range stmts {
+		if decl, ok := stmt.(*ast.FunctionDecl); ok {
+			decl.Conditional = conditional
+			if conditional {
+				functions.DeclareConditional(decl.Name, len(decl.Params))
+			} else {
+				functions.Declare(decl.Name, len(decl.Params))
+			}
+		}
+
+		for _, block := range ast.Blocks(stmt) {
+			declareFunctions(functions, block, true)
+		}
+	}
+}
+
+// bindFunctions emits a FUNC_BIND for each function declared at the top
+// level of the script, ahead of its first statement. Calls by name from
+// the script compile to FUNC_CALL and do not need it, but natives such as
+// array_map find the function they are passed by name among the bound
+// ones. The addresses are relocated like those of calls.
+func (c *Compiler) bindFunctions(stmts []ast.Stmt) {
+	bound := make(map[string]bool)
+	for _, stmt := range stmts {
+		decl, ok := stmt.(*ast.FunctionDecl)
+		if !ok || bound[decl.Name] {
+			continue
+		}
+		bound[decl.Name] = true
+
+		idx := c.context.ConstantPool.Add(constant.Constant{
+			Type:  "string",
+			Value: decl.Name,
+		})
+		c.context.BytecodeBuilder.MarkLine(decl.Pos().Line)
+		c.context.BytecodeBuilder.Append(bytecode.OP_FUNC_BIND)
+		c.context.BytecodeBuilder.Append(byte(idx))
+		c.context.FunctionManager.AddRelocation(decl.Name, c.context.BytecodeBuilder.CurrentPosition())
+		c.context.BytecodeBuilder.AppendUint16(0xFFFF)
+	}
+}
+
+// declareClasses registers every class declared in stmts, wherever it is,
+// so that new can be compiled before the declaration.
+func declareClasses(classes *class.Manager, stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		if decl, ok := stmt.(*ast.ClassDecl); ok {
+			classes.Declare(decl)
+		}
+
+		for _, block := range ast.Blocks(stmt) {
+			declareClasses(classes, block)
+		}
+	}
+}
+
+// hoistClasses moves the classes declared at the top level of the script
+// in front of the other statements. Their CLASS_* instructions then run
+// first, and code above a class can instantiate it like in PHP. A class
+// is moved after the parent, interfaces and traits it depends on, so that
+// it can extend a class declared further down. Classes in conditional
+// blocks are declared when the block runs.
+func hoistClasses(stmts []ast.Stmt) []ast.Stmt {
+	decls := make(map[string]*ast.ClassDecl)
+	for _, stmt := range stmts {
+		if decl, ok := stmt.(*ast.ClassDecl); ok {
+			key := strings.ToLower(decl.Name)
+			if _, exists := decls[key]; !exists {
+				decls[key] = decl
+			}
+		}
+	}
+
+	hoisted := make([]ast.Stmt, 0, len(stmts))
+	visited := make(map[*ast.ClassDecl]bool)
+	var visit func(decl *ast.ClassDecl)
+	visit = func(decl *ast.ClassDecl) {
+		if visited[decl] {
+			return
+		}
+		visited[decl] = true
+
+		deps := append([]string{decl.Parent}, decl.Interfaces...)
+		for _, use := range decl.Traits {
+			deps = append(deps, use.Traits...)
+		}
+		for _, name := range deps {
+			if dep, ok := decls[strings.ToLower(name)]; ok {
+				visit(dep)
+			}
+		}
+		hoisted = append(hoisted, decl)
+	}
+
+	for _, stmt := range stmts {
+		if decl, ok := stmt.(*ast.ClassDecl); ok {
+			visit(decl)
+		}
+	}
+	for _, stmt := range stmts {
+		if _, ok := stmt.(*ast.ClassDecl); !ok {
+			hoisted = append(hoisted, stmt)
+		}
+	}
+	return hoisted
+}
+
+// relocateCalls patches every call site with the final address of its
+// callee. Calls to functions whose code failed to compile keep the
+// placeholder; the failure has been reported already.
+func (c *Compiler) relocateCalls() {
+	for _, reloc := range c.context.FunctionManager.GetRelocations() {
+		callee, exists := c.context.FunctionManager.GetFunction(reloc.Name)
+		if !exists || callee.Address == function.Unresolved {
+			continue
+		}
+		c.context.BytecodeBuilder.PatchUint16(reloc.Position, callee.Address)
+	}
+}
@@ -0,0 +1,45 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package interfaces
+
+import (
+	"fmt"
+
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/diag"
+	"github.com/neokofg/php-compiler/internal/token"
+)
+
+type CompilationError struct {
+	Code string
+	Msg  string
+	Pos  token.Position
+	End  token.Position
+}
+
+func (e *CompilationError) Error() string {
+	if e.Pos.IsValid() {
+		return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+	}
+	return fmt.Sprintf("compilation error: %s", e.Msg)
+}
+
+func (e *CompilationError) Diagnostic() *diag.Diagnostic {
+	code := e.Code
+	if code == "" {
+		code = diag.ErrCompile
+	}
+	return &diag.Diagnostic{Severity: diag.Error, Code: code, Msg: e.Msg, Pos: e.Pos, End: e.End}
+}
+
+// Errorf reports a compilation error spanning node. node may be nil
+// when there is nothing in the source to point at.
+func Errorf(code string, node ast.Node, format string, args ...interface{}) error {
+	err := &CompilationError{
+		Code: code,
+		Msg:  fmt.Sprintf(format, args...),
+	}
+	if node != nil {
+		err.Pos, err.End = node.Pos(), node.End()
+	}
+	return err
+}
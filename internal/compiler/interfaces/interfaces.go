@@ -4,17 +4,33 @@ package interfaces
 import (
 	"github.com/neokofg/php-compiler/internal/ast"
 	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/compiler/class"
 	"github.com/neokofg/php-compiler/internal/compiler/constant"
 	"github.com/neokofg/php-compiler/internal/compiler/function"
 	"github.com/neokofg/php-compiler/internal/compiler/variable"
+	"github.com/neokofg/php-compiler/internal/diag"
 )
 
 type ExprCompiler interface {
 	CompileExpr(expr ast.Expr) error
+
+	// CompileForEffect compiles expr and leaves nothing on the stack, as
+	// for an expression statement.
+	CompileForEffect(expr ast.Expr) error
+
+	// SetClosureCompiler provides the compiler of closures, whose bodies
+	// are statements inside an expression.
+	SetClosureCompiler(closures ClosureCompiler)
+}
+
+type ClosureCompiler interface {
+	CompileClosure(expr *ast.ClosureExpr) error
+	CompileArrowFunction(expr *ast.ArrowFunction) error
 }
 
 type StmtCompiler interface {
 	CompileStmt(stmt ast.Stmt) error
+	CompileBlock(stmts []ast.Stmt) error
 }
 
 type CompilationContext interface {
@@ -22,14 +38,32 @@ type CompilationContext interface {
 	GetConstantPool() *constant.Pool
 	GetVariableManager() *variable.Manager
 
+	EmitLoadVar(name string)
+	EmitLoadVarQuiet(name string)
+	EmitStoreVar(name string)
+	EmitRefVar(name string)
+	EmitUnsetVar(name string)
+	EmitBindVar(name string)
+
 	EnterLoop() *LoopContext
 	ExitLoop()
 	GetCurrentLoop() *LoopContext
+	SetCurrentLoop(loop *LoopContext)
+	StackDepth() int
+
+	EnterTry(finally []ast.Stmt) *TryContext
+	ExitTry()
+	GetCurrentTry() *TryContext
+	SetCurrentTry(try *TryContext)
 
 	AddPendingJump(position int, isBreak bool)
 	ApplyPendingJumps()
 
 	GetFunctionManager() *function.Manager
+	GetClassManager() *class.Manager
+
+	ReportError(err error)
+	Warnf(code string, node ast.Node, format string, args ...interface{})
 }
 
 type JumpPatch struct {
@@ -43,6 +77,56 @@ type LoopContext struct {
 	EndPos       int
 	Parent       *LoopContext
 	PendingJumps []JumpPatch
+	StackSlots   int // values the loop keeps on the stack while its body runs
+}
+
+// Encloses reports whether loop is l or one of its parents.
+func (l *LoopContext) Encloses(loop *LoopContext) bool {
+	for ; loop != nil; loop = loop.Parent {
+		if loop == l {
+			return true
+		}
+	}
+	return false
+}
+
+// TryContext tracks a try block whose body or catch clauses are being
+// compiled. A return, break or continue leaving it runs Finally inline;
+// that code is cut out of the protected ranges, since the block's own
+// handlers must not catch what it throws.
+type TryContext struct {
+	Finally []ast.Stmt
+	Loop    *LoopContext // innermost loop around the try statement
+	Parent  *TryContext
+	open    int
+	ranges  [][2]int
+}
+
+// Begin starts a protected range at pos.
+func (t *TryContext) Begin(pos int) {
+	t.open = pos
+}
+
+// Suspend closes the protected range at pos.
+func (t *TryContext) Suspend(pos int) {
+	if pos > t.open {
+		t.ranges = append(t.ranges, [2]int{t.open, pos})
+	}
+	t.open = pos
+}
+
+// Resume reopens the protected range at pos.
+func (t *TryContext) Resume(pos int) {
+	t.open = pos
+}
+
+// End closes the protected range at pos and returns the ranges recorded
+// since the last End.
+func (t *TryContext) End(pos int) [][2]int {
+	t.Suspend(pos)
+	ranges := t.ranges
+	t.ranges = nil
+	return ranges
 }
 
 type Context struct {
@@ -50,7 +134,10 @@ type Context struct {
 	ConstantPool    *constant.Pool
 	VariableManager *variable.Manager
 	CurrentLoop     *LoopContext
+	CurrentTry      *TryContext
 	FunctionManager *function.Manager
+	ClassManager    *class.Manager
+	Diagnostics     diag.List
 }
 
 func NewContext() *Context {
@@ -60,6 +147,7 @@ func NewContext() *Context {
 		VariableManager: variable.NewManager(),
 		CurrentLoop:     nil,
 		FunctionManager: function.NewManager(),
+		ClassManager:    class.NewManager(),
 	}
 }
 
@@ -79,10 +167,132 @@ func (c *Context) GetCurrentLoop() *LoopContext {
 	return c.CurrentLoop
 }
 
+func (c *Context) SetCurrentLoop(loop *LoopContext) {
+	c.CurrentLoop = loop
+}
+
+// StackDepth returns how many values the enclosing loops of the current
+// function keep on the operand stack.
+func (c *Context) StackDepth() int {
+	depth := 0
+	for loop := c.CurrentLoop; loop != nil; loop = loop.Parent {
+		depth += loop.StackSlots
+	}
+	return depth
+}
+
+func (c *Context) EnterTry(finally []ast.Stmt) *TryContext {
+	try := &TryContext{
+		Finally: finally,
+		Loop:    c.CurrentLoop,
+		Parent:  c.CurrentTry,
+	}
+	c.CurrentTry = try
+	return try
+}
+
+func (c *Context) ExitTry() {
+	if c.CurrentTry != nil {
+		c.CurrentTry = c.CurrentTry.Parent
+	}
+}
+
+func (c *Context) GetCurrentTry() *TryContext {
+	return c.CurrentTry
+}
+
+func (c *Context) SetCurrentTry(try *TryContext) {
+	c.CurrentTry = try
+}
+
 func (c *Context) GetFunctionManager() *function.Manager {
 	return c.FunctionManager
 }
 
+func (c *Context) GetClassManager() *class.Manager {
+	return c.ClassManager
+}
+
+// EmitLoadVar pushes the variable name, reading it from the current
+// call frame or from the globals depending on where it was resolved.
+func (c *Context) EmitLoadVar(name string) {
+	slot := c.VariableManager.Resolve(name)
+	if slot.Kind == variable.Local {
+		c.BytecodeBuilder.Append(bytecode.OP_LOAD_LOCAL)
+	} else {
+		c.BytecodeBuilder.Append(bytecode.OP_LOAD_VAR)
+	}
+	c.BytecodeBuilder.Append(byte(slot.Index))
+}
+
+// EmitLoadVarQuiet pushes the variable name like EmitLoadVar, without
+// warning when it is undefined, for isset, empty, ?? and writes.
+func (c *Context) EmitLoadVarQuiet(name string) {
+	slot := c.VariableManager.Resolve(name)
+	if slot.Kind == variable.Local {
+		c.BytecodeBuilder.Append(bytecode.OP_LOAD_LOCAL_QUIET)
+	} else {
+		c.BytecodeBuilder.Append(bytecode.OP_LOAD_VAR_QUIET)
+	}
+	c.BytecodeBuilder.Append(byte(slot.Index))
+}
+
+// EmitStoreVar pops the top of the stack into the variable name.
+func (c *Context) EmitStoreVar(name string) {
+	slot := c.VariableManager.Resolve(name)
+	if slot.Kind == variable.Local {
+		c.BytecodeBuilder.Append(bytecode.OP_STORE_LOCAL)
+	} else {
+		c.BytecodeBuilder.Append(bytecode.OP_STORE_VAR)
+	}
+	c.BytecodeBuilder.Append(byte(slot.Index))
+}
+
+// EmitRefVar binds the variable name to a reference, unless it is bound
+// already, and pushes the reference.
+func (c *Context) EmitRefVar(name string) {
+	slot := c.VariableManager.Resolve(name)
+	if slot.Kind == variable.Local {
+		c.BytecodeBuilder.Append(bytecode.OP_REF_LOCAL)
+	} else {
+		c.BytecodeBuilder.Append(bytecode.OP_REF_VAR)
+	}
+	c.BytecodeBuilder.Append(byte(slot.Index))
+}
+
+// EmitUnsetVar empties the variable name and detaches it from the
+// reference it may be bound to.
+func (c *Context) EmitUnsetVar(name string) {
+	slot := c.VariableManager.Resolve(name)
+	if slot.Kind == variable.Local {
+		c.BytecodeBuilder.Append(bytecode.OP_UNSET_LOCAL)
+	} else {
+		c.BytecodeBuilder.Append(bytecode.OP_UNSET_VAR)
+	}
+	c.BytecodeBuilder.Append(byte(slot.Index))
+}
+
+// EmitBindVar pops a reference and binds the variable name to it.
+func (c *Context) EmitBindVar(name string) {
+	slot := c.VariableManager.Resolve(name)
+	if slot.Kind == variable.Local {
+		c.BytecodeBuilder.Append(bytecode.OP_BIND_LOCAL)
+	} else {
+		c.BytecodeBuilder.Append(bytecode.OP_BIND_VAR)
+	}
+	c.BytecodeBuilder.Append(byte(slot.Index))
+}
+
+// ReportError records an error and lets compilation continue,
+// so that one run reports as many errors as possible.
+func (c *Context) ReportError(err error) {
+	c.Diagnostics.AddError(err, diag.ErrCompile)
+}
+
+func (c *Context) Warnf(code string, node ast.Node, format string, args ...interface{}) {
+	c.Diagnostics.Warnf(code, node.Pos(), node.End(), format, args...)
+}
+
 func (c *Context) EnterLoop() *LoopContext {
 	loop := &LoopContext{
 		Parent:       c.CurrentLoop,
@@ -119,8 +329,8 @@ func (c *Context) ApplyPendingJumps() {
 		}
 
 		if targetPos > 0 {
-			offset := uint16(targetPos - (patch.Position + 3))
-			c.BytecodeBuilder.PatchUint16(patch.Position+1, offset)
+			offset := targetPos - (patch.Position + 3)
+			c.BytecodeBuilder.PatchInt16(patch.Position+1, offset)
 		}
 	}
 }
@@ -0,0 +1,266 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package stmt
+
+import (
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/compiler/constant"
+	"github.com/neokofg/php-compiler/internal/compiler/interfaces"
+	"github.com/neokofg/php-compiler/internal/diag"
+	"github.com/neokofg/php-compiler/internal/token"
+)
+
+type ClassCompiler struct {
+	context          interfaces.CompilationContext
+	exprCompiler     interfaces.ExprCompiler
+	functionCompiler *FunctionCompiler
+	tables           map[string]resolvedTable
+}
+
+func NewClassCompiler(context interfaces.CompilationContext, exprCompiler interfaces.ExprCompiler, functionCompiler *FunctionCompiler) *ClassCompiler {
+	return &ClassCompiler{
+		context:          context,
+		exprCompiler:     exprCompiler,
+		functionCompiler: functionCompiler,
+		tables:           make(map[string]resolvedTable),
+	}
+}
+
+// Compile emits the method bodies behind a jump, like functions, followed
+// by the instructions that declare the class at run time: CLASS_DECL with
+// the class flags, CLASS_EXTENDS and CLASS_IMPLEMENTS, one CLASS_PROP per
+// property with its default on the stack, and one CLASS_METHOD per method
+// with the address of its FUNC_DECL. Methods are compiled as functions
+// named Class::method whose first parameter is $this, unless they are
+// static. Trait methods are compiled again for every class that uses them.
+// Interfaces and traits declare their name only; the compiler resolves
+// them into the classes that use them.
+func (c *ClassCompiler) Compile(stmt *ast.ClassDecl) error {
+	if err := c.context.GetClassManager().Define(stmt); err != nil {
+		return interfaces.Errorf(diag.ErrRedeclaredClass, stmt, "%v", err)
+	}
+
+	table, err := c.methods(stmt)
+	if err != nil {
+		return err
+	}
+
+	var methods []*member
+	for _, m := range table.members() {
+		if m.class == stmt.Name && stmt.Kind == token.T_CLASS {
+			methods = append(methods, m)
+		}
+	}
+
+	builder := c.context.GetBytecodeBuilder()
+
+	jumpPos := builder.CurrentPosition()
+	builder.Append(bytecode.OP_JUMP)
+	builder.AppendUint16(0)
+
+	addrs := make([]int, len(methods))
+	for i, m := range methods {
+		if m.abstract {
+			continue
+		}
+		addr, err := c.compileMethod(stmt.Name, m)
+		if err != nil {
+			return err
+		}
+		addrs[i] = addr
+	}
+
+	builder.PatchInt16(jumpPos+1, builder.CurrentPosition()-(jumpPos+3))
+
+	builder.Append(bytecode.OP_CLASS_DECL)
+	builder.Append(byte(c.addName(stmt.Name)))
+	builder.Append(classFlags(stmt))
+
+	if stmt.Parent != "" {
+		builder.Append(bytecode.OP_CLASS_EXTENDS)
+		builder.Append(byte(c.addName(stmt.Parent)))
+	}
+	for _, name := range stmt.Interfaces {
+		builder.Append(bytecode.OP_CLASS_IMPLEMENTS)
+		builder.Append(byte(c.addName(name)))
+	}
+
+	if stmt.Kind == token.T_INTERFACE && len(stmt.Properties) > 0 {
+		return interfaces.Errorf(diag.ErrInheritance, stmt.Properties[0], "interfaces may not include properties")
+	}
+	if stmt.Kind != token.T_CLASS {
+		return nil
+	}
+
+	props, err := c.properties(stmt)
+	if err != nil {
+		return err
+	}
+	for _, prop := range props {
+		if prop.Default == nil {
+			builder.Append(bytecode.OP_LOAD_NULL)
+		} else {
+			if !isConstantExpr(prop.Default) {
+				return interfaces.Errorf(diag.ErrInvalidOperand, prop.Default, "constant expression contains invalid operations")
+			}
+			if err := c.exprCompiler.CompileExpr(prop.Default); err != nil {
+				return err
+			}
+		}
+
+		builder.Append(bytecode.OP_CLASS_PROP)
+		builder.Append(byte(c.addName(prop.Name)))
+		builder.Append(modifiers(prop.Visibility))
+	}
+
+	for i, m := range methods {
+		flags := modifiers(m.visibility)
+		if m.decl.Static {
+			flags |= bytecode.MemberStatic
+		}
+		if m.abstract {
+			flags |= bytecode.MemberAbstract
+		}
+
+		builder.Append(bytecode.OP_CLASS_METHOD)
+		builder.Append(byte(c.addName(m.name)))
+		builder.Append(flags)
+		builder.AppendUint16(addrs[i])
+	}
+
+	return nil
+}
+
+// properties returns the properties of the traits decl uses followed by its
+// own. A class property replaces a trait property of the same name.
+func (c *ClassCompiler) properties(decl *ast.ClassDecl) ([]ast.PropertyDecl, error) {
+	var props []ast.PropertyDecl
+	index := make(map[string]int)
+	add := func(prop ast.PropertyDecl) {
+		if i, exists := index[prop.Name]; exists {
+			props[i] = prop
+			return
+		}
+		index[prop.Name] = len(props)
+		props = append(props, prop)
+	}
+
+	for _, use := range decl.Traits {
+		for _, name := range use.Traits {
+			trait, ok := c.context.GetClassManager().GetClass(name)
+			if !ok {
+				continue
+			}
+			traitProps, err := c.properties(trait.Decl)
+			if err != nil {
+				return nil, err
+			}
+			for _, prop := range traitProps {
+				add(prop)
+			}
+		}
+	}
+
+	declared := make(map[string]bool)
+	for _, prop := range decl.Properties {
+		if declared[prop.Name] {
+			return nil, interfaces.Errorf(diag.ErrCompile, prop, "cannot redeclare %s::$%s", decl.Name, prop.Name)
+		}
+		declared[prop.Name] = true
+		add(prop)
+	}
+	return props, nil
+}
+
+// compileMethod emits the code of a method of class and returns its
+// address.
+func (c *ClassCompiler) compileMethod(class string, m *member) (int, error) {
+	fn := m.decl.Function
+	for _, param := range fn.Params {
+		if param == "this" {
+			return 0, interfaces.Errorf(diag.ErrInvalidOperand, fn, "cannot use $this as parameter")
+		}
+	}
+
+	name := class + "::" + m.name
+	addr := c.context.GetBytecodeBuilder().CurrentPosition()
+	if m.origin == class {
+		fn.StartAddr = addr
+	}
+
+	params := fn.Params
+	if !m.decl.Static {
+		params = append([]string{"this"}, fn.Params...)
+	}
+	if err := c.context.GetFunctionManager().AddFunction(name, len(params), addr); err != nil {
+		return 0, interfaces.Errorf(diag.ErrRedeclaredFunction, fn, "%v", err)
+	}
+
+	variables := c.context.GetVariableManager()
+	scope := variables.EnterMethod(class, m.name, m.decl.Static)
+	defer variables.ExitFunction()
+
+	if err := c.functionCompiler.compileBody(fn, name, params, fn.Body, scope); err != nil {
+		return 0, err
+	}
+	return addr, nil
+}
+
+func (c *ClassCompiler) addName(name string) int {
+	return c.context.GetConstantPool().Add(constant.Constant{
+		Type:  "string",
+		Value: name,
+	})
+}
+
+func classFlags(decl *ast.ClassDecl) byte {
+	var flags byte
+	switch decl.Kind {
+	case token.T_INTERFACE:
+		flags |= bytecode.ClassInterface
+	case token.T_TRAIT:
+		flags |= bytecode.ClassTrait
+	}
+	if decl.Abstract {
+		flags |= bytecode.ClassAbstract
+	}
+	if decl.Final {
+		flags |= bytecode.ClassFinal
+	}
+	return flags
+}
+
+func modifiers(visibility token.TokenType) byte {
+	switch visibility {
+	case token.T_PROTECTED:
+		return bytecode.MemberProtected
+	case token.T_PRIVATE:
+		return bytecode.MemberPrivate
+	default:
+		return bytecode.MemberPublic
+	}
+}
+
+// isConstantExpr reports whether expr can be evaluated without any
+// variable or call, as PHP requires for property defaults.
+func isConstantExpr(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.NumberLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.BooleanLiteral, *ast.NullLiteral:
+		return true
+	case *ast.ArrayLiteral:
+		for _, item := range e.Items {
+			if (item.Key != nil && !isConstantExpr(item.Key)) || !isConstantExpr(item.Value) {
+				return false
+			}
+		}
+		return true
+	case *ast.UnaryExpr:
+		return isConstantExpr(e.Expr)
+	case *ast.BinaryExpr:
+		return isConstantExpr(e.Left) && isConstantExpr(e.Right)
+	case *ast.TernaryExpr:
+		return isConstantExpr(e.Cond) && (e.Then == nil || isConstantExpr(e.Then)) && isConstantExpr(e.Else)
+	default:
+		return false
+	}
+}
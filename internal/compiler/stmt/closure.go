@@ -0,0 +1,225 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package stmt
+
+import (
+	"strconv"
+
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/compiler/function"
+	"github.com/neokofg/php-compiler/internal/compiler/interfaces"
+	"github.com/neokofg/php-compiler/internal/diag"
+)
+
+type ClosureCompiler struct {
+	context          interfaces.CompilationContext
+	functionCompiler *FunctionCompiler
+}
+
+func NewClosureCompiler(context interfaces.CompilationContext, functionCompiler *FunctionCompiler) *ClosureCompiler {
+	return &ClosureCompiler{
+		context:          context,
+		functionCompiler: functionCompiler,
+	}
+}
+
+func (c *ClosureCompiler) CompileClosure(expr *ast.ClosureExpr) error {
+	return c.compile(expr, expr.Params, expr.Uses, expr.Body, false)
+}
+
+// CompileArrowFunction compiles fn(params) => expr like a closure that
+// returns expr and captures by value the variables expr uses. Those that
+// are undefined are captured as null without a warning.
+func (c *ClosureCompiler) CompileArrowFunction(expr *ast.ArrowFunction) error {
+	var uses []ast.ClosureUse
+	for _, name := range freeVariables(expr.Expr, expr.Params) {
+		uses = append(uses, ast.ClosureUse{Name: name})
+	}
+
+	body := []ast.Stmt{&ast.ReturnStmt{Span: ast.NewSpan(expr.Expr.Pos(), expr.Expr.End()), Expr: expr.Expr}}
+	return c.compile(expr, expr.Params, uses, body, true)
+}
+
+// compile emits the body of a closure behind a jump, then pushes the
+// captured values and emits CLOSURE. The body finds the captured values in
+// the locals that follow the parameters, in the order of uses, and then
+// $this when the closure is created in an instance method. quiet captures
+// undefined variables without a warning.
+func (c *ClosureCompiler) compile(node ast.Node, params []string, uses []ast.ClosureUse, body []ast.Stmt, quiet bool) error {
+	declared := make(map[string]bool)
+	for _, param := range params {
+		if param == "this" {
+			return interfaces.Errorf(diag.ErrInvalidOperand, node, "cannot use $this as parameter")
+		}
+		if declared[param] {
+			return interfaces.Errorf(diag.ErrInvalidOperand, node, "redefinition of parameter $%s", param)
+		}
+		declared[param] = true
+	}
+
+	variables := c.context.GetVariableManager()
+	bindThis := variables.InMethod()
+
+	var captured []string
+	for _, use := range uses {
+		if declared[use.Name] {
+			return interfaces.Errorf(diag.ErrInvalidOperand, node, "cannot use lexical variable $%s as a parameter name", use.Name)
+		}
+		declared[use.Name] = true
+		captured = append(captured, use.Name)
+	}
+	if bindThis {
+		captured = append(captured, "this")
+	}
+	if len(captured) > 255 {
+		return interfaces.Errorf(diag.ErrInvalidOperand, node, "too many variables captured by closure")
+	}
+
+	builder := c.context.GetBytecodeBuilder()
+	jumpPos := builder.CurrentPosition()
+	builder.Append(bytecode.OP_JUMP)
+	builder.AppendUint16(0)
+
+	addr := builder.CurrentPosition()
+	if err := c.compileBody(node, addr, params, captured, body); err != nil {
+		return err
+	}
+
+	builder.PatchInt16(jumpPos+1, builder.CurrentPosition()-(jumpPos+3))
+	builder.MarkLine(node.End().Line)
+
+	for _, use := range uses {
+		switch {
+		case use.ByRef:
+			c.context.EmitRefVar(use.Name)
+		case quiet:
+			c.context.EmitLoadVarQuiet(use.Name)
+		default:
+			c.context.EmitLoadVar(use.Name)
+		}
+	}
+	if bindThis {
+		c.context.EmitLoadVar("this")
+	}
+
+	builder.Append(bytecode.OP_CLOSURE)
+	builder.AppendUint16(addr)
+	builder.Append(byte(len(captured)))
+	return nil
+}
+
+// compileBody compiles the body of a closure in a scope of its own, which
+// inherits the class of the enclosing method so that $this, self and
+// private members work like there.
+func (c *ClosureCompiler) compileBody(node ast.Node, addr int, params, captured []string, body []ast.Stmt) error {
+	variables := c.context.GetVariableManager()
+	class, static := variables.CurrentClass(), !variables.InMethod()
+
+	// The address tells the static variables of different closures apart.
+	scope := variables.EnterFunction(function.ClosureName + "#" + strconv.Itoa(addr))
+	defer variables.ExitFunction()
+	scope.Class, scope.Static = class, static
+
+	for _, name := range append(append([]string{}, params...), captured...) {
+		variables.Resolve(name)
+	}
+
+	if err := c.functionCompiler.compileBody(node, scope.Function, params, body, scope); err != nil {
+		return err
+	}
+
+	c.context.GetFunctionManager().AddClosure(len(params), addr, scope.Names())
+	return nil
+}
+
+// freeVariables returns the variables expr uses that are not params, in
+// order of first use. They are what an arrow function captures.
+func freeVariables(expr ast.Expr, params []string) []string {
+	bound := make(map[string]bool)
+	for _, param := range params {
+		bound[param] = true
+	}
+	bound["this"] = true
+
+	var names []string
+	seen := make(map[string]bool)
+	use := func(name string) {
+		if !bound[name] && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	var walk func(ast.Expr)
+	walk = func(expr ast.Expr) {
+		switch e := expr.(type) {
+		case *ast.VarExpr:
+			use(e.Name)
+		case *ast.InterpolatedString:
+			for _, part := range e.Parts {
+				walk(part)
+			}
+		case *ast.ArrayLiteral:
+			for _, item := range e.Items {
+				walk(item.Key)
+				walk(item.Value)
+			}
+		case *ast.IndexExpr:
+			walk(e.Base)
+			walk(e.Index)
+		case *ast.BinaryExpr:
+			walk(e.Left)
+			walk(e.Right)
+		case *ast.UnaryExpr:
+			walk(e.Expr)
+		case *ast.PostfixExpr:
+			walk(e.Expr)
+		case *ast.PrefixExpr:
+			walk(e.Expr)
+		case *ast.TernaryExpr:
+			walk(e.Cond)
+			walk(e.Then)
+			walk(e.Else)
+		case *ast.IssetExpr:
+			walkAll(walk, e.Vars)
+		case *ast.EmptyExpr:
+			walk(e.Expr)
+		case *ast.AssignExpr:
+			walk(e.Target)
+			walk(e.Expr)
+		case *ast.FunctionCall:
+			walkAll(walk, e.Args)
+		case *ast.NewExpr:
+			walkAll(walk, e.Args)
+		case *ast.InstanceofExpr:
+			walk(e.Expr)
+		case *ast.PropertyFetch:
+			walk(e.Object)
+		case *ast.MethodCall:
+			walk(e.Object)
+			walkAll(walk, e.Args)
+		case *ast.StaticCall:
+			walkAll(walk, e.Args)
+		case *ast.CallExpr:
+			walk(e.Callee)
+			walkAll(walk, e.Args)
+		case *ast.ClosureExpr:
+			for _, u := range e.Uses {
+				use(u.Name)
+			}
+		case *ast.ArrowFunction:
+			for _, name := range freeVariables(e.Expr, e.Params) {
+				use(name)
+			}
+		}
+	}
+	walk(expr)
+
+	return names
+}
+
+func walkAll(walk func(ast.Expr), exprs []ast.Expr) {
+	for _, expr := range exprs {
+		walk(expr)
+	}
+}
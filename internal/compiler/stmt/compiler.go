@@ -2,26 +2,30 @@
 package stmt
 
 import (
-	"fmt"
 	"github.com/neokofg/php-compiler/internal/ast"
 	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
 	"github.com/neokofg/php-compiler/internal/compiler/interfaces"
+	"github.com/neokofg/php-compiler/internal/diag"
 )
 
 type stmtCompiler struct {
 	context                  interfaces.CompilationContext
 	exprCompiler             interfaces.ExprCompiler
-	assignCompiler           *AssignCompiler
-	compoundAssignCompiler   *CompoundAssignCompiler
 	echoCompiler             *EchoCompiler
 	ifCompiler               *IfCompiler
 	whileCompiler            *WhileCompiler
 	forCompiler              *ForCompiler
+	foreachCompiler          *ForeachCompiler
 	doWhileCompiler          *DoWhileCompiler
 	switchCompiler           *SwitchCompiler
 	functionCompiler         *FunctionCompiler
 	returnCompiler           *ReturnCompiler
 	functionCallStmtCompiler *FunctionCallStmtCompiler
+	globalCompiler           *GlobalCompiler
+	staticCompiler           *StaticCompiler
+	classCompiler            *ClassCompiler
+	tryCompiler              *TryCompiler
+	unsetCompiler            *UnsetCompiler
 }
 
 func NewCompiler(context interfaces.CompilationContext, exprCompiler interfaces.ExprCompiler) interfaces.StmtCompiler {
@@ -30,28 +34,32 @@ func NewCompiler(context interfaces.CompilationContext, exprCompiler interfaces.
 		exprCompiler: exprCompiler,
 	}
 
-	compiler.assignCompiler = NewAssignCompiler(context, exprCompiler)
-	compiler.compoundAssignCompiler = NewCompoundAssignCompiler(context, exprCompiler)
 	compiler.echoCompiler = NewEchoCompiler(context, exprCompiler)
 
 	compiler.ifCompiler = NewIfCompiler(context, exprCompiler, compiler)
 	compiler.whileCompiler = NewWhileCompiler(context, exprCompiler, compiler)
 	compiler.forCompiler = NewForCompiler(context, exprCompiler, compiler)
+	compiler.foreachCompiler = NewForeachCompiler(context, exprCompiler, compiler)
 	compiler.doWhileCompiler = NewDoWhileCompiler(context, exprCompiler, compiler)
 	compiler.switchCompiler = NewSwitchCompiler(context, exprCompiler, compiler)
 	compiler.functionCompiler = NewFunctionCompiler(context, compiler)
-	compiler.returnCompiler = NewReturnCompiler(context, exprCompiler)
+	compiler.tryCompiler = NewTryCompiler(context, exprCompiler, compiler)
+	compiler.returnCompiler = NewReturnCompiler(context, exprCompiler, compiler.tryCompiler)
 	compiler.functionCallStmtCompiler = NewFunctionCallStmtCompiler(context, exprCompiler)
+	compiler.globalCompiler = NewGlobalCompiler(context)
+	compiler.staticCompiler = NewStaticCompiler(context, exprCompiler)
+	compiler.classCompiler = NewClassCompiler(context, exprCompiler, compiler.functionCompiler)
+	compiler.unsetCompiler = NewUnsetCompiler(context, exprCompiler)
+
+	exprCompiler.SetClosureCompiler(NewClosureCompiler(context, compiler.functionCompiler))
 
 	return compiler
 }
 
 func (c *stmtCompiler) CompileStmt(stmt ast.Stmt) error {
+	c.context.GetBytecodeBuilder().MarkLine(stmt.Pos().Line)
+
 	switch s := stmt.(type) {
-	case *ast.AssignStmt:
-		return c.assignCompiler.Compile(s)
-	case *ast.CompoundAssignStmt:
-		return c.compoundAssignCompiler.Compile(s)
 	case *ast.EchoStmt:
 		return c.echoCompiler.Compile(s)
 	case *ast.IfStmt:
@@ -60,6 +68,8 @@ func (c *stmtCompiler) CompileStmt(stmt ast.Stmt) error {
 		return c.whileCompiler.Compile(s)
 	case *ast.ForStmt:
 		return c.forCompiler.Compile(s)
+	case *ast.ForeachStmt:
+		return c.foreachCompiler.Compile(s)
 	case *ast.DoWhileStmt:
 		return c.doWhileCompiler.Compile(s)
 	case *ast.SwitchStmt:
@@ -68,24 +78,74 @@ func (c *stmtCompiler) CompileStmt(stmt ast.Stmt) error {
 		return c.functionCompiler.Compile(s)
 	case *ast.ReturnStmt:
 		return c.returnCompiler.Compile(s)
-	case *ast.PostfixExpr:
-		return c.exprCompiler.CompileExpr(s)
-	case *ast.PrefixExpr:
-		return c.exprCompiler.CompileExpr(s)
 	case *ast.BreakStmt:
-		return c.compileBreak()
+		return c.compileBreak(s)
 	case *ast.ContinueStmt:
-		return c.compileContinue()
+		return c.compileContinue(s)
 	case *ast.FunctionCallStmt:
 		return c.functionCallStmtCompiler.Compile(s)
+	case *ast.GlobalStmt:
+		return c.globalCompiler.Compile(s)
+	case *ast.StaticStmt:
+		return c.staticCompiler.Compile(s)
+	case *ast.ClassDecl:
+		return c.classCompiler.Compile(s)
+	case *ast.ExprStmt:
+		return c.compileExprStmt(s.Expr)
+	case *ast.TryStmt:
+		return c.tryCompiler.Compile(s)
+	case *ast.ThrowStmt:
+		return c.tryCompiler.CompileThrow(s)
+	case *ast.UnsetStmt:
+		return c.unsetCompiler.Compile(s)
 	default:
-		return fmt.Errorf("unsupported statement type: %T", stmt)
+		return interfaces.Errorf(diag.ErrCompile, stmt, "unsupported statement type: %T", stmt)
 	}
 }
 
-func (c *stmtCompiler) compileBreak() error {
+// CompileBlock compiles a list of statements. Errors are reported to the
+// context and compilation goes on with the next statement. Code following
+// a return, break or continue gets a single warning per block.
+func (c *stmtCompiler) CompileBlock(stmts []ast.Stmt) error {
+	for i, stmt := range stmts {
+		if err := c.CompileStmt(stmt); err != nil {
+			c.context.ReportError(err)
+		}
+
+		if i+1 < len(stmts) && endsControlFlow(stmt) {
+			unreachable := ast.NewSpan(stmts[i+1].Pos(), stmts[len(stmts)-1].End())
+			c.context.Warnf(diag.WarnUnreachableCode, unreachable, "unreachable code")
+			for _, rest := range stmts[i+1:] {
+				if err := c.CompileStmt(rest); err != nil {
+					c.context.ReportError(err)
+				}
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+func endsControlFlow(stmt ast.Stmt) bool {
+	switch stmt.(type) {
+	case *ast.ReturnStmt, *ast.BreakStmt, *ast.ContinueStmt, *ast.ThrowStmt:
+		return true
+	}
+	return false
+}
+
+func (c *stmtCompiler) compileExprStmt(expr ast.Expr) error {
+	return c.exprCompiler.CompileForEffect(expr)
+}
+
+func (c *stmtCompiler) compileBreak(stmt *ast.BreakStmt) error {
 	if c.context.GetCurrentLoop() == nil {
-		return fmt.Errorf("break statement outside of loop")
+		return interfaces.Errorf(diag.ErrLoopControl, stmt, "break statement outside of loop")
+	}
+
+	if err := c.tryCompiler.EmitFinally(c.context.GetCurrentLoop()); err != nil {
+		return err
 	}
 
 	jumpPos := c.context.GetBytecodeBuilder().CurrentPosition()
@@ -97,9 +157,13 @@ func (c *stmtCompiler) compileBreak() error {
 	return nil
 }
 
-func (c *stmtCompiler) compileContinue() error {
+func (c *stmtCompiler) compileContinue(stmt *ast.ContinueStmt) error {
 	if c.context.GetCurrentLoop() == nil {
-		return fmt.Errorf("continue statement outside of loop")
+		return interfaces.Errorf(diag.ErrLoopControl, stmt, "continue statement outside of loop")
+	}
+
+	if err := c.tryCompiler.EmitFinally(c.context.GetCurrentLoop()); err != nil {
+		return err
 	}
 
 	jumpPos := c.context.GetBytecodeBuilder().CurrentPosition()
@@ -28,10 +28,8 @@ func (c *DoWhileCompiler) Compile(stmt *ast.DoWhileStmt) error {
 	loopBodyStart := c.context.GetBytecodeBuilder().CurrentPosition()
 	loop.StartPos = loopBodyStart
 
-	for _, bodyStmt := range stmt.Body {
-		if err := c.stmtCompiler.CompileStmt(bodyStmt); err != nil {
-			return err
-		}
+	if err := c.stmtCompiler.CompileBlock(stmt.Body); err != nil {
+		return err
 	}
 
 	conditionPos := c.context.GetBytecodeBuilder().CurrentPosition()
@@ -41,11 +39,16 @@ func (c *DoWhileCompiler) Compile(stmt *ast.DoWhileStmt) error {
 		return err
 	}
 
+	c.context.GetBytecodeBuilder().Append(bytecode.OP_JUMP_IF_FALSE)
+	c.context.GetBytecodeBuilder().AppendUint16(3)
+
 	c.context.GetBytecodeBuilder().Append(bytecode.OP_JUMP)
-	jumpBackOffset := int16(loopBodyStart - (c.context.GetBytecodeBuilder().CurrentPosition() + 3))
+	jumpBackOffset := loopBodyStart - (c.context.GetBytecodeBuilder().CurrentPosition() + 2)
 	c.context.GetBytecodeBuilder().AppendInt16(jumpBackOffset)
 
 	loop.EndPos = c.context.GetBytecodeBuilder().CurrentPosition()
 
+	c.context.ApplyPendingJumps()
+
 	return nil
 }
@@ -24,10 +24,9 @@ func NewForCompiler(context interfaces.CompilationContext, exprCompiler interfac
 
 func (c *ForCompiler) Compile(stmt *ast.ForStmt) error {
 	if stmt.Init != nil {
-		if err := c.exprCompiler.CompileExpr(stmt.Init); err != nil {
+		if err := c.exprCompiler.CompileForEffect(stmt.Init); err != nil {
 			return err
 		}
-		c.context.GetBytecodeBuilder().Append(bytecode.OP_POP)
 	}
 
 	loop := c.context.EnterLoop()
@@ -55,30 +54,27 @@ func (c *ForCompiler) Compile(stmt *ast.ForStmt) error {
 	c.context.GetBytecodeBuilder().Append(bytecode.OP_JUMP_IF_FALSE)
 	c.context.GetBytecodeBuilder().AppendUint16(0xFFFF)
 
-	for _, bodyStmt := range stmt.Body {
-		if err := c.stmtCompiler.CompileStmt(bodyStmt); err != nil {
-			return err
-		}
+	if err := c.stmtCompiler.CompileBlock(stmt.Body); err != nil {
+		return err
 	}
 
 	incrementPos := c.context.GetBytecodeBuilder().CurrentPosition()
 	loop.ConditionPos = incrementPos
 
 	if stmt.Incr != nil {
-		if err := c.exprCompiler.CompileExpr(stmt.Incr); err != nil {
+		if err := c.exprCompiler.CompileForEffect(stmt.Incr); err != nil {
 			return err
 		}
-		c.context.GetBytecodeBuilder().Append(bytecode.OP_POP)
 	}
 
 	jumpBackOffset := conditionStartPos - (c.context.GetBytecodeBuilder().CurrentPosition() + 3)
 	c.context.GetBytecodeBuilder().Append(bytecode.OP_JUMP)
-	c.context.GetBytecodeBuilder().AppendInt16(int16(jumpBackOffset))
+	c.context.GetBytecodeBuilder().AppendInt16(jumpBackOffset)
 
 	loopEndPos := c.context.GetBytecodeBuilder().CurrentPosition()
 	loop.EndPos = loopEndPos
 
-	jumpFalseOffset := uint16(loopEndPos - (jumpFalsePos + 3))
+	jumpFalseOffset := loopEndPos - (jumpFalsePos + 3)
 	c.context.GetBytecodeBuilder().PatchUint16(jumpFalsePos+1, jumpFalseOffset)
 
 	c.context.ApplyPendingJumps()
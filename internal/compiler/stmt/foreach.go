@@ -0,0 +1,210 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package stmt
+
+import (
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/compiler/constant"
+	"github.com/neokofg/php-compiler/internal/compiler/interfaces"
+	"github.com/neokofg/php-compiler/internal/diag"
+)
+
+type ForeachCompiler struct {
+	context      interfaces.CompilationContext
+	exprCompiler interfaces.ExprCompiler
+	stmtCompiler interfaces.StmtCompiler
+}
+
+func NewForeachCompiler(context interfaces.CompilationContext, exprCompiler interfaces.ExprCompiler, stmtCompiler interfaces.StmtCompiler) *ForeachCompiler {
+	return &ForeachCompiler{
+		context:      context,
+		exprCompiler: exprCompiler,
+		stmtCompiler: stmtCompiler,
+	}
+}
+
+// Compile keeps the iterated array and the position on the stack while the
+// loop runs. By value, FOREACH_INIT takes a reference to the array, so writes
+// to the variable in the body go to a copy and the loop sees the array as it
+// was. By reference, every iteration reloads the iterated variable, property
+// or element, so elements added by the body are visited, and binds the value
+// variable to the element, which FOREACH_NEXT turns into a reference. The
+// array, which that may have separated, is stored back first:
+//
+//	cond: LOAD arr FOREACH_NEXT 1 end, STORE arr BIND v STORE k, body JUMP cond, end: FOREACH_END 1
+//
+// Like in PHP, the value variable stays bound to the last element after
+// the loop.
+func (c *ForeachCompiler) Compile(stmt *ast.ForeachStmt) error {
+	var holder *iterated
+	if stmt.ByRef {
+		var err error
+		if holder, err = c.iterated(stmt.Expr); err != nil {
+			return err
+		}
+	}
+
+	if err := c.exprCompiler.CompileExpr(stmt.Expr); err != nil {
+		return err
+	}
+
+	builder := c.context.GetBytecodeBuilder()
+	byRef := byte(0)
+	if stmt.ByRef {
+		byRef = 1
+	}
+
+	builder.Append(bytecode.OP_FOREACH_INIT)
+	builder.Append(byRef)
+
+	loop := c.context.EnterLoop()
+	loop.StackSlots = 2
+	defer func() {
+		c.context.ExitLoop()
+	}()
+
+	loop.ConditionPos = builder.CurrentPosition()
+	loop.StartPos = loop.ConditionPos
+	if holder != nil {
+		holder.load()
+	}
+
+	nextPos := builder.CurrentPosition()
+	builder.Append(bytecode.OP_FOREACH_NEXT)
+	builder.Append(byRef)
+	builder.AppendUint16(0xFFFF)
+
+	if holder != nil {
+		holder.store()
+		c.context.EmitBindVar(stmt.Value)
+	} else {
+		c.context.EmitStoreVar(stmt.Value)
+	}
+	if stmt.Key != "" {
+		c.context.EmitStoreVar(stmt.Key)
+	} else {
+		builder.Append(bytecode.OP_POP)
+	}
+
+	if err := c.stmtCompiler.CompileBlock(stmt.Body); err != nil {
+		return err
+	}
+
+	jumpBackOffset := loop.ConditionPos - (builder.CurrentPosition() + 3)
+	builder.Append(bytecode.OP_JUMP)
+	builder.AppendInt16(jumpBackOffset)
+
+	loop.EndPos = builder.CurrentPosition()
+	builder.PatchUint16(nextPos+2, loop.EndPos-(nextPos+4))
+
+	builder.Append(bytecode.OP_FOREACH_END)
+	builder.Append(byRef)
+
+	c.context.ApplyPendingJumps()
+
+	return nil
+}
+
+// iterated is where a loop by reference finds the array at each
+// iteration and stores it back.
+type iterated struct {
+	load  func()
+	store func()
+}
+
+// iterated accepts a variable, a property of a variable such as
+// $this->items, or an element of one of them such as $m['x'], as the
+// array of a loop by reference. The object and the keys are evaluated
+// again for each access, so keys must be variables or literals; objects
+// are handles, so that is the same object.
+func (c *ForeachCompiler) iterated(expr ast.Expr) (*iterated, error) {
+	builder := c.context.GetBytecodeBuilder()
+
+	switch e := expr.(type) {
+	case *ast.VarExpr:
+		if e.Name != "this" {
+			return &iterated{
+				load: func() {
+					c.context.EmitLoadVarQuiet(e.Name)
+				},
+				store: func() {
+					c.context.EmitStoreVar(e.Name)
+				},
+			}, nil
+		}
+
+	case *ast.PropertyFetch:
+		if object, ok := e.Object.(*ast.VarExpr); ok {
+			nameIdx := c.context.GetConstantPool().Add(constant.Constant{
+				Type:  "string",
+				Value: e.Name,
+			})
+			return &iterated{
+				load: func() {
+					c.context.EmitLoadVar(object.Name)
+					builder.Append(bytecode.OP_PROP_GET)
+					builder.Append(byte(nameIdx))
+				},
+				store: func() {
+					// Move the object under the array for PROP_SET.
+					c.context.EmitLoadVar(object.Name)
+					builder.Append(bytecode.OP_TUCK)
+					builder.Append(1)
+					builder.Append(bytecode.OP_POP)
+					builder.Append(bytecode.OP_PROP_SET)
+					builder.Append(byte(nameIdx))
+				},
+			}, nil
+		}
+
+	case *ast.IndexExpr:
+		base, err := c.iterated(e.Base)
+		if err != nil {
+			return nil, err
+		}
+		if !plainKey(e.Index) {
+			return nil, interfaces.Errorf(diag.ErrInvalidOperand, e.Index,
+				"foreach by reference over an array element needs a variable or a literal as the key")
+		}
+		return &iterated{
+			load: func() {
+				base.load()
+				c.exprCompiler.CompileExpr(e.Index)
+				builder.Append(bytecode.OP_ARRAY_GET_QUIET)
+			},
+			store: func() {
+				// Write the element like an assignment: the array that
+				// holds it and the key go under the value for ARRAY_SET,
+				// which leaves the updated array to store in turn.
+				base.load()
+				swap(builder)
+				c.exprCompiler.CompileExpr(e.Index)
+				swap(builder)
+				builder.Append(bytecode.OP_ARRAY_SET)
+				builder.Append(1)
+				builder.Append(0)
+				base.store()
+			},
+		}, nil
+	}
+
+	return nil, interfaces.Errorf(diag.ErrInvalidOperand, expr,
+		"foreach by reference needs a variable, a property of a variable or an element of one of them to iterate over")
+}
+
+// plainKey reports whether key can be evaluated any number of times
+// with the same result: a variable or a literal.
+func plainKey(key ast.Expr) bool {
+	switch key.(type) {
+	case *ast.VarExpr, *ast.NumberLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.BooleanLiteral, *ast.NullLiteral:
+		return true
+	}
+	return false
+}
+
+// swap exchanges the two values on top of the stack.
+func swap(builder *bytecode.BytecodeBuilder) {
+	builder.Append(bytecode.OP_TUCK)
+	builder.Append(1)
+	builder.Append(bytecode.OP_POP)
+}
@@ -4,7 +4,11 @@ package stmt
 import (
 	"github.com/neokofg/php-compiler/internal/ast"
 	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/compiler/constant"
 	"github.com/neokofg/php-compiler/internal/compiler/interfaces"
+	"github.com/neokofg/php-compiler/internal/compiler/variable"
+	"github.com/neokofg/php-compiler/internal/diag"
+	"github.com/neokofg/php-compiler/internal/native"
 )
 
 type FunctionCompiler struct {
@@ -20,6 +24,16 @@ func NewFunctionCompiler(context interfaces.CompilationContext, stmtCompiler int
 }
 
 func (c *FunctionCompiler) Compile(stmt *ast.FunctionDecl) error {
+	if _, exists := native.Lookup(stmt.Name); exists {
+		return interfaces.Errorf(diag.ErrRedeclaredFunction, stmt, "cannot redeclare built-in function %s()", stmt.Name)
+	}
+
+	for _, param := range stmt.Params {
+		if param == "this" {
+			return interfaces.Errorf(diag.ErrInvalidOperand, stmt, "cannot use $this as parameter")
+		}
+	}
+
 	jumpPos := c.context.GetBytecodeBuilder().CurrentPosition()
 	c.context.GetBytecodeBuilder().Append(bytecode.OP_JUMP)
 	c.context.GetBytecodeBuilder().AppendUint16(0)
@@ -27,30 +41,87 @@ func (c *FunctionCompiler) Compile(stmt *ast.FunctionDecl) error {
 	funcStartAddr := c.context.GetBytecodeBuilder().CurrentPosition()
 	stmt.StartAddr = funcStartAddr
 
-	err := c.context.GetFunctionManager().AddFunction(stmt.Name, len(stmt.Params), funcStartAddr)
-	if err != nil {
+	if !stmt.Conditional {
+		err := c.context.GetFunctionManager().AddFunction(stmt.Name, len(stmt.Params), funcStartAddr)
+		if err != nil {
+			return interfaces.Errorf(diag.ErrRedeclaredFunction, stmt, "%v", err)
+		}
+	}
+
+	variables := c.context.GetVariableManager()
+	scope := variables.EnterFunction(stmt.Name)
+	defer variables.ExitFunction()
+
+	if err := c.compileBody(stmt, stmt.Name, stmt.Params, stmt.Body, scope); err != nil {
 		return err
 	}
 
+	endFuncPos := c.context.GetBytecodeBuilder().CurrentPosition()
+	offset := endFuncPos - (jumpPos + 3)
+	c.context.GetBytecodeBuilder().PatchInt16(jumpPos+1, offset)
+
+	if stmt.Conditional {
+		return c.bind(stmt, funcStartAddr, scope.Names())
+	}
+	return nil
+}
+
+// bind emits the FUNC_BIND that defines a conditional function when its
+// declaration runs. Calls to it look the name up at run time.
+func (c *FunctionCompiler) bind(stmt *ast.FunctionDecl, addr int, locals []string) error {
+	err := c.context.GetFunctionManager().AddConditional(stmt.Name, len(stmt.Params), addr, locals)
+	if err != nil {
+		return interfaces.Errorf(diag.ErrRedeclaredFunction, stmt, "%v", err)
+	}
+
+	idx := c.context.GetConstantPool().Add(constant.Constant{
+		Type:  "string",
+		Value: stmt.Name,
+	})
+
+	c.context.GetBytecodeBuilder().MarkLine(stmt.Pos().Line)
+	c.context.GetBytecodeBuilder().Append(bytecode.OP_FUNC_BIND)
+	c.context.GetBytecodeBuilder().Append(byte(idx))
+	c.context.GetBytecodeBuilder().AppendUint16(addr)
+	return nil
+}
+
+// compileBody emits the FUNC_DECL that binds the parameters to local slots,
+// the body and the closing EXIT_FUNC of a function or method whose scope
+// has been entered. node is the declaration, which errors point at.
+func (c *FunctionCompiler) compileBody(node ast.Node, name string, params []string, body []ast.Stmt, scope *variable.Scope) error {
+	variables := c.context.GetVariableManager()
+
+	// break, continue and return cannot leave the function body, so the
+	// loops and try blocks around the declaration are hidden from it.
+	loop, try := c.context.GetCurrentLoop(), c.context.GetCurrentTry()
+	c.context.SetCurrentLoop(nil)
+	c.context.SetCurrentTry(nil)
+	defer func() {
+		c.context.SetCurrentLoop(loop)
+		c.context.SetCurrentTry(try)
+	}()
+
+	scope.Addr = c.context.GetBytecodeBuilder().CurrentPosition()
 	c.context.GetBytecodeBuilder().Append(bytecode.OP_FUNC_DECL)
-	c.context.GetBytecodeBuilder().Append(byte(len(stmt.Params)))
+	c.context.GetBytecodeBuilder().Append(byte(len(params)))
 
-	for _, param := range stmt.Params {
-		varIdx := c.context.GetVariableManager().GetIndex(param)
-		c.context.GetBytecodeBuilder().Append(byte(varIdx))
+	for _, param := range params {
+		slot := variables.Resolve(param)
+		c.context.GetBytecodeBuilder().Append(byte(slot.Index))
 	}
 
-	for _, bodyStmt := range stmt.Body {
-		if err := c.stmtCompiler.CompileStmt(bodyStmt); err != nil {
-			return err
-		}
+	if err := c.stmtCompiler.CompileBlock(body); err != nil {
+		return err
 	}
 
-	c.context.GetBytecodeBuilder().Append(bytecode.OP_EXIT_FUNC)
+	if n := len(scope.Names()); n > variable.MaxSlots {
+		return interfaces.Errorf(diag.ErrLimit, node, "function uses %d local variables, at most %d are supported",
+			n, variable.MaxSlots)
+	}
 
-	endFuncPos := c.context.GetBytecodeBuilder().CurrentPosition()
-	offset := uint16(endFuncPos - (jumpPos + 3))
-	c.context.GetBytecodeBuilder().PatchUint16(jumpPos+1, offset)
+	c.context.GetBytecodeBuilder().Append(bytecode.OP_EXIT_FUNC)
+	c.context.GetFunctionManager().SetLocals(name, scope.Names())
 
 	return nil
 }
@@ -2,7 +2,6 @@
 package stmt
 
 import (
-	"fmt"
 	"github.com/neokofg/php-compiler/internal/ast"
 	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
 	"github.com/neokofg/php-compiler/internal/compiler/interfaces"
@@ -20,28 +19,13 @@ func NewFunctionCallStmtCompiler(context interfaces.CompilationContext, exprComp
 	}
 }
 
+// Compile emits the call like any other call expression and discards the
+// return value, so both go through the same calling convention.
 func (c *FunctionCallStmtCompiler) Compile(stmt *ast.FunctionCallStmt) error {
-	function, exists := c.context.GetFunctionManager().GetFunction(stmt.Call.Name)
-	if !exists {
-		return fmt.Errorf("undefined function: %s", stmt.Call.Name)
+	if err := c.exprCompiler.CompileExpr(stmt.Call); err != nil {
+		return err
 	}
 
-	if len(stmt.Call.Args) != function.ParamCount {
-		return fmt.Errorf("function %s requires %d arguments, %d given",
-			stmt.Call.Name, function.ParamCount, len(stmt.Call.Args))
-	}
-
-	for i := len(stmt.Call.Args) - 1; i >= 0; i-- {
-		if err := c.exprCompiler.CompileExpr(stmt.Call.Args[i]); err != nil {
-			return err
-		}
-	}
-
-	c.context.GetBytecodeBuilder().Append(bytecode.OP_FUNC_CALL)
-	c.context.GetBytecodeBuilder().Append(byte(len(stmt.Call.Args)))
-
-	c.context.GetBytecodeBuilder().AppendUint16(uint16(function.Address))
-
 	c.context.GetBytecodeBuilder().Append(bytecode.OP_POP)
 
 	return nil
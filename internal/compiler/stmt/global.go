@@ -0,0 +1,25 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package stmt
+
+import (
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/compiler/interfaces"
+)
+
+type GlobalCompiler struct {
+	context interfaces.CompilationContext
+}
+
+func NewGlobalCompiler(context interfaces.CompilationContext) *GlobalCompiler {
+	return &GlobalCompiler{context: context}
+}
+
+// Compile emits no code: `global $name` only changes which slot later
+// accesses to $name resolve to.
+func (c *GlobalCompiler) Compile(stmt *ast.GlobalStmt) error {
+	for _, name := range stmt.Names {
+		c.context.GetVariableManager().BindGlobal(name)
+	}
+
+	return nil
+}
@@ -31,10 +31,8 @@ func (c *IfCompiler) Compile(stmt *ast.IfStmt) error {
 	c.context.GetBytecodeBuilder().AppendUint16(0xFFFF) // Placeholder
 
 	// Compile THEN block
-	for _, thenStmt := range stmt.Then {
-		if err := c.stmtCompiler.CompileStmt(thenStmt); err != nil {
-			return err
-		}
+	if err := c.stmtCompiler.CompileBlock(stmt.Then); err != nil {
+		return err
 	}
 
 	if len(stmt.Else) > 0 {
@@ -45,23 +43,21 @@ func (c *IfCompiler) Compile(stmt *ast.IfStmt) error {
 		elseStart := c.context.GetBytecodeBuilder().CurrentPosition()
 
 		// Compile ELSE block
-		for _, elseStmt := range stmt.Else {
-			if err := c.stmtCompiler.CompileStmt(elseStmt); err != nil {
-				return err
-			}
+		if err := c.stmtCompiler.CompileBlock(stmt.Else); err != nil {
+			return err
 		}
 
 		endElse := c.context.GetBytecodeBuilder().CurrentPosition()
 
 		// Patch offsets
-		offsetJumpIfFalse := uint16(elseStart - (jumpIfFalsePos + 3))
+		offsetJumpIfFalse := elseStart - (jumpIfFalsePos + 3)
 		c.context.GetBytecodeBuilder().PatchUint16(jumpIfFalsePos+1, offsetJumpIfFalse)
 
-		offsetJumpOverElse := uint16(endElse - (jumpOverElsePos + 3))
-		c.context.GetBytecodeBuilder().PatchUint16(jumpOverElsePos+1, offsetJumpOverElse)
+		offsetJumpOverElse := endElse - (jumpOverElsePos + 3)
+		c.context.GetBytecodeBuilder().PatchInt16(jumpOverElsePos+1, offsetJumpOverElse)
 	} else {
 		afterThen := c.context.GetBytecodeBuilder().CurrentPosition()
-		offsetJumpIfFalse := uint16(afterThen - (jumpIfFalsePos + 3))
+		offsetJumpIfFalse := afterThen - (jumpIfFalsePos + 3)
 		c.context.GetBytecodeBuilder().PatchUint16(jumpIfFalsePos+1, offsetJumpIfFalse)
 	}
 
@@ -0,0 +1,533 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package stmt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/compiler/class"
+	"github.com/neokofg/php-compiler/internal/compiler/interfaces"
+	"github.com/neokofg/php-compiler/internal/diag"
+	"github.com/neokofg/php-compiler/internal/native"
+	"github.com/neokofg/php-compiler/internal/token"
+)
+
+// member is a method in the method table of a class: declared by the class,
+// copied from a trait or inherited from a parent or an interface.
+type member struct {
+	name       string // name in the class, which a trait alias can change
+	class      string // class the method belongs to; the using class for trait methods
+	origin     string // class, interface or trait whose source declares the method
+	decl       *ast.MethodDecl
+	visibility token.TokenType
+	abstract   bool
+}
+
+func (m *member) String() string {
+	return m.origin + "::" + m.decl.Function.Name
+}
+
+// signature formats the member like PHP does in compatibility errors.
+func (m *member) signature() string {
+	params := make([]string, len(m.decl.Function.Params))
+	for i, param := range m.decl.Function.Params {
+		params[i] = "$" + param
+	}
+	return fmt.Sprintf("%s(%s)", m, strings.Join(params, ", "))
+}
+
+// methodTable holds the methods of a class by lower-case name, in the order
+// they were added.
+type methodTable struct {
+	order  []string
+	byName map[string]*member
+}
+
+func newMethodTable() *methodTable {
+	return &methodTable{byName: make(map[string]*member)}
+}
+
+func (t *methodTable) get(name string) (*member, bool) {
+	m, ok := t.byName[strings.ToLower(name)]
+	return m, ok
+}
+
+func (t *methodTable) set(m *member) {
+	key := strings.ToLower(m.name)
+	if _, exists := t.byName[key]; !exists {
+		t.order = append(t.order, key)
+	}
+	t.byName[key] = m
+}
+
+func (t *methodTable) members() []*member {
+	members := make([]*member, len(t.order))
+	for i, key := range t.order {
+		members[i] = t.byName[key]
+	}
+	return members
+}
+
+// resolvedTable is the method table of a class together with the first
+// error found while building it.
+type resolvedTable struct {
+	table *methodTable
+	err   error
+}
+
+// methods returns the method table of decl, building it on first use.
+// Tables are built from the tables of the parent, the traits and the
+// interfaces, so a class can be checked before or after them. Errors are
+// reported once, by the declaration they belong to.
+func (c *ClassCompiler) methods(decl *ast.ClassDecl) (*methodTable, error) {
+	key := strings.ToLower(decl.Name)
+	if resolved, ok := c.tables[key]; ok {
+		return resolved.table, resolved.err
+	}
+
+	var table *methodTable
+	err := c.checkCycles(decl, make(map[string]int))
+	if err == nil {
+		table, err = c.buildTable(decl)
+	} else {
+		table = newMethodTable()
+	}
+
+	c.tables[key] = resolvedTable{table: table, err: err}
+	return table, err
+}
+
+// checkCycles walks the classes, interfaces and traits decl depends on and
+// fails when one of them depends on itself.
+func (c *ClassCompiler) checkCycles(decl *ast.ClassDecl, state map[string]int) error {
+	const visiting, done = 1, 2
+
+	key := strings.ToLower(decl.Name)
+	switch state[key] {
+	case visiting:
+		return interfaces.Errorf(diag.ErrInheritance, decl, "circular inheritance involving %s %s", class.KindName(decl), decl.Name)
+	case done:
+		return nil
+	}
+	state[key] = visiting
+
+	for _, name := range dependencies(decl) {
+		if dep, ok := c.context.GetClassManager().GetClass(name); ok {
+			if err := c.checkCycles(dep.Decl, state); err != nil {
+				return err
+			}
+		}
+	}
+
+	state[key] = done
+	return nil
+}
+
+func dependencies(decl *ast.ClassDecl) []string {
+	var names []string
+	if decl.Parent != "" {
+		names = append(names, decl.Parent)
+	}
+	names = append(names, decl.Interfaces...)
+	for _, use := range decl.Traits {
+		names = append(names, use.Traits...)
+	}
+	return names
+}
+
+// lookup finds the declaration a class refers to with extends, implements
+// or use and checks that it is of the expected kind.
+func (c *ClassCompiler) lookup(decl *ast.ClassDecl, node ast.Node, name string, want token.TokenType) (*ast.ClassDecl, error) {
+	found, ok := c.context.GetClassManager().GetClass(name)
+	if !ok {
+		return nil, interfaces.Errorf(diag.ErrUndefinedClass, node, "undefined %s: %s", kindWord(want), name)
+	}
+	target := found.Decl
+
+	switch {
+	case want == token.T_CLASS && target.Kind != token.T_CLASS:
+		return nil, interfaces.Errorf(diag.ErrInheritance, node, "class %s cannot extend %s %s", decl.Name, class.KindName(target), target.Name)
+	case want == token.T_CLASS && target.Final:
+		return nil, interfaces.Errorf(diag.ErrInheritance, node, "class %s cannot extend final class %s", decl.Name, target.Name)
+	case want == token.T_INTERFACE && target.Kind != token.T_INTERFACE:
+		return nil, interfaces.Errorf(diag.ErrInheritance, node, "%s cannot implement %s - it is not an interface", decl.Name, target.Name)
+	case want == token.T_TRAIT && target.Kind != token.T_TRAIT:
+		return nil, interfaces.Errorf(diag.ErrInheritance, node, "%s cannot use %s - it is not a trait", decl.Name, target.Name)
+	}
+	return target, nil
+}
+
+func kindWord(kind token.TokenType) string {
+	switch kind {
+	case token.T_INTERFACE:
+		return "interface"
+	case token.T_TRAIT:
+		return "trait"
+	default:
+		return "class"
+	}
+}
+
+// buildTable lays the methods of decl over the inherited ones: first the
+// methods of the parent, then those of the traits, then the methods the
+// class declares. The methods of the interfaces are added last where the
+// class has no method of that name. Concrete classes must not be left with
+// abstract methods.
+func (c *ClassCompiler) buildTable(decl *ast.ClassDecl) (*methodTable, error) {
+	table := newMethodTable()
+
+	if decl.Parent != "" {
+		parent, err := c.lookup(decl, decl, decl.Parent, token.T_CLASS)
+		if err != nil {
+			return table, err
+		}
+		inherited, _ := c.methods(parent)
+		for _, m := range inherited.members() {
+			table.set(m)
+		}
+	}
+
+	traitMethods, collisions, err := c.traitMethods(decl)
+	if err != nil {
+		return table, err
+	}
+
+	own := newMethodTable()
+	for i := range decl.Methods {
+		m, err := c.ownMethod(decl, &decl.Methods[i])
+		if err != nil {
+			return table, err
+		}
+		if _, exists := own.get(m.name); exists {
+			return table, interfaces.Errorf(diag.ErrRedeclaredFunction, m.decl, "cannot redeclare %s::%s()", decl.Name, m.name)
+		}
+		own.set(m)
+	}
+
+	for _, m := range traitMethods.members() {
+		if _, exists := own.get(m.name); exists {
+			if m.abstract {
+				own, _ := own.get(m.name)
+				if err := checkCompatible(own, m); err != nil {
+					return table, err
+				}
+			}
+			continue
+		}
+		if other, collides := collisions[strings.ToLower(m.name)]; collides {
+			return table, interfaces.Errorf(diag.ErrInheritance, decl,
+				"trait method %s has not been applied as %s::%s, because of collision with %s", m, decl.Name, m.name, other)
+		}
+		own.set(m)
+	}
+
+	for _, m := range own.members() {
+		if inherited, exists := table.get(m.name); exists {
+			if err := checkOverride(decl, m, inherited); err != nil {
+				return table, err
+			}
+		}
+		table.set(m)
+	}
+
+	for _, name := range decl.Interfaces {
+		iface, err := c.lookup(decl, decl, name, token.T_INTERFACE)
+		if err != nil {
+			return table, err
+		}
+		required, _ := c.methods(iface)
+		for _, m := range required.members() {
+			existing, exists := table.get(m.name)
+			if !exists {
+				table.set(m)
+				continue
+			}
+			if err := checkImplements(existing, m); err != nil {
+				return table, err
+			}
+		}
+	}
+
+	if decl.Kind == token.T_CLASS && c.implements(decl, native.Throwable) && !c.builtinAncestry(decl) {
+		return table, interfaces.Errorf(diag.ErrInheritance, decl,
+			"class %s cannot implement interface %s, extend Exception or Error instead", decl.Name, native.Throwable)
+	}
+
+	if decl.Kind == token.T_CLASS && !decl.Abstract {
+		var missing []string
+		for _, m := range table.members() {
+			if m.abstract {
+				missing = append(missing, m.String())
+			}
+		}
+		if len(missing) > 0 {
+			plural := ""
+			if len(missing) > 1 {
+				plural = "s"
+			}
+			return table, interfaces.Errorf(diag.ErrInheritance, decl,
+				"class %s contains %d abstract method%s and must therefore be declared abstract or implement the remaining methods (%s)",
+				decl.Name, len(missing), plural, strings.Join(missing, ", "))
+		}
+	}
+
+	return table, nil
+}
+
+// implements reports whether decl lists the interface name or an
+// interface extending it. Inherited interfaces are not considered.
+func (c *ClassCompiler) implements(decl *ast.ClassDecl, name string) bool {
+	for _, iface := range decl.Interfaces {
+		if strings.EqualFold(iface, name) {
+			return true
+		}
+		if found, ok := c.context.GetClassManager().GetClass(iface); ok && found.Decl.Kind == token.T_INTERFACE && c.implements(found.Decl, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// builtinAncestry reports whether decl or one of its parents is a
+// built-in class.
+func (c *ClassCompiler) builtinAncestry(decl *ast.ClassDecl) bool {
+	for name := decl.Name; name != ""; {
+		found, ok := c.context.GetClassManager().GetClass(name)
+		if !ok {
+			return false
+		}
+		if found.Builtin {
+			return true
+		}
+		name = found.Decl.Parent
+	}
+	return false
+}
+
+// ownMethod checks a method declared by decl itself.
+func (c *ClassCompiler) ownMethod(decl *ast.ClassDecl, md *ast.MethodDecl) (*member, error) {
+	name := md.Function.Name
+	m := &member{
+		name:       name,
+		class:      decl.Name,
+		origin:     decl.Name,
+		decl:       md,
+		visibility: md.Visibility,
+		abstract:   md.Abstract || decl.Kind == token.T_INTERFACE,
+	}
+
+	switch {
+	case decl.Kind == token.T_INTERFACE && md.Visibility != token.T_PUBLIC:
+		return nil, interfaces.Errorf(diag.ErrInheritance, md, "access type for interface method %s::%s() must be public", decl.Name, name)
+	case decl.Kind == token.T_INTERFACE && md.Final:
+		return nil, interfaces.Errorf(diag.ErrInheritance, md, "interface method %s::%s() must not be final", decl.Name, name)
+	case decl.Kind == token.T_INTERFACE && md.HasBody:
+		return nil, interfaces.Errorf(diag.ErrInheritance, md, "interface function %s::%s() cannot contain body", decl.Name, name)
+	case decl.Kind != token.T_INTERFACE && md.Abstract && md.HasBody:
+		return nil, interfaces.Errorf(diag.ErrInheritance, md, "abstract function %s::%s() cannot contain body", decl.Name, name)
+	case decl.Kind != token.T_INTERFACE && !md.Abstract && !md.HasBody:
+		return nil, interfaces.Errorf(diag.ErrInheritance, md, "non-abstract method %s::%s() must contain body", decl.Name, name)
+	case decl.Kind == token.T_CLASS && md.Abstract && md.Visibility == token.T_PRIVATE:
+		return nil, interfaces.Errorf(diag.ErrInheritance, md, "abstract function %s::%s() cannot be declared private", decl.Name, name)
+	case md.Static && strings.EqualFold(name, "__construct"):
+		return nil, interfaces.Errorf(diag.ErrInheritance, md, "method %s::%s() cannot be static", decl.Name, name)
+	}
+	return m, nil
+}
+
+// traitMethods collects the methods decl gets from its traits, applying the
+// insteadof and as rules. collisions maps the names that two traits provide
+// without an insteadof rule to one of the clashing methods; they are errors
+// unless the class declares the method itself.
+func (c *ClassCompiler) traitMethods(decl *ast.ClassDecl) (*methodTable, map[string]*member, error) {
+	result := newMethodTable()
+	collisions := make(map[string]*member)
+
+	if len(decl.Traits) > 0 && decl.Kind == token.T_INTERFACE {
+		return result, collisions, interfaces.Errorf(diag.ErrInheritance, decl, "cannot use traits inside of interface %s", decl.Name)
+	}
+
+	// Method tables of the used traits, by lower-case trait name.
+	traits := make(map[string]*methodTable)
+	var order []string
+	var rules []ast.TraitRule
+	for _, use := range decl.Traits {
+		for _, name := range use.Traits {
+			trait, err := c.lookup(decl, &use, name, token.T_TRAIT)
+			if err != nil {
+				return result, collisions, err
+			}
+			table, _ := c.methods(trait)
+			traits[strings.ToLower(trait.Name)] = table
+			order = append(order, strings.ToLower(trait.Name))
+		}
+		rules = append(rules, use.Rules...)
+	}
+
+	excluded := make(map[string]bool)
+	for i := range rules {
+		rule := &rules[i]
+		if rule.InsteadOf == nil {
+			continue
+		}
+		if _, err := c.traitRuleSource(decl, traits, rule); err != nil {
+			return result, collisions, err
+		}
+		for _, other := range rule.InsteadOf {
+			if _, used := traits[strings.ToLower(other)]; !used {
+				return result, collisions, interfaces.Errorf(diag.ErrInheritance, rule, "required trait %s wasn't added to %s", other, decl.Name)
+			}
+			excluded[strings.ToLower(other+"::"+rule.Method)] = true
+		}
+	}
+
+	for _, traitKey := range order {
+		for _, m := range traits[traitKey].members() {
+			if excluded[strings.ToLower(m.origin+"::"+m.name)] {
+				continue
+			}
+			copied := *m
+			copied.class = decl.Name
+
+			if existing, exists := result.get(m.name); exists && !existing.abstract && !m.abstract && existing.decl != m.decl {
+				collisions[strings.ToLower(m.name)] = existing
+			}
+			if existing, exists := result.get(m.name); exists && m.abstract && !existing.abstract {
+				continue
+			}
+			result.set(&copied)
+		}
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		if rule.InsteadOf != nil {
+			continue
+		}
+		source, err := c.traitRuleSource(decl, traits, rule)
+		if err != nil {
+			return result, collisions, err
+		}
+
+		if rule.Alias == "" {
+			if m, exists := result.get(rule.Method); exists && m.decl == source.decl {
+				m.visibility = rule.Visibility
+			}
+			continue
+		}
+
+		alias := *source
+		alias.name = rule.Alias
+		alias.class = decl.Name
+		if rule.Visibility != 0 {
+			alias.visibility = rule.Visibility
+		}
+		result.set(&alias)
+		delete(collisions, strings.ToLower(rule.Alias))
+	}
+
+	return result, collisions, nil
+}
+
+// traitRuleSource finds the trait method a rule refers to. Without a trait
+// name, the method must exist in exactly one of the used traits.
+func (c *ClassCompiler) traitRuleSource(decl *ast.ClassDecl, traits map[string]*methodTable, rule *ast.TraitRule) (*member, error) {
+	if rule.Trait != "" {
+		table, used := traits[strings.ToLower(rule.Trait)]
+		if !used {
+			return nil, interfaces.Errorf(diag.ErrInheritance, rule, "required trait %s wasn't added to %s", rule.Trait, decl.Name)
+		}
+		m, exists := table.get(rule.Method)
+		if !exists {
+			return nil, interfaces.Errorf(diag.ErrInheritance, rule, "a rule was defined for %s::%s but this method does not exist", rule.Trait, rule.Method)
+		}
+		return m, nil
+	}
+
+	var found *member
+	for _, use := range decl.Traits {
+		for _, name := range use.Traits {
+			m, exists := traits[strings.ToLower(name)].get(rule.Method)
+			if !exists {
+				continue
+			}
+			if found != nil && found.decl != m.decl {
+				return nil, interfaces.Errorf(diag.ErrInheritance, rule,
+					"an alias was defined for method %s(), which exists in both %s and %s. Use %s::%s or %s::%s to resolve the ambiguity",
+					rule.Method, found.origin, m.origin, found.origin, rule.Method, m.origin, rule.Method)
+			}
+			found = m
+		}
+	}
+	if found == nil {
+		return nil, interfaces.Errorf(diag.ErrInheritance, rule, "an alias was defined for %s but this method does not exist", rule.Method)
+	}
+	return found, nil
+}
+
+var visibilityRank = map[token.TokenType]int{
+	token.T_PUBLIC:    0,
+	token.T_PROTECTED: 1,
+	token.T_PRIVATE:   2,
+}
+
+func visibilityName(visibility token.TokenType) string {
+	switch visibility {
+	case token.T_PROTECTED:
+		return "protected"
+	case token.T_PRIVATE:
+		return "private"
+	default:
+		return "public"
+	}
+}
+
+// checkOverride checks that m, declared in decl or copied from one of its
+// traits, can replace the inherited method. Private methods are not
+// inherited in PHP's sense and can be redeclared freely, and constructors
+// only need compatible signatures when they override an abstract one.
+func checkOverride(decl *ast.ClassDecl, m, inherited *member) error {
+	if inherited.visibility == token.T_PRIVATE && !inherited.abstract {
+		return nil
+	}
+
+	switch {
+	case inherited.decl.Final:
+		return interfaces.Errorf(diag.ErrInheritance, m.decl, "cannot override final method %s()", inherited)
+	case inherited.decl.Static && !m.decl.Static:
+		return interfaces.Errorf(diag.ErrInheritance, m.decl, "cannot make static method %s() non static in class %s", inherited, decl.Name)
+	case !inherited.decl.Static && m.decl.Static:
+		return interfaces.Errorf(diag.ErrInheritance, m.decl, "cannot make non static method %s() static in class %s", inherited, decl.Name)
+	case m.abstract && !inherited.abstract:
+		return interfaces.Errorf(diag.ErrInheritance, m.decl, "cannot make non abstract method %s() abstract in class %s", inherited, decl.Name)
+	case visibilityRank[m.visibility] > visibilityRank[inherited.visibility]:
+		return interfaces.Errorf(diag.ErrInheritance, m.decl, "access level to %s::%s() must be %s (as in class %s) or weaker",
+			decl.Name, m.name, visibilityName(inherited.visibility), inherited.origin)
+	case strings.EqualFold(m.name, "__construct") && !inherited.abstract:
+		return nil
+	}
+	return checkCompatible(m, inherited)
+}
+
+// checkImplements checks a method against the interface method it implements.
+func checkImplements(m, required *member) error {
+	switch {
+	case m.visibility != token.T_PUBLIC:
+		return interfaces.Errorf(diag.ErrInheritance, m.decl, "access level to %s::%s() must be public (as in class %s)", m.class, m.name, required.origin)
+	case required.decl.Static && !m.decl.Static:
+		return interfaces.Errorf(diag.ErrInheritance, m.decl, "cannot make static method %s() non static in class %s", required, m.class)
+	case !required.decl.Static && m.decl.Static:
+		return interfaces.Errorf(diag.ErrInheritance, m.decl, "cannot make non static method %s() static in class %s", required, m.class)
+	}
+	return checkCompatible(m, required)
+}
+
+// checkCompatible checks that m accepts every call the method it replaces
+// accepts. Parameters have no defaults, so m may not require more of them.
+func checkCompatible(m, replaced *member) error {
+	if len(m.decl.Function.Params) > len(replaced.decl.Function.Params) {
+		return interfaces.Errorf(diag.ErrInheritance, m.decl, "declaration of %s must be compatible with %s", m.signature(), replaced.signature())
+	}
+	return nil
+}
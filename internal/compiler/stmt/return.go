@@ -11,12 +11,14 @@ import (
 type ReturnCompiler struct {
 	context      interfaces.CompilationContext
 	exprCompiler interfaces.ExprCompiler
+	tryCompiler  *TryCompiler
 }
 
-func NewReturnCompiler(context interfaces.CompilationContext, exprCompiler interfaces.ExprCompiler) *ReturnCompiler {
+func NewReturnCompiler(context interfaces.CompilationContext, exprCompiler interfaces.ExprCompiler, tryCompiler *TryCompiler) *ReturnCompiler {
 	return &ReturnCompiler{
 		context:      context,
 		exprCompiler: exprCompiler,
+		tryCompiler:  tryCompiler,
 	}
 }
 
@@ -34,6 +36,11 @@ func (c *ReturnCompiler) Compile(stmt *ast.ReturnStmt) error {
 		c.context.GetBytecodeBuilder().Append(byte(nullIdx))
 	}
 
+	// The value is computed before the finally blocks run.
+	if err := c.tryCompiler.EmitFinally(nil); err != nil {
+		return err
+	}
+
 	c.context.GetBytecodeBuilder().Append(bytecode.OP_RETURN)
 
 	return nil
@@ -0,0 +1,49 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package stmt
+
+import (
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/compiler/interfaces"
+)
+
+type StaticCompiler struct {
+	context      interfaces.CompilationContext
+	exprCompiler interfaces.ExprCompiler
+}
+
+func NewStaticCompiler(context interfaces.CompilationContext, exprCompiler interfaces.ExprCompiler) *StaticCompiler {
+	return &StaticCompiler{
+		context:      context,
+		exprCompiler: exprCompiler,
+	}
+}
+
+// Compile binds each name to its static slot. An initializer is wrapped in
+// STATIC_INIT so that it runs only the first time the statement is reached.
+func (c *StaticCompiler) Compile(stmt *ast.StaticStmt) error {
+	builder := c.context.GetBytecodeBuilder()
+
+	for _, v := range stmt.Vars {
+		varIdx := c.context.GetVariableManager().BindStatic(v.Name)
+		if v.Init == nil {
+			continue
+		}
+
+		initPos := builder.CurrentPosition()
+		builder.Append(bytecode.OP_STATIC_INIT)
+		builder.Append(byte(varIdx))
+		builder.AppendUint16(0xFFFF) // Placeholder
+
+		if err := c.exprCompiler.CompileExpr(v.Init); err != nil {
+			return err
+		}
+		builder.Append(bytecode.OP_STORE_VAR)
+		builder.Append(byte(varIdx))
+
+		skipOffset := builder.CurrentPosition() - (initPos + 4)
+		builder.PatchUint16(initPos+2, skipOffset)
+	}
+
+	return nil
+}
@@ -29,9 +29,7 @@ func (c *SwitchCompiler) Compile(stmt *ast.SwitchStmt) error {
 		return err
 	}
 
-	switchVarIdx := c.context.GetVariableManager().GetIndex("__switch_value")
-	c.context.GetBytecodeBuilder().Append(bytecode.OP_STORE_VAR)
-	c.context.GetBytecodeBuilder().Append(byte(switchVarIdx))
+	c.context.EmitStoreVar("__switch_value")
 
 	defaultCaseIndex := -1
 	for i, caseStmt := range stmt.Cases {
@@ -48,8 +46,7 @@ func (c *SwitchCompiler) Compile(stmt *ast.SwitchStmt) error {
 			continue
 		}
 
-		c.context.GetBytecodeBuilder().Append(bytecode.OP_LOAD_VAR)
-		c.context.GetBytecodeBuilder().Append(byte(switchVarIdx))
+		c.context.EmitLoadVar("__switch_value")
 
 		if err := c.exprCompiler.CompileExpr(caseStmt.Expr); err != nil {
 			return err
@@ -62,10 +59,8 @@ func (c *SwitchCompiler) Compile(stmt *ast.SwitchStmt) error {
 		ifFalseJumpPos := c.context.GetBytecodeBuilder().CurrentPosition()
 		c.context.GetBytecodeBuilder().AppendUint16(5)
 
-		for _, s := range caseStmt.Stmts {
-			if err := c.stmtCompiler.CompileStmt(s); err != nil {
-				return err
-			}
+		if err := c.stmtCompiler.CompileBlock(caseStmt.Stmts); err != nil {
+			return err
 		}
 
 		if i < len(stmt.Cases)-1 || !endsWithBreak(caseStmt.Stmts) {
@@ -78,19 +73,13 @@ func (c *SwitchCompiler) Compile(stmt *ast.SwitchStmt) error {
 		nextCasePos := c.context.GetBytecodeBuilder().CurrentPosition()
 		jumpOffset := nextCasePos - (ifFalseJumpPos + 2)
 
-		if jumpOffset <= 0 || jumpOffset > 255 {
-			c.context.GetBytecodeBuilder().PatchUint16(ifFalseJumpPos, uint16(jumpOffset))
-		} else {
-			c.context.GetBytecodeBuilder().PatchUint16(ifFalseJumpPos, uint16(jumpOffset))
-		}
+		c.context.GetBytecodeBuilder().PatchUint16(ifFalseJumpPos, jumpOffset)
 	}
 
 	if defaultCaseIndex >= 0 {
 		defaultCase := stmt.Cases[defaultCaseIndex]
-		for _, s := range defaultCase.Stmts {
-			if err := c.stmtCompiler.CompileStmt(s); err != nil {
-				return err
-			}
+		if err := c.stmtCompiler.CompileBlock(defaultCase.Stmts); err != nil {
+			return err
 		}
 	}
 
@@ -99,10 +88,7 @@ func (c *SwitchCompiler) Compile(stmt *ast.SwitchStmt) error {
 
 	for _, jumpPos := range endSwitchJumps {
 		offset := endSwitchPos - (jumpPos + 2)
-		if offset <= 0 || offset > 65535 {
-			offset = 2
-		}
-		c.context.GetBytecodeBuilder().PatchUint16(jumpPos, uint16(offset))
+		c.context.GetBytecodeBuilder().PatchInt16(jumpPos, offset)
 	}
 
 	c.context.ApplyPendingJumps()
@@ -0,0 +1,187 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package stmt
+
+import (
+	"strings"
+
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/compiler/constant"
+	"github.com/neokofg/php-compiler/internal/compiler/interfaces"
+)
+
+type TryCompiler struct {
+	context      interfaces.CompilationContext
+	exprCompiler interfaces.ExprCompiler
+	stmtCompiler interfaces.StmtCompiler
+}
+
+func NewTryCompiler(context interfaces.CompilationContext, exprCompiler interfaces.ExprCompiler, stmtCompiler interfaces.StmtCompiler) *TryCompiler {
+	return &TryCompiler{
+		context:      context,
+		exprCompiler: exprCompiler,
+		stmtCompiler: stmtCompiler,
+	}
+}
+
+// Compile lays out a try statement as
+//
+//	body                  ; protected, caught at dispatch
+//	JUMP finally
+//	dispatch:             ; the exception is on the stack
+//	CATCH A, clause0      ; one CATCH per type of each clause
+//	CATCH B, clause1
+//	THROW                 ; no clause matches: rethrow
+//	clause0: STORE $e, body, JUMP finally
+//	clause1: POP, body, JUMP finally
+//	rethrow:              ; caught here from body and catch code
+//	finally body, THROW
+//	finally:
+//	finally body
+//
+// The rethrow block only exists when there is a finally, whose body is
+// therefore compiled twice.
+func (c *TryCompiler) Compile(stmt *ast.TryStmt) error {
+	builder := c.context.GetBytecodeBuilder()
+	function := c.context.GetVariableManager().FunctionAddr()
+	depth := c.context.StackDepth()
+
+	try := c.context.EnterTry(stmt.Finally)
+	try.Begin(builder.CurrentPosition())
+	bodyErr := c.stmtCompiler.CompileBlock(stmt.Body)
+	bodyRanges := try.End(builder.CurrentPosition())
+
+	exits := []int{c.emitJump()}
+
+	var catchRanges [][2]int
+	if len(stmt.Catches) > 0 {
+		dispatch := builder.CurrentPosition()
+		try.Begin(dispatch)
+
+		var clauses [][]int
+		for _, clause := range stmt.Catches {
+			var patches []int
+			for _, name := range clause.Types {
+				idx := c.context.GetConstantPool().Add(constant.Constant{
+					Type:  "string",
+					Value: strings.ToLower(name),
+				})
+				patches = append(patches, builder.CurrentPosition())
+				builder.Append(bytecode.OP_CATCH)
+				builder.Append(byte(idx))
+				builder.AppendUint16(0xFFFF)
+			}
+			clauses = append(clauses, patches)
+		}
+		builder.Append(bytecode.OP_THROW)
+
+		for i, clause := range stmt.Catches {
+			for _, pos := range clauses[i] {
+				builder.PatchUint16(pos+2, builder.CurrentPosition()-(pos+4))
+			}
+
+			if clause.Var != "" {
+				c.context.EmitStoreVar(clause.Var)
+			} else {
+				builder.Append(bytecode.OP_POP)
+			}
+			if err := c.stmtCompiler.CompileBlock(clause.Body); err != nil {
+				c.context.ExitTry()
+				return err
+			}
+			exits = append(exits, c.emitJump())
+		}
+		catchRanges = try.End(builder.CurrentPosition())
+
+		c.addHandlers(bodyRanges, dispatch, function, depth)
+	}
+	c.context.ExitTry()
+
+	if stmt.Finally != nil {
+		rethrow := builder.CurrentPosition()
+		if len(stmt.Catches) > 0 {
+			c.addHandlers(catchRanges, rethrow, function, depth)
+		} else {
+			c.addHandlers(bodyRanges, rethrow, function, depth)
+		}
+
+		if err := c.stmtCompiler.CompileBlock(stmt.Finally); err != nil {
+			return err
+		}
+		builder.Append(bytecode.OP_THROW)
+	}
+
+	for _, pos := range exits {
+		builder.PatchInt16(pos+1, builder.CurrentPosition()-(pos+3))
+	}
+
+	if stmt.Finally != nil {
+		if err := c.stmtCompiler.CompileBlock(stmt.Finally); err != nil {
+			return err
+		}
+	}
+
+	return bodyErr
+}
+
+func (c *TryCompiler) CompileThrow(stmt *ast.ThrowStmt) error {
+	if err := c.exprCompiler.CompileExpr(stmt.Expr); err != nil {
+		return err
+	}
+
+	c.context.GetBytecodeBuilder().Append(bytecode.OP_THROW)
+	return nil
+}
+
+// EmitFinally inlines the finally blocks that a jump out of the current
+// try blocks passes through, innermost first: every one in the function
+// for a return, the ones inside loop for a break or continue of it. Each
+// block is compiled outside of its own try, so an exception it throws
+// goes to the handlers further out.
+func (c *TryCompiler) EmitFinally(loop *interfaces.LoopContext) error {
+	builder := c.context.GetBytecodeBuilder()
+	current := c.context.GetCurrentTry()
+	defer c.context.SetCurrentTry(current)
+
+	var passed []*interfaces.TryContext
+	for try := current; try != nil && (loop == nil || loop.Encloses(try.Loop)); try = try.Parent {
+		passed = append(passed, try)
+		if try.Finally == nil {
+			continue
+		}
+
+		for _, p := range passed {
+			p.Suspend(builder.CurrentPosition())
+		}
+		c.context.SetCurrentTry(try.Parent)
+		err := c.stmtCompiler.CompileBlock(try.Finally)
+		for _, p := range passed {
+			p.Resume(builder.CurrentPosition())
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *TryCompiler) emitJump() int {
+	builder := c.context.GetBytecodeBuilder()
+	pos := builder.CurrentPosition()
+	builder.Append(bytecode.OP_JUMP)
+	builder.AppendUint16(0xFFFF)
+	return pos
+}
+
+func (c *TryCompiler) addHandlers(ranges [][2]int, target, function, depth int) {
+	for _, r := range ranges {
+		c.context.GetBytecodeBuilder().AddHandler(bytecode.Handler{
+			Start:      r[0],
+			End:        r[1],
+			Target:     target,
+			Function:   function,
+			StackDepth: depth,
+		})
+	}
+}
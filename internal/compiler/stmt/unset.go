@@ -0,0 +1,128 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package stmt
+
+import (
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/compiler/constant"
+	"github.com/neokofg/php-compiler/internal/compiler/interfaces"
+	"github.com/neokofg/php-compiler/internal/diag"
+)
+
+type UnsetCompiler struct {
+	context      interfaces.CompilationContext
+	exprCompiler interfaces.ExprCompiler
+}
+
+func NewUnsetCompiler(context interfaces.CompilationContext, exprCompiler interfaces.ExprCompiler) *UnsetCompiler {
+	return &UnsetCompiler{
+		context:      context,
+		exprCompiler: exprCompiler,
+	}
+}
+
+// Compile unsets each operand in turn. Array elements are removed like
+// they are written: ARRAY_UNSET hands back the updated array, which is
+// stored where it came from.
+//
+//	unset($a['k'][0])   =>   LOAD a, 'k', 0, ARRAY_UNSET 2, STORE a
+//	unset($obj->name)   =>   obj PROP_UNSET name
+func (c *UnsetCompiler) Compile(stmt *ast.UnsetStmt) error {
+	for _, v := range stmt.Vars {
+		if err := c.compileUnset(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *UnsetCompiler) compileUnset(target ast.Expr) error {
+	builder := c.context.GetBytecodeBuilder()
+
+	switch t := target.(type) {
+	case *ast.VarExpr:
+		if t.Name == "this" {
+			return interfaces.Errorf(diag.ErrInvalidOperand, t, "cannot unset $this")
+		}
+		c.context.EmitUnsetVar(t.Name)
+		return nil
+
+	case *ast.PropertyFetch:
+		if err := c.exprCompiler.CompileExpr(t.Object); err != nil {
+			return err
+		}
+		builder.Append(bytecode.OP_PROP_UNSET)
+		builder.Append(byte(c.addName(t.Name)))
+		return nil
+
+	case *ast.IndexExpr:
+		return c.compileElementUnset(t)
+
+	default:
+		return interfaces.Errorf(diag.ErrInvalidOperand, target, "cannot unset the result of an expression")
+	}
+}
+
+// compileElementUnset peels the [index] suffixes off the target, pushes
+// the array that holds the element and the keys, and stores the array
+// back into the variable or the property it was read from.
+func (c *UnsetCompiler) compileElementUnset(target *ast.IndexExpr) error {
+	builder := c.context.GetBytecodeBuilder()
+
+	var indexes []ast.Expr
+	var holder ast.Expr = target
+	for {
+		index, ok := holder.(*ast.IndexExpr)
+		if !ok {
+			break
+		}
+		indexes = append([]ast.Expr{index.Index}, indexes...)
+		holder = index.Base
+	}
+
+	if len(indexes) > bytecode.MaxArrayDims {
+		return interfaces.Errorf(diag.ErrCompile, target, "cannot unset more than %d array dimensions", bytecode.MaxArrayDims)
+	}
+
+	var nameIdx int
+	switch h := holder.(type) {
+	case *ast.VarExpr:
+		c.context.EmitLoadVarQuiet(h.Name)
+	case *ast.PropertyFetch:
+		if err := c.exprCompiler.CompileExpr(h.Object); err != nil {
+			return err
+		}
+		nameIdx = c.addName(h.Name)
+		builder.Append(bytecode.OP_DUP)
+		builder.Append(bytecode.OP_PROP_GET_QUIET)
+		builder.Append(byte(nameIdx))
+	default:
+		return interfaces.Errorf(diag.ErrInvalidOperand, target, "cannot unset the result of an expression")
+	}
+
+	for _, index := range indexes {
+		if index == nil {
+			return interfaces.Errorf(diag.ErrInvalidOperand, target, "cannot use [] for unsetting")
+		}
+		if err := c.exprCompiler.CompileExpr(index); err != nil {
+			return err
+		}
+	}
+	builder.Append(bytecode.OP_ARRAY_UNSET)
+	builder.Append(byte(len(indexes)))
+
+	if h, ok := holder.(*ast.VarExpr); ok {
+		c.context.EmitStoreVar(h.Name)
+	} else {
+		builder.Append(bytecode.OP_PROP_SET)
+		builder.Append(byte(nameIdx))
+	}
+	return nil
+}
+
+func (c *UnsetCompiler) addName(name string) int {
+	return c.context.GetConstantPool().Add(constant.Constant{
+		Type:  "string",
+		Value: name,
+	})
+}
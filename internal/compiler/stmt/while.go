@@ -40,20 +40,18 @@ func (c *WhileCompiler) Compile(stmt *ast.WhileStmt) error {
 	c.context.GetBytecodeBuilder().Append(bytecode.OP_JUMP_IF_FALSE)
 	c.context.GetBytecodeBuilder().AppendUint16(0xFFFF) // Placeholder
 
-	for _, bodyStmt := range stmt.Body {
-		if err := c.stmtCompiler.CompileStmt(bodyStmt); err != nil {
-			return err
-		}
+	if err := c.stmtCompiler.CompileBlock(stmt.Body); err != nil {
+		return err
 	}
 
 	jumpBackOffset := loopStartPos - (c.context.GetBytecodeBuilder().CurrentPosition() + 3)
 	c.context.GetBytecodeBuilder().Append(bytecode.OP_JUMP)
-	c.context.GetBytecodeBuilder().AppendInt16(int16(jumpBackOffset))
+	c.context.GetBytecodeBuilder().AppendInt16(jumpBackOffset)
 
 	loopEndPos := c.context.GetBytecodeBuilder().CurrentPosition()
 	loop.EndPos = loopEndPos
 
-	jumpFalseOffset := uint16(loopEndPos - (jumpFalsePos + 3))
+	jumpFalseOffset := loopEndPos - (jumpFalsePos + 3)
 	c.context.GetBytecodeBuilder().PatchUint16(jumpFalsePos+1, jumpFalseOffset)
 
 	c.context.ApplyPendingJumps()
@@ -1,9 +1,46 @@
 // Licensed under GNU GPL v3. See LICENSE file for details.
 package variable
 
+// MaxSlots is the number of global variables, and of locals in each
+// function: instructions refer to variables by a one-byte slot index.
+const MaxSlots = 256
+
+type Kind int
+
+const (
+	Global Kind = iota // slot in the script-wide variable table
+	Local              // slot in the current call frame
+)
+
+// Slot is where a variable lives at run time.
+type Slot struct {
+	Kind  Kind
+	Index int
+}
+
+// Scope holds the locals of one function body. Names listed by `global`
+// and `static` are bound to global slots instead of local ones.
+type Scope struct {
+	Function  string
+	Class     string // class of a method body, empty in functions
+	Static    bool   // the method is static and has no $this
+	Addr      int    // address of the FUNC_DECL that starts the body
+	locals    map[string]int
+	names     []string
+	bindings  map[string]int
+	nextLocal int
+	parent    *Scope
+}
+
+// Names returns the local variable names in slot order.
+func (s *Scope) Names() []string {
+	return s.names
+}
+
 type Manager struct {
 	variableMap  map[string]int
 	nextVarIndex int
+	scope        *Scope
 }
 
 func NewManager() *Manager {
@@ -13,6 +50,7 @@ func NewManager() *Manager {
 	}
 }
 
+// GetIndex returns the global slot of name, allocating it on first use.
 func (m *Manager) GetIndex(name string) int {
 	if index, exists := m.variableMap[name]; exists {
 		return index
@@ -27,3 +65,106 @@ func (m *Manager) GetIndex(name string) int {
 func (m *Manager) GetAllVariables() map[string]int {
 	return m.variableMap
 }
+
+// EnterFunction starts a new local scope. Function declarations can be
+// nested, so scopes form a stack.
+func (m *Manager) EnterFunction(name string) *Scope {
+	m.scope = &Scope{
+		Function: name,
+		locals:   make(map[string]int),
+		bindings: make(map[string]int),
+		parent:   m.scope,
+	}
+	return m.scope
+}
+
+// EnterMethod starts the scope of a method of class. The first local of
+// an instance method is $this.
+func (m *Manager) EnterMethod(class, name string, static bool) *Scope {
+	scope := m.EnterFunction(class + "::" + name)
+	scope.Class = class
+	scope.Static = static
+	if !static {
+		m.Resolve("this")
+	}
+	return scope
+}
+
+func (m *Manager) ExitFunction() {
+	if m.scope != nil {
+		m.scope = m.scope.parent
+	}
+}
+
+func (m *Manager) InFunction() bool {
+	return m.scope != nil
+}
+
+// FunctionAddr returns the FUNC_DECL address of the function being
+// compiled, or -1 in the top-level script.
+func (m *Manager) FunctionAddr() int {
+	if m.scope == nil {
+		return -1
+	}
+	return m.scope.Addr
+}
+
+// InMethod reports whether code is compiled for the body of an instance
+// method, where $this is defined.
+func (m *Manager) InMethod() bool {
+	return m.scope != nil && m.scope.Class != "" && !m.scope.Static
+}
+
+// CurrentClass returns the class whose method is being compiled, which
+// self, parent and static refer to, or "" outside of methods.
+func (m *Manager) CurrentClass() string {
+	if m.scope == nil {
+		return ""
+	}
+	return m.scope.Class
+}
+
+// Resolve returns the slot name refers to in the current scope. Outside of
+// functions every variable is global; inside, unbound names become locals.
+func (m *Manager) Resolve(name string) Slot {
+	if m.scope == nil {
+		return Slot{Kind: Global, Index: m.GetIndex(name)}
+	}
+
+	if index, bound := m.scope.bindings[name]; bound {
+		return Slot{Kind: Global, Index: index}
+	}
+
+	if index, exists := m.scope.locals[name]; exists {
+		return Slot{Kind: Local, Index: index}
+	}
+
+	index := m.scope.nextLocal
+	m.scope.locals[name] = index
+	m.scope.names = append(m.scope.names, name)
+	m.scope.nextLocal++
+	return Slot{Kind: Local, Index: index}
+}
+
+// BindGlobal makes name refer to the global variable of the same name
+// for the rest of the current function, like PHP's `global $name;`.
+func (m *Manager) BindGlobal(name string) int {
+	index := m.GetIndex(name)
+	if m.scope != nil {
+		m.scope.bindings[name] = index
+	}
+	return index
+}
+
+// BindStatic gives name storage that outlives the call. Static variables are
+// kept in a hidden global slot named after the function, so two functions
+// with a `static $n` each get their own.
+func (m *Manager) BindStatic(name string) int {
+	if m.scope == nil {
+		return m.GetIndex(name)
+	}
+
+	index := m.GetIndex(m.scope.Function + "::" + name)
+	m.scope.bindings[name] = index
+	return index
+}
@@ -0,0 +1,25 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package diag
+
+// Error codes are grouped by the stage that reports them:
+// E00xx lexer, E01xx parser, E02xx compiler, Wxxxx warnings.
+const (
+	ErrLexical = "E0001"
+
+	ErrSyntax        = "E0100"
+	ErrExpectedToken = "E0101"
+	ErrExpectedExpr  = "E0102"
+
+	ErrCompile            = "E0200"
+	ErrUndefinedFunction  = "E0201"
+	ErrArgumentCount      = "E0202"
+	ErrLoopControl        = "E0203"
+	ErrRedeclaredFunction = "E0204"
+	ErrInvalidOperand     = "E0205"
+	ErrUndefinedClass     = "E0206"
+	ErrRedeclaredClass    = "E0207"
+	ErrInheritance        = "E0208"
+	ErrLimit              = "E0209" // the program does not fit the bytecode operands
+
+	WarnUnreachableCode = "W0001"
+)
@@ -0,0 +1,166 @@
+// PHP Compiler - compiles php code to IR and then running it on PHPC VM
+// Copyright (C) 2025  Andrey Vasilev (neokofg)
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package diag collects errors and warnings from the lexer, parser and
+// compiler and renders them either as annotated source snippets or as JSON.
+package diag
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/neokofg/php-compiler/internal/token"
+)
+
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+)
+
+func (s Severity) String() string {
+	if s == Warning {
+		return "warning"
+	}
+	return "error"
+}
+
+type Diagnostic struct {
+	Severity Severity
+	Code     string
+	Msg      string
+	Pos      token.Position
+	End      token.Position
+}
+
+func (d *Diagnostic) Error() string {
+	if d.Pos.IsValid() {
+		return fmt.Sprintf("%s: %s[%s]: %s", d.Pos, d.Severity, d.Code, d.Msg)
+	}
+	return fmt.Sprintf("%s[%s]: %s", d.Severity, d.Code, d.Msg)
+}
+
+// Diagnoser is implemented by errors that can describe themselves as a diagnostic.
+type Diagnoser interface {
+	Diagnostic() *Diagnostic
+}
+
+// FromError converts err into a diagnostic. Errors without position
+// information get the given fallback code.
+func FromError(err error, code string) *Diagnostic {
+	var d *Diagnostic
+	if errors.As(err, &d) {
+		return d
+	}
+
+	var diagnoser Diagnoser
+	if errors.As(err, &diagnoser) {
+		return diagnoser.Diagnostic()
+	}
+
+	var syntaxErr *token.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		if syntaxErr.Code != "" {
+			code = syntaxErr.Code
+		}
+		return &Diagnostic{Severity: Error, Code: code, Msg: syntaxErr.Msg, Pos: syntaxErr.Pos, End: syntaxErr.End}
+	}
+
+	var unexpected *token.UnexpectedTokenError
+	if errors.As(err, &unexpected) {
+		return &Diagnostic{
+			Severity: Error,
+			Code:     ErrExpectedToken,
+			Msg:      fmt.Sprintf("expected %s, but found %v (%q)", unexpected.Expected, unexpected.Found.Type, unexpected.Found.Value),
+			Pos:      unexpected.Found.Pos,
+			End:      unexpected.Found.End,
+		}
+	}
+
+	return &Diagnostic{Severity: Error, Code: code, Msg: err.Error()}
+}
+
+// List is an ordered set of diagnostics. A List with at least one error
+// can itself be returned as an error.
+type List []*Diagnostic
+
+func (l *List) Add(d *Diagnostic) {
+	*l = append(*l, d)
+}
+
+// AddError records err as an error diagnostic. A nested List is flattened.
+func (l *List) AddError(err error, code string) {
+	var nested List
+	if errors.As(err, &nested) {
+		*l = append(*l, nested...)
+		return
+	}
+	l.Add(FromError(err, code))
+}
+
+func (l *List) Errorf(code string, pos, end token.Position, format string, args ...interface{}) {
+	l.Add(&Diagnostic{Severity: Error, Code: code, Msg: fmt.Sprintf(format, args...), Pos: pos, End: end})
+}
+
+func (l *List) Warnf(code string, pos, end token.Position, format string, args ...interface{}) {
+	l.Add(&Diagnostic{Severity: Warning, Code: code, Msg: fmt.Sprintf(format, args...), Pos: pos, End: end})
+}
+
+func (l List) ErrorCount() int {
+	count := 0
+	for _, d := range l {
+		if d.Severity == Error {
+			count++
+		}
+	}
+	return count
+}
+
+func (l List) HasErrors() bool {
+	return l.ErrorCount() > 0
+}
+
+// Sort orders diagnostics by file and position, keeping the report order
+// for diagnostics at the same position.
+func (l List) Sort() {
+	sort.SliceStable(l, func(i, j int) bool {
+		a, b := l[i].Pos, l[j].Pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Offset < b.Offset
+	})
+}
+
+// Err returns the list as an error if it contains errors, nil otherwise.
+func (l List) Err() error {
+	if !l.HasErrors() {
+		return nil
+	}
+	return l
+}
+
+func (l List) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more)", l[0].Error(), len(l)-1)
+}
@@ -0,0 +1,130 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package diag
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Sources maps file names to their contents so that snippets can be shown.
+type Sources map[string]string
+
+func (s Sources) line(filename string, line int) (string, bool) {
+	src, ok := s[filename]
+	if !ok || line < 1 {
+		return "", false
+	}
+
+	lines := strings.Split(src, "\n")
+	if line > len(lines) {
+		return "", false
+	}
+	return strings.TrimRight(lines[line-1], "\r"), true
+}
+
+// Render writes every diagnostic with the offending source line underneath:
+//
+//	error[E0102]: expected expression
+//	 --> index.php:3:10
+//	  |
+//	3 | echo $a +;
+//	  |          ^
+func (l List) Render(w io.Writer, sources Sources) {
+	for _, d := range l {
+		renderOne(w, d, sources)
+	}
+}
+
+func renderOne(w io.Writer, d *Diagnostic, sources Sources) {
+	fmt.Fprintf(w, "%s[%s]: %s\n", d.Severity, d.Code, d.Msg)
+	if !d.Pos.IsValid() {
+		fmt.Fprintln(w)
+		return
+	}
+
+	gutter := strings.Repeat(" ", len(strconv.Itoa(d.Pos.Line)))
+	fmt.Fprintf(w, "%s--> %s\n", gutter, d.Pos)
+
+	line, ok := sources.line(d.Pos.Filename, d.Pos.Line)
+	if !ok {
+		fmt.Fprintln(w)
+		return
+	}
+
+	fmt.Fprintf(w, "%s |\n", gutter)
+	fmt.Fprintf(w, "%d | %s\n", d.Pos.Line, line)
+	fmt.Fprintf(w, "%s | %s\n\n", gutter, underline(line, d))
+}
+
+// underline builds the marker line. Tabs before the marker are kept so it
+// lines up the same way the source line does.
+func underline(line string, d *Diagnostic) string {
+	runes := []rune(line)
+	start := d.Pos.Column - 1
+	if start > len(runes) {
+		start = len(runes)
+	}
+
+	width := 1
+	if d.End.Line == d.Pos.Line && d.End.Column > d.Pos.Column {
+		width = d.End.Column - d.Pos.Column
+	} else if d.End.Line > d.Pos.Line {
+		width = len(runes) - start
+	}
+	if width < 1 {
+		width = 1
+	}
+
+	var sb strings.Builder
+	for _, r := range runes[:start] {
+		if r == '\t' {
+			sb.WriteRune('\t')
+		} else {
+			sb.WriteRune(' ')
+		}
+	}
+
+	marker := "^"
+	if d.Severity == Warning {
+		marker = "-"
+	}
+	sb.WriteString(strings.Repeat(marker, width))
+
+	return sb.String()
+}
+
+type jsonDiagnostic struct {
+	Severity  string `json:"severity"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	File      string `json:"file,omitempty"`
+	Line      int    `json:"line,omitempty"`
+	Column    int    `json:"column,omitempty"`
+	EndLine   int    `json:"endLine,omitempty"`
+	EndColumn int    `json:"endColumn,omitempty"`
+}
+
+// WriteJSON writes one JSON object per line, which is what editor
+// integrations usually expect.
+func (l List) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, d := range l {
+		err := enc.Encode(jsonDiagnostic{
+			Severity:  d.Severity.String(),
+			Code:      d.Code,
+			Message:   d.Msg,
+			File:      d.Pos.Filename,
+			Line:      d.Pos.Line,
+			Column:    d.Pos.Column,
+			EndLine:   d.End.Line,
+			EndColumn: d.End.Column,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
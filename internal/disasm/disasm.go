@@ -0,0 +1,359 @@
+// PHP Compiler - compiles php code to IR and then running it on PHPC VM
+// Copyright (C) 2025  Andrey Vasilev (neokofg)
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+package disasm
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/phpbc"
+)
+
+type Operand struct {
+	Kind   bytecode.OperandKind
+	Value  int
+	Params []int
+}
+
+type Instruction struct {
+	Addr     int
+	Size     int
+	Opcode   byte
+	Info     bytecode.OpInfo
+	Known    bool
+	Operands []Operand
+}
+
+// Target returns the absolute address a jump operand points to.
+func (i Instruction) Target(op Operand) int {
+	return i.Addr + i.Size + op.Value
+}
+
+// Decode reads the instruction starting at addr. Unknown opcodes decode
+// as a single byte so that the walk can continue past them.
+func Decode(code []byte, addr int) (Instruction, error) {
+	inst := Instruction{Addr: addr, Opcode: code[addr], Size: 1}
+
+	info, ok := bytecode.Lookup(inst.Opcode)
+	if !ok {
+		return inst, nil
+	}
+	inst.Info = info
+	inst.Known = true
+
+	pos := addr + 1
+	readByte := func() (int, error) {
+		if pos >= len(code) {
+			return 0, fmt.Errorf("%04x: truncated %s instruction", addr, info.Name)
+		}
+		b := code[pos]
+		pos++
+		return int(b), nil
+	}
+	readUint16 := func() (int, error) {
+		if pos+1 >= len(code) {
+			return 0, fmt.Errorf("%04x: truncated %s instruction", addr, info.Name)
+		}
+		v := int(code[pos]) | int(code[pos+1])<<8
+		pos += 2
+		return v, nil
+	}
+
+	for _, kind := range info.Operands {
+		op := Operand{Kind: kind}
+		var err error
+
+		switch kind {
+		case bytecode.OperandConst, bytecode.OperandVar, bytecode.OperandLocal, bytecode.OperandArgCount,
+			bytecode.OperandDims, bytecode.OperandAppendMask, bytecode.OperandByRef, bytecode.OperandModifiers, bytecode.OperandClassFlags,
+			bytecode.OperandCaptures, bytecode.OperandDepth:
+			op.Value, err = readByte()
+		case bytecode.OperandJump:
+			var raw int
+			raw, err = readUint16()
+			op.Value = int(int16(raw))
+		case bytecode.OperandJumpForward, bytecode.OperandAddr:
+			op.Value, err = readUint16()
+		case bytecode.OperandParams:
+			op.Value, err = readByte()
+			for i := 0; i < op.Value && err == nil; i++ {
+				var param int
+				param, err = readByte()
+				op.Params = append(op.Params, param)
+			}
+		}
+
+		if err != nil {
+			return inst, err
+		}
+		inst.Operands = append(inst.Operands, op)
+	}
+
+	inst.Size = pos - addr
+	return inst, nil
+}
+
+func DecodeAll(code []byte) ([]Instruction, error) {
+	var instructions []Instruction
+
+	for addr := 0; addr < len(code); {
+		inst, err := Decode(code, addr)
+		if err != nil {
+			return instructions, err
+		}
+		instructions = append(instructions, inst)
+		addr += inst.Size
+	}
+
+	return instructions, nil
+}
+
+type disassembler struct {
+	program   *phpbc.Program
+	varNames  map[int]string
+	funcNames map[int]string
+	labels    map[int]string
+	// locals names the local slots of the function being listed.
+	locals []string
+}
+
+// Disassemble writes a human readable listing of the program: jump targets
+// become labels, constants and variables are annotated with their values and names.
+func Disassemble(w io.Writer, program *phpbc.Program) error {
+	instructions, decodeErr := DecodeAll(program.Code)
+
+	d := &disassembler{
+		program:   program,
+		varNames:  make(map[int]string),
+		funcNames: make(map[int]string),
+		labels:    make(map[int]string),
+	}
+
+	for name, idx := range program.Variables {
+		d.varNames[idx] = name
+	}
+	funcLocals := make(map[int][]string)
+	for _, function := range program.Functions {
+		d.funcNames[function.Address] = function.Name
+		funcLocals[function.Address] = function.Locals
+	}
+	d.assignLabels(instructions)
+
+	fmt.Fprintf(w, "; %d bytes, %d constants, %d variables, %d functions\n",
+		len(program.Code), len(program.Constants), len(program.Variables), len(program.Functions))
+
+	// Code is jumped over where a function is declared, so the enclosing
+	// function goes on at the target of the jump before the declaration.
+	type enclosing struct {
+		resume int
+		locals []string
+	}
+	var outer []enclosing
+
+	for i, inst := range instructions {
+		for len(outer) > 0 && inst.Addr >= outer[len(outer)-1].resume {
+			d.locals = outer[len(outer)-1].locals
+			outer = outer[:len(outer)-1]
+		}
+		if name, ok := d.funcNames[inst.Addr]; ok {
+			if i > 0 && instructions[i-1].Opcode == bytecode.OP_JUMP && len(instructions[i-1].Operands) == 1 {
+				jump := instructions[i-1]
+				outer = append(outer, enclosing{resume: jump.Target(jump.Operands[0]), locals: d.locals})
+			}
+			fmt.Fprintf(w, "\nfunction %s:\n", name)
+			d.locals = funcLocals[inst.Addr]
+		}
+		if label, ok := d.labels[inst.Addr]; ok {
+			fmt.Fprintf(w, "%s:\n", label)
+		}
+
+		operands, comment := d.formatOperands(inst)
+		line := fmt.Sprintf("  %04x  %-14s %s", inst.Addr, d.mnemonic(inst), operands)
+		if comment != "" {
+			line = fmt.Sprintf("%-40s ; %s", line, comment)
+		}
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+
+	if len(program.Handlers) > 0 {
+		fmt.Fprintf(w, "\nexception table:\n")
+		for _, h := range program.Handlers {
+			function := "main"
+			if name, ok := d.funcNames[h.Function]; ok {
+				function = name
+			}
+			fmt.Fprintf(w, "  %04x-%04x -> %s  ; %s, stack depth %d\n", h.Start, h.End, d.labels[h.Target], function, h.StackDepth)
+		}
+	}
+
+	return decodeErr
+}
+
+func (d *disassembler) assignLabels(instructions []Instruction) {
+	var targets []int
+	seen := make(map[int]bool)
+
+	for _, inst := range instructions {
+		for _, op := range inst.Operands {
+			if op.Kind != bytecode.OperandJump && op.Kind != bytecode.OperandJumpForward {
+				continue
+			}
+			target := inst.Target(op)
+			if !seen[target] {
+				seen[target] = true
+				targets = append(targets, target)
+			}
+		}
+	}
+
+	for _, handler := range d.program.Handlers {
+		if !seen[handler.Target] {
+			seen[handler.Target] = true
+			targets = append(targets, handler.Target)
+		}
+	}
+
+	sort.Ints(targets)
+	for i, target := range targets {
+		d.labels[target] = fmt.Sprintf("L%d", i+1)
+	}
+}
+
+func (d *disassembler) mnemonic(inst Instruction) string {
+	if !inst.Known {
+		return ".byte"
+	}
+	return inst.Info.Name
+}
+
+func (d *disassembler) formatOperands(inst Instruction) (string, string) {
+	if !inst.Known {
+		return fmt.Sprintf("0x%02X", inst.Opcode), "unknown opcode"
+	}
+
+	var parts []string
+	var comments []string
+
+	for _, op := range inst.Operands {
+		switch op.Kind {
+		case bytecode.OperandConst:
+			parts = append(parts, strconv.Itoa(op.Value))
+			comments = append(comments, d.constantComment(op.Value))
+		case bytecode.OperandVar:
+			parts = append(parts, strconv.Itoa(op.Value))
+			comments = append(comments, d.varName(op.Value))
+		case bytecode.OperandLocal:
+			parts = append(parts, strconv.Itoa(op.Value))
+			comments = append(comments, d.localName(op.Value))
+		case bytecode.OperandJump, bytecode.OperandJumpForward:
+			target := inst.Target(op)
+			parts = append(parts, d.labels[target])
+			comments = append(comments, fmt.Sprintf("-> %04x", target))
+		case bytecode.OperandArgCount, bytecode.OperandDims, bytecode.OperandCaptures, bytecode.OperandDepth:
+			parts = append(parts, strconv.Itoa(op.Value))
+		case bytecode.OperandAppendMask:
+			parts = append(parts, fmt.Sprintf("%#b", op.Value))
+		case bytecode.OperandByRef:
+			if op.Value != 0 {
+				parts = append(parts, "ref")
+			} else {
+				parts = append(parts, "val")
+			}
+		case bytecode.OperandModifiers:
+			parts = append(parts, modifiersText(op.Value))
+		case bytecode.OperandClassFlags:
+			parts = append(parts, classFlagsText(op.Value))
+		case bytecode.OperandAddr:
+			if name, ok := d.funcNames[op.Value]; ok {
+				parts = append(parts, name)
+			} else {
+				parts = append(parts, fmt.Sprintf("@%04x", op.Value))
+			}
+		case bytecode.OperandParams:
+			parts = append(parts, strconv.Itoa(op.Value))
+			for _, param := range op.Params {
+				parts = append(parts, d.localName(param))
+			}
+		}
+	}
+
+	return strings.Join(parts, " "), strings.Join(comments, ", ")
+}
+
+func modifiersText(modifiers int) string {
+	var text string
+	switch modifiers & bytecode.MemberVisibilityMask {
+	case bytecode.MemberProtected:
+		text = "protected"
+	case bytecode.MemberPrivate:
+		text = "private"
+	default:
+		text = "public"
+	}
+
+	if modifiers&bytecode.MemberStatic != 0 {
+		text += ",static"
+	}
+	if modifiers&bytecode.MemberAbstract != 0 {
+		text += ",abstract"
+	}
+	return text
+}
+
+func classFlagsText(flags int) string {
+	switch {
+	case flags&bytecode.ClassInterface != 0:
+		return "interface"
+	case flags&bytecode.ClassTrait != 0:
+		return "trait"
+	case flags&bytecode.ClassAbstract != 0:
+		return "abstract"
+	case flags&bytecode.ClassFinal != 0:
+		return "final"
+	default:
+		return "class"
+	}
+}
+
+func (d *disassembler) constantComment(idx int) string {
+	if idx >= len(d.program.Constants) {
+		return "<invalid constant>"
+	}
+
+	c := d.program.Constants[idx]
+	if c.Type == "string" {
+		return "string " + strconv.Quote(c.Value)
+	}
+	return c.Type + " " + c.Value
+}
+
+func (d *disassembler) varName(idx int) string {
+	if name, ok := d.varNames[idx]; ok {
+		return "$" + name
+	}
+	return fmt.Sprintf("$<%d>", idx)
+}
+
+func (d *disassembler) localName(idx int) string {
+	if idx < len(d.locals) {
+		return "$" + d.locals[idx]
+	}
+	return fmt.Sprintf("local#%d", idx)
+}
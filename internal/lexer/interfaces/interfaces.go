@@ -12,6 +12,7 @@ type Reader interface {
 	ReadWhile(cond func(rune) bool) string
 	GetPos() int
 	SetPos(pos int)
+	Position() token.Position
 }
 
 type Tokenizer interface {
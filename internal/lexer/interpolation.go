@@ -0,0 +1,249 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package lexer
+
+import (
+	"github.com/neokofg/php-compiler/internal/lexer/tokenizer"
+	token2 "github.com/neokofg/php-compiler/internal/token"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// interpolation is a double-quoted string or heredoc with embedded variables
+// that the lexer is splitting into parts. While one is open, the lexer
+// returns its literal text and variables instead of regular tokens, except
+// inside a {$expr} or ${expr} part, which is lexed as code.
+type interpolation struct {
+	heredoc bool
+	end     int  // heredoc: reader position of the newline that ends the body
+	resume  int  // heredoc: reader position right after the closing marker
+	indent  int  // heredoc: indentation removed from every line
+	inExpr  bool // inside {$expr} or ${expr}
+	braces  int  // '{' opened inside the current {$expr} part
+}
+
+// intOffsetPattern matches the string offsets PHP treats as integers in
+// "$arr[key]"; anything else, like "$arr[01]", is a string key.
+var intOffsetPattern = regexp.MustCompile(`^(0|-?[1-9][0-9]*)$`)
+
+func (l *Lexer) openInterpolation() *interpolation {
+	if len(l.interpolations) == 0 {
+		return nil
+	}
+	return l.interpolations[len(l.interpolations)-1]
+}
+
+// track updates the interpolation state after a regular token was lexed.
+func (l *Lexer) track(tok token2.Token) {
+	if tok.Type == token2.T_STRING_START {
+		l.startInterpolation(tok.Value)
+		return
+	}
+
+	s := l.openInterpolation()
+	if s == nil || !s.inExpr {
+		return
+	}
+
+	switch tok.Type {
+	case token2.T_LBRACE:
+		s.braces++
+	case token2.T_RBRACE:
+		if s.braces == 0 {
+			s.inExpr = false
+		} else {
+			s.braces--
+		}
+	}
+}
+
+// startInterpolation opens a string whose T_STRING_START was just returned.
+// For heredocs, label is the heredoc label and the reader is at the body.
+func (l *Lexer) startInterpolation(label string) {
+	s := &interpolation{}
+
+	if label != `"` {
+		bodyPos := l.reader.GetPos()
+		// The string tokenizer has already checked the body.
+		body, _ := tokenizer.ScanHeredocBody(l.reader, label)
+		l.reader.SetPos(bodyPos)
+
+		s.heredoc = true
+		s.end = body.End
+		s.resume = body.Resume
+		s.indent = utf8.RuneCountInString(body.Indent)
+		l.skipIndent(s)
+	}
+
+	l.interpolations = append(l.interpolations, s)
+}
+
+// nextStringToken returns the next part of the open string s.
+func (l *Lexer) nextStringToken(s *interpolation) token2.Token {
+	start := l.reader.Position()
+	emit := func(t token2.TokenType, value string) token2.Token {
+		return token2.Token{Type: t, Value: value, Pos: start, End: l.reader.Position()}
+	}
+
+	if l.atStringEnd(s) {
+		if s.heredoc {
+			l.reader.SetPos(s.resume)
+		} else {
+			l.reader.Next()
+		}
+		l.interpolations = l.interpolations[:len(l.interpolations)-1]
+		return emit(token2.T_STRING_END, "")
+	}
+
+	switch ch, next := l.reader.Peek(), l.reader.PeekNext(); {
+	case ch == 0:
+		l.interpolations = l.interpolations[:len(l.interpolations)-1]
+		return emit(token2.T_ILLEGAL, "Unexpected end of string")
+	case ch == '$' && tokenizer.IsLabelStart(next):
+		return l.scanSimpleVar()
+	case ch == '$' && next == '{':
+		l.reader.Next()
+		l.reader.Next()
+		s.inExpr = true
+		return emit(token2.T_DOLLAR_OPEN_CURLY, "${")
+	case ch == '{' && next == '$':
+		l.reader.Next()
+		s.inExpr = true
+		return emit(token2.T_CURLY_OPEN, "{")
+	}
+
+	var quote byte = '"'
+	if s.heredoc {
+		quote = 0
+	}
+
+	val, err := tokenizer.UnescapeString(l.readStringText(s), quote)
+	if err != nil {
+		return emit(token2.T_ILLEGAL, err.Error())
+	}
+	return emit(token2.T_STRING_PART, val)
+}
+
+func (l *Lexer) atStringEnd(s *interpolation) bool {
+	if s.heredoc {
+		return l.reader.GetPos() >= s.end
+	}
+	return l.reader.Peek() == '"'
+}
+
+// readStringText reads raw literal text up to the next variable or the end
+// of the string. Escapes are kept for tokenizer.UnescapeString.
+func (l *Lexer) readStringText(s *interpolation) string {
+	var sb strings.Builder
+
+	for !l.atStringEnd(s) && l.reader.Peek() != 0 {
+		ch, next := l.reader.Peek(), l.reader.PeekNext()
+		if (ch == '$' && (tokenizer.IsLabelStart(next) || next == '{')) || (ch == '{' && next == '$') {
+			break
+		}
+
+		sb.WriteRune(l.reader.Next())
+		if ch == '\\' && !l.atStringEnd(s) && l.reader.Peek() != 0 {
+			ch = l.reader.Next()
+			sb.WriteRune(ch)
+		}
+		if ch == '\n' && s.heredoc {
+			l.skipIndent(s)
+		}
+	}
+
+	return sb.String()
+}
+
+func (l *Lexer) skipIndent(s *interpolation) {
+	for i := 0; i < s.indent && !l.atStringEnd(s); i++ {
+		if ch := l.reader.Peek(); ch != ' ' && ch != '\t' {
+			return
+		}
+		l.reader.Next()
+	}
+}
+
+// scanSimpleVar splits `$name`, `$name[key]` and `$name->prop` into
+// regular tokens. The first one is returned, the others are queued.
+func (l *Lexer) scanSimpleVar() token2.Token {
+	toks := []token2.Token{
+		l.take(token2.T_DOLLAR, 1),
+		l.takeWhile(token2.T_IDENT, tokenizer.IsLabelChar),
+	}
+
+	switch {
+	case l.reader.Peek() == '[':
+		toks = append(toks, l.take(token2.T_LBRACKET, 1))
+		toks = append(toks, l.scanOffset()...)
+		if l.reader.Peek() == ']' {
+			toks = append(toks, l.take(token2.T_RBRACKET, 1))
+		} else {
+			toks = append(toks, l.illegal("Expected ']' after string offset"))
+		}
+	case l.reader.Peek() == '-' && l.reader.PeekNext() == '>' && l.labelAfterArrow():
+		toks = append(toks, l.take(token2.T_OBJECT_OPERATOR, 2))
+		toks = append(toks, l.takeWhile(token2.T_IDENT, tokenizer.IsLabelChar))
+	}
+
+	l.pending = append(l.pending, toks[1:]...)
+	return toks[0]
+}
+
+// scanOffset reads the key of "$arr[key]": a variable, an integer or an
+// unquoted string.
+func (l *Lexer) scanOffset() []token2.Token {
+	ch, next := l.reader.Peek(), l.reader.PeekNext()
+
+	switch {
+	case ch == '$' && tokenizer.IsLabelStart(next):
+		return []token2.Token{
+			l.take(token2.T_DOLLAR, 1),
+			l.takeWhile(token2.T_IDENT, tokenizer.IsLabelChar),
+		}
+	case tokenizer.IsLabelStart(ch):
+		return []token2.Token{l.takeWhile(token2.T_STRING, tokenizer.IsLabelChar)}
+	case (ch >= '0' && ch <= '9') || (ch == '-' && next >= '0' && next <= '9'):
+		start := l.reader.Position()
+		l.reader.Next()
+		key := string(ch) + l.reader.ReadWhile(tokenizer.IsLabelChar)
+
+		tok := token2.Token{Type: token2.T_STRING, Value: key, Pos: start, End: l.reader.Position()}
+		if _, err := strconv.ParseInt(key, 10, 64); err == nil && intOffsetPattern.MatchString(key) {
+			tok.Type = token2.T_NUMBER
+		}
+		return []token2.Token{tok}
+	default:
+		return nil
+	}
+}
+
+func (l *Lexer) labelAfterArrow() bool {
+	pos := l.reader.GetPos()
+	defer l.reader.SetPos(pos)
+
+	l.reader.Next()
+	l.reader.Next()
+	return tokenizer.IsLabelStart(l.reader.Peek())
+}
+
+func (l *Lexer) take(t token2.TokenType, n int) token2.Token {
+	start := l.reader.Position()
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		sb.WriteRune(l.reader.Next())
+	}
+	return token2.Token{Type: t, Value: sb.String(), Pos: start, End: l.reader.Position()}
+}
+
+func (l *Lexer) takeWhile(t token2.TokenType, cond func(rune) bool) token2.Token {
+	start := l.reader.Position()
+	value := l.reader.ReadWhile(cond)
+	return token2.Token{Type: t, Value: value, Pos: start, End: l.reader.Position()}
+}
+
+func (l *Lexer) illegal(msg string) token2.Token {
+	pos := l.reader.Position()
+	return token2.Token{Type: token2.T_ILLEGAL, Value: msg, Pos: pos, End: pos}
+}
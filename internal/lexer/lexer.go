@@ -23,12 +23,21 @@ import (
 )
 
 type Lexer struct {
-	reader     *reader.SourceReader
-	tokenizers *tokenizer.TokenizerRegistry
+	reader         *reader.SourceReader
+	tokenizers     *tokenizer.TokenizerRegistry
+	interpolations []*interpolation
+	pending        []token2.Token
+	prev           token2.TokenType
 }
 
 func NewLexer(input string) *Lexer {
-	sourceReader := reader.NewSourceReader(input)
+	return NewFileLexer("", input)
+}
+
+// NewFileLexer creates a lexer whose token positions refer to filename.
+func NewFileLexer(filename, input string) *Lexer {
+	sourceReader := reader.NewSourceReader(filename, input)
+	sourceReader.SkipOpenTag()
 	tokenizerRegistry := tokenizer.NewTokenizerRegistry()
 
 	tokenizerRegistry.RegisterTokenizer(tokenizer.NewKeywordTokenizer())
@@ -43,8 +52,40 @@ func NewLexer(input string) *Lexer {
 }
 
 func (l *Lexer) NextToken() token2.Token {
+	tok := l.next()
+
+	// A keyword right after '$' is a variable name, as in $array or $default.
+	if l.prev == token2.T_DOLLAR && tok.Type != token2.T_ILLEGAL && isLabel(tok.Value) {
+		tok.Type = token2.T_IDENT
+	}
+
+	l.prev = tok.Type
+	return tok
+}
+
+func (l *Lexer) next() token2.Token {
+	if len(l.pending) > 0 {
+		tok := l.pending[0]
+		l.pending = l.pending[1:]
+		return tok
+	}
+
+	if s := l.openInterpolation(); s != nil && !s.inExpr {
+		return l.nextStringToken(s)
+	}
+
 	l.reader.SkipWhitespaceAndComments()
 
+	start := l.reader.Position()
+	tok := l.scan()
+	tok.Pos = start
+	tok.End = l.reader.Position()
+
+	l.track(tok)
+	return tok
+}
+
+func (l *Lexer) scan() token2.Token {
 	ch := l.reader.Peek()
 	if ch == 0 {
 		return token2.Token{Type: token2.T_EOF, Value: ""}
@@ -59,3 +100,12 @@ func (l *Lexer) NextToken() token2.Token {
 	l.reader.Next()
 	return token2.Token{Type: token2.T_ILLEGAL, Value: fmt.Sprintf("Undefined symbol: '%s'", charStr)}
 }
+
+func isLabel(s string) bool {
+	for i, r := range s {
+		if !tokenizer.IsLabelChar(r) || (i == 0 && !tokenizer.IsLabelStart(r)) {
+			return false
+		}
+	}
+	return s != ""
+}
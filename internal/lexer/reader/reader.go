@@ -2,18 +2,57 @@
 package reader
 
 import (
+	"sort"
 	"unicode"
+
+	"github.com/neokofg/php-compiler/internal/token"
 )
 
+const openTag = "<?php"
+
 type SourceReader struct {
-	input []rune
-	pos   int
+	filename   string
+	input      []rune
+	pos        int
+	lineStarts []int
+}
+
+func NewSourceReader(filename, input string) *SourceReader {
+	r := &SourceReader{
+		filename: filename,
+		input:    []rune(input),
+		pos:      0,
+	}
+
+	r.lineStarts = []int{0}
+	for i, ch := range r.input {
+		if ch == '\n' {
+			r.lineStarts = append(r.lineStarts, i+1)
+		}
+	}
+
+	return r
+}
+
+// Position converts the current offset into a line and column. Lines are
+// looked up from a table built once, so SetPos can move freely.
+func (r *SourceReader) Position() token.Position {
+	line := sort.Search(len(r.lineStarts), func(i int) bool {
+		return r.lineStarts[i] > r.pos
+	})
+
+	return token.Position{
+		Filename: r.filename,
+		Offset:   r.pos,
+		Line:     line,
+		Column:   r.pos - r.lineStarts[line-1] + 1,
+	}
 }
 
-func NewSourceReader(input string) *SourceReader {
-	return &SourceReader{
-		input: []rune(input),
-		pos:   0,
+// SkipOpenTag consumes the leading "<?php" tag, if the input starts with one.
+func (r *SourceReader) SkipOpenTag() {
+	if r.pos == 0 && len(r.input) >= len(openTag) && string(r.input[:len(openTag)]) == openTag {
+		r.pos = len(openTag)
 	}
 }
 
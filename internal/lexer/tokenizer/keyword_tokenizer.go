@@ -38,6 +38,8 @@ func (t *KeywordTokenizer) Tokenize(reader interfaces.Reader) token.Token {
 		return token.Token{Type: token.T_TRUE, Value: val}
 	case "false":
 		return token.Token{Type: token.T_FALSE, Value: val}
+	case "null":
+		return token.Token{Type: token.T_NULL, Value: val}
 	case "break":
 		return token.Token{Type: token.T_BREAK, Value: val}
 	case "continue":
@@ -52,8 +54,70 @@ func (t *KeywordTokenizer) Tokenize(reader interfaces.Reader) token.Token {
 		return token.Token{Type: token.T_DEFAULT, Value: val}
 	case "function":
 		return token.Token{Type: token.T_FUNCTION, Value: val}
+	case "fn":
+		return token.Token{Type: token.T_FN, Value: val}
 	case "return":
 		return token.Token{Type: token.T_RETURN, Value: val}
+	case "global":
+		return token.Token{Type: token.T_GLOBAL, Value: val}
+	case "static":
+		return token.Token{Type: token.T_STATIC, Value: val}
+	case "array":
+		return token.Token{Type: token.T_ARRAY, Value: val}
+	case "foreach":
+		return token.Token{Type: token.T_FOREACH, Value: val}
+	case "as":
+		return token.Token{Type: token.T_AS, Value: val}
+	case "class":
+		return token.Token{Type: token.T_CLASS, Value: val}
+	case "new":
+		return token.Token{Type: token.T_NEW, Value: val}
+	case "public":
+		return token.Token{Type: token.T_PUBLIC, Value: val}
+	case "protected":
+		return token.Token{Type: token.T_PROTECTED, Value: val}
+	case "private":
+		return token.Token{Type: token.T_PRIVATE, Value: val}
+	case "var":
+		return token.Token{Type: token.T_VAR, Value: val}
+	case "abstract":
+		return token.Token{Type: token.T_ABSTRACT, Value: val}
+	case "final":
+		return token.Token{Type: token.T_FINAL, Value: val}
+	case "interface":
+		return token.Token{Type: token.T_INTERFACE, Value: val}
+	case "trait":
+		return token.Token{Type: token.T_TRAIT, Value: val}
+	case "extends":
+		return token.Token{Type: token.T_EXTENDS, Value: val}
+	case "implements":
+		return token.Token{Type: token.T_IMPLEMENTS, Value: val}
+	case "use":
+		return token.Token{Type: token.T_USE, Value: val}
+	case "insteadof":
+		return token.Token{Type: token.T_INSTEADOF, Value: val}
+	case "try":
+		return token.Token{Type: token.T_TRY, Value: val}
+	case "catch":
+		return token.Token{Type: token.T_CATCH, Value: val}
+	case "finally":
+		return token.Token{Type: token.T_FINALLY, Value: val}
+	case "throw":
+		return token.Token{Type: token.T_THROW, Value: val}
+	case "isset":
+		return token.Token{Type: token.T_ISSET, Value: val}
+	case "empty":
+		return token.Token{Type: token.T_EMPTY, Value: val}
+	case "unset":
+		return token.Token{Type: token.T_UNSET, Value: val}
+	case "and":
+		return token.Token{Type: token.T_LOGICAL_AND, Value: val}
+	case "or":
+		return token.Token{Type: token.T_LOGICAL_OR, Value: val}
+	case "xor":
+		return token.Token{Type: token.T_LOGICAL_XOR, Value: val}
+	case "instanceof":
+		return token.Token{Type: token.T_INSTANCEOF, Value: val}
 	default:
 		return token.Token{Type: token.T_IDENT, Value: val}
 	}
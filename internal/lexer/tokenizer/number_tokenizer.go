@@ -17,7 +17,92 @@ func (t *NumberTokenizer) CanTokenize(r rune) bool {
 	return unicode.IsDigit(r)
 }
 
+// Tokenize reads an integer or a float literal. Integers may be written in
+// hex (0x1F), octal (0o17 or 017) or binary (0b1010), and any literal may use
+// '_' between digits (1_000_000). Floats are written as `1.5`, `1.`, `.5` or
+// with an exponent like `1e10` and `2.5E-3`. A literal starting with '.' is
+// handed over by the operator tokenizer. The value keeps the source text;
+// the parser converts it.
 func (t *NumberTokenizer) Tokenize(reader interfaces.Reader) token2.Token {
-	val := reader.ReadWhile(unicode.IsDigit)
+	if reader.Peek() == '0' {
+		if isDigit, ok := basePrefixes[reader.PeekNext()]; ok {
+			prefix := string(reader.Next()) + string(reader.Next())
+			digits := readDigits(reader, isDigit)
+			if digits == "" {
+				return token2.Token{Type: token2.T_ILLEGAL, Value: "Invalid numeric literal: " + prefix}
+			}
+			return token2.Token{Type: token2.T_NUMBER, Value: prefix + digits}
+		}
+	}
+
+	val := readDigits(reader, isDecimalDigit)
+	isFloat := false
+
+	if reader.Peek() == '.' && (val == "" || reader.PeekNext() != '.') {
+		reader.Next()
+		val += "." + readDigits(reader, isDecimalDigit)
+		isFloat = true
+	}
+
+	if exponent := readExponent(reader); exponent != "" {
+		val += exponent
+		isFloat = true
+	}
+
+	if isFloat {
+		return token2.Token{Type: token2.T_FLOAT, Value: val}
+	}
 	return token2.Token{Type: token2.T_NUMBER, Value: val}
 }
+
+var basePrefixes = map[rune]func(rune) bool{
+	'x': isHexDigit, 'X': isHexDigit,
+	'o': isOctalDigit, 'O': isOctalDigit,
+	'b': isBinaryDigit, 'B': isBinaryDigit,
+}
+
+func isDecimalDigit(r rune) bool { return r >= '0' && r <= '9' }
+func isOctalDigit(r rune) bool   { return r >= '0' && r <= '7' }
+func isBinaryDigit(r rune) bool  { return r == '0' || r == '1' }
+
+func isHexDigit(r rune) bool {
+	return isDecimalDigit(r) || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+}
+
+// readDigits reads digits accepted by isDigit, allowing a single '_'
+// between two of them.
+func readDigits(reader interfaces.Reader, isDigit func(rune) bool) string {
+	var digits []rune
+	for {
+		switch {
+		case isDigit(reader.Peek()):
+			digits = append(digits, reader.Next())
+		case reader.Peek() == '_' && len(digits) > 0 && isDigit(reader.PeekNext()):
+			digits = append(digits, reader.Next())
+		default:
+			return string(digits)
+		}
+	}
+}
+
+// readExponent consumes an exponent such as `e10` or `E-3`. Nothing is
+// consumed when the 'e' is not followed by digits.
+func readExponent(reader interfaces.Reader) string {
+	if reader.Peek() != 'e' && reader.Peek() != 'E' {
+		return ""
+	}
+
+	start := reader.GetPos()
+	exponent := string(reader.Next())
+	if reader.Peek() == '+' || reader.Peek() == '-' {
+		exponent += string(reader.Next())
+	}
+
+	digits := readDigits(reader, isDecimalDigit)
+	if digits == "" {
+		reader.SetPos(start)
+		return ""
+	}
+
+	return exponent + digits
+}
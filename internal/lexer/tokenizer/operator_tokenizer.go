@@ -4,6 +4,7 @@ package tokenizer
 import (
 	"github.com/neokofg/php-compiler/internal/lexer/interfaces"
 	"github.com/neokofg/php-compiler/internal/token"
+	"unicode"
 )
 
 type OperatorTokenizer struct{}
@@ -14,7 +15,7 @@ func NewOperatorTokenizer() *OperatorTokenizer {
 
 func (t *OperatorTokenizer) CanTokenize(r rune) bool {
 	switch r {
-	case '+', '-', '*', '/', '=', ';', '$', '(', ')', '{', '}', '>', '<', '&', '|', '!', '.', '%', '^', '~', ':', ',':
+	case '+', '-', '*', '/', '=', ';', '$', '(', ')', '{', '}', '>', '<', '&', '|', '!', '.', '%', '^', '~', ':', ',', '[', ']', '?':
 		return true
 	default:
 		return false
@@ -41,6 +42,9 @@ func (t *OperatorTokenizer) Tokenize(reader interfaces.Reader) token.Token {
 		} else if reader.Peek() == '=' {
 			reader.Next()
 			return token.Token{Type: token.T_MINUS_EQ, Value: "-="}
+		} else if reader.Peek() == '>' {
+			reader.Next()
+			return token.Token{Type: token.T_OBJECT_OPERATOR, Value: "->"}
 		}
 		return token.Token{Type: token.T_MINUS, Value: "-"}
 	case '*':
@@ -48,6 +52,13 @@ func (t *OperatorTokenizer) Tokenize(reader interfaces.Reader) token.Token {
 		if reader.Peek() == '=' {
 			reader.Next()
 			return token.Token{Type: token.T_MUL_EQ, Value: "*="}
+		} else if reader.Peek() == '*' {
+			reader.Next()
+			if reader.Peek() == '=' {
+				reader.Next()
+				return token.Token{Type: token.T_POW_EQ, Value: "**="}
+			}
+			return token.Token{Type: token.T_POW, Value: "**"}
 		}
 		return token.Token{Type: token.T_STAR, Value: "*"}
 	case '/':
@@ -73,6 +84,9 @@ func (t *OperatorTokenizer) Tokenize(reader interfaces.Reader) token.Token {
 				return token.Token{Type: token.T_EQEQEQ, Value: "==="}
 			}
 			return token.Token{Type: token.T_EQEQ, Value: "=="}
+		} else if reader.Peek() == '>' {
+			reader.Next()
+			return token.Token{Type: token.T_DOUBLE_ARROW, Value: "=>"}
 		}
 		return token.Token{Type: token.T_EQ, Value: "="}
 	case ';':
@@ -100,16 +114,31 @@ func (t *OperatorTokenizer) Tokenize(reader interfaces.Reader) token.Token {
 			return token.Token{Type: token.T_GTE, Value: ">="}
 		} else if reader.Peek() == '>' {
 			reader.Next()
+			if reader.Peek() == '=' {
+				reader.Next()
+				return token.Token{Type: token.T_RSHIFT_EQ, Value: ">>="}
+			}
 			return token.Token{Type: token.T_RSHIFT, Value: ">>"}
 		}
 		return token.Token{Type: token.T_GT, Value: ">"}
 	case '<':
+		if isHeredocStart(reader) {
+			return NewStringTokenizer().Tokenize(reader)
+		}
 		reader.Next()
 		if reader.Peek() == '=' {
 			reader.Next()
+			if reader.Peek() == '>' {
+				reader.Next()
+				return token.Token{Type: token.T_SPACESHIP, Value: "<=>"}
+			}
 			return token.Token{Type: token.T_LTE, Value: "<="}
 		} else if reader.Peek() == '<' {
 			reader.Next()
+			if reader.Peek() == '=' {
+				reader.Next()
+				return token.Token{Type: token.T_LSHIFT_EQ, Value: "<<="}
+			}
 			return token.Token{Type: token.T_LSHIFT, Value: "<<"}
 		}
 		return token.Token{Type: token.T_LT, Value: "<"}
@@ -118,6 +147,9 @@ func (t *OperatorTokenizer) Tokenize(reader interfaces.Reader) token.Token {
 		if reader.Peek() == '&' {
 			reader.Next()
 			return token.Token{Type: token.T_AND, Value: "&&"}
+		} else if reader.Peek() == '=' {
+			reader.Next()
+			return token.Token{Type: token.T_BIT_AND_EQ, Value: "&="}
 		}
 		return token.Token{Type: token.T_BIT_AND, Value: "&"}
 	case '|':
@@ -125,6 +157,9 @@ func (t *OperatorTokenizer) Tokenize(reader interfaces.Reader) token.Token {
 		if reader.Peek() == '|' {
 			reader.Next()
 			return token.Token{Type: token.T_OR, Value: "||"}
+		} else if reader.Peek() == '=' {
+			reader.Next()
+			return token.Token{Type: token.T_BIT_OR_EQ, Value: "|="}
 		}
 		return token.Token{Type: token.T_BIT_OR, Value: "|"}
 	case '!':
@@ -140,11 +175,18 @@ func (t *OperatorTokenizer) Tokenize(reader interfaces.Reader) token.Token {
 		return token.Token{Type: token.T_NOT, Value: "!"}
 	case '^':
 		reader.Next()
+		if reader.Peek() == '=' {
+			reader.Next()
+			return token.Token{Type: token.T_BIT_XOR_EQ, Value: "^="}
+		}
 		return token.Token{Type: token.T_BIT_XOR, Value: "^"}
 	case '~':
 		reader.Next()
 		return token.Token{Type: token.T_BIT_NOT, Value: "~"}
 	case '.':
+		if unicode.IsDigit(reader.PeekNext()) {
+			return NewNumberTokenizer().Tokenize(reader)
+		}
 		reader.Next()
 		if reader.Peek() == '=' {
 			reader.Next()
@@ -153,11 +195,40 @@ func (t *OperatorTokenizer) Tokenize(reader interfaces.Reader) token.Token {
 		return token.Token{Type: token.T_DOT, Value: "."}
 	case ':':
 		reader.Next()
+		if reader.Peek() == ':' {
+			reader.Next()
+			return token.Token{Type: token.T_DOUBLE_COLON, Value: "::"}
+		}
 		return token.Token{Type: token.T_COLON, Value: ":"}
+	case '?':
+		reader.Next()
+		if reader.Peek() == '?' {
+			reader.Next()
+			if reader.Peek() == '=' {
+				reader.Next()
+				return token.Token{Type: token.T_COALESCE_EQ, Value: "??="}
+			}
+			return token.Token{Type: token.T_COALESCE, Value: "??"}
+		}
+		return token.Token{Type: token.T_QUESTION, Value: "?"}
 	case ',':
 		reader.Next()
 		return token.Token{Type: token.T_COMMA, Value: ","}
+	case '[':
+		reader.Next()
+		return token.Token{Type: token.T_LBRACKET, Value: "["}
+	case ']':
+		reader.Next()
+		return token.Token{Type: token.T_RBRACKET, Value: "]"}
 	default:
 		return token.Token{Type: token.T_ILLEGAL, Value: ""}
 	}
 }
+
+// isHeredocStart reports whether the reader is at `<<<`.
+func isHeredocStart(reader interfaces.Reader) bool {
+	pos := reader.GetPos()
+	defer reader.SetPos(pos)
+
+	return reader.Next() == '<' && reader.Next() == '<' && reader.Next() == '<'
+}
@@ -2,9 +2,12 @@
 package tokenizer
 
 import (
+	"fmt"
 	"github.com/neokofg/php-compiler/internal/lexer/interfaces"
 	token2 "github.com/neokofg/php-compiler/internal/token"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 type StringTokenizer struct{}
@@ -14,50 +17,329 @@ func NewStringTokenizer() *StringTokenizer {
 }
 
 func (t *StringTokenizer) CanTokenize(r rune) bool {
-	return r == '"'
+	return r == '"' || r == '\''
 }
 
+// Tokenize reads a double-quoted, single-quoted, heredoc or nowdoc string.
+// Heredocs start with '<', so they are handed over by the operator tokenizer.
 func (t *StringTokenizer) Tokenize(reader interfaces.Reader) token2.Token {
+	switch reader.Peek() {
+	case '\'':
+		return t.tokenizeSingleQuoted(reader)
+	case '<':
+		return t.tokenizeHeredoc(reader)
+	default:
+		return t.tokenizeDoubleQuoted(reader)
+	}
+}
+
+// tokenizeDoubleQuoted returns a T_STRING for a constant string. A string
+// with embedded variables yields T_STRING_START instead and leaves the reader
+// after the opening quote, so that the lexer can split it into parts.
+func (t *StringTokenizer) tokenizeDoubleQuoted(reader interfaces.Reader) token2.Token {
+	startPos := reader.GetPos()
+	raw, ok := readQuoted(reader, '"')
+	if !ok {
+		return token2.Token{Type: token2.T_ILLEGAL, Value: "Unexpected end of string"}
+	}
+
+	if HasInterpolation(raw) {
+		reader.SetPos(startPos + 1)
+		return token2.Token{Type: token2.T_STRING_START, Value: "\""}
+	}
+
+	val, err := UnescapeString(raw, '"')
+	if err != nil {
+		return token2.Token{Type: token2.T_ILLEGAL, Value: err.Error()}
+	}
+
+	return token2.Token{Type: token2.T_STRING, Value: val}
+}
+
+// tokenizeSingleQuoted only knows the escapes \' and \\; any other
+// backslash is kept as is.
+func (t *StringTokenizer) tokenizeSingleQuoted(reader interfaces.Reader) token2.Token {
+	raw, ok := readQuoted(reader, '\'')
+	if !ok {
+		return token2.Token{Type: token2.T_ILLEGAL, Value: "Unexpected end of string"}
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '\\' && i+1 < len(raw) && (raw[i+1] == '\'' || raw[i+1] == '\\') {
+			i++
+		}
+		sb.WriteByte(raw[i])
+	}
+
+	return token2.Token{Type: token2.T_STRING, Value: sb.String()}
+}
+
+// readQuoted returns the text between the opening quote under the reader
+// and the matching closing quote, with escapes left untouched. On an
+// unterminated string the reader is moved back after the opening quote.
+func readQuoted(reader interfaces.Reader, quote rune) (string, bool) {
 	reader.Next()
+	startPos := reader.GetPos()
+
 	var sb strings.Builder
+	for {
+		ch := reader.Next()
+		switch ch {
+		case 0:
+			reader.SetPos(startPos)
+			return "", false
+		case quote:
+			return sb.String(), true
+		case '\\':
+			sb.WriteRune(ch)
+			if next := reader.Next(); next != 0 {
+				sb.WriteRune(next)
+			}
+		default:
+			sb.WriteRune(ch)
+		}
+	}
+}
+
+// tokenizeHeredoc reads `<<<ID`, `<<<"ID"` or the nowdoc form `<<<'ID'`.
+// The closing marker may be indented; that indentation is removed from
+// every line of the body, as in PHP 7.3 and later.
+func (t *StringTokenizer) tokenizeHeredoc(reader interfaces.Reader) token2.Token {
 	startPos := reader.GetPos()
+	illegal := func(format string, args ...interface{}) token2.Token {
+		reader.SetPos(startPos + 3)
+		return token2.Token{Type: token2.T_ILLEGAL, Value: fmt.Sprintf(format, args...)}
+	}
+
+	reader.Next()
+	reader.Next()
+	reader.Next()
+	reader.ReadWhile(func(r rune) bool { return r == ' ' || r == '\t' })
+
+	quote := reader.Peek()
+	if quote == '\'' || quote == '"' {
+		reader.Next()
+	} else {
+		quote = 0
+	}
+
+	label := reader.ReadWhile(IsLabelChar)
+	if label == "" || isDecimalDigit(rune(label[0])) {
+		return illegal("Invalid heredoc label")
+	}
+	if quote != 0 && reader.Next() != quote {
+		return illegal("Unterminated heredoc label %s", label)
+	}
+
+	if reader.Peek() == '\r' {
+		reader.Next()
+	}
+	if reader.Next() != '\n' {
+		return illegal("Heredoc label %s must be followed by a newline", label)
+	}
+
+	bodyPos := reader.GetPos()
+	body, err := ScanHeredocBody(reader, label)
+	if err != nil {
+		return illegal("%v", err)
+	}
+
+	text, err := removeIndentation(body.Lines, body.Indent)
+	if err != nil {
+		return illegal("%v", err)
+	}
+	if quote == '\'' {
+		return token2.Token{Type: token2.T_STRING, Value: text}
+	}
+	if HasInterpolation(text) {
+		reader.SetPos(bodyPos)
+		return token2.Token{Type: token2.T_STRING_START, Value: label}
+	}
+
+	val, err := UnescapeString(text, 0)
+	if err != nil {
+		return illegal("%v", err)
+	}
+	return token2.Token{Type: token2.T_STRING, Value: val}
+}
+
+// HeredocBody describes the body of a heredoc, from the line after the
+// opening label up to the closing marker.
+type HeredocBody struct {
+	Lines  []string
+	Indent string // indentation of the closing marker
+	End    int    // reader position of the newline that ends the body
+	Resume int    // reader position right after the closing marker
+}
+
+// ScanHeredocBody finds the closing marker of the heredoc whose body starts
+// at the reader and leaves the reader at body.Resume.
+func ScanHeredocBody(reader interfaces.Reader, label string) (HeredocBody, error) {
+	var body HeredocBody
+	body.End = reader.GetPos()
 
 	for {
-		ch := reader.Peek()
-		if ch == '"' {
-			reader.Next()
-			break
+		if reader.Peek() == 0 {
+			return body, fmt.Errorf("Unterminated heredoc, expected closing marker %s", label)
 		}
-		if ch == 0 {
-			reader.SetPos(startPos)
-			return token2.Token{Type: token2.T_ILLEGAL, Value: "Unexpected end of string"}
-		}
-		if ch == '\\' {
-			reader.Next()
-			switch reader.Peek() {
-			case 'n':
-				sb.WriteRune('\n')
-				reader.Next()
-			case 't':
-				sb.WriteRune('\t')
-				reader.Next()
-			case 'r':
-				sb.WriteRune('\r')
-				reader.Next()
-			case '"':
-				sb.WriteRune('"')
-				reader.Next()
-			case '\\':
-				sb.WriteRune('\\')
-				reader.Next()
-			default:
-				sb.WriteRune('\\')
-				sb.WriteRune(reader.Next())
-			}
-		} else {
-			sb.WriteRune(reader.Next())
+
+		line := reader.ReadWhile(func(r rune) bool { return r != '\n' && r != 0 })
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		rest := line[indent:]
+
+		if strings.HasPrefix(rest, label) && !startsWithLabelChar(rest[len(label):]) {
+			body.Indent = line[:indent]
+			body.Resume = reader.GetPos() - utf8.RuneCountInString(rest) + utf8.RuneCountInString(label)
+			if len(body.Lines) > 0 && strings.HasSuffix(body.Lines[len(body.Lines)-1], "\r") {
+				body.End--
+			}
+			reader.SetPos(body.Resume)
+			return body, nil
 		}
+
+		body.End = reader.GetPos()
+		body.Lines = append(body.Lines, line)
+		reader.Next() // \n
 	}
+}
 
-	return token2.Token{Type: token2.T_STRING, Value: sb.String()}
+// removeIndentation strips the indentation of the closing marker from the
+// body lines and joins them. The newline before the marker is not part of
+// the string.
+func removeIndentation(lines []string, indent string) (string, error) {
+	if strings.Contains(indent, " ") && strings.Contains(indent, "\t") {
+		return "", fmt.Errorf("Invalid indentation - tabs and spaces cannot be mixed")
+	}
+
+	for i, line := range lines {
+		if strings.TrimRight(line, "\r") == "" {
+			lines[i] = ""
+			continue
+		}
+		if !strings.HasPrefix(line, indent) {
+			return "", fmt.Errorf("Invalid body indentation level (expecting an indentation level of at least %d)", len(indent))
+		}
+		lines[i] = line[len(indent):]
+	}
+
+	body := strings.Join(lines, "\n")
+	return strings.TrimSuffix(body, "\r"), nil
+}
+
+// HasInterpolation reports whether the raw body of a double-quoted string
+// or heredoc embeds a variable: `$name`, `${` or `{$`.
+func HasInterpolation(raw string) bool {
+	for i := 0; i+1 < len(raw); i++ {
+		switch raw[i] {
+		case '\\':
+			i++
+		case '$':
+			if IsLabelStart(rune(raw[i+1])) || raw[i+1] == '{' {
+				return true
+			}
+		case '{':
+			if raw[i+1] == '$' {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsLabelStart reports whether r can start a variable or heredoc label.
+func IsLabelStart(r rune) bool {
+	return IsLabelChar(r) && !isDecimalDigit(r)
+}
+
+// IsLabelChar reports whether r can appear in a variable or heredoc label.
+func IsLabelChar(r rune) bool {
+	return r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || isDecimalDigit(r) || r >= 0x80
+}
+
+func startsWithLabelChar(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return s != "" && IsLabelChar(r)
+}
+
+// UnescapeString processes the escape sequences of a double-quoted string
+// (quote '"') or a heredoc (quote 0): \n \t \r \v \e \f \\ \$, octal \101,
+// hex \x41 and unicode \u{1F600}. \" is an escape only inside double
+// quotes. Unknown sequences keep their backslash, as in PHP.
+func UnescapeString(raw string, quote byte) (string, error) {
+	var sb strings.Builder
+
+	for i := 0; i < len(raw); i++ {
+		ch := raw[i]
+		if ch != '\\' || i+1 >= len(raw) {
+			sb.WriteByte(ch)
+			continue
+		}
+
+		next := raw[i+1]
+		switch {
+		case simpleEscapes[next] != 0:
+			sb.WriteByte(simpleEscapes[next])
+			i++
+		case next == quote:
+			sb.WriteByte(quote)
+			i++
+		case next >= '0' && next <= '7':
+			end := i + 1
+			for end < len(raw) && end < i+4 && raw[end] >= '0' && raw[end] <= '7' {
+				end++
+			}
+			n, _ := strconv.ParseUint(raw[i+1:end], 8, 16)
+			sb.WriteByte(byte(n)) // \400 and above wrap around, as in PHP
+			i = end - 1
+		case next == 'x' && i+2 < len(raw) && isHexDigit(rune(raw[i+2])):
+			end := i + 2
+			for end < len(raw) && end < i+4 && isHexDigit(rune(raw[end])) {
+				end++
+			}
+			n, _ := strconv.ParseUint(raw[i+2:end], 16, 8)
+			sb.WriteByte(byte(n))
+			i = end - 1
+		case next == 'u' && i+2 < len(raw) && raw[i+2] == '{':
+			end := strings.IndexByte(raw[i+3:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("Invalid UTF-8 codepoint escape sequence")
+			}
+			digits := raw[i+3 : i+3+end]
+			n, err := strconv.ParseUint(digits, 16, 32)
+			if err != nil || digits == "" {
+				return "", fmt.Errorf("Invalid UTF-8 codepoint escape sequence")
+			}
+			if n > utf8.MaxRune {
+				return "", fmt.Errorf("Invalid UTF-8 codepoint escape sequence: Codepoint too large")
+			}
+			// Surrogates are encoded as is, like PHP does.
+			sb.WriteString(encodeCodepoint(rune(n)))
+			i += 3 + end
+		default:
+			sb.WriteByte('\\')
+		}
+	}
+
+	return sb.String(), nil
+}
+
+var simpleEscapes = map[byte]byte{
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+	'v':  '\v',
+	'e':  0x1B,
+	'f':  '\f',
+	'\\': '\\',
+	'$':  '$',
+}
+
+// encodeCodepoint is utf8.EncodeRune without the replacement of surrogate
+// halves, which PHP encodes like any other codepoint.
+func encodeCodepoint(r rune) string {
+	if r >= 0xD800 && r <= 0xDFFF {
+		return string([]byte{0xE0 | byte(r>>12), 0x80 | byte(r>>6)&0x3F, 0x80 | byte(r)&0x3F})
+	}
+	return string(r)
 }
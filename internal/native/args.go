@@ -0,0 +1,66 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package native
+
+import (
+	"github.com/neokofg/php-compiler/internal/vm/value"
+)
+
+// The helpers below coerce arguments the way PHP does for scalar parameters
+// outside strict_types mode, and fail with PHP's TypeError message when the
+// value cannot be coerced.
+
+func typeError(fn string, i int, param, want string, got value.Value) error {
+	return NewError("TypeError", "%s(): Argument #%d ($%s) must be of type %s, %s given", fn, i+1, param, want, got.TypeName())
+}
+
+func valueError(fn string, i int, param, msg string) error {
+	return NewError("ValueError", "%s(): Argument #%d ($%s) %s", fn, i+1, param, msg)
+}
+
+func stringArg(fn string, args []value.Value, i int, param string) (string, error) {
+	if args[i].Type == value.TypeArray || args[i].Type == value.TypeObject {
+		return "", typeError(fn, i, param, "string", args[i])
+	}
+	return args[i].ToString(), nil
+}
+
+// numberArg returns an int or a float. Strings must be numeric.
+func numberArg(fn string, args []value.Value, i int, param, want string) (value.Value, error) {
+	v := args[i]
+	switch v.Type {
+	case value.TypeInt, value.TypeFloat:
+		return v, nil
+	case value.TypeString:
+		if n, ok := value.ParseNumeric(v.Str); ok {
+			return n, nil
+		}
+		return value.Value{}, typeError(fn, i, param, want, v)
+	case value.TypeArray, value.TypeObject:
+		return value.Value{}, typeError(fn, i, param, want, v)
+	default:
+		return value.NewInt(v.ToInt()), nil
+	}
+}
+
+func intArg(fn string, args []value.Value, i int, param string) (int64, error) {
+	n, err := numberArg(fn, args, i, param, "int")
+	if err != nil {
+		return 0, err
+	}
+	return n.ToInt(), nil
+}
+
+func floatArg(fn string, args []value.Value, i int, param string) (float64, error) {
+	n, err := numberArg(fn, args, i, param, "float")
+	if err != nil {
+		return 0, err
+	}
+	return n.ToFloat(), nil
+}
+
+func arrayArg(fn string, args []value.Value, i int, param string) (*value.Array, error) {
+	if args[i].Type != value.TypeArray {
+		return nil, typeError(fn, i, param, "array", args[i])
+	}
+	return args[i].Arr, nil
+}
@@ -0,0 +1,453 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package native
+
+import (
+	"math"
+	"sort"
+
+	"github.com/neokofg/php-compiler/internal/vm/value"
+)
+
+func init() {
+	for _, f := range []Function{
+		{Name: "count", MinArgs: 1, MaxArgs: 1, Impl: count},
+		{Name: "array_keys", MinArgs: 1, MaxArgs: 1, Impl: arrayKeys},
+		{Name: "array_values", MinArgs: 1, MaxArgs: 1, Impl: arrayValues},
+		{Name: "in_array", MinArgs: 2, MaxArgs: 3, Impl: inArray},
+		{Name: "array_key_exists", MinArgs: 2, MaxArgs: 2, Impl: arrayKeyExists},
+		{Name: "array_sum", MinArgs: 1, MaxArgs: 1, Impl: arraySum},
+		{Name: "array_merge", MinArgs: 0, MaxArgs: Variadic, Impl: arrayMerge},
+		{Name: "array_reverse", MinArgs: 1, MaxArgs: 2, Impl: arrayReverse},
+		{Name: "range", MinArgs: 2, MaxArgs: 3, Impl: rangeOf},
+		{Name: "array_push", MinArgs: 1, MaxArgs: Variadic, ByRef: []int{0}, Impl: arrayPush},
+		{Name: "array_pop", MinArgs: 1, MaxArgs: 1, ByRef: []int{0}, Impl: arrayPop},
+		{Name: "sort", MinArgs: 1, MaxArgs: 1, ByRef: []int{0}, Impl: sortArray},
+		{Name: "usort", MinArgs: 2, MaxArgs: 2, ByRef: []int{0}, Impl: usort},
+		{Name: "array_map", MinArgs: 2, MaxArgs: 2, Impl: arrayMap},
+
+		{Name: "is_array", MinArgs: 1, MaxArgs: 1, Impl: isType(value.TypeArray)},
+		{Name: "is_bool", MinArgs: 1, MaxArgs: 1, Impl: isType(value.TypeBool)},
+		{Name: "is_float", MinArgs: 1, MaxArgs: 1, Impl: isType(value.TypeFloat)},
+		{Name: "is_int", MinArgs: 1, MaxArgs: 1, Impl: isType(value.TypeInt)},
+		{Name: "is_string", MinArgs: 1, MaxArgs: 1, Impl: isType(value.TypeString)},
+		{Name: "is_numeric", MinArgs: 1, MaxArgs: 1, Impl: isNumeric},
+	} {
+		MustRegister(f)
+	}
+}
+
+func count(env Env, args []value.Value) (value.Value, error) {
+	if args[0].Type != value.TypeArray {
+		return value.Value{}, typeError("count", 0, "value", "Countable|array", args[0])
+	}
+	return value.NewInt(int64(args[0].Arr.Len())), nil
+}
+
+func arrayKeys(env Env, args []value.Value) (value.Value, error) {
+	arr, err := arrayArg("array_keys", args, 0, "array")
+	if err != nil {
+		return value.Value{}, err
+	}
+
+	result := value.NewArray()
+	for i := 0; i < arr.Len(); i++ {
+		k, _ := arr.At(i)
+		result.Append(k.ToValue())
+	}
+	return value.NewArrayValue(result), nil
+}
+
+func arrayValues(env Env, args []value.Value) (value.Value, error) {
+	arr, err := arrayArg("array_values", args, 0, "array")
+	if err != nil {
+		return value.Value{}, err
+	}
+
+	result := value.NewArray()
+	for i := 0; i < arr.Len(); i++ {
+		_, v := arr.At(i)
+		result.Append(v)
+	}
+	return value.NewArrayValue(result), nil
+}
+
+func inArray(env Env, args []value.Value) (value.Value, error) {
+	arr, err := arrayArg("in_array", args, 1, "haystack")
+	if err != nil {
+		return value.Value{}, err
+	}
+
+	strict := len(args) > 2 && args[2].ToBool()
+	for i := 0; i < arr.Len(); i++ {
+		_, v := arr.At(i)
+		if (strict && value.Identical(v, args[0])) || (!strict && value.LooseEquals(v, args[0])) {
+			return value.NewBool(true), nil
+		}
+	}
+	return value.NewBool(false), nil
+}
+
+func arrayKeyExists(env Env, args []value.Value) (value.Value, error) {
+	arr, err := arrayArg("array_key_exists", args, 1, "array")
+	if err != nil {
+		return value.Value{}, err
+	}
+
+	k, err := value.ToKey(args[0])
+	if err != nil {
+		return value.Value{}, valueError("array_key_exists", 0, "key", "must be a valid array offset type")
+	}
+	_, exists := arr.Get(k)
+	return value.NewBool(exists), nil
+}
+
+// arraySum adds the elements like the + operator, skipping arrays.
+func arraySum(env Env, args []value.Value) (value.Value, error) {
+	arr, err := arrayArg("array_sum", args, 0, "array")
+	if err != nil {
+		return value.Value{}, err
+	}
+
+	sum := value.NewInt(0)
+	for i := 0; i < arr.Len(); i++ {
+		_, v := arr.At(i)
+		if v.Type == value.TypeArray {
+			env.Warnf("Addition is not supported on type array")
+			continue
+		}
+
+		n := v.ToNumber()
+		if sum.Type == value.TypeInt && n.Type == value.TypeInt {
+			if result := sum.Int + n.Int; (result > sum.Int) == (n.Int > 0) {
+				sum = value.NewInt(result)
+				continue
+			}
+		}
+		sum = value.NewFloat(sum.ToFloat() + n.ToFloat())
+	}
+	return sum, nil
+}
+
+// arrayMerge appends the elements of each array in turn: int keys are
+// renumbered, string keys overwrite earlier values.
+func arrayMerge(env Env, args []value.Value) (value.Value, error) {
+	result := value.NewArray()
+	for i := range args {
+		arr, err := arrayArg("array_merge", args, i, "arrays")
+		if err != nil {
+			return value.Value{}, err
+		}
+
+		for j := 0; j < arr.Len(); j++ {
+			k, v := arr.At(j)
+			if k.IsString {
+				result.Set(k, v)
+			} else if err := result.Append(v); err != nil {
+				return value.Value{}, err
+			}
+		}
+	}
+	return value.NewArrayValue(result), nil
+}
+
+func arrayReverse(env Env, args []value.Value) (value.Value, error) {
+	arr, err := arrayArg("array_reverse", args, 0, "array")
+	if err != nil {
+		return value.Value{}, err
+	}
+
+	preserveKeys := len(args) > 1 && args[1].ToBool()
+	result := value.NewArray()
+	for i := arr.Len() - 1; i >= 0; i-- {
+		k, v := arr.At(i)
+		if k.IsString || preserveKeys {
+			result.Set(k, v)
+		} else {
+			result.Append(v)
+		}
+	}
+	return value.NewArrayValue(result), nil
+}
+
+// rangeOf builds range($start, $end, $step): ints when both ends and the
+// step are ints, floats when one of them is a float, and letters when both
+// ends are single characters.
+func rangeOf(env Env, args []value.Value) (value.Value, error) {
+	step := value.NewInt(1)
+	if len(args) > 2 {
+		var err error
+		if step, err = numberArg("range", args, 2, "step", "int|float"); err != nil {
+			return value.Value{}, err
+		}
+	}
+	if step.ToFloat() == 0 {
+		return value.Value{}, valueError("range", 2, "step", "cannot be 0")
+	}
+	if step.Type == value.TypeFloat && step.Float == math.Trunc(step.Float) {
+		step = value.NewInt(int64(step.Float))
+	}
+
+	result := value.NewArray()
+
+	if letters, ok := letterRange(args); ok && step.Type == value.TypeInt {
+		start, end := int64(letters[0]), int64(letters[1])
+		n, err := rangeCount(end-start, step.Int)
+		if err != nil {
+			return value.Value{}, err
+		}
+		for i := int64(0); i < n; i++ {
+			c := start + i*abs64(step.Int)
+			if start > end {
+				c = start - i*abs64(step.Int)
+			}
+			result.Append(value.NewString(string([]byte{byte(c)})))
+		}
+		return value.NewArrayValue(result), nil
+	}
+
+	start, err := numberArg("range", args, 0, "start", "int|float|string")
+	if err != nil {
+		return value.Value{}, err
+	}
+	end, err := numberArg("range", args, 1, "end", "int|float|string")
+	if err != nil {
+		return value.Value{}, err
+	}
+
+	if start.Type == value.TypeInt && end.Type == value.TypeInt && step.Type == value.TypeInt {
+		n, err := rangeCount(end.Int-start.Int, step.Int)
+		if err != nil {
+			return value.Value{}, err
+		}
+		for i := int64(0); i < n; i++ {
+			if start.Int > end.Int {
+				result.Append(value.NewInt(start.Int - i*abs64(step.Int)))
+			} else {
+				result.Append(value.NewInt(start.Int + i*abs64(step.Int)))
+			}
+		}
+		return value.NewArrayValue(result), nil
+	}
+
+	low, high, by := start.ToFloat(), end.ToFloat(), math.Abs(step.ToFloat())
+	if math.Abs(high-low) < by && low != high {
+		return value.Value{}, valueError("range", 2, "step", "must not exceed the specified range")
+	}
+	size := math.Floor(math.Abs(high-low)/by) + 1
+	if math.IsNaN(size) || size > math.MaxInt32 {
+		return value.Value{}, NewError("ValueError", "The supplied range exceeds the maximum array size")
+	}
+	n := int64(size)
+	for i := int64(0); i < n; i++ {
+		if low > high {
+			result.Append(value.NewFloat(low - float64(i)*by))
+		} else {
+			result.Append(value.NewFloat(low + float64(i)*by))
+		}
+	}
+	return value.NewArrayValue(result), nil
+}
+
+// letterRange returns the two ends of a range of characters, such as
+// range('a', 'e').
+func letterRange(args []value.Value) ([2]byte, bool) {
+	var letters [2]byte
+	for i := 0; i < 2; i++ {
+		v := args[i]
+		if v.Type != value.TypeString || len(v.Str) != 1 || (v.Str[0] >= '0' && v.Str[0] <= '9') {
+			return letters, false
+		}
+		letters[i] = v.Str[0]
+	}
+	return letters, true
+}
+
+// rangeCount returns the number of elements of a range spanning span with
+// the given step.
+func rangeCount(span, step int64) (int64, error) {
+	span, step = abs64(span), abs64(step)
+	if span != 0 && step > span {
+		return 0, valueError("range", 2, "step", "must not exceed the specified range")
+	}
+	n := span/step + 1
+	if n > math.MaxInt32 {
+		return 0, NewError("ValueError", "The supplied range exceeds the maximum array size")
+	}
+	return n, nil
+}
+
+func abs64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// arrayPush appends the values to the array passed by reference and returns
+// its new size.
+func arrayPush(env Env, args []value.Value) (value.Value, error) {
+	arr, err := arrayArg("array_push", args, 0, "array")
+	if err != nil {
+		return value.Value{}, err
+	}
+
+	arr = arr.Separate()
+	for _, v := range args[1:] {
+		if err := arr.Append(v); err != nil {
+			return value.Value{}, err
+		}
+	}
+
+	args[0] = value.NewArrayValue(arr)
+	return value.NewInt(int64(arr.Len())), nil
+}
+
+func arrayPop(env Env, args []value.Value) (value.Value, error) {
+	arr, err := arrayArg("array_pop", args, 0, "array")
+	if err != nil {
+		return value.Value{}, err
+	}
+
+	arr = arr.Separate()
+	v, _ := arr.Pop()
+	args[0] = value.NewArrayValue(arr)
+	return v, nil
+}
+
+// sortArray sorts the values in ascending order with the rules of the <=>
+// operator and renumbers the keys. Equal values keep their order.
+func sortArray(env Env, args []value.Value) (value.Value, error) {
+	arr, err := arrayArg("sort", args, 0, "array")
+	if err != nil {
+		return value.Value{}, err
+	}
+
+	values := make([]value.Value, arr.Len())
+	for i := range values {
+		_, values[i] = arr.At(i)
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		return value.Compare(values[i], values[j]) < 0
+	})
+
+	sorted := value.NewArray()
+	for _, v := range values {
+		sorted.Append(v)
+	}
+
+	args[0] = value.NewArrayValue(sorted)
+	return value.NewBool(true), nil
+}
+
+// usort sorts the values of the array passed by reference with the
+// callback, which returns an int below, equal to or above zero like <=>,
+// and renumbers the keys. Equal values keep their order.
+func usort(env Env, args []value.Value) (value.Value, error) {
+	arr, err := arrayArg("usort", args, 0, "array")
+	if err != nil {
+		return value.Value{}, err
+	}
+
+	values := make([]value.Value, arr.Len())
+	for i := range values {
+		_, values[i] = arr.At(i)
+	}
+
+	value.Retain(args[0])
+	defer value.Release(args[0])
+	err = mergeSort(values, make([]value.Value, len(values)), func(a, b value.Value) (bool, error) {
+		order, err := env.Call(args[1], []value.Value{a, b})
+		return order.ToInt() < 0, err
+	})
+	if err != nil {
+		return value.Value{}, err
+	}
+
+	sorted := value.NewArray()
+	for _, v := range values {
+		sorted.Append(v)
+	}
+
+	args[0] = value.NewArrayValue(sorted)
+	return value.NewBool(true), nil
+}
+
+// mergeSort sorts values stably with less, stopping at its first error.
+// It compares in the same order as the C VM, which callbacks with side
+// effects can tell.
+func mergeSort(values, scratch []value.Value, less func(a, b value.Value) (bool, error)) error {
+	if len(values) < 2 {
+		return nil
+	}
+
+	half := len(values) / 2
+	if err := mergeSort(values[:half], scratch, less); err != nil {
+		return err
+	}
+	if err := mergeSort(values[half:], scratch, less); err != nil {
+		return err
+	}
+
+	i, j, k := 0, half, 0
+	for ; i < half && j < len(values); k++ {
+		before, err := less(values[j], values[i])
+		if err != nil {
+			return err
+		}
+		if before {
+			scratch[k] = values[j]
+			j++
+		} else {
+			scratch[k] = values[i]
+			i++
+		}
+	}
+	k += copy(scratch[k:], values[i:half])
+	copy(scratch[k:], values[j:])
+	copy(values, scratch[:len(values)])
+	return nil
+}
+
+// arrayMap calls the callback with each element and returns the results
+// under the same keys. A null callback gives back the array.
+func arrayMap(env Env, args []value.Value) (value.Value, error) {
+	arr, err := arrayArg("array_map", args, 1, "array")
+	if err != nil {
+		return value.Value{}, err
+	}
+	if args[0].Type == value.TypeNull {
+		return args[1], nil
+	}
+
+	value.Retain(args[1])
+	defer value.Release(args[1])
+
+	result := value.NewArray()
+	for i := 0; i < arr.Len(); i++ {
+		k, v := arr.At(i)
+		mapped, err := env.Call(args[0], []value.Value{v})
+		if err != nil {
+			return value.Value{}, err
+		}
+		result.Set(k, mapped)
+	}
+	return value.NewArrayValue(result), nil
+}
+
+func isType(t value.Type) Func {
+	return func(env Env, args []value.Value) (value.Value, error) {
+		return value.NewBool(args[0].Type == t), nil
+	}
+}
+
+func isNumeric(env Env, args []value.Value) (value.Value, error) {
+	switch args[0].Type {
+	case value.TypeInt, value.TypeFloat:
+		return value.NewBool(true), nil
+	case value.TypeString:
+		_, ok := value.ParseNumeric(args[0].Str)
+		return value.NewBool(ok), nil
+	default:
+		return value.NewBool(false), nil
+	}
+}
@@ -0,0 +1,64 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package native
+
+import (
+	"strings"
+
+	"github.com/neokofg/php-compiler/internal/vm/value"
+)
+
+// MethodFunc implements a method of a built-in class. this is nil for a
+// static method. Like for functions, args hold the arguments in source order.
+type MethodFunc func(env Env, this *value.Object, args []value.Value) (value.Value, error)
+
+// Method is a method of a built-in class. Methods of interfaces have no Impl.
+type Method struct {
+	Name    string
+	Params  []string
+	MinArgs int
+	Final   bool
+	Impl    MethodFunc
+}
+
+// Property is a property of a built-in class with its starting value.
+type Property struct {
+	Name       string
+	Visibility value.Visibility
+	Default    value.Value
+}
+
+// Class is a class or interface the runtime provides, such as Exception.
+// The compiler knows it like a class declared at the top of every script,
+// and the VM creates it before the script starts. Parent and Interfaces
+// name classes listed before it.
+type Class struct {
+	Name       string
+	Parent     string
+	Interfaces []string
+	Interface  bool
+	Final      bool
+	Properties []Property
+	Methods    []Method
+}
+
+var classes []*Class
+
+// RegisterClass adds a built-in class. It must be called before compiling,
+// usually from an init function.
+func RegisterClass(c Class) {
+	classes = append(classes, &c)
+}
+
+// Classes returns the built-in classes, each after its parent and interfaces.
+func Classes() []*Class {
+	return classes
+}
+
+func LookupClass(name string) (*Class, bool) {
+	for _, c := range classes {
+		if strings.EqualFold(c.Name, name) {
+			return c, true
+		}
+	}
+	return nil, false
+}
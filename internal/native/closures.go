@@ -0,0 +1,10 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package native
+
+// Closure is the class of the values closures and arrow functions
+// evaluate to. Only the CLOSURE instruction creates its objects.
+const Closure = "Closure"
+
+func init() {
+	RegisterClass(Class{Name: Closure, Final: true})
+}
@@ -0,0 +1,187 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package native
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/neokofg/php-compiler/internal/vm/value"
+)
+
+// Throwable is the interface of everything that can be thrown. Scripts
+// cannot implement it directly, only extend Exception or Error.
+const Throwable = "Throwable"
+
+// The VM fills these properties in when a Throwable is created: the file
+// and line of the new expression and the call stack as an array of frames
+// with the keys file, line, function and, for methods, class and type.
+const (
+	PropFile  = "file"
+	PropLine  = "line"
+	PropTrace = "trace"
+)
+
+func init() {
+	getters := []Method{
+		{Name: "getMessage", Impl: propGetter("message")},
+		{Name: "getCode", Impl: propGetter("code")},
+		{Name: "getPrevious", Impl: propGetter("previous")},
+		{Name: "getFile", Impl: propGetter(PropFile)},
+		{Name: "getLine", Impl: propGetter(PropLine)},
+		{Name: "getTrace", Impl: propGetter(PropTrace)},
+		{Name: "getTraceAsString", Impl: getTraceAsString},
+	}
+
+	var abstract []Method
+	for _, m := range getters {
+		abstract = append(abstract, Method{Name: m.Name})
+	}
+	RegisterClass(Class{Name: Throwable, Interface: true, Methods: abstract})
+
+	for _, base := range []string{"Exception", "Error"} {
+		methods := []Method{{
+			Name:   "__construct",
+			Params: []string{"message", "code", "previous"},
+			Impl:   throwableConstruct(base),
+		}}
+		for _, m := range getters {
+			m.Final = true
+			methods = append(methods, m)
+		}
+
+		RegisterClass(Class{
+			Name:       base,
+			Interfaces: []string{Throwable},
+			Properties: []Property{
+				{Name: "message", Visibility: value.Protected, Default: value.NewString("")},
+				{Name: "code", Visibility: value.Protected, Default: value.NewInt(0)},
+				{Name: PropFile, Visibility: value.Protected, Default: value.NewString("")},
+				{Name: PropLine, Visibility: value.Protected, Default: value.NewInt(0)},
+				{Name: PropTrace, Visibility: value.Private, Default: value.NewArrayValue(value.NewArray())},
+				{Name: "previous", Visibility: value.Private, Default: value.NewNull()},
+			},
+			Methods: methods,
+		})
+	}
+
+	for _, c := range [][2]string{
+		{"LogicException", "Exception"},
+		{"BadFunctionCallException", "LogicException"},
+		{"BadMethodCallException", "BadFunctionCallException"},
+		{"DomainException", "LogicException"},
+		{"InvalidArgumentException", "LogicException"},
+		{"LengthException", "LogicException"},
+		{"OutOfRangeException", "LogicException"},
+		{"RuntimeException", "Exception"},
+		{"OutOfBoundsException", "RuntimeException"},
+		{"OverflowException", "RuntimeException"},
+		{"RangeException", "RuntimeException"},
+		{"UnderflowException", "RuntimeException"},
+		{"UnexpectedValueException", "RuntimeException"},
+		{"TypeError", "Error"},
+		{"ArgumentCountError", "TypeError"},
+		{"ValueError", "Error"},
+		{"ArithmeticError", "Error"},
+		{"DivisionByZeroError", "ArithmeticError"},
+	} {
+		RegisterClass(Class{Name: c[0], Parent: c[1]})
+	}
+}
+
+func propGetter(name string) MethodFunc {
+	return func(env Env, this *value.Object, args []value.Value) (value.Value, error) {
+		if v, ok := this.Props.Get(value.StringKey(name)); ok {
+			return v, nil
+		}
+		return value.NewNull(), nil
+	}
+}
+
+// throwableConstruct implements Exception::__construct and
+// Error::__construct($message = "", $code = 0, $previous = null).
+func throwableConstruct(class string) MethodFunc {
+	fn := class + "::__construct"
+	return func(env Env, this *value.Object, args []value.Value) (value.Value, error) {
+		if len(args) > 0 {
+			message, err := stringArg(fn, args, 0, "message")
+			if err != nil {
+				return value.Value{}, err
+			}
+			this.Props.Set(value.StringKey("message"), value.NewString(message))
+		}
+		if len(args) > 1 {
+			code, err := intArg(fn, args, 1, "code")
+			if err != nil {
+				return value.Value{}, err
+			}
+			this.Props.Set(value.StringKey("code"), value.NewInt(code))
+		}
+		if len(args) > 2 {
+			previous := args[2]
+			if previous.Type != value.TypeNull && !IsThrowable(previous) {
+				return value.Value{}, typeError(fn, 2, "previous", "?Throwable", previous)
+			}
+			this.Props.Set(value.StringKey("previous"), previous)
+		}
+		return value.NewNull(), nil
+	}
+}
+
+func getTraceAsString(env Env, this *value.Object, args []value.Value) (value.Value, error) {
+	return value.NewString(TraceAsString(this)), nil
+}
+
+// IsThrowable reports whether v is an object implementing Throwable.
+func IsThrowable(v value.Value) bool {
+	if v.Type != value.TypeObject {
+		return false
+	}
+	for _, iface := range v.Obj.Class.Interfaces {
+		if strings.EqualFold(iface.Name, Throwable) {
+			return true
+		}
+	}
+	return false
+}
+
+// TraceAsString formats the trace of a Throwable like PHP does, one
+// numbered frame per line, ending with {main}.
+func TraceAsString(obj *value.Object) string {
+	var b strings.Builder
+	n := 0
+	if trace, ok := obj.Props.Get(value.StringKey(PropTrace)); ok && trace.Type == value.TypeArray {
+		for ; n < trace.Arr.Len(); n++ {
+			_, frame := trace.Arr.At(n)
+			if frame.Type != value.TypeArray {
+				continue
+			}
+			field := func(name string) string {
+				if v, ok := frame.Arr.Get(value.StringKey(name)); ok {
+					return v.ToString()
+				}
+				return ""
+			}
+			fmt.Fprintf(&b, "#%d %s(%s): %s%s%s()\n", n, field(PropFile), field(PropLine),
+				field("class"), field("type"), field("function"))
+		}
+	}
+	fmt.Fprintf(&b, "#%d {main}", n)
+	return b.String()
+}
+
+// Describe formats a Throwable the way PHP reports an uncaught one:
+// class, message, origin and trace.
+func Describe(obj *value.Object) string {
+	prop := func(name string) string {
+		if v, ok := obj.Props.Get(value.StringKey(name)); ok {
+			return v.ToString()
+		}
+		return ""
+	}
+
+	header := obj.Class.Name
+	if message := prop("message"); message != "" {
+		header += ": " + message
+	}
+	return fmt.Sprintf("%s in %s:%s\nStack trace:\n%s", header, prop(PropFile), prop(PropLine), TraceAsString(obj))
+}
@@ -0,0 +1,176 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package native
+
+import (
+	"math"
+
+	"github.com/neokofg/php-compiler/internal/vm/value"
+)
+
+func init() {
+	for _, f := range []Function{
+		{Name: "abs", MinArgs: 1, MaxArgs: 1, Impl: abs},
+		{Name: "intdiv", MinArgs: 2, MaxArgs: 2, Impl: intdiv},
+		{Name: "max", MinArgs: 1, MaxArgs: Variadic, Impl: maxValue},
+		{Name: "min", MinArgs: 1, MaxArgs: Variadic, Impl: minValue},
+		{Name: "floor", MinArgs: 1, MaxArgs: 1, Impl: floor},
+		{Name: "ceil", MinArgs: 1, MaxArgs: 1, Impl: ceil},
+		{Name: "round", MinArgs: 1, MaxArgs: 2, Impl: round},
+		{Name: "sqrt", MinArgs: 1, MaxArgs: 1, Impl: sqrt},
+		{Name: "pow", MinArgs: 2, MaxArgs: 2, Impl: pow},
+		{Name: "intval", MinArgs: 1, MaxArgs: 1, Impl: intval},
+		{Name: "floatval", MinArgs: 1, MaxArgs: 1, Impl: floatval},
+	} {
+		MustRegister(f)
+	}
+}
+
+func abs(env Env, args []value.Value) (value.Value, error) {
+	n, err := numberArg("abs", args, 0, "num", "int|float")
+	if err != nil {
+		return value.Value{}, err
+	}
+
+	if n.Type == value.TypeFloat {
+		return value.NewFloat(math.Abs(n.Float)), nil
+	}
+	if n.Int == math.MinInt64 {
+		return value.NewFloat(-float64(n.Int)), nil
+	}
+	if n.Int < 0 {
+		return value.NewInt(-n.Int), nil
+	}
+	return n, nil
+}
+
+func intdiv(env Env, args []value.Value) (value.Value, error) {
+	a, err := intArg("intdiv", args, 0, "num1")
+	if err != nil {
+		return value.Value{}, err
+	}
+	b, err := intArg("intdiv", args, 1, "num2")
+	if err != nil {
+		return value.Value{}, err
+	}
+
+	if b == 0 {
+		return value.Value{}, NewError("DivisionByZeroError", "Division by zero")
+	}
+	if a == math.MinInt64 && b == -1 {
+		return value.Value{}, NewError("ArithmeticError", "Division of PHP_INT_MIN by -1 is not an integer")
+	}
+	return value.NewInt(a / b), nil
+}
+
+// extreme returns the value of args, or of the elements of a single array
+// argument, that wins against all others: the first one for which no later
+// value compares as better.
+func extreme(fn string, args []value.Value, better int) (value.Value, error) {
+	values := args
+	if len(args) == 1 {
+		if args[0].Type != value.TypeArray {
+			return value.Value{}, typeError(fn, 0, "value", "array", args[0])
+		}
+		arr := args[0].Arr
+		if arr.Len() == 0 {
+			return value.Value{}, valueError(fn, 0, "value", "must contain at least one element")
+		}
+		values = make([]value.Value, arr.Len())
+		for i := range values {
+			_, values[i] = arr.At(i)
+		}
+	}
+
+	result := values[0]
+	for _, v := range values[1:] {
+		if value.Compare(v, result) == better {
+			result = v
+		}
+	}
+	return result, nil
+}
+
+func maxValue(env Env, args []value.Value) (value.Value, error) {
+	return extreme("max", args, 1)
+}
+
+func minValue(env Env, args []value.Value) (value.Value, error) {
+	return extreme("min", args, -1)
+}
+
+// roundWith applies f to a number argument. The result is always a float.
+func roundWith(fn string, args []value.Value, f func(float64) float64) (value.Value, error) {
+	n, err := floatArg(fn, args, 0, "num")
+	if err != nil {
+		return value.Value{}, err
+	}
+	return value.NewFloat(f(n)), nil
+}
+
+func floor(env Env, args []value.Value) (value.Value, error) {
+	return roundWith("floor", args, math.Floor)
+}
+
+func ceil(env Env, args []value.Value) (value.Value, error) {
+	return roundWith("ceil", args, math.Ceil)
+}
+
+// round rounds half away from zero to precision decimal digits, which may
+// be negative to round to tens, hundreds and so on.
+func round(env Env, args []value.Value) (value.Value, error) {
+	var precision int64
+	if len(args) > 1 {
+		var err error
+		if precision, err = intArg("round", args, 1, "precision"); err != nil {
+			return value.Value{}, err
+		}
+	}
+
+	return roundWith("round", args, func(f float64) float64 {
+		if precision == 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+			return math.Round(f)
+		}
+
+		scale := math.Pow(10, math.Abs(float64(precision)))
+		if precision > 0 {
+			scaled := f * scale
+			if math.IsInf(scaled, 0) {
+				return f
+			}
+			return math.Round(scaled) / scale
+		}
+		return math.Round(f/scale) * scale
+	})
+}
+
+func sqrt(env Env, args []value.Value) (value.Value, error) {
+	return roundWith("sqrt", args, math.Sqrt)
+}
+
+// pow stays in ints for an int base and a non-negative int exponent as long
+// as the result fits, like the ** operator.
+func pow(env Env, args []value.Value) (value.Value, error) {
+	base, err := numberArg("pow", args, 0, "num", "int|float")
+	if err != nil {
+		return value.Value{}, err
+	}
+	exp, err := numberArg("pow", args, 1, "exponent", "int|float")
+	if err != nil {
+		return value.Value{}, err
+	}
+
+	if base.Type == value.TypeInt && exp.Type == value.TypeInt && exp.Int >= 0 {
+		if result, ok := value.PowInt(base.Int, exp.Int); ok {
+			return value.NewInt(result), nil
+		}
+	}
+	return value.NewFloat(math.Pow(base.ToFloat(), exp.ToFloat())), nil
+}
+
+func intval(env Env, args []value.Value) (value.Value, error) {
+	return value.NewInt(args[0].ToInt()), nil
+}
+
+func floatval(env Env, args []value.Value) (value.Value, error) {
+	return value.NewFloat(args[0].ToFloat()), nil
+}
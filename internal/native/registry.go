@@ -0,0 +1,167 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+
+// Package native holds the functions implemented in Go that PHP code can
+// call like any other function, such as strlen or count.
+//
+// The compiler looks a call up in the default registry when no user
+// function of that name exists, checks the argument count and emits
+// CALL_NATIVE; the VM looks the function up again by name at run time.
+// A project registers its own natives before compiling, usually from an
+// init function:
+//
+//	func init() {
+//		native.MustRegister(native.Function{
+//			Name:    "greet",
+//			MinArgs: 1,
+//			MaxArgs: 1,
+//			Impl: func(env native.Env, args []value.Value) (value.Value, error) {
+//				return value.NewString("Hello, " + args[0].ToString()), nil
+//			},
+//		})
+//	}
+//
+// An implementation receives exactly the arguments written at the call
+// site, in source order, and must not keep the slice. Returning an *Error
+// throws an exception of its class, which the script may catch; this is
+// how PHP's TypeError and ValueError surface. Any other error stops the
+// script with its message. env.Warnf reports a warning and lets it
+// continue.
+//
+// Parameters listed in ByRef are passed by reference: the call site must
+// pass a variable, or for the first parameter also an array element or a
+// property, and whatever the implementation leaves in args[i] is stored
+// back into it after the call. Arrays are shared with the caller's
+// variable, so an implementation that changes one must work on
+// args[i].Arr.Separate() and put the result into args[i].
+package native
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/neokofg/php-compiler/internal/vm/value"
+)
+
+// Variadic is the MaxArgs of a function that takes any number of arguments.
+const Variadic = -1
+
+// Env is what the running VM offers to a native function.
+type Env interface {
+	// Output is the script's standard output.
+	Output() io.Writer
+	// Warnf reports a PHP warning and lets the script continue.
+	Warnf(format string, args ...interface{})
+	// Call calls a closure or the function a string names with args and
+	// returns its result. The error of an exception the function does not
+	// catch must be returned as is, for the exception to reach the script.
+	Call(callable value.Value, args []value.Value) (value.Value, error)
+}
+
+type Func func(env Env, args []value.Value) (value.Value, error)
+
+// Error is an exception a native function throws, such as a TypeError.
+// Class names one of the built-in exception classes.
+type Error struct {
+	Class   string
+	Message string
+}
+
+func NewError(class string, format string, args ...interface{}) *Error {
+	return &Error{Class: class, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+type Function struct {
+	Name    string
+	MinArgs int
+	MaxArgs int   // Variadic for no upper limit
+	ByRef   []int // positions of the parameters passed by reference
+	Impl    Func
+}
+
+// Accepts reports whether a call may pass argc arguments.
+func (f *Function) Accepts(argc int) bool {
+	return argc >= f.MinArgs && (f.MaxArgs == Variadic || argc <= f.MaxArgs)
+}
+
+// IsByRef reports whether the i-th parameter is passed by reference.
+func (f *Function) IsByRef(i int) bool {
+	for _, p := range f.ByRef {
+		if p == i {
+			return true
+		}
+	}
+	return false
+}
+
+// Registry maps function names to natives. Names are case-insensitive,
+// as in PHP.
+type Registry struct {
+	functions map[string]*Function
+}
+
+func NewRegistry() *Registry {
+	return &Registry{functions: make(map[string]*Function)}
+}
+
+func (r *Registry) Register(f Function) error {
+	if f.Name == "" || f.Impl == nil {
+		return fmt.Errorf("native function needs a name and an implementation")
+	}
+	if f.MinArgs < 0 || (f.MaxArgs != Variadic && f.MaxArgs < f.MinArgs) {
+		return fmt.Errorf("native function %s has an invalid argument range %d..%d", f.Name, f.MinArgs, f.MaxArgs)
+	}
+	for _, p := range f.ByRef {
+		if p < 0 || (f.MaxArgs != Variadic && p >= f.MaxArgs) {
+			return fmt.Errorf("native function %s has no parameter #%d to pass by reference", f.Name, p+1)
+		}
+	}
+
+	name := strings.ToLower(f.Name)
+	if _, exists := r.functions[name]; exists {
+		return fmt.Errorf("native function '%s' already registered", f.Name)
+	}
+
+	r.functions[name] = &f
+	return nil
+}
+
+func (r *Registry) Lookup(name string) (*Function, bool) {
+	f, ok := r.functions[strings.ToLower(name)]
+	return f, ok
+}
+
+// Names returns the registered names in alphabetical order.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.functions))
+	for name := range r.functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Default is the registry used by the compiler and the VM. It starts with
+// the standard functions of this package.
+var Default = NewRegistry()
+
+func Register(f Function) error {
+	return Default.Register(f)
+}
+
+// MustRegister is like Register but panics on error, for use in init
+// functions.
+func MustRegister(f Function) {
+	if err := Default.Register(f); err != nil {
+		panic(err)
+	}
+}
+
+func Lookup(name string) (*Function, bool) {
+	return Default.Lookup(name)
+}
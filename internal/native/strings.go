@@ -0,0 +1,400 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package native
+
+import (
+	"math"
+	"strings"
+
+	"github.com/neokofg/php-compiler/internal/vm/value"
+)
+
+func init() {
+	for _, f := range []Function{
+		{Name: "strlen", MinArgs: 1, MaxArgs: 1, Impl: strlen},
+		{Name: "strtoupper", MinArgs: 1, MaxArgs: 1, Impl: strtoupper},
+		{Name: "strtolower", MinArgs: 1, MaxArgs: 1, Impl: strtolower},
+		{Name: "ucfirst", MinArgs: 1, MaxArgs: 1, Impl: ucfirst},
+		{Name: "lcfirst", MinArgs: 1, MaxArgs: 1, Impl: lcfirst},
+		{Name: "strrev", MinArgs: 1, MaxArgs: 1, Impl: strrev},
+		{Name: "str_repeat", MinArgs: 2, MaxArgs: 2, Impl: strRepeat},
+		{Name: "substr", MinArgs: 2, MaxArgs: 3, Impl: substr},
+		{Name: "strpos", MinArgs: 2, MaxArgs: 3, Impl: strpos},
+		{Name: "str_contains", MinArgs: 2, MaxArgs: 2, Impl: strContains},
+		{Name: "str_starts_with", MinArgs: 2, MaxArgs: 2, Impl: strStartsWith},
+		{Name: "str_ends_with", MinArgs: 2, MaxArgs: 2, Impl: strEndsWith},
+		{Name: "str_replace", MinArgs: 3, MaxArgs: 3, Impl: strReplace},
+		{Name: "trim", MinArgs: 1, MaxArgs: 2, Impl: trim},
+		{Name: "ltrim", MinArgs: 1, MaxArgs: 2, Impl: ltrim},
+		{Name: "rtrim", MinArgs: 1, MaxArgs: 2, Impl: rtrim},
+		{Name: "implode", MinArgs: 1, MaxArgs: 2, Impl: implode},
+		{Name: "explode", MinArgs: 2, MaxArgs: 3, Impl: explode},
+	} {
+		MustRegister(f)
+	}
+}
+
+// The string functions work on bytes and change the case of ASCII letters
+// only, like PHP 8.2 and later.
+
+func strlen(env Env, args []value.Value) (value.Value, error) {
+	s, err := stringArg("strlen", args, 0, "string")
+	if err != nil {
+		return value.Value{}, err
+	}
+	return value.NewInt(int64(len(s))), nil
+}
+
+func mapBytes(fn string, args []value.Value, f func(c byte) byte, all bool) (value.Value, error) {
+	s, err := stringArg(fn, args, 0, "string")
+	if err != nil || s == "" {
+		return value.NewString(s), err
+	}
+
+	b := []byte(s)
+	if !all {
+		b[0] = f(b[0])
+		return value.NewString(string(b)), nil
+	}
+	for i := range b {
+		b[i] = f(b[i])
+	}
+	return value.NewString(string(b)), nil
+}
+
+func toUpper(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return c - 'a' + 'A'
+	}
+	return c
+}
+
+func toLower(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c - 'A' + 'a'
+	}
+	return c
+}
+
+func strtoupper(env Env, args []value.Value) (value.Value, error) {
+	return mapBytes("strtoupper", args, toUpper, true)
+}
+
+func strtolower(env Env, args []value.Value) (value.Value, error) {
+	return mapBytes("strtolower", args, toLower, true)
+}
+
+func ucfirst(env Env, args []value.Value) (value.Value, error) {
+	return mapBytes("ucfirst", args, toUpper, false)
+}
+
+func lcfirst(env Env, args []value.Value) (value.Value, error) {
+	return mapBytes("lcfirst", args, toLower, false)
+}
+
+func strrev(env Env, args []value.Value) (value.Value, error) {
+	s, err := stringArg("strrev", args, 0, "string")
+	if err != nil {
+		return value.Value{}, err
+	}
+
+	b := make([]byte, len(s))
+	for i := range b {
+		b[i] = s[len(s)-1-i]
+	}
+	return value.NewString(string(b)), nil
+}
+
+func strRepeat(env Env, args []value.Value) (value.Value, error) {
+	s, err := stringArg("str_repeat", args, 0, "string")
+	if err != nil {
+		return value.Value{}, err
+	}
+	times, err := intArg("str_repeat", args, 1, "times")
+	if err != nil {
+		return value.Value{}, err
+	}
+	if times < 0 {
+		return value.Value{}, valueError("str_repeat", 1, "times", "must be greater than or equal to 0")
+	}
+	if s != "" && times > math.MaxInt32/int64(len(s)) {
+		return value.Value{}, valueError("str_repeat", 1, "times", "is too large")
+	}
+	return value.NewString(strings.Repeat(s, int(times))), nil
+}
+
+// substr follows PHP 8: offsets past the end give "" instead of false.
+func substr(env Env, args []value.Value) (value.Value, error) {
+	s, err := stringArg("substr", args, 0, "string")
+	if err != nil {
+		return value.Value{}, err
+	}
+	offset, err := intArg("substr", args, 1, "offset")
+	if err != nil {
+		return value.Value{}, err
+	}
+
+	n := int64(len(s))
+	if offset > n {
+		return value.NewString(""), nil
+	}
+	if offset < 0 {
+		offset = max(n+offset, 0)
+	}
+
+	end := n
+	if len(args) > 2 && args[2].Type != value.TypeNull {
+		length, err := intArg("substr", args, 2, "length")
+		if err != nil {
+			return value.Value{}, err
+		}
+		if length < 0 {
+			end = n + length
+		} else if length < n-offset {
+			end = offset + length
+		}
+	}
+
+	if end <= offset {
+		return value.NewString(""), nil
+	}
+	return value.NewString(s[offset:end]), nil
+}
+
+// strpos returns the position of the first needle at or after offset, or
+// false.
+func strpos(env Env, args []value.Value) (value.Value, error) {
+	haystack, err := stringArg("strpos", args, 0, "haystack")
+	if err != nil {
+		return value.Value{}, err
+	}
+	needle, err := stringArg("strpos", args, 1, "needle")
+	if err != nil {
+		return value.Value{}, err
+	}
+
+	var offset int64
+	if len(args) > 2 {
+		if offset, err = intArg("strpos", args, 2, "offset"); err != nil {
+			return value.Value{}, err
+		}
+	}
+
+	n := int64(len(haystack))
+	if offset < 0 {
+		offset += n
+	}
+	if offset < 0 || offset > n {
+		return value.Value{}, valueError("strpos", 2, "offset", "must be contained in argument #1 ($haystack)")
+	}
+
+	i := strings.Index(haystack[offset:], needle)
+	if i < 0 {
+		return value.NewBool(false), nil
+	}
+	return value.NewInt(offset + int64(i)), nil
+}
+
+// stringPair returns the two string arguments of the str_* predicates.
+func stringPair(fn string, args []value.Value) (string, string, error) {
+	haystack, err := stringArg(fn, args, 0, "haystack")
+	if err != nil {
+		return "", "", err
+	}
+	needle, err := stringArg(fn, args, 1, "needle")
+	return haystack, needle, err
+}
+
+func strContains(env Env, args []value.Value) (value.Value, error) {
+	haystack, needle, err := stringPair("str_contains", args)
+	return value.NewBool(strings.Contains(haystack, needle)), err
+}
+
+func strStartsWith(env Env, args []value.Value) (value.Value, error) {
+	haystack, needle, err := stringPair("str_starts_with", args)
+	return value.NewBool(strings.HasPrefix(haystack, needle)), err
+}
+
+func strEndsWith(env Env, args []value.Value) (value.Value, error) {
+	haystack, needle, err := stringPair("str_ends_with", args)
+	return value.NewBool(strings.HasSuffix(haystack, needle)), err
+}
+
+// strReplace replaces every search string in subject, or in each element
+// of a subject array. With an array of search strings, a replace array
+// gives the replacement for each of them ("" when it runs out) and a
+// replace string is used for all.
+func strReplace(env Env, args []value.Value) (value.Value, error) {
+	var search, replace []string
+
+	if args[0].Type == value.TypeArray {
+		for i := 0; i < args[0].Arr.Len(); i++ {
+			_, v := args[0].Arr.At(i)
+			search = append(search, toString(env, v))
+		}
+
+		if args[1].Type == value.TypeArray {
+			for i := 0; i < args[1].Arr.Len(); i++ {
+				_, v := args[1].Arr.At(i)
+				replace = append(replace, toString(env, v))
+			}
+		} else {
+			for range search {
+				replace = append(replace, args[1].ToString())
+			}
+		}
+	} else {
+		if args[1].Type == value.TypeArray {
+			return value.Value{}, typeError("str_replace", 1, "replace", "string", args[1])
+		}
+		search = []string{args[0].ToString()}
+		replace = []string{args[1].ToString()}
+	}
+
+	apply := func(s string) string {
+		for i, from := range search {
+			if from == "" {
+				continue
+			}
+			to := ""
+			if i < len(replace) {
+				to = replace[i]
+			}
+			s = strings.ReplaceAll(s, from, to)
+		}
+		return s
+	}
+
+	if args[2].Type != value.TypeArray {
+		return value.NewString(apply(args[2].ToString())), nil
+	}
+
+	subject := args[2].Arr
+	result := value.NewArray()
+	for i := 0; i < subject.Len(); i++ {
+		k, v := subject.At(i)
+		if v.Type != value.TypeArray {
+			v = value.NewString(apply(v.ToString()))
+		}
+		result.Set(k, v)
+	}
+	return value.NewArrayValue(result), nil
+}
+
+// defaultTrimChars are the characters trim removes when no list is given.
+const defaultTrimChars = " \n\r\t\v\x00"
+
+func trimWith(fn string, args []value.Value, left, right bool) (value.Value, error) {
+	s, err := stringArg(fn, args, 0, "string")
+	if err != nil {
+		return value.Value{}, err
+	}
+
+	chars := defaultTrimChars
+	if len(args) > 1 {
+		if chars, err = stringArg(fn, args, 1, "characters"); err != nil {
+			return value.Value{}, err
+		}
+	}
+
+	if left {
+		s = strings.TrimLeft(s, chars)
+	}
+	if right {
+		s = strings.TrimRight(s, chars)
+	}
+	return value.NewString(s), nil
+}
+
+func trim(env Env, args []value.Value) (value.Value, error) {
+	return trimWith("trim", args, true, true)
+}
+
+func ltrim(env Env, args []value.Value) (value.Value, error) {
+	return trimWith("ltrim", args, true, false)
+}
+
+func rtrim(env Env, args []value.Value) (value.Value, error) {
+	return trimWith("rtrim", args, false, true)
+}
+
+// implode accepts implode($separator, $array) and implode($array).
+func implode(env Env, args []value.Value) (value.Value, error) {
+	separator := ""
+	pieces := args[0]
+
+	if len(args) == 1 {
+		if pieces.Type != value.TypeArray {
+			return value.Value{}, typeError("implode", 0, "pieces", "array", pieces)
+		}
+	} else {
+		var err error
+		if separator, err = stringArg("implode", args, 0, "separator"); err != nil {
+			return value.Value{}, err
+		}
+		pieces = args[1]
+		if pieces.Type != value.TypeArray {
+			return value.Value{}, typeError("implode", 1, "array", "?array", pieces)
+		}
+	}
+
+	parts := make([]string, pieces.Arr.Len())
+	for i := range parts {
+		_, v := pieces.Arr.At(i)
+		parts[i] = toString(env, v)
+	}
+	return value.NewString(strings.Join(parts, separator)), nil
+}
+
+// explode splits string on separator. A positive limit caps the number of
+// parts, the last one holding the rest; a negative one drops that many
+// parts from the end.
+func explode(env Env, args []value.Value) (value.Value, error) {
+	separator, err := stringArg("explode", args, 0, "separator")
+	if err != nil {
+		return value.Value{}, err
+	}
+	s, err := stringArg("explode", args, 1, "string")
+	if err != nil {
+		return value.Value{}, err
+	}
+	if separator == "" {
+		return value.Value{}, valueError("explode", 0, "separator", "cannot be empty")
+	}
+
+	limit := int64(math.MaxInt64)
+	if len(args) > 2 {
+		if limit, err = intArg("explode", args, 2, "limit"); err != nil {
+			return value.Value{}, err
+		}
+	}
+
+	var parts []string
+	switch {
+	case limit > 0:
+		parts = strings.SplitN(s, separator, int(min(limit, math.MaxInt32)))
+	case limit == 0:
+		parts = []string{s}
+	default:
+		parts = strings.Split(s, separator)
+		if int64(len(parts)) <= -limit {
+			parts = nil
+		} else {
+			parts = parts[:int64(len(parts))+limit]
+		}
+	}
+
+	result := value.NewArray()
+	for _, part := range parts {
+		result.Append(value.NewString(part))
+	}
+	return value.NewArrayValue(result), nil
+}
+
+// toString converts an element to a string, warning about nested arrays
+// like PHP's string conversion does.
+func toString(env Env, v value.Value) string {
+	if v.Type == value.TypeArray {
+		env.Warnf("Array to string conversion")
+	}
+	return v.ToString()
+}
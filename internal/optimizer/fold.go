@@ -0,0 +1,255 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package optimizer
+
+import (
+	"math"
+
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/token"
+	"github.com/neokofg/php-compiler/internal/vm/value"
+)
+
+// constValue returns the value e evaluates to at run time when e is a
+// literal.
+func constValue(e ast.Expr) (value.Value, bool) {
+	switch e := e.(type) {
+	case *ast.NumberLiteral:
+		return value.NewInt(e.Value), true
+	case *ast.FloatLiteral:
+		return value.NewFloat(e.Value), true
+	case *ast.StringLiteral:
+		return value.NewString(e.Value), true
+	case *ast.BooleanLiteral:
+		return value.NewBool(e.Value), true
+	case *ast.NullLiteral:
+		return value.NewNull(), true
+	}
+	return value.Value{}, false
+}
+
+// literal returns the literal that evaluates to v, in place of node.
+func literal(node ast.Node, v value.Value) ast.Expr {
+	span := ast.NewSpan(node.Pos(), node.End())
+	switch v.Type {
+	case value.TypeInt:
+		return &ast.NumberLiteral{Span: span, Value: v.Int}
+	case value.TypeFloat:
+		return &ast.FloatLiteral{Span: span, Value: v.Float}
+	case value.TypeString:
+		return &ast.StringLiteral{Span: span, Value: v.Str}
+	case value.TypeBool:
+		return boolean(node, v.Bool)
+	default:
+		return &ast.NullLiteral{Span: span}
+	}
+}
+
+// boolean returns the true or false literal in place of node.
+func boolean(node ast.Node, b bool) ast.Expr {
+	return &ast.BooleanLiteral{Span: ast.NewSpan(node.Pos(), node.End()), Value: b}
+}
+
+func foldUnary(e *ast.UnaryExpr) ast.Expr {
+	if e.Op == token.T_NOT {
+		e.Expr = cond(e.Expr)
+		if v, ok := constValue(e.Expr); ok {
+			return boolean(e, !v.ToBool())
+		}
+		return e
+	}
+
+	e.Expr = expr(e.Expr)
+	v, ok := constValue(e.Expr)
+	if !ok || !isNumber(v) {
+		return e
+	}
+
+	switch e.Op {
+	case token.T_MINUS:
+		return literal(e, mul(v, value.NewInt(-1)))
+	case token.T_PLUS:
+		return literal(e, v)
+	case token.T_BIT_NOT:
+		if v.Type == value.TypeInt {
+			return literal(e, value.NewInt(^v.Int))
+		}
+	}
+	return e
+}
+
+func foldBinary(e *ast.BinaryExpr, truthy bool) ast.Expr {
+	switch e.Op {
+	case token.T_AND, token.T_OR, token.T_LOGICAL_AND, token.T_LOGICAL_OR:
+		return foldLogical(e, truthy)
+	case token.T_LOGICAL_XOR:
+		e.Left = cond(e.Left)
+		e.Right = cond(e.Right)
+	case token.T_COALESCE:
+		// The left operand is read like in isset, so only a literal there
+		// decides the result.
+		e.Left = expr(e.Left)
+		e.Right = fold(e.Right, truthy)
+		if v, ok := constValue(e.Left); ok {
+			if v.Type == value.TypeNull {
+				return e.Right
+			}
+			return e.Left
+		}
+		return e
+	default:
+		e.Left = expr(e.Left)
+		e.Right = expr(e.Right)
+	}
+
+	if e.Op == token.T_DOT {
+		return foldConcat(e)
+	}
+
+	a, ok := constValue(e.Left)
+	if !ok {
+		return e
+	}
+	b, ok := constValue(e.Right)
+	if !ok {
+		return e
+	}
+
+	switch e.Op {
+	case token.T_EQEQ:
+		return boolean(e, value.LooseEquals(a, b))
+	case token.T_NOTEQ:
+		return boolean(e, !value.LooseEquals(a, b))
+	case token.T_EQEQEQ:
+		return boolean(e, value.Identical(a, b))
+	case token.T_NOTEQEQ:
+		return boolean(e, !value.Identical(a, b))
+	case token.T_LT:
+		return boolean(e, value.Compare(a, b) < 0)
+	case token.T_LTE:
+		return boolean(e, value.Compare(a, b) <= 0)
+	case token.T_GT:
+		return boolean(e, value.Compare(a, b) > 0)
+	case token.T_GTE:
+		return boolean(e, value.Compare(a, b) >= 0)
+	case token.T_SPACESHIP:
+		return literal(e, value.NewInt(int64(value.Compare(a, b))))
+	case token.T_LOGICAL_XOR:
+		return boolean(e, a.ToBool() != b.ToBool())
+	}
+
+	if result, ok := arithmetic(e.Op, a, b); ok {
+		return literal(e, result)
+	}
+	return e
+}
+
+// foldConcat folds the concatenation of two literals. Since . is left
+// associative, the literals of $x . "a" . "b" are not operands of the
+// same BinaryExpr, and are joined into $x . "ab" instead.
+func foldConcat(e *ast.BinaryExpr) ast.Expr {
+	b, ok := constValue(e.Right)
+	if !ok {
+		return e
+	}
+
+	if a, ok := constValue(e.Left); ok {
+		return literal(e, value.NewString(a.ToString()+b.ToString()))
+	}
+
+	if left, ok := e.Left.(*ast.BinaryExpr); ok && left.Op == token.T_DOT {
+		if a, ok := constValue(left.Right); ok {
+			joined := value.NewString(a.ToString() + b.ToString())
+			e.Left = left.Left
+			e.Right = literal(ast.NewSpan(left.Right.Pos(), e.Right.End()), joined)
+		}
+	}
+	return e
+}
+
+func isNumber(v value.Value) bool {
+	return v.Type == value.TypeInt || v.Type == value.TypeFloat
+}
+
+// arithmetic computes the arithmetic and bitwise operators on numbers
+// like the VM does. It reports false when it cannot fold: for other
+// operands, and when the operation throws at run time.
+func arithmetic(op token.TokenType, a, b value.Value) (value.Value, bool) {
+	if !isNumber(a) || !isNumber(b) {
+		return value.Value{}, false
+	}
+	ints := a.Type == value.TypeInt && b.Type == value.TypeInt
+
+	switch op {
+	case token.T_PLUS:
+		if ints {
+			if result, ok := value.AddInt(a.Int, b.Int); ok {
+				return value.NewInt(result), true
+			}
+		}
+		return value.NewFloat(a.ToFloat() + b.ToFloat()), true
+	case token.T_MINUS:
+		if ints {
+			if result, ok := value.SubInt(a.Int, b.Int); ok {
+				return value.NewInt(result), true
+			}
+		}
+		return value.NewFloat(a.ToFloat() - b.ToFloat()), true
+	case token.T_STAR:
+		return mul(a, b), true
+	case token.T_SLASH:
+		if b.ToFloat() == 0 {
+			return value.Value{}, false
+		}
+		if ints && a.Int%b.Int == 0 && !(a.Int == math.MinInt64 && b.Int == -1) {
+			return value.NewInt(a.Int / b.Int), true
+		}
+		return value.NewFloat(a.ToFloat() / b.ToFloat()), true
+	case token.T_MOD:
+		if !ints || b.Int == 0 {
+			return value.Value{}, false
+		}
+		if b.Int == -1 {
+			return value.NewInt(0), true
+		}
+		return value.NewInt(a.Int % b.Int), true
+	case token.T_POW:
+		if ints && b.Int >= 0 {
+			if result, ok := value.PowInt(a.Int, b.Int); ok {
+				return value.NewInt(result), true
+			}
+		}
+		return value.NewFloat(math.Pow(a.ToFloat(), b.ToFloat())), true
+	}
+
+	if !ints {
+		return value.Value{}, false
+	}
+	switch op {
+	case token.T_BIT_AND:
+		return value.NewInt(a.Int & b.Int), true
+	case token.T_BIT_OR:
+		return value.NewInt(a.Int | b.Int), true
+	case token.T_BIT_XOR:
+		return value.NewInt(a.Int ^ b.Int), true
+	case token.T_LSHIFT, token.T_RSHIFT:
+		// Negative shifts are errors, and the VMs disagree on shifts by
+		// the width of an int or more.
+		if b.Int < 0 || b.Int >= 64 {
+			return value.Value{}, false
+		}
+		if op == token.T_LSHIFT {
+			return value.NewInt(a.Int << uint64(b.Int)), true
+		}
+		return value.NewInt(a.Int >> uint64(b.Int)), true
+	}
+	return value.Value{}, false
+}
+
+func mul(a, b value.Value) value.Value {
+	if a.Type == value.TypeInt && b.Type == value.TypeInt {
+		if result, ok := value.MulInt(a.Int, b.Int); ok {
+			return value.NewInt(result)
+		}
+	}
+	return value.NewFloat(a.ToFloat() * b.ToFloat())
+}
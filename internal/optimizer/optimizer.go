@@ -0,0 +1,249 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+
+// Package optimizer rewrites the AST between the parser and the compiler.
+// It folds the operators whose operands are all literals into the literal
+// they evaluate to, with the value semantics of the VM, and drops the
+// branches of if and while statements whose condition is a constant.
+// Operations that fail at run time, such as a division by zero, are left
+// for the VM to report.
+package optimizer
+
+import (
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/token"
+)
+
+// Optimize rewrites stmts in place and returns the statements to compile.
+func Optimize(stmts []ast.Stmt) []ast.Stmt {
+	return block(stmts)
+}
+
+func block(stmts []ast.Stmt) []ast.Stmt {
+	if stmts == nil {
+		return nil
+	}
+
+	result := make([]ast.Stmt, 0, len(stmts))
+	for _, s := range stmts {
+		result = append(result, stmt(s)...)
+	}
+	return result
+}
+
+// stmt returns the statements s is replaced with: s itself, the branch
+// of an if that is always taken, or nothing for code that never runs.
+func stmt(s ast.Stmt) []ast.Stmt {
+	switch s := s.(type) {
+	case *ast.EchoStmt:
+		s.Expr = expr(s.Expr)
+	case *ast.IfStmt:
+		s.Cond = cond(s.Cond)
+		s.Then = block(s.Then)
+		s.Else = block(s.Else)
+		if v, ok := constValue(s.Cond); ok && !declares(s.Then) && !declares(s.Else) {
+			if v.ToBool() {
+				return s.Then
+			}
+			return s.Else
+		}
+	case *ast.WhileStmt:
+		s.Cond = cond(s.Cond)
+		s.Body = block(s.Body)
+		if v, ok := constValue(s.Cond); ok && !v.ToBool() && !declares(s.Body) {
+			return nil
+		}
+	case *ast.ForStmt:
+		s.Init = expr(s.Init)
+		s.Cond = cond(s.Cond)
+		s.Incr = expr(s.Incr)
+		s.Body = block(s.Body)
+	case *ast.ForeachStmt:
+		s.Expr = expr(s.Expr)
+		s.Body = block(s.Body)
+	case *ast.DoWhileStmt:
+		s.Body = block(s.Body)
+		s.Cond = cond(s.Cond)
+	case *ast.SwitchStmt:
+		s.Expr = expr(s.Expr)
+		for i := range s.Cases {
+			s.Cases[i].Expr = expr(s.Cases[i].Expr)
+			s.Cases[i].Stmts = block(s.Cases[i].Stmts)
+		}
+	case *ast.FunctionDecl:
+		s.Body = block(s.Body)
+	case *ast.ReturnStmt:
+		s.Expr = expr(s.Expr)
+	case *ast.StaticStmt:
+		for i := range s.Vars {
+			s.Vars[i].Init = expr(s.Vars[i].Init)
+		}
+	case *ast.FunctionCallStmt:
+		exprs(s.Call.Args)
+	case *ast.ExprStmt:
+		s.Expr = expr(s.Expr)
+	case *ast.ClassDecl:
+		for i := range s.Properties {
+			s.Properties[i].Default = expr(s.Properties[i].Default)
+		}
+		for _, m := range s.Methods {
+			m.Function.Body = block(m.Function.Body)
+		}
+	case *ast.UnsetStmt:
+		exprs(s.Vars)
+	case *ast.ThrowStmt:
+		s.Expr = expr(s.Expr)
+	case *ast.TryStmt:
+		s.Body = block(s.Body)
+		for i := range s.Catches {
+			s.Catches[i].Body = block(s.Catches[i].Body)
+		}
+		s.Finally = block(s.Finally)
+	}
+	return []ast.Stmt{s}
+}
+
+// declares reports whether stmts declare a function or a class. The
+// compiler knows about every declaration before it compiles any code, so
+// a branch that holds one is kept even when it never runs.
+func declares(stmts []ast.Stmt) bool {
+	for _, s := range stmts {
+		switch s.(type) {
+		case *ast.FunctionDecl, *ast.ClassDecl:
+			return true
+		}
+		for _, block := range ast.Blocks(s) {
+			if declares(block) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func exprs(list []ast.Expr) {
+	for i, e := range list {
+		list[i] = expr(e)
+	}
+}
+
+// expr folds an expression whose value is used.
+func expr(e ast.Expr) ast.Expr {
+	return fold(e, false)
+}
+
+// cond folds an expression of which only the truthiness is used.
+func cond(e ast.Expr) ast.Expr {
+	return fold(e, true)
+}
+
+// fold folds the operands of e and then e itself when they are constant.
+// truthy is set when only the truthiness of e is used.
+func fold(e ast.Expr, truthy bool) ast.Expr {
+	switch e := e.(type) {
+	case nil:
+		return nil
+	case *ast.InterpolatedString:
+		exprs(e.Parts)
+	case *ast.ArrayLiteral:
+		for i := range e.Items {
+			e.Items[i].Key = expr(e.Items[i].Key)
+			e.Items[i].Value = expr(e.Items[i].Value)
+		}
+	case *ast.IndexExpr:
+		e.Base = expr(e.Base)
+		e.Index = expr(e.Index)
+	case *ast.BinaryExpr:
+		return foldBinary(e, truthy)
+	case *ast.UnaryExpr:
+		return foldUnary(e)
+	case *ast.TernaryExpr:
+		return foldTernary(e, truthy)
+	case *ast.IssetExpr:
+		exprs(e.Vars)
+	case *ast.EmptyExpr:
+		e.Expr = expr(e.Expr)
+	case *ast.PostfixExpr:
+		e.Expr = expr(e.Expr)
+	case *ast.PrefixExpr:
+		e.Expr = expr(e.Expr)
+	case *ast.AssignExpr:
+		e.Target = expr(e.Target)
+		e.Expr = expr(e.Expr)
+	case *ast.FunctionCall:
+		exprs(e.Args)
+	case *ast.NewExpr:
+		exprs(e.Args)
+	case *ast.InstanceofExpr:
+		e.Expr = expr(e.Expr)
+	case *ast.PropertyFetch:
+		e.Object = expr(e.Object)
+	case *ast.MethodCall:
+		e.Object = expr(e.Object)
+		exprs(e.Args)
+	case *ast.StaticCall:
+		exprs(e.Args)
+	case *ast.CallExpr:
+		e.Callee = expr(e.Callee)
+		exprs(e.Args)
+	case *ast.ClosureExpr:
+		e.Body = block(e.Body)
+	case *ast.ArrowFunction:
+		e.Expr = expr(e.Expr)
+	}
+	return e
+}
+
+// foldTernary keeps the branch a constant condition selects. The short
+// form c ?: else has the condition as its value, so it is folded as one.
+func foldTernary(e *ast.TernaryExpr, truthy bool) ast.Expr {
+	if e.Then == nil {
+		e.Cond = fold(e.Cond, truthy)
+	} else {
+		e.Cond = cond(e.Cond)
+		e.Then = fold(e.Then, truthy)
+	}
+	e.Else = fold(e.Else, truthy)
+
+	v, ok := constValue(e.Cond)
+	switch {
+	case !ok:
+		return e
+	case v.ToBool() && e.Then == nil:
+		return e.Cond
+	case v.ToBool():
+		return e.Then
+	default:
+		return e.Else
+	}
+}
+
+// foldLogical folds && and ||, and their keyword forms, whose operands
+// are only used for their truthiness. A constant left operand decides
+// the result or gives way to the right one; a constant right operand
+// that cannot change the result gives way to the left one, which still
+// has to run.
+func foldLogical(e *ast.BinaryExpr, truthy bool) ast.Expr {
+	e.Left = cond(e.Left)
+	e.Right = cond(e.Right)
+
+	and := e.Op == token.T_AND || e.Op == token.T_LOGICAL_AND
+	left, leftOK := constValue(e.Left)
+	right, rightOK := constValue(e.Right)
+
+	switch {
+	case leftOK && left.ToBool() != and:
+		// false && x, true || x
+		return boolean(e, !and)
+	case leftOK && rightOK:
+		return boolean(e, right.ToBool())
+	case !truthy:
+		return e
+	case leftOK:
+		// true && x, false || x
+		return e.Right
+	case rightOK && right.ToBool() == and:
+		// x && true, x || false
+		return e.Left
+	}
+	return e
+}
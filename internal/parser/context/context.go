@@ -2,13 +2,15 @@
 package context
 
 import (
-	"fmt"
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/diag"
 	"github.com/neokofg/php-compiler/internal/token"
 )
 
 type ParserContext struct {
 	tokens []token.Token
 	pos    int
+	errors diag.List
 }
 
 func NewParserContext(tokens []token.Token) *ParserContext {
@@ -28,18 +30,26 @@ func (c *ParserContext) SetPos(pos int) {
 
 func (c *ParserContext) Peek() token.Token {
 	if c.pos >= len(c.tokens) {
-		return token.Token{Type: token.T_EOF, Value: ""}
+		return c.eof()
 	}
 	return c.tokens[c.pos]
 }
 
 func (c *ParserContext) PeekNext() token.Token {
-	if c.pos >= len(c.tokens) {
-		return token.Token{Type: token.T_EOF, Value: ""}
+	if c.pos+1 >= len(c.tokens) {
+		return c.eof()
 	}
 	return c.tokens[c.pos+1]
 }
 
+// Prev returns the last consumed token.
+func (c *ParserContext) Prev() token.Token {
+	if c.pos == 0 || c.pos > len(c.tokens) {
+		return c.eof()
+	}
+	return c.tokens[c.pos-1]
+}
+
 func (c *ParserContext) Next() token.Token {
 	peekedToken := c.Peek()
 	if peekedToken.Type != token.T_EOF {
@@ -57,8 +67,76 @@ func (c *ParserContext) Match(t token.TokenType) bool {
 }
 
 func (c *ParserContext) Expect(t token.TokenType) (token.Token, error) {
-	if c.Peek().Type == t {
+	found := c.Peek()
+	if found.Type == t {
 		return c.Next(), nil
 	}
-	return token.Token{}, fmt.Errorf("Position %d: expected token %v, but found %v (%q)", c.pos, t, c.Peek().Type, c.Peek().Value)
+	if found.Type == token.T_ILLEGAL {
+		c.Next()
+		return token.Token{}, token.ErrorAt(diag.ErrLexical, found, "%s", found.Value)
+	}
+	return token.Token{}, token.ErrorAt(diag.ErrExpectedToken, found, "expected token %v, but found %v (%q)", t, found.Type, found.Value)
+}
+
+// ReportError records a syntax error so that parsing can go on and
+// report the following errors in the same run.
+func (c *ParserContext) ReportError(err error) {
+	c.errors.AddError(err, diag.ErrSyntax)
+}
+
+func (c *ParserContext) Errors() diag.List {
+	return c.errors
+}
+
+// Synchronize skips tokens after a syntax error up to a point where a new
+// statement can start: right after a ';' or a balanced '{ ... }' block, or
+// before a '}' that closes the enclosing block. Lexer errors among the
+// skipped tokens are still reported.
+func (c *ParserContext) Synchronize() {
+	depth := 0
+	for first := true; ; first = false {
+		tok := c.Peek()
+		switch tok.Type {
+		case token.T_EOF:
+			return
+		case token.T_LBRACE:
+			depth++
+		case token.T_RBRACE:
+			if depth == 0 {
+				return
+			}
+			depth--
+			if depth == 0 {
+				c.Next()
+				return
+			}
+		case token.T_SEMI:
+			if depth == 0 {
+				c.Next()
+				return
+			}
+		case token.T_ILLEGAL:
+			// The first token is usually the one the error was reported at.
+			if !first {
+				c.ReportError(token.ErrorAt(diag.ErrLexical, tok, "%s", tok.Value))
+			}
+		}
+		c.Next()
+	}
+}
+
+// SpanFrom returns the span from start to the end of the last consumed token.
+func (c *ParserContext) SpanFrom(start token.Position) ast.Span {
+	return ast.NewSpan(start, c.Prev().End)
+}
+
+// eof is returned past the last token. It sits right after the last token,
+// so "unexpected end of file" errors still point into the source.
+func (c *ParserContext) eof() token.Token {
+	eof := token.Token{Type: token.T_EOF, Value: ""}
+	if len(c.tokens) > 0 {
+		last := c.tokens[len(c.tokens)-1]
+		eof.Pos, eof.End = last.End, last.End
+	}
+	return eof
 }
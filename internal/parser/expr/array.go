@@ -0,0 +1,65 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package expr
+
+import (
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/token"
+)
+
+// parseArrayLiteral parses [...] or array(...). The opening token is
+// consumed by the caller, closing is the token that ends the list.
+func (p *PrimaryParser) parseArrayLiteral(start token.Position, closing token.TokenType) (ast.Expr, error) {
+	var items []ast.ArrayItem
+
+	for p.context.Peek().Type != closing {
+		itemStart := p.context.Peek().Pos
+
+		value, err := p.exprParser.ParseExpression()
+		if err != nil {
+			return nil, err
+		}
+
+		item := ast.ArrayItem{Value: value}
+		if p.context.Peek().Type == token.T_DOUBLE_ARROW {
+			p.context.Next()
+			if item.Value, err = p.exprParser.ParseExpression(); err != nil {
+				return nil, err
+			}
+			item.Key = value
+		}
+		item.Span = p.context.SpanFrom(itemStart)
+		items = append(items, item)
+
+		if p.context.Peek().Type != token.T_COMMA {
+			break
+		}
+		p.context.Next() // Consume ','; a trailing comma is allowed
+	}
+
+	if _, err := p.context.Expect(closing); err != nil {
+		return nil, err
+	}
+
+	return &ast.ArrayLiteral{Span: p.context.SpanFrom(start), Items: items}, nil
+}
+
+// parseIndex parses one [index] suffix after base. The '[' has not been
+// consumed yet. An empty [] gives a nil index, which only a write can
+// use.
+func (p *PrimaryParser) parseIndex(base ast.Expr) (ast.Expr, error) {
+	p.context.Next() // [
+
+	var index ast.Expr
+	if p.context.Peek().Type != token.T_RBRACKET {
+		var err error
+		if index, err = p.exprParser.ParseExpression(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.context.Expect(token.T_RBRACKET); err != nil {
+		return nil, err
+	}
+
+	return &ast.IndexExpr{Span: p.context.SpanFrom(base.Pos()), Base: base, Index: index}, nil
+}
@@ -0,0 +1,39 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package expr
+
+import (
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/diag"
+	"github.com/neokofg/php-compiler/internal/token"
+)
+
+// parseAssignment parses the assignment operator and the value that
+// follow target. Assignments are parsed with their target rather than in
+// the operator loop, so that they apply to the variable right before
+// them whatever precedes it, as in PHP: !$a = f() is !($a = f()). The
+// value can itself be an assignment, which makes them right associative:
+// $a = $b = 1 is $a = ($b = 1).
+func (p *Parser) parseAssignment(target ast.Expr) (ast.Expr, error) {
+	opTok := p.context.Next()
+
+	if !isAssignable(target) {
+		return nil, token.ErrorAt(diag.ErrInvalidOperand, opTok, "cannot assign to the result of an expression")
+	}
+
+	value, err := p.parseExpr(precAssignment)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.AssignExpr{Span: ast.NewSpan(target.Pos(), value.End()), Target: target, Op: opTok.Type, Expr: value}, nil
+}
+
+// isAssignable reports whether expr can be written: a variable, an array
+// element or a property.
+func isAssignable(expr ast.Expr) bool {
+	switch expr.(type) {
+	case *ast.VarExpr, *ast.IndexExpr, *ast.PropertyFetch:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package expr
+
+import (
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/diag"
+	"github.com/neokofg/php-compiler/internal/token"
+)
+
+// parseClosure parses function (params) use ($a, &$b) { body }.
+func (p *PrimaryParser) parseClosure() (ast.Expr, error) {
+	start := p.context.Next().Pos // function
+
+	params, err := p.parseParameters()
+	if err != nil {
+		return nil, err
+	}
+
+	var uses []ast.ClosureUse
+	if p.context.Peek().Type == token.T_USE {
+		p.context.Next()
+		if uses, err = p.parseUses(); err != nil {
+			return nil, err
+		}
+	}
+
+	body, err := p.stmtParser.ParseBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.ClosureExpr{Span: p.context.SpanFrom(start), Params: params, Uses: uses, Body: body}, nil
+}
+
+// parseArrowFunction parses fn(params) => expr.
+func (p *PrimaryParser) parseArrowFunction() (ast.Expr, error) {
+	start := p.context.Next().Pos // fn
+
+	params, err := p.parseParameters()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.context.Expect(token.T_DOUBLE_ARROW); err != nil {
+		return nil, err
+	}
+
+	expr, err := p.exprParser.ParseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.ArrowFunction{Span: p.context.SpanFrom(start), Params: params, Expr: expr}, nil
+}
+
+// parseParameters parses the parenthesized parameter names of a closure.
+func (p *PrimaryParser) parseParameters() ([]string, error) {
+	if _, err := p.context.Expect(token.T_LPAREN); err != nil {
+		return nil, err
+	}
+
+	var params []string
+	for p.context.Peek().Type != token.T_RPAREN {
+		if _, err := p.context.Expect(token.T_DOLLAR); err != nil {
+			return nil, err
+		}
+		param, err := p.context.Expect(token.T_IDENT)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, param.Value)
+
+		if p.context.Peek().Type != token.T_COMMA {
+			break
+		}
+		p.context.Next() // ,
+	}
+
+	if _, err := p.context.Expect(token.T_RPAREN); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// parseUses parses the parenthesized variable list after use.
+func (p *PrimaryParser) parseUses() ([]ast.ClosureUse, error) {
+	if _, err := p.context.Expect(token.T_LPAREN); err != nil {
+		return nil, err
+	}
+
+	var uses []ast.ClosureUse
+	for p.context.Peek().Type != token.T_RPAREN {
+		byRef := false
+		if p.context.Peek().Type == token.T_BIT_AND {
+			p.context.Next()
+			byRef = true
+		}
+
+		if _, err := p.context.Expect(token.T_DOLLAR); err != nil {
+			return nil, err
+		}
+		name, err := p.context.Expect(token.T_IDENT)
+		if err != nil {
+			return nil, err
+		}
+		if name.Value == "this" {
+			return nil, token.ErrorAt(diag.ErrInvalidOperand, name, "cannot use $this as lexical variable")
+		}
+		uses = append(uses, ast.ClosureUse{Name: name.Value, ByRef: byRef})
+
+		if p.context.Peek().Type != token.T_COMMA {
+			break
+		}
+		p.context.Next() // ,
+	}
+
+	if _, err := p.context.Expect(token.T_RPAREN); err != nil {
+		return nil, err
+	}
+	return uses, nil
+}
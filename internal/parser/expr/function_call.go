@@ -20,6 +20,8 @@ func NewFunctionCallParser(context interfaces.TokenReader, exprParser interfaces
 }
 
 func (p *FunctionCallParser) Parse(funcName string) (ast.Expr, error) {
+	start := p.context.Prev().Pos
+
 	_, err := p.context.Expect(token.T_LPAREN)
 	if err != nil {
 		return nil, err
@@ -49,6 +51,7 @@ func (p *FunctionCallParser) Parse(funcName string) (ast.Expr, error) {
 	}
 
 	return &ast.FunctionCall{
+		Span: p.context.SpanFrom(start),
 		Name: funcName,
 		Args: args,
 	}, nil
@@ -0,0 +1,122 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package expr
+
+import (
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/diag"
+	"github.com/neokofg/php-compiler/internal/token"
+)
+
+// parseInterpolatedString parses the parts the lexer splits a string with
+// embedded variables into, from T_STRING_START to T_STRING_END.
+func (p *PrimaryParser) parseInterpolatedString() (ast.Expr, error) {
+	start := p.context.Next().Pos
+	var parts []ast.Expr
+
+	for {
+		tok := p.context.Peek()
+
+		switch tok.Type {
+		case token.T_STRING_END:
+			p.context.Next()
+			return &ast.InterpolatedString{Span: p.context.SpanFrom(start), Parts: parts}, nil
+
+		case token.T_STRING_PART:
+			p.context.Next()
+			parts = append(parts, &ast.StringLiteral{Span: p.context.SpanFrom(tok.Pos), Value: tok.Value})
+
+		case token.T_DOLLAR:
+			part, err := p.parseSimpleVar()
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
+
+		case token.T_CURLY_OPEN:
+			p.context.Next()
+			part, err := p.exprParser.ParseExpression()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.context.Expect(token.T_RBRACE); err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
+
+		case token.T_DOLLAR_OPEN_CURLY:
+			p.context.Next()
+			ident, err := p.context.Expect(token.T_IDENT)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.context.Expect(token.T_RBRACE); err != nil {
+				return nil, err
+			}
+			parts = append(parts, &ast.VarExpr{Span: p.context.SpanFrom(tok.Pos), Name: ident.Value})
+
+		case token.T_ILLEGAL:
+			p.context.Next()
+			return nil, token.ErrorAt(diag.ErrLexical, tok, "%s", tok.Value)
+
+		default:
+			return nil, token.ErrorAt(diag.ErrSyntax, tok, "unexpected %v in string", tok.Type)
+		}
+	}
+}
+
+// parseSimpleVar parses `$name`, `$name[key]` and `$name->prop` inside a
+// string.
+func (p *PrimaryParser) parseSimpleVar() (ast.Expr, error) {
+	start := p.context.Next().Pos
+	ident, err := p.context.Expect(token.T_IDENT)
+	if err != nil {
+		return nil, err
+	}
+	expr := &ast.VarExpr{Span: p.context.SpanFrom(start), Name: ident.Value}
+
+	switch tok := p.context.Peek(); tok.Type {
+	case token.T_LBRACKET:
+		p.context.Next()
+		index, err := p.parseStringOffset()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.context.Expect(token.T_RBRACKET); err != nil {
+			return nil, err
+		}
+		return &ast.IndexExpr{Span: p.context.SpanFrom(start), Base: expr, Index: index}, nil
+
+	case token.T_OBJECT_OPERATOR:
+		p.context.Next()
+		name, err := p.context.Expect(token.T_IDENT)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.PropertyFetch{Span: p.context.SpanFrom(start), Object: expr, Name: name.Value}, nil
+	}
+
+	return expr, nil
+}
+
+// parseStringOffset parses the key of "$name[key]": an unquoted string,
+// an integer or a variable.
+func (p *PrimaryParser) parseStringOffset() (ast.Expr, error) {
+	tok := p.context.Next()
+
+	switch tok.Type {
+	case token.T_STRING:
+		return &ast.StringLiteral{Span: p.context.SpanFrom(tok.Pos), Value: tok.Value}, nil
+	case token.T_NUMBER:
+		return parseIntegerLiteral(tok, p.context.SpanFrom(tok.Pos))
+	case token.T_DOLLAR:
+		ident, err := p.context.Expect(token.T_IDENT)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.VarExpr{Span: p.context.SpanFrom(tok.Pos), Name: ident.Value}, nil
+	case token.T_ILLEGAL:
+		return nil, token.ErrorAt(diag.ErrLexical, tok, "%s", tok.Value)
+	default:
+		return nil, token.ErrorAt(diag.ErrSyntax, tok, "unexpected %v in string offset", tok.Type)
+	}
+}
@@ -0,0 +1,39 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package expr
+
+import (
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/diag"
+	"github.com/neokofg/php-compiler/internal/token"
+)
+
+// parseIsset parses isset(vars). Whether each var can be checked is left
+// to the compiler.
+func (p *PrimaryParser) parseIsset() (ast.Expr, error) {
+	tok := p.context.Next() // isset
+
+	vars, err := p.parseArguments()
+	if err != nil {
+		return nil, err
+	}
+	if len(vars) == 0 {
+		return nil, token.ErrorAt(diag.ErrSyntax, tok, "isset() expects at least one variable")
+	}
+
+	return &ast.IssetExpr{Span: p.context.SpanFrom(tok.Pos), Vars: vars}, nil
+}
+
+// parseEmpty parses empty(expr).
+func (p *PrimaryParser) parseEmpty() (ast.Expr, error) {
+	tok := p.context.Next() // empty
+
+	args, err := p.parseArguments()
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != 1 {
+		return nil, token.ErrorAt(diag.ErrSyntax, tok, "empty() expects exactly one expression")
+	}
+
+	return &ast.EmptyExpr{Span: p.context.SpanFrom(tok.Pos), Expr: args[0]}, nil
+}
@@ -0,0 +1,132 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package expr
+
+import (
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/diag"
+	"github.com/neokofg/php-compiler/internal/token"
+)
+
+// parseNew parses new Class or new Class(args). As in PHP before 8.4, the
+// result can only be dereferenced inside parentheses: (new Foo)->bar().
+func (p *PrimaryParser) parseNew() (ast.Expr, error) {
+	start := p.context.Next().Pos // new
+
+	class := p.context.Next()
+	if class.Type != token.T_IDENT && class.Type != token.T_STATIC {
+		return nil, token.ErrorAt(diag.ErrExpectedToken, class, "expected class name after 'new', but found %v", class.Type)
+	}
+
+	var err error
+
+	var args []ast.Expr
+	if p.context.Peek().Type == token.T_LPAREN {
+		if args, err = p.parseArguments(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ast.NewExpr{Span: p.context.SpanFrom(start), Class: class.Value, Args: args}, nil
+}
+
+// parseStaticCall parses Class::name(args) once the class name has been
+// consumed. Static properties and class constants are not supported.
+func (p *PrimaryParser) parseStaticCall(class token.Token) (ast.Expr, error) {
+	p.context.Next() // ::
+
+	name := p.context.Next()
+	if !name.IsName() {
+		return nil, token.ErrorAt(diag.ErrExpectedToken, name, "expected method name after '::', but found %v", name.Type)
+	}
+	if p.context.Peek().Type != token.T_LPAREN {
+		return nil, token.ErrorAt(diag.ErrSyntax, name, "static properties and class constants are not supported: %s::%s", class.Value, name.Value)
+	}
+
+	args, err := p.parseArguments()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.StaticCall{Span: p.context.SpanFrom(class.Pos), Class: class.Value, Name: name.Value, Args: args}, nil
+}
+
+// parseSuffixes parses any number of [index], ->name, ->name(args) and
+// (args) suffixes after base.
+func (p *PrimaryParser) parseSuffixes(base ast.Expr) (ast.Expr, error) {
+	for {
+		var err error
+
+		switch p.context.Peek().Type {
+		case token.T_LBRACKET:
+			base, err = p.parseIndex(base)
+		case token.T_OBJECT_OPERATOR:
+			base, err = p.parseMember(base)
+		case token.T_LPAREN:
+			base, err = p.parseCall(base)
+		default:
+			return base, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseMember parses ->name or ->name(args) after object. Keywords are
+// valid member names.
+func (p *PrimaryParser) parseMember(object ast.Expr) (ast.Expr, error) {
+	p.context.Next() // ->
+
+	name := p.context.Next()
+	if !name.IsName() {
+		return nil, token.ErrorAt(diag.ErrExpectedToken, name, "expected property or method name after '->', but found %v", name.Type)
+	}
+
+	if p.context.Peek().Type != token.T_LPAREN {
+		return &ast.PropertyFetch{Span: p.context.SpanFrom(object.Pos()), Object: object, Name: name.Value}, nil
+	}
+
+	args, err := p.parseArguments()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.MethodCall{Span: p.context.SpanFrom(object.Pos()), Object: object, Name: name.Value, Args: args}, nil
+}
+
+// parseCall parses the arguments of a call to the value of callee.
+func (p *PrimaryParser) parseCall(callee ast.Expr) (ast.Expr, error) {
+	args, err := p.parseArguments()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.CallExpr{Span: p.context.SpanFrom(callee.Pos()), Callee: callee, Args: args}, nil
+}
+
+// parseArguments parses a parenthesized, comma separated argument list.
+func (p *PrimaryParser) parseArguments() ([]ast.Expr, error) {
+	if _, err := p.context.Expect(token.T_LPAREN); err != nil {
+		return nil, err
+	}
+
+	var args []ast.Expr
+	if p.context.Peek().Type != token.T_RPAREN {
+		for {
+			arg, err := p.exprParser.ParseExpression()
+			if err != nil {
+				return nil, err
+			}
+
+			args = append(args, arg)
+
+			if p.context.Peek().Type != token.T_COMMA {
+				break
+			}
+			p.context.Next() // Consume ','
+		}
+	}
+
+	if _, err := p.context.Expect(token.T_RPAREN); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
@@ -3,19 +3,17 @@ package expr
 
 import (
 	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/diag"
 	"github.com/neokofg/php-compiler/internal/parser/interfaces"
+	"github.com/neokofg/php-compiler/internal/token"
 )
 
+// Parser parses expressions by precedence climbing over the operator
+// tables in precedence.go. Operands are parsed by the PrimaryParser.
 type Parser struct {
 	context interfaces.TokenReader
 
-	primaryParser    *PrimaryParser
-	mulDivParser     *MulDivParser
-	addSubParser     *AddSubParser
-	concatParser     *ConcatParser
-	comparisonParser *ComparisonParser
-	andParser        *AndParser
-	orParser         *OrParser
+	primaryParser *PrimaryParser
 }
 
 func NewParser(context interfaces.TokenReader) interfaces.ExpressionParser {
@@ -24,18 +22,109 @@ func NewParser(context interfaces.TokenReader) interfaces.ExpressionParser {
 	}
 
 	parser.primaryParser = NewPrimaryParser(context)
-	parser.mulDivParser = NewMulDivParser(context, parser.primaryParser)
-	parser.addSubParser = NewAddSubParser(context, parser.mulDivParser)
-	parser.concatParser = NewConcatParser(context, parser.addSubParser)
-	parser.comparisonParser = NewComparisonParser(context, parser.concatParser)
-	parser.andParser = NewAndParser(context, parser.comparisonParser)
-	parser.orParser = NewOrParser(context, parser.andParser)
-
 	parser.primaryParser.SetExprParser(parser)
 
 	return parser
 }
 
 func (p *Parser) ParseExpression() (ast.Expr, error) {
-	return p.orParser.Parse()
+	return p.parseExpr(precLowest)
+}
+
+func (p *Parser) SetStatementParser(stmtParser interfaces.StatementParser) {
+	p.primaryParser.stmtParser = stmtParser
+}
+
+// ParseArguments parses a parenthesized call argument list.
+func (p *Parser) ParseArguments() ([]ast.Expr, error) {
+	return p.primaryParser.parseArguments()
+}
+
+// parseExpr parses an expression whose operators all bind more tightly
+// than prec.
+func (p *Parser) parseExpr(prec int) (ast.Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	var prevTernary *ast.TernaryExpr
+	for {
+		opTok := p.context.Peek()
+
+		if opTok.Type == token.T_QUESTION && precTernary > prec {
+			if left, err = p.parseTernary(left, prevTernary); err != nil {
+				return nil, err
+			}
+			prevTernary = left.(*ast.TernaryExpr)
+			continue
+		}
+
+		if opTok.Type == token.T_INSTANCEOF && precInstanceof > prec {
+			if left, err = p.parseInstanceof(left); err != nil {
+				return nil, err
+			}
+			prevTernary = nil
+			continue
+		}
+
+		op, ok := binaryOperators[opTok.Type]
+		if !ok || op.precedence <= prec {
+			return left, nil
+		}
+		p.context.Next()
+
+		rightPrec := op.precedence
+		if op.assoc == assocRight {
+			rightPrec--
+		}
+		right, err := p.parseExpr(rightPrec)
+		if err != nil {
+			return nil, err
+		}
+		left = &ast.BinaryExpr{Span: ast.NewSpan(left.Pos(), right.End()), Left: left, Op: opTok.Type, Right: right}
+
+		if next, ok := binaryOperators[p.context.Peek().Type]; ok && op.assoc == assocNone && next.precedence == op.precedence {
+			return nil, token.ErrorAt(diag.ErrSyntax, p.context.Peek(),
+				"unexpected %v, comparison operators of the same precedence cannot be chained without parentheses", p.context.Peek().Type)
+		}
+		prevTernary = nil
+	}
+}
+
+// parseUnary parses a prefix operator and its operand, or a primary
+// expression and the assignment to it, if any.
+func (p *Parser) parseUnary() (ast.Expr, error) {
+	opTok := p.context.Peek()
+
+	prec, ok := prefixOperators[opTok.Type]
+	if !ok {
+		operand, err := p.primaryParser.Parse()
+		if err != nil {
+			return nil, err
+		}
+		if assignmentOperators[p.context.Peek().Type] {
+			return p.parseAssignment(operand)
+		}
+		return operand, nil
+	}
+	p.context.Next()
+
+	operand, err := p.parseExpr(prec)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.UnaryExpr{Span: p.context.SpanFrom(opTok.Pos), Op: opTok.Type, Expr: operand}, nil
+}
+
+// parseInstanceof parses the class name after expr instanceof. As for
+// new, the class cannot be given by an expression.
+func (p *Parser) parseInstanceof(left ast.Expr) (ast.Expr, error) {
+	p.context.Next() // instanceof
+
+	class := p.context.Next()
+	if class.Type != token.T_IDENT && class.Type != token.T_STATIC {
+		return nil, token.ErrorAt(diag.ErrExpectedToken, class, "expected class name after 'instanceof', but found %v", class.Type)
+	}
+	return &ast.InstanceofExpr{Span: p.context.SpanFrom(left.Pos()), Expr: left, Class: class.Value}, nil
 }
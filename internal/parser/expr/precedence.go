@@ -0,0 +1,106 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package expr
+
+import "github.com/neokofg/php-compiler/internal/token"
+
+// Precedence levels of PHP 8 operators, from the loosest to the tightest.
+const (
+	precLowest         = iota
+	precLogicalOr      // or
+	precLogicalXor     // xor
+	precLogicalAnd     // and
+	precAssignment     // = += -= ...
+	precTernary        // ? :
+	precCoalesce       // ??
+	precOr             // ||
+	precAnd            // &&
+	precBitOr          // |
+	precBitXor         // ^
+	precBitAnd         // &
+	precEquality       // == != === !== <=>
+	precRelational     // < <= > >=
+	precConcat         // .
+	precShift          // << >>
+	precAdditive       // + -
+	precMultiplicative // * / %
+	precUnary          // ! ~ unary - +
+	precInstanceof     // instanceof
+	precPow            // **
+)
+
+type associativity int
+
+const (
+	assocLeft associativity = iota
+	assocRight
+	// assocNone operators cannot follow each other without parentheses:
+	// a == b == c is a syntax error.
+	assocNone
+)
+
+type binaryOperator struct {
+	precedence int
+	assoc      associativity
+}
+
+// binaryOperators are the infix operators that build an ast.BinaryExpr.
+// The ternary operator, which has three operands, and instanceof, whose
+// right operand is a class name, are parsed apart.
+var binaryOperators = map[token.TokenType]binaryOperator{
+	token.T_LOGICAL_OR:  {precLogicalOr, assocLeft},
+	token.T_LOGICAL_XOR: {precLogicalXor, assocLeft},
+	token.T_LOGICAL_AND: {precLogicalAnd, assocLeft},
+	token.T_COALESCE:    {precCoalesce, assocRight},
+	token.T_OR:          {precOr, assocLeft},
+	token.T_AND:         {precAnd, assocLeft},
+	token.T_BIT_OR:      {precBitOr, assocLeft},
+	token.T_BIT_XOR:     {precBitXor, assocLeft},
+	token.T_BIT_AND:     {precBitAnd, assocLeft},
+	token.T_EQEQ:        {precEquality, assocNone},
+	token.T_NOTEQ:       {precEquality, assocNone},
+	token.T_EQEQEQ:      {precEquality, assocNone},
+	token.T_NOTEQEQ:     {precEquality, assocNone},
+	token.T_SPACESHIP:   {precEquality, assocNone},
+	token.T_LT:          {precRelational, assocNone},
+	token.T_LTE:         {precRelational, assocNone},
+	token.T_GT:          {precRelational, assocNone},
+	token.T_GTE:         {precRelational, assocNone},
+	token.T_DOT:         {precConcat, assocLeft},
+	token.T_LSHIFT:      {precShift, assocLeft},
+	token.T_RSHIFT:      {precShift, assocLeft},
+	token.T_PLUS:        {precAdditive, assocLeft},
+	token.T_MINUS:       {precAdditive, assocLeft},
+	token.T_STAR:        {precMultiplicative, assocLeft},
+	token.T_SLASH:       {precMultiplicative, assocLeft},
+	token.T_MOD:         {precMultiplicative, assocLeft},
+	token.T_POW:         {precPow, assocRight},
+}
+
+// assignmentOperators are the operators that build an ast.AssignExpr.
+// They all have precAssignment and are right associative.
+var assignmentOperators = map[token.TokenType]bool{
+	token.T_EQ:          true,
+	token.T_PLUS_EQ:     true,
+	token.T_MINUS_EQ:    true,
+	token.T_MUL_EQ:      true,
+	token.T_DIV_EQ:      true,
+	token.T_MOD_EQ:      true,
+	token.T_DOT_EQ:      true,
+	token.T_POW_EQ:      true,
+	token.T_COALESCE_EQ: true,
+	token.T_BIT_AND_EQ:  true,
+	token.T_BIT_OR_EQ:   true,
+	token.T_BIT_XOR_EQ:  true,
+	token.T_LSHIFT_EQ:   true,
+	token.T_RSHIFT_EQ:   true,
+}
+
+// prefixOperators are the operators that build an ast.UnaryExpr, with the
+// precedence their operand is parsed at. ** binds more tightly than the
+// unary minus: -2 ** 2 is -(2 ** 2).
+var prefixOperators = map[token.TokenType]int{
+	token.T_NOT:     precUnary,
+	token.T_BIT_NOT: precUnary,
+	token.T_MINUS:   precUnary,
+	token.T_PLUS:    precUnary,
+}
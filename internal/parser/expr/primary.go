@@ -2,16 +2,20 @@
 package expr
 
 import (
-	"fmt"
+	"errors"
 	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/diag"
 	"github.com/neokofg/php-compiler/internal/parser/interfaces"
 	"github.com/neokofg/php-compiler/internal/token"
+	"math/big"
 	"strconv"
+	"strings"
 )
 
 type PrimaryParser struct {
 	context    interfaces.TokenReader
 	exprParser interfaces.ExpressionParser
+	stmtParser interfaces.StatementParser
 }
 
 func NewPrimaryParser(context interfaces.TokenReader) *PrimaryParser {
@@ -33,34 +37,61 @@ func (p *PrimaryParser) Parse() (ast.Expr, error) {
 	if tok.Type == token.T_INC || tok.Type == token.T_DEC {
 		op := p.context.Next().Type
 
-		if p.context.Peek().Type != token.T_DOLLAR {
-			return nil, fmt.Errorf("Position %d: expected variable after increment/decrement", p.context.GetPos())
-		}
-
-		p.context.Next()
-		identToken, err := p.context.Expect(token.T_IDENT)
+		operand, err := p.Parse()
 		if err != nil {
 			return nil, err
 		}
+		if !isAssignable(operand) {
+			return nil, token.ErrorAt(diag.ErrInvalidOperand, tok, "can only increment/decrement variables, array elements and properties")
+		}
 
-		return &ast.PrefixExpr{
-			Op:   op,
-			Expr: &ast.VarExpr{Name: identToken.Value},
-		}, nil
+		return &ast.PrefixExpr{Span: p.context.SpanFrom(tok.Pos), Op: op, Expr: operand}, nil
 	}
 
 	switch tok.Type {
 	case token.T_NUMBER:
 		p.context.Next()
-		val, err := strconv.Atoi(tok.Value)
+		var err error
+		expr, err = parseIntegerLiteral(tok, p.context.SpanFrom(tok.Pos))
 		if err != nil {
-			return nil, fmt.Errorf("Position %d: wrong number format: %s", p.context.GetPos()-1, tok.Value)
+			return nil, err
 		}
-		expr = &ast.NumberLiteral{Value: val}
+
+	case token.T_FLOAT:
+		p.context.Next()
+		val, err := strconv.ParseFloat(strings.ReplaceAll(tok.Value, "_", ""), 64)
+		if err != nil && !errors.Is(err, strconv.ErrRange) {
+			return nil, token.ErrorAt(diag.ErrSyntax, tok, "wrong number format: %s", tok.Value)
+		}
+		// Out of range literals become INF or 0, as in PHP.
+		expr = &ast.FloatLiteral{Span: p.context.SpanFrom(tok.Pos), Value: val}
 
 	case token.T_STRING:
 		p.context.Next()
-		expr = &ast.StringLiteral{Value: tok.Value}
+		expr = &ast.StringLiteral{Span: p.context.SpanFrom(tok.Pos), Value: tok.Value}
+
+	case token.T_STRING_START:
+		expr, err = p.parseInterpolatedString()
+		if err != nil {
+			return nil, err
+		}
+
+	case token.T_LBRACKET:
+		p.context.Next()
+		expr, err = p.parseArrayLiteral(tok.Pos, token.T_RBRACKET)
+		if err != nil {
+			return nil, err
+		}
+
+	case token.T_ARRAY:
+		p.context.Next()
+		if _, err := p.context.Expect(token.T_LPAREN); err != nil {
+			return nil, err
+		}
+		expr, err = p.parseArrayLiteral(tok.Pos, token.T_RPAREN)
+		if err != nil {
+			return nil, err
+		}
 
 	case token.T_DOLLAR:
 		p.context.Next()
@@ -68,7 +99,7 @@ func (p *PrimaryParser) Parse() (ast.Expr, error) {
 		if err != nil {
 			return nil, err
 		}
-		expr = &ast.VarExpr{Name: identToken.Value}
+		expr = &ast.VarExpr{Span: p.context.SpanFrom(tok.Pos), Name: identToken.Value}
 
 	case token.T_LPAREN:
 		p.context.Next()
@@ -83,94 +114,97 @@ func (p *PrimaryParser) Parse() (ast.Expr, error) {
 		}
 		expr = innerExpr
 
-	case token.T_IDENT:
+	case token.T_IDENT, token.T_STATIC:
 		name := p.context.Next().Value
-		if p.context.Peek().Type == token.T_LPAREN {
-			p.context.Next() // Consume '('
-
-			if p.exprParser == nil {
-				return nil, fmt.Errorf("Position %d: expression parser not initialized", p.context.GetPos())
-			}
-
-			var args []ast.Expr
-			if p.context.Peek().Type != token.T_RPAREN {
-				for {
-					arg, err := p.exprParser.ParseExpression()
-					if err != nil {
-						return nil, err
-					}
-
-					args = append(args, arg)
-
-					if p.context.Peek().Type != token.T_COMMA {
-						break
-					}
-					p.context.Next() // Consume ','
-				}
+		if p.context.Peek().Type == token.T_DOUBLE_COLON {
+			expr, err = p.parseStaticCall(tok)
+			if err != nil {
+				return nil, err
 			}
-
-			_, err = p.context.Expect(token.T_RPAREN)
+			break
+		}
+		if tok.Type == token.T_IDENT && p.context.Peek().Type == token.T_LPAREN {
+			args, err := p.parseArguments()
 			if err != nil {
 				return nil, err
 			}
 
-			return &ast.FunctionCall{
+			expr = &ast.FunctionCall{
+				Span: p.context.SpanFrom(tok.Pos),
 				Name: name,
 				Args: args,
-			}, nil
+			}
+			break
 		}
-		return nil, fmt.Errorf("Position %d: unexpected identifier: %s", p.context.GetPos()-1, name)
+		return nil, token.ErrorAt(diag.ErrSyntax, tok, "unexpected identifier: %s", name)
 
-	case token.T_ILLEGAL:
-		p.context.Next()
-		return nil, fmt.Errorf("Lexer error in position %d: %s", p.context.GetPos()-1, tok.Value)
+	case token.T_NEW:
+		return p.parseNew()
+
+	case token.T_FUNCTION:
+		return p.parseClosure()
+
+	case token.T_FN:
+		return p.parseArrowFunction()
 
-	case token.T_NOT:
+	case token.T_ILLEGAL:
 		p.context.Next()
-		innerExpr, err := p.Parse()
-		if err != nil {
-			return nil, err
-		}
-		expr = &ast.UnaryExpr{Op: token.T_NOT, Expr: innerExpr}
+		return nil, token.ErrorAt(diag.ErrLexical, tok, "%s", tok.Value)
 
 	case token.T_TRUE:
 		p.context.Next()
-		expr = &ast.BooleanLiteral{Value: true}
+		expr = &ast.BooleanLiteral{Span: p.context.SpanFrom(tok.Pos), Value: true}
 
 	case token.T_FALSE:
 		p.context.Next()
-		expr = &ast.BooleanLiteral{Value: false}
+		expr = &ast.BooleanLiteral{Span: p.context.SpanFrom(tok.Pos), Value: false}
 
-	case token.T_INC, token.T_DEC:
-		op := p.context.Next().Type
-		exprValue, err := p.Parse()
-		if err != nil {
-			return nil, err
-		}
+	case token.T_NULL:
+		p.context.Next()
+		expr = &ast.NullLiteral{Span: p.context.SpanFrom(tok.Pos)}
 
-		varExpr, ok := exprValue.(*ast.VarExpr)
-		if !ok {
-			return nil, fmt.Errorf("Position %d: can only increment/decrement variables", p.context.GetPos()-1)
-		}
+	case token.T_ISSET:
+		return p.parseIsset()
+
+	case token.T_EMPTY:
+		return p.parseEmpty()
 
-		return &ast.PrefixExpr{Op: op, Expr: varExpr}, nil
 	default:
-		p.context.Next()
-		return nil, fmt.Errorf("Position %d: expected expression (num, string, var, function call, '('), but found token: %v (%q)",
-			p.context.GetPos()-1, tok.Type, tok.Value)
+		return nil, token.ErrorAt(diag.ErrExpectedExpr, tok, "expected expression (num, string, var, function call, '('), but found token: %v (%q)",
+			tok.Type, tok.Value)
 	}
 
 	if expr != nil {
+		if expr, err = p.parseSuffixes(expr); err != nil {
+			return nil, err
+		}
+
 		if p.context.Peek().Type == token.T_INC || p.context.Peek().Type == token.T_DEC {
-			_, ok := expr.(*ast.VarExpr)
-			if !ok {
-				return nil, fmt.Errorf("Position %d: can only increment/decrement variables", p.context.GetPos())
+			if !isAssignable(expr) {
+				return nil, token.ErrorAt(diag.ErrInvalidOperand, p.context.Peek(), "can only increment/decrement variables, array elements and properties")
 			}
 
 			op := p.context.Next().Type
-			return &ast.PostfixExpr{Expr: expr, Op: op}, nil
+			return &ast.PostfixExpr{Span: p.context.SpanFrom(tok.Pos), Expr: expr, Op: op}, nil
 		}
 	}
 
 	return expr, err
 }
+
+// parseIntegerLiteral converts a decimal, hex, octal or binary literal.
+// Literals beyond the int64 range become floats, as in PHP.
+func parseIntegerLiteral(tok token.Token, span ast.Span) (ast.Expr, error) {
+	digits := strings.ReplaceAll(tok.Value, "_", "")
+	if n, err := strconv.ParseInt(digits, 0, 64); err == nil {
+		return &ast.NumberLiteral{Span: span, Value: n}, nil
+	}
+
+	n, ok := new(big.Int).SetString(digits, 0)
+	if !ok {
+		return nil, token.ErrorAt(diag.ErrSyntax, tok, "invalid numeric literal: %s", tok.Value)
+	}
+
+	f, _ := new(big.Float).SetInt(n).Float64()
+	return &ast.FloatLiteral{Span: span, Value: f}, nil
+}
@@ -0,0 +1,41 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package expr
+
+import (
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/diag"
+	"github.com/neokofg/php-compiler/internal/token"
+)
+
+// parseTernary parses the ? then : else or ?: else that follows cond. prev
+// is cond when it is itself an unparenthesized ternary: as in PHP 8, only
+// short ternaries chain without parentheses, so a ?: b ?: c is
+// (a ?: b) ?: c, while a ? b : c ? d : e is an error.
+func (p *Parser) parseTernary(cond ast.Expr, prev *ast.TernaryExpr) (ast.Expr, error) {
+	questionTok := p.context.Next()
+
+	var then ast.Expr
+	if p.context.Peek().Type == token.T_COLON {
+		p.context.Next()
+	} else {
+		var err error
+		if then, err = p.ParseExpression(); err != nil {
+			return nil, err
+		}
+		if _, err := p.context.Expect(token.T_COLON); err != nil {
+			return nil, err
+		}
+	}
+
+	if prev != nil && (prev.Then != nil || then != nil) {
+		return nil, token.ErrorAt(diag.ErrSyntax, questionTok,
+			"unparenthesized `a ? b : c ? d : e` is not supported, use either `(a ? b : c) ? d : e` or `a ? b : (c ? d : e)`")
+	}
+
+	els, err := p.parseExpr(precTernary)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.TernaryExpr{Span: ast.NewSpan(cond.Pos(), els.End()), Cond: cond, Then: then, Else: els}, nil
+}
@@ -10,13 +10,23 @@ type TokenReader interface {
 	Next() token.Token
 	Peek() token.Token
 	PeekNext() token.Token
+	Prev() token.Token
 	Expect(t token.TokenType) (token.Token, error)
 	GetPos() int
 	SetPos(int)
+	SpanFrom(start token.Position) ast.Span
+
+	ReportError(err error)
+	Synchronize()
 }
 
 type ExpressionParser interface {
 	ParseExpression() (ast.Expr, error)
+	ParseArguments() ([]ast.Expr, error)
+
+	// SetStatementParser provides the parser of closure bodies, which are
+	// blocks of statements inside an expression.
+	SetStatementParser(stmtParser StatementParser)
 }
 
 type StatementParser interface {
@@ -35,6 +35,7 @@ func NewParser(tokens []token.Token) *Parser {
 
 	exprParser := expr.NewParser(ctx)
 	stmtParser := stmt.NewParser(ctx, exprParser)
+	exprParser.SetStatementParser(stmtParser)
 
 	return &Parser{
 		context:    ctx,
@@ -71,18 +72,31 @@ func (p *Parser) ParseOptionalExpression(terminator token.TokenType) (ast.Expr,
 	return p.stmtParser.ParseOptionalExpression(terminator)
 }
 
+// Parse parses the whole program. After a syntax error it resynchronizes
+// and keeps going, so the returned error is a diag.List with every error found.
 func (p *Parser) Parse() ([]ast.Stmt, error) {
 	var stmts []ast.Stmt
 
 	for p.Peek().Type != token.T_EOF {
 		stmt, err := p.stmtParser.ParseStatement()
 		if err != nil {
-			return nil, err
+			p.context.ReportError(err)
+
+			errPos := p.GetPos()
+			p.context.Synchronize()
+			if p.GetPos() == errPos && p.Peek().Type == token.T_RBRACE {
+				p.Next() // Stray '}' with no block to close
+			}
+			continue
 		}
 		if stmt != nil {
 			stmts = append(stmts, stmt)
 		}
 	}
 
+	if err := p.context.Errors().Err(); err != nil {
+		return nil, err
+	}
+
 	return stmts, nil
 }
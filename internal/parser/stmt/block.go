@@ -29,7 +29,9 @@ func (p *BlockParser) Parse() ([]ast.Stmt, error) {
 	for p.context.Peek().Type != token.T_RBRACE && p.context.Peek().Type != token.T_EOF {
 		stmt, err := p.stmtParser.ParseStatement()
 		if err != nil {
-			return nil, err
+			p.context.ReportError(err)
+			p.context.Synchronize()
+			continue
 		}
 		stmts = append(stmts, stmt)
 	}
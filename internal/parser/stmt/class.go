@@ -0,0 +1,362 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package stmt
+
+import (
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/diag"
+	"github.com/neokofg/php-compiler/internal/parser/interfaces"
+	"github.com/neokofg/php-compiler/internal/token"
+)
+
+type ClassParser struct {
+	context        interfaces.TokenReader
+	exprParser     interfaces.ExpressionParser
+	functionParser *FunctionParser
+}
+
+func NewClassParser(context interfaces.TokenReader, exprParser interfaces.ExpressionParser, functionParser *FunctionParser) *ClassParser {
+	return &ClassParser{
+		context:        context,
+		exprParser:     exprParser,
+		functionParser: functionParser,
+	}
+}
+
+// Parse parses a class, interface or trait declaration, with the abstract
+// and final modifiers of classes.
+func (p *ClassParser) Parse() (ast.Stmt, error) {
+	start := p.context.Peek().Pos
+	decl := &ast.ClassDecl{}
+
+	for {
+		tok := p.context.Peek()
+		if tok.Type == token.T_ABSTRACT {
+			if decl.Abstract {
+				return nil, token.ErrorAt(diag.ErrSyntax, tok, "multiple abstract modifiers are not allowed")
+			}
+			decl.Abstract = true
+		} else if tok.Type == token.T_FINAL {
+			if decl.Final {
+				return nil, token.ErrorAt(diag.ErrSyntax, tok, "multiple final modifiers are not allowed")
+			}
+			decl.Final = true
+		} else {
+			break
+		}
+		p.context.Next()
+	}
+
+	kind := p.context.Next()
+	switch {
+	case kind.Type != token.T_CLASS && kind.Type != token.T_INTERFACE && kind.Type != token.T_TRAIT:
+		return nil, token.ErrorAt(diag.ErrExpectedToken, kind, "expected 'class', 'interface' or 'trait', but found %v", kind.Type)
+	case kind.Type != token.T_CLASS && (decl.Abstract || decl.Final):
+		return nil, token.ErrorAt(diag.ErrSyntax, kind, "only classes can be declared abstract or final")
+	case decl.Abstract && decl.Final:
+		return nil, token.ErrorAt(diag.ErrSyntax, kind, "cannot use the final modifier on an abstract class")
+	}
+	decl.Kind = kind.Type
+
+	name, err := p.context.Expect(token.T_IDENT)
+	if err != nil {
+		return nil, err
+	}
+	decl.Name = name.Value
+
+	if tok := p.context.Peek(); tok.Type == token.T_EXTENDS {
+		p.context.Next()
+
+		switch decl.Kind {
+		case token.T_CLASS:
+			parent, err := p.context.Expect(token.T_IDENT)
+			if err != nil {
+				return nil, err
+			}
+			decl.Parent = parent.Value
+		case token.T_INTERFACE:
+			if decl.Interfaces, err = p.parseNames(); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, token.ErrorAt(diag.ErrSyntax, tok, "a trait cannot extend other classes")
+		}
+	}
+
+	if tok := p.context.Peek(); tok.Type == token.T_IMPLEMENTS {
+		if decl.Kind != token.T_CLASS {
+			return nil, token.ErrorAt(diag.ErrSyntax, tok, "only classes can implement interfaces")
+		}
+		p.context.Next()
+
+		if decl.Interfaces, err = p.parseNames(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.context.Expect(token.T_LBRACE); err != nil {
+		return nil, err
+	}
+
+	for p.context.Peek().Type != token.T_RBRACE && p.context.Peek().Type != token.T_EOF {
+		if err := p.parseMember(decl); err != nil {
+			p.context.ReportError(err)
+			p.context.Synchronize()
+		}
+	}
+
+	if _, err := p.context.Expect(token.T_RBRACE); err != nil {
+		return nil, err
+	}
+
+	decl.Span = p.context.SpanFrom(start)
+	return decl, nil
+}
+
+// parseNames parses a comma separated list of class names.
+func (p *ClassParser) parseNames() ([]string, error) {
+	var names []string
+	for {
+		name, err := p.context.Expect(token.T_IDENT)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name.Value)
+
+		if p.context.Peek().Type != token.T_COMMA {
+			return names, nil
+		}
+		p.context.Next()
+	}
+}
+
+// memberModifiers are the modifiers in front of a method or property.
+type memberModifiers struct {
+	visibility token.TokenType
+	static     bool
+	abstract   bool
+	final      bool
+}
+
+func (p *ClassParser) parseModifiers() (memberModifiers, error) {
+	var mods memberModifiers
+	for {
+		tok := p.context.Peek()
+
+		var seen bool
+		switch tok.Type {
+		case token.T_PUBLIC, token.T_PROTECTED, token.T_PRIVATE, token.T_VAR:
+			if mods.visibility != 0 {
+				return mods, token.ErrorAt(diag.ErrSyntax, tok, "multiple access type modifiers are not allowed")
+			}
+			mods.visibility = tok.Type
+			if tok.Type == token.T_VAR {
+				mods.visibility = token.T_PUBLIC
+			}
+		case token.T_STATIC:
+			seen, mods.static = mods.static, true
+		case token.T_ABSTRACT:
+			seen, mods.abstract = mods.abstract, true
+		case token.T_FINAL:
+			seen, mods.final = mods.final, true
+		default:
+			return mods, nil
+		}
+
+		if seen {
+			return mods, token.ErrorAt(diag.ErrSyntax, tok, "multiple %s modifiers are not allowed", tok.Value)
+		}
+		p.context.Next()
+	}
+}
+
+// parseMember parses a trait use, a method or a property list such as
+// `private $a = 1, $b;` and adds it to decl. Methods without a visibility
+// modifier are public; properties need one, or the old `var` keyword.
+// Methods that end with a semicolon instead of a body are kept as such;
+// the compiler checks that only abstract methods lack a body.
+func (p *ClassParser) parseMember(decl *ast.ClassDecl) error {
+	start := p.context.Peek().Pos
+
+	if p.context.Peek().Type == token.T_USE {
+		return p.parseTraitUse(decl)
+	}
+
+	mods, err := p.parseModifiers()
+	if err != nil {
+		return err
+	}
+
+	switch tok := p.context.Peek(); tok.Type {
+	case token.T_FUNCTION:
+		p.context.Next()
+
+		name := p.context.Next()
+		if !name.IsName() {
+			return token.ErrorAt(diag.ErrExpectedToken, name, "expected method name after 'function', but found %v", name.Type)
+		}
+		if mods.abstract && mods.final {
+			return token.ErrorAt(diag.ErrSyntax, name, "cannot use the final modifier on an abstract method")
+		}
+
+		function, err := p.functionParser.parseSignature(start, name.Value)
+		if err != nil {
+			return err
+		}
+
+		hasBody := p.context.Peek().Type != token.T_SEMI
+		if hasBody {
+			if function.Body, err = p.functionParser.blockParser.Parse(); err != nil {
+				return err
+			}
+		} else {
+			p.context.Next()
+		}
+		function.Span = p.context.SpanFrom(start)
+
+		if mods.visibility == 0 {
+			mods.visibility = token.T_PUBLIC
+		}
+		decl.Methods = append(decl.Methods, ast.MethodDecl{
+			Span:       function.Span,
+			Visibility: mods.visibility,
+			Static:     mods.static,
+			Abstract:   mods.abstract,
+			Final:      mods.final,
+			HasBody:    hasBody,
+			Function:   function,
+		})
+		return nil
+
+	case token.T_DOLLAR:
+		switch {
+		case mods.static:
+			return token.ErrorAt(diag.ErrSyntax, tok, "static properties are not supported")
+		case mods.abstract || mods.final:
+			return token.ErrorAt(diag.ErrSyntax, tok, "properties cannot be declared abstract or final")
+		case mods.visibility == 0:
+			return token.ErrorAt(diag.ErrSyntax, tok, "property declaration needs a visibility modifier or 'var'")
+		}
+		return p.parseProperties(decl, mods.visibility)
+
+	default:
+		return token.ErrorAt(diag.ErrSyntax, tok, "expected property or method declaration in %s, but found %v", decl.Name, tok.Type)
+	}
+}
+
+// parseTraitUse parses use A, B; or use A, B { rules }.
+func (p *ClassParser) parseTraitUse(decl *ast.ClassDecl) error {
+	start := p.context.Next().Pos // use
+
+	traits, err := p.parseNames()
+	if err != nil {
+		return err
+	}
+	use := ast.TraitUse{Traits: traits}
+
+	if p.context.Peek().Type == token.T_SEMI {
+		p.context.Next()
+	} else {
+		if _, err := p.context.Expect(token.T_LBRACE); err != nil {
+			return err
+		}
+		for p.context.Peek().Type != token.T_RBRACE && p.context.Peek().Type != token.T_EOF {
+			rule, err := p.parseTraitRule()
+			if err != nil {
+				return err
+			}
+			use.Rules = append(use.Rules, rule)
+		}
+		if _, err := p.context.Expect(token.T_RBRACE); err != nil {
+			return err
+		}
+	}
+
+	use.Span = p.context.SpanFrom(start)
+	decl.Traits = append(decl.Traits, use)
+	return nil
+}
+
+// parseTraitRule parses Trait::method insteadof Other; or
+// [Trait::]method as [visibility] [alias];.
+func (p *ClassParser) parseTraitRule() (ast.TraitRule, error) {
+	start := p.context.Peek().Pos
+	var rule ast.TraitRule
+
+	name := p.context.Next()
+	if !name.IsName() {
+		return rule, token.ErrorAt(diag.ErrExpectedToken, name, "expected method name in trait rule, but found %v", name.Type)
+	}
+	rule.Method = name.Value
+
+	if name.Type == token.T_IDENT && p.context.Peek().Type == token.T_DOUBLE_COLON {
+		p.context.Next()
+		method := p.context.Next()
+		if !method.IsName() {
+			return rule, token.ErrorAt(diag.ErrExpectedToken, method, "expected method name after '::', but found %v", method.Type)
+		}
+		rule.Trait, rule.Method = name.Value, method.Value
+	}
+
+	switch tok := p.context.Next(); tok.Type {
+	case token.T_INSTEADOF:
+		if rule.Trait == "" {
+			return rule, token.ErrorAt(diag.ErrSyntax, tok, "insteadof needs a trait name: Trait::%s insteadof Other", rule.Method)
+		}
+		names, err := p.parseNames()
+		if err != nil {
+			return rule, err
+		}
+		rule.InsteadOf = names
+
+	case token.T_AS:
+		if v := p.context.Peek().Type; v == token.T_PUBLIC || v == token.T_PROTECTED || v == token.T_PRIVATE {
+			rule.Visibility = p.context.Next().Type
+		}
+		if p.context.Peek().IsName() {
+			rule.Alias = p.context.Next().Value
+		}
+		if rule.Visibility == 0 && rule.Alias == "" {
+			return rule, token.ErrorAt(diag.ErrExpectedToken, p.context.Peek(), "expected visibility or alias after 'as', but found %v", p.context.Peek().Type)
+		}
+
+	default:
+		return rule, token.ErrorAt(diag.ErrExpectedToken, tok, "expected 'insteadof' or 'as' in trait rule, but found %v", tok.Type)
+	}
+
+	if _, err := p.context.Expect(token.T_SEMI); err != nil {
+		return rule, err
+	}
+	rule.Span = p.context.SpanFrom(start)
+	return rule, nil
+}
+
+func (p *ClassParser) parseProperties(decl *ast.ClassDecl, visibility token.TokenType) error {
+	for {
+		start, err := p.context.Expect(token.T_DOLLAR)
+		if err != nil {
+			return err
+		}
+		name, err := p.context.Expect(token.T_IDENT)
+		if err != nil {
+			return err
+		}
+
+		prop := ast.PropertyDecl{Name: name.Value, Visibility: visibility}
+		if p.context.Peek().Type == token.T_EQ {
+			p.context.Next()
+			if prop.Default, err = p.exprParser.ParseExpression(); err != nil {
+				return err
+			}
+		}
+		prop.Span = p.context.SpanFrom(start.Pos)
+		decl.Properties = append(decl.Properties, prop)
+
+		if p.context.Peek().Type != token.T_COMMA {
+			break
+		}
+		p.context.Next()
+	}
+
+	_, err := p.context.Expect(token.T_SEMI)
+	return err
+}
@@ -22,7 +22,7 @@ func NewDoWhileParser(context interfaces.TokenReader, exprParser interfaces.Expr
 }
 
 func (p *DoWhileParser) Parse() (ast.Stmt, error) {
-	p.context.Next() // do
+	start := p.context.Next().Pos // do
 
 	bodyBlock, err := p.blockParser.Parse()
 	if err != nil {
@@ -54,5 +54,5 @@ func (p *DoWhileParser) Parse() (ast.Stmt, error) {
 		return nil, err
 	}
 
-	return &ast.DoWhileStmt{Body: bodyBlock, Cond: condExpr}, nil
+	return &ast.DoWhileStmt{Span: p.context.SpanFrom(start), Body: bodyBlock, Cond: condExpr}, nil
 }
@@ -20,7 +20,7 @@ func NewEchoParser(context interfaces.TokenReader, exprParser interfaces.Express
 }
 
 func (p *EchoParser) Parse() (ast.Stmt, error) {
-	p.context.Next()
+	start := p.context.Next().Pos
 
 	expr, err := p.exprParser.ParseExpression()
 	if err != nil {
@@ -32,5 +32,5 @@ func (p *EchoParser) Parse() (ast.Stmt, error) {
 		return nil, err
 	}
 
-	return &ast.EchoStmt{Expr: expr}, nil
+	return &ast.EchoStmt{Span: p.context.SpanFrom(start), Expr: expr}, nil
 }
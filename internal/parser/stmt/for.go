@@ -23,7 +23,7 @@ func NewForParser(context interfaces.TokenReader, exprParser interfaces.Expressi
 }
 
 func (p *ForParser) Parse() (ast.Stmt, error) {
-	p.context.Next() // for
+	start := p.context.Next().Pos // for
 
 	_, err := p.context.Expect(token.T_LPAREN)
 	if err != nil {
@@ -32,39 +32,9 @@ func (p *ForParser) Parse() (ast.Stmt, error) {
 
 	var initExpr ast.Expr = nil
 	if p.context.Peek().Type != token.T_SEMI {
-		if p.context.Peek().Type == token.T_DOLLAR {
-			startPos := p.context.GetPos()
-
-			p.context.Next() // $
-			identToken, err := p.context.Expect(token.T_IDENT)
-			if err != nil {
-				return nil, err
-			}
-
-			if p.context.Peek().Type == token.T_EQ {
-				p.context.Next() // =
-
-				expr, err := p.exprParser.ParseExpression()
-				if err != nil {
-					return nil, err
-				}
-
-				initExpr = &ast.AssignExpr{
-					Name: identToken.Value,
-					Expr: expr,
-				}
-			} else {
-				p.context.SetPos(startPos)
-				initExpr, err = p.exprParser.ParseExpression()
-				if err != nil {
-					return nil, fmt.Errorf("error parsing for-loop initializer: %w", err)
-				}
-			}
-		} else {
-			initExpr, err = p.exprParser.ParseExpression()
-			if err != nil {
-				return nil, fmt.Errorf("error parsing for-loop initializer: %w", err)
-			}
+		initExpr, err = p.exprParser.ParseExpression()
+		if err != nil {
+			return nil, fmt.Errorf("error parsing for-loop initializer: %w", err)
 		}
 	}
 
@@ -104,5 +74,5 @@ func (p *ForParser) Parse() (ast.Stmt, error) {
 		return nil, err
 	}
 
-	return &ast.ForStmt{Init: initExpr, Cond: condExpr, Incr: incrExpr, Body: bodyBlock}, nil
+	return &ast.ForStmt{Span: p.context.SpanFrom(start), Init: initExpr, Cond: condExpr, Incr: incrExpr, Body: bodyBlock}, nil
 }
@@ -0,0 +1,94 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package stmt
+
+import (
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/diag"
+	"github.com/neokofg/php-compiler/internal/parser/interfaces"
+	"github.com/neokofg/php-compiler/internal/token"
+)
+
+type ForeachParser struct {
+	context     interfaces.TokenReader
+	exprParser  interfaces.ExpressionParser
+	blockParser *BlockParser
+}
+
+func NewForeachParser(context interfaces.TokenReader, exprParser interfaces.ExpressionParser, blockParser *BlockParser) *ForeachParser {
+	return &ForeachParser{
+		context:     context,
+		exprParser:  exprParser,
+		blockParser: blockParser,
+	}
+}
+
+// Parse parses foreach ($expr as $v), foreach ($expr as $k => $v) and the
+// by-reference forms with &$v.
+func (p *ForeachParser) Parse() (ast.Stmt, error) {
+	start := p.context.Next().Pos // foreach
+
+	if _, err := p.context.Expect(token.T_LPAREN); err != nil {
+		return nil, err
+	}
+
+	expr, err := p.exprParser.ParseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.context.Expect(token.T_AS); err != nil {
+		return nil, err
+	}
+
+	stmt := &ast.ForeachStmt{Expr: expr}
+
+	stmt.Value, stmt.ByRef, err = p.parseTarget()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.context.Peek().Type == token.T_DOUBLE_ARROW {
+		arrow := p.context.Next()
+		if stmt.ByRef {
+			return nil, token.ErrorAt(diag.ErrSyntax, arrow, "key element cannot be a reference")
+		}
+
+		stmt.Key = stmt.Value
+		stmt.Value, stmt.ByRef, err = p.parseTarget()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.context.Expect(token.T_RPAREN); err != nil {
+		return nil, err
+	}
+
+	stmt.Body, err = p.blockParser.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	stmt.Span = p.context.SpanFrom(start)
+	return stmt, nil
+}
+
+// parseTarget parses $name or &$name.
+func (p *ForeachParser) parseTarget() (string, bool, error) {
+	byRef := false
+	if p.context.Peek().Type == token.T_BIT_AND {
+		p.context.Next()
+		byRef = true
+	}
+
+	if _, err := p.context.Expect(token.T_DOLLAR); err != nil {
+		return "", false, err
+	}
+
+	ident, err := p.context.Expect(token.T_IDENT)
+	if err != nil {
+		return "", false, err
+	}
+
+	return ident.Value, byRef, nil
+}
@@ -2,8 +2,8 @@
 package stmt
 
 import (
-	"fmt"
 	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/diag"
 	"github.com/neokofg/php-compiler/internal/parser/interfaces"
 	"github.com/neokofg/php-compiler/internal/token"
 )
@@ -23,18 +23,39 @@ func NewFunctionParser(context interfaces.TokenReader, exprParser interfaces.Exp
 }
 
 func (p *FunctionParser) Parse() (ast.Stmt, error) {
-	p.context.Next()
+	start := p.context.Next().Pos
 
 	if p.context.Peek().Type != token.T_IDENT {
-		return nil, fmt.Errorf("Position %d: expected function name after 'function' keyword, got: %v (%s)",
-			p.context.GetPos(), p.context.Peek().Type, p.context.Peek().Value)
+		return nil, token.ErrorAt(diag.ErrExpectedToken, p.context.Peek(), "expected function name after 'function' keyword, got: %v (%s)",
+			p.context.Peek().Type, p.context.Peek().Value)
 	}
 
 	name := p.context.Next()
+	return p.parseRest(start, name.Value)
+}
+
+// parseRest parses the parameter list and body that follow the name of a
+// function or method.
+func (p *FunctionParser) parseRest(start token.Position, name string) (*ast.FunctionDecl, error) {
+	function, err := p.parseSignature(start, name)
+	if err != nil {
+		return nil, err
+	}
 
+	if function.Body, err = p.blockParser.Parse(); err != nil {
+		return nil, err
+	}
+
+	function.Span = p.context.SpanFrom(start)
+	return function, nil
+}
+
+// parseSignature parses the parameter list that follows the name of a
+// function or method.
+func (p *FunctionParser) parseSignature(start token.Position, name string) (*ast.FunctionDecl, error) {
 	if p.context.Peek().Type != token.T_LPAREN {
-		return nil, fmt.Errorf("Position %d: expected '(' after function name, got: %v (%s)",
-			p.context.GetPos(), p.context.Peek().Type, p.context.Peek().Value)
+		return nil, token.ErrorAt(diag.ErrExpectedToken, p.context.Peek(), "expected '(' after function name, got: %v (%s)",
+			p.context.Peek().Type, p.context.Peek().Value)
 	}
 
 	p.context.Next()
@@ -62,20 +83,15 @@ func (p *FunctionParser) Parse() (ast.Stmt, error) {
 	}
 
 	if p.context.Peek().Type != token.T_RPAREN {
-		return nil, fmt.Errorf("Position %d: expected ')' after function parameters, got: %v (%s)",
-			p.context.GetPos(), p.context.Peek().Type, p.context.Peek().Value)
+		return nil, token.ErrorAt(diag.ErrExpectedToken, p.context.Peek(), "expected ')' after function parameters, got: %v (%s)",
+			p.context.Peek().Type, p.context.Peek().Value)
 	}
 
 	p.context.Next()
 
-	body, err := p.blockParser.Parse()
-	if err != nil {
-		return nil, err
-	}
-
 	return &ast.FunctionDecl{
-		Name:   name.Value,
+		Span:   p.context.SpanFrom(start),
+		Name:   name,
 		Params: params,
-		Body:   body,
 	}, nil
 }
@@ -20,7 +20,7 @@ func NewFunctionCallParser(context interfaces.TokenReader, exprParser interfaces
 }
 
 func (p *FunctionCallParser) Parse() (ast.Stmt, error) {
-	name := p.context.Next().Value
+	nameToken := p.context.Next()
 
 	_, err := p.context.Expect(token.T_LPAREN)
 	if err != nil {
@@ -49,14 +49,18 @@ func (p *FunctionCallParser) Parse() (ast.Stmt, error) {
 		return nil, err
 	}
 
+	callSpan := p.context.SpanFrom(nameToken.Pos)
+
 	_, err = p.context.Expect(token.T_SEMI)
 	if err != nil {
 		return nil, err
 	}
 
 	return &ast.FunctionCallStmt{
+		Span: p.context.SpanFrom(nameToken.Pos),
 		Call: &ast.FunctionCall{
-			Name: name,
+			Span: callSpan,
+			Name: nameToken.Value,
 			Args: args,
 		},
 	}, nil
@@ -0,0 +1,44 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package stmt
+
+import (
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/parser/interfaces"
+	"github.com/neokofg/php-compiler/internal/token"
+)
+
+type GlobalParser struct {
+	context interfaces.TokenReader
+}
+
+func NewGlobalParser(context interfaces.TokenReader) *GlobalParser {
+	return &GlobalParser{context: context}
+}
+
+// Parse reads `global $a, $b;`.
+func (p *GlobalParser) Parse() (ast.Stmt, error) {
+	start := p.context.Next().Pos // global keyword
+
+	var names []string
+	for {
+		if _, err := p.context.Expect(token.T_DOLLAR); err != nil {
+			return nil, err
+		}
+		name, err := p.context.Expect(token.T_IDENT)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name.Value)
+
+		if p.context.Peek().Type != token.T_COMMA {
+			break
+		}
+		p.context.Next() // ,
+	}
+
+	if _, err := p.context.Expect(token.T_SEMI); err != nil {
+		return nil, err
+	}
+
+	return &ast.GlobalStmt{Span: p.context.SpanFrom(start), Names: names}, nil
+}
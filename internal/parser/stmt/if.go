@@ -22,7 +22,7 @@ func NewIfParser(context interfaces.TokenReader, exprParser interfaces.Expressio
 }
 
 func (p *IfParser) Parse() (ast.Stmt, error) {
-	p.context.Next() // if
+	start := p.context.Next().Pos // if
 
 	_, err := p.context.Expect(token.T_LPAREN)
 	if err != nil {
@@ -53,5 +53,5 @@ func (p *IfParser) Parse() (ast.Stmt, error) {
 		}
 	}
 
-	return &ast.IfStmt{Cond: cond, Then: thenBlock, Else: elseBlock}, nil
+	return &ast.IfStmt{Span: p.context.SpanFrom(start), Cond: cond, Then: thenBlock, Else: elseBlock}, nil
 }
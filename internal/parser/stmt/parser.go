@@ -4,6 +4,7 @@ package stmt
 import (
 	"fmt"
 	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/diag"
 	"github.com/neokofg/php-compiler/internal/parser/interfaces"
 	"github.com/neokofg/php-compiler/internal/token"
 )
@@ -12,17 +13,22 @@ type Parser struct {
 	context    interfaces.TokenReader
 	exprParser interfaces.ExpressionParser
 
-	assignParser       *AssignParser
 	echoParser         *EchoParser
 	ifParser           *IfParser
 	whileParser        *WhileParser
 	forParser          *ForParser
+	foreachParser      *ForeachParser
 	blockParser        *BlockParser
 	doWhileParser      *DoWhileParser
 	switchParser       *SwitchParser
 	functionParser     *FunctionParser
 	returnParser       *ReturnParser
 	functionCallParser *FunctionCallParser
+	globalParser       *GlobalParser
+	staticParser       *StaticParser
+	classParser        *ClassParser
+	tryParser          *TryParser
+	unsetParser        *UnsetParser
 }
 
 func NewParser(context interfaces.TokenReader, exprParser interfaces.ExpressionParser) interfaces.StatementParser {
@@ -33,16 +39,21 @@ func NewParser(context interfaces.TokenReader, exprParser interfaces.ExpressionP
 
 	parser.blockParser = NewBlockParser(context, parser)
 
-	parser.assignParser = NewAssignParser(context, exprParser)
 	parser.echoParser = NewEchoParser(context, exprParser)
 	parser.ifParser = NewIfParser(context, exprParser, parser.blockParser)
 	parser.whileParser = NewWhileParser(context, exprParser, parser.blockParser)
 	parser.forParser = NewForParser(context, exprParser, parser.blockParser)
+	parser.foreachParser = NewForeachParser(context, exprParser, parser.blockParser)
 	parser.doWhileParser = NewDoWhileParser(context, exprParser, parser.blockParser)
 	parser.switchParser = NewSwitchParser(context, exprParser, parser)
 	parser.functionParser = NewFunctionParser(context, exprParser, parser.blockParser)
 	parser.returnParser = NewReturnParser(context, exprParser)
 	parser.functionCallParser = NewFunctionCallParser(context, exprParser)
+	parser.globalParser = NewGlobalParser(context)
+	parser.staticParser = NewStaticParser(context, exprParser)
+	parser.classParser = NewClassParser(context, exprParser, parser.functionParser)
+	parser.tryParser = NewTryParser(context, exprParser, parser.blockParser)
+	parser.unsetParser = NewUnsetParser(context, exprParser)
 
 	return parser
 }
@@ -51,8 +62,8 @@ func (p *Parser) ParseStatement() (ast.Stmt, error) {
 	peekedToken := p.context.Peek()
 
 	switch peekedToken.Type {
-	case token.T_DOLLAR:
-		return p.assignParser.Parse()
+	case token.T_DOLLAR, token.T_INC, token.T_DEC:
+		return p.parseExprStmt()
 	case token.T_ECHO:
 		return p.echoParser.Parse()
 	case token.T_IF:
@@ -61,39 +72,81 @@ func (p *Parser) ParseStatement() (ast.Stmt, error) {
 		return p.whileParser.Parse()
 	case token.T_FOR:
 		return p.forParser.Parse()
+	case token.T_FOREACH:
+		return p.foreachParser.Parse()
 	case token.T_BREAK:
 		p.context.Next()
 		_, err := p.context.Expect(token.T_SEMI)
 		if err != nil {
 			return nil, err
 		}
-		return &ast.BreakStmt{}, nil
+		return &ast.BreakStmt{Span: p.context.SpanFrom(peekedToken.Pos)}, nil
 	case token.T_CONTINUE:
 		p.context.Next()
 		_, err := p.context.Expect(token.T_SEMI)
 		if err != nil {
 			return nil, err
 		}
-		return &ast.ContinueStmt{}, nil
+		return &ast.ContinueStmt{Span: p.context.SpanFrom(peekedToken.Pos)}, nil
 	case token.T_DO:
 		return p.doWhileParser.Parse()
 	case token.T_SWITCH:
 		return p.switchParser.Parse()
 	case token.T_FUNCTION:
+		if p.context.PeekNext().Type == token.T_LPAREN {
+			return p.parseExprStmt()
+		}
 		return p.functionParser.Parse()
 	case token.T_RETURN:
 		return p.returnParser.Parse()
+	case token.T_GLOBAL:
+		return p.globalParser.Parse()
+	case token.T_STATIC:
+		if p.context.PeekNext().Type == token.T_DOUBLE_COLON {
+			return p.parseExprStmt()
+		}
+		return p.staticParser.Parse()
+	case token.T_CLASS, token.T_ABSTRACT, token.T_FINAL, token.T_INTERFACE, token.T_TRAIT:
+		return p.classParser.Parse()
+	case token.T_TRY:
+		return p.tryParser.Parse()
+	case token.T_THROW:
+		return p.tryParser.parseThrow()
+	case token.T_UNSET:
+		return p.unsetParser.Parse()
+	case token.T_NEW, token.T_LPAREN, token.T_FN:
+		return p.parseExprStmt()
 	case token.T_IDENT:
 		if p.context.PeekNext().Type == token.T_LPAREN {
 			return p.functionCallParser.Parse()
 		}
-		return nil, fmt.Errorf("Position %d: unexpected identifier: %s", p.context.GetPos(), peekedToken.Value)
+		if p.context.PeekNext().Type == token.T_DOUBLE_COLON {
+			return p.parseExprStmt()
+		}
+		return nil, token.ErrorAt(diag.ErrSyntax, peekedToken, "unexpected identifier: %s", peekedToken.Value)
 	default:
 		if peekedToken.Type == token.T_ILLEGAL {
-			return nil, fmt.Errorf("Lexer error in position %d: %s", p.context.GetPos(), peekedToken.Value)
+			p.context.Next()
+			return nil, token.ErrorAt(diag.ErrLexical, peekedToken, "%s", peekedToken.Value)
 		}
-		return nil, fmt.Errorf("Position %d: unexpected token in the start of instruction: %v (%q)", p.context.GetPos(), peekedToken.Type, peekedToken.Value)
+		return nil, token.ErrorAt(diag.ErrSyntax, peekedToken, "unexpected token in the start of instruction: %v (%q)", peekedToken.Type, peekedToken.Value)
+	}
+}
+
+// parseExprStmt parses an expression used as a statement, such as
+// $x = 1;, $obj->run(); or parent::__construct();.
+func (p *Parser) parseExprStmt() (ast.Stmt, error) {
+	start := p.context.Peek().Pos
+
+	expr, err := p.exprParser.ParseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.context.Expect(token.T_SEMI); err != nil {
+		return nil, err
 	}
+	return &ast.ExprStmt{Span: p.context.SpanFrom(start), Expr: expr}, nil
 }
 
 func (p *Parser) ParseBlock() ([]ast.Stmt, error) {
@@ -20,7 +20,7 @@ func NewReturnParser(context interfaces.TokenReader, exprParser interfaces.Expre
 }
 
 func (p *ReturnParser) Parse() (ast.Stmt, error) {
-	p.context.Next() // return keyword
+	start := p.context.Next().Pos // return keyword
 
 	var expr ast.Expr
 	var err error
@@ -37,5 +37,5 @@ func (p *ReturnParser) Parse() (ast.Stmt, error) {
 		return nil, err
 	}
 
-	return &ast.ReturnStmt{Expr: expr}, nil
+	return &ast.ReturnStmt{Span: p.context.SpanFrom(start), Expr: expr}, nil
 }
@@ -0,0 +1,63 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package stmt
+
+import (
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/parser/interfaces"
+	"github.com/neokofg/php-compiler/internal/token"
+)
+
+type StaticParser struct {
+	context    interfaces.TokenReader
+	exprParser interfaces.ExpressionParser
+}
+
+func NewStaticParser(context interfaces.TokenReader, exprParser interfaces.ExpressionParser) *StaticParser {
+	return &StaticParser{
+		context:    context,
+		exprParser: exprParser,
+	}
+}
+
+// Parse reads `static $a = expr, $b;`.
+func (p *StaticParser) Parse() (ast.Stmt, error) {
+	start := p.context.Next().Pos // static keyword
+
+	var vars []ast.StaticVar
+	for {
+		varStart, err := p.context.Expect(token.T_DOLLAR)
+		if err != nil {
+			return nil, err
+		}
+		name, err := p.context.Expect(token.T_IDENT)
+		if err != nil {
+			return nil, err
+		}
+
+		var init ast.Expr
+		if p.context.Peek().Type == token.T_EQ {
+			p.context.Next() // =
+			init, err = p.exprParser.ParseExpression()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		vars = append(vars, ast.StaticVar{
+			Span: p.context.SpanFrom(varStart.Pos),
+			Name: name.Value,
+			Init: init,
+		})
+
+		if p.context.Peek().Type != token.T_COMMA {
+			break
+		}
+		p.context.Next() // ,
+	}
+
+	if _, err := p.context.Expect(token.T_SEMI); err != nil {
+		return nil, err
+	}
+
+	return &ast.StaticStmt{Span: p.context.SpanFrom(start), Vars: vars}, nil
+}
@@ -22,7 +22,7 @@ func NewSwitchParser(context interfaces.TokenReader, exprParser interfaces.Expre
 }
 
 func (p *SwitchParser) Parse() (ast.Stmt, error) {
-	p.context.Next()
+	start := p.context.Next().Pos
 
 	_, err := p.context.Expect(token.T_LPAREN)
 	if err != nil {
@@ -48,6 +48,7 @@ func (p *SwitchParser) Parse() (ast.Stmt, error) {
 	for p.context.Peek().Type != token.T_RBRACE && p.context.Peek().Type != token.T_EOF {
 		var caseExpr ast.Expr
 		var caseStmts []ast.Stmt
+		caseStart := p.context.Peek().Pos
 
 		if p.context.Peek().Type == token.T_CASE {
 			p.context.Next()
@@ -73,7 +74,7 @@ func (p *SwitchParser) Parse() (ast.Stmt, error) {
 			return nil, &token.UnexpectedTokenError{
 				Expected: "case или default",
 				Found:    p.context.Peek(),
-				Pos:      p.context.GetPos(),
+				Pos:      p.context.Peek().Pos,
 			}
 		}
 
@@ -91,6 +92,7 @@ func (p *SwitchParser) Parse() (ast.Stmt, error) {
 		}
 
 		cases = append(cases, ast.CaseStmt{
+			Span:  p.context.SpanFrom(caseStart),
 			Expr:  caseExpr,
 			Stmts: caseStmts,
 		})
@@ -102,6 +104,7 @@ func (p *SwitchParser) Parse() (ast.Stmt, error) {
 	}
 
 	return &ast.SwitchStmt{
+		Span:  p.context.SpanFrom(start),
 		Expr:  expr,
 		Cases: cases,
 	}, nil
@@ -0,0 +1,121 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package stmt
+
+import (
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/diag"
+	"github.com/neokofg/php-compiler/internal/parser/interfaces"
+	"github.com/neokofg/php-compiler/internal/token"
+)
+
+type TryParser struct {
+	context     interfaces.TokenReader
+	exprParser  interfaces.ExpressionParser
+	blockParser *BlockParser
+}
+
+func NewTryParser(context interfaces.TokenReader, exprParser interfaces.ExpressionParser, blockParser *BlockParser) *TryParser {
+	return &TryParser{
+		context:     context,
+		exprParser:  exprParser,
+		blockParser: blockParser,
+	}
+}
+
+// Parse reads try { } followed by any number of catch (A | B $e) { }
+// clauses and an optional finally { }. At least one of them is required.
+func (p *TryParser) Parse() (ast.Stmt, error) {
+	tryToken := p.context.Next() // try keyword
+
+	body, err := p.blockParser.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := &ast.TryStmt{Body: body}
+	for p.context.Peek().Type == token.T_CATCH {
+		clause, err := p.parseCatch()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Catches = append(stmt.Catches, clause)
+	}
+
+	if p.context.Peek().Type == token.T_FINALLY {
+		p.context.Next() // finally keyword
+		finally, err := p.blockParser.Parse()
+		if err != nil {
+			return nil, err
+		}
+		if finally == nil {
+			finally = []ast.Stmt{}
+		}
+		stmt.Finally = finally
+	}
+
+	if stmt.Catches == nil && stmt.Finally == nil {
+		return nil, token.ErrorAt(diag.ErrSyntax, tryToken, "cannot use try without catch or finally")
+	}
+
+	stmt.Span = p.context.SpanFrom(tryToken.Pos)
+	return stmt, nil
+}
+
+func (p *TryParser) parseCatch() (ast.CatchClause, error) {
+	start := p.context.Next().Pos // catch keyword
+
+	if _, err := p.context.Expect(token.T_LPAREN); err != nil {
+		return ast.CatchClause{}, err
+	}
+
+	var clause ast.CatchClause
+	for {
+		name, err := p.context.Expect(token.T_IDENT)
+		if err != nil {
+			return ast.CatchClause{}, err
+		}
+		clause.Types = append(clause.Types, name.Value)
+
+		if p.context.Peek().Type != token.T_BIT_OR {
+			break
+		}
+		p.context.Next() // |
+	}
+
+	if p.context.Peek().Type == token.T_DOLLAR {
+		p.context.Next() // $
+		name, err := p.context.Expect(token.T_IDENT)
+		if err != nil {
+			return ast.CatchClause{}, err
+		}
+		clause.Var = name.Value
+	}
+
+	if _, err := p.context.Expect(token.T_RPAREN); err != nil {
+		return ast.CatchClause{}, err
+	}
+
+	body, err := p.blockParser.Parse()
+	if err != nil {
+		return ast.CatchClause{}, err
+	}
+	clause.Body = body
+	clause.Span = p.context.SpanFrom(start)
+	return clause, nil
+}
+
+// parseThrow reads throw expr;.
+func (p *TryParser) parseThrow() (ast.Stmt, error) {
+	start := p.context.Next().Pos // throw keyword
+
+	expr, err := p.exprParser.ParseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.context.Expect(token.T_SEMI); err != nil {
+		return nil, err
+	}
+
+	return &ast.ThrowStmt{Span: p.context.SpanFrom(start), Expr: expr}, nil
+}
@@ -0,0 +1,41 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package stmt
+
+import (
+	"github.com/neokofg/php-compiler/internal/ast"
+	"github.com/neokofg/php-compiler/internal/diag"
+	"github.com/neokofg/php-compiler/internal/parser/interfaces"
+	"github.com/neokofg/php-compiler/internal/token"
+)
+
+type UnsetParser struct {
+	context    interfaces.TokenReader
+	exprParser interfaces.ExpressionParser
+}
+
+func NewUnsetParser(context interfaces.TokenReader, exprParser interfaces.ExpressionParser) *UnsetParser {
+	return &UnsetParser{
+		context:    context,
+		exprParser: exprParser,
+	}
+}
+
+// Parse reads `unset($a, $b['k'], $o->p);`. Whether each operand can be
+// unset is left to the compiler.
+func (p *UnsetParser) Parse() (ast.Stmt, error) {
+	tok := p.context.Next() // unset
+
+	vars, err := p.exprParser.ParseArguments()
+	if err != nil {
+		return nil, err
+	}
+	if len(vars) == 0 {
+		return nil, token.ErrorAt(diag.ErrSyntax, tok, "unset() expects at least one variable")
+	}
+
+	if _, err := p.context.Expect(token.T_SEMI); err != nil {
+		return nil, err
+	}
+
+	return &ast.UnsetStmt{Span: p.context.SpanFrom(tok.Pos), Vars: vars}, nil
+}
@@ -22,7 +22,7 @@ func NewWhileParser(context interfaces.TokenReader, exprParser interfaces.Expres
 }
 
 func (p *WhileParser) Parse() (ast.Stmt, error) {
-	p.context.Next() // while
+	start := p.context.Next().Pos // while
 
 	_, err := p.context.Expect(token.T_LPAREN)
 	if err != nil {
@@ -44,5 +44,5 @@ func (p *WhileParser) Parse() (ast.Stmt, error) {
 		return nil, err
 	}
 
-	return &ast.WhileStmt{Cond: condExpr, Body: bodyBlock}, nil
+	return &ast.WhileStmt{Span: p.context.SpanFrom(start), Cond: condExpr, Body: bodyBlock}, nil
 }
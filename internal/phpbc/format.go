@@ -0,0 +1,67 @@
+// PHP Compiler - compiles php code to IR and then running it on PHPC VM
+// Copyright (C) 2025  Andrey Vasilev (neokofg)
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package phpbc reads and writes compiled programs in the .phpbc container format.
+//
+// All integers are little-endian. A file is laid out as:
+//
+//	magic         [4]byte  "PHBC"
+//	version       uint16   FormatVersion
+//	section count uint16
+//	sections      section id (uint8), payload length (uint32), payload
+//
+// Readers skip sections with unknown ids, so new sections can be added
+// without breaking older tools. Changing the layout of an existing section
+// requires bumping FormatVersion.
+package phpbc
+
+import (
+	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/compiler/constant"
+	"github.com/neokofg/php-compiler/internal/compiler/function"
+)
+
+const (
+	Magic         = "PHBC"
+	FormatVersion = 2
+)
+
+const (
+	SectionConstants byte = 0x01
+	SectionFunctions byte = 0x02
+	SectionVariables byte = 0x03
+	SectionCode      byte = 0x04
+	SectionLocals    byte = 0x05 // local variable names, keyed by function address
+	SectionHandlers  byte = 0x06 // exception table
+	SectionLines     byte = 0x07 // source file name and line table
+)
+
+const (
+	constInt    byte = 0x01
+	constString byte = 0x02
+	constFloat  byte = 0x03
+	constBool   byte = 0x04 // followed by one byte, 0 or 1; since version 2
+)
+
+type Program struct {
+	Constants []constant.Constant
+	Functions []function.Function
+	Variables map[string]int
+	Code      []byte
+	Handlers  []bytecode.Handler
+	File      string
+	Lines     []bytecode.Line
+}
@@ -0,0 +1,109 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package phpbc
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/compiler/constant"
+	"github.com/neokofg/php-compiler/internal/compiler/function"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		program *Program
+	}{
+		{
+			name: "minimal",
+			program: &Program{
+				Variables: map[string]int{},
+				Code:      []byte{0xFF},
+			},
+		},
+		{
+			name: "full",
+			program: &Program{
+				Constants: []constant.Constant{
+					{Type: "int", Value: "42"},
+					{Type: "int", Value: "-9223372036854775808"},
+					{Type: "float", Value: "3.25"},
+					{Type: "string", Value: "hello"},
+					{Type: "string", Value: ""},
+					{Type: "bool", Value: "true"},
+					{Type: "bool", Value: "false"},
+				},
+				Functions: []function.Function{
+					{Name: "add", ParamCount: 2, Address: 12, Locals: []string{"a", "b", "sum"}},
+					{Name: "noop", ParamCount: 0, Address: 40},
+				},
+				Variables: map[string]int{"x": 0, "y": 1, "result": 2},
+				Code:      []byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x30, 0xFF},
+				Handlers: []bytecode.Handler{
+					{Start: 2, End: 8, Target: 10, Function: bytecode.MainFunction, StackDepth: 0},
+					{Start: 14, End: 20, Target: 24, Function: 12, StackDepth: 3},
+				},
+				File: "example.php",
+				Lines: []bytecode.Line{
+					{Addr: 0, Line: 1},
+					{Addr: 5, Line: 3},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, tt.program); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+
+			got, err := Read(&buf)
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.program) {
+				t.Errorf("round trip mismatch\n got: %+v\nwant: %+v", got, tt.program)
+			}
+		})
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "bad magic", data: []byte("ELF\x7F\x02\x00\x00\x00")},
+		{name: "bad version", data: []byte("PHBC\x63\x00\x00\x00")},
+		{name: "missing code", data: []byte("PHBC\x02\x00\x00\x00")},
+		{name: "truncated section", data: []byte("PHBC\x02\x00\x01\x00\x04\x10\x00\x00\x00\xFF")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(bytes.NewReader(tt.data))
+			var formatErr *FormatError
+			if !errors.As(err, &formatErr) {
+				t.Fatalf("Read: got error %v, want *FormatError", err)
+			}
+		})
+	}
+}
+
+func TestWriteInvalidConstant(t *testing.T) {
+	program := &Program{
+		Constants: []constant.Constant{{Type: "int", Value: "not a number"}},
+		Code:      []byte{0xFF},
+	}
+
+	if err := Write(&bytes.Buffer{}, program); err == nil {
+		t.Fatal("Write: expected an error for a malformed int constant")
+	}
+}
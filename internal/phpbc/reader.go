@@ -0,0 +1,235 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package phpbc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/compiler/constant"
+	"github.com/neokofg/php-compiler/internal/compiler/function"
+)
+
+type FormatError struct {
+	Msg string
+}
+
+func (e *FormatError) Error() string {
+	return fmt.Sprintf("invalid bytecode file: %s", e.Msg)
+}
+
+func Read(r io.Reader) (*Program, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &decoder{data: data}
+
+	if string(d.bytes(len(Magic))) != Magic {
+		return nil, &FormatError{Msg: "bad magic, not a .phpbc file"}
+	}
+
+	version := d.uint16()
+	if d.err == nil && version != FormatVersion {
+		return nil, &FormatError{Msg: fmt.Sprintf("unsupported format version %d, expected %d", version, FormatVersion)}
+	}
+
+	program := &Program{Variables: make(map[string]int)}
+	seen := make(map[byte]bool)
+	locals := make(map[int][]string)
+
+	sectionCount := d.uint16()
+	for i := 0; i < int(sectionCount) && d.err == nil; i++ {
+		id := d.byte()
+		payload := &decoder{data: d.bytes(int(d.uint32()))}
+		if d.err != nil {
+			break
+		}
+
+		if seen[id] {
+			return nil, &FormatError{Msg: fmt.Sprintf("duplicate section 0x%02X", id)}
+		}
+		seen[id] = true
+
+		switch id {
+		case SectionConstants:
+			decodeConstants(payload, program)
+		case SectionFunctions:
+			decodeFunctions(payload, program)
+		case SectionVariables:
+			decodeVariables(payload, program)
+		case SectionCode:
+			program.Code = payload.data
+		case SectionLocals:
+			decodeLocals(payload, locals)
+		case SectionHandlers:
+			decodeHandlers(payload, program)
+		case SectionLines:
+			decodeLines(payload, program)
+		default:
+			continue
+		}
+
+		if payload.err != nil {
+			return nil, &FormatError{Msg: fmt.Sprintf("section 0x%02X: %v", id, payload.err)}
+		}
+	}
+
+	if d.err != nil {
+		return nil, &FormatError{Msg: d.err.Error()}
+	}
+
+	if !seen[SectionCode] {
+		return nil, &FormatError{Msg: "missing code section"}
+	}
+
+	// Sections may come in any order, so locals are attached last.
+	for i := range program.Functions {
+		program.Functions[i].Locals = locals[program.Functions[i].Address]
+	}
+
+	return program, nil
+}
+
+func decodeConstants(d *decoder, program *Program) {
+	count := d.uint32()
+	for i := 0; i < int(count) && d.err == nil; i++ {
+		switch tag := d.byte(); tag {
+		case constInt:
+			num := int64(d.uint64())
+			program.Constants = append(program.Constants, constant.Constant{Type: "int", Value: strconv.FormatInt(num, 10)})
+		case constFloat:
+			num := math.Float64frombits(d.uint64())
+			program.Constants = append(program.Constants, constant.Constant{Type: "float", Value: strconv.FormatFloat(num, 'g', -1, 64)})
+		case constString:
+			program.Constants = append(program.Constants, constant.Constant{Type: "string", Value: d.string()})
+		case constBool:
+			program.Constants = append(program.Constants, constant.Constant{Type: "bool", Value: strconv.FormatBool(d.byte() != 0)})
+		default:
+			if d.err == nil {
+				d.err = fmt.Errorf("constant %d: unknown type tag 0x%02X", i, tag)
+			}
+		}
+	}
+}
+
+func decodeFunctions(d *decoder, program *Program) {
+	count := d.uint32()
+	for i := 0; i < int(count) && d.err == nil; i++ {
+		name := d.string()
+		paramCount := d.uint16()
+		address := d.uint32()
+		program.Functions = append(program.Functions, function.Function{
+			Name:       name,
+			ParamCount: int(paramCount),
+			Address:    int(address),
+		})
+	}
+}
+
+func decodeLocals(d *decoder, locals map[int][]string) {
+	count := d.uint32()
+	for i := 0; i < int(count) && d.err == nil; i++ {
+		address := int(d.uint32())
+		nameCount := d.uint32()
+		var names []string
+		for j := 0; j < int(nameCount) && d.err == nil; j++ {
+			names = append(names, d.string())
+		}
+		locals[address] = names
+	}
+}
+
+func decodeHandlers(d *decoder, program *Program) {
+	count := d.uint32()
+	for i := 0; i < int(count) && d.err == nil; i++ {
+		program.Handlers = append(program.Handlers, bytecode.Handler{
+			Start:      int(d.uint32()),
+			End:        int(d.uint32()),
+			Target:     int(d.uint32()),
+			Function:   int(int32(d.uint32())),
+			StackDepth: int(d.uint32()),
+		})
+	}
+}
+
+func decodeLines(d *decoder, program *Program) {
+	program.File = d.string()
+	count := d.uint32()
+	for i := 0; i < int(count) && d.err == nil; i++ {
+		program.Lines = append(program.Lines, bytecode.Line{
+			Addr: int(d.uint32()),
+			Line: int(d.uint32()),
+		})
+	}
+}
+
+func decodeVariables(d *decoder, program *Program) {
+	count := d.uint32()
+	for i := 0; i < int(count) && d.err == nil; i++ {
+		index := d.uint32()
+		name := d.string()
+		program.Variables[name] = int(index)
+	}
+}
+
+// decoder reads little-endian values from a byte slice.
+// The first out-of-bounds read sets err and turns later reads into no-ops.
+type decoder struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (d *decoder) bytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || d.pos+n > len(d.data) {
+		d.err = fmt.Errorf("unexpected end of data at offset %d", d.pos)
+		return nil
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b
+}
+
+func (d *decoder) byte() byte {
+	b := d.bytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (d *decoder) uint16() uint16 {
+	b := d.bytes(2)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(b)
+}
+
+func (d *decoder) uint32() uint32 {
+	b := d.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+func (d *decoder) uint64() uint64 {
+	b := d.bytes(8)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes(int(d.uint32())))
+}
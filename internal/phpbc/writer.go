@@ -0,0 +1,168 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package phpbc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+func Write(w io.Writer, program *Program) error {
+	sections := []struct {
+		id     byte
+		encode func(*bytes.Buffer, *Program) error
+	}{
+		{SectionConstants, encodeConstants},
+		{SectionFunctions, encodeFunctions},
+		{SectionVariables, encodeVariables},
+		{SectionCode, encodeCode},
+		{SectionLocals, encodeLocals},
+		{SectionHandlers, encodeHandlers},
+		{SectionLines, encodeLines},
+	}
+
+	var out bytes.Buffer
+	out.WriteString(Magic)
+	binary.Write(&out, binary.LittleEndian, uint16(FormatVersion))
+	binary.Write(&out, binary.LittleEndian, uint16(len(sections)))
+
+	for _, section := range sections {
+		var payload bytes.Buffer
+		if err := section.encode(&payload, program); err != nil {
+			return err
+		}
+
+		out.WriteByte(section.id)
+		binary.Write(&out, binary.LittleEndian, uint32(payload.Len()))
+		out.Write(payload.Bytes())
+	}
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+func encodeConstants(buf *bytes.Buffer, program *Program) error {
+	binary.Write(buf, binary.LittleEndian, uint32(len(program.Constants)))
+
+	for i, c := range program.Constants {
+		switch c.Type {
+		case "int":
+			num, err := strconv.ParseInt(c.Value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("constant %d: cannot convert '%s' to integer: %v", i, c.Value, err)
+			}
+			buf.WriteByte(constInt)
+			binary.Write(buf, binary.LittleEndian, num)
+		case "float":
+			num, err := strconv.ParseFloat(c.Value, 64)
+			if err != nil {
+				return fmt.Errorf("constant %d: cannot convert '%s' to float: %v", i, c.Value, err)
+			}
+			buf.WriteByte(constFloat)
+			binary.Write(buf, binary.LittleEndian, math.Float64bits(num))
+		case "string":
+			buf.WriteByte(constString)
+			writeString(buf, c.Value)
+		case "bool":
+			b, err := strconv.ParseBool(c.Value)
+			if err != nil {
+				return fmt.Errorf("constant %d: cannot convert '%s' to bool: %v", i, c.Value, err)
+			}
+			buf.WriteByte(constBool)
+			if b {
+				buf.WriteByte(1)
+			} else {
+				buf.WriteByte(0)
+			}
+		default:
+			return fmt.Errorf("constant %d: unknown constant type '%s'", i, c.Type)
+		}
+	}
+
+	return nil
+}
+
+func encodeFunctions(buf *bytes.Buffer, program *Program) error {
+	binary.Write(buf, binary.LittleEndian, uint32(len(program.Functions)))
+
+	for _, function := range program.Functions {
+		writeString(buf, function.Name)
+		binary.Write(buf, binary.LittleEndian, uint16(function.ParamCount))
+		binary.Write(buf, binary.LittleEndian, uint32(function.Address))
+	}
+
+	return nil
+}
+
+func encodeVariables(buf *bytes.Buffer, program *Program) error {
+	names := make([]string, 0, len(program.Variables))
+	for name := range program.Variables {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return program.Variables[names[i]] < program.Variables[names[j]]
+	})
+
+	binary.Write(buf, binary.LittleEndian, uint32(len(names)))
+
+	for _, name := range names {
+		binary.Write(buf, binary.LittleEndian, uint32(program.Variables[name]))
+		writeString(buf, name)
+	}
+
+	return nil
+}
+
+func encodeLocals(buf *bytes.Buffer, program *Program) error {
+	binary.Write(buf, binary.LittleEndian, uint32(len(program.Functions)))
+
+	for _, function := range program.Functions {
+		binary.Write(buf, binary.LittleEndian, uint32(function.Address))
+		binary.Write(buf, binary.LittleEndian, uint32(len(function.Locals)))
+		for _, name := range function.Locals {
+			writeString(buf, name)
+		}
+	}
+
+	return nil
+}
+
+func encodeHandlers(buf *bytes.Buffer, program *Program) error {
+	binary.Write(buf, binary.LittleEndian, uint32(len(program.Handlers)))
+
+	for _, h := range program.Handlers {
+		binary.Write(buf, binary.LittleEndian, uint32(h.Start))
+		binary.Write(buf, binary.LittleEndian, uint32(h.End))
+		binary.Write(buf, binary.LittleEndian, uint32(h.Target))
+		binary.Write(buf, binary.LittleEndian, int32(h.Function))
+		binary.Write(buf, binary.LittleEndian, uint32(h.StackDepth))
+	}
+
+	return nil
+}
+
+func encodeLines(buf *bytes.Buffer, program *Program) error {
+	writeString(buf, program.File)
+	binary.Write(buf, binary.LittleEndian, uint32(len(program.Lines)))
+
+	for _, line := range program.Lines {
+		binary.Write(buf, binary.LittleEndian, uint32(line.Addr))
+		binary.Write(buf, binary.LittleEndian, uint32(line.Line))
+	}
+
+	return nil
+}
+
+func encodeCode(buf *bytes.Buffer, program *Program) error {
+	buf.Write(program.Code)
+	return nil
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	binary.Write(buf, binary.LittleEndian, uint32(len(s)))
+	buf.WriteString(s)
+}
@@ -0,0 +1,130 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package token
+
+import "strconv"
+
+var tokenNames = map[TokenType]string{
+	T_EOF:               "end of file",
+	T_ILLEGAL:           "illegal token",
+	T_IDENT:             "identifier",
+	T_NUMBER:            "number",
+	T_FLOAT:             "floating-point number",
+	T_STRING:            "string literal",
+	T_STRING_START:      "start of string",
+	T_STRING_PART:       "string text",
+	T_STRING_END:        "end of string",
+	T_CURLY_OPEN:        "'{$'",
+	T_DOLLAR_OPEN_CURLY: "'${'",
+	T_PLUS:              "'+'",
+	T_MINUS:             "'-'",
+	T_STAR:              "'*'",
+	T_SLASH:             "'/'",
+	T_EQ:                "'='",
+	T_EQEQ:              "'=='",
+	T_GT:                "'>'",
+	T_LT:                "'<'",
+	T_AND:               "'&&'",
+	T_OR:                "'||'",
+	T_NOT:               "'!'",
+	T_NOTEQ:             "'!='",
+	T_GTE:               "'>='",
+	T_LTE:               "'<='",
+	T_EQEQEQ:            "'==='",
+	T_NOTEQEQ:           "'!=='",
+	T_SEMI:              "';'",
+	T_DOLLAR:            "'$'",
+	T_LPAREN:            "'('",
+	T_RPAREN:            "')'",
+	T_LBRACE:            "'{'",
+	T_RBRACE:            "'}'",
+	T_COLON:             "':'",
+	T_COMMA:             "','",
+	T_LBRACKET:          "'['",
+	T_RBRACKET:          "']'",
+	T_OBJECT_OPERATOR:   "'->'",
+	T_DOUBLE_ARROW:      "'=>'",
+	T_DOUBLE_COLON:      "'::'",
+	T_QUESTION:          "'?'",
+	T_ECHO:              "'echo'",
+	T_IF:                "'if'",
+	T_ELSE:              "'else'",
+	T_WHILE:             "'while'",
+	T_FOR:               "'for'",
+	T_BREAK:             "'break'",
+	T_CONTINUE:          "'continue'",
+	T_DO:                "'do'",
+	T_SWITCH:            "'switch'",
+	T_CASE:              "'case'",
+	T_DEFAULT:           "'default'",
+	T_FUNCTION:          "'function'",
+	T_FN:                "'fn'",
+	T_RETURN:            "'return'",
+	T_GLOBAL:            "'global'",
+	T_STATIC:            "'static'",
+	T_ARRAY:             "'array'",
+	T_FOREACH:           "'foreach'",
+	T_AS:                "'as'",
+	T_CLASS:             "'class'",
+	T_NEW:               "'new'",
+	T_PUBLIC:            "'public'",
+	T_PROTECTED:         "'protected'",
+	T_PRIVATE:           "'private'",
+	T_VAR:               "'var'",
+	T_ABSTRACT:          "'abstract'",
+	T_FINAL:             "'final'",
+	T_INTERFACE:         "'interface'",
+	T_TRAIT:             "'trait'",
+	T_EXTENDS:           "'extends'",
+	T_IMPLEMENTS:        "'implements'",
+	T_USE:               "'use'",
+	T_INSTEADOF:         "'insteadof'",
+	T_TRY:               "'try'",
+	T_CATCH:             "'catch'",
+	T_FINALLY:           "'finally'",
+	T_THROW:             "'throw'",
+	T_ISSET:             "'isset'",
+	T_EMPTY:             "'empty'",
+	T_UNSET:             "'unset'",
+	T_LOGICAL_AND:       "'and'",
+	T_LOGICAL_OR:        "'or'",
+	T_LOGICAL_XOR:       "'xor'",
+	T_INSTANCEOF:        "'instanceof'",
+	T_TRUE:              "'true'",
+	T_FALSE:             "'false'",
+	T_NULL:              "'null'",
+	T_DOT:               "'.'",
+	T_COALESCE:          "'??'",
+	T_SPACESHIP:         "'<=>'",
+	T_INC:               "'++'",
+	T_DEC:               "'--'",
+	T_PLUS_EQ:           "'+='",
+	T_MINUS_EQ:          "'-='",
+	T_MUL_EQ:            "'*='",
+	T_DIV_EQ:            "'/='",
+	T_MOD_EQ:            "'%='",
+	T_DOT_EQ:            "'.='",
+	T_COALESCE_EQ:       "'??='",
+	T_BIT_AND_EQ:        "'&='",
+	T_BIT_OR_EQ:         "'|='",
+	T_BIT_XOR_EQ:        "'^='",
+	T_LSHIFT_EQ:         "'<<='",
+	T_RSHIFT_EQ:         "'>>='",
+	T_BIT_AND:           "'&'",
+	T_BIT_OR:            "'|'",
+	T_BIT_XOR:           "'^'",
+	T_BIT_NOT:           "'~'",
+	T_LSHIFT:            "'<<'",
+	T_RSHIFT:            "'>>'",
+	T_MOD:               "'%'",
+	T_POW:               "'**'",
+	T_POW_EQ:            "'**='",
+}
+
+// String returns the source spelling of the token, or a description
+// for tokens such as identifiers that have no fixed spelling.
+func (t TokenType) String() string {
+	if name, ok := tokenNames[t]; ok {
+		return name
+	}
+	return "token(" + strconv.Itoa(int(t)) + ")"
+}
@@ -0,0 +1,33 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package token
+
+import "fmt"
+
+// Position is a location in a source file. Line and Column are 1-based,
+// Column and Offset count runes. The zero Position is invalid.
+type Position struct {
+	Filename string
+	Offset   int
+	Line     int
+	Column   int
+}
+
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String formats the position as "file:line:col", omitting the parts
+// that are unknown.
+func (p Position) String() string {
+	s := p.Filename
+	if p.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	if s == "" {
+		s = "-"
+	}
+	return s
+}
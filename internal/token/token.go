@@ -3,18 +3,47 @@ package token
 
 import "fmt"
 
+// Token is a single lexeme. Pos is where it starts, End is the position
+// right after its last character.
 type Token struct {
 	Type  TokenType
 	Value string
+	Pos   Position
+	End   Position
 }
 
 type UnexpectedTokenError struct {
 	Expected string
 	Found    Token
-	Pos      int
+	Pos      Position
 }
 
 func (e *UnexpectedTokenError) Error() string {
-	return fmt.Sprintf("Position %d: expected %s, but found %v (%q)",
+	return fmt.Sprintf("%s: expected %s, but found %v (%q)",
 		e.Pos, e.Expected, e.Found.Type, e.Found.Value)
 }
+
+// SyntaxError is an error reported by the lexer or the parser.
+// Pos and End span the offending token, Code is a diagnostic code.
+type SyntaxError struct {
+	Code string
+	Pos  Position
+	End  Position
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// ErrorAt reports a syntax error spanning tok.
+func ErrorAt(code string, tok Token, format string, args ...interface{}) error {
+	return &SyntaxError{Code: code, Pos: tok.Pos, End: tok.End, Msg: fmt.Sprintf(format, args...)}
+}
+
+// IsName reports whether the token can name a class member: an identifier
+// or any keyword, since PHP accepts both after -> and in method declarations.
+// Keywords are the token types from T_ECHO to T_NULL.
+func (t Token) IsName() bool {
+	return t.Type == T_IDENT || (t.Type >= T_ECHO && t.Type <= T_NULL)
+}
@@ -11,8 +11,16 @@ const (
 	// -- Lexems --
 	T_IDENT
 	T_NUMBER
+	T_FLOAT
 	T_STRING
 
+	// -- Interpolated strings --
+	T_STRING_START      // opening " or heredoc label of a string with variables
+	T_STRING_PART       // literal text between the variables
+	T_STRING_END        // closing " or heredoc marker
+	T_CURLY_OPEN        // {$
+	T_DOLLAR_OPEN_CURLY // ${
+
 	// -- Arithmetic --
 	T_PLUS  // +
 	T_MINUS // -
@@ -34,14 +42,20 @@ const (
 	T_NOTEQEQ // !==
 
 	// -- Separators --
-	T_SEMI   // ;
-	T_DOLLAR // $
-	T_LPAREN // (
-	T_RPAREN // )
-	T_LBRACE // {
-	T_RBRACE // }
-	T_COLON  // :
-	T_COMMA  // ,
+	T_SEMI            // ;
+	T_DOLLAR          // $
+	T_LPAREN          // (
+	T_RPAREN          // )
+	T_LBRACE          // {
+	T_RBRACE          // }
+	T_COLON           // :
+	T_COMMA           // ,
+	T_LBRACKET        // [
+	T_RBRACKET        // ]
+	T_OBJECT_OPERATOR // ->
+	T_DOUBLE_ARROW    // =>
+	T_DOUBLE_COLON    // ::
+	T_QUESTION        // ?
 
 	// -- Statements --
 	T_ECHO     // echo
@@ -56,26 +70,74 @@ const (
 	T_CASE     // case
 	T_DEFAULT  // default
 	T_FUNCTION // function
+	T_FN       // fn
 	T_RETURN   // return
+	T_GLOBAL   // global
+	T_STATIC   // static
+	T_ARRAY    // array
+	T_FOREACH  // foreach
+	T_AS       // as
+
+	// -- Classes --
+	T_CLASS      // class
+	T_NEW        // new
+	T_PUBLIC     // public
+	T_PROTECTED  // protected
+	T_PRIVATE    // private
+	T_VAR        // var
+	T_ABSTRACT   // abstract
+	T_FINAL      // final
+	T_INTERFACE  // interface
+	T_TRAIT      // trait
+	T_EXTENDS    // extends
+	T_IMPLEMENTS // implements
+	T_USE        // use
+	T_INSTEADOF  // insteadof
+
+	// -- Exceptions --
+	T_TRY     // try
+	T_CATCH   // catch
+	T_FINALLY // finally
+	T_THROW   // throw
+
+	// -- Language constructs --
+	T_ISSET // isset
+	T_EMPTY // empty
+	T_UNSET // unset
+
+	// -- Keyword operators --
+	T_LOGICAL_AND // and
+	T_LOGICAL_OR  // or
+	T_LOGICAL_XOR // xor
+	T_INSTANCEOF  // instanceof
 
 	// -- Literals --
 	T_TRUE  // true
 	T_FALSE // false
+	T_NULL  // null
 
 	// -- Binary Operators --
-	T_DOT // .
+	T_DOT       // .
+	T_COALESCE  // ??
+	T_SPACESHIP // <=>
 
 	// -- Unary operators --
 	T_INC // ++
 	T_DEC // --
 
 	// -- Compound assignment operators ---
-	T_PLUS_EQ  // +=
-	T_MINUS_EQ // -=
-	T_MUL_EQ   // *=
-	T_DIV_EQ   // /=
-	T_MOD_EQ   // %=
-	T_DOT_EQ   // .=
+	T_PLUS_EQ     // +=
+	T_MINUS_EQ    // -=
+	T_MUL_EQ      // *=
+	T_DIV_EQ      // /=
+	T_MOD_EQ      // %=
+	T_DOT_EQ      // .=
+	T_COALESCE_EQ // ??=
+	T_BIT_AND_EQ  // &=
+	T_BIT_OR_EQ   // |=
+	T_BIT_XOR_EQ  // ^=
+	T_LSHIFT_EQ   // <<=
+	T_RSHIFT_EQ   // >>=
 
 	// -- Bitwise operators --
 	T_BIT_AND // &
@@ -87,4 +149,8 @@ const (
 
 	// -- Modulo --
 	T_MOD // %
+
+	// -- Exponentiation --
+	T_POW    // **
+	T_POW_EQ // **=
 )
@@ -0,0 +1,201 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package vm
+
+import (
+	"math"
+
+	"github.com/neokofg/php-compiler/internal/vm/value"
+)
+
+func binaryIntOp(vm *VM, op func(a, b int64) int64) error {
+	a, b, err := vm.pop2()
+	if err != nil {
+		return err
+	}
+
+	return vm.push(value.NewInt(op(a.ToInt(), b.ToInt())))
+}
+
+// arithmeticOp applies intOp when both operands are ints and floatOp
+// otherwise, promoting the int operand to float. Like PHP, an int result
+// that overflows int64 is computed as a float instead.
+func arithmeticOp(vm *VM, symbol string, intOp func(a, b int64) (int64, bool), floatOp func(a, b float64) float64) error {
+	a, b, err := vm.pop2()
+	if err != nil {
+		return err
+	}
+
+	if err := vm.checkArithmeticOperands(a, b, symbol); err != nil {
+		return err
+	}
+
+	an, bn := a.ToNumber(), b.ToNumber()
+	if an.Type == value.TypeInt && bn.Type == value.TypeInt {
+		if result, ok := intOp(an.Int, bn.Int); ok {
+			return vm.push(value.NewInt(result))
+		}
+	}
+
+	return vm.push(value.NewFloat(floatOp(an.ToFloat(), bn.ToFloat())))
+}
+
+// checkArithmeticOperands rejects objects and arrays, which only support
+// + between two arrays.
+func (vm *VM) checkArithmeticOperands(a, b value.Value, symbol string) error {
+	if a.Type == value.TypeArray || b.Type == value.TypeArray || a.Type == value.TypeObject || b.Type == value.TypeObject {
+		return vm.throwf("TypeError", "Unsupported operand types: %s %s %s", a.TypeName(), symbol, b.TypeName())
+	}
+	return nil
+}
+
+// handleAdd also implements the array union: the elements of the right
+// array whose keys are missing in the left one are added to it.
+func handleAdd(vm *VM) error {
+	if n := len(vm.stack); n >= 2 && vm.stack[n-2].Type == value.TypeArray && vm.stack[n-1].Type == value.TypeArray {
+		a, b, _ := vm.pop2()
+		union := a.Arr.Copy()
+		for i := 0; i < b.Arr.Len(); i++ {
+			k, v := b.Arr.At(i)
+			if _, exists := union.Get(k); !exists {
+				union.Set(k, v)
+			}
+		}
+		return vm.push(value.NewArrayValue(union))
+	}
+
+	return arithmeticOp(vm, "+", value.AddInt, func(a, b float64) float64 { return a + b })
+}
+
+func handleSub(vm *VM) error {
+	return arithmeticOp(vm, "-", value.SubInt, func(a, b float64) float64 { return a - b })
+}
+
+func handleMul(vm *VM) error {
+	return arithmeticOp(vm, "*", value.MulInt, func(a, b float64) float64 { return a * b })
+}
+
+// handleDiv returns an int only when both operands are ints and the
+// division is exact, otherwise a float, as in PHP.
+func handleDiv(vm *VM) error {
+	a, b, err := vm.pop2()
+	if err != nil {
+		return err
+	}
+
+	if err := vm.checkArithmeticOperands(a, b, "/"); err != nil {
+		return err
+	}
+
+	an, bn := a.ToNumber(), b.ToNumber()
+	if bn.ToFloat() == 0 {
+		return vm.throwf("DivisionByZeroError", "Division by zero")
+	}
+
+	if an.Type == value.TypeInt && bn.Type == value.TypeInt && an.Int%bn.Int == 0 && !(an.Int == math.MinInt64 && bn.Int == -1) {
+		return vm.push(value.NewInt(an.Int / bn.Int))
+	}
+
+	return vm.push(value.NewFloat(an.ToFloat() / bn.ToFloat()))
+}
+
+func handleMod(vm *VM) error {
+	a, b, err := vm.pop2()
+	if err != nil {
+		return err
+	}
+
+	if err := vm.checkArithmeticOperands(a, b, "%"); err != nil {
+		return err
+	}
+
+	divisor := b.ToInt()
+	if divisor == 0 {
+		return vm.throwf("DivisionByZeroError", "Modulo by zero")
+	}
+
+	if divisor == -1 {
+		// MinInt64 % -1 overflows in Go; the result is always 0.
+		return vm.push(value.NewInt(0))
+	}
+
+	return vm.push(value.NewInt(a.ToInt() % divisor))
+}
+
+// handlePow stays in ints for an int base and a non-negative int exponent
+// as long as the result fits. A negative exponent gives a float, as in PHP.
+func handlePow(vm *VM) error {
+	a, b, err := vm.pop2()
+	if err != nil {
+		return err
+	}
+
+	if err := vm.checkArithmeticOperands(a, b, "**"); err != nil {
+		return err
+	}
+
+	base, exp := a.ToNumber(), b.ToNumber()
+	if base.Type == value.TypeInt && exp.Type == value.TypeInt && exp.Int >= 0 {
+		if result, ok := value.PowInt(base.Int, exp.Int); ok {
+			return vm.push(value.NewInt(result))
+		}
+	}
+
+	return vm.push(value.NewFloat(math.Pow(base.ToFloat(), exp.ToFloat())))
+}
+
+// step adds delta to the numeric value of v, keeping floats as floats.
+func step(v value.Value, delta int64) value.Value {
+	n := v.ToNumber()
+	if n.Type == value.TypeInt {
+		if result, ok := value.AddInt(n.Int, delta); ok {
+			return value.NewInt(result)
+		}
+	}
+	return value.NewFloat(n.ToFloat() + float64(delta))
+}
+
+func handleInc(vm *VM) error {
+	v, err := vm.pop()
+	if err != nil {
+		return err
+	}
+
+	return vm.push(step(v, 1))
+}
+
+func handleDec(vm *VM) error {
+	v, err := vm.pop()
+	if err != nil {
+		return err
+	}
+
+	return vm.push(step(v, -1))
+}
+
+// handlePostInc leaves the old value below the incremented one,
+// so the following STORE_VAR keeps the old value as the expression result.
+func handlePostInc(vm *VM) error {
+	v, err := vm.pop()
+	if err != nil {
+		return err
+	}
+
+	old := v.ToNumber()
+	if err := vm.push(old); err != nil {
+		return err
+	}
+	return vm.push(step(old, 1))
+}
+
+func handlePostDec(vm *VM) error {
+	v, err := vm.pop()
+	if err != nil {
+		return err
+	}
+
+	old := v.ToNumber()
+	if err := vm.push(old); err != nil {
+		return err
+	}
+	return vm.push(step(old, -1))
+}
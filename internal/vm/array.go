@@ -0,0 +1,314 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package vm
+
+import (
+	"github.com/neokofg/php-compiler/internal/vm/value"
+)
+
+func handleArrayNew(vm *VM) error {
+	return vm.push(value.NewArrayValue(value.NewArray()))
+}
+
+// handleArrayPush appends the value on top of the stack to the array below
+// it, which stays on the stack. It builds array literals.
+func handleArrayPush(vm *VM) error {
+	v, err := vm.pop()
+	if err != nil {
+		return err
+	}
+
+	arr, err := vm.literalArray()
+	if err != nil {
+		return err
+	}
+
+	if err := arr.Append(v); err != nil {
+		return vm.errorf("%v", err)
+	}
+	return nil
+}
+
+// handleArrayInsert is ARRAY_PUSH with an explicit key below the value.
+func handleArrayInsert(vm *VM) error {
+	k, v, err := vm.pop2()
+	if err != nil {
+		return err
+	}
+
+	arr, err := vm.literalArray()
+	if err != nil {
+		return err
+	}
+
+	key, err := value.ToKey(k)
+	if err != nil {
+		return vm.errorf("%v", err)
+	}
+
+	arr.Set(key, v)
+	return nil
+}
+
+func (vm *VM) literalArray() (*value.Array, error) {
+	if len(vm.stack) == 0 || vm.stack[len(vm.stack)-1].Type != value.TypeArray {
+		return nil, vm.errorf("array literal expected on the stack")
+	}
+	return vm.stack[len(vm.stack)-1].Arr, nil
+}
+
+// handleArrayGet pops a key and a container and pushes container[key].
+func handleArrayGet(vm *VM) error {
+	return vm.arrayGet(false)
+}
+
+// handleArrayGetQuiet is ARRAY_GET for isset, empty and ??, which read
+// missing elements as null without a warning.
+func handleArrayGetQuiet(vm *VM) error {
+	return vm.arrayGet(true)
+}
+
+func (vm *VM) arrayGet(quiet bool) error {
+	container, k, err := vm.pop2()
+	if err != nil {
+		return err
+	}
+
+	v, err := vm.readDim(container, k, quiet)
+	if err != nil {
+		return err
+	}
+	return vm.push(v)
+}
+
+// handleArrayFetch reads the element addressed by the container and the
+// keys on the stack without popping them, so that a compound assignment
+// can compute the new value and hand everything to ARRAY_SET.
+func handleArrayFetch(vm *VM) error {
+	return vm.arrayFetch(false)
+}
+
+// handleArrayFetchQuiet is ARRAY_FETCH for ??=, which reads missing
+// elements as null without a warning.
+func handleArrayFetchQuiet(vm *VM) error {
+	return vm.arrayFetch(true)
+}
+
+func (vm *VM) arrayFetch(quiet bool) error {
+	dims, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	if len(vm.stack) < int(dims)+1 {
+		return vm.errorf("Stack underflow, need %d elements, have %d", dims+1, len(vm.stack))
+	}
+
+	operands := vm.stack[len(vm.stack)-int(dims)-1:]
+	v := operands[0]
+	for _, k := range operands[1:] {
+		if v, err = vm.readDim(v, k, quiet); err != nil {
+			return err
+		}
+	}
+
+	return vm.push(v)
+}
+
+// readDim returns container[k]. Missing keys and containers that are not
+// arrays or strings give null with a warning, as in PHP, or without one
+// when quiet is set.
+func (vm *VM) readDim(container, k value.Value, quiet bool) (value.Value, error) {
+	switch container.Type {
+	case value.TypeArray:
+		key, err := value.ToKey(k)
+		if err != nil {
+			return value.Value{}, vm.errorf("%v", err)
+		}
+
+		v, ok := container.Arr.Get(key)
+		if !ok && !quiet {
+			vm.warnf("Undefined array key %s", key)
+		}
+		return v, nil
+
+	case value.TypeString:
+		offset := k.ToInt()
+		if offset < 0 {
+			offset += int64(len(container.Str))
+		}
+		if offset < 0 || offset >= int64(len(container.Str)) {
+			if quiet {
+				return value.NewNull(), nil
+			}
+			vm.warnf("Uninitialized string offset %d", k.ToInt())
+			return value.NewString(""), nil
+		}
+		return value.NewString(container.Str[offset : offset+1]), nil
+
+	case value.TypeObject:
+		return value.Value{}, vm.throwf("Error", "Cannot use object of type %s as array", container.TypeName())
+
+	default:
+		if !quiet {
+			vm.warnf("Trying to access array offset on value of type %s", container.TypeName())
+		}
+		return value.NewNull(), nil
+	}
+}
+
+// handleArraySet writes into nested arrays: it pops the value, the keys of
+// every dimension that is not an append (`[]`) and the container, and
+// pushes the updated container for the following store.
+func handleArraySet(vm *VM) error {
+	dims, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	appendMask, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	keyCount := 0
+	for i := 0; i < int(dims); i++ {
+		if appendMask&(1<<i) == 0 {
+			keyCount++
+		}
+	}
+
+	if len(vm.stack) < keyCount+2 {
+		return vm.errorf("Stack underflow, need %d elements, have %d", keyCount+2, len(vm.stack))
+	}
+
+	operands := vm.stack[len(vm.stack)-keyCount-2:]
+	container, keys, v := operands[0], operands[1:keyCount+1], operands[keyCount+1]
+	vm.stack = vm.stack[:len(vm.stack)-keyCount-2]
+
+	dimKeys := make([]*value.Value, dims)
+	for i := range dimKeys {
+		if appendMask&(1<<i) == 0 {
+			dimKeys[i] = &keys[0]
+			keys = keys[1:]
+		}
+	}
+
+	// Holding v while writing makes $a[0] = $a copy $a first.
+	value.Retain(v)
+	result, err := vm.writeDim(container, dimKeys, v)
+	value.Release(v)
+	if err != nil {
+		return err
+	}
+
+	return vm.push(result)
+}
+
+// writeDim stores v at keys inside container and returns the container to
+// store back. A nil key appends. Null containers become arrays, and arrays
+// that are shared are copied before the write.
+func (vm *VM) writeDim(container value.Value, keys []*value.Value, v value.Value) (value.Value, error) {
+	switch {
+	case container.Type == value.TypeNull, container.Type == value.TypeBool && !container.Bool:
+		container = value.NewArrayValue(value.NewArray())
+	case container.Type == value.TypeString:
+		return value.Value{}, vm.errorf("Writing to string offsets is not supported")
+	case container.Type == value.TypeObject:
+		return value.Value{}, vm.throwf("Error", "Cannot use object of type %s as array", container.TypeName())
+	case container.Type != value.TypeArray:
+		return value.Value{}, vm.throwf("Error", "Cannot use a scalar value as an array")
+	}
+
+	arr := container.Arr.Separate()
+
+	var key value.Key
+	var err error
+	if keys[0] != nil {
+		if key, err = value.ToKey(*keys[0]); err != nil {
+			return value.Value{}, vm.errorf("%v", err)
+		}
+	}
+
+	if len(keys) > 1 {
+		inner := value.NewNull()
+		if keys[0] != nil {
+			inner, _ = arr.Get(key)
+		}
+		if v, err = vm.writeDim(inner, keys[1:], v); err != nil {
+			return value.Value{}, err
+		}
+	}
+
+	if keys[0] == nil {
+		if err := arr.Append(v); err != nil {
+			return value.Value{}, vm.errorf("%v", err)
+		}
+	} else {
+		arr.Set(key, v)
+	}
+
+	return value.NewArrayValue(arr), nil
+}
+
+// handleArrayUnset removes an element from nested arrays: it pops the keys
+// of every dimension and the container, and pushes the updated container
+// for the following store.
+func handleArrayUnset(vm *VM) error {
+	dims, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	if dims == 0 || len(vm.stack) < int(dims)+1 {
+		return vm.errorf("Stack underflow, need %d elements, have %d", dims+1, len(vm.stack))
+	}
+
+	operands := vm.stack[len(vm.stack)-int(dims)-1:]
+	container, keys := operands[0], append([]value.Value(nil), operands[1:]...)
+	vm.stack = vm.stack[:len(vm.stack)-int(dims)-1]
+
+	result, err := vm.unsetDim(container, keys)
+	if err != nil {
+		return err
+	}
+	return vm.push(result)
+}
+
+// unsetDim removes the element at keys inside container and returns the
+// container to store back. Missing elements and null containers are left
+// alone; arrays that are shared are copied before the removal.
+func (vm *VM) unsetDim(container value.Value, keys []value.Value) (value.Value, error) {
+	switch container.Type {
+	case value.TypeNull:
+		return container, nil
+	case value.TypeString:
+		return value.Value{}, vm.throwf("Error", "Cannot unset string offsets")
+	case value.TypeObject:
+		return value.Value{}, vm.throwf("Error", "Cannot use object of type %s as array", container.TypeName())
+	case value.TypeArray:
+	default:
+		return value.Value{}, vm.throwf("Error", "Cannot unset offset in a non-array variable")
+	}
+
+	key, err := value.ToKey(keys[0])
+	if err != nil {
+		return value.Value{}, vm.errorf("%v", err)
+	}
+
+	inner, ok := container.Arr.Get(key)
+	if !ok {
+		return container, nil
+	}
+
+	arr := container.Arr.Separate()
+	if len(keys) == 1 {
+		arr.Delete(key)
+		return value.NewArrayValue(arr), nil
+	}
+
+	if inner, err = vm.unsetDim(inner, keys[1:]); err != nil {
+		return value.Value{}, err
+	}
+	arr.Set(key, inner)
+	return value.NewArrayValue(arr), nil
+}
@@ -0,0 +1,165 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package vm
+
+import (
+	"strings"
+
+	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/compiler/function"
+	"github.com/neokofg/php-compiler/internal/native"
+	"github.com/neokofg/php-compiler/internal/vm/value"
+)
+
+// handleClosure creates a Closure object for the function at the address
+// operand. It takes the captured values off the stack and remembers the
+// object and class of the running method, which the closure runs with.
+func handleClosure(vm *VM) error {
+	addr, err := vm.readUint16()
+	if err != nil {
+		return err
+	}
+
+	count, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	if int(addr) >= len(vm.code) || vm.code[addr] != bytecode.OP_FUNC_DECL {
+		return vm.errorf("Invalid closure address %d", addr)
+	}
+	if int(count) > len(vm.stack) {
+		return vm.errorf("CLOSURE expects %d captured values, stack has %d values", count, len(vm.stack))
+	}
+
+	base := len(vm.stack) - int(count)
+	closure := &value.Closure{
+		Addr:     int(addr),
+		Captured: append([]value.Value(nil), vm.stack[base:]...),
+	}
+	vm.stack = vm.stack[:base]
+	for _, v := range closure.Captured {
+		value.Retain(v)
+	}
+
+	if len(vm.frames) > 0 {
+		f := vm.frames[len(vm.frames)-1]
+		closure.This, closure.Scope, closure.Static = f.this, f.scope, f.static
+	}
+
+	obj := value.NewObject(vm.classes[strings.ToLower(native.Closure)])
+	obj.Closure = closure
+	return vm.push(value.NewObjectValue(obj))
+}
+
+// handleCallIndirect calls the closure or the function named by the value
+// below the arguments. The call starts like FUNC_CALL; the captured values
+// of a closure are then copied into the locals that follow the parameters.
+func handleCallIndirect(vm *VM) error {
+	argc, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	if int(argc) >= len(vm.stack) {
+		return vm.errorf("CALL_INDIRECT expects %d arguments and a callee, stack has %d values", argc, len(vm.stack))
+	}
+
+	base := len(vm.stack) - int(argc)
+	callee := vm.stack[base-1]
+	copy(vm.stack[base-1:], vm.stack[base:])
+	vm.stack = vm.stack[:len(vm.stack)-1]
+
+	return vm.enter(callee, int(argc))
+}
+
+// enter enters callee, a closure or the name of a function bound by
+// FUNC_BIND, with the argc arguments on top of the stack.
+func (vm *VM) enter(callee value.Value, argc int) error {
+	switch {
+	case callee.Type == value.TypeString:
+		funcAddr, exists := vm.functions[callee.Str]
+		if !exists {
+			return vm.throwf("Error", "Call to undefined function %s()", callee.Str)
+		}
+		if paramCount := int(vm.code[funcAddr+1]); argc < paramCount {
+			return vm.throwf("ArgumentCountError", "Too few arguments to function %s(), %d passed and exactly %d expected",
+				callee.Str, argc, paramCount)
+		}
+		return vm.callFunction(funcAddr, argc)
+	case callee.Type == value.TypeObject && callee.Obj.Closure == nil:
+		return vm.throwf("Error", "Object of type %s is not callable", callee.Obj.Class.Name)
+	case callee.Type != value.TypeObject:
+		return vm.throwf("Error", "Value not callable")
+	}
+
+	closure := callee.Obj.Closure
+	paramCount := int(vm.code[closure.Addr+1])
+	if argc < paramCount {
+		return vm.throwf("ArgumentCountError", "Too few arguments to function %s(), %d passed and exactly %d expected",
+			function.ClosureName, argc, paramCount)
+	}
+
+	f := frame{
+		addr:       closure.Addr,
+		returnAddr: vm.ip,
+		argCount:   argc,
+		stackBase:  len(vm.stack) - argc,
+		locals:     make([]value.Value, paramCount, paramCount+len(closure.Captured)),
+		scope:      closure.Scope,
+		this:       closure.This,
+		static:     closure.Static,
+	}
+	for i := range f.locals {
+		f.locals[i] = value.NewNull()
+	}
+	for _, v := range closure.Captured {
+		value.Retain(v)
+		f.locals = append(f.locals, v)
+	}
+
+	if err := vm.pushFrame(f); err != nil {
+		return err
+	}
+	vm.ip = closure.Addr
+	return nil
+}
+
+// call calls callable, a closure or the name of a function, with args and
+// runs it to completion. It is how natives call back into the script. An
+// exception the callable does not catch is returned, for the native to
+// pass on to the code that called it.
+func (vm *VM) call(callable value.Value, args []value.Value) (value.Value, error) {
+	if callable.Type == value.TypeString {
+		if fn, ok := native.Lookup(callable.Str); ok {
+			if !fn.Accepts(len(args)) {
+				return value.Value{}, vm.throwf("ArgumentCountError", "%s() does not accept %d arguments", fn.Name, len(args))
+			}
+			return fn.Impl(nativeEnv{vm}, args)
+		}
+	}
+
+	depth := len(vm.frames)
+	for i := len(args) - 1; i >= 0; i-- {
+		if err := vm.push(args[i]); err != nil {
+			return value.Value{}, err
+		}
+	}
+	if err := vm.enter(callable, len(args)); err != nil {
+		vm.stack = vm.stack[:len(vm.stack)-len(args)]
+		return value.Value{}, err
+	}
+
+	for vm.running && len(vm.frames) > depth {
+		err := vm.step()
+		if t, ok := err.(*thrown); ok {
+			err = vm.unwind(t.exception, depth)
+		}
+		if err != nil {
+			return value.Value{}, err
+		}
+	}
+	if !vm.running {
+		return value.NewNull(), nil
+	}
+	return vm.pop()
+}
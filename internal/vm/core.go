@@ -0,0 +1,330 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package vm
+
+import "github.com/neokofg/php-compiler/internal/vm/value"
+
+func handleLoadConst(vm *VM) error {
+	constIdx, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	if int(constIdx) >= len(vm.constants) {
+		return vm.errorf("Invalid constant index %d, max allowed: %d", constIdx, len(vm.constants)-1)
+	}
+
+	return vm.push(vm.constants[constIdx])
+}
+
+func handlePrint(vm *VM) error {
+	v, err := vm.pop()
+	if err != nil {
+		return err
+	}
+
+	if v.Type == value.TypeArray {
+		vm.warnf("Array to string conversion")
+	}
+	if v.Type == value.TypeObject {
+		return vm.objectToStringError(v)
+	}
+
+	_, err = vm.out.WriteString(v.ToString())
+	return err
+}
+
+func handleHalt(vm *VM) error {
+	vm.running = false
+	return nil
+}
+
+func handlePop(vm *VM) error {
+	_, err := vm.pop()
+	return err
+}
+
+func handleDup(vm *VM) error {
+	if len(vm.stack) == 0 {
+		return vm.errorf("Stack underflow")
+	}
+	return vm.push(vm.stack[len(vm.stack)-1])
+}
+
+// handleTuck copies the value on top of the stack under the n values
+// below it, where it stays as the result of an assignment once the write
+// has consumed them.
+func handleTuck(vm *VM) error {
+	n, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	if int(n) >= len(vm.stack) {
+		return vm.errorf("TUCK expects %d values, stack has %d values", int(n)+1, len(vm.stack))
+	}
+
+	top := vm.stack[len(vm.stack)-1]
+	if err := vm.push(top); err != nil {
+		return err
+	}
+
+	pos := len(vm.stack) - 2 - int(n)
+	copy(vm.stack[pos+1:], vm.stack[pos:len(vm.stack)-1])
+	vm.stack[pos] = top
+	return nil
+}
+
+func handleLoadNull(vm *VM) error {
+	return vm.push(value.NewNull())
+}
+
+func handleStoreVar(vm *VM) error {
+	varIdx, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	v, err := vm.pop()
+	if err != nil {
+		return err
+	}
+
+	value.Assign(&vm.variables[varIdx], v)
+	return nil
+}
+
+func handleLoadVar(vm *VM) error {
+	return vm.loadVar(false)
+}
+
+// handleLoadVarQuiet is LOAD_VAR for isset, empty, ?? and writes, which
+// read undefined variables as null without a warning.
+func handleLoadVarQuiet(vm *VM) error {
+	return vm.loadVar(true)
+}
+
+func (vm *VM) loadVar(quiet bool) error {
+	varIdx, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	v := value.Deref(vm.variables[varIdx])
+	if v.Type == value.TypeUndef {
+		if !quiet {
+			vm.warnf("Undefined variable $%s", vm.variableName(int(varIdx)))
+		}
+		v = value.NewNull()
+	}
+	return vm.push(v)
+}
+
+func handleStoreLocal(vm *VM) error {
+	slot, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	v, err := vm.pop()
+	if err != nil {
+		return err
+	}
+
+	return vm.storeLocal(int(slot), v)
+}
+
+func handleLoadLocal(vm *VM) error {
+	return vm.loadLocal(false)
+}
+
+func handleLoadLocalQuiet(vm *VM) error {
+	return vm.loadLocal(true)
+}
+
+func (vm *VM) loadLocal(quiet bool) error {
+	slot, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	f, err := vm.currentFrame()
+	if err != nil {
+		return err
+	}
+
+	v := value.NewUndef()
+	if int(slot) < len(f.locals) {
+		v = value.Deref(f.locals[slot])
+	}
+	if v.Type == value.TypeUndef {
+		if !quiet {
+			vm.warnf("Undefined variable $%s", vm.localName(f.addr, int(slot)))
+		}
+		v = value.NewNull()
+	}
+	return vm.push(v)
+}
+
+// handleRefVar binds a global variable to a reference, which it keeps for
+// good, and pushes the reference for CLOSURE to capture.
+func handleRefVar(vm *VM) error {
+	varIdx, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	return vm.push(makeRef(&vm.variables[varIdx]))
+}
+
+func handleRefLocal(vm *VM) error {
+	slot, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	f, err := vm.currentFrame()
+	if err != nil {
+		return err
+	}
+
+	for len(f.locals) <= int(slot) {
+		f.locals = append(f.locals, value.NewUndef())
+	}
+	return vm.push(makeRef(&f.locals[slot]))
+}
+
+// handleUnsetVar empties a global variable slot. A slot bound to a
+// reference is detached from it; the other variables bound to the
+// reference keep its value.
+func handleUnsetVar(vm *VM) error {
+	varIdx, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	unsetSlot(&vm.variables[varIdx])
+	return nil
+}
+
+func handleUnsetLocal(vm *VM) error {
+	slot, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	f, err := vm.currentFrame()
+	if err != nil {
+		return err
+	}
+
+	if int(slot) < len(f.locals) {
+		unsetSlot(&f.locals[slot])
+	}
+	return nil
+}
+
+// handleBindVar pops a reference and binds a global variable to it, in
+// place of the value or the reference the variable held.
+func handleBindVar(vm *VM) error {
+	varIdx, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	ref, err := vm.pop()
+	if err != nil {
+		return err
+	}
+	if ref.Type != value.TypeReference {
+		return vm.errorf("BIND_VAR expects a reference, got %s", ref.TypeName())
+	}
+
+	value.Bind(&vm.variables[varIdx], ref)
+	return nil
+}
+
+func handleBindLocal(vm *VM) error {
+	slot, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	ref, err := vm.pop()
+	if err != nil {
+		return err
+	}
+	if ref.Type != value.TypeReference {
+		return vm.errorf("BIND_LOCAL expects a reference, got %s", ref.TypeName())
+	}
+
+	f, err := vm.currentFrame()
+	if err != nil {
+		return err
+	}
+
+	for len(f.locals) <= int(slot) {
+		f.locals = append(f.locals, value.NewUndef())
+	}
+	value.Bind(&f.locals[slot], ref)
+	return nil
+}
+
+func unsetSlot(slot *value.Value) {
+	value.Release(*slot)
+	*slot = value.NewUndef()
+}
+
+// makeRef moves the value of a variable slot into a reference, unless the
+// slot holds one already, and returns the reference. An undefined
+// variable becomes null, as binding a reference defines it.
+func makeRef(slot *value.Value) value.Value {
+	if slot.Type == value.TypeUndef {
+		*slot = value.NewNull()
+	}
+	if slot.Type != value.TypeReference {
+		*slot = value.NewReference(*slot)
+	}
+	return *slot
+}
+
+// handleStaticInit runs the initializer of a static variable only the first
+// time it is reached. Later it jumps over the initializer.
+func handleStaticInit(vm *VM) error {
+	varIdx, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	offset, err := vm.readUint16()
+	if err != nil {
+		return err
+	}
+
+	if vm.statics[varIdx] {
+		vm.ip += int(offset)
+		return nil
+	}
+
+	vm.statics[varIdx] = true
+	return nil
+}
+
+func (vm *VM) currentFrame() (*frame, error) {
+	if len(vm.frames) == 0 {
+		return nil, vm.errorf("local variable access outside of a function")
+	}
+	return &vm.frames[len(vm.frames)-1], nil
+}
+
+func (vm *VM) storeLocal(slot int, v value.Value) error {
+	f, err := vm.currentFrame()
+	if err != nil {
+		return err
+	}
+
+	for len(f.locals) <= slot {
+		f.locals = append(f.locals, value.NewUndef())
+	}
+	value.Assign(&f.locals[slot], v)
+	return nil
+}
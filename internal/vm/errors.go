@@ -0,0 +1,29 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package vm
+
+import (
+	"fmt"
+	"os"
+)
+
+type RuntimeError struct {
+	Msg string
+	IP  int
+}
+
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("runtime error at ip=%d: %s", e.IP, e.Msg)
+}
+
+func (vm *VM) errorf(format string, args ...interface{}) error {
+	return &RuntimeError{
+		Msg: fmt.Sprintf(format, args...),
+		IP:  vm.ip - 1,
+	}
+}
+
+// warnf reports a PHP warning on stderr and lets the script continue.
+func (vm *VM) warnf(format string, args ...interface{}) {
+	vm.out.Flush()
+	fmt.Fprintf(os.Stderr, "WARNING: %s\n", fmt.Sprintf(format, args...))
+}
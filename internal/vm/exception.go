@@ -0,0 +1,237 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package vm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/compiler/function"
+	"github.com/neokofg/php-compiler/internal/native"
+	"github.com/neokofg/php-compiler/internal/vm/value"
+)
+
+// thrown is returned by a handler that throws. The step loop unwinds to
+// the handler that catches it.
+type thrown struct {
+	exception *value.Object
+}
+
+func (t *thrown) Error() string {
+	return "Uncaught " + native.Describe(t.exception)
+}
+
+// UncaughtError ends a script that throws an exception no handler catches.
+// Its message is what PHP prints after "PHP Fatal error:  ".
+type UncaughtError struct {
+	Exception *value.Object
+}
+
+func (e *UncaughtError) Error() string {
+	file, _ := e.Exception.Props.Get(value.StringKey(native.PropFile))
+	line, _ := e.Exception.Props.Get(value.StringKey(native.PropLine))
+	return fmt.Sprintf("Uncaught %s\n  thrown in %s on line %d", native.Describe(e.Exception), file.ToString(), line.ToInt())
+}
+
+// SetExceptionTable sets the handlers that try blocks compile to.
+func (vm *VM) SetExceptionTable(handlers []bytecode.Handler) {
+	vm.exceptions = handlers
+}
+
+// SetDebugInfo sets what exceptions report about where they were thrown,
+// the source file, the line table and the names of the functions, and the
+// variable names that warnings show.
+func (vm *VM) SetDebugInfo(file string, lines []bytecode.Line, functions []function.Function, variables map[string]int) {
+	vm.file = file
+	vm.lines = lines
+	vm.functionNames = make(map[int]string)
+	vm.localNames = make(map[int][]string)
+	for _, f := range functions {
+		vm.functionNames[f.Address] = f.Name
+		vm.localNames[f.Address] = f.Locals
+	}
+	vm.variableNames = make([]string, VarCount)
+	for name, idx := range variables {
+		if idx < VarCount {
+			vm.variableNames[idx] = name
+		}
+	}
+}
+
+func (vm *VM) variableName(idx int) string {
+	if idx < len(vm.variableNames) {
+		return vm.variableNames[idx]
+	}
+	return ""
+}
+
+// localName returns the name of a local of the function whose FUNC_DECL
+// is at addr.
+func (vm *VM) localName(addr, slot int) string {
+	if names := vm.localNames[addr]; slot < len(names) {
+		return names[slot]
+	}
+	return ""
+}
+
+// declareBuiltinClasses creates the classes of the runtime, such as
+// Exception, before the script declares its own.
+func (vm *VM) declareBuiltinClasses() {
+	for _, spec := range native.Classes() {
+		class := value.NewClass(spec.Name)
+		class.Interface = spec.Interface
+		class.Final = spec.Final
+		if spec.Parent != "" {
+			class.Inherit(vm.classes[strings.ToLower(spec.Parent)])
+		}
+		for _, name := range spec.Interfaces {
+			class.Implement(vm.classes[strings.ToLower(name)])
+		}
+		for _, p := range spec.Properties {
+			class.AddProperty(value.Property{Name: p.Name, Class: class, Default: p.Default, Visibility: p.Visibility})
+		}
+		for _, m := range spec.Methods {
+			method := &value.Method{Name: m.Name, Class: class, ParamCount: m.MinArgs, Abstract: m.Impl == nil}
+			if impl := m.Impl; impl != nil {
+				method.Native = func(this *value.Object, args []value.Value) (value.Value, error) {
+					return impl(nativeEnv{vm}, this, args)
+				}
+			}
+			class.AddMethod(method)
+		}
+		vm.classes[strings.ToLower(spec.Name)] = class
+	}
+}
+
+// isThrowable reports whether objects of class can be thrown.
+func (vm *VM) isThrowable(class *value.Class) bool {
+	throwable, ok := vm.classes[strings.ToLower(native.Throwable)]
+	return ok && class.InstanceOf(throwable)
+}
+
+// initThrowable records where a new Throwable is created: the line of the
+// running instruction and, for each active call, the call site and callee.
+func (vm *VM) initThrowable(obj *value.Object) {
+	trace := value.NewArray()
+	for i := len(vm.frames) - 1; i >= 0; i-- {
+		f := vm.frames[i]
+		entry := value.NewArray()
+		entry.Set(value.StringKey(native.PropFile), value.NewString(vm.file))
+		entry.Set(value.StringKey(native.PropLine), value.NewInt(int64(bytecode.LineAt(vm.lines, f.returnAddr-1))))
+		if f.method != nil {
+			entry.Set(value.StringKey("function"), value.NewString(f.method.Name))
+			entry.Set(value.StringKey("class"), value.NewString(f.method.Class.Name))
+			if f.this != nil {
+				entry.Set(value.StringKey("type"), value.NewString("->"))
+			} else {
+				entry.Set(value.StringKey("type"), value.NewString("::"))
+			}
+		} else {
+			entry.Set(value.StringKey("function"), value.NewString(vm.functionNames[f.addr]))
+		}
+		trace.Append(value.NewArrayValue(entry))
+	}
+
+	obj.Props.Set(value.StringKey(native.PropFile), value.NewString(vm.file))
+	obj.Props.Set(value.StringKey(native.PropLine), value.NewInt(int64(bytecode.LineAt(vm.lines, vm.ip-1))))
+	obj.Props.Set(value.StringKey(native.PropTrace), value.NewArrayValue(trace))
+}
+
+// throwf throws a new instance of the built-in class with the message.
+// It is how PHP's Error exceptions for failed operations come about.
+func (vm *VM) throwf(class, format string, args ...interface{}) error {
+	c, ok := vm.classes[strings.ToLower(class)]
+	if !ok {
+		return vm.errorf(format, args...)
+	}
+
+	obj := value.NewObject(c)
+	vm.initThrowable(obj)
+	obj.Props.Set(value.StringKey("message"), value.NewString(fmt.Sprintf(format, args...)))
+	return &thrown{exception: obj}
+}
+
+// unwind transfers control to the innermost handler that covers the
+// instruction being run, leaving the frames that have none. The handler
+// finds the exception on top of the stack. Returns an UncaughtError when
+// no handler is left. depth is the number of frames below a function a
+// native called, see call, or -1: an exception that leaves the function
+// is returned for the native to pass on.
+func (vm *VM) unwind(exception *value.Object, depth int) error {
+	addr := vm.ip - 1
+	for {
+		function, base := bytecode.MainFunction, 0
+		if n := len(vm.frames); n > 0 {
+			function, base = vm.frames[n-1].addr, vm.frames[n-1].stackBase
+		}
+
+		for _, h := range vm.exceptions {
+			if h.Function != function || addr < h.Start || addr >= h.End {
+				continue
+			}
+			if depth := base + h.StackDepth; depth < len(vm.stack) {
+				vm.stack = vm.stack[:depth]
+			}
+			vm.ip = h.Target
+			return vm.push(value.NewObjectValue(exception))
+		}
+
+		if len(vm.frames) == 0 {
+			return &UncaughtError{Exception: exception}
+		}
+		top := vm.frames[len(vm.frames)-1]
+		vm.frames = vm.frames[:len(vm.frames)-1]
+		for _, local := range top.locals {
+			value.Release(local)
+		}
+		addr = top.returnAddr - 1
+		if len(vm.frames) == depth {
+			vm.ip = top.returnAddr
+			return &thrown{exception: exception}
+		}
+	}
+}
+
+// handleThrow pops the exception and throws it.
+func handleThrow(vm *VM) error {
+	v, err := vm.pop()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case v.Type != value.TypeObject:
+		return vm.throwf("Error", "Can only throw objects")
+	case !vm.isThrowable(v.Obj.Class):
+		return vm.throwf("Error", "Cannot throw objects that do not implement Throwable")
+	}
+	return &thrown{exception: v.Obj}
+}
+
+// handleCatch jumps when the exception on top of the stack is an instance
+// of the class operand. A class that was never declared matches nothing.
+func handleCatch(vm *VM) error {
+	name, err := vm.readName()
+	if err != nil {
+		return err
+	}
+	offset, err := vm.readUint16()
+	if err != nil {
+		return err
+	}
+
+	if len(vm.stack) == 0 {
+		return vm.errorf("CATCH expects an exception on the stack")
+	}
+	exception := vm.stack[len(vm.stack)-1]
+
+	class, ok := vm.classes[strings.ToLower(name)]
+	if ok && exception.Type == value.TypeObject && exception.Obj.Class.InstanceOf(class) {
+		target := vm.ip + int(offset)
+		if target > len(vm.code) {
+			return vm.errorf("CATCH target out of bounds (offset=%d, target=%d)", offset, target)
+		}
+		vm.ip = target
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package vm
+
+import "github.com/neokofg/php-compiler/internal/vm/value"
+
+func handleJump(vm *VM) error {
+	offset, err := vm.readUint16()
+	if err != nil {
+		return err
+	}
+
+	target := vm.ip + int(int16(offset))
+	if target < 0 || target > len(vm.code) {
+		return vm.errorf("Jump target out of bounds (offset=%d, target=%d)", int16(offset), target)
+	}
+
+	vm.ip = target
+	return nil
+}
+
+// handleCoalesce jumps when the value on top of the stack is not null,
+// leaving it there as the result of ??. A null value is popped, and the
+// right operand that follows computes the result instead.
+func handleCoalesce(vm *VM) error {
+	offset, err := vm.readUint16()
+	if err != nil {
+		return err
+	}
+
+	if len(vm.stack) == 0 {
+		return vm.errorf("Stack underflow")
+	}
+
+	if vm.stack[len(vm.stack)-1].Type == value.TypeNull {
+		vm.stack = vm.stack[:len(vm.stack)-1]
+		return nil
+	}
+
+	target := vm.ip + int(offset)
+	if target > len(vm.code) {
+		return vm.errorf("COALESCE target out of bounds (offset=%d, target=%d)", offset, target)
+	}
+	vm.ip = target
+	return nil
+}
+
+func handleJumpIfFalse(vm *VM) error {
+	offset, err := vm.readUint16()
+	if err != nil {
+		return err
+	}
+
+	cond, err := vm.pop()
+	if err != nil {
+		return err
+	}
+
+	if !cond.ToBool() {
+		target := vm.ip + int(offset)
+		if target > len(vm.code) {
+			return vm.errorf("JUMP_IF_FALSE target out of bounds (offset=%d, target=%d)", offset, target)
+		}
+		vm.ip = target
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package vm
+
+import (
+	"github.com/neokofg/php-compiler/internal/vm/value"
+)
+
+// A foreach loop keeps two values on the stack while it runs: the array it
+// iterates over and the position of the next element.
+
+// handleForeachInit pops the iterated value and pushes the loop state. A
+// loop by value holds a reference to the array, so that writes to the
+// variable in the body separate it and do not affect the iteration.
+func handleForeachInit(vm *VM) error {
+	byRef, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	v, err := vm.pop()
+	if err != nil {
+		return err
+	}
+
+	if v.Type != value.TypeArray {
+		vm.warnf("foreach() argument must be of type array|object, %s given", v.TypeName())
+		v = value.NewArrayValue(value.NewArray())
+	}
+
+	if byRef == 0 {
+		value.Retain(v)
+	}
+
+	if err := vm.push(v); err != nil {
+		return err
+	}
+	return vm.push(value.NewInt(0))
+}
+
+// handleForeachNext pushes the key and the value of the next element, or
+// jumps out of the loop when there is none. By reference, the current value
+// of the iterated variable is on top of the stack and replaces the iterated
+// array: the element is bound to a reference, which is pushed in place of
+// the value, followed by the array to store back into the variable.
+func handleForeachNext(vm *VM) error {
+	byRef, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	offset, err := vm.readUint16()
+	if err != nil {
+		return err
+	}
+
+	if byRef != 0 {
+		current, err := vm.pop()
+		if err != nil {
+			return err
+		}
+		if current.Type != value.TypeArray {
+			current = value.NewArrayValue(value.NewArray())
+		}
+		if err := vm.setLoopArray(current); err != nil {
+			return err
+		}
+	}
+
+	arr, pos, err := vm.loopState()
+	if err != nil {
+		return err
+	}
+
+	if pos >= arr.Len() {
+		target := vm.ip + int(offset)
+		if target > len(vm.code) {
+			return vm.errorf("FOREACH_NEXT target out of bounds (offset=%d, target=%d)", offset, target)
+		}
+		vm.ip = target
+		return nil
+	}
+
+	vm.stack[len(vm.stack)-1] = value.NewInt(int64(pos + 1))
+
+	if byRef != 0 {
+		// The element is written through the reference, so the array
+		// must not be shared with other variables first.
+		arr = arr.Separate()
+		if err := vm.setLoopArray(value.NewArrayValue(arr)); err != nil {
+			return err
+		}
+		k, _ := arr.At(pos)
+		if err := vm.push(k.ToValue()); err != nil {
+			return err
+		}
+		if err := vm.push(arr.RefAt(pos)); err != nil {
+			return err
+		}
+		return vm.push(value.NewArrayValue(arr))
+	}
+
+	k, v := arr.At(pos)
+	if err := vm.push(k.ToValue()); err != nil {
+		return err
+	}
+	return vm.push(v)
+}
+
+// handleForeachEnd drops the loop state.
+func handleForeachEnd(vm *VM) error {
+	byRef, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	arr, _, err := vm.pop2()
+	if err != nil {
+		return err
+	}
+
+	if byRef == 0 {
+		value.Release(arr)
+	}
+	return nil
+}
+
+func (vm *VM) loopState() (*value.Array, int, error) {
+	n := len(vm.stack)
+	if n < 2 || vm.stack[n-2].Type != value.TypeArray || vm.stack[n-1].Type != value.TypeInt {
+		return nil, 0, vm.errorf("foreach state expected on the stack")
+	}
+	return vm.stack[n-2].Arr, int(vm.stack[n-1].Int), nil
+}
+
+func (vm *VM) setLoopArray(arr value.Value) error {
+	if _, _, err := vm.loopState(); err != nil {
+		return err
+	}
+	vm.stack[len(vm.stack)-2] = arr
+	return nil
+}
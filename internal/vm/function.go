@@ -0,0 +1,187 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package vm
+
+import (
+	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/vm/value"
+)
+
+// Calling convention: the caller pushes the arguments last to first, so the
+// first argument is on top, then FUNC_CALL pushes a frame with the return
+// address and argument count. FUNC_DECL binds the arguments to local slots
+// and RETURN drops whatever the callee left on the stack.
+func handleFuncCall(vm *VM) error {
+	argCount, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	funcAddr, err := vm.readUint16()
+	if err != nil {
+		return err
+	}
+
+	return vm.callFunction(int(funcAddr), int(argCount))
+}
+
+// handleFuncBind defines a function by name: one declared at the top level
+// before the first statement runs, one declared in a block or a function
+// body once its declaration runs.
+func handleFuncBind(vm *VM) error {
+	name, err := vm.readName()
+	if err != nil {
+		return err
+	}
+
+	funcAddr, err := vm.readUint16()
+	if err != nil {
+		return err
+	}
+
+	if _, exists := vm.functions[name]; exists {
+		return vm.errorf("Cannot redeclare %s()", name)
+	}
+	vm.functions[name] = int(funcAddr)
+	return nil
+}
+
+// handleCallNamed calls a function bound by FUNC_BIND. The arguments are
+// on the stack like for FUNC_CALL.
+func handleCallNamed(vm *VM) error {
+	name, err := vm.readName()
+	if err != nil {
+		return err
+	}
+
+	argCount, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	funcAddr, exists := vm.functions[name]
+	if !exists {
+		return vm.throwf("Error", "Call to undefined function %s()", name)
+	}
+
+	if paramCount := int(vm.code[funcAddr+1]); int(argCount) < paramCount {
+		return vm.throwf("ArgumentCountError", "Too few arguments to function %s(), %d passed and exactly %d expected",
+			name, argCount, paramCount)
+	}
+
+	return vm.callFunction(funcAddr, int(argCount))
+}
+
+// callFunction enters the function at funcAddr with the argCount arguments
+// on top of the stack.
+func (vm *VM) callFunction(funcAddr int, argCount int) error {
+	if funcAddr >= len(vm.code) {
+		return vm.errorf("Invalid function address %d, bytecode_len=%d", funcAddr, len(vm.code))
+	}
+
+	if vm.code[funcAddr] != bytecode.OP_FUNC_DECL {
+		return vm.errorf("Invalid function opcode 0x%02X at address %d", vm.code[funcAddr], funcAddr)
+	}
+
+	if argCount > len(vm.stack) {
+		return vm.errorf("FUNC_CALL expects %d arguments, stack has %d values", argCount, len(vm.stack))
+	}
+
+	if err := vm.pushFrame(frame{
+		addr:       funcAddr,
+		returnAddr: vm.ip,
+		argCount:   argCount,
+		stackBase:  len(vm.stack) - argCount,
+	}); err != nil {
+		return err
+	}
+	vm.ip = funcAddr
+
+	return nil
+}
+
+// pushFrame enters a call, failing like a stack overflow past FrameCount
+// nested calls.
+func (vm *VM) pushFrame(f frame) error {
+	if len(vm.frames) >= FrameCount {
+		return vm.errorf("Call stack overflow, max depth: %d", FrameCount)
+	}
+	vm.frames = append(vm.frames, f)
+	return nil
+}
+
+func handleFuncDecl(vm *VM) error {
+	paramCount, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	current, err := vm.currentFrame()
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < int(paramCount); i++ {
+		slot, err := vm.readByte()
+		if err != nil {
+			return err
+		}
+
+		param := value.NewNull()
+		if i < current.argCount {
+			if param, err = vm.pop(); err != nil {
+				return err
+			}
+		}
+
+		if err := vm.storeLocal(int(slot), param); err != nil {
+			return err
+		}
+	}
+
+	// Extra arguments are dropped, like PHP does for user functions.
+	vm.stack = vm.stack[:current.stackBase]
+
+	return nil
+}
+
+func handleEnterFunc(vm *VM) error {
+	return nil
+}
+
+func handleReturn(vm *VM) error {
+	result, err := vm.pop()
+	if err != nil {
+		return err
+	}
+
+	return vm.returnFromFunction(result)
+}
+
+func handleExitFunc(vm *VM) error {
+	return vm.returnFromFunction(value.NewNull())
+}
+
+// returnFromFunction pops the current call frame and pushes the result for the caller.
+// A constructor gives back the object it initialized instead. A return
+// outside of any function ends the script, as in PHP.
+func (vm *VM) returnFromFunction(result value.Value) error {
+	if len(vm.frames) == 0 {
+		vm.running = false
+		return nil
+	}
+
+	top := vm.frames[len(vm.frames)-1]
+	vm.frames = vm.frames[:len(vm.frames)-1]
+	for _, local := range top.locals {
+		value.Release(local)
+	}
+	vm.ip = top.returnAddr
+	if len(vm.stack) > top.stackBase {
+		vm.stack = vm.stack[:top.stackBase]
+	}
+
+	if top.construct != nil {
+		result = value.NewObjectValue(top.construct)
+	}
+	return vm.push(result)
+}
@@ -0,0 +1,120 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package vm
+
+import (
+	"github.com/neokofg/php-compiler/internal/vm/value"
+)
+
+func compareOp(vm *VM, test func(a, b value.Value) bool) error {
+	a, b, err := vm.pop2()
+	if err != nil {
+		return err
+	}
+
+	return vm.push(value.NewBool(test(a, b)))
+}
+
+func handleGt(vm *VM) error {
+	return compareOp(vm, func(a, b value.Value) bool { return value.Compare(a, b) > 0 })
+}
+
+func handleLt(vm *VM) error {
+	return compareOp(vm, func(a, b value.Value) bool { return value.Compare(a, b) < 0 })
+}
+
+func handleGte(vm *VM) error {
+	return compareOp(vm, func(a, b value.Value) bool { return value.Compare(a, b) >= 0 })
+}
+
+func handleLte(vm *VM) error {
+	return compareOp(vm, func(a, b value.Value) bool { return value.Compare(a, b) <= 0 })
+}
+
+// handleSpaceship pushes -1, 0 or 1 as the first operand is less than,
+// equal to or greater than the second.
+func handleSpaceship(vm *VM) error {
+	a, b, err := vm.pop2()
+	if err != nil {
+		return err
+	}
+
+	return vm.push(value.NewInt(int64(value.Compare(a, b))))
+}
+
+func handleEq(vm *VM) error {
+	return compareOp(vm, value.LooseEquals)
+}
+
+func handleIdentityEq(vm *VM) error {
+	return compareOp(vm, value.Identical)
+}
+
+func handleIdentityNe(vm *VM) error {
+	return compareOp(vm, func(a, b value.Value) bool { return !value.Identical(a, b) })
+}
+
+func handleAnd(vm *VM) error {
+	return compareOp(vm, func(a, b value.Value) bool { return a.ToBool() && b.ToBool() })
+}
+
+func handleOr(vm *VM) error {
+	return compareOp(vm, func(a, b value.Value) bool { return a.ToBool() || b.ToBool() })
+}
+
+func handleNot(vm *VM) error {
+	v, err := vm.pop()
+	if err != nil {
+		return err
+	}
+
+	return vm.push(value.NewBool(!v.ToBool()))
+}
+
+func handleBitAnd(vm *VM) error {
+	return binaryIntOp(vm, func(a, b int64) int64 { return a & b })
+}
+
+func handleBitOr(vm *VM) error {
+	return binaryIntOp(vm, func(a, b int64) int64 { return a | b })
+}
+
+func handleBitXor(vm *VM) error {
+	return binaryIntOp(vm, func(a, b int64) int64 { return a ^ b })
+}
+
+func handleBitNot(vm *VM) error {
+	v, err := vm.pop()
+	if err != nil {
+		return err
+	}
+
+	return vm.push(value.NewInt(^v.ToInt()))
+}
+
+func handleLshift(vm *VM) error {
+	a, b, err := vm.pop2()
+	if err != nil {
+		return err
+	}
+
+	shift := b.ToInt()
+	if shift < 0 {
+		return vm.throwf("ArithmeticError", "Bit shift by negative number")
+	}
+
+	return vm.push(value.NewInt(a.ToInt() << uint64(shift)))
+}
+
+func handleRshift(vm *VM) error {
+	a, b, err := vm.pop2()
+	if err != nil {
+		return err
+	}
+
+	shift := b.ToInt()
+	if shift < 0 {
+		return vm.throwf("ArithmeticError", "Bit shift by negative number")
+	}
+
+	return vm.push(value.NewInt(a.ToInt() >> uint64(shift)))
+}
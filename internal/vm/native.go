@@ -0,0 +1,87 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package vm
+
+import (
+	"errors"
+	"io"
+
+	"github.com/neokofg/php-compiler/internal/native"
+	"github.com/neokofg/php-compiler/internal/vm/value"
+)
+
+// nativeEnv is what a native function sees of the VM.
+type nativeEnv struct {
+	vm *VM
+}
+
+func (e nativeEnv) Output() io.Writer {
+	return e.vm.out
+}
+
+func (e nativeEnv) Warnf(format string, args ...interface{}) {
+	e.vm.warnf(format, args...)
+}
+
+func (e nativeEnv) Call(callable value.Value, args []value.Value) (value.Value, error) {
+	return e.vm.call(callable, args)
+}
+
+// handleCallNative pops the arguments, which the caller pushed in source
+// order, and calls the native named by the constant operand. It pushes the
+// result, then the final value of each by-reference argument in parameter
+// order for the caller to store back.
+func handleCallNative(vm *VM) error {
+	nameIdx, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+	argc, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	if int(nameIdx) >= len(vm.constants) || vm.constants[nameIdx].Type != value.TypeString {
+		return vm.errorf("Invalid native function name constant %d", nameIdx)
+	}
+	name := vm.constants[nameIdx].Str
+
+	fn, ok := native.Lookup(name)
+	if !ok {
+		return vm.throwf("Error", "Call to undefined function %s()", name)
+	}
+
+	if int(argc) > len(vm.stack) {
+		return vm.errorf("CALL_NATIVE expects %d arguments, stack has %d values", argc, len(vm.stack))
+	}
+
+	base := len(vm.stack) - int(argc)
+	args := make([]value.Value, argc)
+	copy(args, vm.stack[base:])
+	vm.stack = vm.stack[:base]
+
+	result, err := fn.Impl(nativeEnv{vm}, args)
+	if err != nil {
+		var nativeErr *native.Error
+		if errors.As(err, &nativeErr) {
+			return vm.throwf(nativeErr.Class, "%s", nativeErr.Message)
+		}
+		switch err.(type) {
+		case *thrown, *RuntimeError:
+			// Raised by a function the native called back.
+			return err
+		}
+		return vm.errorf("%v", err)
+	}
+
+	if err := vm.push(result); err != nil {
+		return err
+	}
+	for i := range args {
+		if fn.IsByRef(i) {
+			if err := vm.push(args[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,603 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package vm
+
+import (
+	"strings"
+
+	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/native"
+	"github.com/neokofg/php-compiler/internal/vm/value"
+)
+
+// readName reads a constant operand that names a class or a member.
+func (vm *VM) readName() (string, error) {
+	idx, err := vm.readByte()
+	if err != nil {
+		return "", err
+	}
+
+	if int(idx) >= len(vm.constants) || vm.constants[idx].Type != value.TypeString {
+		return "", vm.errorf("Invalid name constant %d", idx)
+	}
+	return vm.constants[idx].Str, nil
+}
+
+// handleClassDecl creates a class, an interface or a trait. The CLASS_*
+// instructions that follow add its parent, interfaces and members.
+func handleClassDecl(vm *VM) error {
+	name, err := vm.readName()
+	if err != nil {
+		return err
+	}
+	flags, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	class := value.NewClass(name)
+	class.Abstract = flags&bytecode.ClassAbstract != 0
+	class.Final = flags&bytecode.ClassFinal != 0
+	class.Interface = flags&bytecode.ClassInterface != 0
+	class.Trait = flags&bytecode.ClassTrait != 0
+
+	key := strings.ToLower(name)
+	if _, exists := vm.classes[key]; exists {
+		return vm.errorf("Cannot declare %s %s, because the name is already in use", class.KindName(), name)
+	}
+
+	vm.declaring = class
+	vm.classes[key] = class
+	return nil
+}
+
+// handleClassExtends makes the class being declared inherit the members
+// of its parent.
+func handleClassExtends(vm *VM) error {
+	name, err := vm.readName()
+	if err != nil {
+		return err
+	}
+
+	if vm.declaring == nil {
+		return vm.errorf("CLASS_EXTENDS outside of a class declaration")
+	}
+	parent, ok := vm.classes[strings.ToLower(name)]
+	if !ok {
+		return vm.errorf("Class \"%s\" not found", name)
+	}
+	if parent.Final {
+		return vm.errorf("Class %s cannot extend final class %s", vm.declaring.Name, parent.Name)
+	}
+	if parent.Interface != vm.declaring.Interface || parent.Trait {
+		return vm.errorf("Class %s cannot extend %s %s", vm.declaring.Name, parent.KindName(), parent.Name)
+	}
+
+	if vm.declaring.Interface {
+		vm.declaring.Implement(parent)
+	} else {
+		vm.declaring.Inherit(parent)
+	}
+	return nil
+}
+
+// handleClassImplements records an interface of the class being declared.
+// The compiler has checked that the class implements its methods.
+func handleClassImplements(vm *VM) error {
+	name, err := vm.readName()
+	if err != nil {
+		return err
+	}
+
+	if vm.declaring == nil {
+		return vm.errorf("CLASS_IMPLEMENTS outside of a class declaration")
+	}
+	iface, ok := vm.classes[strings.ToLower(name)]
+	if !ok {
+		return vm.errorf("Interface \"%s\" not found", name)
+	}
+	if !iface.Interface {
+		return vm.errorf("%s cannot implement %s - it is not an interface", vm.declaring.Name, iface.Name)
+	}
+
+	vm.declaring.Implement(iface)
+	return nil
+}
+
+// handleClassProp pops the default value of a property of the class being
+// declared.
+func handleClassProp(vm *VM) error {
+	name, err := vm.readName()
+	if err != nil {
+		return err
+	}
+	modifiers, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	def, err := vm.pop()
+	if err != nil {
+		return err
+	}
+
+	if vm.declaring == nil {
+		return vm.errorf("CLASS_PROP outside of a class declaration")
+	}
+	vm.declaring.AddProperty(value.Property{
+		Name:       name,
+		Class:      vm.declaring,
+		Default:    def,
+		Visibility: value.Visibility(modifiers & bytecode.MemberVisibilityMask),
+	})
+	return nil
+}
+
+// handleClassMethod adds a method whose code starts at the address operand.
+// Abstract methods have no code and the address is 0.
+func handleClassMethod(vm *VM) error {
+	name, err := vm.readName()
+	if err != nil {
+		return err
+	}
+	modifiers, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+	addr, err := vm.readUint16()
+	if err != nil {
+		return err
+	}
+
+	if vm.declaring == nil {
+		return vm.errorf("CLASS_METHOD outside of a class declaration")
+	}
+
+	method := &value.Method{
+		Name:       name,
+		Class:      vm.declaring,
+		Addr:       int(addr),
+		Visibility: value.Visibility(modifiers & bytecode.MemberVisibilityMask),
+		Static:     modifiers&bytecode.MemberStatic != 0,
+		Abstract:   modifiers&bytecode.MemberAbstract != 0,
+	}
+
+	if !method.Abstract {
+		if int(addr)+1 >= len(vm.code) || vm.code[addr] != bytecode.OP_FUNC_DECL {
+			return vm.errorf("Invalid method address %d for %s::%s()", addr, vm.declaring.Name, name)
+		}
+		method.ParamCount = int(vm.code[addr+1])
+		if !method.Static {
+			method.ParamCount-- // without $this
+		}
+	}
+
+	vm.declaring.AddMethod(method)
+	return nil
+}
+
+// handleNew creates an object with the default property values and calls
+// its constructor with the arguments on the stack. Without a constructor
+// the arguments are dropped and the object is pushed right away.
+func handleNew(vm *VM) error {
+	name, err := vm.readName()
+	if err != nil {
+		return err
+	}
+	argc, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	class, err := vm.lookupClass(name)
+	if err != nil {
+		return err
+	}
+	switch {
+	case class.Interface || class.Trait:
+		return vm.throwf("Error", "Cannot instantiate %s %s", class.KindName(), class.Name)
+	case class.Abstract:
+		return vm.throwf("Error", "Cannot instantiate abstract class %s", class.Name)
+	case class == vm.classes[strings.ToLower(native.Closure)]:
+		return vm.throwf("Error", "Instantiation of class %s is not allowed", class.Name)
+	}
+	if int(argc) > len(vm.stack) {
+		return vm.errorf("NEW expects %d arguments, stack has %d values", argc, len(vm.stack))
+	}
+
+	obj := value.NewObject(class)
+	if vm.isThrowable(class) {
+		vm.initThrowable(obj)
+	}
+
+	ctor, ok := class.Method("__construct")
+	if !ok {
+		vm.stack = vm.stack[:len(vm.stack)-int(argc)]
+		return vm.push(value.NewObjectValue(obj))
+	}
+
+	if !vm.canAccess(ctor.Class, ctor.Visibility) {
+		return vm.throwf("Error", "Call to %s %s::__construct() from %s", ctor.Visibility, class.Name, vm.scopeName())
+	}
+	return vm.callMethod(obj, class, ctor, int(argc), true)
+}
+
+// handleMethodCall calls a method of the object below the arguments.
+func handleMethodCall(vm *VM) error {
+	name, err := vm.readName()
+	if err != nil {
+		return err
+	}
+	argc, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	if int(argc) >= len(vm.stack) {
+		return vm.errorf("METHOD_CALL expects an object and %d arguments, stack has %d values", argc, len(vm.stack))
+	}
+
+	// Take the object out from under the arguments.
+	pos := len(vm.stack) - int(argc) - 1
+	target := vm.stack[pos]
+	copy(vm.stack[pos:], vm.stack[pos+1:])
+	vm.stack = vm.stack[:len(vm.stack)-1]
+
+	if target.Type != value.TypeObject {
+		return vm.throwf("Error", "Call to a member function %s() on %s", name, target.TypeName())
+	}
+
+	class := target.Obj.Class
+	method, ok := vm.findMethod(class, name)
+	if !ok {
+		return vm.throwf("Error", "Call to undefined method %s::%s()", class.Name, name)
+	}
+	if !vm.canAccess(method.Class, method.Visibility) {
+		return vm.throwf("Error", "Call to %s method %s::%s() from %s", method.Visibility, method.Class.Name, method.Name, vm.scopeName())
+	}
+
+	if method.Static {
+		return vm.callMethod(nil, class, method, int(argc), false)
+	}
+	return vm.callMethod(target.Obj, class, method, int(argc), false)
+}
+
+// handleStaticCall calls Class::method(). Through self, parent and static
+// the called class of the running method is kept for static::, and an
+// instance method is called on the current $this.
+func handleStaticCall(vm *VM) error {
+	className, err := vm.readName()
+	if err != nil {
+		return err
+	}
+	name, err := vm.readName()
+	if err != nil {
+		return err
+	}
+	argc, err := vm.readByte()
+	if err != nil {
+		return err
+	}
+
+	if int(argc) > len(vm.stack) {
+		return vm.errorf("STATIC_CALL expects %d arguments, stack has %d values", argc, len(vm.stack))
+	}
+
+	class, err := vm.lookupClass(className)
+	if err != nil {
+		return err
+	}
+	method, ok := vm.findMethod(class, name)
+	if !ok {
+		return vm.throwf("Error", "Call to undefined method %s::%s()", class.Name, name)
+	}
+	if !vm.canAccess(method.Class, method.Visibility) {
+		return vm.throwf("Error", "Call to %s method %s::%s() from %s", method.Visibility, method.Class.Name, method.Name, vm.scopeName())
+	}
+	if method.Abstract {
+		return vm.throwf("Error", "Cannot call abstract method %s::%s()", method.Class.Name, method.Name)
+	}
+
+	current, _ := vm.currentFrame()
+	if method.Static {
+		called := class
+		if forwarding(className) && current != nil && current.static != nil {
+			called = current.static
+		}
+		return vm.callMethod(nil, called, method, int(argc), false)
+	}
+
+	if current == nil || current.this == nil || !current.this.Class.InstanceOf(method.Class) {
+		return vm.throwf("Error", "Non-static method %s::%s() cannot be called statically", method.Class.Name, method.Name)
+	}
+	return vm.callMethod(current.this, current.this.Class, method, int(argc), false)
+}
+
+// forwarding reports whether a class reference passes on the called class.
+func forwarding(name string) bool {
+	switch strings.ToLower(name) {
+	case "self", "parent", "static":
+		return true
+	}
+	return false
+}
+
+// handleInstanceof pops a value and pushes whether it is an object of the
+// class operand, of a subclass of it or of a class implementing it. A
+// class that was never declared has no instances.
+func handleInstanceof(vm *VM) error {
+	name, err := vm.readName()
+	if err != nil {
+		return err
+	}
+
+	v, err := vm.pop()
+	if err != nil {
+		return err
+	}
+
+	var class *value.Class
+	switch strings.ToLower(name) {
+	case "self", "parent", "static":
+		if class, err = vm.lookupClass(name); err != nil {
+			return err
+		}
+	default:
+		class = vm.classes[strings.ToLower(name)]
+	}
+
+	return vm.push(value.NewBool(class != nil && v.Type == value.TypeObject && v.Obj.Class.InstanceOf(class)))
+}
+
+// lookupClass resolves a class name operand. self, parent and static are
+// relative to the running method.
+func (vm *VM) lookupClass(name string) (*value.Class, error) {
+	switch keyword := strings.ToLower(name); keyword {
+	case "self", "parent", "static":
+		scope := vm.scope()
+		if scope == nil {
+			return nil, vm.throwf("Error", "Cannot use \"%s\" when no class scope is active", keyword)
+		}
+		switch keyword {
+		case "parent":
+			if scope.Parent == nil {
+				return nil, vm.throwf("Error", "Cannot use \"parent\" when current class scope has no parent")
+			}
+			return scope.Parent, nil
+		case "static":
+			if current, _ := vm.currentFrame(); current.static != nil {
+				return current.static, nil
+			}
+		}
+		return scope, nil
+	}
+
+	class, ok := vm.classes[strings.ToLower(name)]
+	if !ok {
+		return nil, vm.throwf("Error", "Class \"%s\" not found", name)
+	}
+	return class, nil
+}
+
+// findMethod looks up a method of class. A private method of the running
+// class takes precedence over the methods of its subclasses, as in PHP.
+func (vm *VM) findMethod(class *value.Class, name string) (*value.Method, bool) {
+	if scope := vm.scope(); scope != nil && class.InstanceOf(scope) {
+		if own, ok := scope.Method(name); ok && own.Class == scope && own.Visibility == value.Private {
+			return own, true
+		}
+	}
+	return class.Method(name)
+}
+
+// callMethod enters method with the argc arguments on top of the stack,
+// pushed last to first like for FUNC_CALL. For an instance method, the
+// object goes on top of them, where FUNC_DECL binds it to $this. called is
+// the class static:: refers to in the method.
+func (vm *VM) callMethod(obj *value.Object, called *value.Class, method *value.Method, argc int, construct bool) error {
+	if argc < method.ParamCount {
+		return vm.throwf("ArgumentCountError", "Too few arguments to function %s::%s(), %d passed and exactly %d expected",
+			method.Class.Name, method.Name, argc, method.ParamCount)
+	}
+
+	if method.Native != nil {
+		return vm.callNativeMethod(obj, method, argc, construct)
+	}
+
+	f := frame{
+		addr:       method.Addr,
+		returnAddr: vm.ip,
+		argCount:   argc,
+		stackBase:  len(vm.stack) - argc,
+		scope:      method.Class,
+		static:     called,
+		method:     method,
+	}
+	if obj != nil {
+		if err := vm.push(value.NewObjectValue(obj)); err != nil {
+			return err
+		}
+		f.argCount++
+		f.this = obj
+	}
+	if construct {
+		f.construct = obj
+	}
+	if err := vm.pushFrame(f); err != nil {
+		return err
+	}
+	vm.ip = method.Addr
+
+	return nil
+}
+
+// callNativeMethod runs a method of a built-in class without entering a
+// frame. The arguments on the stack are last to first, so they are
+// reversed into source order.
+func (vm *VM) callNativeMethod(obj *value.Object, method *value.Method, argc int, construct bool) error {
+	base := len(vm.stack) - argc
+	args := make([]value.Value, argc)
+	for i := range args {
+		args[i] = vm.stack[len(vm.stack)-1-i]
+	}
+	vm.stack = vm.stack[:base]
+
+	result, err := method.Native(obj, args)
+	if err != nil {
+		return vm.errorf("%v", err)
+	}
+	if construct {
+		result = value.NewObjectValue(obj)
+	}
+	return vm.push(result)
+}
+
+// handlePropGet replaces the object on top of the stack with the value of
+// one of its properties. Reading a missing property or a property of a
+// non-object gives null with a warning, as in PHP.
+func handlePropGet(vm *VM) error {
+	name, err := vm.readName()
+	if err != nil {
+		return err
+	}
+
+	target, err := vm.pop()
+	if err != nil {
+		return err
+	}
+
+	if target.Type != value.TypeObject {
+		vm.warnf("Attempt to read property \"%s\" on %s", name, target.TypeName())
+		return vm.push(value.NewNull())
+	}
+
+	if err := vm.checkPropertyAccess(target.Obj, name); err != nil {
+		return err
+	}
+
+	v, ok := target.Obj.Props.Get(value.StringKey(name))
+	if !ok {
+		vm.warnf("Undefined property: %s::$%s", target.Obj.Class.Name, name)
+	}
+	return vm.push(v)
+}
+
+// handlePropGetQuiet is PROP_GET for isset, empty and ??. Properties that
+// are missing or not accessible from here read as null, and nothing is
+// reported.
+func handlePropGetQuiet(vm *VM) error {
+	name, err := vm.readName()
+	if err != nil {
+		return err
+	}
+
+	target, err := vm.pop()
+	if err != nil {
+		return err
+	}
+
+	if target.Type != value.TypeObject {
+		return vm.push(value.NewNull())
+	}
+
+	prop, declared := target.Obj.Class.Property(name)
+	if declared && !vm.canAccess(prop.Class, prop.Visibility) {
+		return vm.push(value.NewNull())
+	}
+
+	v, _ := target.Obj.Props.Get(value.StringKey(name))
+	return vm.push(v)
+}
+
+// handlePropUnset pops an object and removes one of its properties. Later
+// reads of the property warn like reads of one that was never set.
+func handlePropUnset(vm *VM) error {
+	name, err := vm.readName()
+	if err != nil {
+		return err
+	}
+
+	target, err := vm.pop()
+	if err != nil {
+		return err
+	}
+
+	if target.Type != value.TypeObject {
+		return nil
+	}
+
+	if err := vm.checkPropertyAccess(target.Obj, name); err != nil {
+		return err
+	}
+
+	target.Obj.Props.Delete(value.StringKey(name))
+	return nil
+}
+
+// handlePropSet pops a value and an object and stores the value in a
+// property. Properties that are not declared are created, public.
+func handlePropSet(vm *VM) error {
+	name, err := vm.readName()
+	if err != nil {
+		return err
+	}
+
+	target, v, err := vm.pop2()
+	if err != nil {
+		return err
+	}
+
+	if target.Type != value.TypeObject {
+		return vm.throwf("Error", "Attempt to assign property \"%s\" on %s", name, target.TypeName())
+	}
+
+	if err := vm.checkPropertyAccess(target.Obj, name); err != nil {
+		return err
+	}
+
+	target.Obj.Props.Set(value.StringKey(name), v)
+	return nil
+}
+
+func (vm *VM) checkPropertyAccess(obj *value.Object, name string) error {
+	prop, declared := obj.Class.Property(name)
+	if declared && !vm.canAccess(prop.Class, prop.Visibility) {
+		return vm.throwf("Error", "Cannot access %s property %s::$%s", prop.Visibility, obj.Class.Name, name)
+	}
+	return nil
+}
+
+// canAccess reports whether a member that class declares with the given
+// visibility can be used from the running code. Private members are only
+// visible to the methods of class, protected members to the methods of
+// the classes related to it by inheritance.
+func (vm *VM) canAccess(class *value.Class, visibility value.Visibility) bool {
+	scope := vm.scope()
+	switch visibility {
+	case value.Private:
+		return scope == class
+	case value.Protected:
+		return scope != nil && (scope.InstanceOf(class) || class.InstanceOf(scope))
+	default:
+		return true
+	}
+}
+
+// scope returns the class of the running method, or nil outside methods.
+func (vm *VM) scope() *value.Class {
+	if len(vm.frames) == 0 {
+		return nil
+	}
+	return vm.frames[len(vm.frames)-1].scope
+}
+
+// scopeName describes the calling scope the way PHP error messages do.
+func (vm *VM) scopeName() string {
+	if scope := vm.scope(); scope != nil {
+		return "scope " + scope.Name
+	}
+	return "global scope"
+}
+
+func (vm *VM) objectToStringError(v value.Value) error {
+	return vm.throwf("Error", "Object of class %s could not be converted to string", v.TypeName())
+}
@@ -0,0 +1,52 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package vm
+
+import (
+	"github.com/neokofg/php-compiler/internal/vm/value"
+)
+
+func (vm *VM) push(v value.Value) error {
+	if len(vm.stack) >= StackSize {
+		return vm.errorf("Stack overflow")
+	}
+	vm.stack = append(vm.stack, v)
+	return nil
+}
+
+func (vm *VM) pop() (value.Value, error) {
+	if len(vm.stack) == 0 {
+		return value.Value{}, vm.errorf("Stack underflow")
+	}
+	v := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return v, nil
+}
+
+// pop2 pops the right and then the left operand of a binary instruction.
+func (vm *VM) pop2() (value.Value, value.Value, error) {
+	if len(vm.stack) < 2 {
+		return value.Value{}, value.Value{}, vm.errorf("Stack underflow, need 2 elements, have %d", len(vm.stack))
+	}
+	b := vm.stack[len(vm.stack)-1]
+	a := vm.stack[len(vm.stack)-2]
+	vm.stack = vm.stack[:len(vm.stack)-2]
+	return a, b, nil
+}
+
+func (vm *VM) readByte() (byte, error) {
+	if vm.ip >= len(vm.code) {
+		return 0, vm.errorf("Unexpected end of bytecode")
+	}
+	b := vm.code[vm.ip]
+	vm.ip++
+	return b, nil
+}
+
+func (vm *VM) readUint16() (uint16, error) {
+	if vm.ip+1 >= len(vm.code) {
+		return 0, vm.errorf("Unexpected end of bytecode while reading uint16")
+	}
+	v := uint16(vm.code[vm.ip]) | uint16(vm.code[vm.ip+1])<<8
+	vm.ip += 2
+	return v, nil
+}
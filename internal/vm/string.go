@@ -0,0 +1,24 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package vm
+
+import (
+	"github.com/neokofg/php-compiler/internal/vm/value"
+)
+
+func handleConcat(vm *VM) error {
+	a, b, err := vm.pop2()
+	if err != nil {
+		return err
+	}
+
+	if a.Type == value.TypeArray || b.Type == value.TypeArray {
+		vm.warnf("Array to string conversion")
+	}
+	for _, v := range []value.Value{a, b} {
+		if v.Type == value.TypeObject {
+			return vm.objectToStringError(v)
+		}
+	}
+
+	return vm.push(value.NewString(a.ToString() + b.ToString()))
+}
@@ -0,0 +1,388 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package value
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Key is an array key. PHP arrays are keyed by ints and strings only,
+// other key types are converted by ToKey.
+type Key struct {
+	IsString bool
+	Int      int64
+	Str      string
+}
+
+func IntKey(n int64) Key {
+	return Key{Int: n}
+}
+
+func StringKey(s string) Key {
+	return Key{IsString: true, Str: s}
+}
+
+func (k Key) ToValue() Value {
+	if k.IsString {
+		return NewString(k.Str)
+	}
+	return NewInt(k.Int)
+}
+
+// String formats the key the way PHP quotes it in warnings: 5 or "name".
+func (k Key) String() string {
+	if k.IsString {
+		return strconv.Quote(k.Str)
+	}
+	return strconv.FormatInt(k.Int, 10)
+}
+
+// ToKey converts v to an array key: integer strings such as "5" become
+// ints, floats are truncated, bools become 0 or 1 and null becomes "".
+func ToKey(v Value) (Key, error) {
+	switch v.Type {
+	case TypeInt:
+		return IntKey(v.Int), nil
+	case TypeString:
+		if n, ok := canonicalInt(v.Str); ok {
+			return IntKey(n), nil
+		}
+		return StringKey(v.Str), nil
+	case TypeFloat:
+		return IntKey(floatToInt(v.Float)), nil
+	case TypeBool:
+		return IntKey(v.ToInt()), nil
+	case TypeNull:
+		return StringKey(""), nil
+	default:
+		return Key{}, fmt.Errorf("Illegal offset type %s", v.TypeName())
+	}
+}
+
+// canonicalInt reports whether s is the decimal form of an int64 without
+// leading zeros or a plus sign, which PHP stores as an int key.
+func canonicalInt(s string) (int64, bool) {
+	if s == "" || s == "-0" || len(s) > 20 {
+		return 0, false
+	}
+
+	digits := s
+	if digits[0] == '-' {
+		digits = digits[1:]
+	}
+	if digits == "" || (digits[0] == '0' && len(digits) > 1) {
+		return 0, false
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return 0, false
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	return n, err == nil
+}
+
+type entry struct {
+	key Key
+	val Value
+}
+
+// deleted reports whether the entry was unset. Like in PHP's hash table,
+// Delete leaves an undefined value in place of the element, so that it
+// does not move the elements after it.
+func (e entry) deleted() bool {
+	return e.val.Type == TypeUndef
+}
+
+// Array is PHP's ordered hash map. Arrays have value semantics: Refs counts
+// the variables and array elements that hold the array, and a write to an
+// array held more than once goes to a copy (see Separate).
+type Array struct {
+	entries   []entry
+	index     map[Key]int
+	count     int   // elements in entries, the deleted entries aside
+	cursor    int   // a position in entries, see slot
+	cursorPos int   // number of elements in entries[:cursor]
+	nextIndex int64 // key used by Append, once an int key was stored
+	hasIntKey bool
+	full      bool // MaxInt64 is used, Append has no key left
+	Refs      int
+}
+
+func NewArray() *Array {
+	return &Array{index: make(map[Key]int)}
+}
+
+func NewArrayValue(arr *Array) Value {
+	return Value{Type: TypeArray, Arr: arr}
+}
+
+func (a *Array) Len() int {
+	return a.count
+}
+
+// At returns the i-th element in insertion order.
+func (a *Array) At(i int) (Key, Value) {
+	e := a.entries[a.slot(i)]
+	return e.key, Deref(e.val)
+}
+
+// slot returns the position in entries of the i-th element. Elements are
+// mostly visited in order, so the search starts at the cursor left by the
+// previous call rather than at the first entry.
+func (a *Array) slot(i int) int {
+	if a.count == len(a.entries) {
+		return i
+	}
+
+	s, n := a.cursor, a.cursorPos
+	for n > i {
+		s--
+		if !a.entries[s].deleted() {
+			n--
+		}
+	}
+	for a.entries[s].deleted() || n < i {
+		if !a.entries[s].deleted() {
+			n++
+		}
+		s++
+	}
+
+	a.cursor, a.cursorPos = s, n
+	return s
+}
+
+func (a *Array) Get(k Key) (Value, bool) {
+	i, ok := a.index[k]
+	if !ok {
+		return NewNull(), false
+	}
+	return Deref(a.entries[i].val), true
+}
+
+// RefAt binds the i-th element to a reference, unless it is bound
+// already, and returns the reference.
+func (a *Array) RefAt(i int) Value {
+	e := &a.entries[a.slot(i)]
+	if e.val.Type != TypeReference {
+		e.val = NewReference(e.val)
+	}
+	return e.val
+}
+
+// Set stores v under k. The array takes a reference to v and drops the
+// one it held to the value it replaces. An element bound to a reference
+// is written through it.
+func (a *Array) Set(k Key, v Value) {
+	if i, ok := a.index[k]; ok {
+		Assign(&a.entries[i].val, v)
+		return
+	}
+
+	Retain(v)
+
+	a.index[k] = len(a.entries)
+	a.entries = append(a.entries, entry{key: k, val: v})
+	a.count++
+
+	if !k.IsString && (!a.hasIntKey || k.Int >= a.nextIndex) {
+		a.hasIntKey = true
+		if k.Int == math.MaxInt64 {
+			a.full = true
+		} else {
+			a.nextIndex = k.Int + 1
+		}
+	}
+}
+
+// Append stores v under the next integer key: one past the largest int
+// key, or 0 when there is none yet. As in PHP 8.3, [-5 => 'a'] continues
+// with -4.
+func (a *Array) Append(v Value) error {
+	if a.full {
+		return fmt.Errorf("Cannot add element to the array as the next element is already occupied")
+	}
+	a.Set(IntKey(a.nextIndex), v)
+	return nil
+}
+
+// Pop removes the last element and returns its value, which the array no
+// longer holds. Like PHP's array_pop, it gives the next appended element
+// the removed key again when that key was the last one used by Append.
+func (a *Array) Pop() (Value, bool) {
+	if a.count == 0 {
+		return NewNull(), false
+	}
+
+	e := a.entries[len(a.entries)-1]
+	a.entries = a.entries[:len(a.entries)-1]
+	a.count--
+	a.trim()
+	delete(a.index, e.key)
+	Release(e.val)
+	e.val = Deref(e.val)
+
+	switch {
+	case e.key.IsString:
+	case a.full && e.key.Int == math.MaxInt64:
+		a.full = false
+		a.nextIndex = math.MaxInt64
+	case e.key.Int == a.nextIndex-1:
+		a.nextIndex--
+	}
+	return e.val, true
+}
+
+// Delete removes the element under k, if any. Unlike Pop, it leaves the
+// key used by the next Append alone, as unset does in PHP.
+//
+// The entry is only marked as deleted. Once deleted entries outnumber the
+// elements, they are dropped in one pass, which keeps Delete O(1) on
+// average.
+func (a *Array) Delete(k Key) {
+	i, ok := a.index[k]
+	if !ok {
+		return
+	}
+
+	Release(a.entries[i].val)
+	a.entries[i] = entry{val: NewUndef()}
+	delete(a.index, k)
+	a.count--
+	if i < a.cursor {
+		a.cursorPos--
+	}
+
+	a.trim()
+	if len(a.entries)-a.count > a.count {
+		a.compact()
+	}
+}
+
+// trim drops the deleted entries at the end, so that the last entry is
+// always the last element.
+func (a *Array) trim() {
+	n := len(a.entries)
+	for n > 0 && a.entries[n-1].deleted() {
+		n--
+	}
+	clear(a.entries[n:])
+	a.entries = a.entries[:n]
+
+	if a.cursor > n {
+		a.cursor, a.cursorPos = n, a.count
+	}
+}
+
+// compact drops the deleted entries and moves the elements up, in order.
+func (a *Array) compact() {
+	live := a.entries[:0]
+	for _, e := range a.entries {
+		if !e.deleted() {
+			a.index[e.key] = len(live)
+			live = append(live, e)
+		}
+	}
+	clear(a.entries[len(live):])
+	a.entries = live
+	a.cursor, a.cursorPos = 0, 0
+}
+
+// Copy returns a shallow copy. Nested arrays are shared with the copy and
+// are themselves copied when one of the two arrays writes to them. Like in
+// PHP, an element bound to a reference that some variable still holds
+// stays shared, the others are copied as plain values.
+func (a *Array) Copy() *Array {
+	c := &Array{
+		entries:   make([]entry, 0, a.count),
+		index:     make(map[Key]int, a.count),
+		count:     a.count,
+		nextIndex: a.nextIndex,
+		hasIntKey: a.hasIntKey,
+		full:      a.full,
+	}
+	for _, e := range a.entries {
+		if e.deleted() {
+			continue
+		}
+		if e.val.Type == TypeReference && e.val.Ref.Refs <= 1 {
+			e.val = e.val.Ref.Value
+		}
+		c.index[e.key] = len(c.entries)
+		c.entries = append(c.entries, e)
+		Retain(e.val)
+	}
+	return c
+}
+
+// Separate returns an array that can be written without affecting any
+// other holder: a itself when at most one variable refers to it, a copy
+// otherwise.
+func (a *Array) Separate() *Array {
+	if a.Refs <= 1 {
+		return a
+	}
+	return a.Copy()
+}
+
+// Retain records one more holder of v when v is an array or a reference.
+func Retain(v Value) {
+	switch v.Type {
+	case TypeArray:
+		v.Arr.Refs++
+	case TypeReference:
+		v.Ref.Refs++
+	}
+}
+
+// Release drops a holder of v when v is an array or a reference.
+func Release(v Value) {
+	switch {
+	case v.Type == TypeArray && v.Arr.Refs > 0:
+		v.Arr.Refs--
+	case v.Type == TypeReference && v.Ref.Refs > 0:
+		v.Ref.Refs--
+	}
+}
+
+// compareArrays compares arrays like PHP: the smaller array is less, and
+// arrays of the same size are compared element by element. ok is false
+// when a key of a is missing in b, which makes the arrays uncomparable.
+func compareArrays(a, b *Array) (result int, ok bool) {
+	if a.Len() != b.Len() {
+		return compareInt(int64(a.Len()), int64(b.Len())), true
+	}
+
+	for _, e := range a.entries {
+		if e.deleted() {
+			continue
+		}
+		other, found := b.Get(e.key)
+		if !found {
+			return 1, false
+		}
+		if c := Compare(Deref(e.val), other); c != 0 {
+			return c, true
+		}
+	}
+	return 0, true
+}
+
+// identicalArrays implements === on arrays: the same key/value pairs in
+// the same order, with identical values.
+func identicalArrays(a, b *Array) bool {
+	if a.Len() != b.Len() {
+		return false
+	}
+	for i := 0; i < a.Len(); i++ {
+		ka, va := a.At(i)
+		kb, vb := b.At(i)
+		if ka != kb || !Identical(va, vb) {
+			return false
+		}
+	}
+	return true
+}
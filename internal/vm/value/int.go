@@ -0,0 +1,51 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package value
+
+import "math"
+
+// AddInt adds two ints and reports false when the sum overflows, which PHP
+// computes as a float instead.
+func AddInt(a, b int64) (int64, bool) {
+	result := a + b
+	return result, (result > a) == (b > 0)
+}
+
+// SubInt subtracts b from a and reports false on overflow, like AddInt.
+func SubInt(a, b int64) (int64, bool) {
+	result := a - b
+	return result, (result < a) == (b > 0)
+}
+
+// MulInt multiplies two ints and reports false when the product overflows,
+// which PHP computes as a float instead.
+func MulInt(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	result := a * b
+	if (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) || result/b != a {
+		return 0, false
+	}
+	return result, true
+}
+
+// PowInt computes base**exp for exp >= 0 by squaring and reports false on
+// overflow.
+func PowInt(base, exp int64) (int64, bool) {
+	result := int64(1)
+	for exp > 0 {
+		var ok bool
+		if exp&1 == 1 {
+			if result, ok = MulInt(result, base); !ok {
+				return 0, false
+			}
+		}
+		exp >>= 1
+		if exp > 0 {
+			if base, ok = MulInt(base, base); !ok {
+				return 0, false
+			}
+		}
+	}
+	return result, true
+}
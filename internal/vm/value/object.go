@@ -0,0 +1,199 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package value
+
+import "strings"
+
+// Visibility restricts where a property or method can be used from.
+type Visibility int
+
+const (
+	Public Visibility = iota
+	Protected
+	Private
+)
+
+func (v Visibility) String() string {
+	switch v {
+	case Protected:
+		return "protected"
+	case Private:
+		return "private"
+	default:
+		return "public"
+	}
+}
+
+// Property is a declared property and the value new objects start with.
+type Property struct {
+	Name       string
+	Class      *Class // the class that declares the property
+	Default    Value
+	Visibility Visibility
+}
+
+// NativeMethod implements a method of a built-in class. this is nil for
+// static methods; args are in source order.
+type NativeMethod func(this *Object, args []Value) (Value, error)
+
+// Method is the code of a method. The FUNC_DECL at Addr of an instance
+// method binds $this to the first local, so ParamCount does not include it.
+// Abstract methods have no code; methods of built-in classes run Native.
+type Method struct {
+	Name       string
+	Class      *Class // the class that declares the method
+	Addr       int
+	ParamCount int
+	Visibility Visibility
+	Static     bool
+	Abstract   bool
+	Native     NativeMethod
+}
+
+// Class is the run-time description of a class, an interface or a trait,
+// built by the CLASS_* instructions. Method names are case-insensitive,
+// property names are not.
+type Class struct {
+	Name       string
+	Parent     *Class
+	Interfaces []*Class // every interface the class implements, inherited ones included
+	Abstract   bool
+	Final      bool
+	Interface  bool
+	Trait      bool
+	Properties []Property
+	props      map[string]int
+	methods    map[string]*Method
+}
+
+func NewClass(name string) *Class {
+	return &Class{
+		Name:    name,
+		props:   make(map[string]int),
+		methods: make(map[string]*Method),
+	}
+}
+
+// AddProperty declares a property, replacing an earlier one of the same name.
+func (c *Class) AddProperty(p Property) {
+	Retain(p.Default)
+
+	if i, ok := c.props[p.Name]; ok {
+		Release(c.Properties[i].Default)
+		c.Properties[i] = p
+		return
+	}
+	c.props[p.Name] = len(c.Properties)
+	c.Properties = append(c.Properties, p)
+}
+
+func (c *Class) Property(name string) (*Property, bool) {
+	i, ok := c.props[name]
+	if !ok {
+		return nil, false
+	}
+	return &c.Properties[i], true
+}
+
+// Inherit makes c a subclass of parent. It runs before c declares its own
+// members, which then replace the inherited ones.
+func (c *Class) Inherit(parent *Class) {
+	c.Parent = parent
+	for _, p := range parent.Properties {
+		c.AddProperty(p)
+	}
+	for key, m := range parent.methods {
+		c.methods[key] = m
+	}
+	c.Interfaces = append(c.Interfaces, parent.Interfaces...)
+}
+
+// Implement adds iface and the interfaces it extends to the interfaces of c.
+func (c *Class) Implement(iface *Class) {
+	for _, known := range append([]*Class{iface}, iface.Interfaces...) {
+		if !c.InstanceOf(known) {
+			c.Interfaces = append(c.Interfaces, known)
+		}
+	}
+}
+
+// InstanceOf reports whether c is other, extends it or implements it.
+func (c *Class) InstanceOf(other *Class) bool {
+	for class := c; class != nil; class = class.Parent {
+		if class == other {
+			return true
+		}
+	}
+	for _, iface := range c.Interfaces {
+		if iface == other {
+			return true
+		}
+	}
+	return false
+}
+
+// KindName returns "class", "interface" or "trait" for use in messages.
+func (c *Class) KindName() string {
+	switch {
+	case c.Interface:
+		return "interface"
+	case c.Trait:
+		return "trait"
+	default:
+		return "class"
+	}
+}
+
+func (c *Class) AddMethod(m *Method) {
+	c.methods[strings.ToLower(m.Name)] = m
+}
+
+func (c *Class) Method(name string) (*Method, bool) {
+	m, ok := c.methods[strings.ToLower(name)]
+	return m, ok
+}
+
+// Object is an instance of a class. Unlike arrays, objects are handles:
+// copying the value shares the object, so a property write through one
+// variable is seen through all of them.
+type Object struct {
+	Class   *Class
+	Props   *Array   // property values by name, declared properties first
+	Closure *Closure // function of a Closure object, nil for other classes
+}
+
+// Closure is the function a Closure object calls. The FUNC_DECL at Addr
+// binds the parameters; Captured holds the values of the use variables,
+// followed by $this when the closure was created in an instance method,
+// which a call copies into the locals that follow the parameters.
+type Closure struct {
+	Addr     int
+	Captured []Value
+	This     *Object // object of the method that created the closure
+	Scope    *Class  // class of that method, nil outside of methods
+	Static   *Class  // class static:: referred to there
+}
+
+// NewObject creates an object whose properties hold the class defaults.
+func NewObject(class *Class) *Object {
+	obj := &Object{Class: class, Props: NewArray()}
+	for _, p := range class.Properties {
+		obj.Props.Set(StringKey(p.Name), p.Default)
+	}
+	return obj
+}
+
+func NewObjectValue(obj *Object) Value {
+	return Value{Type: TypeObject, Obj: obj}
+}
+
+// compareObjects compares objects of the same class property by property,
+// like ==. Objects of different classes are uncomparable.
+func compareObjects(a, b *Object) (result int, ok bool) {
+	if a == b {
+		return 0, true
+	}
+	if a.Class != b.Class {
+		return 1, false
+	}
+	return compareArrays(a.Props, b.Props)
+}
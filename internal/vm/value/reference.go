@@ -0,0 +1,44 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package value
+
+// Reference is a variable shared by several scopes, such as one a closure
+// captures with use (&$name), or an array element a foreach by reference
+// binds its variable to. Each variable slot and element bound to it holds
+// a TypeReference value: reading it yields the value inside, writing it
+// replaces that value for all of them. Refs counts them, like for arrays.
+type Reference struct {
+	Value Value
+	Refs  int
+}
+
+// NewReference moves v into a new reference, held by the slot v came from.
+func NewReference(v Value) Value {
+	return Value{Type: TypeReference, Ref: &Reference{Value: v, Refs: 1}}
+}
+
+// Deref returns the value v refers to, or v itself when it is no reference.
+func Deref(v Value) Value {
+	if v.Type == TypeReference {
+		return v.Ref.Value
+	}
+	return v
+}
+
+// Bind makes the variable slot hold ref, dropping the value or reference
+// it held before.
+func Bind(slot *Value, ref Value) {
+	Retain(ref)
+	Release(*slot)
+	*slot = ref
+}
+
+// Assign stores v in the variable slot, through the reference the slot is
+// bound to if any.
+func Assign(slot *Value, v Value) {
+	if slot.Type == TypeReference {
+		slot = &slot.Ref.Value
+	}
+	Retain(v)
+	Release(*slot)
+	*slot = v
+}
@@ -0,0 +1,424 @@
+// Licensed under GNU GPL v3. See LICENSE file for details.
+package value
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+type Type int
+
+const (
+	TypeInt Type = iota
+	TypeString
+	TypeBool
+	TypeNull
+	TypeFloat
+	TypeArray
+	TypeObject
+	TypeReference // only held by variable slots and array elements, see Reference
+	TypeUndef     // only held by variable slots never assigned or unset, and by deleted array entries
+)
+
+// Precision is the number of significant digits used when a float is
+// converted to a string, like PHP's default `precision` ini setting.
+const Precision = 14
+
+type Value struct {
+	Type  Type
+	Int   int64
+	Float float64
+	Str   string
+	Bool  bool
+	Arr   *Array
+	Obj   *Object
+	Ref   *Reference
+}
+
+func NewInt(v int64) Value {
+	return Value{Type: TypeInt, Int: v}
+}
+
+func NewFloat(v float64) Value {
+	return Value{Type: TypeFloat, Float: v}
+}
+
+func NewString(v string) Value {
+	return Value{Type: TypeString, Str: v}
+}
+
+func NewBool(v bool) Value {
+	return Value{Type: TypeBool, Bool: v}
+}
+
+func NewNull() Value {
+	return Value{Type: TypeNull}
+}
+
+// NewUndef returns the content of a variable slot that holds no value;
+// reading the variable warns and yields null.
+func NewUndef() Value {
+	return Value{Type: TypeUndef}
+}
+
+// TypeName names the type of v in error messages; objects are named by
+// their class.
+func (v Value) TypeName() string {
+	switch v.Type {
+	case TypeInt:
+		return "int"
+	case TypeFloat:
+		return "float"
+	case TypeString:
+		return "string"
+	case TypeBool:
+		return "bool"
+	case TypeNull:
+		return "null"
+	case TypeArray:
+		return "array"
+	case TypeObject:
+		return v.Obj.Class.Name
+	default:
+		return "unknown"
+	}
+}
+
+func (v Value) ToInt() int64 {
+	switch v.Type {
+	case TypeInt:
+		return v.Int
+	case TypeFloat:
+		return floatToInt(v.Float)
+	case TypeString:
+		n, _ := parseNumberPrefix(v.Str)
+		return n.ToInt()
+	case TypeBool, TypeArray, TypeObject:
+		if v.ToBool() {
+			return 1
+		}
+		return 0
+	default:
+		return 0
+	}
+}
+
+func (v Value) ToFloat() float64 {
+	switch v.Type {
+	case TypeInt:
+		return float64(v.Int)
+	case TypeFloat:
+		return v.Float
+	case TypeString:
+		n, _ := parseNumberPrefix(v.Str)
+		return n.ToFloat()
+	case TypeBool, TypeArray, TypeObject:
+		if v.ToBool() {
+			return 1
+		}
+		return 0
+	default:
+		return 0
+	}
+}
+
+// ToNumber converts v to an int or a float the way arithmetic operators do.
+func (v Value) ToNumber() Value {
+	switch v.Type {
+	case TypeInt, TypeFloat:
+		return v
+	case TypeString:
+		n, _ := parseNumberPrefix(v.Str)
+		return n
+	default:
+		return NewInt(v.ToInt())
+	}
+}
+
+func (v Value) ToString() string {
+	switch v.Type {
+	case TypeInt:
+		return strconv.FormatInt(v.Int, 10)
+	case TypeFloat:
+		return FormatFloat(v.Float)
+	case TypeString:
+		return v.Str
+	case TypeBool:
+		if v.Bool {
+			return "1"
+		}
+		return ""
+	case TypeArray:
+		return "Array"
+	case TypeObject:
+		return "Object"
+	default:
+		return ""
+	}
+}
+
+func (v Value) ToBool() bool {
+	switch v.Type {
+	case TypeInt:
+		return v.Int != 0
+	case TypeFloat:
+		return v.Float != 0
+	case TypeString:
+		return v.Str != "" && v.Str != "0"
+	case TypeBool:
+		return v.Bool
+	case TypeArray:
+		return v.Arr.Len() > 0
+	case TypeObject:
+		return true
+	default:
+		return false
+	}
+}
+
+// FormatFloat formats f like PHP's echo: Precision significant digits,
+// scientific notation for very large or small magnitudes, and a ".0"
+// mantissa so that the result still reads as a float.
+func FormatFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NAN"
+	case math.IsInf(f, 1):
+		return "INF"
+	case math.IsInf(f, -1):
+		return "-INF"
+	}
+
+	s := strconv.FormatFloat(f, 'g', Precision, 64)
+	mantissa, exponent, scientific := strings.Cut(s, "e")
+	if !scientific {
+		return s
+	}
+
+	if !strings.Contains(mantissa, ".") {
+		mantissa += ".0"
+	}
+
+	expSign := exponent[0]
+	expDigits := strings.TrimLeft(exponent[1:], "0")
+	return mantissa + "E" + string(expSign) + expDigits
+}
+
+// floatToInt truncates f towards zero. Values without an integer
+// representation become 0, as in PHP 8 on 64-bit platforms.
+func floatToInt(f float64) int64 {
+	if math.IsNaN(f) || math.IsInf(f, 0) || f >= math.MaxInt64 || f < math.MinInt64 {
+		return 0
+	}
+	return int64(f)
+}
+
+// Identical implements the === operator: same type and same value.
+func Identical(a, b Value) bool {
+	if a.Type != b.Type {
+		return false
+	}
+
+	switch a.Type {
+	case TypeInt:
+		return a.Int == b.Int
+	case TypeFloat:
+		return a.Float == b.Float
+	case TypeString:
+		return a.Str == b.Str
+	case TypeBool:
+		return a.Bool == b.Bool
+	case TypeArray:
+		return identicalArrays(a.Arr, b.Arr)
+	case TypeObject:
+		return a.Obj == b.Obj
+	default:
+		return true
+	}
+}
+
+// LooseEquals implements the == operator following PHP 8 comparison rules.
+func LooseEquals(a, b Value) bool {
+	if a.Type == TypeArray && b.Type == TypeArray {
+		result, ok := compareArrays(a.Arr, b.Arr)
+		return ok && result == 0
+	}
+	if a.Type == TypeObject && b.Type == TypeObject {
+		result, ok := compareObjects(a.Obj, b.Obj)
+		return ok && result == 0
+	}
+	return Compare(a, b) == 0
+}
+
+// Compare implements the <=> operator following PHP 8 comparison rules
+// and returns -1, 0 or 1.
+func Compare(a, b Value) int {
+	switch {
+	case a.Type == TypeBool || b.Type == TypeBool || (a.Type == TypeNull && b.Type != TypeString) || (b.Type == TypeNull && a.Type != TypeString):
+		return compareBool(a.ToBool(), b.ToBool())
+	case a.Type == TypeNull || b.Type == TypeNull:
+		return strings.Compare(a.ToString(), b.ToString())
+	case a.Type == TypeObject && b.Type == TypeObject:
+		result, _ := compareObjects(a.Obj, b.Obj)
+		return result
+	case a.Type == TypeObject:
+		return 1 // an object is greater than any other value
+	case b.Type == TypeObject:
+		return -1
+	case a.Type == TypeArray && b.Type == TypeArray:
+		result, _ := compareArrays(a.Arr, b.Arr)
+		return result
+	case a.Type == TypeArray:
+		return 1 // an array is greater than any scalar
+	case b.Type == TypeArray:
+		return -1
+	case a.isNumber() && b.isNumber():
+		return compareNumbers(a, b)
+	case a.Type == TypeString && b.Type == TypeString:
+		an, aNumeric := ParseNumeric(a.Str)
+		bn, bNumeric := ParseNumeric(b.Str)
+		if aNumeric && bNumeric {
+			return compareNumbers(an, bn)
+		}
+		return sign(strings.Compare(a.Str, b.Str))
+	case a.isNumber():
+		if bn, ok := ParseNumeric(b.Str); ok {
+			return compareNumbers(a, bn)
+		}
+		return sign(strings.Compare(a.ToString(), b.Str))
+	default:
+		if an, ok := ParseNumeric(a.Str); ok {
+			return compareNumbers(an, b)
+		}
+		return sign(strings.Compare(a.Str, b.ToString()))
+	}
+}
+
+func (v Value) isNumber() bool {
+	return v.Type == TypeInt || v.Type == TypeFloat
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
+
+func compareNumbers(a, b Value) int {
+	if a.Type == TypeInt && b.Type == TypeInt {
+		return compareInt(a.Int, b.Int)
+	}
+	return compareFloat(a.ToFloat(), b.ToFloat())
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// compareFloat treats NAN as unordered: it is never equal to anything.
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a == b:
+		return 0
+	default:
+		return 1
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// ParseNumeric reports whether s is a PHP numeric string: an int or
+// float literal with optional surrounding whitespace.
+func ParseNumeric(s string) (Value, bool) {
+	n, consumed := parseNumberPrefix(s)
+	return n, consumed > 0 && strings.TrimRight(s[consumed:], " \t\n\r\v\f") == ""
+}
+
+// parseNumberPrefix parses the numeric prefix of s the way PHP casts
+// strings to numbers, returning the value and the number of bytes consumed.
+// The value is a float when the prefix has a fraction or an exponent, or
+// does not fit in an int.
+func parseNumberPrefix(s string) (Value, int) {
+	i := 0
+	for i < len(s) && isSpace(s[i]) {
+		i++
+	}
+
+	start := i
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+
+	intDigits := skipDigits(s, i)
+	fracDigits := 0
+	isFloat := false
+
+	i += intDigits
+	if i < len(s) && s[i] == '.' {
+		fracDigits = skipDigits(s, i+1)
+		if intDigits > 0 || fracDigits > 0 {
+			i += 1 + fracDigits
+			isFloat = true
+		}
+	}
+
+	if intDigits == 0 && fracDigits == 0 {
+		return NewInt(0), 0
+	}
+
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if j < len(s) && (s[j] == '+' || s[j] == '-') {
+			j++
+		}
+		if expDigits := skipDigits(s, j); expDigits > 0 {
+			i = j + expDigits
+			isFloat = true
+		}
+	}
+
+	if !isFloat {
+		if n, err := strconv.ParseInt(s[start:i], 10, 64); err == nil {
+			return NewInt(n), i
+		}
+	}
+
+	f, _ := strconv.ParseFloat(s[start:i], 64)
+	return NewFloat(f), i
+}
+
+func skipDigits(s string, i int) int {
+	n := 0
+	for i+n < len(s) && s[i+n] >= '0' && s[i+n] <= '9' {
+		n++
+	}
+	return n
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
@@ -0,0 +1,302 @@
+// PHP Compiler - compiles php code to IR and then running it on PHPC VM
+// Copyright (C) 2025  Andrey Vasilev (neokofg)
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+package vm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/neokofg/php-compiler/internal/compiler/bytecode"
+	"github.com/neokofg/php-compiler/internal/compiler/constant"
+	"github.com/neokofg/php-compiler/internal/vm/value"
+)
+
+const (
+	StackSize  = 1 << 16
+	VarCount   = 256
+	FrameCount = 1 << 14 // call depth, reached by runaway recursion
+)
+
+type HandlerFunc func(vm *VM) error
+
+// frame is a function activation. Locals grow on first store, so a frame
+// costs nothing for variables the function never touches.
+type frame struct {
+	addr       int // address of the FUNC_DECL of the running function
+	returnAddr int
+	argCount   int
+	stackBase  int // stack height below the arguments
+	locals     []value.Value
+	scope      *value.Class  // class of the running method, nil in functions
+	this       *value.Object // object of an instance method, nil otherwise
+	static     *value.Class  // class the method was called on, which static:: refers to
+	construct  *value.Object // object a constructor initializes, returned in place of its result
+	method     *value.Method // running method, nil in functions
+}
+
+type VM struct {
+	code      []byte
+	constants []value.Value
+	ip        int
+
+	stack     []value.Value
+	variables []value.Value
+	statics   []bool
+	frames    []frame
+	classes   map[string]*value.Class // keyed by lower-case name
+	functions map[string]int          // FUNC_DECL address of the functions bound so far
+	declaring *value.Class            // class the CLASS_PROP and CLASS_METHOD instructions add to
+
+	exceptions    []bytecode.Handler
+	file          string
+	lines         []bytecode.Line
+	functionNames map[int]string   // by FUNC_DECL address
+	variableNames []string         // by global slot
+	localNames    map[int][]string // by FUNC_DECL address, then by local slot
+
+	handlers [256]HandlerFunc
+
+	out     *bufio.Writer
+	running bool
+	debug   bool
+}
+
+func New(out io.Writer) *VM {
+	vm := &VM{
+		out: bufio.NewWriter(out),
+	}
+
+	vm.RegisterHandler(bytecode.OP_LOAD_CONST, handleLoadConst)
+	vm.RegisterHandler(bytecode.OP_PRINT, handlePrint)
+	vm.RegisterHandler(bytecode.OP_HALT, handleHalt)
+	vm.RegisterHandler(bytecode.OP_POP, handlePop)
+	vm.RegisterHandler(bytecode.OP_DUP, handleDup)
+	vm.RegisterHandler(bytecode.OP_TUCK, handleTuck)
+
+	vm.RegisterHandler(bytecode.OP_ADD, handleAdd)
+	vm.RegisterHandler(bytecode.OP_SUB, handleSub)
+	vm.RegisterHandler(bytecode.OP_MUL, handleMul)
+	vm.RegisterHandler(bytecode.OP_DIV, handleDiv)
+
+	vm.RegisterHandler(bytecode.OP_CONCAT, handleConcat)
+
+	vm.RegisterHandler(bytecode.OP_STORE_VAR, handleStoreVar)
+	vm.RegisterHandler(bytecode.OP_LOAD_VAR, handleLoadVar)
+	vm.RegisterHandler(bytecode.OP_STORE_LOCAL, handleStoreLocal)
+	vm.RegisterHandler(bytecode.OP_LOAD_LOCAL, handleLoadLocal)
+	vm.RegisterHandler(bytecode.OP_STATIC_INIT, handleStaticInit)
+	vm.RegisterHandler(bytecode.OP_LOAD_NULL, handleLoadNull)
+	vm.RegisterHandler(bytecode.OP_REF_VAR, handleRefVar)
+	vm.RegisterHandler(bytecode.OP_REF_LOCAL, handleRefLocal)
+	vm.RegisterHandler(bytecode.OP_UNSET_VAR, handleUnsetVar)
+	vm.RegisterHandler(bytecode.OP_UNSET_LOCAL, handleUnsetLocal)
+	vm.RegisterHandler(bytecode.OP_BIND_VAR, handleBindVar)
+	vm.RegisterHandler(bytecode.OP_BIND_LOCAL, handleBindLocal)
+	vm.RegisterHandler(bytecode.OP_LOAD_VAR_QUIET, handleLoadVarQuiet)
+	vm.RegisterHandler(bytecode.OP_LOAD_LOCAL_QUIET, handleLoadLocalQuiet)
+
+	vm.RegisterHandler(bytecode.OP_JUMP, handleJump)
+	vm.RegisterHandler(bytecode.OP_JUMP_IF_FALSE, handleJumpIfFalse)
+	vm.RegisterHandler(bytecode.OP_COALESCE, handleCoalesce)
+
+	vm.RegisterHandler(bytecode.OP_GT, handleGt)
+	vm.RegisterHandler(bytecode.OP_LT, handleLt)
+	vm.RegisterHandler(bytecode.OP_EQ, handleEq)
+	vm.RegisterHandler(bytecode.OP_NOT, handleNot)
+
+	vm.RegisterHandler(bytecode.OP_AND, handleAnd)
+	vm.RegisterHandler(bytecode.OP_OR, handleOr)
+
+	vm.RegisterHandler(bytecode.OP_INC, handleInc)
+	vm.RegisterHandler(bytecode.OP_DEC, handleDec)
+	vm.RegisterHandler(bytecode.OP_POST_INC, handlePostInc)
+	vm.RegisterHandler(bytecode.OP_POST_DEC, handlePostDec)
+
+	vm.RegisterHandler(bytecode.OP_MOD, handleMod)
+	vm.RegisterHandler(bytecode.OP_POW, handlePow)
+
+	vm.RegisterHandler(bytecode.OP_GTE, handleGte)
+	vm.RegisterHandler(bytecode.OP_LTE, handleLte)
+	vm.RegisterHandler(bytecode.OP_IDENTITY_EQ, handleIdentityEq)
+	vm.RegisterHandler(bytecode.OP_IDENTITY_NE, handleIdentityNe)
+	vm.RegisterHandler(bytecode.OP_SPACESHIP, handleSpaceship)
+
+	vm.RegisterHandler(bytecode.OP_BIT_AND, handleBitAnd)
+	vm.RegisterHandler(bytecode.OP_BIT_OR, handleBitOr)
+	vm.RegisterHandler(bytecode.OP_BIT_XOR, handleBitXor)
+	vm.RegisterHandler(bytecode.OP_BIT_NOT, handleBitNot)
+
+	vm.RegisterHandler(bytecode.OP_LSHIFT, handleLshift)
+	vm.RegisterHandler(bytecode.OP_RSHIFT, handleRshift)
+
+	vm.RegisterHandler(bytecode.OP_ASSIGN_ADD, handleAdd)
+	vm.RegisterHandler(bytecode.OP_ASSIGN_SUB, handleSub)
+	vm.RegisterHandler(bytecode.OP_ASSIGN_MUL, handleMul)
+	vm.RegisterHandler(bytecode.OP_ASSIGN_DIV, handleDiv)
+	vm.RegisterHandler(bytecode.OP_ASSIGN_MOD, handleMod)
+	vm.RegisterHandler(bytecode.OP_ASSIGN_CONCAT, handleConcat)
+	vm.RegisterHandler(bytecode.OP_ASSIGN_POW, handlePow)
+	vm.RegisterHandler(bytecode.OP_ASSIGN_BIT_AND, handleBitAnd)
+	vm.RegisterHandler(bytecode.OP_ASSIGN_BIT_OR, handleBitOr)
+	vm.RegisterHandler(bytecode.OP_ASSIGN_BIT_XOR, handleBitXor)
+	vm.RegisterHandler(bytecode.OP_ASSIGN_LSHIFT, handleLshift)
+	vm.RegisterHandler(bytecode.OP_ASSIGN_RSHIFT, handleRshift)
+
+	vm.RegisterHandler(bytecode.OP_BREAK, handleJump)
+	vm.RegisterHandler(bytecode.OP_CONTINUE, handleJump)
+
+	vm.RegisterHandler(bytecode.OP_FUNC_DECL, handleFuncDecl)
+	vm.RegisterHandler(bytecode.OP_FUNC_CALL, handleFuncCall)
+	vm.RegisterHandler(bytecode.OP_RETURN, handleReturn)
+	vm.RegisterHandler(bytecode.OP_ENTER_FUNC, handleEnterFunc)
+	vm.RegisterHandler(bytecode.OP_EXIT_FUNC, handleExitFunc)
+	vm.RegisterHandler(bytecode.OP_CALL_NATIVE, handleCallNative)
+	vm.RegisterHandler(bytecode.OP_CLOSURE, handleClosure)
+	vm.RegisterHandler(bytecode.OP_CALL_INDIRECT, handleCallIndirect)
+	vm.RegisterHandler(bytecode.OP_FUNC_BIND, handleFuncBind)
+	vm.RegisterHandler(bytecode.OP_CALL_NAMED, handleCallNamed)
+
+	vm.RegisterHandler(bytecode.OP_ARRAY_NEW, handleArrayNew)
+	vm.RegisterHandler(bytecode.OP_ARRAY_PUSH, handleArrayPush)
+	vm.RegisterHandler(bytecode.OP_ARRAY_INSERT, handleArrayInsert)
+	vm.RegisterHandler(bytecode.OP_ARRAY_GET, handleArrayGet)
+	vm.RegisterHandler(bytecode.OP_ARRAY_SET, handleArraySet)
+	vm.RegisterHandler(bytecode.OP_ARRAY_FETCH, handleArrayFetch)
+	vm.RegisterHandler(bytecode.OP_ARRAY_GET_QUIET, handleArrayGetQuiet)
+	vm.RegisterHandler(bytecode.OP_ARRAY_FETCH_QUIET, handleArrayFetchQuiet)
+	vm.RegisterHandler(bytecode.OP_ARRAY_UNSET, handleArrayUnset)
+
+	vm.RegisterHandler(bytecode.OP_FOREACH_INIT, handleForeachInit)
+	vm.RegisterHandler(bytecode.OP_FOREACH_NEXT, handleForeachNext)
+	vm.RegisterHandler(bytecode.OP_FOREACH_END, handleForeachEnd)
+
+	vm.RegisterHandler(bytecode.OP_CLASS_DECL, handleClassDecl)
+	vm.RegisterHandler(bytecode.OP_CLASS_PROP, handleClassProp)
+	vm.RegisterHandler(bytecode.OP_CLASS_METHOD, handleClassMethod)
+	vm.RegisterHandler(bytecode.OP_CLASS_EXTENDS, handleClassExtends)
+	vm.RegisterHandler(bytecode.OP_CLASS_IMPLEMENTS, handleClassImplements)
+	vm.RegisterHandler(bytecode.OP_NEW, handleNew)
+	vm.RegisterHandler(bytecode.OP_PROP_GET, handlePropGet)
+	vm.RegisterHandler(bytecode.OP_PROP_SET, handlePropSet)
+	vm.RegisterHandler(bytecode.OP_PROP_GET_QUIET, handlePropGetQuiet)
+	vm.RegisterHandler(bytecode.OP_PROP_UNSET, handlePropUnset)
+	vm.RegisterHandler(bytecode.OP_INSTANCEOF, handleInstanceof)
+	vm.RegisterHandler(bytecode.OP_METHOD_CALL, handleMethodCall)
+	vm.RegisterHandler(bytecode.OP_STATIC_CALL, handleStaticCall)
+
+	vm.RegisterHandler(bytecode.OP_THROW, handleThrow)
+	vm.RegisterHandler(bytecode.OP_CATCH, handleCatch)
+
+	return vm
+}
+
+func (vm *VM) RegisterHandler(opcode byte, handler HandlerFunc) {
+	vm.handlers[opcode] = handler
+}
+
+func (vm *VM) SetDebugMode(debug bool) {
+	vm.debug = debug
+}
+
+func (vm *VM) Execute(code []byte, constants []constant.Constant) error {
+	pool, err := loadConstants(constants)
+	if err != nil {
+		return err
+	}
+
+	vm.code = code
+	vm.constants = pool
+	vm.ip = 0
+	vm.stack = make([]value.Value, 0, 64)
+	vm.variables = make([]value.Value, VarCount)
+	for i := range vm.variables {
+		vm.variables[i] = value.NewUndef()
+	}
+	vm.statics = make([]bool, VarCount)
+	vm.frames = nil
+	vm.classes = make(map[string]*value.Class)
+	vm.functions = make(map[string]int)
+	vm.declaring = nil
+	vm.running = true
+	vm.declareBuiltinClasses()
+
+	defer vm.out.Flush()
+
+	for vm.running && vm.ip < len(vm.code) {
+		err := vm.step()
+		if t, ok := err.(*thrown); ok {
+			err = vm.unwind(t.exception, -1)
+		}
+		if err != nil {
+			vm.running = false
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (vm *VM) step() error {
+	opcode := vm.code[vm.ip]
+	vm.ip++
+
+	if vm.debug {
+		fmt.Fprintf(os.Stderr, "DEBUG: ip=%d, opcode=0x%02X, stack=%d\n", vm.ip-1, opcode, len(vm.stack))
+	}
+
+	handler := vm.handlers[opcode]
+	if handler == nil {
+		return vm.errorf("Invalid opcode: 0x%02X at position %d", opcode, vm.ip-1)
+	}
+
+	return handler(vm)
+}
+
+func loadConstants(constants []constant.Constant) ([]value.Value, error) {
+	pool := make([]value.Value, len(constants))
+
+	for i, c := range constants {
+		switch c.Type {
+		case "string":
+			pool[i] = value.NewString(c.Value)
+		case "int":
+			num, err := strconv.ParseInt(c.Value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("Cannot convert const '%s' to integer: %v", c.Value, err)
+			}
+			pool[i] = value.NewInt(num)
+		case "float":
+			num, err := strconv.ParseFloat(c.Value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("Cannot convert const '%s' to float: %v", c.Value, err)
+			}
+			pool[i] = value.NewFloat(num)
+		case "bool":
+			b, err := strconv.ParseBool(c.Value)
+			if err != nil {
+				return nil, fmt.Errorf("Cannot convert const '%s' to bool: %v", c.Value, err)
+			}
+			pool[i] = value.NewBool(b)
+		default:
+			return nil, fmt.Errorf("Unknown constant type '%s'", c.Type)
+		}
+	}
+
+	return pool, nil
+}
@@ -3,6 +3,7 @@
 #define VM_COMMON_H
 
 #include <stdint.h>
+#include <inttypes.h>
 #include <stddef.h>
 #include <stdbool.h>
 #include <stdio.h>
@@ -10,8 +11,11 @@
 #include <string.h>
 
 typedef uint8_t byte_t;
-typedef int32_t int_t;
-typedef int_t status_t;
+typedef int64_t int_t;
+typedef int32_t status_t;
+
+// INT_FMT is the printf conversion for int_t, e.g. printf("%" INT_FMT, n).
+#define INT_FMT PRId64
 
 #define STATUS_SUCCESS 0
 #define STATUS_ERROR -1
@@ -21,6 +25,7 @@ typedef int_t status_t;
 #define STATUS_INVALID_OPCODE -5
 #define STATUS_OUT_OF_MEMORY -6
 #define STATUS_DIVISION_BY_ZERO -7
+#define STATUS_THROWN -8  // an exception is in flight, see VMContext.exception
 
 #include "config.h"
 
@@ -8,6 +8,10 @@
 
 #define VAR_COUNT 256
 
+#define FRAME_COUNT 256
+
+#define LOCAL_COUNT 256
+
 // Debug configuration
 // #define VM_DEBUG_TRACE
 
@@ -0,0 +1,63 @@
+/* Licensed under GNU GPL v3. See LICENSE file for details. */
+#ifndef VM_ARRAY_H
+#define VM_ARRAY_H
+
+#include "../common.h"
+#include "value_handler.h"
+
+// ArrayKey is an int or a string key; other values are converted by
+// array_to_key.
+typedef struct {
+    bool is_string;
+    int_t int_key;
+    char* str_key;
+} ArrayKey;
+
+// ArrayEntry is an element, or a deleted one when value is TYPE_UNDEF.
+typedef struct {
+    ArrayKey key;
+    Value value;
+} ArrayEntry;
+
+// Array is PHP's ordered hash map: entries in insertion order plus an open
+// addressing index into them. Arrays have value semantics: refs counts the
+// variables and array elements holding the array, and a write to an array
+// held more than once goes to a copy (see array_separate).
+typedef struct Array {
+    ArrayEntry* entries;
+    size_t count;       // elements in entries, the deleted entries aside
+    size_t used;        // entries in use, the deleted ones included
+    size_t capacity;
+    size_t cursor;      // a position in entries, see entry_at
+    size_t cursor_pos;  // number of elements before entries[cursor]
+
+    int32_t* index;     // entry number per slot, -1 when the slot is empty
+    size_t index_size;  // always a power of two
+
+    int_t next_index;   // key used by array_append, once an int key was stored
+    bool has_int_key;
+    bool full;          // INT64_MAX is used, array_append has no key left
+
+    int refs;
+} Array;
+
+Array* array_new(void);
+Array* array_copy(const Array* array);
+Array* array_separate(Array* array);
+
+bool array_get(const Array* array, ArrayKey key, Value* out);
+Value array_at(Array* array, size_t i);
+ArrayKey array_key_at(Array* array, size_t i);
+Value array_ref_at(Array* array, size_t i);
+void array_set(Array* array, ArrayKey key, Value value);
+bool array_append(Array* array, Value value);
+bool array_pop(Array* array, Value* out);
+bool array_remove(Array* array, ArrayKey key);
+
+bool array_to_key(Value value, ArrayKey* key);
+void array_format_key(ArrayKey key, char* buffer, size_t size);
+
+void value_retain(Value value);
+void value_release(Value value);
+
+#endif /* VM_ARRAY_H */
@@ -0,0 +1,33 @@
+/* Licensed under GNU GPL v3. See LICENSE file for details. */
+#ifndef VM_EXCEPTION_H
+#define VM_EXCEPTION_H
+
+#include "../common.h"
+#include "opcode_handler.h"
+#include "object.h"
+
+// THROWABLE is the interface of everything that can be thrown. Scripts
+// cannot implement it directly, only extend Exception or Error.
+#define THROWABLE "Throwable"
+
+// The VM fills these properties in when a Throwable is created: the file
+// and line of the new expression and the call stack as an array of frames
+// with the keys file, line, function and, for methods, class and type.
+#define PROP_FILE "file"
+#define PROP_LINE "line"
+#define PROP_TRACE "trace"
+
+void declare_builtin_classes(VMContext* context);
+bool is_throwable(VMContext* context, const Class* class);
+void init_throwable(VMContext* context, Object* object);
+
+status_t throw_object(VMContext* context, Object* exception);
+status_t throw_error(VMContext* context, const char* class_name, const char* format, ...);
+status_t unwind(VMContext* context, int depth);
+void report_uncaught(VMContext* context);
+
+uint32_t line_at(VMContext* context, size_t addr);
+char* trace_as_string(VMContext* context, Object* object);
+char* describe_throwable(VMContext* context, Object* object);
+
+#endif /* VM_EXCEPTION_H */
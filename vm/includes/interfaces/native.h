@@ -0,0 +1,42 @@
+/* Licensed under GNU GPL v3. See LICENSE file for details. */
+#ifndef VM_NATIVE_H
+#define VM_NATIVE_H
+
+#include "../common.h"
+#include "opcode_handler.h"
+
+// NativeFunc computes the result of a native function from the arguments
+// of a call, in source order. The compiler has checked their count, and
+// call_value does for the calls natives make. A function may replace the
+// arguments passed by reference in args; the caller stores them back into
+// its variables. Failures such as a TypeError are thrown with throw_error,
+// so that the script may catch them.
+typedef status_t (*NativeFunc)(VMContext* context, Value* args, int argc, Value* result);
+
+// VARIADIC is the max_args of a function that takes any number of
+// arguments.
+#define VARIADIC -1
+
+typedef struct {
+    const char* name;
+    int min_args;
+    int max_args;     // VARIADIC for no upper limit
+    uint32_t by_ref;  // bit i is set when parameter i is passed by reference
+    NativeFunc impl;
+} NativeFunction;
+
+const NativeFunction* native_lookup(const char* name);
+
+// call_value calls callee, a closure or the name of a function, with args
+// and runs it to completion. It is how natives call back into the script.
+// An exception the callee does not catch leaves it with STATUS_THROWN, which
+// the native returns for the exception to reach the script.
+status_t call_value(VMContext* context, Value callee, Value* args, int argc, Value* result);
+
+// Argument checks shared with the methods of built-in classes. fn names
+// the function in messages and i is the index of the argument.
+status_t type_error(VMContext* context, const char* fn, int i, const char* param, const char* want, Value got);
+status_t string_arg(VMContext* context, const char* fn, Value* args, int i, const char* param, char** out);
+status_t int_arg(VMContext* context, const char* fn, Value* args, int i, const char* param, int_t* out);
+
+#endif /* VM_NATIVE_H */
@@ -0,0 +1,126 @@
+/* Licensed under GNU GPL v3. See LICENSE file for details. */
+#ifndef VM_OBJECT_H
+#define VM_OBJECT_H
+
+#include "../common.h"
+#include "value_handler.h"
+#include "array.h"
+
+// Visibility restricts where a property or method can be used from. The
+// values match the modifier bits of CLASS_PROP and CLASS_METHOD.
+typedef enum {
+    VISIBILITY_PUBLIC = 0,
+    VISIBILITY_PROTECTED = 1,
+    VISIBILITY_PRIVATE = 2
+} Visibility;
+
+#define VISIBILITY_MASK 0x03
+
+// Modifier bits of CLASS_METHOD besides the visibility.
+#define MEMBER_STATIC 0x04
+#define MEMBER_ABSTRACT 0x08  // declared without a body; the address is 0
+
+// Flags of CLASS_DECL.
+#define CLASS_ABSTRACT 0x01
+#define CLASS_FINAL 0x02
+#define CLASS_INTERFACE 0x04
+#define CLASS_TRAIT 0x08
+
+// Property is a declared property and the value new objects start with.
+typedef struct {
+    char* name;
+    struct Class* class;  // the class that declares the property
+    Value default_value;
+    Visibility visibility;
+} Property;
+
+struct VMContext;
+struct Object;
+
+// NativeMethod implements a method of a built-in class, like NativeFunc
+// with the object it is called on, NULL for static methods. The
+// arguments are in source order.
+typedef status_t (*NativeMethod)(struct VMContext* context, struct Object* this_obj, Value* args, int argc,
+                                 Value* result);
+
+// Method is the code of a method. The FUNC_DECL at addr of an instance
+// method binds $this to the first local, so param_count does not include
+// it. Abstract methods have no code; methods of built-in classes run native.
+typedef struct Method {
+    char* name;
+    struct Class* class;  // the class that declares the method
+    size_t addr;
+    int param_count;
+    Visibility visibility;
+    bool is_static;
+    bool is_abstract;
+    NativeMethod native;
+} Method;
+
+// Class is the run-time description of a class, an interface or a trait,
+// built by the CLASS_* instructions. Classes are kept in a list linked
+// through next. Method names are case-insensitive, property names are not.
+typedef struct Class {
+    char* name;
+    byte_t flags;  // CLASS_* flags
+    struct Class* parent;
+
+    // Every interface the class implements, inherited ones included.
+    struct Class** interfaces;
+    size_t interface_count;
+    size_t interface_capacity;
+
+    Property* properties;
+    size_t property_count;
+    size_t property_capacity;
+
+    Method* methods;
+    size_t method_count;
+    size_t method_capacity;
+
+    struct Class* next;
+} Class;
+
+// Object is an instance of a class. Unlike arrays, objects are handles:
+// copying the value shares the object, so a property write through one
+// variable is seen through all of them.
+typedef struct Object {
+    Class* class;
+    Array* props;             // property values by name, declared properties first
+    struct Closure* closure;  // function of a Closure object, NULL for other classes
+} Object;
+
+// CLOSURE_CLASS is the class of the values closures and arrow functions
+// evaluate to. Only the CLOSURE instruction creates its objects.
+#define CLOSURE_CLASS "Closure"
+
+// Closure is the function a Closure object calls. The FUNC_DECL at addr
+// binds the parameters; captured holds the values of the use variables,
+// followed by $this when the closure was created in an instance method,
+// which a call copies into the locals that follow the parameters.
+typedef struct Closure {
+    size_t addr;
+    Value* captured;
+    int captured_count;
+    Object* this_obj;     // object of the method that created the closure
+    Class* scope;         // class of that method, NULL outside of methods
+    Class* static_class;  // class static:: referred to there
+} Closure;
+
+Class* class_new(const char* name, byte_t flags);
+Class* class_find(Class* classes, const char* name);
+void class_add_property(Class* class, const char* name, Value default_value, Visibility visibility);
+const Property* class_property(const Class* class, const char* name);
+void class_add_method(Class* class, const Method* method);
+const Method* class_method(const Class* class, const char* name);
+void class_inherit(Class* class, Class* parent);
+void class_implement(Class* class, Class* iface);
+bool class_instance_of(const Class* class, const Class* other);
+const char* class_kind_name(const Class* class);
+
+Object* object_new(Class* class);
+ArrayKey object_key(const char* name);
+
+const char* visibility_name(Visibility visibility);
+
+#endif /* VM_OBJECT_H */
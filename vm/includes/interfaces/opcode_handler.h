@@ -10,6 +10,70 @@
 
 typedef struct VMContext VMContext;
 
+struct Class;
+struct Method;
+
+// ExceptionHandler is one entry of the exception table. An exception thrown
+// by an instruction in [start, end) of a frame running the function whose
+// FUNC_DECL is at function (-1 for the script itself) is caught at target,
+// with the stack cut back to stack_depth values above the frame base and
+// the exception pushed. Entries are ordered innermost first.
+typedef struct {
+    uint32_t start;
+    uint32_t end;
+    uint32_t target;
+    int32_t function;
+    uint32_t stack_depth;
+} ExceptionHandler;
+
+// LineEntry maps the instructions from addr up to the next entry to a
+// source line.
+typedef struct {
+    uint32_t addr;
+    uint32_t line;
+} LineEntry;
+
+// FunctionName names the function whose FUNC_DECL is at addr, for traces.
+typedef struct {
+    uint32_t addr;
+    const char* name;
+} FunctionName;
+
+// VariableName names the variable in slot of the function whose FUNC_DECL
+// is at function, -1 for the globals, for warnings.
+typedef struct {
+    int32_t function;
+    uint32_t slot;
+    const char* name;
+} VariableName;
+
+// BoundFunction is a function defined by FUNC_BIND: one declared at the top
+// level of the script before its first statement, one declared in a block
+// or a function body when its declaration runs.
+typedef struct BoundFunction {
+    const char* name;
+    size_t addr;  // address of its FUNC_DECL
+    struct BoundFunction* next;
+} BoundFunction;
+
+// CallFrame is one function invocation: where to return to, how many
+// arguments the caller pushed, the stack height below them and the locals.
+// Methods also record their class, their object, the class they were
+// called on, which static:: refers to, and constructors the object they
+// initialize, which is returned in place of their result.
+typedef struct CallFrame {
+    size_t addr;  // address of the FUNC_DECL of the running function
+    size_t return_address;
+    byte_t arg_count;
+    int stack_base;
+    Value locals[LOCAL_COUNT];
+    struct Class* scope;
+    struct Object* this_obj;
+    struct Class* static_class;
+    struct Object* construct;
+    const struct Method* method;  // running method, NULL in functions
+} CallFrame;
+
 typedef status_t (*OpcodeHandlerFunc)(VMContext* context);
 
 typedef struct OpcodeHandler {
@@ -32,10 +96,31 @@ struct VMContext {
     size_t constants_len;
 
     Value* variables;
+    bool* statics;  // static variables whose initializer already ran
+
+    CallFrame* frames;
+    int frame_count;
+
+    struct Class* classes;    // declared classes, see class_find
+    struct Class* declaring;  // class the CLASS_PROP and CLASS_METHOD instructions add to
+    BoundFunction* functions; // functions bound so far, see find_function
+
+    struct Object* exception;  // exception being thrown while a handler returns STATUS_THROWN
+    const ExceptionHandler* exception_handlers;
+    size_t exception_handler_count;
+
+    const char* source_file;
+    const LineEntry* lines;
+    size_t line_count;
+    const FunctionName* function_names;
+    size_t function_name_count;
+    const VariableName* variable_names;
+    size_t variable_name_count;
 
     ValueHandler* value_handler;
     StackManager* stack_manager;
     ErrorHandler* error_handler;
+    OpcodeHandler* opcode_handler;  // runs the functions natives call back
 
     void* user_data;
 };
@@ -44,10 +129,17 @@ OpcodeHandler* opcode_handler_new(void);
 
 void opcode_handler_free(OpcodeHandler* handler);
 
+CallFrame* push_frame(VMContext* context, byte_t arg_count);
+status_t call_function(VMContext* context, size_t func_addr, byte_t arg_count);
+BoundFunction* find_function(VMContext* context, const char* name);
+
 status_t handle_load_const(VMContext* context);
 status_t handle_print(VMContext* context);
 status_t handle_halt(VMContext* context);
 status_t handle_pop(VMContext* context);
+status_t handle_dup(VMContext* context);
+status_t handle_tuck(VMContext* context);
+status_t handle_load_null(VMContext* context);
 
 status_t handle_add(VMContext* context);
 status_t handle_sub(VMContext* context);
@@ -58,9 +150,21 @@ status_t handle_concat(VMContext* context);
 
 status_t handle_store_var(VMContext* context);
 status_t handle_load_var(VMContext* context);
+status_t handle_store_local(VMContext* context);
+status_t handle_load_local(VMContext* context);
+status_t handle_ref_var(VMContext* context);
+status_t handle_ref_local(VMContext* context);
+status_t handle_unset_var(VMContext* context);
+status_t handle_unset_local(VMContext* context);
+status_t handle_bind_var(VMContext* context);
+status_t handle_bind_local(VMContext* context);
+status_t handle_load_var_quiet(VMContext* context);
+status_t handle_load_local_quiet(VMContext* context);
+status_t handle_static_init(VMContext* context);
 
 status_t handle_jump(VMContext* context);
 status_t handle_jump_if_false(VMContext* context);
+status_t handle_coalesce(VMContext* context);
 
 status_t handle_gt(VMContext* context);
 status_t handle_lt(VMContext* context);
@@ -76,11 +180,13 @@ status_t handle_post_inc(VMContext* context);
 status_t handle_post_dec(VMContext* context);
 
 status_t handle_mod(VMContext* context);
+status_t handle_pow(VMContext* context);
 
 status_t handle_gte(VMContext* context);
 status_t handle_lte(VMContext* context);
 status_t handle_identity_eq(VMContext* context);
 status_t handle_identity_ne(VMContext* context);
+status_t handle_spaceship(VMContext* context);
 
 status_t handle_bit_and(VMContext* context);
 status_t handle_bit_or(VMContext* context);
@@ -96,6 +202,12 @@ status_t handle_assign_mul(VMContext* context);
 status_t handle_assign_div(VMContext* context);
 status_t handle_assign_mod(VMContext* context);
 status_t handle_assign_concat(VMContext* context);
+status_t handle_assign_pow(VMContext* context);
+status_t handle_assign_bit_and(VMContext* context);
+status_t handle_assign_bit_or(VMContext* context);
+status_t handle_assign_bit_xor(VMContext* context);
+status_t handle_assign_lshift(VMContext* context);
+status_t handle_assign_rshift(VMContext* context);
 
 status_t handle_break(VMContext* context);
 status_t handle_continue(VMContext* context);
@@ -105,5 +217,41 @@ status_t handle_func_call(VMContext* context);
 status_t handle_return(VMContext* context);
 status_t handle_enter_func(VMContext* context);
 status_t handle_exit_func(VMContext* context);
+status_t handle_call_native(VMContext* context);
+status_t handle_closure(VMContext* context);
+status_t handle_call_indirect(VMContext* context);
+status_t handle_func_bind(VMContext* context);
+status_t handle_call_named(VMContext* context);
+
+status_t handle_array_new(VMContext* context);
+status_t handle_array_push(VMContext* context);
+status_t handle_array_insert(VMContext* context);
+status_t handle_array_get(VMContext* context);
+status_t handle_array_set(VMContext* context);
+status_t handle_array_fetch(VMContext* context);
+status_t handle_array_get_quiet(VMContext* context);
+status_t handle_array_fetch_quiet(VMContext* context);
+status_t handle_array_unset(VMContext* context);
+
+status_t handle_foreach_init(VMContext* context);
+status_t handle_foreach_next(VMContext* context);
+status_t handle_foreach_end(VMContext* context);
+
+status_t handle_class_decl(VMContext* context);
+status_t handle_class_prop(VMContext* context);
+status_t handle_class_method(VMContext* context);
+status_t handle_new(VMContext* context);
+status_t handle_prop_get(VMContext* context);
+status_t handle_prop_set(VMContext* context);
+status_t handle_prop_get_quiet(VMContext* context);
+status_t handle_prop_unset(VMContext* context);
+status_t handle_instanceof(VMContext* context);
+status_t handle_method_call(VMContext* context);
+status_t handle_class_extends(VMContext* context);
+status_t handle_class_implements(VMContext* context);
+status_t handle_static_call(VMContext* context);
+
+status_t handle_throw(VMContext* context);
+status_t handle_catch(VMContext* context);
 
 #endif /* VM_OPCODE_HANDLER_H */
\ No newline at end of file
@@ -8,31 +8,71 @@ typedef enum {
     TYPE_INT = 0,
     TYPE_STRING = 1,
     TYPE_BOOLEAN = 2,
-    TYPE_NULL = 3
+    TYPE_NULL = 3,
+    TYPE_FLOAT = 4,
+    TYPE_ARRAY = 5,
+    TYPE_OBJECT = 6,
+    TYPE_REFERENCE = 7,  // only held by variable slots and array elements, see Reference
+    TYPE_UNDEF = 8       // only held by variable slots never assigned or unset
 } ValueType;
 
+// FLOAT_PRECISION is the number of significant digits printed for floats,
+// like PHP's default `precision` setting.
+#define FLOAT_PRECISION 14
+
+struct Array;
+struct Object;
+struct Reference;
+
 typedef struct {
     ValueType type;
     union {
         int_t int_val;
         char* str_val;
         bool bool_val;
+        double float_val;
+        struct Array* arr_val;
+        struct Object* obj_val;
+        struct Reference* ref_val;
     } value;
 } Value;
 
+// Reference is a variable shared by several scopes, such as one a closure
+// captures with use (&$name), or an array element a foreach by reference
+// binds its variable to. Each variable slot and element bound to it holds
+// a TYPE_REFERENCE value: reading it yields the value inside, writing it
+// replaces that value for all of them. refs counts them, like for arrays.
+typedef struct Reference {
+    Value value;
+    int refs;
+} Reference;
+
+Value value_deref(Value value);
+void value_assign(Value* slot, Value value);
+void value_bind(Value* slot, Value ref);
+Value value_make_ref(Value* slot);
+
 typedef struct ValueHandler {
     Value (*create_int)(int_t value);
     Value (*create_string)(const char* value);
     Value (*create_boolean)(bool value);
     Value (*create_null)(void);
+    Value (*create_float)(double value);
+    Value (*create_array)(struct Array* value);
+    Value (*create_object)(struct Object* value);
 
     int_t (*to_int)(Value value);
+    double (*to_float)(Value value);
+    bool (*is_float)(Value value);
     char* (*to_string)(Value value);
     bool (*to_boolean)(Value value);
+    const char* (*type_name)(Value value);
 
     bool (*equals)(Value a, Value b);
     bool (*less_than)(Value a, Value b);
     bool (*greater_than)(Value a, Value b);
+    int (*compare)(Value a, Value b);  // <=>: -1, 0 or 1
+    bool (*identical)(Value a, Value b);
 
     void (*print)(Value value);
 
@@ -41,6 +81,8 @@ typedef struct ValueHandler {
 
 ValueHandler* value_handler_new(void);
 
+void value_format_float(double value, char* buffer, size_t size);
+
 void value_handler_free(ValueHandler* handler);
 
 #endif /* VM_VALUE_HANDLER_H */
\ No newline at end of file
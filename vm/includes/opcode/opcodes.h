@@ -6,6 +6,8 @@
 #define OP_PRINT            0x02
 #define OP_HALT             0xFF
 #define OP_POP              0x0C
+#define OP_DUP              0x0E
+#define OP_TUCK             0x1A
 
 #define OP_ADD              0x03
 #define OP_SUB              0x04
@@ -16,9 +18,23 @@
 
 #define OP_STORE_VAR        0x10
 #define OP_LOAD_VAR         0x11
+#define OP_STORE_LOCAL      0x12
+#define OP_LOAD_LOCAL       0x13
+#define OP_STATIC_INIT      0x14
+#define OP_LOAD_NULL        0x15
+#define OP_REF_VAR          0x16
+#define OP_REF_LOCAL        0x17
+#define OP_UNSET_VAR        0x18
+#define OP_UNSET_LOCAL      0x19
+#define OP_BIND_VAR         0x1B
+#define OP_BIND_LOCAL       0x1C
+
+#define OP_LOAD_VAR_QUIET   0x1D
+#define OP_LOAD_LOCAL_QUIET 0x1E
 
 #define OP_JUMP             0x21
 #define OP_JUMP_IF_FALSE    0x20
+#define OP_COALESCE         0x22
 
 #define OP_GT               0x07
 #define OP_LT               0x08
@@ -34,6 +50,7 @@
 #define OP_POST_DEC         0x33
 
 #define OP_MOD              0x34
+#define OP_POW              0x35
 
 #define OP_BIT_AND          0x40
 #define OP_BIT_OR           0x41
@@ -46,6 +63,7 @@
 #define OP_LTE              0x51
 #define OP_IDENTITY_EQ      0x52
 #define OP_IDENTITY_NE      0x53
+#define OP_SPACESHIP        0x54
 
 #define OP_ASSIGN_ADD       0x60
 #define OP_ASSIGN_SUB       0x61
@@ -53,6 +71,12 @@
 #define OP_ASSIGN_DIV       0x63
 #define OP_ASSIGN_MOD       0x64
 #define OP_ASSIGN_CONCAT    0x65
+#define OP_ASSIGN_POW       0x66
+#define OP_ASSIGN_BIT_AND   0x67
+#define OP_ASSIGN_BIT_OR    0x68
+#define OP_ASSIGN_BIT_XOR   0x69
+#define OP_ASSIGN_LSHIFT    0x6A
+#define OP_ASSIGN_RSHIFT    0x6B
 
 #define OP_BREAK            0x70
 #define OP_CONTINUE         0x71
@@ -62,5 +86,43 @@
 #define OP_RETURN         0x82
 #define OP_ENTER_FUNC     0x83
 #define OP_EXIT_FUNC      0x84
+#define OP_CALL_NATIVE    0x85
+#define OP_CLOSURE        0x86
+#define OP_CALL_INDIRECT  0x87
+#define OP_FUNC_BIND      0x88
+#define OP_CALL_NAMED     0x89
+
+#define OP_ARRAY_NEW        0x90
+#define OP_ARRAY_PUSH       0x91
+#define OP_ARRAY_INSERT     0x92
+#define OP_ARRAY_GET        0x93
+#define OP_ARRAY_SET        0x94
+#define OP_ARRAY_FETCH      0x95
+
+#define OP_ARRAY_GET_QUIET   0x9A
+#define OP_ARRAY_FETCH_QUIET 0x9B
+#define OP_ARRAY_UNSET       0x9C
+
+#define OP_FOREACH_INIT     0x96
+#define OP_FOREACH_NEXT     0x97
+#define OP_FOREACH_END      0x99
+
+#define OP_CLASS_DECL       0xA0
+#define OP_CLASS_PROP       0xA1
+#define OP_CLASS_METHOD     0xA2
+#define OP_NEW              0xA3
+#define OP_PROP_GET         0xA4
+#define OP_PROP_SET         0xA5
+#define OP_METHOD_CALL      0xA6
+#define OP_CLASS_EXTENDS    0xA7
+#define OP_CLASS_IMPLEMENTS 0xA8
+#define OP_STATIC_CALL      0xA9
+
+#define OP_PROP_GET_QUIET   0xAA
+#define OP_PROP_UNSET       0xAB
+#define OP_INSTANCEOF       0xAC
+
+#define OP_THROW 0xB0
+#define OP_CATCH 0xB1
 
 #endif /* VM_OPCODES_H */
\ No newline at end of file
@@ -38,6 +38,10 @@ void vm_set_debug_mode(VM* vm, bool debug_mode);
 
 void vm_register_opcode_handler(VM* vm, byte_t opcode, OpcodeHandlerFunc handler);
 void vm_set_user_data(VM* vm, void* user_data);
+void vm_set_exception_table(VM* vm, const ExceptionHandler* handlers, size_t count);
+void vm_set_debug_info(VM* vm, const char* source_file, const LineEntry* lines, size_t line_count,
+                       const FunctionName* function_names, size_t function_name_count,
+                       const VariableName* variable_names, size_t variable_name_count);
 void* vm_get_user_data(VM* vm);
 
 #endif /* VM_H */
\ No newline at end of file
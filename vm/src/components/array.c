@@ -0,0 +1,422 @@
+/* Licensed under GNU GPL v3. See LICENSE file for details. */
+#include "../../includes/interfaces/array.h"
+#include <stdlib.h>
+#include <string.h>
+#include <stdio.h>
+#include <errno.h>
+#include <math.h>
+
+#define ARRAY_INITIAL_CAPACITY 8
+
+Array* array_new(void) {
+    Array* array = (Array*)calloc(1, sizeof(Array));
+    return array;
+}
+
+static uint64_t hash_key(ArrayKey key) {
+    if (!key.is_string) {
+        uint64_t h = (uint64_t)key.int_key;
+        h ^= h >> 33;
+        h *= 0xff51afd7ed558ccdULL;
+        h ^= h >> 33;
+        return h;
+    }
+
+    // FNV-1a
+    uint64_t h = 0xcbf29ce484222325ULL;
+    for (const char* p = key.str_key; *p; p++) {
+        h ^= (unsigned char)*p;
+        h *= 0x100000001b3ULL;
+    }
+    return h;
+}
+
+static bool keys_equal(ArrayKey a, ArrayKey b) {
+    if (a.is_string != b.is_string) {
+        return false;
+    }
+    return a.is_string ? strcmp(a.str_key, b.str_key) == 0 : a.int_key == b.int_key;
+}
+
+// find_slot returns the index slot holding key, or the empty slot where it
+// would be inserted.
+static size_t find_slot(const Array* array, ArrayKey key) {
+    size_t mask = array->index_size - 1;
+    size_t slot = hash_key(key) & mask;
+
+    while (array->index[slot] != -1 && !keys_equal(array->entries[array->index[slot]].key, key)) {
+        slot = (slot + 1) & mask;
+    }
+    return slot;
+}
+
+static bool is_deleted(const ArrayEntry* entry) {
+    return entry->value.type == TYPE_UNDEF;
+}
+
+static void rebuild_index(Array* array, size_t index_size) {
+    free(array->index);
+    array->index = (int32_t*)malloc(index_size * sizeof(int32_t));
+    array->index_size = index_size;
+    memset(array->index, -1, index_size * sizeof(int32_t));
+
+    for (size_t i = 0; i < array->used; i++) {
+        if (!is_deleted(&array->entries[i])) {
+            array->index[find_slot(array, array->entries[i].key)] = (int32_t)i;
+        }
+    }
+}
+
+static void grow(Array* array) {
+    size_t capacity = array->capacity ? array->capacity * 2 : ARRAY_INITIAL_CAPACITY;
+    array->entries = (ArrayEntry*)realloc(array->entries, capacity * sizeof(ArrayEntry));
+    array->capacity = capacity;
+
+    // The index stays at most half full.
+    rebuild_index(array, capacity * 2);
+}
+
+// unlink_key removes key from the index.
+static void unlink_key(Array* array, ArrayKey key) {
+    size_t mask = array->index_size - 1;
+    size_t slot = find_slot(array, key);
+    array->index[slot] = -1;
+
+    // Reinsert the rest of the probe run so that lookups do not stop at
+    // the freed slot.
+    for (size_t next = (slot + 1) & mask; array->index[next] != -1; next = (next + 1) & mask) {
+        int32_t i = array->index[next];
+        array->index[next] = -1;
+        array->index[find_slot(array, array->entries[i].key)] = i;
+    }
+}
+
+// trim drops the deleted entries at the end, so that the last entry is
+// always the last element.
+static void trim(Array* array) {
+    while (array->used > 0 && is_deleted(&array->entries[array->used - 1])) {
+        array->used--;
+    }
+
+    if (array->cursor > array->used) {
+        array->cursor = array->used;
+        array->cursor_pos = array->count;
+    }
+}
+
+// compact drops the deleted entries and moves the elements up, in order.
+static void compact(Array* array) {
+    size_t live = 0;
+    for (size_t i = 0; i < array->used; i++) {
+        if (!is_deleted(&array->entries[i])) {
+            array->entries[live++] = array->entries[i];
+        }
+    }
+
+    array->used = live;
+    array->cursor = 0;
+    array->cursor_pos = 0;
+    rebuild_index(array, array->index_size);
+}
+
+// entry_at returns the i-th element. Elements are mostly visited in order,
+// so the search starts at the cursor left by the previous call rather than
+// at the first entry.
+static ArrayEntry* entry_at(Array* array, size_t i) {
+    if (array->count == array->used) {
+        return &array->entries[i];
+    }
+
+    size_t s = array->cursor;
+    size_t n = array->cursor_pos;
+    while (n > i) {
+        s--;
+        if (!is_deleted(&array->entries[s])) {
+            n--;
+        }
+    }
+    while (is_deleted(&array->entries[s]) || n < i) {
+        if (!is_deleted(&array->entries[s])) {
+            n++;
+        }
+        s++;
+    }
+
+    array->cursor = s;
+    array->cursor_pos = n;
+    return &array->entries[s];
+}
+
+bool array_get(const Array* array, ArrayKey key, Value* out) {
+    if (array->count == 0) {
+        return false;
+    }
+
+    int32_t i = array->index[find_slot(array, key)];
+    if (i == -1) {
+        return false;
+    }
+
+    *out = value_deref(array->entries[i].value);
+    return true;
+}
+
+// array_at returns the value of the i-th element in insertion order.
+Value array_at(Array* array, size_t i) {
+    return value_deref(entry_at(array, i)->value);
+}
+
+// array_key_at returns the key of the i-th element in insertion order.
+ArrayKey array_key_at(Array* array, size_t i) {
+    return entry_at(array, i)->key;
+}
+
+// array_ref_at binds the i-th element to a reference, unless it is bound
+// already, and returns the reference.
+Value array_ref_at(Array* array, size_t i) {
+    return value_make_ref(&entry_at(array, i)->value);
+}
+
+// array_set stores value under key. The array takes a reference to value and
+// drops the one it held to the value it replaces. An element bound to a
+// reference is written through it. String keys are copied.
+void array_set(Array* array, ArrayKey key, Value value) {
+    if (array->count > 0) {
+        int32_t i = array->index[find_slot(array, key)];
+        if (i != -1) {
+            value_assign(&array->entries[i].value, value);
+            return;
+        }
+    }
+
+    value_retain(value);
+
+    if (array->used == array->capacity) {
+        grow(array);
+    }
+
+    if (key.is_string) {
+        key.str_key = strdup(key.str_key);
+    }
+
+    array->entries[array->used].key = key;
+    array->entries[array->used].value = value;
+    array->index[find_slot(array, key)] = (int32_t)array->used;
+    array->used++;
+    array->count++;
+
+    if (!key.is_string && (!array->has_int_key || key.int_key >= array->next_index)) {
+        array->has_int_key = true;
+        if (key.int_key == INT64_MAX) {
+            array->full = true;
+        } else {
+            array->next_index = key.int_key + 1;
+        }
+    }
+}
+
+// array_append stores value under the next integer key: one past the
+// largest int key, or 0 when there is none yet. It fails once INT64_MAX
+// is used.
+bool array_append(Array* array, Value value) {
+    if (array->full) {
+        return false;
+    }
+
+    ArrayKey key = {false, array->next_index, NULL};
+    array_set(array, key, value);
+    return true;
+}
+
+// array_pop removes the last element and stores its value in out. Like
+// PHP's array_pop, it gives the next appended element the removed key again
+// when that key was the last one used by array_append.
+bool array_pop(Array* array, Value* out) {
+    if (array->count == 0) {
+        return false;
+    }
+
+    ArrayEntry last = array->entries[array->used - 1];
+    unlink_key(array, last.key);
+    array->used--;
+    array->count--;
+    trim(array);
+
+    *out = value_deref(last.value);
+    value_release(last.value);
+
+    if (last.key.is_string) {
+        free(last.key.str_key);
+    } else if (array->full && last.key.int_key == INT64_MAX) {
+        array->full = false;
+        array->next_index = INT64_MAX;
+    } else if (last.key.int_key == array->next_index - 1) {
+        array->next_index--;
+    }
+    return true;
+}
+
+// array_remove removes the element under key, if any. Unlike array_pop, it
+// leaves the key used by the next array_append alone, as unset does in PHP.
+//
+// The entry is only marked as deleted. Once deleted entries outnumber the
+// elements, they are dropped in one pass, which keeps array_remove O(1) on
+// average.
+bool array_remove(Array* array, ArrayKey key) {
+    if (array->count == 0) {
+        return false;
+    }
+
+    int32_t i = array->index[find_slot(array, key)];
+    if (i == -1) {
+        return false;
+    }
+
+    unlink_key(array, key);
+
+    ArrayEntry* entry = &array->entries[i];
+    value_release(entry->value);
+    if (entry->key.is_string) {
+        free(entry->key.str_key);
+        entry->key.str_key = NULL;
+    }
+    entry->value.type = TYPE_UNDEF;
+
+    array->count--;
+    if ((size_t)i < array->cursor) {
+        array->cursor_pos--;
+    }
+
+    trim(array);
+    if (array->used - array->count > array->count) {
+        compact(array);
+    }
+    return true;
+}
+
+// array_copy returns a shallow copy. Nested arrays are shared with the copy
+// and are themselves copied when one of the two arrays writes to them. Like
+// in PHP, an element bound to a reference that some variable still holds
+// stays shared, the others are copied as plain values.
+Array* array_copy(const Array* array) {
+    Array* copy = array_new();
+    if (!copy) return NULL;
+
+    for (size_t i = 0; i < array->used; i++) {
+        if (is_deleted(&array->entries[i])) {
+            continue;
+        }
+
+        Value value = array->entries[i].value;
+        if (value.type == TYPE_REFERENCE && value.value.ref_val->refs <= 1) {
+            value = value.value.ref_val->value;
+        }
+        array_set(copy, array->entries[i].key, value);
+    }
+
+    copy->next_index = array->next_index;
+    copy->has_int_key = array->has_int_key;
+    copy->full = array->full;
+    return copy;
+}
+
+// array_separate returns an array that can be written without affecting any
+// other holder: array itself when at most one variable refers to it, a copy
+// otherwise.
+Array* array_separate(Array* array) {
+    if (array->refs <= 1) {
+        return array;
+    }
+    return array_copy(array);
+}
+
+// canonical_int reports whether s is the decimal form of an int_t without
+// leading zeros or a plus sign, which PHP stores as an int key.
+static bool canonical_int(const char* s, int_t* out) {
+    const char* digits = s[0] == '-' ? s + 1 : s;
+
+    if (digits[0] == '\0' || strcmp(s, "-0") == 0 || (digits[0] == '0' && digits[1] != '\0')) {
+        return false;
+    }
+    for (const char* p = digits; *p; p++) {
+        if (*p < '0' || *p > '9') {
+            return false;
+        }
+    }
+
+    errno = 0;
+    long long n = strtoll(s, NULL, 10);
+    if (errno == ERANGE) {
+        return false;
+    }
+
+    *out = (int_t)n;
+    return true;
+}
+
+// array_to_key converts value to an array key: integer strings such as "5"
+// become ints, floats are truncated, booleans become 0 or 1 and null
+// becomes "". String keys point into value. Arrays are not valid keys.
+bool array_to_key(Value value, ArrayKey* key) {
+    key->is_string = false;
+    key->int_key = 0;
+    key->str_key = NULL;
+
+    switch (value.type) {
+        case TYPE_INT:
+            key->int_key = value.value.int_val;
+            return true;
+        case TYPE_STRING: {
+            const char* s = value.value.str_val ? value.value.str_val : "";
+            if (!canonical_int(s, &key->int_key)) {
+                key->is_string = true;
+                key->str_key = (char*)s;
+            }
+            return true;
+        }
+        case TYPE_FLOAT: {
+            double f = value.value.float_val;
+            if (!isnan(f) && f < 9223372036854775808.0 && f >= -9223372036854775808.0) {
+                key->int_key = (int_t)f;
+            }
+            return true;
+        }
+        case TYPE_BOOLEAN:
+            key->int_key = value.value.bool_val ? 1 : 0;
+            return true;
+        case TYPE_NULL:
+            key->is_string = true;
+            key->str_key = "";
+            return true;
+        default:
+            return false;
+    }
+}
+
+// array_format_key formats key the way PHP quotes it in warnings: 5 or "name".
+void array_format_key(ArrayKey key, char* buffer, size_t size) {
+    if (key.is_string) {
+        snprintf(buffer, size, "\"%s\"", key.str_key);
+    } else {
+        snprintf(buffer, size, "%" INT_FMT, key.int_key);
+    }
+}
+
+// value_retain records one more holder of value when value is an array.
+void value_retain(Value value) {
+    if (value.type == TYPE_ARRAY) {
+        value.value.arr_val->refs++;
+    } else if (value.type == TYPE_REFERENCE) {
+        value.value.ref_val->refs++;
+    }
+}
+
+// value_release drops a holder of value when value is an array or a
+// reference. Neither is freed: values on the stack do not hold references.
+void value_release(Value value) {
+    if (value.type == TYPE_ARRAY && value.value.arr_val->refs > 0) {
+        value.value.arr_val->refs--;
+    } else if (value.type == TYPE_REFERENCE && value.value.ref_val->refs > 0) {
+        value.value.ref_val->refs--;
+    }
+}
@@ -0,0 +1,294 @@
+/* Licensed under GNU GPL v3. See LICENSE file for details. */
+#include "../../includes/interfaces/exception.h"
+#include "../../includes/interfaces/native.h"
+#include "../../includes/interfaces/array.h"
+#include <stdarg.h>
+#include <stdlib.h>
+#include <string.h>
+
+/*
+ * The built-in classes, the same set the compiler registers in
+ * internal/native: the Throwable interface, Exception and Error with their
+ * constructor and final getters, the standard subclasses of both, and
+ * Closure.
+ */
+
+static status_t get_prop(VMContext* context, Object* this_obj, const char* name, Value* result) {
+    if (!array_get(this_obj->props, object_key(name), result)) {
+        *result = context->value_handler->create_null();
+    }
+    return STATUS_SUCCESS;
+}
+
+static status_t get_message(VMContext* context, Object* this_obj, Value* args, int argc, Value* result) {
+    (void)args;
+    (void)argc;
+    return get_prop(context, this_obj, "message", result);
+}
+
+static status_t get_code(VMContext* context, Object* this_obj, Value* args, int argc, Value* result) {
+    (void)args;
+    (void)argc;
+    return get_prop(context, this_obj, "code", result);
+}
+
+static status_t get_previous(VMContext* context, Object* this_obj, Value* args, int argc, Value* result) {
+    (void)args;
+    (void)argc;
+    return get_prop(context, this_obj, "previous", result);
+}
+
+static status_t get_file(VMContext* context, Object* this_obj, Value* args, int argc, Value* result) {
+    (void)args;
+    (void)argc;
+    return get_prop(context, this_obj, PROP_FILE, result);
+}
+
+static status_t get_line(VMContext* context, Object* this_obj, Value* args, int argc, Value* result) {
+    (void)args;
+    (void)argc;
+    return get_prop(context, this_obj, PROP_LINE, result);
+}
+
+static status_t get_trace(VMContext* context, Object* this_obj, Value* args, int argc, Value* result) {
+    (void)args;
+    (void)argc;
+    return get_prop(context, this_obj, PROP_TRACE, result);
+}
+
+static status_t get_trace_as_string(VMContext* context, Object* this_obj, Value* args, int argc, Value* result) {
+    (void)args;
+    (void)argc;
+    char* trace = trace_as_string(context, this_obj);
+    *result = context->value_handler->create_string(trace);
+    free(trace);
+    return STATUS_SUCCESS;
+}
+
+// construct implements Exception::__construct and
+// Error::__construct($message = "", $code = 0, $previous = null).
+static status_t construct(VMContext* context, const char* fn, Object* this_obj, Value* args, int argc) {
+    if (argc > 0) {
+        char* message;
+        status_t status = string_arg(context, fn, args, 0, "message", &message);
+        if (status != STATUS_SUCCESS) {
+            return status;
+        }
+        array_set(this_obj->props, object_key("message"), context->value_handler->create_string(message));
+        free(message);
+    }
+    if (argc > 1) {
+        int_t code;
+        status_t status = int_arg(context, fn, args, 1, "code", &code);
+        if (status != STATUS_SUCCESS) {
+            return status;
+        }
+        array_set(this_obj->props, object_key("code"), context->value_handler->create_int(code));
+    }
+    if (argc > 2) {
+        Value previous = args[2];
+        if (previous.type != TYPE_NULL &&
+            (previous.type != TYPE_OBJECT || !is_throwable(context, previous.value.obj_val->class))) {
+            return type_error(context, fn, 2, "previous", "?Throwable", previous);
+        }
+        array_set(this_obj->props, object_key("previous"), previous);
+    }
+    return STATUS_SUCCESS;
+}
+
+static status_t exception_construct(VMContext* context, Object* this_obj, Value* args, int argc, Value* result) {
+    *result = context->value_handler->create_null();
+    return construct(context, "Exception::__construct", this_obj, args, argc);
+}
+
+static status_t error_construct(VMContext* context, Object* this_obj, Value* args, int argc, Value* result) {
+    *result = context->value_handler->create_null();
+    return construct(context, "Error::__construct", this_obj, args, argc);
+}
+
+static const struct {
+    const char* name;
+    NativeMethod impl;
+} getters[] = {
+    {"getMessage", get_message},
+    {"getCode", get_code},
+    {"getPrevious", get_previous},
+    {"getFile", get_file},
+    {"getLine", get_line},
+    {"getTrace", get_trace},
+    {"getTraceAsString", get_trace_as_string},
+};
+
+static const char* subclasses[][2] = {
+    {"LogicException", "Exception"},
+    {"BadFunctionCallException", "LogicException"},
+    {"BadMethodCallException", "BadFunctionCallException"},
+    {"DomainException", "LogicException"},
+    {"InvalidArgumentException", "LogicException"},
+    {"LengthException", "LogicException"},
+    {"OutOfRangeException", "LogicException"},
+    {"RuntimeException", "Exception"},
+    {"OutOfBoundsException", "RuntimeException"},
+    {"OverflowException", "RuntimeException"},
+    {"RangeException", "RuntimeException"},
+    {"UnderflowException", "RuntimeException"},
+    {"UnexpectedValueException", "RuntimeException"},
+    {"TypeError", "Error"},
+    {"ArgumentCountError", "TypeError"},
+    {"ValueError", "Error"},
+    {"ArithmeticError", "Error"},
+    {"DivisionByZeroError", "ArithmeticError"},
+};
+
+static Class* declare(VMContext* context, const char* name, byte_t flags) {
+    Class* class = class_new(name, flags);
+    class->next = context->classes;
+    context->classes = class;
+    return class;
+}
+
+static void add_method(Class* class, const char* name, int param_count, NativeMethod impl) {
+    Method method = {
+        .name = (char*)name,
+        .class = class,
+        .param_count = param_count,
+        .visibility = VISIBILITY_PUBLIC,
+        .is_abstract = impl == NULL,
+        .native = impl,
+    };
+    class_add_method(class, &method);
+}
+
+// declare_builtin_classes creates the classes of the runtime, such as
+// Exception, before the script declares its own.
+void declare_builtin_classes(VMContext* context) {
+    ValueHandler* values = context->value_handler;
+
+    Class* throwable = declare(context, THROWABLE, CLASS_INTERFACE);
+    for (size_t i = 0; i < sizeof(getters) / sizeof(getters[0]); i++) {
+        add_method(throwable, getters[i].name, 0, NULL);
+    }
+
+    const char* bases[] = {"Exception", "Error"};
+    NativeMethod constructors[] = {exception_construct, error_construct};
+    for (int b = 0; b < 2; b++) {
+        Class* class = declare(context, bases[b], 0);
+        class_implement(class, throwable);
+
+        class_add_property(class, "message", values->create_string(""), VISIBILITY_PROTECTED);
+        class_add_property(class, "code", values->create_int(0), VISIBILITY_PROTECTED);
+        class_add_property(class, PROP_FILE, values->create_string(""), VISIBILITY_PROTECTED);
+        class_add_property(class, PROP_LINE, values->create_int(0), VISIBILITY_PROTECTED);
+        class_add_property(class, PROP_TRACE, values->create_array(array_new()), VISIBILITY_PRIVATE);
+        class_add_property(class, "previous", values->create_null(), VISIBILITY_PRIVATE);
+
+        add_method(class, "__construct", 0, constructors[b]);
+        for (size_t i = 0; i < sizeof(getters) / sizeof(getters[0]); i++) {
+            add_method(class, getters[i].name, 0, getters[i].impl);
+        }
+    }
+
+    for (size_t i = 0; i < sizeof(subclasses) / sizeof(subclasses[0]); i++) {
+        Class* class = declare(context, subclasses[i][0], 0);
+        class_inherit(class, class_find(context->classes, subclasses[i][1]));
+    }
+
+    declare(context, CLOSURE_CLASS, CLASS_FINAL);
+}
+
+// is_throwable reports whether objects of class can be thrown.
+bool is_throwable(VMContext* context, const Class* class) {
+    Class* throwable = class_find(context->classes, THROWABLE);
+    return throwable && class_instance_of(class, throwable);
+}
+
+// append adds formatted text to the string in *buffer, which grows as
+// needed.
+static void append(char** buffer, size_t* len, size_t* capacity, const char* format, ...) {
+    va_list args;
+    va_start(args, format);
+    int n = vsnprintf(NULL, 0, format, args);
+    va_end(args);
+    if (n < 0) {
+        return;
+    }
+
+    if (*len + (size_t)n + 1 > *capacity) {
+        while (*len + (size_t)n + 1 > *capacity) {
+            *capacity = *capacity ? *capacity * 2 : 128;
+        }
+        *buffer = (char*)realloc(*buffer, *capacity);
+    }
+
+    va_start(args, format);
+    vsnprintf(*buffer + *len, *capacity - *len, format, args);
+    va_end(args);
+    *len += (size_t)n;
+}
+
+// field converts an entry of a property array to a string the caller
+// frees, an empty one when the key is missing.
+static char* field(VMContext* context, Array* array, const char* name) {
+    Value value;
+    if (!array_get(array, object_key(name), &value)) {
+        return strdup("");
+    }
+    return context->value_handler->to_string(value);
+}
+
+// trace_as_string formats the trace of a Throwable like PHP does, one
+// numbered frame per line, ending with {main}. The caller frees the result.
+char* trace_as_string(VMContext* context, Object* object) {
+    char* buffer = NULL;
+    size_t len = 0, capacity = 0;
+    size_t n = 0;
+
+    Value trace;
+    if (array_get(object->props, object_key(PROP_TRACE), &trace) && trace.type == TYPE_ARRAY) {
+        Array* frames = trace.value.arr_val;
+        for (; n < frames->count; n++) {
+            Value frame = array_at(frames, n);
+            if (frame.type != TYPE_ARRAY) {
+                continue;
+            }
+
+            const char* names[] = {PROP_FILE, PROP_LINE, "class", "type", "function"};
+            char* fields[5];
+            for (int i = 0; i < 5; i++) {
+                fields[i] = field(context, frame.value.arr_val, names[i]);
+            }
+            append(&buffer, &len, &capacity, "#%zu %s(%s): %s%s%s()\n",
+                   n, fields[0], fields[1], fields[2], fields[3], fields[4]);
+            for (int i = 0; i < 5; i++) {
+                free(fields[i]);
+            }
+        }
+    }
+
+    append(&buffer, &len, &capacity, "#%zu {main}", n);
+    return buffer;
+}
+
+// describe_throwable formats a Throwable the way PHP reports an uncaught
+// one: class, message, origin and trace. The caller frees the result.
+char* describe_throwable(VMContext* context, Object* object) {
+    char* buffer = NULL;
+    size_t len = 0, capacity = 0;
+
+    char* message = field(context, object->props, "message");
+    char* file = field(context, object->props, PROP_FILE);
+    char* line = field(context, object->props, PROP_LINE);
+    char* trace = trace_as_string(context, object);
+
+    append(&buffer, &len, &capacity, "%s", object->class->name);
+    if (message[0] != '\0') {
+        append(&buffer, &len, &capacity, ": %s", message);
+    }
+    append(&buffer, &len, &capacity, " in %s:%s\nStack trace:\n%s", file, line, trace);
+
+    free(message);
+    free(file);
+    free(line);
+    free(trace);
+    return buffer;
+}
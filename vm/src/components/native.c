@@ -0,0 +1,1374 @@
+/* Licensed under GNU GPL v3. See LICENSE file for details. */
+#include "../../includes/interfaces/native.h"
+#include "../../includes/interfaces/array.h"
+#include "../../includes/interfaces/exception.h"
+#include <ctype.h>
+#include <errno.h>
+#include <math.h>
+#include <stdlib.h>
+#include <string.h>
+
+/*
+ * The standard native functions, the same set the compiler registers in
+ * internal/native. The compiler checks the argument counts, so a function
+ * only reads an optional argument when argc says it was passed. Strings work
+ * on bytes and change the case of ASCII letters only.
+ */
+
+status_t type_error(VMContext* context, const char* fn, int i, const char* param, const char* want, Value got) {
+    return throw_error(context, "TypeError", "%s(): Argument #%d ($%s) must be of type %s, %s given",
+                       fn, i + 1, param, want, context->value_handler->type_name(got));
+}
+
+static status_t value_error(VMContext* context, const char* fn, int i, const char* param, const char* msg) {
+    return throw_error(context, "ValueError", "%s(): Argument #%d ($%s) %s", fn, i + 1, param, msg);
+}
+
+// parse_numeric reports whether s is a PHP numeric string, an int or float
+// literal with optional surrounding whitespace, and stores its value.
+static bool parse_numeric(VMContext* context, const char* s, Value* out) {
+    const char* p = s;
+    while (isspace((unsigned char)*p)) p++;
+
+    const char* start = p;
+    if (*p == '+' || *p == '-') p++;
+
+    const char* digits = p;
+    while (isdigit((unsigned char)*p)) p++;
+    bool has_digits = p > digits;
+    bool is_float = false;
+
+    if (*p == '.') {
+        const char* fraction = ++p;
+        while (isdigit((unsigned char)*p)) p++;
+        has_digits = has_digits || p > fraction;
+        is_float = true;
+    }
+    if (!has_digits) {
+        return false;
+    }
+
+    if (*p == 'e' || *p == 'E') {
+        const char* exponent = p + 1;
+        if (*exponent == '+' || *exponent == '-') exponent++;
+        if (isdigit((unsigned char)*exponent)) {
+            while (isdigit((unsigned char)*exponent)) exponent++;
+            p = exponent;
+            is_float = true;
+        }
+    }
+
+    while (isspace((unsigned char)*p)) p++;
+    if (*p != '\0') {
+        return false;
+    }
+
+    if (!is_float) {
+        errno = 0;
+        long long n = strtoll(start, NULL, 10);
+        if (errno != ERANGE) {
+            *out = context->value_handler->create_int((int_t)n);
+            return true;
+        }
+    }
+
+    *out = context->value_handler->create_float(strtod(start, NULL));
+    return true;
+}
+
+// string_arg converts args[i] to a string the caller frees.
+status_t string_arg(VMContext* context, const char* fn, Value* args, int i, const char* param, char** out) {
+    if (args[i].type == TYPE_ARRAY || args[i].type == TYPE_OBJECT) {
+        return type_error(context, fn, i, param, "string", args[i]);
+    }
+    *out = context->value_handler->to_string(args[i]);
+    return STATUS_SUCCESS;
+}
+
+// number_arg converts args[i] to an int or a float. Strings must be numeric.
+static status_t number_arg(VMContext* context, const char* fn, Value* args, int i, const char* param,
+                           const char* want, Value* out) {
+    Value value = args[i];
+    switch (value.type) {
+        case TYPE_INT:
+        case TYPE_FLOAT:
+            *out = value;
+            return STATUS_SUCCESS;
+        case TYPE_STRING:
+            if (value.value.str_val && parse_numeric(context, value.value.str_val, out)) {
+                return STATUS_SUCCESS;
+            }
+            return type_error(context, fn, i, param, want, value);
+        case TYPE_ARRAY:
+        case TYPE_OBJECT:
+            return type_error(context, fn, i, param, want, value);
+        default:
+            *out = context->value_handler->create_int(context->value_handler->to_int(value));
+            return STATUS_SUCCESS;
+    }
+}
+
+status_t int_arg(VMContext* context, const char* fn, Value* args, int i, const char* param, int_t* out) {
+    Value number;
+    status_t status = number_arg(context, fn, args, i, param, "int", &number);
+    if (status == STATUS_SUCCESS) {
+        *out = context->value_handler->to_int(number);
+    }
+    return status;
+}
+
+static status_t float_arg(VMContext* context, const char* fn, Value* args, int i, const char* param, double* out) {
+    Value number;
+    status_t status = number_arg(context, fn, args, i, param, "float", &number);
+    if (status == STATUS_SUCCESS) {
+        *out = context->value_handler->to_float(number);
+    }
+    return status;
+}
+
+static status_t array_arg(VMContext* context, const char* fn, Value* args, int i, const char* param, Array** out) {
+    if (args[i].type != TYPE_ARRAY) {
+        return type_error(context, fn, i, param, "array", args[i]);
+    }
+    *out = args[i].value.arr_val;
+    return STATUS_SUCCESS;
+}
+
+// element_string converts an array element to a string the caller frees,
+// warning about nested arrays like PHP's string conversion does.
+static char* element_string(VMContext* context, Value value) {
+    if (value.type == TYPE_ARRAY) {
+        context->error_handler->warning("Array to string conversion");
+    }
+    return context->value_handler->to_string(value);
+}
+
+static Value take_string(VMContext* context, char* s) {
+    Value value = context->value_handler->create_string(s);
+    free(s);
+    return value;
+}
+
+static Value key_value(VMContext* context, ArrayKey key) {
+    if (key.is_string) {
+        return context->value_handler->create_string(key.str_key);
+    }
+    return context->value_handler->create_int(key.int_key);
+}
+
+/* Strings */
+
+static status_t native_strlen(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    char* s;
+    status_t status = string_arg(context, "strlen", args, 0, "string", &s);
+    if (status != STATUS_SUCCESS) return status;
+
+    *result = context->value_handler->create_int((int_t)strlen(s));
+    free(s);
+    return STATUS_SUCCESS;
+}
+
+static int ascii_upper(int c) {
+    return c >= 'a' && c <= 'z' ? c - 'a' + 'A' : c;
+}
+
+static int ascii_lower(int c) {
+    return c >= 'A' && c <= 'Z' ? c - 'A' + 'a' : c;
+}
+
+static status_t map_bytes(VMContext* context, const char* fn, Value* args, int (*f)(int), bool all, Value* result) {
+    char* s;
+    status_t status = string_arg(context, fn, args, 0, "string", &s);
+    if (status != STATUS_SUCCESS) return status;
+
+    for (char* p = s; *p; p++) {
+        *p = (char)f((unsigned char)*p);
+        if (!all) break;
+    }
+
+    *result = take_string(context, s);
+    return STATUS_SUCCESS;
+}
+
+static status_t native_strtoupper(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    return map_bytes(context, "strtoupper", args, ascii_upper, true, result);
+}
+
+static status_t native_strtolower(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    return map_bytes(context, "strtolower", args, ascii_lower, true, result);
+}
+
+static status_t native_ucfirst(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    return map_bytes(context, "ucfirst", args, ascii_upper, false, result);
+}
+
+static status_t native_lcfirst(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    return map_bytes(context, "lcfirst", args, ascii_lower, false, result);
+}
+
+static status_t native_strrev(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    char* s;
+    status_t status = string_arg(context, "strrev", args, 0, "string", &s);
+    if (status != STATUS_SUCCESS) return status;
+
+    size_t len = strlen(s);
+    for (size_t i = 0; i < len / 2; i++) {
+        char c = s[i];
+        s[i] = s[len - 1 - i];
+        s[len - 1 - i] = c;
+    }
+
+    *result = take_string(context, s);
+    return STATUS_SUCCESS;
+}
+
+static status_t native_str_repeat(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    char* s;
+    int_t times;
+    status_t status = string_arg(context, "str_repeat", args, 0, "string", &s);
+    if (status != STATUS_SUCCESS) return status;
+    status = int_arg(context, "str_repeat", args, 1, "times", &times);
+    if (status != STATUS_SUCCESS) {
+        free(s);
+        return status;
+    }
+
+    size_t len = strlen(s);
+    if (times < 0) {
+        free(s);
+        return value_error(context, "str_repeat", 1, "times", "must be greater than or equal to 0");
+    }
+    if (len > 0 && times > INT32_MAX / (int_t)len) {
+        free(s);
+        return value_error(context, "str_repeat", 1, "times", "is too large");
+    }
+
+    char* repeated = (char*)malloc(len * (size_t)times + 1);
+    for (int_t i = 0; i < times; i++) {
+        memcpy(repeated + i * len, s, len);
+    }
+    repeated[len * (size_t)times] = '\0';
+    free(s);
+
+    *result = take_string(context, repeated);
+    return STATUS_SUCCESS;
+}
+
+// native_substr follows PHP 8: offsets past the end give "" instead of false.
+static status_t native_substr(VMContext* context, Value* args, int argc, Value* result) {
+    char* s;
+    int_t offset;
+    status_t status = string_arg(context, "substr", args, 0, "string", &s);
+    if (status != STATUS_SUCCESS) return status;
+    status = int_arg(context, "substr", args, 1, "offset", &offset);
+    if (status != STATUS_SUCCESS) {
+        free(s);
+        return status;
+    }
+
+    int_t n = (int_t)strlen(s);
+    if (offset > n) {
+        offset = n;
+    }
+    if (offset < 0) {
+        offset = n + offset < 0 ? 0 : n + offset;
+    }
+
+    int_t end = n;
+    if (argc > 2 && args[2].type != TYPE_NULL) {
+        int_t length;
+        status = int_arg(context, "substr", args, 2, "length", &length);
+        if (status != STATUS_SUCCESS) {
+            free(s);
+            return status;
+        }
+        if (length < 0) {
+            end = n + length;
+        } else if (length < n - offset) {
+            end = offset + length;
+        }
+    }
+
+    if (end <= offset) {
+        s[0] = '\0';
+    } else {
+        s[end] = '\0';
+        memmove(s, s + offset, (size_t)(end - offset) + 1);
+    }
+
+    *result = take_string(context, s);
+    return STATUS_SUCCESS;
+}
+
+static status_t string_pair(VMContext* context, const char* fn, Value* args, char** haystack, char** needle) {
+    status_t status = string_arg(context, fn, args, 0, "haystack", haystack);
+    if (status != STATUS_SUCCESS) return status;
+    status = string_arg(context, fn, args, 1, "needle", needle);
+    if (status != STATUS_SUCCESS) free(*haystack);
+    return status;
+}
+
+// native_strpos returns the position of the first needle at or after the
+// offset, or false.
+static status_t native_strpos(VMContext* context, Value* args, int argc, Value* result) {
+    char* haystack;
+    char* needle;
+    status_t status = string_pair(context, "strpos", args, &haystack, &needle);
+    if (status != STATUS_SUCCESS) return status;
+
+    int_t offset = 0;
+    if (argc > 2) {
+        status = int_arg(context, "strpos", args, 2, "offset", &offset);
+    }
+
+    int_t n = (int_t)strlen(haystack);
+    if (offset < 0) {
+        offset += n;
+    }
+    if (status == STATUS_SUCCESS && (offset < 0 || offset > n)) {
+        status = value_error(context, "strpos", 2, "offset", "must be contained in argument #1 ($haystack)");
+    }
+
+    if (status == STATUS_SUCCESS) {
+        char* found = strstr(haystack + offset, needle);
+        *result = found ? context->value_handler->create_int((int_t)(found - haystack))
+                        : context->value_handler->create_boolean(false);
+    }
+
+    free(haystack);
+    free(needle);
+    return status;
+}
+
+static bool ends_with(const char* s, const char* suffix) {
+    size_t len = strlen(s);
+    size_t suffix_len = strlen(suffix);
+    return suffix_len <= len && strcmp(s + len - suffix_len, suffix) == 0;
+}
+
+static status_t string_predicate(VMContext* context, const char* fn, Value* args, Value* result, int kind) {
+    char* haystack;
+    char* needle;
+    status_t status = string_pair(context, fn, args, &haystack, &needle);
+    if (status != STATUS_SUCCESS) return status;
+
+    bool found;
+    switch (kind) {
+        case 0:
+            found = strstr(haystack, needle) != NULL;
+            break;
+        case 1:
+            found = strncmp(haystack, needle, strlen(needle)) == 0;
+            break;
+        default:
+            found = ends_with(haystack, needle);
+            break;
+    }
+
+    *result = context->value_handler->create_boolean(found);
+    free(haystack);
+    free(needle);
+    return STATUS_SUCCESS;
+}
+
+static status_t native_str_contains(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    return string_predicate(context, "str_contains", args, result, 0);
+}
+
+static status_t native_str_starts_with(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    return string_predicate(context, "str_starts_with", args, result, 1);
+}
+
+static status_t native_str_ends_with(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    return string_predicate(context, "str_ends_with", args, result, 2);
+}
+
+// replace_all returns a copy of s with every from replaced by to. It takes
+// ownership of s.
+static char* replace_all(char* s, const char* from, const char* to) {
+    size_t from_len = strlen(from);
+    if (from_len == 0) {
+        return s;
+    }
+
+    size_t to_len = strlen(to);
+    size_t count = 0;
+    for (const char* p = strstr(s, from); p; p = strstr(p + from_len, from)) {
+        count++;
+    }
+    if (count == 0) {
+        return s;
+    }
+
+    char* replaced = (char*)malloc(strlen(s) + count * to_len - count * from_len + 1);
+    char* out = replaced;
+    const char* p = s;
+    for (const char* match = strstr(p, from); match; match = strstr(p, from)) {
+        memcpy(out, p, (size_t)(match - p));
+        out += match - p;
+        memcpy(out, to, to_len);
+        out += to_len;
+        p = match + from_len;
+    }
+    strcpy(out, p);
+
+    free(s);
+    return replaced;
+}
+
+// native_str_replace replaces every search string in the subject, or in each
+// element of a subject array. With an array of search strings, a replace
+// array gives the replacement for each of them ("" when it runs out) and a
+// replace string is used for all.
+static status_t native_str_replace(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    ValueHandler* values = context->value_handler;
+
+    size_t count = 1;
+    if (args[0].type == TYPE_ARRAY) {
+        count = args[0].value.arr_val->count;
+    } else if (args[1].type == TYPE_ARRAY) {
+        return type_error(context, "str_replace", 1, "replace", "string", args[1]);
+    }
+
+    char** search = (char**)calloc(count + 1, sizeof(char*));
+    char** replace = (char**)calloc(count + 1, sizeof(char*));
+    for (size_t i = 0; i < count; i++) {
+        if (args[0].type != TYPE_ARRAY) {
+            search[i] = values->to_string(args[0]);
+        } else {
+            search[i] = element_string(context, array_at(args[0].value.arr_val, i));
+        }
+
+        if (args[1].type != TYPE_ARRAY) {
+            replace[i] = values->to_string(args[1]);
+        } else if (i < args[1].value.arr_val->count) {
+            replace[i] = element_string(context, array_at(args[1].value.arr_val, i));
+        } else {
+            replace[i] = strdup("");
+        }
+    }
+
+    if (args[2].type != TYPE_ARRAY) {
+        char* s = values->to_string(args[2]);
+        for (size_t i = 0; i < count; i++) {
+            s = replace_all(s, search[i], replace[i]);
+        }
+        *result = take_string(context, s);
+    } else {
+        Array* subject = args[2].value.arr_val;
+        Array* replaced = array_new();
+        for (size_t j = 0; j < subject->count; j++) {
+            Value element = array_at(subject, j);
+            if (element.type != TYPE_ARRAY) {
+                char* s = values->to_string(element);
+                for (size_t i = 0; i < count; i++) {
+                    s = replace_all(s, search[i], replace[i]);
+                }
+                element = take_string(context, s);
+            }
+            array_set(replaced, array_key_at(subject, j), element);
+        }
+        *result = values->create_array(replaced);
+    }
+
+    for (size_t i = 0; i < count; i++) {
+        free(search[i]);
+        free(replace[i]);
+    }
+    free(search);
+    free(replace);
+    return STATUS_SUCCESS;
+}
+
+// DEFAULT_TRIM_CHARS are the characters trim removes when no list is given.
+// NUL cannot occur inside a string of this VM.
+#define DEFAULT_TRIM_CHARS " \n\r\t\v"
+
+static status_t trim_with(VMContext* context, const char* fn, Value* args, int argc, bool left, bool right,
+                          Value* result) {
+    char* s;
+    status_t status = string_arg(context, fn, args, 0, "string", &s);
+    if (status != STATUS_SUCCESS) return status;
+
+    char* chars = NULL;
+    if (argc > 1) {
+        status = string_arg(context, fn, args, 1, "characters", &chars);
+        if (status != STATUS_SUCCESS) {
+            free(s);
+            return status;
+        }
+    }
+    const char* set = chars ? chars : DEFAULT_TRIM_CHARS;
+
+    size_t start = 0;
+    size_t end = strlen(s);
+    if (left) {
+        while (start < end && strchr(set, s[start])) start++;
+    }
+    if (right) {
+        while (end > start && strchr(set, s[end - 1])) end--;
+    }
+
+    s[end] = '\0';
+    memmove(s, s + start, end - start + 1);
+    free(chars);
+
+    *result = take_string(context, s);
+    return STATUS_SUCCESS;
+}
+
+static status_t native_trim(VMContext* context, Value* args, int argc, Value* result) {
+    return trim_with(context, "trim", args, argc, true, true, result);
+}
+
+static status_t native_ltrim(VMContext* context, Value* args, int argc, Value* result) {
+    return trim_with(context, "ltrim", args, argc, true, false, result);
+}
+
+static status_t native_rtrim(VMContext* context, Value* args, int argc, Value* result) {
+    return trim_with(context, "rtrim", args, argc, false, true, result);
+}
+
+// native_implode accepts implode($separator, $array) and implode($array).
+static status_t native_implode(VMContext* context, Value* args, int argc, Value* result) {
+    char* separator = NULL;
+    Value pieces = args[0];
+
+    if (argc == 1) {
+        if (pieces.type != TYPE_ARRAY) {
+            return type_error(context, "implode", 0, "pieces", "array", pieces);
+        }
+    } else {
+        status_t status = string_arg(context, "implode", args, 0, "separator", &separator);
+        if (status != STATUS_SUCCESS) return status;
+        pieces = args[1];
+        if (pieces.type != TYPE_ARRAY) {
+            free(separator);
+            return type_error(context, "implode", 1, "array", "?array", pieces);
+        }
+    }
+
+    Array* array = pieces.value.arr_val;
+    size_t separator_len = separator ? strlen(separator) : 0;
+    size_t capacity = 64;
+    size_t len = 0;
+    char* joined = (char*)malloc(capacity);
+    joined[0] = '\0';
+
+    for (size_t i = 0; i < array->count; i++) {
+        char* part = element_string(context, array_at(array, i));
+        size_t part_len = strlen(part);
+        size_t needed = len + (i > 0 ? separator_len : 0) + part_len + 1;
+        if (needed > capacity) {
+            while (needed > capacity) capacity *= 2;
+            joined = (char*)realloc(joined, capacity);
+        }
+
+        if (i > 0 && separator_len > 0) {
+            memcpy(joined + len, separator, separator_len);
+            len += separator_len;
+        }
+        memcpy(joined + len, part, part_len + 1);
+        len += part_len;
+        free(part);
+    }
+
+    free(separator);
+    *result = take_string(context, joined);
+    return STATUS_SUCCESS;
+}
+
+// native_explode splits the string on the separator. A positive limit caps
+// the number of parts, the last one holding the rest; a negative one drops
+// that many parts from the end.
+static status_t native_explode(VMContext* context, Value* args, int argc, Value* result) {
+    char* separator;
+    char* s;
+    status_t status = string_arg(context, "explode", args, 0, "separator", &separator);
+    if (status != STATUS_SUCCESS) return status;
+    status = string_arg(context, "explode", args, 1, "string", &s);
+    if (status != STATUS_SUCCESS) {
+        free(separator);
+        return status;
+    }
+
+    int_t limit = INT64_MAX;
+    if (argc > 2) {
+        status = int_arg(context, "explode", args, 2, "limit", &limit);
+    }
+    if (status == STATUS_SUCCESS && separator[0] == '\0') {
+        status = value_error(context, "explode", 0, "separator", "cannot be empty");
+    }
+    if (status != STATUS_SUCCESS) {
+        free(separator);
+        free(s);
+        return status;
+    }
+
+    size_t separator_len = strlen(separator);
+    int_t total = 1;
+    for (const char* p = strstr(s, separator); p; p = strstr(p + separator_len, separator)) {
+        total++;
+    }
+
+    int_t parts = total;
+    if (limit == 0) {
+        limit = 1;
+    }
+    if (limit > 0 && limit < total) {
+        parts = limit;
+    } else if (limit < 0) {
+        parts = total + limit;
+    }
+
+    Array* array = array_new();
+    char* p = s;
+    for (int_t i = 0; i < parts; i++) {
+        char* match = (limit > 0 && i == parts - 1) ? NULL : strstr(p, separator);
+        if (match) {
+            *match = '\0';
+        }
+        array_append(array, context->value_handler->create_string(p));
+        if (!match) {
+            break;
+        }
+        p = match + separator_len;
+    }
+
+    free(separator);
+    free(s);
+    *result = context->value_handler->create_array(array);
+    return STATUS_SUCCESS;
+}
+
+/* Math */
+
+static status_t native_abs(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    Value n;
+    status_t status = number_arg(context, "abs", args, 0, "num", "int|float", &n);
+    if (status != STATUS_SUCCESS) return status;
+
+    if (n.type == TYPE_FLOAT) {
+        *result = context->value_handler->create_float(fabs(n.value.float_val));
+    } else if (n.value.int_val == INT64_MIN) {
+        *result = context->value_handler->create_float(-(double)n.value.int_val);
+    } else {
+        *result = context->value_handler->create_int(n.value.int_val < 0 ? -n.value.int_val : n.value.int_val);
+    }
+    return STATUS_SUCCESS;
+}
+
+static status_t native_intdiv(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    int_t a, b;
+    status_t status = int_arg(context, "intdiv", args, 0, "num1", &a);
+    if (status != STATUS_SUCCESS) return status;
+    status = int_arg(context, "intdiv", args, 1, "num2", &b);
+    if (status != STATUS_SUCCESS) return status;
+
+    if (b == 0) {
+        return throw_error(context, "DivisionByZeroError", "Division by zero");
+    }
+    if (a == INT64_MIN && b == -1) {
+        return throw_error(context, "ArithmeticError", "Division of PHP_INT_MIN by -1 is not an integer");
+    }
+
+    *result = context->value_handler->create_int(a / b);
+    return STATUS_SUCCESS;
+}
+
+// extreme returns the value of the arguments, or of the elements of a single
+// array argument, that wins against all others: the first one that no later
+// value beats.
+static status_t extreme(VMContext* context, const char* fn, Value* args, int argc, bool want_max, Value* result) {
+    Value* values = args;
+    size_t count = (size_t)argc;
+
+    if (argc == 1) {
+        if (args[0].type != TYPE_ARRAY) {
+            return type_error(context, fn, 0, "value", "array", args[0]);
+        }
+        Array* array = args[0].value.arr_val;
+        if (array->count == 0) {
+            return value_error(context, fn, 0, "value", "must contain at least one element");
+        }
+
+        *result = array_at(array, 0);
+        for (size_t i = 1; i < array->count; i++) {
+            Value v = array_at(array, i);
+            if (want_max ? context->value_handler->greater_than(v, *result)
+                         : context->value_handler->less_than(v, *result)) {
+                *result = v;
+            }
+        }
+        return STATUS_SUCCESS;
+    }
+
+    *result = values[0];
+    for (size_t i = 1; i < count; i++) {
+        if (want_max ? context->value_handler->greater_than(values[i], *result)
+                     : context->value_handler->less_than(values[i], *result)) {
+            *result = values[i];
+        }
+    }
+    return STATUS_SUCCESS;
+}
+
+static status_t native_max(VMContext* context, Value* args, int argc, Value* result) {
+    return extreme(context, "max", args, argc, true, result);
+}
+
+static status_t native_min(VMContext* context, Value* args, int argc, Value* result) {
+    return extreme(context, "min", args, argc, false, result);
+}
+
+static status_t round_with(VMContext* context, const char* fn, Value* args, double (*f)(double), Value* result) {
+    double n;
+    status_t status = float_arg(context, fn, args, 0, "num", &n);
+    if (status != STATUS_SUCCESS) return status;
+
+    *result = context->value_handler->create_float(f(n));
+    return STATUS_SUCCESS;
+}
+
+static status_t native_floor(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    return round_with(context, "floor", args, floor, result);
+}
+
+static status_t native_ceil(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    return round_with(context, "ceil", args, ceil, result);
+}
+
+static status_t native_sqrt(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    return round_with(context, "sqrt", args, sqrt, result);
+}
+
+// native_round rounds half away from zero to the given number of decimal
+// digits, which may be negative to round to tens, hundreds and so on.
+static status_t native_round(VMContext* context, Value* args, int argc, Value* result) {
+    double n;
+    int_t precision = 0;
+    status_t status = float_arg(context, "round", args, 0, "num", &n);
+    if (status != STATUS_SUCCESS) return status;
+    if (argc > 1) {
+        status = int_arg(context, "round", args, 1, "precision", &precision);
+        if (status != STATUS_SUCCESS) return status;
+    }
+
+    double rounded;
+    if (precision == 0 || isinf(n) || isnan(n)) {
+        rounded = round(n);
+    } else {
+        double scale = pow(10, fabs((double)precision));
+        if (precision > 0) {
+            double scaled = n * scale;
+            rounded = isinf(scaled) ? n : round(scaled) / scale;
+        } else {
+            rounded = round(n / scale) * scale;
+        }
+    }
+
+    *result = context->value_handler->create_float(rounded);
+    return STATUS_SUCCESS;
+}
+
+// native_pow stays in ints for an int base and a non-negative int exponent
+// as long as the result fits, like the ** operator.
+static status_t native_pow(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    Value base, exponent;
+    status_t status = number_arg(context, "pow", args, 0, "num", "int|float", &base);
+    if (status != STATUS_SUCCESS) return status;
+    status = number_arg(context, "pow", args, 1, "exponent", "int|float", &exponent);
+    if (status != STATUS_SUCCESS) return status;
+
+    if (base.type == TYPE_INT && exponent.type == TYPE_INT && exponent.value.int_val >= 0) {
+        int_t b = base.value.int_val;
+        int_t e = exponent.value.int_val;
+        int_t r = 1;
+        bool overflow = false;
+
+        while (e > 0 && !overflow) {
+            if (e & 1) {
+                overflow = __builtin_mul_overflow(r, b, &r);
+            }
+            e >>= 1;
+            if (e > 0 && !overflow) {
+                overflow = __builtin_mul_overflow(b, b, &b);
+            }
+        }
+
+        if (!overflow) {
+            *result = context->value_handler->create_int(r);
+            return STATUS_SUCCESS;
+        }
+    }
+
+    *result = context->value_handler->create_float(
+        pow(context->value_handler->to_float(base), context->value_handler->to_float(exponent)));
+    return STATUS_SUCCESS;
+}
+
+static status_t native_intval(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    *result = context->value_handler->create_int(context->value_handler->to_int(args[0]));
+    return STATUS_SUCCESS;
+}
+
+static status_t native_floatval(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    *result = context->value_handler->create_float(context->value_handler->to_float(args[0]));
+    return STATUS_SUCCESS;
+}
+
+/* Arrays */
+
+static status_t native_count(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    if (args[0].type != TYPE_ARRAY) {
+        return type_error(context, "count", 0, "value", "Countable|array", args[0]);
+    }
+    *result = context->value_handler->create_int((int_t)args[0].value.arr_val->count);
+    return STATUS_SUCCESS;
+}
+
+static status_t native_array_keys(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    Array* array;
+    status_t status = array_arg(context, "array_keys", args, 0, "array", &array);
+    if (status != STATUS_SUCCESS) return status;
+
+    Array* keys = array_new();
+    for (size_t i = 0; i < array->count; i++) {
+        array_append(keys, key_value(context, array_key_at(array, i)));
+    }
+    *result = context->value_handler->create_array(keys);
+    return STATUS_SUCCESS;
+}
+
+static status_t native_array_values(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    Array* array;
+    status_t status = array_arg(context, "array_values", args, 0, "array", &array);
+    if (status != STATUS_SUCCESS) return status;
+
+    Array* values = array_new();
+    for (size_t i = 0; i < array->count; i++) {
+        array_append(values, array_at(array, i));
+    }
+    *result = context->value_handler->create_array(values);
+    return STATUS_SUCCESS;
+}
+
+static status_t native_in_array(VMContext* context, Value* args, int argc, Value* result) {
+    Array* array;
+    status_t status = array_arg(context, "in_array", args, 1, "haystack", &array);
+    if (status != STATUS_SUCCESS) return status;
+
+    bool strict = argc > 2 && context->value_handler->to_boolean(args[2]);
+    bool found = false;
+    for (size_t i = 0; i < array->count && !found; i++) {
+        Value v = array_at(array, i);
+        found = strict ? context->value_handler->identical(v, args[0]) : context->value_handler->equals(v, args[0]);
+    }
+
+    *result = context->value_handler->create_boolean(found);
+    return STATUS_SUCCESS;
+}
+
+static status_t native_array_key_exists(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    Array* array;
+    status_t status = array_arg(context, "array_key_exists", args, 1, "array", &array);
+    if (status != STATUS_SUCCESS) return status;
+
+    ArrayKey key;
+    if (!array_to_key(args[0], &key)) {
+        return value_error(context, "array_key_exists", 0, "key", "must be a valid array offset type");
+    }
+
+    Value ignored;
+    *result = context->value_handler->create_boolean(array_get(array, key, &ignored));
+    return STATUS_SUCCESS;
+}
+
+// native_array_sum adds the elements like the + operator, skipping arrays.
+static status_t native_array_sum(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    ValueHandler* values = context->value_handler;
+    Array* array;
+    status_t status = array_arg(context, "array_sum", args, 0, "array", &array);
+    if (status != STATUS_SUCCESS) return status;
+
+    Value sum = values->create_int(0);
+    for (size_t i = 0; i < array->count; i++) {
+        Value v = array_at(array, i);
+        if (v.type == TYPE_ARRAY) {
+            context->error_handler->warning("Addition is not supported on type array");
+            continue;
+        }
+
+        if (sum.type == TYPE_INT && !values->is_float(v)) {
+            int_t total;
+            if (!__builtin_add_overflow(sum.value.int_val, values->to_int(v), &total)) {
+                sum = values->create_int(total);
+                continue;
+            }
+        }
+        sum = values->create_float(values->to_float(sum) + values->to_float(v));
+    }
+
+    *result = sum;
+    return STATUS_SUCCESS;
+}
+
+// native_array_merge appends the elements of each array in turn: int keys
+// are renumbered, string keys overwrite earlier values.
+static status_t native_array_merge(VMContext* context, Value* args, int argc, Value* result) {
+    Array* merged = array_new();
+    for (int i = 0; i < argc; i++) {
+        Array* array;
+        status_t status = array_arg(context, "array_merge", args, i, "arrays", &array);
+        if (status != STATUS_SUCCESS) return status;
+
+        for (size_t j = 0; j < array->count; j++) {
+            ArrayKey key = array_key_at(array, j);
+            if (key.is_string) {
+                array_set(merged, key, array_at(array, j));
+            } else if (!array_append(merged, array_at(array, j))) {
+                return context->error_handler->runtime_error(
+                    "Cannot add element to the array as the next element is already occupied");
+            }
+        }
+    }
+
+    *result = context->value_handler->create_array(merged);
+    return STATUS_SUCCESS;
+}
+
+static status_t native_array_reverse(VMContext* context, Value* args, int argc, Value* result) {
+    Array* array;
+    status_t status = array_arg(context, "array_reverse", args, 0, "array", &array);
+    if (status != STATUS_SUCCESS) return status;
+
+    bool preserve_keys = argc > 1 && context->value_handler->to_boolean(args[1]);
+    Array* reversed = array_new();
+    for (size_t i = array->count; i > 0; i--) {
+        ArrayKey key = array_key_at(array, i - 1);
+        if (key.is_string || preserve_keys) {
+            array_set(reversed, key, array_at(array, i - 1));
+        } else {
+            array_append(reversed, array_at(array, i - 1));
+        }
+    }
+
+    *result = context->value_handler->create_array(reversed);
+    return STATUS_SUCCESS;
+}
+
+// range_count returns the number of elements of a range spanning span with
+// the given step.
+static status_t range_count(VMContext* context, int_t span, int_t step, int_t* count) {
+    span = span < 0 ? -span : span;
+    step = step < 0 ? -step : step;
+    if (span != 0 && step > span) {
+        return value_error(context, "range", 2, "step", "must not exceed the specified range");
+    }
+
+    *count = span / step + 1;
+    if (*count > INT32_MAX) {
+        return throw_error(context, "ValueError", "The supplied range exceeds the maximum array size");
+    }
+    return STATUS_SUCCESS;
+}
+
+static bool is_letter_arg(Value value) {
+    return value.type == TYPE_STRING && value.value.str_val && strlen(value.value.str_val) == 1 &&
+           !isdigit((unsigned char)value.value.str_val[0]);
+}
+
+// native_range builds range($start, $end, $step): ints when both ends and
+// the step are ints, floats when one of them is a float, and letters when
+// both ends are single characters.
+static status_t native_range(VMContext* context, Value* args, int argc, Value* result) {
+    ValueHandler* values = context->value_handler;
+    Value step = values->create_int(1);
+    status_t status;
+
+    if (argc > 2) {
+        status = number_arg(context, "range", args, 2, "step", "int|float", &step);
+        if (status != STATUS_SUCCESS) return status;
+    }
+    if (values->to_float(step) == 0) {
+        return value_error(context, "range", 2, "step", "cannot be 0");
+    }
+    if (step.type == TYPE_FLOAT && step.value.float_val == trunc(step.value.float_val)) {
+        step = values->create_int((int_t)step.value.float_val);
+    }
+
+    Array* array = array_new();
+
+    if (is_letter_arg(args[0]) && is_letter_arg(args[1]) && step.type == TYPE_INT) {
+        int_t start = (unsigned char)args[0].value.str_val[0];
+        int_t end = (unsigned char)args[1].value.str_val[0];
+        int_t by = step.value.int_val < 0 ? -step.value.int_val : step.value.int_val;
+        int_t count;
+        status = range_count(context, end - start, by, &count);
+        if (status != STATUS_SUCCESS) return status;
+
+        for (int_t i = 0; i < count; i++) {
+            char letter[2] = {(char)(start > end ? start - i * by : start + i * by), '\0'};
+            array_append(array, values->create_string(letter));
+        }
+        *result = values->create_array(array);
+        return STATUS_SUCCESS;
+    }
+
+    Value start, end;
+    status = number_arg(context, "range", args, 0, "start", "int|float|string", &start);
+    if (status != STATUS_SUCCESS) return status;
+    status = number_arg(context, "range", args, 1, "end", "int|float|string", &end);
+    if (status != STATUS_SUCCESS) return status;
+
+    if (start.type == TYPE_INT && end.type == TYPE_INT && step.type == TYPE_INT) {
+        int_t low = start.value.int_val;
+        int_t high = end.value.int_val;
+        int_t by = step.value.int_val < 0 ? -step.value.int_val : step.value.int_val;
+        int_t count;
+        status = range_count(context, high - low, by, &count);
+        if (status != STATUS_SUCCESS) return status;
+
+        for (int_t i = 0; i < count; i++) {
+            array_append(array, values->create_int(low > high ? low - i * by : low + i * by));
+        }
+        *result = values->create_array(array);
+        return STATUS_SUCCESS;
+    }
+
+    double low = values->to_float(start);
+    double high = values->to_float(end);
+    double by = fabs(values->to_float(step));
+    if (fabs(high - low) < by && low != high) {
+        return value_error(context, "range", 2, "step", "must not exceed the specified range");
+    }
+
+    double size = floor(fabs(high - low) / by) + 1;
+    if (isnan(size) || size > INT32_MAX) {
+        return throw_error(context, "ValueError", "The supplied range exceeds the maximum array size");
+    }
+    for (int_t i = 0; i < (int_t)size; i++) {
+        array_append(array, values->create_float(low > high ? low - (double)i * by : low + (double)i * by));
+    }
+    *result = values->create_array(array);
+    return STATUS_SUCCESS;
+}
+
+// native_array_push appends the values to the array passed by reference and
+// returns its new size.
+static status_t native_array_push(VMContext* context, Value* args, int argc, Value* result) {
+    Array* array;
+    status_t status = array_arg(context, "array_push", args, 0, "array", &array);
+    if (status != STATUS_SUCCESS) return status;
+
+    array = array_separate(array);
+    for (int i = 1; i < argc; i++) {
+        if (!array_append(array, args[i])) {
+            return context->error_handler->runtime_error(
+                "Cannot add element to the array as the next element is already occupied");
+        }
+    }
+
+    args[0] = context->value_handler->create_array(array);
+    *result = context->value_handler->create_int((int_t)array->count);
+    return STATUS_SUCCESS;
+}
+
+static status_t native_array_pop(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    Array* array;
+    status_t status = array_arg(context, "array_pop", args, 0, "array", &array);
+    if (status != STATUS_SUCCESS) return status;
+
+    array = array_separate(array);
+    if (!array_pop(array, result)) {
+        *result = context->value_handler->create_null();
+    }
+
+    args[0] = context->value_handler->create_array(array);
+    return STATUS_SUCCESS;
+}
+
+// merge_sort sorts values in ascending order and keeps equal values in
+// their order.
+static void merge_sort(ValueHandler* handler, Value* values, Value* scratch, size_t count) {
+    if (count < 2) {
+        return;
+    }
+
+    size_t half = count / 2;
+    merge_sort(handler, values, scratch, half);
+    merge_sort(handler, values + half, scratch, count - half);
+
+    size_t i = 0, j = half, k = 0;
+    while (i < half && j < count) {
+        if (handler->less_than(values[j], values[i])) {
+            scratch[k++] = values[j++];
+        } else {
+            scratch[k++] = values[i++];
+        }
+    }
+    while (i < half) scratch[k++] = values[i++];
+    while (j < count) scratch[k++] = values[j++];
+
+    memcpy(values, scratch, count * sizeof(Value));
+}
+
+// native_sort sorts the values of the array passed by reference with the
+// rules of the comparison operators and renumbers the keys.
+static status_t native_sort(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    Array* array;
+    status_t status = array_arg(context, "sort", args, 0, "array", &array);
+    if (status != STATUS_SUCCESS) return status;
+
+    size_t count = array->count;
+    Value* values = (Value*)malloc((count + 1) * sizeof(Value));
+    Value* scratch = (Value*)malloc((count + 1) * sizeof(Value));
+    for (size_t i = 0; i < count; i++) {
+        values[i] = array_at(array, i);
+    }
+    merge_sort(context->value_handler, values, scratch, count);
+
+    Array* sorted = array_new();
+    for (size_t i = 0; i < count; i++) {
+        array_append(sorted, values[i]);
+    }
+    free(values);
+    free(scratch);
+
+    args[0] = context->value_handler->create_array(sorted);
+    *result = context->value_handler->create_boolean(true);
+    return STATUS_SUCCESS;
+}
+
+// user_merge_sort sorts values like merge_sort, in the order the callback
+// gives, which returns an int below, equal to or above zero like <=>. It
+// stops at the first failure of the callback.
+static status_t user_merge_sort(VMContext* context, Value callback, Value* values, Value* scratch, size_t count) {
+    if (count < 2) {
+        return STATUS_SUCCESS;
+    }
+
+    size_t half = count / 2;
+    status_t status = user_merge_sort(context, callback, values, scratch, half);
+    if (status != STATUS_SUCCESS) return status;
+    status = user_merge_sort(context, callback, values + half, scratch, count - half);
+    if (status != STATUS_SUCCESS) return status;
+
+    size_t i = 0, j = half, k = 0;
+    while (i < half && j < count) {
+        Value pair[2] = {values[j], values[i]};
+        Value order;
+        status = call_value(context, callback, pair, 2, &order);
+        if (status != STATUS_SUCCESS) return status;
+
+        if (context->value_handler->to_int(order) < 0) {
+            scratch[k++] = values[j++];
+        } else {
+            scratch[k++] = values[i++];
+        }
+    }
+    while (i < half) scratch[k++] = values[i++];
+    while (j < count) scratch[k++] = values[j++];
+
+    memcpy(values, scratch, count * sizeof(Value));
+    return STATUS_SUCCESS;
+}
+
+// native_usort sorts the values of the array passed by reference with the
+// callback and renumbers the keys. Equal values keep their order.
+static status_t native_usort(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    Array* array;
+    status_t status = array_arg(context, "usort", args, 0, "array", &array);
+    if (status != STATUS_SUCCESS) return status;
+
+    size_t count = array->count;
+    Value* values = (Value*)malloc((count + 1) * sizeof(Value));
+    Value* scratch = (Value*)malloc((count + 1) * sizeof(Value));
+    for (size_t i = 0; i < count; i++) {
+        values[i] = array_at(array, i);
+    }
+
+    value_retain(args[0]);
+    status = user_merge_sort(context, args[1], values, scratch, count);
+    value_release(args[0]);
+
+    Array* sorted = NULL;
+    if (status == STATUS_SUCCESS) {
+        sorted = array_new();
+        for (size_t i = 0; i < count; i++) {
+            array_append(sorted, values[i]);
+        }
+    }
+    free(values);
+    free(scratch);
+    if (status != STATUS_SUCCESS) return status;
+
+    args[0] = context->value_handler->create_array(sorted);
+    *result = context->value_handler->create_boolean(true);
+    return STATUS_SUCCESS;
+}
+
+// native_array_map calls the callback with each element and returns the
+// results under the same keys. A null callback gives back the array.
+static status_t native_array_map(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    Array* array;
+    status_t status = array_arg(context, "array_map", args, 1, "array", &array);
+    if (status != STATUS_SUCCESS) return status;
+
+    if (args[0].type == TYPE_NULL) {
+        *result = args[1];
+        return STATUS_SUCCESS;
+    }
+
+    value_retain(args[1]);
+    Array* mapped = array_new();
+    for (size_t i = 0; i < array->count && status == STATUS_SUCCESS; i++) {
+        Value element = array_at(array, i);
+        Value value;
+        status = call_value(context, args[0], &element, 1, &value);
+        if (status == STATUS_SUCCESS) {
+            array_set(mapped, array_key_at(array, i), value);
+        }
+    }
+    value_release(args[1]);
+    if (status != STATUS_SUCCESS) return status;
+
+    *result = context->value_handler->create_array(mapped);
+    return STATUS_SUCCESS;
+}
+
+/* Types */
+
+static status_t is_type(VMContext* context, Value* args, ValueType type, Value* result) {
+    *result = context->value_handler->create_boolean(args[0].type == type);
+    return STATUS_SUCCESS;
+}
+
+static status_t native_is_array(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    return is_type(context, args, TYPE_ARRAY, result);
+}
+
+static status_t native_is_bool(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    return is_type(context, args, TYPE_BOOLEAN, result);
+}
+
+static status_t native_is_float(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    return is_type(context, args, TYPE_FLOAT, result);
+}
+
+static status_t native_is_int(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    return is_type(context, args, TYPE_INT, result);
+}
+
+static status_t native_is_string(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    return is_type(context, args, TYPE_STRING, result);
+}
+
+static status_t native_is_numeric(VMContext* context, Value* args, int argc, Value* result) {
+    (void)argc;
+    Value ignored;
+    bool numeric = args[0].type == TYPE_INT || args[0].type == TYPE_FLOAT ||
+                   (args[0].type == TYPE_STRING && args[0].value.str_val &&
+                    parse_numeric(context, args[0].value.str_val, &ignored));
+
+    *result = context->value_handler->create_boolean(numeric);
+    return STATUS_SUCCESS;
+}
+
+static const NativeFunction natives[] = {
+    {"strlen", 1, 1, 0, native_strlen},
+    {"strtoupper", 1, 1, 0, native_strtoupper},
+    {"strtolower", 1, 1, 0, native_strtolower},
+    {"ucfirst", 1, 1, 0, native_ucfirst},
+    {"lcfirst", 1, 1, 0, native_lcfirst},
+    {"strrev", 1, 1, 0, native_strrev},
+    {"str_repeat", 2, 2, 0, native_str_repeat},
+    {"substr", 2, 3, 0, native_substr},
+    {"strpos", 2, 3, 0, native_strpos},
+    {"str_contains", 2, 2, 0, native_str_contains},
+    {"str_starts_with", 2, 2, 0, native_str_starts_with},
+    {"str_ends_with", 2, 2, 0, native_str_ends_with},
+    {"str_replace", 3, 3, 0, native_str_replace},
+    {"trim", 1, 2, 0, native_trim},
+    {"ltrim", 1, 2, 0, native_ltrim},
+    {"rtrim", 1, 2, 0, native_rtrim},
+    {"implode", 1, 2, 0, native_implode},
+    {"explode", 2, 3, 0, native_explode},
+
+    {"abs", 1, 1, 0, native_abs},
+    {"intdiv", 2, 2, 0, native_intdiv},
+    {"max", 1, VARIADIC, 0, native_max},
+    {"min", 1, VARIADIC, 0, native_min},
+    {"floor", 1, 1, 0, native_floor},
+    {"ceil", 1, 1, 0, native_ceil},
+    {"round", 1, 2, 0, native_round},
+    {"sqrt", 1, 1, 0, native_sqrt},
+    {"pow", 2, 2, 0, native_pow},
+    {"intval", 1, 1, 0, native_intval},
+    {"floatval", 1, 1, 0, native_floatval},
+
+    {"count", 1, 1, 0, native_count},
+    {"array_keys", 1, 1, 0, native_array_keys},
+    {"array_values", 1, 1, 0, native_array_values},
+    {"in_array", 2, 3, 0, native_in_array},
+    {"array_key_exists", 2, 2, 0, native_array_key_exists},
+    {"array_sum", 1, 1, 0, native_array_sum},
+    {"array_merge", 0, VARIADIC, 0, native_array_merge},
+    {"array_reverse", 1, 2, 0, native_array_reverse},
+    {"range", 2, 3, 0, native_range},
+    {"array_push", 1, VARIADIC, 1u << 0, native_array_push},
+    {"array_pop", 1, 1, 1u << 0, native_array_pop},
+    {"sort", 1, 1, 1u << 0, native_sort},
+    {"usort", 2, 2, 1u << 0, native_usort},
+    {"array_map", 2, 2, 0, native_array_map},
+
+    {"is_array", 1, 1, 0, native_is_array},
+    {"is_bool", 1, 1, 0, native_is_bool},
+    {"is_float", 1, 1, 0, native_is_float},
+    {"is_int", 1, 1, 0, native_is_int},
+    {"is_string", 1, 1, 0, native_is_string},
+    {"is_numeric", 1, 1, 0, native_is_numeric},
+};
+
+// native_lookup finds a native by the name the compiler emits.
+const NativeFunction* native_lookup(const char* name) {
+    for (size_t i = 0; i < sizeof(natives) / sizeof(natives[0]); i++) {
+        if (strcmp(natives[i].name, name) == 0) {
+            return &natives[i];
+        }
+    }
+    return NULL;
+}
@@ -0,0 +1,193 @@
+/* Licensed under GNU GPL v3. See LICENSE file for details. */
+#include "../../includes/interfaces/object.h"
+#include <stdlib.h>
+#include <string.h>
+#include <strings.h>
+
+Class* class_new(const char* name, byte_t flags) {
+    Class* class = (Class*)calloc(1, sizeof(Class));
+    if (!class) return NULL;
+
+    class->name = strdup(name);
+    class->flags = flags;
+    return class;
+}
+
+Class* class_find(Class* classes, const char* name) {
+    for (Class* class = classes; class; class = class->next) {
+        if (strcasecmp(class->name, name) == 0) {
+            return class;
+        }
+    }
+    return NULL;
+}
+
+// put_property adds a copy of property, replacing an earlier one of the
+// same name.
+static void put_property(Class* class, const Property* property) {
+    value_retain(property->default_value);
+
+    for (size_t i = 0; i < class->property_count; i++) {
+        if (strcmp(class->properties[i].name, property->name) == 0) {
+            value_release(class->properties[i].default_value);
+            class->properties[i].class = property->class;
+            class->properties[i].default_value = property->default_value;
+            class->properties[i].visibility = property->visibility;
+            return;
+        }
+    }
+
+    if (class->property_count == class->property_capacity) {
+        class->property_capacity = class->property_capacity ? class->property_capacity * 2 : 4;
+        class->properties = (Property*)realloc(class->properties, class->property_capacity * sizeof(Property));
+    }
+
+    Property* added = &class->properties[class->property_count++];
+    *added = *property;
+    added->name = strdup(property->name);
+}
+
+// class_add_property declares a property, replacing an earlier one of the
+// same name.
+void class_add_property(Class* class, const char* name, Value default_value, Visibility visibility) {
+    Property property = {(char*)name, class, default_value, visibility};
+    put_property(class, &property);
+}
+
+const Property* class_property(const Class* class, const char* name) {
+    for (size_t i = 0; i < class->property_count; i++) {
+        if (strcmp(class->properties[i].name, name) == 0) {
+            return &class->properties[i];
+        }
+    }
+    return NULL;
+}
+
+// class_add_method adds a copy of method, replacing an earlier method of
+// the same name, such as an inherited one.
+void class_add_method(Class* class, const Method* method) {
+    for (size_t i = 0; i < class->method_count; i++) {
+        if (strcasecmp(class->methods[i].name, method->name) == 0) {
+            char* name = class->methods[i].name;
+            class->methods[i] = *method;
+            class->methods[i].name = name;
+            return;
+        }
+    }
+
+    if (class->method_count == class->method_capacity) {
+        class->method_capacity = class->method_capacity ? class->method_capacity * 2 : 4;
+        class->methods = (Method*)realloc(class->methods, class->method_capacity * sizeof(Method));
+    }
+
+    Method* added = &class->methods[class->method_count++];
+    *added = *method;
+    added->name = strdup(method->name);
+}
+
+const Method* class_method(const Class* class, const char* name) {
+    for (size_t i = 0; i < class->method_count; i++) {
+        if (strcasecmp(class->methods[i].name, name) == 0) {
+            return &class->methods[i];
+        }
+    }
+    return NULL;
+}
+
+// class_inherit makes class a subclass of parent. It runs before class
+// declares its own members, which then replace the inherited ones.
+void class_inherit(Class* class, Class* parent) {
+    class->parent = parent;
+    for (size_t i = 0; i < parent->property_count; i++) {
+        put_property(class, &parent->properties[i]);
+    }
+    for (size_t i = 0; i < parent->method_count; i++) {
+        class_add_method(class, &parent->methods[i]);
+    }
+    for (size_t i = 0; i < parent->interface_count; i++) {
+        class_implement(class, parent->interfaces[i]);
+    }
+}
+
+static void add_interface(Class* class, Class* iface) {
+    if (class_instance_of(class, iface)) {
+        return;
+    }
+
+    if (class->interface_count == class->interface_capacity) {
+        class->interface_capacity = class->interface_capacity ? class->interface_capacity * 2 : 4;
+        class->interfaces = (Class**)realloc(class->interfaces, class->interface_capacity * sizeof(Class*));
+    }
+    class->interfaces[class->interface_count++] = iface;
+}
+
+// class_implement adds iface and the interfaces it extends to the
+// interfaces of class.
+void class_implement(Class* class, Class* iface) {
+    add_interface(class, iface);
+    for (size_t i = 0; i < iface->interface_count; i++) {
+        add_interface(class, iface->interfaces[i]);
+    }
+}
+
+// class_instance_of reports whether class is other, extends it or
+// implements it.
+bool class_instance_of(const Class* class, const Class* other) {
+    for (const Class* c = class; c; c = c->parent) {
+        if (c == other) {
+            return true;
+        }
+    }
+    for (size_t i = 0; i < class->interface_count; i++) {
+        if (class->interfaces[i] == other) {
+            return true;
+        }
+    }
+    return false;
+}
+
+const char* class_kind_name(const Class* class) {
+    if (class->flags & CLASS_INTERFACE) {
+        return "interface";
+    }
+    if (class->flags & CLASS_TRAIT) {
+        return "trait";
+    }
+    return "class";
+}
+
+// object_new creates an object whose properties hold the class defaults.
+Object* object_new(Class* class) {
+    Object* object = (Object*)malloc(sizeof(Object));
+    if (!object) return NULL;
+
+    object->class = class;
+    object->props = array_new();
+    object->closure = NULL;
+    for (size_t i = 0; i < class->property_count; i++) {
+        array_set(object->props, object_key(class->properties[i].name), class->properties[i].default_value);
+    }
+
+    return object;
+}
+
+// object_key returns the key of a property in Object.props. Property names
+// are always string keys, even when they look like integers.
+ArrayKey object_key(const char* name) {
+    ArrayKey key;
+    key.is_string = true;
+    key.int_key = 0;
+    key.str_key = (char*)name;
+    return key;
+}
+
+const char* visibility_name(Visibility visibility) {
+    switch (visibility) {
+        case VISIBILITY_PROTECTED:
+            return "protected";
+        case VISIBILITY_PRIVATE:
+            return "private";
+        default:
+            return "public";
+    }
+}
@@ -104,7 +104,7 @@ static void print() {
 
         switch (value.type) {
             case TYPE_INT:
-                printf("%d", value.value.int_val);
+                printf("%" INT_FMT, value.value.int_val);
                 break;
             case TYPE_STRING:
                 printf("\"%s\"", value.value.str_val ? value.value.str_val : "");
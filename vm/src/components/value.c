@@ -1,8 +1,11 @@
 /* Licensed under GNU GPL v3. See LICENSE file for details. */
 #include "../../includes/interfaces/value_handler.h"
+#include "../../includes/interfaces/array.h"
+#include "../../includes/interfaces/object.h"
 #include <stdlib.h>
 #include <string.h>
 #include <stdio.h>
+#include <math.h>
 
 static Value create_int(int_t value) {
     Value val;
@@ -43,28 +46,123 @@ static Value create_null(void) {
     return val;
 }
 
+static Value create_float(double value) {
+    Value val;
+    val.type = TYPE_FLOAT;
+    val.value.float_val = value;
+    return val;
+}
+
+static Value create_array(Array* value) {
+    Value val;
+    val.type = TYPE_ARRAY;
+    val.value.arr_val = value;
+    return val;
+}
+
+static Value create_object(Object* value) {
+    Value val;
+    val.type = TYPE_OBJECT;
+    val.value.obj_val = value;
+    return val;
+}
+
+static bool to_boolean(Value value);
+
 static int_t to_int(Value value) {
     switch (value.type) {
         case TYPE_INT:
             return value.value.int_val;
+        case TYPE_FLOAT:
+            // Floats without an int_t representation become 0, as in PHP 8.
+            if (isnan(value.value.float_val) || value.value.float_val >= 9223372036854775808.0 ||
+                value.value.float_val < -9223372036854775808.0) {
+                return 0;
+            }
+            return (int_t)value.value.float_val;
         case TYPE_STRING:
-            return value.value.str_val ? atoi(value.value.str_val) : 0;
+            return value.value.str_val ? (int_t)strtoll(value.value.str_val, NULL, 10) : 0;
         case TYPE_BOOLEAN:
             return value.value.bool_val ? 1 : 0;
         case TYPE_NULL:
             return 0;
+        case TYPE_ARRAY:
+        case TYPE_OBJECT:
+            return to_boolean(value) ? 1 : 0;
         default:
             return 0;
     }
 }
 
+static double to_float(Value value) {
+    switch (value.type) {
+        case TYPE_FLOAT:
+            return value.value.float_val;
+        case TYPE_STRING:
+            return value.value.str_val ? strtod(value.value.str_val, NULL) : 0.0;
+        default:
+            return (double)to_int(value);
+    }
+}
+
+// is_float reports whether arithmetic on value has to be done in floating
+// point: floats and strings holding a fraction or an exponent.
+static bool is_float(Value value) {
+    if (value.type == TYPE_FLOAT) {
+        return true;
+    }
+
+    if (value.type == TYPE_STRING && value.value.str_val) {
+        char* end = NULL;
+        strtod(value.value.str_val, &end);
+        return end != value.value.str_val && strpbrk(value.value.str_val, ".eE") != NULL &&
+               strpbrk(value.value.str_val, ".eE") < end;
+    }
+
+    return false;
+}
+
+void value_format_float(double value, char* buffer, size_t size) {
+    if (isnan(value)) {
+        snprintf(buffer, size, "NAN");
+        return;
+    }
+    if (isinf(value)) {
+        snprintf(buffer, size, value > 0 ? "INF" : "-INF");
+        return;
+    }
+
+    char formatted[64];
+    snprintf(formatted, sizeof(formatted), "%.*G", FLOAT_PRECISION, value);
+
+    char* exponent = strchr(formatted, 'E');
+    if (!exponent) {
+        snprintf(buffer, size, "%s", formatted);
+        return;
+    }
+
+    // PHP prints 1.0E+25 and 1.0E-5 where C prints 1E+25 and 1E-05.
+    *exponent = '\0';
+    char sign = exponent[1];
+    char* digits = exponent + 2;
+    while (digits[0] == '0' && digits[1] != '\0') {
+        digits++;
+    }
+
+    snprintf(buffer, size, "%s%sE%c%s", formatted, strchr(formatted, '.') ? "" : ".0", sign, digits);
+}
+
 static char* to_string(Value value) {
     char buffer[64];
     char* result = NULL;
 
     switch (value.type) {
         case TYPE_INT:
-            snprintf(buffer, sizeof(buffer), "%d", value.value.int_val);
+            snprintf(buffer, sizeof(buffer), "%" INT_FMT, value.value.int_val);
+            result = strdup(buffer);
+            break;
+        case TYPE_FLOAT:
+            value_format_float(value.value.float_val, buffer, sizeof(buffer));
             result = strdup(buffer);
             break;
         case TYPE_STRING:
@@ -75,10 +173,16 @@ static char* to_string(Value value) {
             }
             break;
         case TYPE_BOOLEAN:
-            result = strdup(value.value.bool_val ? "true" : "false");
+            result = strdup(value.value.bool_val ? "1" : "");
             break;
         case TYPE_NULL:
-            result = strdup("null");
+            result = strdup("");
+            break;
+        case TYPE_ARRAY:
+            result = strdup("Array");
+            break;
+        case TYPE_OBJECT:
+            result = strdup("Object");
             break;
         default:
             result = strdup("unknown");
@@ -96,6 +200,8 @@ static bool to_boolean(Value value) {
     switch (value.type) {
         case TYPE_INT:
             return value.value.int_val != 0;
+        case TYPE_FLOAT:
+            return value.value.float_val != 0.0;
         case TYPE_STRING:
             return value.value.str_val &&
                   (strcmp(value.value.str_val, "") != 0) &&
@@ -104,12 +210,135 @@ static bool to_boolean(Value value) {
             return value.value.bool_val;
         case TYPE_NULL:
             return false;
+        case TYPE_ARRAY:
+            return value.value.arr_val->count > 0;
+        case TYPE_OBJECT:
+            return true;
         default:
             return false;
     }
 }
 
+static const char* type_name(Value value) {
+    switch (value.type) {
+        case TYPE_INT:
+            return "int";
+        case TYPE_FLOAT:
+            return "float";
+        case TYPE_STRING:
+            return "string";
+        case TYPE_BOOLEAN:
+            return "bool";
+        case TYPE_NULL:
+            return "null";
+        case TYPE_ARRAY:
+            return "array";
+        case TYPE_OBJECT:
+            return value.value.obj_val->class->name;
+        default:
+            return "unknown";
+    }
+}
+
+static bool is_number(Value value) {
+    return value.type == TYPE_INT || value.type == TYPE_FLOAT;
+}
+
+static bool equals(Value a, Value b);
+static bool less_than(Value a, Value b);
+
+static int compare(Value a, Value b) {
+    if (equals(a, b)) {
+        return 0;
+    }
+    return less_than(a, b) ? -1 : 1;
+}
+
+// compare_arrays compares arrays like PHP: the smaller array is less, and
+// arrays of the same size are compared element by element. comparable is
+// false when a key of a is missing in b.
+static int compare_arrays(Array* a, Array* b, bool* comparable) {
+    *comparable = true;
+
+    if (a->count != b->count) {
+        return a->count < b->count ? -1 : 1;
+    }
+
+    for (size_t i = 0; i < a->count; i++) {
+        Value other;
+        if (!array_get(b, array_key_at(a, i), &other)) {
+            *comparable = false;
+            return 1;
+        }
+
+        int result = compare(array_at(a, i), other);
+        if (result != 0) {
+            return result;
+        }
+    }
+
+    return 0;
+}
+
+// compare_with_array handles comparisons where a or b is an array: null and
+// booleans compare as booleans, and an array is greater than other scalars.
+static int compare_with_array(Value a, Value b, bool* comparable) {
+    *comparable = true;
+
+    if (a.type == TYPE_ARRAY && b.type == TYPE_ARRAY) {
+        return compare_arrays(a.value.arr_val, b.value.arr_val, comparable);
+    }
+
+    if (a.type == TYPE_BOOLEAN || b.type == TYPE_BOOLEAN || a.type == TYPE_NULL || b.type == TYPE_NULL) {
+        return (int)to_boolean(a) - (int)to_boolean(b);
+    }
+
+    return a.type == TYPE_ARRAY ? 1 : -1;
+}
+
+// compare_with_object handles comparisons where a or b is an object. Objects
+// of the same class are compared property by property; objects of different
+// classes are not comparable. An object is greater than any other value.
+static int compare_with_object(Value a, Value b, bool* comparable) {
+    *comparable = true;
+
+    if (a.type != TYPE_OBJECT) {
+        return -1;
+    }
+    if (b.type != TYPE_OBJECT) {
+        return 1;
+    }
+
+    const Object* x = a.value.obj_val;
+    const Object* y = b.value.obj_val;
+    if (x == y) {
+        return 0;
+    }
+    if (x->class != y->class) {
+        *comparable = false;
+        return 1;
+    }
+    return compare_arrays(x->props, y->props, comparable);
+}
+
 static bool equals(Value a, Value b) {
+    if (a.type == TYPE_OBJECT || b.type == TYPE_OBJECT) {
+        bool comparable;
+        int result = compare_with_object(a, b, &comparable);
+        return comparable && result == 0;
+    }
+
+    if (a.type == TYPE_ARRAY || b.type == TYPE_ARRAY) {
+        bool comparable;
+        int result = compare_with_array(a, b, &comparable);
+        return comparable && result == 0;
+    }
+
+    if ((a.type == TYPE_FLOAT || b.type == TYPE_FLOAT) &&
+        (is_number(a) || a.type == TYPE_STRING) && (is_number(b) || b.type == TYPE_STRING)) {
+        return to_float(a) == to_float(b);
+    }
+
     if (a.type != b.type) {
         if ((a.type == TYPE_INT && b.type == TYPE_STRING) ||
             (a.type == TYPE_STRING && b.type == TYPE_INT)) {
@@ -145,6 +374,21 @@ static bool equals(Value a, Value b) {
 }
 
 static bool less_than(Value a, Value b) {
+    if (a.type == TYPE_OBJECT || b.type == TYPE_OBJECT) {
+        bool comparable;
+        return compare_with_object(a, b, &comparable) < 0;
+    }
+
+    if (a.type == TYPE_ARRAY || b.type == TYPE_ARRAY) {
+        bool comparable;
+        return compare_with_array(a, b, &comparable) < 0;
+    }
+
+    if ((a.type == TYPE_FLOAT || b.type == TYPE_FLOAT) &&
+        (is_number(a) || a.type == TYPE_STRING) && (is_number(b) || b.type == TYPE_STRING)) {
+        return to_float(a) < to_float(b);
+    }
+
     if (a.type != b.type) {
         if ((a.type == TYPE_INT && b.type == TYPE_STRING) ||
             (a.type == TYPE_STRING && b.type == TYPE_INT)) {
@@ -183,10 +427,50 @@ static bool greater_than(Value a, Value b) {
     return !equals(a, b) && !less_than(a, b);
 }
 
+// identical implements ===: the same type and value, and for arrays the
+// same key/value pairs in the same order, and for objects the same object.
+static bool identical(Value a, Value b) {
+    if (a.type != b.type) {
+        return false;
+    }
+
+    if (a.type == TYPE_OBJECT) {
+        return a.value.obj_val == b.value.obj_val;
+    }
+
+    if (a.type != TYPE_ARRAY) {
+        return equals(a, b);
+    }
+
+    Array* x = a.value.arr_val;
+    Array* y = b.value.arr_val;
+    if (x->count != y->count) {
+        return false;
+    }
+
+    for (size_t i = 0; i < x->count; i++) {
+        ArrayKey kx = array_key_at(x, i);
+        ArrayKey ky = array_key_at(y, i);
+        if (kx.is_string != ky.is_string ||
+            (kx.is_string ? strcmp(kx.str_key, ky.str_key) != 0 : kx.int_key != ky.int_key) ||
+            !identical(array_at(x, i), array_at(y, i))) {
+            return false;
+        }
+    }
+
+    return true;
+}
+
 static void print(Value value) {
+    char buffer[64];
+
     switch (value.type) {
         case TYPE_INT:
-            printf("%d", value.value.int_val);
+            printf("%" INT_FMT, value.value.int_val);
+            break;
+        case TYPE_FLOAT:
+            value_format_float(value.value.float_val, buffer, sizeof(buffer));
+            printf("%s", buffer);
             break;
         case TYPE_STRING:
             if (value.value.str_val) {
@@ -194,10 +478,15 @@ static void print(Value value) {
             }
             break;
         case TYPE_BOOLEAN:
-            printf("%s", value.value.bool_val ? "true" : "false");
+            printf("%s", value.value.bool_val ? "1" : "");
             break;
         case TYPE_NULL:
-            printf("null");
+            break;
+        case TYPE_ARRAY:
+            printf("Array");
+            break;
+        case TYPE_OBJECT:
+            printf("Object");
             break;
         default:
             printf("unknown");
@@ -222,12 +511,20 @@ ValueHandler* value_handler_new(void) {
     handler->create_string = create_string;
     handler->create_boolean = create_boolean;
     handler->create_null = create_null;
+    handler->create_float = create_float;
+    handler->create_array = create_array;
+    handler->create_object = create_object;
     handler->to_int = to_int;
+    handler->to_float = to_float;
+    handler->is_float = is_float;
     handler->to_string = to_string;
     handler->to_boolean = to_boolean;
+    handler->type_name = type_name;
     handler->equals = equals;
     handler->less_than = less_than;
     handler->greater_than = greater_than;
+    handler->compare = compare;
+    handler->identical = identical;
     handler->print = print;
     handler->free = free_value;
 
@@ -236,4 +533,51 @@ ValueHandler* value_handler_new(void) {
 
 void value_handler_free(ValueHandler* handler) {
     free(handler);
-}
\ No newline at end of file
+}
+// value_deref returns the value a reference holds, or value itself when it
+// is no reference.
+Value value_deref(Value value) {
+    if (value.type == TYPE_REFERENCE) {
+        return value.value.ref_val->value;
+    }
+    return value;
+}
+
+// value_assign stores value in a variable slot, through the reference the
+// slot is bound to if any.
+void value_assign(Value* slot, Value value) {
+    if (slot->type == TYPE_REFERENCE) {
+        slot = &slot->value.ref_val->value;
+    }
+    value_retain(value);
+    value_release(*slot);
+    *slot = value;
+}
+
+// value_bind makes a variable slot hold ref, dropping the value or
+// reference it held before.
+void value_bind(Value* slot, Value ref) {
+    value_retain(ref);
+    value_release(*slot);
+    *slot = ref;
+}
+
+// value_make_ref moves the value of a variable slot or an array element into
+// a new reference, unless it holds one already, and returns the reference.
+// An undefined variable becomes null, as binding a reference defines it.
+Value value_make_ref(Value* slot) {
+    if (slot->type == TYPE_UNDEF) {
+        slot->type = TYPE_NULL;
+    }
+    if (slot->type != TYPE_REFERENCE) {
+        Reference* ref = (Reference*)malloc(sizeof(Reference));
+        if (!ref) {
+            return *slot;
+        }
+        ref->value = *slot;
+        ref->refs = 1;
+        slot->type = TYPE_REFERENCE;
+        slot->value.ref_val = ref;
+    }
+    return *slot;
+}
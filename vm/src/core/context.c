@@ -13,11 +13,33 @@ VMContext* vm_context_new() {
     context->constants = NULL;
     context->constants_len = 0;
     context->variables = (Value*)calloc(VAR_COUNT, sizeof(Value));
+    context->statics = (bool*)calloc(VAR_COUNT, sizeof(bool));
+    context->frames = (CallFrame*)calloc(FRAME_COUNT, sizeof(CallFrame));
+    context->frame_count = 0;
+    context->classes = NULL;
+    context->declaring = NULL;
+    context->functions = NULL;
+    context->exception = NULL;
+    context->exception_handlers = NULL;
+    context->exception_handler_count = 0;
+    context->source_file = NULL;
+    context->lines = NULL;
+    context->line_count = 0;
+    context->function_names = NULL;
+    context->function_name_count = 0;
+    context->variable_names = NULL;
+    context->variable_name_count = 0;
     context->value_handler = NULL;
     context->stack_manager = NULL;
     context->error_handler = NULL;
+    context->opcode_handler = NULL;
     context->user_data = NULL;
 
+    // Undefined variables read as null, which array writes turn into arrays.
+    for (int i = 0; context->variables && i < VAR_COUNT; i++) {
+        context->variables[i].type = TYPE_UNDEF;
+    }
+
     return context;
 }
 
@@ -28,6 +50,14 @@ void vm_context_free(VMContext* context) {
         free(context->variables);
     }
 
+    if (context->statics) {
+        free(context->statics);
+    }
+
+    if (context->frames) {
+        free(context->frames);
+    }
+
     free(context);
 }
 
@@ -84,7 +114,17 @@ void vm_context_reset(VMContext* context) {
     context->constants = NULL;
     context->constants_len = 0;
 
-    if (context->variables) {
-        memset(context->variables, 0, VAR_COUNT * sizeof(Value));
+    for (int i = 0; context->variables && i < VAR_COUNT; i++) {
+        context->variables[i].type = TYPE_UNDEF;
     }
+
+    if (context->statics) {
+        memset(context->statics, 0, VAR_COUNT * sizeof(bool));
+    }
+
+    context->frame_count = 0;
+    context->classes = NULL;
+    context->declaring = NULL;
+    context->functions = NULL;
+    context->exception = NULL;
 }
\ No newline at end of file
@@ -44,6 +44,8 @@ static void init_opcode_names(void) {
     impl.opcode_names[OP_PRINT] = "PRINT";
     impl.opcode_names[OP_HALT] = "HALT";
     impl.opcode_names[OP_POP] = "POP";
+    impl.opcode_names[OP_DUP] = "DUP";
+    impl.opcode_names[OP_TUCK] = "TUCK";
 
     impl.opcode_names[OP_ADD] = "ADD";
     impl.opcode_names[OP_SUB] = "SUB";
@@ -54,9 +56,22 @@ static void init_opcode_names(void) {
 
     impl.opcode_names[OP_STORE_VAR] = "STORE_VAR";
     impl.opcode_names[OP_LOAD_VAR] = "LOAD_VAR";
+    impl.opcode_names[OP_STORE_LOCAL] = "STORE_LOCAL";
+    impl.opcode_names[OP_LOAD_LOCAL] = "LOAD_LOCAL";
+    impl.opcode_names[OP_STATIC_INIT] = "STATIC_INIT";
+    impl.opcode_names[OP_LOAD_NULL] = "LOAD_NULL";
+    impl.opcode_names[OP_REF_VAR] = "REF_VAR";
+    impl.opcode_names[OP_REF_LOCAL] = "REF_LOCAL";
+    impl.opcode_names[OP_UNSET_VAR] = "UNSET_VAR";
+    impl.opcode_names[OP_UNSET_LOCAL] = "UNSET_LOCAL";
+    impl.opcode_names[OP_BIND_VAR] = "BIND_VAR";
+    impl.opcode_names[OP_BIND_LOCAL] = "BIND_LOCAL";
+    impl.opcode_names[OP_LOAD_VAR_QUIET] = "LOAD_VAR_QUIET";
+    impl.opcode_names[OP_LOAD_LOCAL_QUIET] = "LOAD_LOCAL_QUIET";
 
     impl.opcode_names[OP_JUMP] = "JUMP";
     impl.opcode_names[OP_JUMP_IF_FALSE] = "JUMP_IF_FALSE";
+    impl.opcode_names[OP_COALESCE] = "COALESCE";
 
     impl.opcode_names[OP_GT] = "GT";
     impl.opcode_names[OP_LT] = "LT";
@@ -72,11 +87,13 @@ static void init_opcode_names(void) {
     impl.opcode_names[OP_POST_DEC] = "POST_DEC";
 
     impl.opcode_names[OP_MOD] = "MOD";
+    impl.opcode_names[OP_POW] = "POW";
 
     impl.opcode_names[OP_GTE] = "GTE";
     impl.opcode_names[OP_LTE] = "LTE";
     impl.opcode_names[OP_IDENTITY_EQ] = "IDENTITY_EQ";
     impl.opcode_names[OP_IDENTITY_NE] = "IDENTITY_NE";
+    impl.opcode_names[OP_SPACESHIP] = "SPACESHIP";
 
     impl.opcode_names[OP_BIT_AND] = "BIT_AND";
     impl.opcode_names[OP_BIT_OR] = "BIT_OR";
@@ -91,9 +108,51 @@ static void init_opcode_names(void) {
     impl.opcode_names[OP_ASSIGN_DIV] = "ASSIGN_DIV";
     impl.opcode_names[OP_ASSIGN_MOD] = "ASSIGN_MOD";
     impl.opcode_names[OP_ASSIGN_CONCAT] = "ASSIGN_CONCAT";
+    impl.opcode_names[OP_ASSIGN_POW] = "ASSIGN_POW";
+    impl.opcode_names[OP_ASSIGN_BIT_AND] = "ASSIGN_BIT_AND";
+    impl.opcode_names[OP_ASSIGN_BIT_OR] = "ASSIGN_BIT_OR";
+    impl.opcode_names[OP_ASSIGN_BIT_XOR] = "ASSIGN_BIT_XOR";
+    impl.opcode_names[OP_ASSIGN_LSHIFT] = "ASSIGN_LSHIFT";
+    impl.opcode_names[OP_ASSIGN_RSHIFT] = "ASSIGN_RSHIFT";
 
     impl.opcode_names[OP_BREAK] = "BREAK";
     impl.opcode_names[OP_CONTINUE] = "CONTINUE";
+
+    impl.opcode_names[OP_CLOSURE] = "CLOSURE";
+    impl.opcode_names[OP_CALL_INDIRECT] = "CALL_INDIRECT";
+    impl.opcode_names[OP_FUNC_BIND] = "FUNC_BIND";
+    impl.opcode_names[OP_CALL_NAMED] = "CALL_NAMED";
+
+    impl.opcode_names[OP_ARRAY_NEW] = "ARRAY_NEW";
+    impl.opcode_names[OP_ARRAY_PUSH] = "ARRAY_PUSH";
+    impl.opcode_names[OP_ARRAY_INSERT] = "ARRAY_INSERT";
+    impl.opcode_names[OP_ARRAY_GET] = "ARRAY_GET";
+    impl.opcode_names[OP_ARRAY_SET] = "ARRAY_SET";
+    impl.opcode_names[OP_ARRAY_FETCH] = "ARRAY_FETCH";
+    impl.opcode_names[OP_ARRAY_GET_QUIET] = "ARRAY_GET_QUIET";
+    impl.opcode_names[OP_ARRAY_FETCH_QUIET] = "ARRAY_FETCH_QUIET";
+    impl.opcode_names[OP_ARRAY_UNSET] = "ARRAY_UNSET";
+
+    impl.opcode_names[OP_FOREACH_INIT] = "FOREACH_INIT";
+    impl.opcode_names[OP_FOREACH_NEXT] = "FOREACH_NEXT";
+    impl.opcode_names[OP_FOREACH_END] = "FOREACH_END";
+
+    impl.opcode_names[OP_CLASS_DECL] = "CLASS_DECL";
+    impl.opcode_names[OP_CLASS_PROP] = "CLASS_PROP";
+    impl.opcode_names[OP_CLASS_METHOD] = "CLASS_METHOD";
+    impl.opcode_names[OP_NEW] = "NEW";
+    impl.opcode_names[OP_PROP_GET] = "PROP_GET";
+    impl.opcode_names[OP_PROP_SET] = "PROP_SET";
+    impl.opcode_names[OP_PROP_GET_QUIET] = "PROP_GET_QUIET";
+    impl.opcode_names[OP_PROP_UNSET] = "PROP_UNSET";
+    impl.opcode_names[OP_INSTANCEOF] = "INSTANCEOF";
+    impl.opcode_names[OP_METHOD_CALL] = "METHOD_CALL";
+    impl.opcode_names[OP_CLASS_EXTENDS] = "CLASS_EXTENDS";
+    impl.opcode_names[OP_CLASS_IMPLEMENTS] = "CLASS_IMPLEMENTS";
+    impl.opcode_names[OP_STATIC_CALL] = "STATIC_CALL";
+
+    impl.opcode_names[OP_THROW] = "THROW";
+    impl.opcode_names[OP_CATCH] = "CATCH";
 }
 
 OpcodeHandler* opcode_handler_new(void) {
@@ -17,6 +17,7 @@
  */
 #include "../../includes/vm.h"
 #include "../../includes/context.h"
+#include "../../includes/interfaces/exception.h"
 #include <stdlib.h>
 #include <stdio.h>
 #include <string.h>
@@ -38,6 +39,7 @@ VM* vm_new(void) {
     }
 
     vm_context_set_handlers(vm->context, vm->value_handler, vm->stack_manager, vm->error_handler);
+    vm->context->opcode_handler = vm->opcode_handler;
 
     vm->running = false;
     vm->last_status = STATUS_SUCCESS;
@@ -47,6 +49,8 @@ VM* vm_new(void) {
     vm_register_opcode_handler(vm, OP_PRINT, handle_print);
     vm_register_opcode_handler(vm, OP_HALT, handle_halt);
     vm_register_opcode_handler(vm, OP_POP, handle_pop);
+    vm_register_opcode_handler(vm, OP_DUP, handle_dup);
+    vm_register_opcode_handler(vm, OP_TUCK, handle_tuck);
 
     vm_register_opcode_handler(vm, OP_ADD, handle_add);
     vm_register_opcode_handler(vm, OP_SUB, handle_sub);
@@ -57,9 +61,22 @@ VM* vm_new(void) {
 
     vm_register_opcode_handler(vm, OP_STORE_VAR, handle_store_var);
     vm_register_opcode_handler(vm, OP_LOAD_VAR, handle_load_var);
+    vm_register_opcode_handler(vm, OP_STORE_LOCAL, handle_store_local);
+    vm_register_opcode_handler(vm, OP_LOAD_LOCAL, handle_load_local);
+    vm_register_opcode_handler(vm, OP_STATIC_INIT, handle_static_init);
+    vm_register_opcode_handler(vm, OP_LOAD_NULL, handle_load_null);
+    vm_register_opcode_handler(vm, OP_REF_VAR, handle_ref_var);
+    vm_register_opcode_handler(vm, OP_REF_LOCAL, handle_ref_local);
+    vm_register_opcode_handler(vm, OP_UNSET_VAR, handle_unset_var);
+    vm_register_opcode_handler(vm, OP_UNSET_LOCAL, handle_unset_local);
+    vm_register_opcode_handler(vm, OP_BIND_VAR, handle_bind_var);
+    vm_register_opcode_handler(vm, OP_BIND_LOCAL, handle_bind_local);
+    vm_register_opcode_handler(vm, OP_LOAD_VAR_QUIET, handle_load_var_quiet);
+    vm_register_opcode_handler(vm, OP_LOAD_LOCAL_QUIET, handle_load_local_quiet);
 
     vm_register_opcode_handler(vm, OP_JUMP, handle_jump);
     vm_register_opcode_handler(vm, OP_JUMP_IF_FALSE, handle_jump_if_false);
+    vm_register_opcode_handler(vm, OP_COALESCE, handle_coalesce);
 
     vm_register_opcode_handler(vm, OP_GT, handle_gt);
     vm_register_opcode_handler(vm, OP_LT, handle_lt);
@@ -75,11 +92,13 @@ VM* vm_new(void) {
     vm_register_opcode_handler(vm, OP_POST_DEC, handle_post_dec);
 
     vm_register_opcode_handler(vm, OP_MOD, handle_mod);
+    vm_register_opcode_handler(vm, OP_POW, handle_pow);
 
     vm_register_opcode_handler(vm, OP_GTE, handle_gte);
     vm_register_opcode_handler(vm, OP_LTE, handle_lte);
     vm_register_opcode_handler(vm, OP_IDENTITY_EQ, handle_identity_eq);
     vm_register_opcode_handler(vm, OP_IDENTITY_NE, handle_identity_ne);
+    vm_register_opcode_handler(vm, OP_SPACESHIP, handle_spaceship);
 
     vm_register_opcode_handler(vm, OP_BIT_AND, handle_bit_and);
     vm_register_opcode_handler(vm, OP_BIT_OR, handle_bit_or);
@@ -95,6 +114,12 @@ VM* vm_new(void) {
     vm_register_opcode_handler(vm, OP_ASSIGN_DIV, handle_assign_div);
     vm_register_opcode_handler(vm, OP_ASSIGN_MOD, handle_assign_mod);
     vm_register_opcode_handler(vm, OP_ASSIGN_CONCAT, handle_assign_concat);
+    vm_register_opcode_handler(vm, OP_ASSIGN_POW, handle_assign_pow);
+    vm_register_opcode_handler(vm, OP_ASSIGN_BIT_AND, handle_assign_bit_and);
+    vm_register_opcode_handler(vm, OP_ASSIGN_BIT_OR, handle_assign_bit_or);
+    vm_register_opcode_handler(vm, OP_ASSIGN_BIT_XOR, handle_assign_bit_xor);
+    vm_register_opcode_handler(vm, OP_ASSIGN_LSHIFT, handle_assign_lshift);
+    vm_register_opcode_handler(vm, OP_ASSIGN_RSHIFT, handle_assign_rshift);
 
     vm_register_opcode_handler(vm, OP_BREAK, handle_break);
     vm_register_opcode_handler(vm, OP_CONTINUE, handle_continue);
@@ -104,6 +129,42 @@ VM* vm_new(void) {
     vm_register_opcode_handler(vm, OP_RETURN, handle_return);
     vm_register_opcode_handler(vm, OP_ENTER_FUNC, handle_enter_func);
     vm_register_opcode_handler(vm, OP_EXIT_FUNC, handle_exit_func);
+    vm_register_opcode_handler(vm, OP_CALL_NATIVE, handle_call_native);
+    vm_register_opcode_handler(vm, OP_CLOSURE, handle_closure);
+    vm_register_opcode_handler(vm, OP_CALL_INDIRECT, handle_call_indirect);
+    vm_register_opcode_handler(vm, OP_FUNC_BIND, handle_func_bind);
+    vm_register_opcode_handler(vm, OP_CALL_NAMED, handle_call_named);
+
+    vm_register_opcode_handler(vm, OP_ARRAY_NEW, handle_array_new);
+    vm_register_opcode_handler(vm, OP_ARRAY_PUSH, handle_array_push);
+    vm_register_opcode_handler(vm, OP_ARRAY_INSERT, handle_array_insert);
+    vm_register_opcode_handler(vm, OP_ARRAY_GET, handle_array_get);
+    vm_register_opcode_handler(vm, OP_ARRAY_SET, handle_array_set);
+    vm_register_opcode_handler(vm, OP_ARRAY_FETCH, handle_array_fetch);
+    vm_register_opcode_handler(vm, OP_ARRAY_GET_QUIET, handle_array_get_quiet);
+    vm_register_opcode_handler(vm, OP_ARRAY_FETCH_QUIET, handle_array_fetch_quiet);
+    vm_register_opcode_handler(vm, OP_ARRAY_UNSET, handle_array_unset);
+
+    vm_register_opcode_handler(vm, OP_FOREACH_INIT, handle_foreach_init);
+    vm_register_opcode_handler(vm, OP_FOREACH_NEXT, handle_foreach_next);
+    vm_register_opcode_handler(vm, OP_FOREACH_END, handle_foreach_end);
+
+    vm_register_opcode_handler(vm, OP_CLASS_DECL, handle_class_decl);
+    vm_register_opcode_handler(vm, OP_CLASS_PROP, handle_class_prop);
+    vm_register_opcode_handler(vm, OP_CLASS_METHOD, handle_class_method);
+    vm_register_opcode_handler(vm, OP_NEW, handle_new);
+    vm_register_opcode_handler(vm, OP_PROP_GET, handle_prop_get);
+    vm_register_opcode_handler(vm, OP_PROP_SET, handle_prop_set);
+    vm_register_opcode_handler(vm, OP_PROP_GET_QUIET, handle_prop_get_quiet);
+    vm_register_opcode_handler(vm, OP_PROP_UNSET, handle_prop_unset);
+    vm_register_opcode_handler(vm, OP_INSTANCEOF, handle_instanceof);
+    vm_register_opcode_handler(vm, OP_METHOD_CALL, handle_method_call);
+    vm_register_opcode_handler(vm, OP_CLASS_EXTENDS, handle_class_extends);
+    vm_register_opcode_handler(vm, OP_CLASS_IMPLEMENTS, handle_class_implements);
+    vm_register_opcode_handler(vm, OP_STATIC_CALL, handle_static_call);
+
+    vm_register_opcode_handler(vm, OP_THROW, handle_throw);
+    vm_register_opcode_handler(vm, OP_CATCH, handle_catch);
 
     return vm;
 }
@@ -136,6 +197,8 @@ status_t vm_execute(VM* vm, byte_t* bytecode, size_t bytecode_len, Value* consta
     bool* debug_ptr = &vm->debug_mode;
     vm_context_set_user_data(vm->context, debug_ptr);
 
+    declare_builtin_classes(vm->context);
+
     while (vm->running && vm->context->ip < vm->context->bytecode_len) {
         if (vm->debug_mode) {
             printf("DEBUG VM: About to execute instruction at ip=%zu\n", vm->context->ip);
@@ -145,6 +208,12 @@ status_t vm_execute(VM* vm, byte_t* bytecode, size_t bytecode_len, Value* consta
         }
 
         status_t status = vm_execute_instruction(vm);
+        if (status == STATUS_THROWN) {
+            status = unwind(vm->context, -1);
+            if (status == STATUS_THROWN) {
+                report_uncaught(vm->context);
+            }
+        }
 
         if (vm->debug_mode) {
             printf("DEBUG VM: After execution, ip=%zu, status=%d\n", vm->context->ip, status);
@@ -219,4 +288,29 @@ void vm_set_user_data(VM* vm, void* user_data) {
 
 void* vm_get_user_data(VM* vm) {
     return (vm && vm->context) ? vm->context->user_data : NULL;
+}
+
+// vm_set_exception_table sets the handlers that try blocks compile to.
+void vm_set_exception_table(VM* vm, const ExceptionHandler* handlers, size_t count) {
+    if (vm && vm->context) {
+        vm->context->exception_handlers = handlers;
+        vm->context->exception_handler_count = count;
+    }
+}
+
+// vm_set_debug_info sets what exceptions report about where they were
+// thrown, the source file, the line table and the names of the functions,
+// and the variable names that warnings show.
+void vm_set_debug_info(VM* vm, const char* source_file, const LineEntry* lines, size_t line_count,
+                       const FunctionName* function_names, size_t function_name_count,
+                       const VariableName* variable_names, size_t variable_name_count) {
+    if (vm && vm->context) {
+        vm->context->source_file = source_file;
+        vm->context->lines = lines;
+        vm->context->line_count = line_count;
+        vm->context->function_names = function_names;
+        vm->context->function_name_count = function_name_count;
+        vm->context->variable_names = variable_names;
+        vm->context->variable_name_count = variable_name_count;
+    }
 }
\ No newline at end of file
@@ -1,5 +1,8 @@
 /* Licensed under GNU GPL v3. See LICENSE file for details. */
 #include "../../includes/interfaces/opcode_handler.h"
+#include "../../includes/interfaces/array.h"
+#include "../../includes/interfaces/exception.h"
+#include <math.h>
 
 static status_t check_stack_size(VMContext* context, int required_size) {
     if (!context || !context->stack_manager) {
@@ -15,6 +18,52 @@ static status_t check_stack_size(VMContext* context, int required_size) {
     return STATUS_SUCCESS;
 }
 
+// check_operands rejects objects and arrays, which only support + between
+// two arrays.
+static status_t check_operands(VMContext* context, Value a, Value b, const char* symbol) {
+    if (a.type == TYPE_ARRAY || b.type == TYPE_ARRAY || a.type == TYPE_OBJECT || b.type == TYPE_OBJECT) {
+        return throw_error(context, "TypeError", "Unsupported operand types: %s %s %s",
+                           context->value_handler->type_name(a), symbol, context->value_handler->type_name(b));
+    }
+
+    return STATUS_SUCCESS;
+}
+
+// array_union adds the elements of b whose keys are missing in a to a copy
+// of a.
+static Value array_union(VMContext* context, Array* a, Array* b) {
+    Array* result = array_copy(a);
+
+    for (size_t i = 0; i < b->count; i++) {
+        Value existing;
+        ArrayKey key = array_key_at(b, i);
+        if (!array_get(result, key, &existing)) {
+            array_set(result, key, array_at(b, i));
+        }
+    }
+
+    return context->value_handler->create_array(result);
+}
+
+static bool is_float_operation(VMContext* context, Value a, Value b) {
+    return context->value_handler->is_float(a) || context->value_handler->is_float(b);
+}
+
+// step_value adds delta to the numeric value of val, keeping floats as floats.
+static Value step_value(VMContext* context, Value val, int_t delta) {
+    if (context->value_handler->is_float(val)) {
+        return context->value_handler->create_float(context->value_handler->to_float(val) + delta);
+    }
+
+    int_t int_val = context->value_handler->to_int(val);
+    int_t result;
+    if (__builtin_add_overflow(int_val, delta, &result)) {
+        return context->value_handler->create_float((double)int_val + delta);
+    }
+
+    return context->value_handler->create_int(result);
+}
+
 status_t handle_add(VMContext* context) {
     status_t status = check_stack_size(context, 2);
     if (status != STATUS_SUCCESS) {
@@ -24,10 +73,32 @@ status_t handle_add(VMContext* context) {
     Value b = context->stack_manager->pop();
     Value a = context->stack_manager->pop();
 
+    if (a.type == TYPE_ARRAY && b.type == TYPE_ARRAY) {
+        context->stack_manager->push(array_union(context, a.value.arr_val, b.value.arr_val));
+        return STATUS_SUCCESS;
+    }
+
+    status = check_operands(context, a, b, "+");
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    if (is_float_operation(context, a, b)) {
+        double result = context->value_handler->to_float(a) + context->value_handler->to_float(b);
+        context->stack_manager->push(context->value_handler->create_float(result));
+        return STATUS_SUCCESS;
+    }
+
     int_t a_int = context->value_handler->to_int(a);
     int_t b_int = context->value_handler->to_int(b);
 
-    int_t result = a_int + b_int;
+    int_t result;
+
+    // Like PHP, an int result that does not fit in 64 bits becomes a float.
+    if (__builtin_add_overflow(a_int, b_int, &result)) {
+        context->stack_manager->push(context->value_handler->create_float((double)a_int + (double)b_int));
+        return STATUS_SUCCESS;
+    }
 
     context->stack_manager->push(context->value_handler->create_int(result));
 
@@ -43,10 +114,27 @@ status_t handle_sub(VMContext* context) {
     Value b = context->stack_manager->pop();
     Value a = context->stack_manager->pop();
 
+    status = check_operands(context, a, b, "-");
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    if (is_float_operation(context, a, b)) {
+        double result = context->value_handler->to_float(a) - context->value_handler->to_float(b);
+        context->stack_manager->push(context->value_handler->create_float(result));
+        return STATUS_SUCCESS;
+    }
+
     int_t a_int = context->value_handler->to_int(a);
     int_t b_int = context->value_handler->to_int(b);
 
-    int_t result = a_int - b_int;
+    int_t result;
+
+    // Like PHP, an int result that does not fit in 64 bits becomes a float.
+    if (__builtin_sub_overflow(a_int, b_int, &result)) {
+        context->stack_manager->push(context->value_handler->create_float((double)a_int - (double)b_int));
+        return STATUS_SUCCESS;
+    }
 
     context->stack_manager->push(context->value_handler->create_int(result));
 
@@ -62,10 +150,27 @@ status_t handle_mul(VMContext* context) {
     Value b = context->stack_manager->pop();
     Value a = context->stack_manager->pop();
 
+    status = check_operands(context, a, b, "*");
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    if (is_float_operation(context, a, b)) {
+        double result = context->value_handler->to_float(a) * context->value_handler->to_float(b);
+        context->stack_manager->push(context->value_handler->create_float(result));
+        return STATUS_SUCCESS;
+    }
+
     int_t a_int = context->value_handler->to_int(a);
     int_t b_int = context->value_handler->to_int(b);
 
-    int_t result = a_int * b_int;
+    int_t result;
+
+    // Like PHP, an int result that does not fit in 64 bits becomes a float.
+    if (__builtin_mul_overflow(a_int, b_int, &result)) {
+        context->stack_manager->push(context->value_handler->create_float((double)a_int * (double)b_int));
+        return STATUS_SUCCESS;
+    }
 
     context->stack_manager->push(context->value_handler->create_int(result));
 
@@ -81,23 +186,30 @@ status_t handle_div(VMContext* context) {
     Value b = context->stack_manager->pop();
     Value a = context->stack_manager->pop();
 
-    int_t a_int = context->value_handler->to_int(a);
-    int_t b_int = context->value_handler->to_int(b);
-
-    if (b_int == 0) {
-        context->error_handler->warning("Division by zero at ip=%zu", context->ip - 1);
-        context->stack_manager->push(context->value_handler->create_int(0));
-        return STATUS_DIVISION_BY_ZERO;
+    status = check_operands(context, a, b, "/");
+    if (status != STATUS_SUCCESS) {
+        return status;
     }
 
-    if (a_int % b_int != 0) {
-        context->error_handler->warning("Integer division resulting in truncation (%d / %d) at ip=%zu",
-                                       a_int, b_int, context->ip - 1);
+    double divisor = context->value_handler->to_float(b);
+
+    if (divisor == 0.0) {
+        return throw_error(context, "DivisionByZeroError", "Division by zero");
     }
 
-    int_t result = a_int / b_int;
+    // Like PHP, the result is an int only for an exact division of two ints.
+    if (!is_float_operation(context, a, b)) {
+        int_t a_int = context->value_handler->to_int(a);
+        int_t b_int = context->value_handler->to_int(b);
 
-    context->stack_manager->push(context->value_handler->create_int(result));
+        if (!(a_int == INT64_MIN && b_int == -1) && a_int % b_int == 0) {
+            context->stack_manager->push(context->value_handler->create_int(a_int / b_int));
+            return STATUS_SUCCESS;
+        }
+    }
+
+    double result = context->value_handler->to_float(a) / divisor;
+    context->stack_manager->push(context->value_handler->create_float(result));
 
     return STATUS_SUCCESS;
 }
@@ -113,10 +225,8 @@ status_t handle_inc(VMContext* context) {
     }
 
     Value val = context->stack_manager->pop();
-    int_t int_val = context->value_handler->to_int(val);
-    int_val++;
 
-    context->stack_manager->push(context->value_handler->create_int(int_val));
+    context->stack_manager->push(step_value(context, val, 1));
     return STATUS_SUCCESS;
 }
 
@@ -131,10 +241,8 @@ status_t handle_dec(VMContext* context) {
     }
 
     Value val = context->stack_manager->pop();
-    int_t int_val = context->value_handler->to_int(val);
-    int_val--;
 
-    context->stack_manager->push(context->value_handler->create_int(int_val));
+    context->stack_manager->push(step_value(context, val, -1));
     return STATUS_SUCCESS;
 }
 
@@ -148,11 +256,10 @@ status_t handle_post_inc(VMContext* context) {
         return STATUS_STACK_UNDERFLOW;
     }
 
-    Value val = context->stack_manager->pop();
-    int_t int_val = context->value_handler->to_int(val);
+    Value val = step_value(context, context->stack_manager->pop(), 0);
 
-    context->stack_manager->push(context->value_handler->create_int(int_val));
-    context->stack_manager->push(context->value_handler->create_int(int_val + 1));
+    context->stack_manager->push(val);
+    context->stack_manager->push(step_value(context, val, 1));
     return STATUS_SUCCESS;
 }
 
@@ -166,11 +273,10 @@ status_t handle_post_dec(VMContext* context) {
         return STATUS_STACK_UNDERFLOW;
     }
 
-    Value val = context->stack_manager->pop();
-    int_t int_val = context->value_handler->to_int(val);
+    Value val = step_value(context, context->stack_manager->pop(), 0);
 
-    context->stack_manager->push(context->value_handler->create_int(int_val));
-    context->stack_manager->push(context->value_handler->create_int(int_val - 1));
+    context->stack_manager->push(val);
+    context->stack_manager->push(step_value(context, val, -1));
     return STATUS_SUCCESS;
 }
 
@@ -183,16 +289,62 @@ status_t handle_mod(VMContext* context) {
     Value b = context->stack_manager->pop();
     Value a = context->stack_manager->pop();
 
+    status = check_operands(context, a, b, "%");
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
     int_t a_int = context->value_handler->to_int(a);
     int_t b_int = context->value_handler->to_int(b);
 
     if (b_int == 0) {
-        context->error_handler->warning("Modulo by zero at ip=%zu", context->ip - 1);
-        context->stack_manager->push(context->value_handler->create_int(0));
-        return STATUS_DIVISION_BY_ZERO;
+        return throw_error(context, "DivisionByZeroError", "Modulo by zero");
     }
 
-    int_t result = a_int % b_int;
+    int_t result = b_int == -1 ? 0 : a_int % b_int;
     context->stack_manager->push(context->value_handler->create_int(result));
     return STATUS_SUCCESS;
 }
+
+// handle_pow stays in ints for an int base and a non-negative int exponent
+// as long as the result fits. A negative exponent gives a float, as in PHP.
+status_t handle_pow(VMContext* context) {
+    status_t status = check_stack_size(context, 2);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    Value b = context->stack_manager->pop();
+    Value a = context->stack_manager->pop();
+
+    status = check_operands(context, a, b, "**");
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    if (!is_float_operation(context, a, b) && context->value_handler->to_int(b) >= 0) {
+        int_t base = context->value_handler->to_int(a);
+        int_t exponent = context->value_handler->to_int(b);
+        int_t result = 1;
+        bool overflow = false;
+
+        while (exponent > 0 && !overflow) {
+            if (exponent & 1) {
+                overflow = __builtin_mul_overflow(result, base, &result);
+            }
+            exponent >>= 1;
+            if (exponent > 0 && !overflow) {
+                overflow = __builtin_mul_overflow(base, base, &base);
+            }
+        }
+
+        if (!overflow) {
+            context->stack_manager->push(context->value_handler->create_int(result));
+            return STATUS_SUCCESS;
+        }
+    }
+
+    double result = pow(context->value_handler->to_float(a), context->value_handler->to_float(b));
+    context->stack_manager->push(context->value_handler->create_float(result));
+    return STATUS_SUCCESS;
+}
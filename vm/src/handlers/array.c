@@ -0,0 +1,427 @@
+/* Licensed under GNU GPL v3. See LICENSE file for details. */
+#include "../../includes/interfaces/opcode_handler.h"
+#include "../../includes/interfaces/array.h"
+#include "../../includes/interfaces/exception.h"
+
+static status_t check_stack_size(VMContext* context, int required_size) {
+    if (!context || !context->stack_manager) {
+        return STATUS_ERROR;
+    }
+
+    if (context->stack_manager->size() < required_size) {
+        context->error_handler->runtime_error("Stack underflow at ip=%zu, need %d elements, have %d",
+                                             context->ip - 1, required_size, context->stack_manager->size());
+        return STATUS_STACK_UNDERFLOW;
+    }
+
+    return STATUS_SUCCESS;
+}
+
+static status_t read_byte(VMContext* context, byte_t* out) {
+    if (context->ip >= context->bytecode_len) {
+        context->error_handler->runtime_error("Unexpected end of bytecode at ip=%zu", context->ip);
+        return STATUS_ERROR;
+    }
+
+    *out = context->bytecode[context->ip++];
+    return STATUS_SUCCESS;
+}
+
+static status_t to_key(VMContext* context, Value value, ArrayKey* key) {
+    if (!array_to_key(value, key)) {
+        context->error_handler->runtime_error("Illegal offset type %s", context->value_handler->type_name(value));
+        return STATUS_RUNTIME_ERROR;
+    }
+    return STATUS_SUCCESS;
+}
+
+status_t handle_array_new(VMContext* context) {
+    Array* array = array_new();
+    if (!array) {
+        context->error_handler->runtime_error("Memory allocation failed for array at ip=%zu", context->ip - 1);
+        return STATUS_OUT_OF_MEMORY;
+    }
+
+    context->stack_manager->push(context->value_handler->create_array(array));
+    return STATUS_SUCCESS;
+}
+
+// literal_array returns the array being built by an array literal, which
+// stays on the stack below its elements.
+static Array* literal_array(VMContext* context) {
+    Value top = context->stack_manager->peek(0);
+    if (top.type != TYPE_ARRAY) {
+        context->error_handler->runtime_error("Array literal expected on the stack at ip=%zu", context->ip - 1);
+        return NULL;
+    }
+    return top.value.arr_val;
+}
+
+status_t handle_array_push(VMContext* context) {
+    status_t status = check_stack_size(context, 2);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    Value value = context->stack_manager->pop();
+    Array* array = literal_array(context);
+    if (!array) {
+        return STATUS_ERROR;
+    }
+
+    if (!array_append(array, value)) {
+        context->error_handler->runtime_error("Cannot add element to the array as the next element is already occupied");
+        return STATUS_RUNTIME_ERROR;
+    }
+    return STATUS_SUCCESS;
+}
+
+status_t handle_array_insert(VMContext* context) {
+    status_t status = check_stack_size(context, 3);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    Value value = context->stack_manager->pop();
+    Value key_value = context->stack_manager->pop();
+    Array* array = literal_array(context);
+    if (!array) {
+        return STATUS_ERROR;
+    }
+
+    ArrayKey key;
+    status = to_key(context, key_value, &key);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    array_set(array, key, value);
+    return STATUS_SUCCESS;
+}
+
+// read_dim returns container[key_value]. Missing keys and containers that
+// are not arrays or strings give null with a warning, as in PHP, or without
+// one when quiet is set.
+static status_t read_dim(VMContext* context, Value container, Value key_value, bool quiet, Value* out) {
+    ValueHandler* values = context->value_handler;
+
+    switch (container.type) {
+        case TYPE_ARRAY: {
+            ArrayKey key;
+            status_t status = to_key(context, key_value, &key);
+            if (status != STATUS_SUCCESS) {
+                return status;
+            }
+
+            if (!array_get(container.value.arr_val, key, out)) {
+                if (!quiet) {
+                    char buffer[1024];
+                    array_format_key(key, buffer, sizeof(buffer));
+                    context->error_handler->warning("Undefined array key %s", buffer);
+                }
+                *out = values->create_null();
+            }
+            return STATUS_SUCCESS;
+        }
+
+        case TYPE_STRING: {
+            const char* str = container.value.str_val ? container.value.str_val : "";
+            int_t len = (int_t)strlen(str);
+            int_t offset = values->to_int(key_value);
+            int_t position = offset < 0 ? offset + len : offset;
+
+            if (position < 0 || position >= len) {
+                if (quiet) {
+                    *out = values->create_null();
+                    return STATUS_SUCCESS;
+                }
+                context->error_handler->warning("Uninitialized string offset %" INT_FMT, offset);
+                *out = values->create_string("");
+                return STATUS_SUCCESS;
+            }
+
+            char character[2] = {str[position], '\0'};
+            *out = values->create_string(character);
+            return STATUS_SUCCESS;
+        }
+
+        case TYPE_OBJECT:
+            return throw_error(context, "Error", "Cannot use object of type %s as array",
+                               values->type_name(container));
+
+        default:
+            if (!quiet) {
+                context->error_handler->warning("Trying to access array offset on value of type %s",
+                                                values->type_name(container));
+            }
+            *out = values->create_null();
+            return STATUS_SUCCESS;
+    }
+}
+
+static status_t array_get_op(VMContext* context, bool quiet) {
+    status_t status = check_stack_size(context, 2);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    Value key = context->stack_manager->pop();
+    Value container = context->stack_manager->pop();
+
+    Value result;
+    status = read_dim(context, container, key, quiet, &result);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    context->stack_manager->push(result);
+    return STATUS_SUCCESS;
+}
+
+status_t handle_array_get(VMContext* context) {
+    return array_get_op(context, false);
+}
+
+// handle_array_get_quiet is ARRAY_GET for isset, empty and ??, which read
+// missing elements as null without a warning.
+status_t handle_array_get_quiet(VMContext* context) {
+    return array_get_op(context, true);
+}
+
+static status_t array_fetch_op(VMContext* context, bool quiet) {
+    byte_t dims;
+    status_t status = read_byte(context, &dims);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    status = check_stack_size(context, dims + 1);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    Value value = context->stack_manager->peek(dims);
+    for (int i = dims - 1; i >= 0; i--) {
+        status = read_dim(context, value, context->stack_manager->peek(i), quiet, &value);
+        if (status != STATUS_SUCCESS) {
+            return status;
+        }
+    }
+
+    context->stack_manager->push(value);
+    return STATUS_SUCCESS;
+}
+
+// handle_array_fetch reads the element addressed by the container and the
+// keys on the stack without popping them, so that a compound assignment can
+// compute the new value and hand everything to ARRAY_SET.
+status_t handle_array_fetch(VMContext* context) {
+    return array_fetch_op(context, false);
+}
+
+// handle_array_fetch_quiet is ARRAY_FETCH for ??=, which reads missing
+// elements as null without a warning.
+status_t handle_array_fetch_quiet(VMContext* context) {
+    return array_fetch_op(context, true);
+}
+
+// write_dim stores value at keys inside container and returns the container
+// to store back. A NULL key appends. Null containers become arrays, and
+// arrays that are shared are copied before the write.
+static status_t write_dim(VMContext* context, Value container, Value** keys, int dims, Value value, Value* out) {
+    switch (container.type) {
+        case TYPE_NULL:
+            container = context->value_handler->create_array(array_new());
+            break;
+        case TYPE_BOOLEAN:
+            if (!container.value.bool_val) {
+                container = context->value_handler->create_array(array_new());
+                break;
+            }
+            return throw_error(context, "Error", "Cannot use a scalar value as an array");
+        case TYPE_ARRAY:
+            break;
+        case TYPE_STRING:
+            context->error_handler->runtime_error("Writing to string offsets is not supported");
+            return STATUS_RUNTIME_ERROR;
+        case TYPE_OBJECT:
+            return throw_error(context, "Error", "Cannot use object of type %s as array",
+                               context->value_handler->type_name(container));
+        default:
+            return throw_error(context, "Error", "Cannot use a scalar value as an array");
+    }
+
+    Array* array = array_separate(container.value.arr_val);
+    status_t status;
+
+    ArrayKey key;
+    if (keys[0]) {
+        status = to_key(context, *keys[0], &key);
+        if (status != STATUS_SUCCESS) {
+            return status;
+        }
+    }
+
+    if (dims > 1) {
+        Value inner = context->value_handler->create_null();
+        if (keys[0] && !array_get(array, key, &inner)) {
+            inner = context->value_handler->create_null();
+        }
+
+        status = write_dim(context, inner, keys + 1, dims - 1, value, &value);
+        if (status != STATUS_SUCCESS) {
+            return status;
+        }
+    }
+
+    if (!keys[0]) {
+        if (!array_append(array, value)) {
+            context->error_handler->runtime_error("Cannot add element to the array as the next element is already occupied");
+            return STATUS_RUNTIME_ERROR;
+        }
+    } else {
+        array_set(array, key, value);
+    }
+
+    *out = context->value_handler->create_array(array);
+    return STATUS_SUCCESS;
+}
+
+// handle_array_set writes into nested arrays: it pops the value, the keys of
+// every dimension that is not an append (`[]`) and the container, and pushes
+// the updated container for the following store.
+status_t handle_array_set(VMContext* context) {
+    byte_t dims;
+    byte_t append_mask;
+    status_t status = read_byte(context, &dims);
+    if (status == STATUS_SUCCESS) {
+        status = read_byte(context, &append_mask);
+    }
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    if (dims == 0 || dims > 8) {
+        context->error_handler->runtime_error("Invalid ARRAY_SET dimensions %d at ip=%zu", dims, context->ip - 3);
+        return STATUS_ERROR;
+    }
+
+    int key_count = 0;
+    for (int i = 0; i < dims; i++) {
+        if (!(append_mask & (1 << i))) {
+            key_count++;
+        }
+    }
+
+    status = check_stack_size(context, key_count + 2);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    Value value = context->stack_manager->pop();
+    Value keys[8];
+    for (int i = key_count - 1; i >= 0; i--) {
+        keys[i] = context->stack_manager->pop();
+    }
+    Value container = context->stack_manager->pop();
+
+    Value* dim_keys[8];
+    int next_key = 0;
+    for (int i = 0; i < dims; i++) {
+        dim_keys[i] = (append_mask & (1 << i)) ? NULL : &keys[next_key++];
+    }
+
+    // Holding value while writing makes $a[0] = $a copy $a first.
+    Value result;
+    value_retain(value);
+    status = write_dim(context, container, dim_keys, dims, value, &result);
+    value_release(value);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    context->stack_manager->push(result);
+    return STATUS_SUCCESS;
+}
+
+// unset_dim removes the element at keys inside container and returns the
+// container to store back. Missing elements and null containers are left
+// alone; arrays that are shared are copied before the removal.
+static status_t unset_dim(VMContext* context, Value container, Value* keys, int dims, Value* out) {
+    switch (container.type) {
+        case TYPE_NULL:
+            *out = container;
+            return STATUS_SUCCESS;
+        case TYPE_ARRAY:
+            break;
+        case TYPE_STRING:
+            return throw_error(context, "Error", "Cannot unset string offsets");
+        case TYPE_OBJECT:
+            return throw_error(context, "Error", "Cannot use object of type %s as array",
+                               context->value_handler->type_name(container));
+        default:
+            return throw_error(context, "Error", "Cannot unset offset in a non-array variable");
+    }
+
+    ArrayKey key;
+    status_t status = to_key(context, keys[0], &key);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    Value inner;
+    if (!array_get(container.value.arr_val, key, &inner)) {
+        *out = container;
+        return STATUS_SUCCESS;
+    }
+
+    Array* array = array_separate(container.value.arr_val);
+    if (dims == 1) {
+        array_remove(array, key);
+    } else {
+        status = unset_dim(context, inner, keys + 1, dims - 1, &inner);
+        if (status != STATUS_SUCCESS) {
+            return status;
+        }
+        array_set(array, key, inner);
+    }
+
+    *out = context->value_handler->create_array(array);
+    return STATUS_SUCCESS;
+}
+
+// handle_array_unset removes an element from nested arrays: it pops the keys
+// of every dimension and the container, and pushes the updated container for
+// the following store.
+status_t handle_array_unset(VMContext* context) {
+    byte_t dims;
+    status_t status = read_byte(context, &dims);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    if (dims == 0 || dims > 8) {
+        context->error_handler->runtime_error("Invalid ARRAY_UNSET dimensions %d at ip=%zu", dims, context->ip - 2);
+        return STATUS_ERROR;
+    }
+
+    status = check_stack_size(context, dims + 1);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    Value keys[8];
+    for (int i = dims - 1; i >= 0; i--) {
+        keys[i] = context->stack_manager->pop();
+    }
+    Value container = context->stack_manager->pop();
+
+    Value result;
+    status = unset_dim(context, container, keys, dims, &result);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    context->stack_manager->push(result);
+    return STATUS_SUCCESS;
+}
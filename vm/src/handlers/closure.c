@@ -0,0 +1,194 @@
+/* Licensed under GNU GPL v3. See LICENSE file for details. */
+#include "../../includes/interfaces/opcode_handler.h"
+#include "../../includes/interfaces/object.h"
+#include "../../includes/interfaces/exception.h"
+#include "../../includes/interfaces/array.h"
+#include "../../includes/interfaces/native.h"
+#include <stdlib.h>
+
+/*
+ * A closure is a function compiled in place, behind a jump. CLOSURE wraps
+ * its address and the captured values in a Closure object; CALL_INDIRECT
+ * calls the closure below the arguments like FUNC_CALL, then copies the
+ * captured values into the locals that follow the parameters. Natives call
+ * closures and functions back through call_value.
+ */
+
+// handle_closure creates a Closure object for the function at the address
+// operand. It takes the captured values off the stack and remembers the
+// object and class of the running method, which the closure runs with.
+status_t handle_closure(VMContext* context) {
+    if (context->ip + 3 > context->bytecode_len) {
+        context->error_handler->runtime_error("Unexpected end of bytecode at ip=%zu", context->ip);
+        return STATUS_ERROR;
+    }
+    byte_t low_byte = context->bytecode[context->ip++];
+    byte_t high_byte = context->bytecode[context->ip++];
+    uint16_t addr = (uint16_t)((high_byte << 8) | low_byte);
+    byte_t count = context->bytecode[context->ip++];
+
+    if (addr >= context->bytecode_len || context->bytecode[addr] != OP_FUNC_DECL) {
+        context->error_handler->runtime_error("Invalid closure address %u at ip=%zu", addr, context->ip - 4);
+        return STATUS_ERROR;
+    }
+    if (context->stack_manager->size() < count) {
+        context->error_handler->runtime_error("CLOSURE expects %d captured values, stack has %d values",
+                                             count, context->stack_manager->size());
+        return STATUS_STACK_UNDERFLOW;
+    }
+
+    Class* class = class_find(context->classes, CLOSURE_CLASS);
+    Object* object = class ? object_new(class) : NULL;
+    Closure* closure = (Closure*)malloc(sizeof(Closure));
+    Value* captured = count > 0 ? (Value*)malloc(count * sizeof(Value)) : NULL;
+    if (!object || !closure || (count > 0 && !captured)) {
+        context->error_handler->runtime_error("Memory allocation failed for closure at ip=%zu", context->ip - 4);
+        return STATUS_OUT_OF_MEMORY;
+    }
+
+    for (int i = count - 1; i >= 0; i--) {
+        captured[i] = context->stack_manager->pop();
+        value_retain(captured[i]);
+    }
+
+    closure->addr = addr;
+    closure->captured = captured;
+    closure->captured_count = count;
+    closure->this_obj = NULL;
+    closure->scope = NULL;
+    closure->static_class = NULL;
+    if (context->frame_count > 0) {
+        CallFrame* frame = &context->frames[context->frame_count - 1];
+        closure->this_obj = frame->this_obj;
+        closure->scope = frame->scope;
+        closure->static_class = frame->static_class;
+    }
+
+    object->closure = closure;
+    context->stack_manager->push(context->value_handler->create_object(object));
+    return STATUS_SUCCESS;
+}
+
+// enter_callable enters callee, a closure or the name of a function bound
+// by FUNC_BIND, with the top argc values on the stack as its arguments.
+static status_t enter_callable(VMContext* context, Value callee, int argc) {
+    if (callee.type == TYPE_STRING) {
+        BoundFunction* function = find_function(context, callee.value.str_val);
+        if (!function) {
+            return throw_error(context, "Error", "Call to undefined function %s()", callee.value.str_val);
+        }
+        int param_count = context->bytecode[function->addr + 1];
+        if (argc < param_count) {
+            return throw_error(context, "ArgumentCountError",
+                               "Too few arguments to function %s(), %d passed and exactly %d expected",
+                               callee.value.str_val, argc, param_count);
+        }
+        return call_function(context, function->addr, argc);
+    }
+    if (callee.type == TYPE_OBJECT && !callee.value.obj_val->closure) {
+        return throw_error(context, "Error", "Object of type %s is not callable", callee.value.obj_val->class->name);
+    }
+    if (callee.type != TYPE_OBJECT) {
+        return throw_error(context, "Error", "Value not callable");
+    }
+
+    Closure* closure = callee.value.obj_val->closure;
+    int param_count = context->bytecode[closure->addr + 1];
+    if (argc < param_count) {
+        return throw_error(context, "ArgumentCountError",
+                           "Too few arguments to function {closure}(), %d passed and exactly %d expected",
+                           argc, param_count);
+    }
+    if (param_count + closure->captured_count > LOCAL_COUNT) {
+        context->error_handler->runtime_error("Closure at %zu has too many locals", closure->addr);
+        return STATUS_ERROR;
+    }
+
+    CallFrame* frame = push_frame(context, argc);
+    if (!frame) {
+        return STATUS_ERROR;
+    }
+    frame->addr = closure->addr;
+    frame->scope = closure->scope;
+    frame->this_obj = closure->this_obj;
+    frame->static_class = closure->static_class;
+    for (int i = 0; i < closure->captured_count; i++) {
+        frame->locals[param_count + i] = closure->captured[i];
+        value_retain(closure->captured[i]);
+    }
+
+    context->ip = closure->addr;
+    return STATUS_SUCCESS;
+}
+
+// handle_call_indirect calls the closure or the function named by the value
+// below the arguments.
+status_t handle_call_indirect(VMContext* context) {
+    if (context->ip >= context->bytecode_len) {
+        context->error_handler->runtime_error("Unexpected end of bytecode at ip=%zu", context->ip);
+        return STATUS_ERROR;
+    }
+    byte_t argc = context->bytecode[context->ip++];
+
+    if (context->stack_manager->size() <= argc) {
+        context->error_handler->runtime_error("CALL_INDIRECT expects %d arguments and a callee, stack has %d values",
+                                             argc, context->stack_manager->size());
+        return STATUS_STACK_UNDERFLOW;
+    }
+
+    // Take the callee out from under the arguments.
+    Value args[256];
+    for (int i = 0; i < argc; i++) {
+        args[i] = context->stack_manager->pop();
+    }
+    Value callee = context->stack_manager->pop();
+    for (int i = argc - 1; i >= 0; i--) {
+        context->stack_manager->push(args[i]);
+    }
+
+    return enter_callable(context, callee, argc);
+}
+
+status_t call_value(VMContext* context, Value callee, Value* args, int argc, Value* result) {
+    if (callee.type == TYPE_STRING) {
+        const NativeFunction* function = native_lookup(callee.value.str_val);
+        if (function) {
+            if (argc < function->min_args || (function->max_args != VARIADIC && argc > function->max_args)) {
+                return throw_error(context, "ArgumentCountError", "%s() does not accept %d arguments",
+                                   function->name, argc);
+            }
+            return function->impl(context, args, argc, result);
+        }
+    }
+
+    int depth = context->frame_count;
+    for (int i = argc - 1; i >= 0; i--) {
+        context->stack_manager->push(args[i]);
+    }
+    status_t status = enter_callable(context, callee, argc);
+    if (status != STATUS_SUCCESS) {
+        for (int i = 0; i < argc; i++) {
+            context->stack_manager->pop();
+        }
+        return status;
+    }
+
+    while (context->frame_count > depth && context->ip < context->bytecode_len) {
+        byte_t opcode = context->bytecode[context->ip++];
+        status = context->opcode_handler->execute(context, opcode);
+        if (status == STATUS_THROWN) {
+            status = unwind(context, depth);
+        }
+        if (status != STATUS_SUCCESS) {
+            return status;
+        }
+    }
+    if (context->frame_count > depth) {
+        // The script halted.
+        *result = context->value_handler->create_null();
+        return STATUS_SUCCESS;
+    }
+
+    *result = context->stack_manager->pop();
+    return STATUS_SUCCESS;
+}
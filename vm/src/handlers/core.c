@@ -1,5 +1,7 @@
 /* Licensed under GNU GPL v3. See LICENSE file for details. */
 #include "../../includes/interfaces/opcode_handler.h"
+#include "../../includes/interfaces/array.h"
+#include "../../includes/interfaces/exception.h"
 
 status_t handle_load_const(VMContext* context) {
     if (!context || !context->bytecode || !context->constants) {
@@ -35,9 +37,15 @@ status_t handle_print(VMContext* context) {
     }
 
     Value value = context->stack_manager->pop();
+    char buffer[64];
+
     switch (value.type) {
         case TYPE_INT:
-            printf("%d", value.value.int_val);
+            printf("%" INT_FMT, value.value.int_val);
+            break;
+        case TYPE_FLOAT:
+            value_format_float(value.value.float_val, buffer, sizeof(buffer));
+            printf("%s", buffer);
             break;
         case TYPE_STRING:
             if (value.value.str_val) {
@@ -45,11 +53,17 @@ status_t handle_print(VMContext* context) {
             }
             break;
         case TYPE_BOOLEAN:
-            printf("%s", value.value.bool_val ? "true" : "false");
+            printf("%s", value.value.bool_val ? "1" : "");
             break;
         case TYPE_NULL:
-            printf("null");
             break;
+        case TYPE_ARRAY:
+            context->error_handler->warning("Array to string conversion");
+            printf("Array");
+            break;
+        case TYPE_OBJECT:
+            return throw_error(context, "Error", "Object of class %s could not be converted to string",
+                               context->value_handler->type_name(value));
         default:
             printf("unknown");
             break;
@@ -86,6 +100,46 @@ status_t handle_pop(VMContext* context) {
     return STATUS_SUCCESS;
 }
 
+status_t handle_dup(VMContext* context) {
+    if (context->stack_manager->is_empty()) {
+        context->error_handler->runtime_error("Stack underflow in DUP at ip=%zu", context->ip - 1);
+        return STATUS_STACK_UNDERFLOW;
+    }
+
+    context->stack_manager->push(context->stack_manager->peek(0));
+
+    return STATUS_SUCCESS;
+}
+
+// handle_tuck copies the value on top of the stack under the n values
+// below it, where it stays as the result of an assignment once the write
+// has consumed them.
+status_t handle_tuck(VMContext* context) {
+    if (context->ip >= context->bytecode_len) {
+        context->error_handler->runtime_error("Unexpected end of bytecode at ip=%zu", context->ip);
+        return STATUS_ERROR;
+    }
+
+    byte_t n = context->bytecode[context->ip++];
+
+    if (context->stack_manager->size() <= n) {
+        context->error_handler->runtime_error("TUCK expects %d values, stack has %d values",
+                                              n + 1, context->stack_manager->size());
+        return STATUS_STACK_UNDERFLOW;
+    }
+
+    context->stack_manager->push(context->stack_manager->peek(0));
+    context->stack_manager->rotate(n + 2);
+
+    return STATUS_SUCCESS;
+}
+
+status_t handle_load_null(VMContext* context) {
+    context->stack_manager->push(context->value_handler->create_null());
+
+    return STATUS_SUCCESS;
+}
+
 status_t handle_store_var(VMContext* context) {
     if (!context || !context->bytecode || !context->variables) {
         return STATUS_ERROR;
@@ -110,12 +164,37 @@ status_t handle_store_var(VMContext* context) {
     }
 
     Value value = context->stack_manager->pop();
-    context->variables[var_idx] = value;
+    value_assign(&context->variables[var_idx], value);
 
     return STATUS_SUCCESS;
 }
 
-status_t handle_load_var(VMContext* context) {
+// variable_name returns the name of the variable in slot of the function
+// whose FUNC_DECL is at function, -1 for the globals.
+static const char* variable_name(VMContext* context, int32_t function, byte_t slot) {
+    for (size_t i = 0; i < context->variable_name_count; i++) {
+        const VariableName* entry = &context->variable_names[i];
+        if (entry->function == function && entry->slot == slot) {
+            return entry->name;
+        }
+    }
+    return "";
+}
+
+// push_variable pushes the value of a variable slot. An undefined variable
+// reads as null, with a warning unless quiet.
+static void push_variable(VMContext* context, Value slot, int32_t function, byte_t idx, bool quiet) {
+    Value value = value_deref(slot);
+    if (value.type == TYPE_UNDEF) {
+        if (!quiet) {
+            context->error_handler->warning("Undefined variable $%s", variable_name(context, function, idx));
+        }
+        value = context->value_handler->create_null();
+    }
+    context->stack_manager->push(value);
+}
+
+static status_t load_var(VMContext* context, bool quiet) {
     if (!context || !context->bytecode || !context->variables) {
         return STATUS_ERROR;
     }
@@ -133,7 +212,237 @@ status_t handle_load_var(VMContext* context) {
         return STATUS_ERROR;
     }
 
-    context->stack_manager->push(context->variables[var_idx]);
+    push_variable(context, context->variables[var_idx], -1, var_idx, quiet);
 
     return STATUS_SUCCESS;
-}
\ No newline at end of file
+}
+
+status_t handle_load_var(VMContext* context) {
+    return load_var(context, false);
+}
+
+// handle_load_var_quiet is LOAD_VAR for isset, empty, ?? and writes, which
+// read undefined variables as null without a warning.
+status_t handle_load_var_quiet(VMContext* context) {
+    return load_var(context, true);
+}
+static CallFrame* current_frame(VMContext* context) {
+    if (context->frame_count == 0) {
+        context->error_handler->runtime_error("Local variable access outside of a function at ip=%zu", context->ip - 1);
+        return NULL;
+    }
+
+    return &context->frames[context->frame_count - 1];
+}
+
+status_t handle_store_local(VMContext* context) {
+    if (!context || !context->bytecode || !context->frames) {
+        return STATUS_ERROR;
+    }
+
+    if (context->ip >= context->bytecode_len) {
+        context->error_handler->runtime_error("Unexpected end of bytecode at ip=%zu", context->ip);
+        return STATUS_ERROR;
+    }
+
+    byte_t slot = context->bytecode[context->ip++];
+
+    CallFrame* frame = current_frame(context);
+    if (!frame) {
+        return STATUS_ERROR;
+    }
+
+    if (context->stack_manager->is_empty()) {
+        context->error_handler->runtime_error("Stack underflow in STORE_LOCAL at ip=%zu", context->ip - 1);
+        return STATUS_STACK_UNDERFLOW;
+    }
+
+    Value value = context->stack_manager->pop();
+    value_assign(&frame->locals[slot], value);
+
+    return STATUS_SUCCESS;
+}
+
+static status_t load_local(VMContext* context, bool quiet) {
+    if (!context || !context->bytecode || !context->frames) {
+        return STATUS_ERROR;
+    }
+
+    if (context->ip >= context->bytecode_len) {
+        context->error_handler->runtime_error("Unexpected end of bytecode at ip=%zu", context->ip);
+        return STATUS_ERROR;
+    }
+
+    byte_t slot = context->bytecode[context->ip++];
+
+    CallFrame* frame = current_frame(context);
+    if (!frame) {
+        return STATUS_ERROR;
+    }
+
+    push_variable(context, frame->locals[slot], (int32_t)frame->addr, slot, quiet);
+
+    return STATUS_SUCCESS;
+}
+
+status_t handle_load_local(VMContext* context) {
+    return load_local(context, false);
+}
+
+status_t handle_load_local_quiet(VMContext* context) {
+    return load_local(context, true);
+}
+
+// handle_ref_var binds a global variable to a reference, which it keeps for
+// good, and pushes the reference for CLOSURE to capture.
+status_t handle_ref_var(VMContext* context) {
+    if (context->ip >= context->bytecode_len) {
+        context->error_handler->runtime_error("Unexpected end of bytecode at ip=%zu", context->ip);
+        return STATUS_ERROR;
+    }
+
+    byte_t var_idx = context->bytecode[context->ip++];
+    context->stack_manager->push(value_make_ref(&context->variables[var_idx]));
+
+    return STATUS_SUCCESS;
+}
+
+status_t handle_ref_local(VMContext* context) {
+    if (context->ip >= context->bytecode_len) {
+        context->error_handler->runtime_error("Unexpected end of bytecode at ip=%zu", context->ip);
+        return STATUS_ERROR;
+    }
+
+    byte_t slot = context->bytecode[context->ip++];
+
+    CallFrame* frame = current_frame(context);
+    if (!frame) {
+        return STATUS_ERROR;
+    }
+
+    context->stack_manager->push(value_make_ref(&frame->locals[slot]));
+
+    return STATUS_SUCCESS;
+}
+
+// handle_unset_var empties a global variable slot. A slot bound to a
+// reference is detached from it; the other variables bound to the
+// reference keep its value.
+status_t handle_unset_var(VMContext* context) {
+    if (context->ip >= context->bytecode_len) {
+        context->error_handler->runtime_error("Unexpected end of bytecode at ip=%zu", context->ip);
+        return STATUS_ERROR;
+    }
+
+    byte_t var_idx = context->bytecode[context->ip++];
+    value_release(context->variables[var_idx]);
+    context->variables[var_idx].type = TYPE_UNDEF;
+
+    return STATUS_SUCCESS;
+}
+
+status_t handle_unset_local(VMContext* context) {
+    if (context->ip >= context->bytecode_len) {
+        context->error_handler->runtime_error("Unexpected end of bytecode at ip=%zu", context->ip);
+        return STATUS_ERROR;
+    }
+
+    byte_t slot = context->bytecode[context->ip++];
+
+    CallFrame* frame = current_frame(context);
+    if (!frame) {
+        return STATUS_ERROR;
+    }
+
+    value_release(frame->locals[slot]);
+    frame->locals[slot].type = TYPE_UNDEF;
+
+    return STATUS_SUCCESS;
+}
+
+// pop_reference pops the reference a BIND_VAR or BIND_LOCAL binds a
+// variable to.
+static status_t pop_reference(VMContext* context, Value* out) {
+    if (context->stack_manager->is_empty()) {
+        context->error_handler->runtime_error("Stack underflow in BIND at ip=%zu", context->ip - 2);
+        return STATUS_STACK_UNDERFLOW;
+    }
+
+    *out = context->stack_manager->pop();
+    if (out->type != TYPE_REFERENCE) {
+        context->error_handler->runtime_error("BIND expects a reference at ip=%zu, got %s", context->ip - 2,
+                                             context->value_handler->type_name(*out));
+        return STATUS_ERROR;
+    }
+    return STATUS_SUCCESS;
+}
+
+// handle_bind_var pops a reference and binds a global variable to it, in
+// place of the value or the reference the variable held.
+status_t handle_bind_var(VMContext* context) {
+    if (context->ip >= context->bytecode_len) {
+        context->error_handler->runtime_error("Unexpected end of bytecode at ip=%zu", context->ip);
+        return STATUS_ERROR;
+    }
+
+    byte_t var_idx = context->bytecode[context->ip++];
+
+    Value ref;
+    status_t status = pop_reference(context, &ref);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    value_bind(&context->variables[var_idx], ref);
+    return STATUS_SUCCESS;
+}
+
+status_t handle_bind_local(VMContext* context) {
+    if (context->ip >= context->bytecode_len) {
+        context->error_handler->runtime_error("Unexpected end of bytecode at ip=%zu", context->ip);
+        return STATUS_ERROR;
+    }
+
+    byte_t slot = context->bytecode[context->ip++];
+
+    Value ref;
+    status_t status = pop_reference(context, &ref);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    CallFrame* frame = current_frame(context);
+    if (!frame) {
+        return STATUS_ERROR;
+    }
+
+    value_bind(&frame->locals[slot], ref);
+    return STATUS_SUCCESS;
+}
+
+// handle_static_init runs the initializer of a static variable only the
+// first time it is reached, later calls jump over it.
+status_t handle_static_init(VMContext* context) {
+    if (!context || !context->bytecode || !context->statics) {
+        return STATUS_ERROR;
+    }
+
+    if (context->ip + 2 >= context->bytecode_len) {
+        context->error_handler->runtime_error("Unexpected end of bytecode at ip=%zu", context->ip);
+        return STATUS_ERROR;
+    }
+
+    byte_t var_idx = context->bytecode[context->ip++];
+    byte_t low_byte = context->bytecode[context->ip++];
+    byte_t high_byte = context->bytecode[context->ip++];
+    uint16_t offset = (uint16_t)((high_byte << 8) | low_byte);
+
+    if (context->statics[var_idx]) {
+        context->ip += offset;
+        return STATUS_SUCCESS;
+    }
+
+    context->statics[var_idx] = true;
+
+    return STATUS_SUCCESS;
+}
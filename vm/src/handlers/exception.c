@@ -0,0 +1,221 @@
+/* Licensed under GNU GPL v3. See LICENSE file for details. */
+#include "../../includes/interfaces/opcode_handler.h"
+#include "../../includes/interfaces/exception.h"
+#include "../../includes/interfaces/array.h"
+#include <stdarg.h>
+#include <stdlib.h>
+#include <string.h>
+
+/*
+ * A handler throws by storing the exception in context->exception and
+ * returning STATUS_THROWN. vm_execute then calls unwind, which looks up the
+ * exception table for the instruction that threw and, frame by frame, for
+ * the calls that led to it. A handler gets the exception on the stack.
+ */
+
+// line_at returns the source line of the instruction at addr, or 0 when
+// the line table has no entry for it.
+uint32_t line_at(VMContext* context, size_t addr) {
+    size_t lo = 0, hi = context->line_count;
+    while (lo < hi) {
+        size_t mid = lo + (hi - lo) / 2;
+        if (context->lines[mid].addr > addr) {
+            hi = mid;
+        } else {
+            lo = mid + 1;
+        }
+    }
+    return lo == 0 ? 0 : context->lines[lo - 1].line;
+}
+
+static const char* function_name(VMContext* context, size_t addr) {
+    for (size_t i = 0; i < context->function_name_count; i++) {
+        if (context->function_names[i].addr == addr) {
+            return context->function_names[i].name;
+        }
+    }
+    return "";
+}
+
+static const char* source_file(VMContext* context) {
+    return context->source_file ? context->source_file : "";
+}
+
+static void set_string(VMContext* context, Array* array, const char* key, const char* str) {
+    array_set(array, object_key(key), context->value_handler->create_string(str));
+}
+
+// init_throwable records where a new Throwable is created: the line of the
+// running instruction and, for each active call, the call site and callee.
+void init_throwable(VMContext* context, Object* object) {
+    ValueHandler* values = context->value_handler;
+
+    Array* trace = array_new();
+    for (int i = context->frame_count - 1; i >= 0; i--) {
+        CallFrame* frame = &context->frames[i];
+        Array* entry = array_new();
+        set_string(context, entry, PROP_FILE, source_file(context));
+        array_set(entry, object_key(PROP_LINE), values->create_int(line_at(context, frame->return_address - 1)));
+        if (frame->method) {
+            set_string(context, entry, "function", frame->method->name);
+            set_string(context, entry, "class", frame->method->class->name);
+            set_string(context, entry, "type", frame->this_obj ? "->" : "::");
+        } else {
+            set_string(context, entry, "function", function_name(context, frame->addr));
+        }
+        array_append(trace, values->create_array(entry));
+    }
+
+    set_string(context, object->props, PROP_FILE, source_file(context));
+    array_set(object->props, object_key(PROP_LINE), values->create_int(line_at(context, context->ip - 1)));
+    array_set(object->props, object_key(PROP_TRACE), values->create_array(trace));
+}
+
+status_t throw_object(VMContext* context, Object* exception) {
+    context->exception = exception;
+    return STATUS_THROWN;
+}
+
+// throw_error throws a new instance of the built-in class with the message.
+// It is how PHP's Error exceptions for failed operations come about.
+status_t throw_error(VMContext* context, const char* class_name, const char* format, ...) {
+    char message[1024];
+    va_list args;
+    va_start(args, format);
+    vsnprintf(message, sizeof(message), format, args);
+    va_end(args);
+
+    Class* class = class_find(context->classes, class_name);
+    if (!class) {
+        return context->error_handler->runtime_error("%s", message);
+    }
+
+    Object* object = object_new(class);
+    if (!object) {
+        context->error_handler->runtime_error("Memory allocation failed for object of class %s", class->name);
+        return STATUS_OUT_OF_MEMORY;
+    }
+    init_throwable(context, object);
+    set_string(context, object->props, "message", message);
+    return throw_object(context, object);
+}
+
+// unwind transfers control to the innermost handler that covers the
+// instruction being run, leaving the frames that have none. Returns
+// STATUS_THROWN when no handler is left. depth is the number of frames
+// below a function a native called, see call_value, or -1: an exception
+// that leaves the function is returned with STATUS_THROWN for the native
+// to pass on.
+status_t unwind(VMContext* context, int depth) {
+    size_t addr = context->ip - 1;
+    for (;;) {
+        int32_t function = -1;
+        int base = 0;
+        if (context->frame_count > 0) {
+            CallFrame* frame = &context->frames[context->frame_count - 1];
+            function = (int32_t)frame->addr;
+            base = frame->stack_base;
+        }
+
+        for (size_t i = 0; i < context->exception_handler_count; i++) {
+            const ExceptionHandler* handler = &context->exception_handlers[i];
+            if (handler->function != function || addr < handler->start || addr >= handler->end) {
+                continue;
+            }
+
+            while (context->stack_manager->size() > base + (int)handler->stack_depth) {
+                context->stack_manager->pop();
+            }
+            context->ip = handler->target;
+            context->stack_manager->push(context->value_handler->create_object(context->exception));
+            context->exception = NULL;
+            return STATUS_SUCCESS;
+        }
+
+        if (context->frame_count == 0) {
+            return STATUS_THROWN;
+        }
+        CallFrame* frame = &context->frames[--context->frame_count];
+        for (int i = 0; i < LOCAL_COUNT; i++) {
+            value_release(frame->locals[i]);
+        }
+        addr = frame->return_address - 1;
+        if (context->frame_count == depth) {
+            context->ip = frame->return_address;
+            return STATUS_THROWN;
+        }
+    }
+}
+
+// report_uncaught prints the exception that ended the script the way PHP
+// does.
+void report_uncaught(VMContext* context) {
+    Object* exception = context->exception;
+    char* description = describe_throwable(context, exception);
+
+    Value file, line;
+    if (!array_get(exception->props, object_key(PROP_FILE), &file)) {
+        file = context->value_handler->create_string("");
+    }
+    if (!array_get(exception->props, object_key(PROP_LINE), &line)) {
+        line = context->value_handler->create_int(0);
+    }
+    char* file_str = context->value_handler->to_string(file);
+
+    printf("PHP Fatal error:  Uncaught %s\n  thrown in %s on line %" INT_FMT "\n",
+           description, file_str, context->value_handler->to_int(line));
+
+    free(file_str);
+    free(description);
+}
+
+// handle_throw pops the exception and throws it.
+status_t handle_throw(VMContext* context) {
+    if (context->stack_manager->is_empty()) {
+        context->error_handler->runtime_error("Stack underflow in THROW at ip=%zu", context->ip - 1);
+        return STATUS_STACK_UNDERFLOW;
+    }
+    Value value = context->stack_manager->pop();
+
+    if (value.type != TYPE_OBJECT) {
+        return throw_error(context, "Error", "Can only throw objects");
+    }
+    if (!is_throwable(context, value.value.obj_val->class)) {
+        return throw_error(context, "Error", "Cannot throw objects that do not implement Throwable");
+    }
+    return throw_object(context, value.value.obj_val);
+}
+
+// handle_catch jumps when the exception on top of the stack is an instance
+// of the class operand. A class that was never declared matches nothing.
+status_t handle_catch(VMContext* context) {
+    if (context->ip + 3 > context->bytecode_len) {
+        context->error_handler->runtime_error("Unexpected end of bytecode at ip=%zu", context->ip);
+        return STATUS_ERROR;
+    }
+    byte_t idx = context->bytecode[context->ip++];
+    byte_t low_byte = context->bytecode[context->ip++];
+    byte_t high_byte = context->bytecode[context->ip++];
+    uint16_t offset = (uint16_t)((high_byte << 8) | low_byte);
+
+    if (idx >= context->constants_len || context->constants[idx].type != TYPE_STRING) {
+        context->error_handler->runtime_error("Invalid name constant %d at ip=%zu", idx, context->ip - 3);
+        return STATUS_ERROR;
+    }
+    if (context->stack_manager->is_empty()) {
+        context->error_handler->runtime_error("CATCH expects an exception on the stack at ip=%zu", context->ip - 4);
+        return STATUS_STACK_UNDERFLOW;
+    }
+    Value exception = context->stack_manager->peek(0);
+
+    Class* class = class_find(context->classes, context->constants[idx].value.str_val);
+    if (class && exception.type == TYPE_OBJECT && class_instance_of(exception.value.obj_val->class, class)) {
+        size_t target = context->ip + offset;
+        if (target > context->bytecode_len) {
+            context->error_handler->runtime_error("CATCH target out of bounds (offset=%u, target=%zu)", offset, target);
+            return STATUS_ERROR;
+        }
+        context->ip = target;
+    }
+    return STATUS_SUCCESS;
+}
@@ -65,6 +65,32 @@ status_t handle_jump_if_false(VMContext* context) {
     return STATUS_SUCCESS;
 }
 
+// handle_coalesce jumps when the value on top of the stack is not null,
+// leaving it there as the result of ??. A null value is popped, and the
+// right operand that follows computes the result instead.
+status_t handle_coalesce(VMContext* context) {
+    uint16_t offset = read_uint16(context);
+
+    if (context->stack_manager->is_empty()) {
+        context->error_handler->runtime_error("Stack underflow in COALESCE at ip=%zu", context->ip - 3);
+        return STATUS_STACK_UNDERFLOW;
+    }
+
+    if (context->stack_manager->peek(0).type == TYPE_NULL) {
+        context->stack_manager->pop();
+        return STATUS_SUCCESS;
+    }
+
+    if (context->ip + offset > context->bytecode_len) {
+        context->error_handler->runtime_error("COALESCE target out of bounds (ip=%zu, offset=%u, target=%zu, bytecode_len=%zu)",
+                                             context->ip - 3, offset, context->ip + offset, context->bytecode_len);
+        return STATUS_ERROR;
+    }
+
+    context->ip += offset;
+    return STATUS_SUCCESS;
+}
+
 status_t handle_break(VMContext* context) {
     if (!context || !context->bytecode) {
         return STATUS_ERROR;
@@ -0,0 +1,155 @@
+/* Licensed under GNU GPL v3. See LICENSE file for details. */
+#include "../../includes/interfaces/opcode_handler.h"
+#include "../../includes/interfaces/array.h"
+
+/*
+ * A foreach loop keeps two values on the stack while it runs: the array it
+ * iterates over and the position of the next element.
+ */
+
+static bool read_operands(VMContext* context, size_t count) {
+    if (context->ip + count > context->bytecode_len) {
+        context->error_handler->runtime_error("Unexpected end of bytecode at ip=%zu", context->ip);
+        return false;
+    }
+    return true;
+}
+
+static bool check_loop_state(VMContext* context) {
+    if (context->stack_manager->size() < 2 ||
+        context->stack_manager->peek(1).type != TYPE_ARRAY ||
+        context->stack_manager->peek(0).type != TYPE_INT) {
+        context->error_handler->runtime_error("Foreach state expected on the stack at ip=%zu", context->ip - 1);
+        return false;
+    }
+    return true;
+}
+
+// handle_foreach_init pops the iterated value and pushes the loop state. A
+// loop by value holds a reference to the array, so that writes to the
+// variable in the body separate it and do not affect the iteration.
+status_t handle_foreach_init(VMContext* context) {
+    if (!read_operands(context, 1)) {
+        return STATUS_ERROR;
+    }
+    byte_t by_ref = context->bytecode[context->ip++];
+
+    if (context->stack_manager->is_empty()) {
+        context->error_handler->runtime_error("Stack underflow in FOREACH_INIT at ip=%zu", context->ip - 2);
+        return STATUS_STACK_UNDERFLOW;
+    }
+
+    Value value = context->stack_manager->pop();
+    if (value.type != TYPE_ARRAY) {
+        context->error_handler->warning("foreach() argument must be of type array|object, %s given",
+                                        context->value_handler->type_name(value));
+        value = context->value_handler->create_array(array_new());
+    }
+
+    if (!by_ref) {
+        value_retain(value);
+    }
+
+    context->stack_manager->push(value);
+    context->stack_manager->push(context->value_handler->create_int(0));
+    return STATUS_SUCCESS;
+}
+
+// handle_foreach_next pushes the key and the value of the next element, or
+// jumps out of the loop when there is none. By reference, the current value
+// of the iterated variable is on top of the stack and replaces the iterated
+// array: the element is bound to a reference, which is pushed in place of
+// the value, followed by the array to store back into the variable.
+status_t handle_foreach_next(VMContext* context) {
+    if (!read_operands(context, 3)) {
+        return STATUS_ERROR;
+    }
+    byte_t by_ref = context->bytecode[context->ip++];
+    byte_t low_byte = context->bytecode[context->ip++];
+    byte_t high_byte = context->bytecode[context->ip++];
+    uint16_t offset = (uint16_t)((high_byte << 8) | low_byte);
+
+    Value current = context->value_handler->create_null();
+    if (by_ref) {
+        if (context->stack_manager->is_empty()) {
+            context->error_handler->runtime_error("Stack underflow in FOREACH_NEXT at ip=%zu", context->ip - 4);
+            return STATUS_STACK_UNDERFLOW;
+        }
+        current = context->stack_manager->pop();
+        if (current.type != TYPE_ARRAY) {
+            current = context->value_handler->create_array(array_new());
+        }
+    }
+
+    if (!check_loop_state(context)) {
+        return STATUS_ERROR;
+    }
+
+    Value position = context->stack_manager->pop();
+    Value array = context->stack_manager->pop();
+    if (by_ref) {
+        array = current;
+    }
+
+    Array* arr = array.value.arr_val;
+    int_t pos = position.value.int_val;
+
+    if (pos >= (int_t)arr->count) {
+        context->stack_manager->push(array);
+        context->stack_manager->push(position);
+
+        if (context->ip + offset > context->bytecode_len) {
+            context->error_handler->runtime_error("FOREACH_NEXT target out of bounds (ip=%zu, offset=%u, target=%zu, bytecode_len=%zu)",
+                                                 context->ip - 4, offset, context->ip + offset, context->bytecode_len);
+            return STATUS_ERROR;
+        }
+        context->ip += offset;
+        return STATUS_SUCCESS;
+    }
+
+    // The element is written through the reference, so the array must not
+    // be shared with other variables first.
+    if (by_ref) {
+        arr = array_separate(arr);
+        array = context->value_handler->create_array(arr);
+    }
+
+    context->stack_manager->push(array);
+    context->stack_manager->push(context->value_handler->create_int(pos + 1));
+
+    ArrayKey key = array_key_at(arr, pos);
+    if (key.is_string) {
+        context->stack_manager->push(context->value_handler->create_string(key.str_key));
+    } else {
+        context->stack_manager->push(context->value_handler->create_int(key.int_key));
+    }
+
+    if (by_ref) {
+        context->stack_manager->push(array_ref_at(arr, pos));
+        context->stack_manager->push(array);
+    } else {
+        context->stack_manager->push(array_at(arr, pos));
+    }
+
+    return STATUS_SUCCESS;
+}
+
+// handle_foreach_end drops the loop state.
+status_t handle_foreach_end(VMContext* context) {
+    if (!read_operands(context, 1)) {
+        return STATUS_ERROR;
+    }
+    byte_t by_ref = context->bytecode[context->ip++];
+
+    if (!check_loop_state(context)) {
+        return STATUS_ERROR;
+    }
+
+    context->stack_manager->pop();
+    Value array = context->stack_manager->pop();
+
+    if (!by_ref) {
+        value_release(array);
+    }
+    return STATUS_SUCCESS;
+}
@@ -1,111 +1,259 @@
 /* Licensed under GNU GPL v3. See LICENSE file for details. */
 #include "../../includes/interfaces/opcode_handler.h"
 #include "../../includes/vm.h"
+#include "../../includes/interfaces/array.h"
+#include "../../includes/interfaces/exception.h"
+#include <stdlib.h>
+#include <string.h>
 
-static int_t return_address = -1;
+/*
+ * Calling convention: the caller pushes the arguments last to first, so the
+ * first argument is on top, then FUNC_CALL pushes a call frame with the
+ * return address and argument count. FUNC_DECL binds the arguments to local
+ * slots and RETURN/EXIT_FUNC drop whatever the callee left on the stack.
+ */
 
-status_t handle_func_call(VMContext* context) {
-    byte_t param_count = context->bytecode[context->ip++];
+// push_frame enters a function whose arguments are the top arg_count values
+// on the stack.
+CallFrame* push_frame(VMContext* context, byte_t arg_count) {
+    if (context->frame_count >= FRAME_COUNT) {
+        context->error_handler->runtime_error("Call stack overflow at ip=%zu, max depth: %d",
+                                             context->ip, FRAME_COUNT);
+        return NULL;
+    }
 
-    byte_t low_byte = context->bytecode[context->ip++];
-    byte_t high_byte = context->bytecode[context->ip++];
-    uint16_t func_addr = (uint16_t)((high_byte << 8) | low_byte);
+    CallFrame* frame = &context->frames[context->frame_count++];
+    frame->addr = 0;
+    frame->return_address = context->ip;
+    frame->arg_count = arg_count;
+    frame->stack_base = context->stack_manager->size() - arg_count;
+    for (int i = 0; i < LOCAL_COUNT; i++) {
+        frame->locals[i].type = TYPE_UNDEF;
+    }
+    frame->scope = NULL;
+    frame->this_obj = NULL;
+    frame->static_class = NULL;
+    frame->construct = NULL;
+    frame->method = NULL;
+
+    return frame;
+}
+
+static status_t return_from_function(VMContext* context, Value return_value) {
+    if (context->frame_count == 0) {
+        context->error_handler->runtime_error("Return outside of a function at ip=%zu", context->ip);
+        return STATUS_ERROR;
+    }
+
+    CallFrame* frame = &context->frames[--context->frame_count];
+
+    if (frame->return_address >= context->bytecode_len) {
+        context->error_handler->runtime_error("Invalid return address %zu at ip=%zu, bytecode_len=%zu",
+                                             frame->return_address, context->ip, context->bytecode_len);
+        return STATUS_ERROR;
+    }
+
+    while (context->stack_manager->size() > frame->stack_base) {
+        context->stack_manager->pop();
+    }
+
+    for (int i = 0; i < LOCAL_COUNT; i++) {
+        value_release(frame->locals[i]);
+    }
+
+    // A constructor gives back the object it initialized.
+    if (frame->construct) {
+        return_value = context->value_handler->create_object(frame->construct);
+    }
+
+    context->ip = frame->return_address;
+    context->stack_manager->push(return_value);
+
+    return STATUS_SUCCESS;
+}
 
+// call_function enters the function whose FUNC_DECL is at func_addr with
+// the top arg_count values on the stack as its arguments.
+status_t call_function(VMContext* context, size_t func_addr, byte_t arg_count) {
     if (func_addr >= context->bytecode_len) {
-        context->error_handler->runtime_error("Invalid function address %u at ip=%zu, bytecode_len=%zu",
-                                             func_addr, context->ip - 2, context->bytecode_len);
+        context->error_handler->runtime_error("Invalid function address %zu at ip=%zu, bytecode_len=%zu",
+                                             func_addr, context->ip, context->bytecode_len);
         return STATUS_ERROR;
     }
 
     byte_t func_opcode = context->bytecode[func_addr];
 
-    if (func_opcode != 0x80) {
-        context->error_handler->runtime_error("Invalid function opcode 0x%02X at address %u",
+    if (func_opcode != OP_FUNC_DECL) {
+        context->error_handler->runtime_error("Invalid function opcode 0x%02X at address %zu",
                                              func_opcode, func_addr);
         return STATUS_ERROR;
     }
 
-    return_address = context->ip;
+    if (context->stack_manager->size() < arg_count) {
+        context->error_handler->runtime_error("FUNC_CALL expects %d arguments at ip=%zu, stack has %d values",
+                                             arg_count, context->ip, context->stack_manager->size());
+        return STATUS_STACK_UNDERFLOW;
+    }
+
+    CallFrame* frame = push_frame(context, arg_count);
+    if (!frame) {
+        return STATUS_ERROR;
+    }
+    frame->addr = func_addr;
+
     context->ip = func_addr;
 
     return STATUS_SUCCESS;
 }
 
-status_t handle_func_decl(VMContext* context) {
-    byte_t param_count = context->bytecode[context->ip++];
+status_t handle_func_call(VMContext* context) {
+    byte_t arg_count = context->bytecode[context->ip++];
 
-    for (int i = 0; i < param_count; i++) {
-        byte_t var_idx = context->bytecode[context->ip++];
+    byte_t low_byte = context->bytecode[context->ip++];
+    byte_t high_byte = context->bytecode[context->ip++];
+    uint16_t func_addr = (uint16_t)((high_byte << 8) | low_byte);
 
-        if (!context->stack_manager->is_empty()) {
-            Value param_val = context->stack_manager->pop();
-            context->variables[var_idx] = param_val;
-        } else {
-            context->error_handler->warning("Not enough parameters for function at ip=%zu", context->ip);
+    return call_function(context, func_addr, arg_count);
+}
 
-            Value default_val;
-            default_val.type = TYPE_INT;
-            default_val.value.int_val = 0;
-            context->variables[var_idx] = default_val;
-        }
+// read_function_name reads the constant operand naming a bound
+// function.
+static status_t read_function_name(VMContext* context, const char** out) {
+    byte_t idx = context->bytecode[context->ip++];
+    if (idx >= context->constants_len || context->constants[idx].type != TYPE_STRING) {
+        context->error_handler->runtime_error("Invalid name constant %d at ip=%zu", idx, context->ip - 1);
+        return STATUS_ERROR;
     }
 
+    *out = context->constants[idx].value.str_val;
     return STATUS_SUCCESS;
 }
 
-
-status_t handle_enter_func(VMContext* context) {
-    return STATUS_SUCCESS;
+// find_function finds a function bound by FUNC_BIND.
+BoundFunction* find_function(VMContext* context, const char* name) {
+    for (BoundFunction* function = context->functions; function; function = function->next) {
+        if (strcmp(function->name, name) == 0) {
+            return function;
+        }
+    }
+    return NULL;
 }
 
-status_t handle_return(VMContext* context) {
-    if (return_address < 0) {
-        context->error_handler->runtime_error("No return address set in RETURN at ip=%zu", context->ip);
+// handle_func_bind defines a function by name: one declared at the top level
+// before the first statement runs, one declared in a block or a function
+// body once its declaration runs.
+status_t handle_func_bind(VMContext* context) {
+    if (context->ip + 3 > context->bytecode_len) {
+        context->error_handler->runtime_error("Unexpected end of bytecode at ip=%zu", context->ip);
         return STATUS_ERROR;
     }
 
-    if (context->stack_manager->is_empty()) {
-        context->error_handler->runtime_error("Stack empty in RETURN at ip=%zu", context->ip);
-        return STATUS_STACK_UNDERFLOW;
+    const char* name;
+    status_t status = read_function_name(context, &name);
+    if (status != STATUS_SUCCESS) {
+        return status;
     }
 
-    Value return_value = context->stack_manager->pop();
+    byte_t low_byte = context->bytecode[context->ip++];
+    byte_t high_byte = context->bytecode[context->ip++];
+    uint16_t func_addr = (uint16_t)((high_byte << 8) | low_byte);
 
-    if (return_address < 0 || return_address >= (int)context->bytecode_len) {
-        context->error_handler->runtime_error("Invalid return address %d at ip=%zu, bytecode_len=%zu",
-                                             return_address, context->ip, context->bytecode_len);
+    if (find_function(context, name)) {
+        context->error_handler->runtime_error("Cannot redeclare %s() at ip=%zu", name, context->ip - 4);
         return STATUS_ERROR;
     }
 
-    context->ip = return_address;
-    context->stack_manager->push(return_value);
-
-    return_address = -1;
+    BoundFunction* function = (BoundFunction*)malloc(sizeof(BoundFunction));
+    if (!function) {
+        context->error_handler->runtime_error("Out of memory binding function %s()", name);
+        return STATUS_ERROR;
+    }
+    function->name = name;
+    function->addr = func_addr;
+    function->next = context->functions;
+    context->functions = function;
 
     return STATUS_SUCCESS;
 }
 
+// handle_call_named calls a function bound by FUNC_BIND. The arguments are
+// on the stack like for FUNC_CALL.
+status_t handle_call_named(VMContext* context) {
+    if (context->ip + 2 > context->bytecode_len) {
+        context->error_handler->runtime_error("Unexpected end of bytecode at ip=%zu", context->ip);
+        return STATUS_ERROR;
+    }
 
-status_t handle_exit_func(VMContext* context) {
-    Value null_val;
-    null_val.type = TYPE_NULL;
+    const char* name;
+    status_t status = read_function_name(context, &name);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+    byte_t arg_count = context->bytecode[context->ip++];
 
-    if (return_address < 0) {
-        context->error_handler->runtime_error("No return address set in EXIT_FUNC at ip=%zu", context->ip);
-        return STATUS_ERROR;
+    BoundFunction* function = find_function(context, name);
+    if (!function) {
+        return throw_error(context, "Error", "Call to undefined function %s()", name);
     }
 
-    if (return_address < 0 || return_address >= (int)context->bytecode_len) {
-        context->error_handler->runtime_error("Invalid return address %d at ip=%zu, bytecode_len=%zu",
-                                             return_address, context->ip, context->bytecode_len);
+    int param_count = context->bytecode[function->addr + 1];
+    if (arg_count < param_count) {
+        return throw_error(context, "ArgumentCountError",
+                           "Too few arguments to function %s(), %d passed and exactly %d expected",
+                           name, arg_count, param_count);
+    }
+
+    return call_function(context, function->addr, arg_count);
+}
+
+status_t handle_func_decl(VMContext* context) {
+    byte_t param_count = context->bytecode[context->ip++];
+
+    if (context->frame_count == 0) {
+        context->error_handler->runtime_error("FUNC_DECL reached without a call at ip=%zu", context->ip - 2);
         return STATUS_ERROR;
     }
+    CallFrame* frame = &context->frames[context->frame_count - 1];
+
+    for (int i = 0; i < param_count; i++) {
+        byte_t slot = context->bytecode[context->ip++];
+
+        if (i < frame->arg_count) {
+            frame->locals[slot] = context->stack_manager->pop();
+            value_retain(frame->locals[slot]);
+        } else {
+            context->error_handler->warning("Not enough parameters for function at ip=%zu", context->ip);
+        }
+    }
 
-    context->ip = return_address;
-    context->stack_manager->push(null_val);
+    // Extra arguments are dropped, like PHP does for user functions.
+    while (context->stack_manager->size() > frame->stack_base) {
+        context->stack_manager->pop();
+    }
+
+    return STATUS_SUCCESS;
+}
 
-    return_address = -1;
 
+status_t handle_enter_func(VMContext* context) {
     return STATUS_SUCCESS;
 }
 
+status_t handle_return(VMContext* context) {
+    if (context->stack_manager->is_empty()) {
+        context->error_handler->runtime_error("Stack empty in RETURN at ip=%zu", context->ip);
+        return STATUS_STACK_UNDERFLOW;
+    }
+
+    Value return_value = context->stack_manager->pop();
+
+    return return_from_function(context, return_value);
+}
+
+
+status_t handle_exit_func(VMContext* context) {
+    Value null_val;
+    null_val.type = TYPE_NULL;
+
+    return return_from_function(context, null_val);
+}
@@ -1,5 +1,6 @@
 /* Licensed under GNU GPL v3. See LICENSE file for details. */
 #include "../../includes/interfaces/opcode_handler.h"
+#include "../../includes/interfaces/exception.h"
 
 static status_t check_stack_size(VMContext* context, int required_size) {
     if (!context || !context->stack_manager) {
@@ -154,7 +155,7 @@ status_t handle_identity_eq(VMContext* context) {
     Value b = context->stack_manager->pop();
     Value a = context->stack_manager->pop();
 
-    bool result = a.type == b.type && context->value_handler->equals(a, b);
+    bool result = context->value_handler->identical(a, b);
     context->stack_manager->push(context->value_handler->create_boolean(result));
     return STATUS_SUCCESS;
 }
@@ -168,11 +169,27 @@ status_t handle_identity_ne(VMContext* context) {
     Value b = context->stack_manager->pop();
     Value a = context->stack_manager->pop();
 
-    bool result = a.type != b.type || !context->value_handler->equals(a, b);
+    bool result = !context->value_handler->identical(a, b);
     context->stack_manager->push(context->value_handler->create_boolean(result));
     return STATUS_SUCCESS;
 }
 
+// handle_spaceship pushes -1, 0 or 1 as the first operand is less than,
+// equal to or greater than the second.
+status_t handle_spaceship(VMContext* context) {
+    status_t status = check_stack_size(context, 2);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    Value b = context->stack_manager->pop();
+    Value a = context->stack_manager->pop();
+
+    int result = context->value_handler->compare(a, b);
+    context->stack_manager->push(context->value_handler->create_int(result));
+    return STATUS_SUCCESS;
+}
+
 status_t handle_bit_and(VMContext* context) {
     status_t status = check_stack_size(context, 2);
     if (status != STATUS_SUCCESS) {
@@ -255,11 +272,11 @@ status_t handle_lshift(VMContext* context) {
     int_t b_int = context->value_handler->to_int(b);
 
     if (b_int < 0) {
-        context->error_handler->warning("Negative shift amount at ip=%zu", context->ip - 1);
-        b_int = 0;
+        return throw_error(context, "ArithmeticError", "Bit shift by negative number");
     }
 
-    int_t result = a_int << b_int;
+    // Shifting by the width of int_t or more is undefined in C, PHP gives 0.
+    int_t result = b_int >= 64 ? 0 : (int_t)((uint64_t)a_int << b_int);
     context->stack_manager->push(context->value_handler->create_int(result));
     return STATUS_SUCCESS;
 }
@@ -277,87 +294,52 @@ status_t handle_rshift(VMContext* context) {
     int_t b_int = context->value_handler->to_int(b);
 
     if (b_int < 0) {
-        context->error_handler->warning("Negative shift amount at ip=%zu", context->ip - 1);
-        b_int = 0;
+        return throw_error(context, "ArithmeticError", "Bit shift by negative number");
     }
 
-    int_t result = a_int >> b_int;
+    int_t result = b_int >= 64 ? (a_int < 0 ? -1 : 0) : a_int >> b_int;
     context->stack_manager->push(context->value_handler->create_int(result));
     return STATUS_SUCCESS;
 }
 
 status_t handle_assign_add(VMContext* context) {
-    status_t status = check_stack_size(context, 2);
-    if (status != STATUS_SUCCESS) {
-        return status;
-    }
-
-    Value value = context->stack_manager->pop();
-    Value var = context->stack_manager->pop();
-
-    int_t var_int = context->value_handler->to_int(var);
-    int_t value_int = context->value_handler->to_int(value);
-    int_t result = var_int + value_int;
-
-    context->stack_manager->push(context->value_handler->create_int(result));
-    return STATUS_SUCCESS;
+    return handle_add(context);
 }
 
 status_t handle_assign_sub(VMContext* context) {
-    status_t status = check_stack_size(context, 2);
-    if (status != STATUS_SUCCESS) {
-        return status;
-    }
-
-    Value value = context->stack_manager->pop();
-    Value var = context->stack_manager->pop();
-
-    int_t var_int = context->value_handler->to_int(var);
-    int_t value_int = context->value_handler->to_int(value);
-    int_t result = var_int - value_int;
-
-    context->stack_manager->push(context->value_handler->create_int(result));
-    return STATUS_SUCCESS;
+    return handle_sub(context);
 }
 
 status_t handle_assign_mul(VMContext* context) {
-    status_t status = check_stack_size(context, 2);
-    if (status != STATUS_SUCCESS) {
-        return status;
-    }
-
-    Value value = context->stack_manager->pop();
-    Value var = context->stack_manager->pop();
+    return handle_mul(context);
+}
 
-    int_t var_int = context->value_handler->to_int(var);
-    int_t value_int = context->value_handler->to_int(value);
-    int_t result = var_int * value_int;
+status_t handle_assign_div(VMContext* context) {
+    return handle_div(context);
+}
 
-    context->stack_manager->push(context->value_handler->create_int(result));
-    return STATUS_SUCCESS;
+status_t handle_assign_pow(VMContext* context) {
+    return handle_pow(context);
 }
 
-status_t handle_assign_div(VMContext* context) {
-    status_t status = check_stack_size(context, 2);
-    if (status != STATUS_SUCCESS) {
-        return status;
-    }
+status_t handle_assign_bit_and(VMContext* context) {
+    return handle_bit_and(context);
+}
 
-    Value value = context->stack_manager->pop();
-    Value var = context->stack_manager->pop();
+status_t handle_assign_bit_or(VMContext* context) {
+    return handle_bit_or(context);
+}
 
-    int_t var_int = context->value_handler->to_int(var);
-    int_t value_int = context->value_handler->to_int(value);
+status_t handle_assign_bit_xor(VMContext* context) {
+    return handle_bit_xor(context);
+}
 
-    if (value_int == 0) {
-        context->error_handler->warning("Division by zero in /= at ip=%zu", context->ip - 1);
-        context->stack_manager->push(context->value_handler->create_int(0));
-        return STATUS_DIVISION_BY_ZERO;
-    }
+status_t handle_assign_lshift(VMContext* context) {
+    return handle_lshift(context);
+}
 
-    int_t result = var_int / value_int;
-    context->stack_manager->push(context->value_handler->create_int(result));
-    return STATUS_SUCCESS;
+status_t handle_assign_rshift(VMContext* context) {
+    return handle_rshift(context);
 }
 
 status_t handle_assign_mod(VMContext* context) {
@@ -373,12 +355,10 @@ status_t handle_assign_mod(VMContext* context) {
     int_t value_int = context->value_handler->to_int(value);
 
     if (value_int == 0) {
-        context->error_handler->warning("Modulo by zero in %= at ip=%zu", context->ip - 1);
-        context->stack_manager->push(context->value_handler->create_int(0));
-        return STATUS_DIVISION_BY_ZERO;
+        return throw_error(context, "DivisionByZeroError", "Modulo by zero");
     }
 
-    int_t result = var_int % value_int;
+    int_t result = value_int == -1 ? 0 : var_int % value_int;
     context->stack_manager->push(context->value_handler->create_int(result));
     return STATUS_SUCCESS;
 }
@@ -0,0 +1,54 @@
+/* Licensed under GNU GPL v3. See LICENSE file for details. */
+#include "../../includes/interfaces/opcode_handler.h"
+#include "../../includes/interfaces/native.h"
+#include "../../includes/interfaces/exception.h"
+
+// handle_call_native pops the arguments, which the caller pushed in source
+// order, and calls the native named by the constant operand. It pushes the
+// result, then the final value of each by-reference argument in parameter
+// order for the caller to store back.
+status_t handle_call_native(VMContext* context) {
+    if (context->ip + 2 > context->bytecode_len) {
+        context->error_handler->runtime_error("Unexpected end of bytecode at ip=%zu", context->ip);
+        return STATUS_ERROR;
+    }
+    byte_t name_idx = context->bytecode[context->ip++];
+    byte_t argc = context->bytecode[context->ip++];
+
+    if (name_idx >= context->constants_len || context->constants[name_idx].type != TYPE_STRING) {
+        context->error_handler->runtime_error("Invalid native function name constant %d at ip=%zu",
+                                             name_idx, context->ip - 3);
+        return STATUS_ERROR;
+    }
+    const char* name = context->constants[name_idx].value.str_val;
+
+    const NativeFunction* function = native_lookup(name);
+    if (!function) {
+        return throw_error(context, "Error", "Call to undefined function %s()", name);
+    }
+
+    if (context->stack_manager->size() < argc) {
+        context->error_handler->runtime_error("CALL_NATIVE expects %d arguments, stack has %d values",
+                                             argc, context->stack_manager->size());
+        return STATUS_STACK_UNDERFLOW;
+    }
+
+    Value args[256];
+    for (int i = argc - 1; i >= 0; i--) {
+        args[i] = context->stack_manager->pop();
+    }
+
+    Value result;
+    status_t status = function->impl(context, args, argc, &result);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    context->stack_manager->push(result);
+    for (int i = 0; i < argc && i < 32; i++) {
+        if (function->by_ref & (1u << i)) {
+            context->stack_manager->push(args[i]);
+        }
+    }
+    return STATUS_SUCCESS;
+}
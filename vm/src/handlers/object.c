@@ -0,0 +1,707 @@
+/* Licensed under GNU GPL v3. See LICENSE file for details. */
+#include "../../includes/interfaces/opcode_handler.h"
+#include "../../includes/interfaces/object.h"
+#include "../../includes/interfaces/exception.h"
+#include <string.h>
+#include <strings.h>
+
+/*
+ * Classes are built at run time: CLASS_DECL creates one, CLASS_EXTENDS and
+ * CLASS_IMPLEMENTS link it to its parent and interfaces, then CLASS_PROP
+ * and CLASS_METHOD add its members. Instance methods are functions whose
+ * first parameter is $this; calls push the object on top of the arguments
+ * so that FUNC_DECL binds it to local slot 0. Static methods have no $this.
+ */
+
+static status_t read_byte(VMContext* context, byte_t* out) {
+    if (context->ip >= context->bytecode_len) {
+        context->error_handler->runtime_error("Unexpected end of bytecode at ip=%zu", context->ip);
+        return STATUS_ERROR;
+    }
+
+    *out = context->bytecode[context->ip++];
+    return STATUS_SUCCESS;
+}
+
+// read_name reads a constant operand that names a class or a member.
+static status_t read_name(VMContext* context, const char** out) {
+    byte_t idx;
+    status_t status = read_byte(context, &idx);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    if (idx >= context->constants_len || context->constants[idx].type != TYPE_STRING) {
+        context->error_handler->runtime_error("Invalid name constant %d at ip=%zu", idx, context->ip - 1);
+        return STATUS_ERROR;
+    }
+
+    *out = context->constants[idx].value.str_val;
+    return STATUS_SUCCESS;
+}
+
+// scope returns the class of the running method, or NULL outside methods.
+static Class* scope(VMContext* context) {
+    if (context->frame_count == 0) {
+        return NULL;
+    }
+    return context->frames[context->frame_count - 1].scope;
+}
+
+// current_frame returns the frame of the running function, or NULL at the
+// top level.
+static CallFrame* current_frame(VMContext* context) {
+    if (context->frame_count == 0) {
+        return NULL;
+    }
+    return &context->frames[context->frame_count - 1];
+}
+
+// can_access reports whether a member that class declares with the given
+// visibility can be used from the running code. Private members are only
+// visible to the methods of class, protected members to the methods of the
+// classes related to it by inheritance.
+static bool can_access(VMContext* context, const Class* class, Visibility visibility) {
+    Class* current = scope(context);
+    switch (visibility) {
+        case VISIBILITY_PRIVATE:
+            return current == class;
+        case VISIBILITY_PROTECTED:
+            return current && (class_instance_of(current, class) || class_instance_of(class, current));
+        default:
+            return true;
+    }
+}
+
+// scope_name describes the calling scope the way PHP error messages do.
+static const char* scope_name(VMContext* context, char* buffer, size_t size) {
+    Class* class = scope(context);
+    if (!class) {
+        return "global scope";
+    }
+
+    snprintf(buffer, size, "scope %s", class->name);
+    return buffer;
+}
+
+// handle_class_decl creates a class, an interface or a trait.
+status_t handle_class_decl(VMContext* context) {
+    const char* name;
+    byte_t flags;
+    status_t status = read_name(context, &name);
+    if (status == STATUS_SUCCESS) {
+        status = read_byte(context, &flags);
+    }
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    Class* class = class_new(name, flags);
+    if (!class) {
+        context->error_handler->runtime_error("Memory allocation failed for class %s", name);
+        return STATUS_OUT_OF_MEMORY;
+    }
+
+    if (class_find(context->classes, name)) {
+        return context->error_handler->runtime_error("Cannot declare %s %s, because the name is already in use",
+                                                    class_kind_name(class), name);
+    }
+
+    class->next = context->classes;
+    context->classes = class;
+    context->declaring = class;
+    return STATUS_SUCCESS;
+}
+
+// handle_class_extends makes the class being declared inherit the members
+// of its parent.
+status_t handle_class_extends(VMContext* context) {
+    const char* name;
+    status_t status = read_name(context, &name);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    Class* class = context->declaring;
+    if (!class) {
+        context->error_handler->runtime_error("CLASS_EXTENDS outside of a class declaration at ip=%zu", context->ip - 2);
+        return STATUS_ERROR;
+    }
+
+    Class* parent = class_find(context->classes, name);
+    if (!parent) {
+        return context->error_handler->runtime_error("Class \"%s\" not found", name);
+    }
+    if (parent->flags & CLASS_FINAL) {
+        return context->error_handler->runtime_error("Class %s cannot extend final class %s", class->name, parent->name);
+    }
+    if ((parent->flags & CLASS_INTERFACE) != (class->flags & CLASS_INTERFACE) || (parent->flags & CLASS_TRAIT)) {
+        return context->error_handler->runtime_error("Class %s cannot extend %s %s",
+                                                    class->name, class_kind_name(parent), parent->name);
+    }
+
+    if (class->flags & CLASS_INTERFACE) {
+        class_implement(class, parent);
+    } else {
+        class_inherit(class, parent);
+    }
+    return STATUS_SUCCESS;
+}
+
+// handle_class_implements records an interface of the class being
+// declared. The compiler has checked that the class implements its methods.
+status_t handle_class_implements(VMContext* context) {
+    const char* name;
+    status_t status = read_name(context, &name);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    Class* class = context->declaring;
+    if (!class) {
+        context->error_handler->runtime_error("CLASS_IMPLEMENTS outside of a class declaration at ip=%zu", context->ip - 2);
+        return STATUS_ERROR;
+    }
+
+    Class* iface = class_find(context->classes, name);
+    if (!iface) {
+        return context->error_handler->runtime_error("Interface \"%s\" not found", name);
+    }
+    if (!(iface->flags & CLASS_INTERFACE)) {
+        return context->error_handler->runtime_error("%s cannot implement %s - it is not an interface",
+                                                    class->name, iface->name);
+    }
+
+    class_implement(class, iface);
+    return STATUS_SUCCESS;
+}
+
+// handle_class_prop pops the default value of a property of the class
+// being declared.
+status_t handle_class_prop(VMContext* context) {
+    const char* name;
+    byte_t modifiers;
+    status_t status = read_name(context, &name);
+    if (status == STATUS_SUCCESS) {
+        status = read_byte(context, &modifiers);
+    }
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    if (context->stack_manager->is_empty()) {
+        context->error_handler->runtime_error("Stack underflow in CLASS_PROP at ip=%zu", context->ip - 3);
+        return STATUS_STACK_UNDERFLOW;
+    }
+    Value default_value = context->stack_manager->pop();
+
+    if (!context->declaring) {
+        context->error_handler->runtime_error("CLASS_PROP outside of a class declaration at ip=%zu", context->ip - 3);
+        return STATUS_ERROR;
+    }
+
+    class_add_property(context->declaring, name, default_value, (Visibility)(modifiers & VISIBILITY_MASK));
+    return STATUS_SUCCESS;
+}
+
+// handle_class_method adds a method whose code starts at the address operand.
+status_t handle_class_method(VMContext* context) {
+    const char* name;
+    byte_t modifiers, low_byte, high_byte;
+    status_t status = read_name(context, &name);
+    if (status == STATUS_SUCCESS) {
+        status = read_byte(context, &modifiers);
+    }
+    if (status == STATUS_SUCCESS) {
+        status = read_byte(context, &low_byte);
+    }
+    if (status == STATUS_SUCCESS) {
+        status = read_byte(context, &high_byte);
+    }
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+    uint16_t addr = (uint16_t)((high_byte << 8) | low_byte);
+
+    Class* class = context->declaring;
+    if (!class) {
+        context->error_handler->runtime_error("CLASS_METHOD outside of a class declaration at ip=%zu", context->ip - 5);
+        return STATUS_ERROR;
+    }
+
+    Method method = {
+        .name = (char*)name,
+        .class = class,
+        .addr = addr,
+        .visibility = (Visibility)(modifiers & VISIBILITY_MASK),
+        .is_static = (modifiers & MEMBER_STATIC) != 0,
+        .is_abstract = (modifiers & MEMBER_ABSTRACT) != 0,
+    };
+
+    // Abstract methods have no code. The parameter count of FUNC_DECL
+    // includes $this for instance methods.
+    if (!method.is_abstract) {
+        if ((size_t)addr + 1 >= context->bytecode_len || context->bytecode[addr] != OP_FUNC_DECL) {
+            context->error_handler->runtime_error("Invalid method address %u for %s::%s()", addr, class->name, name);
+            return STATUS_ERROR;
+        }
+        method.param_count = context->bytecode[addr + 1] - (method.is_static ? 0 : 1);
+    }
+
+    class_add_method(class, &method);
+    return STATUS_SUCCESS;
+}
+
+// call_native_method runs a method of a built-in class without entering a
+// frame. The arguments on the stack are last to first, so popping them
+// gives source order.
+static status_t call_native_method(VMContext* context, Object* object, const Method* method, int argc,
+                                   bool construct) {
+    Value args[256];
+    for (int i = 0; i < argc; i++) {
+        args[i] = context->stack_manager->pop();
+    }
+
+    Value result;
+    status_t status = method->native(context, object, args, argc, &result);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+    if (construct) {
+        result = context->value_handler->create_object(object);
+    }
+
+    context->stack_manager->push(result);
+    return STATUS_SUCCESS;
+}
+
+// call_method enters method with the argc arguments on top of the stack,
+// pushed last to first like for FUNC_CALL. For an instance method, the
+// object goes above them. called is the class static:: refers to.
+static status_t call_method(VMContext* context, Object* object, Class* called, const Method* method, int argc,
+                            bool construct) {
+    if (argc < method->param_count) {
+        return throw_error(context, "ArgumentCountError",
+                           "Too few arguments to function %s::%s(), %d passed and exactly %d expected",
+                           method->class->name, method->name, argc, method->param_count);
+    }
+
+    if (method->native) {
+        return call_native_method(context, object, method, argc, construct);
+    }
+
+    if (object) {
+        if (context->stack_manager->is_full()) {
+            context->error_handler->runtime_error("Stack overflow at ip=%zu", context->ip);
+            return STATUS_STACK_OVERFLOW;
+        }
+        context->stack_manager->push(context->value_handler->create_object(object));
+        argc++;
+    }
+
+    CallFrame* frame = push_frame(context, (byte_t)argc);
+    if (!frame) {
+        return STATUS_ERROR;
+    }
+    frame->addr = method->addr;
+    frame->method = method;
+    frame->scope = method->class;
+    frame->this_obj = object;
+    frame->static_class = called;
+    if (construct) {
+        frame->construct = object;
+    }
+
+    context->ip = method->addr;
+    return STATUS_SUCCESS;
+}
+
+// lookup_class resolves a class name operand. self, parent and static are
+// relative to the running method.
+static status_t lookup_class(VMContext* context, const char* name, Class** out) {
+    bool is_self = strcasecmp(name, "self") == 0;
+    bool is_parent = strcasecmp(name, "parent") == 0;
+    bool is_static = strcasecmp(name, "static") == 0;
+
+    if (is_self || is_parent || is_static) {
+        Class* current = scope(context);
+        if (!current) {
+            return throw_error(context, "Error", "Cannot use \"%s\" when no class scope is active",
+                               is_self ? "self" : is_parent ? "parent" : "static");
+        }
+        if (is_parent) {
+            if (!current->parent) {
+                return throw_error(context, "Error", "Cannot use \"parent\" when current class scope has no parent");
+            }
+            *out = current->parent;
+        } else if (is_static && current_frame(context)->static_class) {
+            *out = current_frame(context)->static_class;
+        } else {
+            *out = current;
+        }
+        return STATUS_SUCCESS;
+    }
+
+    *out = class_find(context->classes, name);
+    if (!*out) {
+        return throw_error(context, "Error", "Class \"%s\" not found", name);
+    }
+    return STATUS_SUCCESS;
+}
+
+// handle_instanceof pops a value and pushes whether it is an object of the
+// class operand, of a subclass of it or of a class implementing it. A
+// class that was never declared has no instances.
+status_t handle_instanceof(VMContext* context) {
+    const char* name;
+    status_t status = read_name(context, &name);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+    if (context->stack_manager->is_empty()) {
+        context->error_handler->runtime_error("Stack underflow in INSTANCEOF at ip=%zu", context->ip - 2);
+        return STATUS_STACK_UNDERFLOW;
+    }
+    Value value = context->stack_manager->pop();
+
+    Class* class;
+    if (strcasecmp(name, "self") == 0 || strcasecmp(name, "parent") == 0 || strcasecmp(name, "static") == 0) {
+        status = lookup_class(context, name, &class);
+        if (status != STATUS_SUCCESS) {
+            return status;
+        }
+    } else {
+        class = class_find(context->classes, name);
+    }
+
+    bool result = class && value.type == TYPE_OBJECT && class_instance_of(value.value.obj_val->class, class);
+    context->stack_manager->push(context->value_handler->create_boolean(result));
+    return STATUS_SUCCESS;
+}
+
+// find_method looks up a method of class. A private method of the running
+// class takes precedence over the methods of its subclasses, as in PHP.
+static const Method* find_method(VMContext* context, const Class* class, const char* name) {
+    Class* current = scope(context);
+    if (current && class_instance_of(class, current)) {
+        const Method* own = class_method(current, name);
+        if (own && own->class == current && own->visibility == VISIBILITY_PRIVATE) {
+            return own;
+        }
+    }
+    return class_method(class, name);
+}
+
+static status_t method_access_error(VMContext* context, const Method* method) {
+    char buffer[256];
+    return throw_error(context, "Error", "Call to %s method %s::%s() from %s",
+                       visibility_name(method->visibility), method->class->name,
+                       method->name, scope_name(context, buffer, sizeof(buffer)));
+}
+
+// handle_new creates an object with the default property values and calls
+// its constructor with the arguments on the stack. Without a constructor
+// the arguments are dropped and the object is pushed right away.
+status_t handle_new(VMContext* context) {
+    const char* name;
+    byte_t argc;
+    status_t status = read_name(context, &name);
+    if (status == STATUS_SUCCESS) {
+        status = read_byte(context, &argc);
+    }
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    Class* class;
+    status = lookup_class(context, name, &class);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+    if (class->flags & (CLASS_INTERFACE | CLASS_TRAIT)) {
+        return throw_error(context, "Error", "Cannot instantiate %s %s", class_kind_name(class), class->name);
+    }
+    if (class->flags & CLASS_ABSTRACT) {
+        return throw_error(context, "Error", "Cannot instantiate abstract class %s", class->name);
+    }
+    if (class == class_find(context->classes, CLOSURE_CLASS)) {
+        return throw_error(context, "Error", "Instantiation of class %s is not allowed", class->name);
+    }
+
+    if (context->stack_manager->size() < argc) {
+        context->error_handler->runtime_error("NEW expects %d arguments, stack has %d values",
+                                             argc, context->stack_manager->size());
+        return STATUS_STACK_UNDERFLOW;
+    }
+
+    Object* object = object_new(class);
+    if (!object) {
+        context->error_handler->runtime_error("Memory allocation failed for object of class %s", class->name);
+        return STATUS_OUT_OF_MEMORY;
+    }
+    if (is_throwable(context, class)) {
+        init_throwable(context, object);
+    }
+
+    const Method* constructor = class_method(class, "__construct");
+    if (!constructor) {
+        for (int i = 0; i < argc; i++) {
+            context->stack_manager->pop();
+        }
+        context->stack_manager->push(context->value_handler->create_object(object));
+        return STATUS_SUCCESS;
+    }
+
+    if (!can_access(context, constructor->class, constructor->visibility)) {
+        char buffer[256];
+        return throw_error(context, "Error", "Call to %s %s::__construct() from %s",
+                           visibility_name(constructor->visibility), class->name,
+                           scope_name(context, buffer, sizeof(buffer)));
+    }
+
+    return call_method(context, object, class, constructor, argc, true);
+}
+
+// handle_method_call calls a method of the object below the arguments.
+status_t handle_method_call(VMContext* context) {
+    const char* name;
+    byte_t argc;
+    status_t status = read_name(context, &name);
+    if (status == STATUS_SUCCESS) {
+        status = read_byte(context, &argc);
+    }
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    if (context->stack_manager->size() <= argc) {
+        context->error_handler->runtime_error("METHOD_CALL expects an object and %d arguments, stack has %d values",
+                                             argc, context->stack_manager->size());
+        return STATUS_STACK_UNDERFLOW;
+    }
+
+    // Take the object out from under the arguments.
+    Value args[256];
+    for (int i = 0; i < argc; i++) {
+        args[i] = context->stack_manager->pop();
+    }
+    Value target = context->stack_manager->pop();
+    for (int i = argc - 1; i >= 0; i--) {
+        context->stack_manager->push(args[i]);
+    }
+
+    if (target.type != TYPE_OBJECT) {
+        return throw_error(context, "Error", "Call to a member function %s() on %s",
+                           name, context->value_handler->type_name(target));
+    }
+
+    Object* object = target.value.obj_val;
+    const Method* method = find_method(context, object->class, name);
+    if (!method) {
+        return throw_error(context, "Error", "Call to undefined method %s::%s()", object->class->name, name);
+    }
+
+    if (!can_access(context, method->class, method->visibility)) {
+        return method_access_error(context, method);
+    }
+
+    return call_method(context, method->is_static ? NULL : object, object->class, method, argc, false);
+}
+
+// handle_static_call calls Class::method(). Through self, parent and
+// static the called class of the running method is kept for static::, and
+// an instance method is called on the current $this.
+status_t handle_static_call(VMContext* context) {
+    const char* class_name;
+    const char* name;
+    byte_t argc;
+    status_t status = read_name(context, &class_name);
+    if (status == STATUS_SUCCESS) {
+        status = read_name(context, &name);
+    }
+    if (status == STATUS_SUCCESS) {
+        status = read_byte(context, &argc);
+    }
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    if (context->stack_manager->size() < argc) {
+        context->error_handler->runtime_error("STATIC_CALL expects %d arguments, stack has %d values",
+                                             argc, context->stack_manager->size());
+        return STATUS_STACK_UNDERFLOW;
+    }
+
+    Class* class;
+    status = lookup_class(context, class_name, &class);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    const Method* method = find_method(context, class, name);
+    if (!method) {
+        return throw_error(context, "Error", "Call to undefined method %s::%s()", class->name, name);
+    }
+    if (!can_access(context, method->class, method->visibility)) {
+        return method_access_error(context, method);
+    }
+    if (method->is_abstract) {
+        return throw_error(context, "Error", "Cannot call abstract method %s::%s()",
+                           method->class->name, method->name);
+    }
+
+    CallFrame* current = current_frame(context);
+    if (method->is_static) {
+        bool forwarding = strcasecmp(class_name, "self") == 0 || strcasecmp(class_name, "parent") == 0 ||
+                          strcasecmp(class_name, "static") == 0;
+        Class* called = class;
+        if (forwarding && current && current->static_class) {
+            called = current->static_class;
+        }
+        return call_method(context, NULL, called, method, argc, false);
+    }
+
+    if (!current || !current->this_obj || !class_instance_of(current->this_obj->class, method->class)) {
+        return throw_error(context, "Error", "Non-static method %s::%s() cannot be called statically",
+                           method->class->name, method->name);
+    }
+    return call_method(context, current->this_obj, current->this_obj->class, method, argc, false);
+}
+
+static status_t check_property_access(VMContext* context, const Object* object, const char* name) {
+    const Property* property = class_property(object->class, name);
+    if (property && !can_access(context, property->class, property->visibility)) {
+        return throw_error(context, "Error", "Cannot access %s property %s::$%s",
+                           visibility_name(property->visibility), object->class->name, name);
+    }
+    return STATUS_SUCCESS;
+}
+
+// handle_prop_get replaces the object on top of the stack with the value of
+// one of its properties. Reading a missing property or a property of a
+// non-object gives null with a warning, as in PHP.
+status_t handle_prop_get(VMContext* context) {
+    const char* name;
+    status_t status = read_name(context, &name);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    if (context->stack_manager->is_empty()) {
+        context->error_handler->runtime_error("Stack underflow in PROP_GET at ip=%zu", context->ip - 2);
+        return STATUS_STACK_UNDERFLOW;
+    }
+    Value target = context->stack_manager->pop();
+
+    if (target.type != TYPE_OBJECT) {
+        context->error_handler->warning("Attempt to read property \"%s\" on %s",
+                                        name, context->value_handler->type_name(target));
+        context->stack_manager->push(context->value_handler->create_null());
+        return STATUS_SUCCESS;
+    }
+
+    Object* object = target.value.obj_val;
+    status = check_property_access(context, object, name);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    Value value;
+    if (!array_get(object->props, object_key(name), &value)) {
+        context->error_handler->warning("Undefined property: %s::$%s", object->class->name, name);
+        value = context->value_handler->create_null();
+    }
+
+    context->stack_manager->push(value);
+    return STATUS_SUCCESS;
+}
+
+// handle_prop_get_quiet is PROP_GET for isset, empty and ??. Properties that
+// are missing or not accessible from here read as null, and nothing is
+// reported.
+status_t handle_prop_get_quiet(VMContext* context) {
+    const char* name;
+    status_t status = read_name(context, &name);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    if (context->stack_manager->is_empty()) {
+        context->error_handler->runtime_error("Stack underflow in PROP_GET_QUIET at ip=%zu", context->ip - 2);
+        return STATUS_STACK_UNDERFLOW;
+    }
+    Value target = context->stack_manager->pop();
+
+    Value value = context->value_handler->create_null();
+    if (target.type == TYPE_OBJECT) {
+        Object* object = target.value.obj_val;
+        const Property* property = class_property(object->class, name);
+        if ((!property || can_access(context, property->class, property->visibility)) &&
+            !array_get(object->props, object_key(name), &value)) {
+            value = context->value_handler->create_null();
+        }
+    }
+
+    context->stack_manager->push(value);
+    return STATUS_SUCCESS;
+}
+
+// handle_prop_unset pops an object and removes one of its properties. Later
+// reads of the property warn like reads of one that was never set.
+status_t handle_prop_unset(VMContext* context) {
+    const char* name;
+    status_t status = read_name(context, &name);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    if (context->stack_manager->is_empty()) {
+        context->error_handler->runtime_error("Stack underflow in PROP_UNSET at ip=%zu", context->ip - 2);
+        return STATUS_STACK_UNDERFLOW;
+    }
+    Value target = context->stack_manager->pop();
+
+    if (target.type != TYPE_OBJECT) {
+        return STATUS_SUCCESS;
+    }
+
+    Object* object = target.value.obj_val;
+    status = check_property_access(context, object, name);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    array_remove(object->props, object_key(name));
+    return STATUS_SUCCESS;
+}
+
+// handle_prop_set pops a value and an object and stores the value in a
+// property. Properties that are not declared are created, public.
+status_t handle_prop_set(VMContext* context) {
+    const char* name;
+    status_t status = read_name(context, &name);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    if (context->stack_manager->size() < 2) {
+        context->error_handler->runtime_error("Stack underflow in PROP_SET at ip=%zu", context->ip - 2);
+        return STATUS_STACK_UNDERFLOW;
+    }
+    Value value = context->stack_manager->pop();
+    Value target = context->stack_manager->pop();
+
+    if (target.type != TYPE_OBJECT) {
+        return throw_error(context, "Error", "Attempt to assign property \"%s\" on %s",
+                           name, context->value_handler->type_name(target));
+    }
+
+    Object* object = target.value.obj_val;
+    status = check_property_access(context, object, name);
+    if (status != STATUS_SUCCESS) {
+        return status;
+    }
+
+    array_set(object->props, object_key(name), value);
+    return STATUS_SUCCESS;
+}
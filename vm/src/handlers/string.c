@@ -1,5 +1,6 @@
 /* Licensed under GNU GPL v3. See LICENSE file for details. */
 #include "../../includes/interfaces/opcode_handler.h"
+#include "../../includes/interfaces/exception.h"
 #include <string.h>
 
 status_t handle_concat(VMContext* context) {
@@ -16,6 +17,14 @@ status_t handle_concat(VMContext* context) {
     Value b = context->stack_manager->peek(0);
     Value a = context->stack_manager->peek(1);
 
+    if (a.type == TYPE_ARRAY || b.type == TYPE_ARRAY) {
+        context->error_handler->warning("Array to string conversion");
+    }
+    if (a.type == TYPE_OBJECT || b.type == TYPE_OBJECT) {
+        return throw_error(context, "Error", "Object of class %s could not be converted to string",
+                           context->value_handler->type_name(a.type == TYPE_OBJECT ? a : b));
+    }
+
     // Convert to strings
     char* str_a = context->value_handler->to_string(a);
     char* str_b = context->value_handler->to_string(b);